@@ -0,0 +1,36 @@
+package persistent
+
+import "github.com/TykTechnologies/storage/persistent/internal/types"
+
+// The errors of the persistent storages, to be checked with errors.Is. Some of them are returned along with
+// details, e.g. ErrStageUnsupported with the unsupported stage. Whatever the driver is, the errors of the rows
+// not found, of the duplicate keys and of the timeouts are checked with utils.IsNotFound, utils.IsDuplicateKey
+// and utils.IsTimeout.
+var (
+	ErrRowQueryDiffLength        = types.ErrRowQueryDiffLength
+	ErrEmptyRow                  = types.ErrEmptyRow
+	ErrMultipleQueryForSingleRow = types.ErrMultipleQueryForSingleRow
+	ErrMultipleDBM               = types.ErrMultipleDBM
+	ErrReconnecting              = types.ErrReconnecting
+	ErrIndexEmpty                = types.ErrIndexEmpty
+	ErrIndexAlreadyExist         = types.ErrIndexAlreadyExist
+	ErrIndexComposedTTL          = types.ErrIndexComposedTTL
+	ErrSessionClosed             = types.ErrSessionClosed
+	ErrRowOptDiffLength          = types.ErrRowOptDiffLength
+	ErrCollectionNotFound        = types.ErrCollectionNotFound
+	ErrTransactionsUnsupported   = types.ErrTransactionsUnsupported
+	ErrUnknownReadPreference     = types.ErrUnknownReadPreference
+	ErrChangeStreamsUnsupported  = types.ErrChangeStreamsUnsupported
+	ErrNotSoftDeletable          = types.ErrNotSoftDeletable
+	ErrMultipleFindOneOpts       = types.ErrMultipleFindOneOpts
+	ErrCircuitOpen               = types.ErrCircuitOpen
+	ErrSRVUnsupported            = types.ErrSRVUnsupported
+	ErrStageUnsupported          = types.ErrStageUnsupported
+	ErrInvalidPageCursor         = types.ErrInvalidPageCursor
+	ErrDryRunUnsupported         = types.ErrDryRunUnsupported
+	ErrTenantMismatch            = types.ErrTenantMismatch
+	ErrTenantField               = types.ErrTenantField
+	ErrTenantUnsupported         = types.ErrTenantUnsupported
+	ErrShardKeyMissing           = types.ErrShardKeyMissing
+	ErrShardingUnsupported       = types.ErrShardingUnsupported
+)
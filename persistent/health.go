@@ -0,0 +1,110 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// DefaultHealthLoopInterval is the interval of the HealthLoop started without a positive one.
+const DefaultHealthLoopInterval = 10 * time.Second
+
+// HealthChecker is implemented by every PersistentStorage.
+type HealthChecker types.HealthChecker
+
+// HealthLoop checks the health of a storage periodically in the background, keeping the last status
+// so it can be served without hitting the database, e.g. by a health endpoint.
+type HealthLoop struct {
+	checker  HealthChecker
+	interval time.Duration
+
+	mu     sync.RWMutex
+	status model.HealthStatus
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartBackgroundHealthLoop checks the health of checker every interval until ctx is done or the loop is stopped.
+// The first check runs right away. Each check is bounded by interval, so a hanging database is reported
+// as not live instead of blocking the loop. DefaultHealthLoopInterval is used if interval isn't positive.
+func StartBackgroundHealthLoop(ctx context.Context, checker HealthChecker, interval time.Duration) *HealthLoop {
+	if interval <= 0 {
+		interval = DefaultHealthLoopInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	l := &HealthLoop{
+		checker:  checker,
+		interval: interval,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	go l.run(ctx)
+
+	return l
+}
+
+// Status returns the status of the last check. It's the zero model.HealthStatus, not live, until the first
+// check completes.
+func (l *HealthLoop) Status() model.HealthStatus {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.status
+}
+
+// Live reports whether the database was reachable on the last check.
+func (l *HealthLoop) Live() bool {
+	return l.Status().Live
+}
+
+// Ready reports whether the database could take writes on the last check.
+func (l *HealthLoop) Ready() bool {
+	return l.Status().Ready
+}
+
+// Stop stops the loop and waits for the running check, if any, to complete.
+func (l *HealthLoop) Stop() {
+	l.cancel()
+	<-l.done
+}
+
+func (l *HealthLoop) run(ctx context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		l.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *HealthLoop) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, l.interval)
+	defer cancel()
+
+	status := l.checker.Health(ctx)
+
+	// the check interrupted by Stop isn't a failure of the database
+	if errors.Is(status.Err, context.Canceled) {
+		return
+	}
+
+	l.mu.Lock()
+	l.status = status
+	l.mu.Unlock()
+}
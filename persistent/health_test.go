@@ -0,0 +1,75 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// fakeChecker reports the database as ready, until down is set.
+type fakeChecker struct {
+	checks int32
+	down   int32
+}
+
+func (c *fakeChecker) Health(ctx context.Context) model.HealthStatus {
+	atomic.AddInt32(&c.checks, 1)
+
+	if atomic.LoadInt32(&c.down) == 1 {
+		return model.HealthStatus{Err: errors.New("no reachable servers")}
+	}
+
+	return model.HealthStatus{Live: true, Ready: true, PrimaryAvailable: true, Latency: time.Millisecond}
+}
+
+func TestHealthLoop(t *testing.T) {
+	checker := &fakeChecker{}
+
+	loop := StartBackgroundHealthLoop(context.Background(), checker, 10*time.Millisecond)
+	defer loop.Stop()
+
+	assert.Eventually(t, loop.Ready, time.Second, time.Millisecond)
+	assert.True(t, loop.Live())
+	assert.Equal(t, time.Millisecond, loop.Status().Latency)
+
+	atomic.StoreInt32(&checker.down, 1)
+
+	assert.Eventually(t, func() bool {
+		return !loop.Live()
+	}, time.Second, time.Millisecond)
+	assert.False(t, loop.Ready())
+	assert.Equal(t, errors.New("no reachable servers"), loop.Status().Err)
+}
+
+func TestHealthLoopStop(t *testing.T) {
+	checker := &fakeChecker{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loop := StartBackgroundHealthLoop(ctx, checker, time.Millisecond)
+	assert.Eventually(t, loop.Live, time.Second, time.Millisecond)
+
+	loop.Stop()
+
+	checks := atomic.LoadInt32(&checker.checks)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, checks, atomic.LoadInt32(&checker.checks))
+}
+
+func TestHealthLoopDefaultInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		loop := StartBackgroundHealthLoop(context.Background(), &fakeChecker{}, interval)
+
+		assert.Equal(t, DefaultHealthLoopInterval, loop.interval)
+		assert.Eventually(t, loop.Ready, time.Second, time.Millisecond)
+
+		loop.Stop()
+	}
+}
@@ -0,0 +1,146 @@
+// Package instrumentation wraps a persistent storage so each of its operations emits an OpenTelemetry span,
+// along with metrics of its latency and errors, e.g.
+//
+//	storage, err := persistent.NewPersistentStorage(opts)
+//	...
+//	storage, err = instrumentation.New(storage, opts.Type)
+//
+// The spans and metrics are tagged with the operation, the driver type and the table they run against.
+package instrumentation
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/TykTechnologies/storage/persistent/internal/middleware"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+)
+
+// InstrumentationName is the name of the tracer and meter used to instrument the storage.
+const InstrumentationName = "github.com/TykTechnologies/storage/persistent"
+
+const (
+	// DurationMetric is the histogram of the duration of the operations, in milliseconds.
+	DurationMetric = "persistent.operation.duration"
+	// ErrorsMetric is the counter of the operations which returned an error.
+	ErrorsMetric = "persistent.operation.errors"
+)
+
+// The attributes set in the spans and metrics of the operations.
+const (
+	OperationKey = attribute.Key("db.operation")
+	DriverKey    = attribute.Key("db.driver")
+	TableKey     = attribute.Key("db.table")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithTracerProvider sets the provider of the tracer emitting the spans. The global one is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider of the meter recording the metrics. The global one is used by default.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+type instrumentation struct {
+	driver   string
+	tracer   trace.Tracer
+	duration instrument.Float64Histogram
+	errors   instrument.Int64Counter
+}
+
+// New returns a persistent storage running the operations of next, instrumented with OpenTelemetry.
+// driver is the type of driver of next, e.g. persistent.OfficialMongo, which is set in the spans and metrics.
+// The operations run within WithTransaction are instrumented as well, and Watch only instruments the start
+// of the watch.
+func New(next types.PersistentStorage, driver string, opts ...Option) (types.PersistentStorage, error) {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.tracerProvider == nil {
+		c.tracerProvider = otel.GetTracerProvider()
+	}
+
+	if c.meterProvider == nil {
+		c.meterProvider = global.MeterProvider()
+	}
+
+	meter := c.meterProvider.Meter(InstrumentationName)
+
+	duration, err := meter.Float64Histogram(DurationMetric,
+		instrument.WithUnit("ms"),
+		instrument.WithDescription("Duration of the persistent storage operations"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := meter.Int64Counter(ErrorsMetric,
+		instrument.WithDescription("Number of persistent storage operations which returned an error"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &instrumentation{
+		driver:   driver,
+		tracer:   c.tracerProvider.Tracer(InstrumentationName),
+		duration: duration,
+		errors:   errs,
+	}
+
+	return middleware.Wrap(next, i.handle), nil
+}
+
+// handle runs op within a span, recording its duration and its error, if any.
+func (i *instrumentation) handle(ctx context.Context,
+	op middleware.Operation,
+	run func(ctx context.Context) error,
+) error {
+	attrs := []attribute.KeyValue{OperationKey.String(op.Name), DriverKey.String(i.driver)}
+	if op.Table != "" {
+		attrs = append(attrs, TableKey.String(op.Table))
+	}
+
+	ctx, span := i.tracer.Start(ctx, "persistent."+op.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := run(ctx)
+
+	i.duration.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), attrs...)
+
+	if err != nil {
+		i.errors.Add(ctx, 1, attrs...)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
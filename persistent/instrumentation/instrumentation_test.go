@@ -0,0 +1,154 @@
+package instrumentation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+type dummyDBObject struct {
+	ID model.ObjectID `bson:"_id,omitempty"`
+}
+
+func (d *dummyDBObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyDBObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyDBObject) TableName() string {
+	return "dummy"
+}
+
+// fakeStorage returns err from the operations used by the tests. Calling any other method panics.
+type fakeStorage struct {
+	types.PersistentStorage
+	err error
+}
+
+func (s *fakeStorage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	return s.err
+}
+
+func (s *fakeStorage) Ping(ctx context.Context) error {
+	return s.err
+}
+
+func (s *fakeStorage) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	return fn(s)
+}
+
+func newInstrumented(t *testing.T, next types.PersistentStorage) (
+	types.PersistentStorage, *tracetest.SpanRecorder, sdkmetric.Reader,
+) {
+	t.Helper()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	storage, err := New(next, "mongo-go",
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	assert.Nil(t, err)
+
+	return storage, spans, reader
+}
+
+// collect returns the metrics recorded by reader, by name.
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+
+	err := reader.Collect(context.Background(), &rm)
+	assert.Nil(t, err)
+
+	metrics := map[string]metricdata.Aggregation{}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	return metrics
+}
+
+func TestInstrumentation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful operation", func(t *testing.T) {
+		storage, spans, reader := newInstrumented(t, &fakeStorage{})
+
+		err := storage.Insert(ctx, &dummyDBObject{})
+		assert.Nil(t, err)
+
+		attrs := []attribute.KeyValue{OperationKey.String("Insert"), DriverKey.String("mongo-go"), TableKey.String("dummy")}
+
+		ended := spans.Ended()
+		assert.Len(t, ended, 1)
+		assert.Equal(t, "persistent.Insert", ended[0].Name())
+		assert.Equal(t, attrs, ended[0].Attributes())
+		assert.Equal(t, codes.Unset, ended[0].Status().Code)
+
+		metrics := collect(t, reader)
+
+		duration, ok := metrics[DurationMetric].(metricdata.Histogram)
+		assert.True(t, ok)
+		assert.Len(t, duration.DataPoints, 1)
+		assert.Equal(t, uint64(1), duration.DataPoints[0].Count)
+		assert.Equal(t, attribute.NewSet(attrs...), duration.DataPoints[0].Attributes)
+
+		_, ok = metrics[ErrorsMetric]
+		assert.False(t, ok)
+	})
+
+	t.Run("failed operation", func(t *testing.T) {
+		storage, spans, reader := newInstrumented(t, &fakeStorage{err: errors.New("test")})
+
+		err := storage.Ping(ctx)
+		assert.Equal(t, errors.New("test"), err)
+
+		attrs := []attribute.KeyValue{OperationKey.String("Ping"), DriverKey.String("mongo-go")}
+
+		ended := spans.Ended()
+		assert.Len(t, ended, 1)
+		assert.Equal(t, attrs, ended[0].Attributes())
+		assert.Equal(t, codes.Error, ended[0].Status().Code)
+		assert.Equal(t, "test", ended[0].Status().Description)
+
+		errs, ok := collect(t, reader)[ErrorsMetric].(metricdata.Sum[int64])
+		assert.True(t, ok)
+		assert.Len(t, errs.DataPoints, 1)
+		assert.Equal(t, int64(1), errs.DataPoints[0].Value)
+		assert.Equal(t, attribute.NewSet(attrs...), errs.DataPoints[0].Attributes)
+	})
+
+	t.Run("operations within a transaction", func(t *testing.T) {
+		storage, spans, _ := newInstrumented(t, &fakeStorage{})
+
+		err := storage.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+			return tx.Insert(ctx, &dummyDBObject{})
+		})
+		assert.Nil(t, err)
+
+		ended := spans.Ended()
+		assert.Len(t, ended, 2)
+		assert.Equal(t, "persistent.Insert", ended[0].Name())
+		assert.Equal(t, "persistent.WithTransaction", ended[1].Name())
+	})
+}
@@ -0,0 +1,112 @@
+package mgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var _ model.Cursor = &mgoCursor{}
+
+// mgoCursor wraps a *mgo.Iter along with the session copy it runs on, which is closed with the cursor,
+// and the table of its rows, to decode their fields with a codec registered.
+type mgoCursor struct {
+	sess    *mgo.Session
+	iter    *contextIter
+	current bson.Raw
+	table   string
+}
+
+func (c *mgoCursor) Next() bool {
+	return c.iter.Next(&c.current)
+}
+
+func (c *mgoCursor) Decode(result interface{}) error {
+	if err := c.current.Unmarshal(result); err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(c.table, result)
+}
+
+func (c *mgoCursor) Err() error {
+	return c.iter.Err()
+}
+
+func (c *mgoCursor) Close() error {
+	defer c.sess.Close()
+
+	return c.iter.Close()
+}
+
+// contextIter is a *mgo.Iter which stops iterating once its context is done, given that mgo ignores the contexts:
+// the documents of the current batch aren't returned anymore, and Err and Close return the error of the context.
+type contextIter struct {
+	*mgo.Iter
+	ctx context.Context
+	err error
+}
+
+func newContextIter(ctx context.Context, iter *mgo.Iter) *contextIter {
+	return &contextIter{Iter: iter, ctx: ctx}
+}
+
+func (it *contextIter) Next(result interface{}) bool {
+	if it.err == nil {
+		it.err = it.ctx.Err()
+	}
+
+	if it.err != nil {
+		return false
+	}
+
+	return it.Iter.Next(result)
+}
+
+func (it *contextIter) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	return it.Iter.Err()
+}
+
+// All decodes the remaining documents into the slice pointed by result, like mgo.Iter.All, and closes the iterator.
+// It stops once the context is done, returning its error.
+func (it *contextIter) All(result interface{}) error {
+	resultValue := reflect.ValueOf(result)
+	if resultValue.Kind() != reflect.Ptr || resultValue.Elem().Kind() != reflect.Slice {
+		return errors.New("result argument must be a slice address")
+	}
+
+	slice := resultValue.Elem().Slice(0, 0)
+	elemType := slice.Type().Elem()
+
+	for {
+		elem := reflect.New(elemType)
+		if !it.Next(elem.Interface()) {
+			break
+		}
+
+		slice = reflect.Append(slice, elem.Elem())
+	}
+
+	resultValue.Elem().Set(slice)
+
+	return it.Close()
+}
+
+// Close closes the iterator, killing its cursor on the server even if the context is done.
+func (it *contextIter) Close() error {
+	err := it.Iter.Close()
+	if it.err != nil {
+		return it.err
+	}
+
+	return err
+}
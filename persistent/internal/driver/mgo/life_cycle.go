@@ -4,12 +4,16 @@ import (
 	"crypto/tls"
 	"errors"
 	"net"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
 	"github.com/TykTechnologies/storage/persistent/utils"
 
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/TykTechnologies/storage/persistent/internal/types"
 )
@@ -20,10 +24,33 @@ type lifeCycle struct {
 	session          *mgo.Session
 	db               *mgo.Database
 	connectionString string
+	// poolSize is the maximum number of connections of the pool, per server.
+	poolSize int
+	// socketTimeout is the socket timeout of the session, shortened for the operations with an earlier deadline.
+	socketTimeout time.Duration
+	onEvent       func(model.ConnectionEvent)
+	// dbType is the type of the database, detected when connecting.
+	dbType utils.DBType
+	// refresher reconnects before the credentials of ClientOpts.CredentialsProvider expire.
+	refresher helper.CredentialsRefresher
 }
 
-// Connect connects to the mongo database given the ClientOpts.
-func (lc *lifeCycle) Connect(opts *types.ClientOpts) error {
+// Connect connects to the mongo database given the ClientOpts. The credentials of the connection string
+// are removed from the errors, which may be logged.
+func (lc *lifeCycle) Connect(opts *types.ClientOpts) (err error) {
+	dsn := opts.ConnectionString
+	defer func() {
+		err = utils.SanitizeError(err, dsn)
+	}()
+
+	reconnecting := lc.session != nil
+	lc.onEvent = opts.OnConnectionEvent
+
+	// mgo doesn't resolve the hosts of mongodb+srv connection strings, and would take the scheme as a host
+	if strings.HasPrefix(opts.ConnectionString, "mongodb+srv://") {
+		return types.ErrSRVUnsupported
+	}
+
 	dialInfo, err := mgo.ParseURL(opts.ConnectionString)
 	if err != nil {
 		return err
@@ -36,7 +63,25 @@ func (lc *lifeCycle) Connect(opts *types.ClientOpts) error {
 
 	dialInfo.Direct = opts.DirectConnection
 
-	if opts.UseSSL {
+	// mgo only supports limiting the pool size. The maxPoolSize of the connection string has precedence.
+	if opts.MaxOpenConns > 0 && dialInfo.PoolLimit == 0 {
+		dialInfo.PoolLimit = opts.MaxOpenConns
+	}
+
+	setAuthMechanism(dialInfo, opts)
+
+	creds, err := opts.FetchCredentials()
+	if err != nil {
+		lc.notifyConnect(reconnecting, utils.SanitizeError(err, dsn))
+		return err
+	}
+
+	if creds != nil {
+		dialInfo.Username = creds.Username
+		dialInfo.Password = creds.Password
+	}
+
+	if opts.TLSConfig().Enable {
 		tlsConfig, err := opts.GetTLSConfig()
 		if err != nil {
 			return err
@@ -49,6 +94,7 @@ func (lc *lifeCycle) Connect(opts *types.ClientOpts) error {
 
 	sess, err := mgo.DialWithInfo(dialInfo)
 	if err != nil {
+		lc.notifyConnect(reconnecting, utils.SanitizeError(err, dsn))
 		return err
 	}
 
@@ -56,30 +102,117 @@ func (lc *lifeCycle) Connect(opts *types.ClientOpts) error {
 	sess.SetSyncTimeout(dialInfo.Timeout)
 
 	lc.session = sess
+	lc.socketTimeout = dialInfo.Timeout
 
-	lc.setSessionConsistency(opts)
+	if err := lc.setSessionConsistency(opts); err != nil {
+		lc.session.Close()
+		lc.session = nil
+
+		return err
+	}
 	lc.connectionString = opts.ConnectionString
 	lc.db = lc.session.DB("")
+	lc.poolSize = dialInfo.PoolLimit
+
+	// mgo defaults to a pool of 4096 connections
+	if lc.poolSize == 0 {
+		lc.poolSize = 4096
+	}
+
+	lc.dbType = lc.detectDBType()
+
+	if creds != nil {
+		lc.refresher.Schedule(creds.Expiration, func() error {
+			return lc.refreshCredentials(opts)
+		})
+	}
+
+	lc.notifyConnect(reconnecting, nil)
 
 	return nil
 }
 
+// refreshCredentials reconnects with new credentials, and closes the session of the previous ones.
+func (lc *lifeCycle) refreshCredentials(opts *types.ClientOpts) error {
+	previous := lc.session
+
+	if err := lc.Connect(opts); err != nil {
+		return err
+	}
+
+	if previous != nil {
+		previous.Close()
+	}
+
+	return nil
+}
+
+// setAuthMechanism sets the mechanism, the source and the GSSAPI service of ClientOpts, unless set by the
+// connection string. mgo doesn't support MONGODB-AWS.
+func setAuthMechanism(dialInfo *mgo.DialInfo, opts *types.ClientOpts) {
+	if dialInfo.Mechanism == "" {
+		dialInfo.Mechanism = opts.AuthMechanism
+	}
+
+	if dialInfo.Source == "" {
+		dialInfo.Source = opts.AuthSource
+	}
+
+	if dialInfo.Service == "" {
+		dialInfo.Service = opts.AuthMechanismProperties["SERVICE_NAME"]
+	}
+
+	if dialInfo.ServiceHost == "" {
+		dialInfo.ServiceHost = opts.AuthMechanismProperties["SERVICE_HOST"]
+	}
+}
+
+// notifyConnect sends the event of a connection attempt. A failed first attempt isn't notified,
+// as its error is returned when creating the driver.
+func (lc *lifeCycle) notifyConnect(reconnecting bool, err error) {
+	switch {
+	case err == nil && reconnecting:
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionReconnected})
+	case err == nil:
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionConnected})
+	case reconnecting:
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionDisconnected, Err: err})
+	}
+}
+
+func (lc *lifeCycle) notify(event model.ConnectionEvent) {
+	if lc.onEvent == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	lc.onEvent(event)
+}
+
 // Close finish the session.
 func (lc *lifeCycle) Close() error {
+	lc.refresher.Stop()
+
 	if lc.session != nil {
 		lc.session.Close()
 
 		lc.session = nil
 		lc.db = nil
 
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionDisconnected})
+
 		return nil
 	}
 
 	return errors.New("closing a no connected database")
 }
 
-// DBType returns the type of the registered storage driver.
+// DBType returns the type of the registered storage driver, detected when connecting.
 func (lc *lifeCycle) DBType() utils.DBType {
+	return lc.dbType
+}
+
+func (lc *lifeCycle) detectDBType() utils.DBType {
 	if helper.IsCosmosDB(lc.connectionString) {
 		return utils.CosmosDB
 	}
@@ -95,7 +228,22 @@ func (lc *lifeCycle) DBType() utils.DBType {
 	return utils.StandardMongo
 }
 
-func (lc *lifeCycle) setSessionConsistency(opts *types.ClientOpts) {
+func (lc *lifeCycle) setSessionConsistency(opts *types.ClientOpts) error {
+	if opts.ReadPreference != "" {
+		mode, err := modeFromReadPreference(opts.ReadPreference)
+		if err != nil {
+			return err
+		}
+
+		lc.session.SetMode(mode, true)
+
+		if len(opts.ReadPreferenceTags) > 0 {
+			lc.session.SelectServers(tagSetsToBSON(opts.ReadPreferenceTags)...)
+		}
+
+		return nil
+	}
+
 	switch opts.SessionConsistency {
 	case "eventual":
 		lc.session.SetMode(mgo.Eventual, true)
@@ -104,4 +252,29 @@ func (lc *lifeCycle) setSessionConsistency(opts *types.ClientOpts) {
 	default:
 		lc.session.SetMode(mgo.Strong, true)
 	}
+
+	return nil
+}
+
+// tagSetsToBSON converts the read preference tag sets to the format expected by mgo, sorting the tags by name.
+func tagSetsToBSON(tagSets []map[string]string) []bson.D {
+	result := make([]bson.D, 0, len(tagSets))
+
+	for _, tagSet := range tagSets {
+		names := make([]string, 0, len(tagSet))
+		for name := range tagSet {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		tags := make(bson.D, 0, len(names))
+		for _, name := range names {
+			tags = append(tags, bson.DocElem{Name: name, Value: tagSet[name]})
+		}
+
+		result = append(result, tags)
+	}
+
+	return result
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
 	"strings"
 	"time"
 
@@ -28,7 +30,7 @@ type mgoDriver struct {
 
 // NewMgoDriver returns an instance of the driver connected to the database.
 func NewMgoDriver(opts *types.ClientOpts) (*mgoDriver, error) {
-	newDriver := &mgoDriver{}
+	newDriver := &mgoDriver{options: *opts}
 
 	// create the db life cycle manager
 	lc := &lifeCycle{}
@@ -43,327 +45,1184 @@ func NewMgoDriver(opts *types.ClientOpts) (*mgoDriver, error) {
 	return newDriver, nil
 }
 
-func (d *mgoDriver) Insert(ctx context.Context, rows ...model.DBObject) error {
-	if len(rows) == 0 {
-		return errors.New(types.ErrorEmptyRow)
+// copySession returns a copy of the session for an operation of ctx, failing if ctx is already done.
+// mgo ignores the contexts, so the socket timeout of the copy is shortened to the deadline of ctx, if any:
+// the operations then fail once the deadline is exceeded instead of hanging, as with the mongo driver.
+func (d *mgoDriver) copySession(ctx context.Context) (*mgo.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	sess := d.session.Copy()
-	defer sess.Close()
 
-	colName := rows[0].TableName()
-	col := sess.DB("").C(colName)
-	bulk := col.Bulk()
-
-	for _, row := range rows {
-		if row.GetObjectID() == "" {
-			row.SetObjectID(model.NewObjectID())
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			sess.Close()
+			return nil, context.DeadlineExceeded
 		}
 
-		bulk.Insert(row)
+		if d.socketTimeout == 0 || timeout < d.socketTimeout {
+			sess.SetSocketTimeout(timeout)
+		}
 	}
 
-	_, err := bulk.Run()
+	return sess, nil
+}
 
-	return d.handleStoreError(err)
+// tableName returns the name of the collection of row, as resolved by ClientOpts.TableNameResolver.
+func (d *mgoDriver) tableName(ctx context.Context, row model.DBObject) string {
+	return d.options.ResolveTableName(helper.TableName(ctx, row))
 }
 
-func (d *mgoDriver) Delete(ctx context.Context, row model.DBObject, queries ...model.DBM) error {
-	if len(queries) > 1 {
-		return errors.New(types.ErrorMultipleQueryForSingleRow)
+// colName returns the name of the collection of a query: the one of its "_collection" key or the one of row.
+func (d *mgoDriver) colName(ctx context.Context, query model.DBM, row model.DBObject) (string, error) {
+	if _, ok := query["_collection"]; !ok && row != nil {
+		return d.tableName(ctx, row), nil
 	}
 
-	if len(queries) == 0 {
-		queries = append(queries, model.DBM{"_id": row.GetObjectID()})
+	colName, err := getColName(query, row)
+	if err != nil {
+		return "", err
 	}
 
-	sess := d.session.Copy()
-	defer sess.Close()
-
-	col := sess.DB("").C(row.TableName())
-
-	res, err := col.RemoveAll(buildQuery(queries[0]))
+	return d.options.ResolveTableName(colName), nil
+}
 
-	if err == nil && res.Removed == 0 {
-		return mgo.ErrNotFound
+func (d *mgoDriver) Insert(ctx context.Context, rows ...model.DBObject) error {
+	if helper.IsDryRun(ctx) {
+		rows = helper.DryRunRows(rows...)
 	}
 
-	return d.handleStoreError(err)
-}
+	helper.SetInsertTimestamps(rows...)
 
-func (d *mgoDriver) Update(ctx context.Context, row model.DBObject, queries ...model.DBM) error {
-	if len(queries) > 1 {
-		return errors.New(types.ErrorMultipleQueryForSingleRow)
+	if len(rows) == 0 {
+		return types.ErrEmptyRow
 	}
 
-	if len(queries) == 0 {
-		queries = append(queries, model.DBM{"_id": row.GetObjectID()})
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return err
 	}
 
-	sess := d.session.Copy()
-	defer sess.Close()
+	defer restore()
 
-	col := sess.DB("").C(row.TableName())
+	for _, row := range rows {
+		if row.GetObjectID() == "" {
+			row.SetObjectID(model.NewObjectID())
+		}
+	}
 
-	return d.handleStoreError(col.Update(buildQuery(queries[0]), bson.M{"$set": row}))
-}
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		docs, err := documents(rows...)
+		if err == nil {
+			dryRun.Record(helper.InsertCommand(d.tableName(ctx, rows[0]), docs))
+		}
 
-func (d *mgoDriver) BulkUpdate(ctx context.Context, rows []model.DBObject, query ...model.DBM) error {
-	if len(rows) == 0 {
-		return errors.New(types.ErrorEmptyRow)
+		return err
 	}
 
-	if len(rows) != len(query) && len(query) != 0 {
-		return errors.New(types.ErrorRowQueryDiffLenght)
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
 	}
 
-	sess := d.session.Copy()
 	defer sess.Close()
 
-	colName := rows[0].TableName()
+	colName := d.tableName(ctx, rows[0])
 	col := sess.DB("").C(colName)
 	bulk := col.Bulk()
 
-	for i := range rows {
-		if len(query) == 0 {
-			bulk.Update(bson.M{"_id": rows[i].GetObjectID()}, bson.M{"$set": rows[i]})
-
-			continue
-		}
-
-		bulk.Update(buildQuery(query[i]), bson.M{"$set": rows[i]})
+	for _, row := range rows {
+		bulk.Insert(row)
 	}
 
-	res, err := bulk.Run()
-	if err == nil && res.Modified == 0 {
-		return mgo.ErrNotFound
-	}
+	_, err = bulk.Run()
 
-	return d.handleStoreError(err)
+	return d.handleStoreError(ctx, err)
 }
 
-func (d *mgoDriver) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
-	sess := d.session.Copy()
-	defer sess.Close()
+func (d *mgoDriver) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
+	}
 
-	col := sess.DB("").C(row.TableName())
+	helper.SetInsertTimestamps(rows...)
 
-	result, err := col.UpdateAll(buildQuery(query), buildQuery(update))
-	if err == nil && result.Matched == 0 {
-		return mgo.ErrNotFound
+	if len(rows) == 0 {
+		return 0, types.ErrEmptyRow
 	}
 
-	return d.handleStoreError(err)
-}
-
-func (d *mgoDriver) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
-	if len(filters) > 1 {
-		return 0, errors.New(types.ErrorMultipleDBM)
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return 0, err
 	}
 
-	filter := bson.M{}
-	if len(filters) == 1 {
-		filter = buildQuery(filters[0])
+	defer restore()
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
 	}
 
-	sess := d.session.Copy()
 	defer sess.Close()
 
-	col := sess.DB("").C(row.TableName())
+	col := sess.DB("").C(d.tableName(ctx, rows[0]))
+
+	return helper.BulkInsert(rows, opts, func(batch []model.DBObject) (int, map[int]error) {
+		// mgo ignores the contexts, so the remaining batches fail once ctx is done
+		if err := ctx.Err(); err != nil {
+			return 0, failedBatch(len(batch), err)
+		}
+
+		bulk := col.Bulk()
+		if opts.ContinueOnError {
+			bulk.Unordered()
+		}
+
+		for _, row := range batch {
+			if row.GetObjectID() == "" {
+				row.SetObjectID(model.NewObjectID())
+			}
+
+			bulk.Insert(row)
+		}
+
+		_, err := bulk.Run()
+
+		failed := map[int]error{}
+		firstFailed := len(batch)
+
+		var bulkErr *mgo.BulkError
+		if errors.As(err, &bulkErr) {
+			for _, errCase := range bulkErr.Cases() {
+				if errCase.Index < 0 || errCase.Index >= len(batch) {
+					continue
+				}
+
+				failed[errCase.Index] = errCase.Err
 
-	n, err := col.Find(filter).Count()
+				if errCase.Index < firstFailed {
+					firstFailed = errCase.Index
+				}
+			}
+		}
+
+		switch {
+		case len(failed) > 0:
+			if !opts.ContinueOnError {
+				// an ordered insert stops at the failed row
+				return firstFailed, failed
+			}
+
+			return len(batch) - len(failed), failed
+		case err != nil:
+			err = d.handleStoreError(ctx, err)
+			for i := range batch {
+				failed[i] = err
+			}
+
+			return 0, failed
+		}
 
-	return n, d.handleStoreError(err)
+		return len(batch), failed
+	})
 }
 
-func (d *mgoDriver) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
-	session := d.session.Copy()
-	defer session.Close()
+func (d *mgoDriver) Delete(ctx context.Context, row model.DBObject, queries ...model.DBM) error {
+	if len(queries) > 1 {
+		return types.ErrMultipleQueryForSingleRow
+	}
 
-	colName, err := getColName(query, row)
+	if len(queries) == 0 {
+		queries = append(queries, model.DBM{"_id": row.GetObjectID()})
+	}
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(d.deleteCommand(ctx, row, queries[0]))
+		return nil
+	}
+
+	sess, err := d.copySession(ctx)
 	if err != nil {
 		return err
 	}
 
-	col := session.DB("").C(colName)
+	defer sess.Close()
 
-	search := buildQuery(query)
+	col := sess.DB("").C(d.tableName(ctx, row))
 
-	q := col.Find(search)
+	if _, ok := row.(model.SoftDeletable); ok {
+		res, err := softDelete(col, helper.SoftDeleteFilter(row, queries[0]))
+		if err == nil && res == 0 {
+			return mgo.ErrNotFound
+		}
 
-	sort, sortFound := query["_sort"].(string)
-	if sortFound {
-		q = q.Sort(sort)
+		return d.handleStoreError(ctx, err)
 	}
 
-	if limit, ok := query["_limit"].(int); ok && limit > 0 {
-		q = q.Limit(limit)
-	}
+	res, err := col.RemoveAll(buildQuery(queries[0]))
 
-	if offset, ok := query["_offset"].(int); ok && offset > 0 {
-		q = q.Skip(offset)
+	if err == nil && res.Removed == 0 {
+		return mgo.ErrNotFound
 	}
 
-	if helper.IsSlice(result) {
-		err = q.All(result)
-	} else {
-		err = q.One(result)
+	return d.handleStoreError(ctx, err)
+}
+
+// softDelete marks as deleted the rows matching query, returning how many were marked.
+func softDelete(col *mgo.Collection, query model.DBM) (int, error) {
+	res, err := col.UpdateAll(buildQuery(query), softDeleteUpdate())
+	if err != nil {
+		return 0, err
 	}
 
-	return d.handleStoreError(err)
+	return res.Updated, nil
 }
 
-func (d *mgoDriver) Drop(ctx context.Context, row model.DBObject) error {
-	sess := d.session.Copy()
-	defer sess.Close()
+// softDeleteUpdate returns the update marking rows as deleted now.
+func softDeleteUpdate() bson.M {
+	return bson.M{"$set": bson.M{model.DeletedAtField: time.Now()}}
+}
+
+// deleteCommand returns the command deleting the rows of the row table matching filter, or marking them as
+// deleted if row is a model.SoftDeletable.
+func (d *mgoDriver) deleteCommand(ctx context.Context, row model.DBObject, filter model.DBM) model.DBM {
+	if _, ok := row.(model.SoftDeletable); ok {
+		return helper.UpdateCommand(d.tableName(ctx, row), buildQuery(helper.SoftDeleteFilter(row, filter)),
+			softDeleteUpdate(), true)
+	}
 
-	return d.handleStoreError(sess.DB("").C(row.TableName()).DropCollection())
+	return helper.DeleteCommand(d.tableName(ctx, row), buildQuery(filter))
 }
 
-func (d *mgoDriver) Ping(ctx context.Context) (result error) {
-	if d.session == nil {
-		return errors.New(types.ErrorSessionClosed)
+func (d *mgoDriver) Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
 	}
 
-	defer func() {
-		if err := recover(); err != nil {
-			result = errors.New(types.ErrorSessionClosed + " from panic")
-		}
-	}()
+	if _, ok := row.(model.SoftDeletable); !ok {
+		return 0, types.ErrNotSoftDeletable
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
 
-	sess := d.session.Copy()
 	defer sess.Close()
 
-	return d.handleStoreError(sess.Ping())
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	deletedBefore := time.Now().Add(-olderThan)
+
+	res, err := col.RemoveAll(bson.M{
+		model.DeletedAtField: bson.M{"$gt": time.Time{}, "$lte": deletedBefore},
+	})
+	if err != nil {
+		return 0, d.handleStoreError(ctx, err)
+	}
+
+	return res.Removed, nil
 }
 
-func (d *mgoDriver) HasTable(ctx context.Context, collection string) (result bool, errResult error) {
-	if d.session == nil {
-		return false, errors.New(types.ErrorSessionClosed)
+func (d *mgoDriver) DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error) {
+	if len(filter) == 0 {
+		filter = model.DBM{"_id": row.GetObjectID()}
 	}
 
-	defer func() {
-		if err := recover(); err != nil {
-			errResult = errors.New(types.ErrorSessionClosed + " from panic")
-		}
-	}()
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(d.deleteCommand(ctx, row, filter))
+		return 0, nil
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
 
-	sess := d.session.Copy()
 	defer sess.Close()
 
-	names, err := sess.DB("").CollectionNames()
-	if err != nil {
-		return false, d.handleStoreError(err)
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	if _, ok := row.(model.SoftDeletable); ok {
+		deleted, err := softDelete(col, helper.SoftDeleteFilter(row, filter))
+		return int64(deleted), d.handleStoreError(ctx, err)
 	}
 
-	for _, name := range names {
-		if name == collection {
-			return true, nil
-		}
+	res, err := col.RemoveAll(buildQuery(filter))
+	if err != nil {
+		return 0, d.handleStoreError(ctx, err)
 	}
 
-	return false, nil
+	return int64(res.Removed), nil
 }
 
-func (d *mgoDriver) handleStoreError(err error) error {
-	if err == nil {
-		return nil
+func (d *mgoDriver) Update(ctx context.Context, row model.DBObject, queries ...model.DBM) error {
+	if helper.IsDryRun(ctx) {
+		row = helper.DryRunRows(row)[0]
 	}
 
-	listOfErrors := []string{
-		"EOF",
-		"Closed explicitly",
-		"reset by peer",
-		"no reachable servers",
-		"i/o timeout",
-	}
+	helper.SetUpdateTimestamps(row)
 
-	for _, substr := range listOfErrors {
-		if strings.Contains(err.Error(), substr) {
-			connErr := d.Connect(&d.options)
-			if connErr != nil {
-				return errors.New("error reconnecting to mongo: " + connErr.Error() + " after error: " + err.Error())
-			}
+	if len(queries) > 1 {
+		return types.ErrMultipleQueryForSingleRow
+	}
 
-			return err
-		}
+	if len(queries) == 0 {
+		queries = append(queries, model.DBM{"_id": row.GetObjectID()})
 	}
 
-	return err
-}
+	queries[0] = helper.SoftDeleteFilter(row, queries[0])
 
-func (d *mgoDriver) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
-	if len(index.Keys) == 0 {
-		return errors.New(types.ErrorIndexEmpty)
-	} else if len(index.Keys) > 1 && index.IsTTLIndex {
-		return errors.New(types.ErrorIndexComposedTTL)
+	restore, err := helper.EncodeFields(row)
+	if err != nil {
+		return err
 	}
 
-	var indexes []string
+	defer restore()
 
-	for _, key := range index.Keys {
-		for k, v := range key {
-			switch v.(type) {
-			case int, int32, int64:
-				if v.(int) == -1 {
-					indexes = append(indexes, "-"+k)
-				} else {
-					indexes = append(indexes, k)
-				}
-			default:
-				indexes = append(indexes, k+"_"+fmt.Sprint(v))
-			}
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		docs, err := documents(row)
+		if err == nil {
+			dryRun.Record(helper.UpdateCommand(d.tableName(ctx, row), buildQuery(queries[0]), bson.M{"$set": docs[0]}, false))
 		}
+
+		return err
 	}
 
-	newIndex := mgo.Index{
-		Name: index.Name,
-		Key:  indexes,
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
 	}
 
-	sess := d.session.Copy()
 	defer sess.Close()
 
-	col := sess.DB("").C(row.TableName())
+	col := sess.DB("").C(d.tableName(ctx, row))
 
-	if index.IsTTLIndex {
-		newIndex.ExpireAfter = time.Duration(index.TTL) * time.Second
-	}
-
-	return d.handleStoreError(col.EnsureIndex(newIndex))
+	return d.handleStoreError(ctx, col.Update(buildQuery(queries[0]), bson.M{"$set": row}))
 }
 
-func (d *mgoDriver) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
-	hasTable, err := d.HasTable(ctx, row.TableName())
-	if err != nil {
-		return nil, d.handleStoreError(err)
+func (d *mgoDriver) BulkUpdate(ctx context.Context, rows []model.DBObject, query ...model.DBM) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
 	}
 
-	if !hasTable {
-		return nil, errors.New(types.ErrorCollectionNotFound)
+	helper.SetUpdateTimestamps(rows...)
+
+	if len(rows) == 0 {
+		return types.ErrEmptyRow
 	}
 
-	var indexes []model.Index
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return err
+	}
 
-	sess := d.session.Copy()
-	defer sess.Close()
+	defer restore()
 
-	col := sess.DB("").C(row.TableName())
+	if len(rows) != len(query) && len(query) != 0 {
+		return types.ErrRowQueryDiffLength
+	}
 
-	indexesSpec, err := col.Indexes()
+	sess, err := d.copySession(ctx)
 	if err != nil {
-		return indexes, d.handleStoreError(err)
+		return err
 	}
 
-	for i := range indexesSpec {
-		var newKeys []model.DBM
+	defer sess.Close()
 
-		for _, strKey := range indexesSpec[i].Key {
+	colName := d.tableName(ctx, rows[0])
+	col := sess.DB("").C(colName)
+	bulk := col.Bulk()
+
+	for i := range rows {
+		if len(query) == 0 {
+			bulk.Update(buildQuery(helper.SoftDeleteFilter(rows[i], model.DBM{"_id": rows[i].GetObjectID()})),
+				bson.M{"$set": rows[i]})
+
+			continue
+		}
+
+		bulk.Update(buildQuery(helper.SoftDeleteFilter(rows[i], query[i])), bson.M{"$set": rows[i]})
+	}
+
+	res, err := bulk.Run()
+	if err == nil && res.Modified == 0 {
+		return mgo.ErrNotFound
+	}
+
+	return d.handleStoreError(ctx, err)
+}
+
+func (d *mgoDriver) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	query = helper.SoftDeleteFilter(row, query)
+
+	update, err := helper.EncodeUpdate(row, helper.TimestampedUpdate(row, update))
+	if err != nil {
+		return err
+	}
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.UpdateCommand(d.tableName(ctx, row), buildQuery(query), buildQuery(update), true))
+		return nil
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	result, err := col.UpdateAll(buildQuery(query), buildQuery(update))
+	if err == nil && result.Matched == 0 {
+		return mgo.ErrNotFound
+	}
+
+	return d.handleStoreError(ctx, err)
+}
+
+func (d *mgoDriver) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
+	if len(filters) > 1 {
+		return 0, types.ErrMultipleDBM
+	}
+
+	query := model.DBM{}
+	if len(filters) == 1 {
+		query = filters[0]
+	}
+
+	query = helper.SoftDeleteFilter(row, query)
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.CountCommand(d.tableName(ctx, row), buildQuery(query)))
+		return 0, nil
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer sess.Close()
+
+	if err := setQueryReadPref(sess, query); err != nil {
+		return 0, err
+	}
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	n, err := col.Find(buildQuery(query)).Count()
+
+	return n, d.handleStoreError(ctx, err)
+}
+
+func (d *mgoDriver) CountWithOpts(ctx context.Context,
+	row model.DBObject,
+	opts model.CountOpts,
+	filters ...model.DBM,
+) (int, error) {
+	query := model.DBM{}
+	if len(filters) == 1 {
+		query = filters[0]
+	}
+
+	// the estimation ignores the filters, so the rows are counted when they filter any field,
+	// including the deleted_at field skipping the soft deleted rows
+	if !opts.Estimated || len(filters) > 1 || len(buildQuery(helper.SoftDeleteFilter(row, query))) > 0 {
+		return d.Count(ctx, row, filters...)
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer sess.Close()
+
+	if err := setQueryReadPref(sess, query); err != nil {
+		return 0, err
+	}
+
+	// the count command without query returns the number of documents from the metadata of the collection
+	n, err := sess.DB("").C(d.tableName(ctx, row)).Count()
+
+	return n, d.handleStoreError(ctx, err)
+}
+
+func (d *mgoDriver) Distinct(ctx context.Context,
+	row model.DBObject,
+	field string,
+	filter model.DBM,
+) ([]interface{}, error) {
+	filter = helper.SoftDeleteFilter(row, filter)
+
+	session, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer session.Close()
+
+	if err := setQueryReadPref(session, filter); err != nil {
+		return nil, err
+	}
+
+	col := session.DB("").C(d.tableName(ctx, row))
+
+	values := make([]interface{}, 0)
+
+	if err := col.Find(buildQuery(filter)).Distinct(field, &values); err != nil {
+		return nil, d.handleStoreError(ctx, err)
+	}
+
+	for i, value := range values {
+		// Parsing ids from bson.ObjectId to model.ObjectID
+		if id, ok := value.(bson.ObjectId); ok {
+			values[i] = model.ObjectIDHex(id.Hex())
+		}
+	}
+
+	if err := helper.DecodeValues(row.TableName(), field, values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (d *mgoDriver) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	query = helper.SoftDeleteFilter(row, query)
+
+	colName, err := d.colName(ctx, query, row)
+	if err != nil {
+		return err
+	}
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.FindCommand(colName, buildQuery(query), buildSort(query), query))
+		return nil
+	}
+
+	session, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer session.Close()
+
+	if err := setQueryReadPref(session, query); err != nil {
+		return err
+	}
+
+	col := session.DB("").C(colName)
+
+	q := buildFind(col, query)
+
+	if warnings, ok := query["_lenient_decode"].(*model.DecodeWarnings); ok {
+		if err := lenientQuery(ctx, q, result, warnings); err != nil {
+			return d.handleStoreError(ctx, err)
+		}
+
+		return helper.DecodeFields(row.TableName(), result)
+	}
+
+	if helper.IsSlice(result) {
+		err = newContextIter(ctx, q.Iter()).All(result)
+	} else {
+		err = q.One(result)
+	}
+
+	if err != nil {
+		return d.handleStoreError(ctx, err)
+	}
+
+	helper.NormalizeDocuments(result, modelValue)
+
+	return helper.DecodeFields(row.TableName(), result)
+}
+
+// modelValue returns the ObjectIDs and the UUIDs decoded into a model.DBM, which mgo decodes as
+// bson.ObjectId and bson.Binary, as model.ObjectID and model.UUID.
+func modelValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.ObjectId:
+		return model.ObjectID(v)
+	case bson.Binary:
+		var u model.UUID
+		if v.Kind == 0x04 && len(v.Data) == len(u) {
+			copy(u[:], v.Data)
+
+			return u
+		}
+	}
+
+	return value
+}
+
+func (d *mgoDriver) SearchText(ctx context.Context,
+	row model.DBObject,
+	result interface{},
+	text string,
+	filter model.DBM,
+) error {
+	filter = helper.SoftDeleteFilter(row, filter)
+
+	session, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer session.Close()
+
+	if err := setQueryReadPref(session, filter); err != nil {
+		return err
+	}
+
+	col := session.DB("").C(d.tableName(ctx, row))
+
+	// the score must be projected to sort by it on versions prior to MongoDB 4.4
+	projection := bson.M{helper.TextScoreField: bson.M{"$meta": "textScore"}}
+	if fields, ok := filter["_fields"].([]string); ok {
+		for _, field := range fields {
+			projection[field] = 1
+		}
+	}
+
+	q := buildFind(col, helper.TextSearchQuery(text, filter)).Select(projection)
+
+	if helper.IsSlice(result) {
+		err = newContextIter(ctx, q.Iter()).All(result)
+	} else {
+		err = q.One(result)
+	}
+
+	if err != nil {
+		return d.handleStoreError(ctx, err)
+	}
+
+	return helper.DecodeFields(row.TableName(), result)
+}
+
+func (d *mgoDriver) Watch(ctx context.Context, row model.DBObject, filter model.DBM) (<-chan model.ChangeEvent, error) {
+	return nil, types.ErrChangeStreamsUnsupported
+}
+
+// buildSort returns the sort document of the _sort key of query, as built by mgo.Query.Sort, or nil if it has none.
+func buildSort(query model.DBM) interface{} {
+	sort, sortFound := query["_sort"].(string)
+	if !sortFound || sort == "" {
+		return nil
+	}
+
+	if kind, field, found := strings.Cut(sort, ":"); found && kind != "" && kind[0] == '$' {
+		return bson.D{{Name: field, Value: bson.M{"$meta": kind[1:]}}}
+	}
+
+	order := 1
+
+	switch sort[0] {
+	case '+':
+		sort = sort[1:]
+	case '-':
+		order = -1
+		sort = sort[1:]
+	}
+
+	return bson.D{{Name: sort, Value: order}}
+}
+
+// buildFind returns the *mgo.Query for the given query, applying the meta keys such as _sort or _limit.
+func buildFind(col *mgo.Collection, query model.DBM) *mgo.Query {
+	q := col.Find(buildQuery(query))
+
+	sort, sortFound := query["_sort"].(string)
+	if sortFound {
+		q = q.Sort(sort)
+	}
+
+	if limit, ok := query["_limit"].(int); ok && limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	if offset, ok := query["_offset"].(int); ok && offset > 0 {
+		q = q.Skip(offset)
+	}
+
+	if maxTime, ok := helper.GetMaxTime(query); ok {
+		q = q.SetMaxTime(maxTime)
+	}
+
+	if fields, ok := query["_fields"].([]string); ok && len(fields) > 0 {
+		projection := bson.M{}
+		for _, field := range fields {
+			projection[field] = 1
+		}
+
+		q = q.Select(projection)
+	}
+
+	return q
+}
+
+func (d *mgoDriver) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	query = helper.SoftDeleteFilter(row, query)
+
+	colName, err := d.colName(ctx, query, row)
+	if err != nil {
+		return nil, err
+	}
+
+	// the session copy is closed along with the cursor
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setQueryReadPref(sess, query); err != nil {
+		sess.Close()
+		return nil, err
+	}
+
+	iter := newContextIter(ctx, buildFind(sess.DB("").C(colName), query).Iter())
+
+	return &mgoCursor{sess: sess, iter: iter, table: row.TableName()}, nil
+}
+
+func (d *mgoDriver) ListPage(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+	page model.PageRequest,
+) (model.PageResult, error) {
+	filter = helper.SoftDeleteFilter(row, filter)
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return model.PageResult{}, err
+	}
+
+	defer sess.Close()
+
+	if err := setQueryReadPref(sess, filter); err != nil {
+		return model.PageResult{}, err
+	}
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+	search := buildQuery(filter)
+
+	total, err := col.Find(search).Count()
+	if err != nil {
+		return model.PageResult{}, d.handleStoreError(ctx, err)
+	}
+
+	field, descending := helper.PageSort(page.Sort)
+	limit := helper.PageLimit(page)
+
+	if page.AfterCursor != "" {
+		after, err := decodePageCursor(page.AfterCursor)
+		if err != nil {
+			return model.PageResult{}, err
+		}
+
+		search = bson.M{"$and": []interface{}{search, buildKeysetQuery(field, descending, after)}}
+	}
+
+	sort := []string{field, "_id"}
+	if field == "_id" {
+		sort = sort[:1]
+	}
+
+	if descending {
+		for i := range sort {
+			sort[i] = "-" + sort[i]
+		}
+	}
+
+	// one more row is requested to know if there is a next page
+	q := col.Find(search).Sort(sort...).Limit(limit + 1)
+	if maxTime, ok := helper.GetMaxTime(filter); ok {
+		q = q.SetMaxTime(maxTime)
+	}
+
+	items := make([]model.DBM, 0, limit)
+	if err := newContextIter(ctx, q.Iter()).All(&items); err != nil {
+		return model.PageResult{}, d.handleStoreError(ctx, err)
+	}
+
+	result := model.PageResult{Items: items, Total: total}
+
+	if len(items) > limit {
+		last := items[limit-1]
+
+		result.Items = items[:limit]
+		if result.NextCursor, err = encodePageCursor(pageCursor{Value: last[field], ID: last["_id"]}); err != nil {
+			return model.PageResult{}, err
+		}
+	}
+
+	// Parsing _id from bson.ObjectID to model.ObjectID
+	for _, item := range result.Items {
+		if id, ok := item["_id"].(bson.ObjectId); ok {
+			item["_id"] = model.ObjectIDHex(id.Hex())
+		}
+	}
+
+	// decoded once the next cursor is built, as it's compared with the stored values
+	if err := helper.DecodeFields(row.TableName(), &result.Items); err != nil {
+		return model.PageResult{}, err
+	}
+
+	return result, nil
+}
+
+// lenientQuery runs the query reporting in warnings the fields of the documents that don't fit the result type.
+// mgo already leaves those fields with their zero value instead of failing, but it does so silently.
+func lenientQuery(ctx context.Context, q *mgo.Query, result interface{}, warnings *model.DecodeWarnings) error {
+	isSlice := helper.IsSlice(result)
+	if !isSlice {
+		q = q.Limit(1)
+	}
+
+	iter := newContextIter(ctx, q.Iter())
+
+	resultValue := reflect.ValueOf(result).Elem()
+	if isSlice {
+		resultValue.Set(reflect.MakeSlice(resultValue.Type(), 0, 0))
+	}
+
+	found := false
+
+	var raw bson.Raw
+	for iter.Next(&raw) {
+		found = true
+
+		if !isSlice {
+			lenientDecode(raw, result, warnings)
+			continue
+		}
+
+		elem := reflect.New(resultValue.Type().Elem())
+		lenientDecode(raw, elem.Interface(), warnings)
+		resultValue.Set(reflect.Append(resultValue, elem.Elem()))
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	if !isSlice && !found {
+		return mgo.ErrNotFound
+	}
+
+	return nil
+}
+
+// lenientDecode decodes raw into result and adds to warnings the top level fields of the document
+// whose value isn't compatible with the type of the matching struct field.
+func lenientDecode(raw bson.Raw, result interface{}, warnings *model.DecodeWarnings) {
+	var id model.ObjectID
+
+	var elements bson.RawD
+
+	err := raw.Unmarshal(&elements)
+	if err == nil {
+		err = raw.Unmarshal(result)
+	}
+
+	if err != nil {
+		warnings.Add(id, "", err)
+		return
+	}
+
+	resultType := reflect.TypeOf(result).Elem()
+	if resultType.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, element := range elements {
+		if element.Name == "_id" {
+			var oid bson.ObjectId
+			if element.Value.Unmarshal(&oid) == nil {
+				id = model.ObjectIDHex(oid.Hex())
+			}
+		}
+	}
+
+	for _, element := range elements {
+		fieldType, ok := bsonFieldType(resultType, element.Name)
+		if !ok {
+			continue
+		}
+
+		if err := element.Value.Unmarshal(reflect.New(fieldType).Interface()); err != nil {
+			warnings.Add(id, element.Name, err)
+		}
+	}
+}
+
+// bsonFieldType returns the type of the field of the struct type t stored under the given bson key.
+func bsonFieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, ok := model.FieldName(field); ok && name == key {
+			return field.Type, true
+		}
+	}
+
+	return nil, false
+}
+
+func (d *mgoDriver) Drop(ctx context.Context, row model.DBObject) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	return d.handleStoreError(ctx, sess.DB("").C(d.tableName(ctx, row)).DropCollection())
+}
+
+func (d *mgoDriver) Ping(ctx context.Context) (result error) {
+	if d.session == nil {
+		return types.ErrSessionClosed
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			result = fmt.Errorf("%w from panic", types.ErrSessionClosed)
+		}
+	}()
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	return d.handleStoreError(ctx, sess.Ping())
+}
+
+func (d *mgoDriver) Health(ctx context.Context) (status model.HealthStatus) {
+	status = model.HealthStatus{CheckedAt: time.Now(), PoolSize: d.poolSize}
+
+	if d.session == nil {
+		status.Err = types.ErrSessionClosed
+		return status
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			status.Live = false
+			status.Err = fmt.Errorf("%w from panic", types.ErrSessionClosed)
+		}
+	}()
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+
+	defer sess.Close()
+
+	var result struct {
+		IsMaster bool   `bson:"ismaster"`
+		SetName  string `bson:"setName"`
+		Primary  string `bson:"primary"`
+	}
+
+	err = sess.Run("ismaster", &result)
+	status.Latency = time.Since(status.CheckedAt)
+
+	if err != nil {
+		status.Err = d.handleStoreError(ctx, err)
+		return status
+	}
+
+	status.Live = true
+	status.ReplicaSet = result.SetName
+	status.Primary = result.Primary
+	status.PrimaryAvailable = result.IsMaster || result.Primary != ""
+	status.Ready = status.PrimaryAvailable
+
+	return status
+}
+
+func (d *mgoDriver) HasTable(ctx context.Context, collection string) (result bool, errResult error) {
+	if d.session == nil {
+		return false, types.ErrSessionClosed
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			errResult = fmt.Errorf("%w from panic", types.ErrSessionClosed)
+		}
+	}()
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer sess.Close()
+
+	names, err := sess.DB("").CollectionNames()
+	if err != nil {
+		return false, d.handleStoreError(ctx, err)
+	}
+
+	collection = d.options.ResolveTableName(collection)
+
+	for _, name := range names {
+		if name == collection {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (d *mgoDriver) handleStoreError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	listOfErrors := []string{
+		"EOF",
+		"Closed explicitly",
+		"reset by peer",
+		"no reachable servers",
+		"i/o timeout",
+	}
+
+	// the credentials of the provider may have changed, and are fetched again on reconnect
+	if d.options.CredentialsProvider != nil {
+		listOfErrors = append(listOfErrors, "Authentication failed")
+	}
+
+	for _, substr := range listOfErrors {
+		if strings.Contains(err.Error(), substr) {
+			// the socket timed out at the deadline of the context, which doesn't call for a reconnection
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return fmt.Errorf("%w: %s", ctxErr, err.Error())
+			}
+
+			connErr := d.Connect(&d.options)
+			if connErr != nil {
+				return errors.New("error reconnecting to mongo: " + connErr.Error() + " after error: " + err.Error())
+			}
+
+			return err
+		}
+	}
+
+	return err
+}
+
+func (d *mgoDriver) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	if len(index.Keys) == 0 {
+		return types.ErrIndexEmpty
+	} else if len(index.Keys) > 1 && index.IsTTLIndex {
+		return types.ErrIndexComposedTTL
+	}
+
+	var indexes []string
+
+	for _, key := range index.Keys {
+		for k, v := range key {
+			if index.GeoIndex {
+				v = "2dsphere"
+			}
+
+			switch v.(type) {
+			case int, int32, int64:
+				if v.(int) == -1 {
+					indexes = append(indexes, "-"+k)
+				} else {
+					indexes = append(indexes, k)
+				}
+			default:
+				// special index types such as text or 2dsphere, e.g. "$text:name"
+				indexes = append(indexes, "$"+fmt.Sprint(v)+":"+k)
+			}
+		}
+	}
+
+	newIndex := mgo.Index{
+		Name: index.Name,
+		Key:  indexes,
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	if index.IsTTLIndex {
+		newIndex.ExpireAfter = time.Duration(index.TTL) * time.Second
+
+		if ttlField := d.DBType().Capabilities().TTLField; ttlField != "" {
+			newIndex.Key = []string{ttlField}
+		}
+	}
+
+	return d.handleStoreError(ctx, col.EnsureIndex(newIndex))
+}
+
+func (d *mgoDriver) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	hasTable, err := d.HasTable(ctx, helper.TableName(ctx, row))
+	if err != nil {
+		return nil, d.handleStoreError(ctx, err)
+	}
+
+	if !hasTable {
+		return nil, types.ErrCollectionNotFound
+	}
+
+	var indexes []model.Index
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	indexesSpec, err := col.Indexes()
+	if err != nil {
+		return indexes, d.handleStoreError(ctx, err)
+	}
+
+	for i := range indexesSpec {
+		var newKeys []model.DBM
+
+		for _, strKey := range indexesSpec[i].Key {
 			newKey := model.DBM{}
 
 			switch {
 			case strings.HasPrefix(strKey, "-"):
 				newKey[strKey[1:]] = int32(-1)
+			case strings.HasPrefix(strKey, "$") && strings.Contains(strKey, ":"):
+				kind, field, _ := strings.Cut(strKey[1:], ":")
+				newKey[field] = kind
 			case strKey != "_id" && strings.Contains(strKey, "_"):
 				values := strings.Split(strKey, "_")
 				newKey[values[0]] = values[1]
@@ -374,161 +1233,761 @@ func (d *mgoDriver) GetIndexes(ctx context.Context, row model.DBObject) ([]model
 			newKeys = append(newKeys, newKey)
 		}
 
-		newIndex := model.Index{
-			Name: indexesSpec[i].Name,
-			Keys: newKeys,
+		newIndex := model.Index{
+			Name: indexesSpec[i].Name,
+			Keys: newKeys,
+		}
+
+		if indexesSpec[i].ExpireAfter > 0 {
+			newIndex.IsTTLIndex = true
+			newIndex.TTL = int(indexesSpec[i].ExpireAfter.Seconds())
+		}
+
+		indexes = append(indexes, newIndex)
+	}
+
+	return indexes, nil
+}
+
+func (d *mgoDriver) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	if len(opts) > 0 && len(opts) != len(rows) {
+		return types.ErrRowOptDiffLength
+	}
+
+	for i, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		col := sess.DB("").C(d.tableName(ctx, row))
+
+		if len(opts) > 0 {
+			opt := buildOpt(opts[i])
+
+			err := col.Create(opt)
+			if err != nil {
+				return d.handleStoreError(ctx, err)
+			}
+
+			continue
+		}
+
+		err := col.Create(&mgo.CollectionInfo{})
+		if err != nil {
+			return d.handleStoreError(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *mgoDriver) DropDatabase(ctx context.Context) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	return d.handleStoreError(ctx, sess.DB("").DropDatabase())
+}
+
+func (d *mgoDriver) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
+	var stats model.DBM
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer sess.Close()
+
+	err = sess.DB("").Run(model.DBM{"collStats": d.tableName(ctx, row)}, &stats)
+
+	return stats, d.handleStoreError(ctx, err)
+}
+
+func (d *mgoDriver) Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error) {
+	pipeline, pipelineOpts := helper.SplitPipelineOptions(query)
+	if err := d.checkPipeline(pipeline); err != nil {
+		return nil, err
+	}
+
+	pipeline = helper.SoftDeletePipeline(row, pipeline, pipelineOpts)
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		command := helper.AggregateCommand(d.tableName(ctx, row), pipeline, pipelineOpts)
+		command["allowDiskUse"] = true
+		dryRun.Record(command)
+
+		return []model.DBM{}, nil
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	if err := setQueryReadPref(sess, pipelineOpts); err != nil {
+		return nil, err
+	}
+
+	var iter *mgo.Iter
+
+	if maxTime, ok := helper.GetMaxTime(pipelineOpts); ok {
+		iter = aggregateWithMaxTime(sess, col, pipeline, maxTime)
+	} else {
+		pipe := col.Pipe(pipeline)
+		pipe.AllowDiskUse()
+		iter = pipe.Iter()
+	}
+
+	results := newContextIter(ctx, iter)
+
+	resultSlice := make([]model.DBM, 0)
+
+	for {
+		var result model.DBM
+		if !results.Next(&result) {
+			break
+		}
+		// Parsing _id from bson.ObjectID to model.ObjectID
+		resultId, ok := result["_id"].(bson.ObjectId)
+		if ok {
+			result["_id"] = model.ObjectIDHex(resultId.Hex())
+		}
+
+		resultSlice = append(resultSlice, result)
+	}
+
+	if err := results.Close(); err != nil {
+		return nil, d.handleStoreError(ctx, err)
+	}
+
+	return resultSlice, nil
+}
+
+// aggregateWithMaxTime runs the aggregate command directly, given that mgo.Pipe doesn't support maxTimeMS.
+func aggregateWithMaxTime(sess *mgo.Session,
+	col *mgo.Collection,
+	pipeline []model.DBM,
+	maxTime time.Duration,
+) *mgo.Iter {
+	var result struct {
+		Cursor struct {
+			FirstBatch []bson.Raw `bson:"firstBatch"`
+			ID         int64      `bson:"id"`
+		} `bson:"cursor"`
+	}
+
+	cmd := bson.D{
+		{Name: "aggregate", Value: col.Name},
+		{Name: "pipeline", Value: pipeline},
+		{Name: "allowDiskUse", Value: true},
+		{Name: "cursor", Value: bson.M{}},
+		{Name: "maxTimeMS", Value: maxTime.Milliseconds()},
+	}
+
+	err := col.Database.Run(cmd, &result)
+
+	return col.NewIter(sess, result.Cursor.FirstBatch, result.Cursor.ID, err)
+}
+
+func (d *mgoDriver) Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error) {
+	return d.explain(ctx, bson.D{
+		{Name: "find", Value: d.tableName(ctx, row)},
+		{Name: "filter", Value: buildQuery(filter)},
+	})
+}
+
+func (d *mgoDriver) ExplainAggregate(ctx context.Context, row model.DBObject, query []model.DBM) (model.DBM, error) {
+	pipeline, _ := helper.SplitPipelineOptions(query)
+	if err := d.checkPipeline(pipeline); err != nil {
+		return nil, err
+	}
+
+	return d.explain(ctx, bson.D{
+		{Name: "aggregate", Value: d.tableName(ctx, row)},
+		{Name: "pipeline", Value: pipeline},
+		{Name: "cursor", Value: bson.M{}},
+	})
+}
+
+// checkPipeline returns an error if pipeline has a stage the database doesn't support.
+func (d *mgoDriver) checkPipeline(pipeline []model.DBM) error {
+	if stage, found := helper.UnsupportedStage(pipeline, d.DBType().Capabilities().UnsupportedStages); found {
+		return fmt.Errorf("%w: %s", types.ErrStageUnsupported, stage)
+	}
+
+	return nil
+}
+
+// explain runs the explain command over cmd with the executionStats verbosity, so the command is executed
+// and its statistics are reported along with the plan.
+func (d *mgoDriver) explain(ctx context.Context, cmd bson.D) (model.DBM, error) {
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer sess.Close()
+
+	var result model.DBM
+
+	err = sess.DB("").Run(bson.D{
+		{Name: "explain", Value: cmd},
+		{Name: "verbosity", Value: "executionStats"},
+	}, &result)
+	if err != nil {
+		return nil, d.handleStoreError(ctx, err)
+	}
+
+	return helper.NormalizeExplain(result), nil
+}
+
+func (d *mgoDriver) CleanIndexes(ctx context.Context, row model.DBObject) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	indexes, err := col.Indexes()
+	if err != nil {
+		return d.handleStoreError(ctx, err)
+	}
+
+	for i := 0; i < len(indexes); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		index := &indexes[i]      // using pointers to avoid copying and improve performance
+		if index.Name != "_id_" { // cannot drop _id index
+			err = col.DropIndexName(index.Name)
+			if err != nil {
+				return d.handleStoreError(ctx, err)
+			}
 		}
+	}
 
-		if indexesSpec[i].ExpireAfter > 0 {
-			newIndex.IsTTLIndex = true
-			newIndex.TTL = int(indexesSpec[i].ExpireAfter.Seconds())
-		}
+	return nil
+}
 
-		indexes = append(indexes, newIndex)
+func (d *mgoDriver) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	update, err := helper.EncodeUpdate(row, helper.RevivingUpdate(row, helper.TimestampedUpdate(row, update)))
+	if err != nil {
+		return err
 	}
 
-	return indexes, nil
-}
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.UpsertCommand(d.tableName(ctx, row), buildQuery(query), update))
+		return nil
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
 
-func (d *mgoDriver) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
-	sess := d.session.Copy()
 	defer sess.Close()
 
-	if len(opts) > 0 && len(opts) != len(rows) {
-		return errors.New(types.ErrorRowOptDiffLenght)
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	_, err = col.Find(buildQuery(query)).Apply(mgo.Change{
+		Update:    update,
+		Upsert:    true,
+		ReturnNew: true,
+	}, row)
+	if err != nil {
+		return d.handleStoreError(ctx, err)
 	}
 
-	for i, row := range rows {
-		col := sess.DB("").C(row.TableName())
+	return helper.DecodeFields(row.TableName(), row)
+}
 
-		if len(opts) > 0 {
-			opt := buildOpt(opts[i])
+func (d *mgoDriver) FindOneAndUpdate(ctx context.Context,
+	row model.DBObject,
+	query, update model.DBM,
+	opts ...model.FindOneOpts,
+) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
 
-			err := col.Create(opt)
-			if err != nil {
-				return d.handleStoreError(err)
-			}
+	update, err := helper.EncodeUpdate(row, helper.TimestampedUpdate(row, update))
+	if err != nil {
+		return err
+	}
 
-			continue
-		}
+	if len(opts) > 1 {
+		return types.ErrMultipleFindOneOpts
+	}
 
-		err := col.Create(&mgo.CollectionInfo{})
-		if err != nil {
-			return d.handleStoreError(err)
+	if len(opts) == 1 && opts[0].Upsert {
+		// same as Upsert, a deleted row matching query is restored
+		update = helper.RevivingUpdate(row, update)
+	} else {
+		query = helper.SoftDeleteFilter(row, query)
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	q := col.Find(buildQuery(query))
+	change := mgo.Change{Update: buildQuery(update)}
+
+	if len(opts) == 1 {
+		if len(opts[0].Sort) > 0 {
+			q = q.Sort(opts[0].Sort...)
 		}
+
+		change.ReturnNew = opts[0].ReturnNew
+		change.Upsert = opts[0].Upsert
 	}
 
-	return nil
+	_, err = q.Apply(change, row)
+	if err != nil {
+		return d.handleStoreError(ctx, err)
+	}
+
+	return helper.DecodeFields(row.TableName(), row)
 }
 
-func (d *mgoDriver) DropDatabase(ctx context.Context) error {
-	sess := d.session.Copy()
+func (d *mgoDriver) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
+	result := utils.Info{}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return result, err
+	}
+
 	defer sess.Close()
 
-	return d.handleStoreError(sess.DB("").DropDatabase())
+	err = sess.DB("admin").Run(bson.D{{Name: "buildInfo", Value: 1}}, &result)
+	result.Type = d.lifeCycle.DBType()
+	result.Capabilities = result.Type.Capabilities()
+
+	return result, d.handleStoreError(ctx, err)
 }
 
-func (d *mgoDriver) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
-	var stats model.DBM
+func (d *mgoDriver) GetTables(ctx context.Context) ([]string, error) {
+	return d.db.CollectionNames()
+}
 
-	sess := d.session.Copy()
-	defer sess.Close()
+func (d *mgoDriver) DropTable(ctx context.Context, collectionName string) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
+	}
+
+	collectionName = d.options.ResolveTableName(collectionName)
 
-	err := sess.DB("").Run(model.DBM{"collStats": row.TableName()}, &stats)
+	info, err := d.db.C(collectionName).RemoveAll(bson.M{})
+	if err != nil {
+		return 0, err
+	}
 
-	return stats, d.handleStoreError(err)
+	return info.Removed, d.db.C(collectionName).DropCollection()
 }
 
-func (d *mgoDriver) Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error) {
-	sess := d.session.Copy()
+func (d *mgoDriver) ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error) {
+	return d.Export(ctx, row, query, w, model.NDJSON)
+}
+
+func (d *mgoDriver) Export(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+	w io.Writer,
+	format model.ExportFormat,
+) (int, error) {
+	fields, _ := query["_fields"].([]string)
+
+	encoder, err := helper.NewExportEncoder(w, format, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	defer sess.Close()
 
-	col := sess.DB("").C(row.TableName())
-	pipe := col.Pipe(query)
-	pipe.AllowDiskUse()
-	iter := pipe.Iter()
+	col := sess.DB("").C(d.tableName(ctx, row))
+	iter := newContextIter(ctx, buildFind(col, query).Iter())
 
-	resultSlice := make([]model.DBM, 0)
+	exported := 0
 
 	for {
-		var result model.DBM
-		if !iter.Next(&result) {
+		var document model.DBM
+		if !iter.Next(&document) {
 			break
 		}
+
 		// Parsing _id from bson.ObjectID to model.ObjectID
-		resultId, ok := result["_id"].(bson.ObjectId)
-		if ok {
-			result["_id"] = model.ObjectIDHex(resultId.Hex())
+		if documentID, ok := document["_id"].(bson.ObjectId); ok {
+			document["_id"] = model.ObjectIDHex(documentID.Hex())
 		}
 
-		resultSlice = append(resultSlice, result)
+		if err := helper.DecodeFields(row.TableName(), &document); err != nil {
+			helper.ErrPrint(iter.Close())
+			return exported, err
+		}
+
+		if err := encoder.Encode(document); err != nil {
+			helper.ErrPrint(iter.Close())
+			return exported, err
+		}
+
+		exported++
 	}
 
-	if iter.Err() != nil {
-		return nil, d.handleStoreError(iter.Err())
+	if err := iter.Close(); err != nil {
+		return exported, d.handleStoreError(ctx, err)
 	}
 
-	return resultSlice, nil
+	return exported, encoder.Flush()
 }
 
-func (d *mgoDriver) CleanIndexes(ctx context.Context, row model.DBObject) error {
-	sess := d.session.Copy()
-	defer sess.Close()
+func (d *mgoDriver) ImportNDJSON(ctx context.Context, row model.DBObject, r io.Reader, opts ...model.DBM) (int, error) {
+	return d.Import(ctx, row, r, model.NDJSON, opts...)
+}
 
-	col := sess.DB("").C(row.TableName())
+func (d *mgoDriver) Import(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	format model.ExportFormat,
+	opts ...model.DBM,
+) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
+	}
 
-	indexes, err := col.Indexes()
+	importRows, err := helper.NewImporter(format)
 	if err != nil {
-		return d.handleStoreError(err)
+		return 0, err
 	}
 
-	for i := 0; i < len(indexes); i++ {
-		index := &indexes[i]      // using pointers to avoid copying and improve performance
-		if index.Name != "_id_" { // cannot drop _id index
-			err = col.DropIndexName(index.Name)
+	if len(opts) > 1 {
+		return 0, types.ErrMultipleDBM
+	}
+
+	importOpts := model.DBM{}
+	if len(opts) == 1 {
+		importOpts = opts[0]
+	}
+
+	upsert, batchSize := helper.ImportOptions(importOpts)
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	return importRows(r, batchSize, func(documents []model.DBM) (int, map[int]error) {
+		if err := ctx.Err(); err != nil {
+			return 0, failedBatch(len(documents), err)
+		}
+
+		failed := map[int]error{}
+		bulk := col.Bulk()
+		bulk.Unordered()
+
+		// position of each bulk operation in documents
+		var positions []int
+
+		for i, document := range documents {
+			newRow, err := decodeDocument(row, document)
 			if err != nil {
-				return d.handleStoreError(err)
+				failed[i] = err
+				continue
+			}
+
+			// the rows are only stored, so their encoded fields aren't restored
+			if _, err := helper.EncodeFields(newRow); err != nil {
+				failed[i] = err
+				continue
+			}
+
+			if newRow.GetObjectID() == "" {
+				newRow.SetObjectID(model.NewObjectID())
+			}
+
+			if upsert {
+				bulk.Upsert(bson.M{"_id": newRow.GetObjectID()}, newRow)
+			} else {
+				bulk.Insert(newRow)
+			}
+
+			positions = append(positions, i)
+		}
+
+		if len(positions) == 0 {
+			return 0, failed
+		}
+
+		imported := len(positions)
+
+		_, err := bulk.Run()
+
+		var bulkErr *mgo.BulkError
+
+		switch {
+		case errors.As(err, &bulkErr):
+			for _, errCase := range bulkErr.Cases() {
+				if errCase.Index < 0 || errCase.Index >= len(positions) {
+					continue
+				}
+
+				failed[positions[errCase.Index]] = errCase.Err
+				imported--
 			}
+		case err != nil:
+			err = d.handleStoreError(ctx, err)
+			for _, position := range positions {
+				failed[position] = err
+			}
+
+			imported = 0
 		}
+
+		return imported, failed
+	})
+}
+
+// failedBatch returns the errors of a batch of n rows which all failed with err.
+func failedBatch(n int, err error) map[int]error {
+	failed := make(map[int]error, n)
+	for i := 0; i < n; i++ {
+		failed[i] = err
 	}
 
-	return nil
+	return failed
 }
 
-func (d *mgoDriver) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
-	sess := d.session.Copy()
+// decodeDocument converts document into a new model.DBObject of the same type as row, following its bson tags.
+func decodeDocument(row model.DBObject, document model.DBM) (model.DBObject, error) {
+	newRow, err := helper.NewDBObject(row)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bson.Unmarshal(data, newRow); err != nil {
+		return nil, err
+	}
+
+	return newRow, nil
+}
+
+// documents returns the rows as the documents sent to the database, for the dry-run mode.
+func documents(rows ...model.DBObject) ([]model.DBM, error) {
+	docs := make([]model.DBM, 0, len(rows))
+
+	for _, row := range rows {
+		data, err := bson.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc model.DBM
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+func (d *mgoDriver) SessionSettings(ctx context.Context) (model.DBM, error) {
+	if d.session == nil {
+		return nil, types.ErrSessionClosed
+	}
+
+	sess, err := d.copySession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	defer sess.Close()
 
-	col := sess.DB("").C(row.TableName())
+	settings := model.DBM{
+		"database":       d.db.Name,
+		"readPreference": readPreferenceFromMode(sess.Mode()),
+		// mgo doesn't support read concerns
+		"readConcern": "",
+	}
 
-	_, err := col.Find(query).Apply(mgo.Change{
-		Update:    update,
-		Upsert:    true,
-		ReturnNew: true,
-	}, row)
+	// a nil safe mode means unacknowledged writes
+	writeConcern := model.DBM{"w": 0}
+
+	if safe := sess.Safe(); safe != nil {
+		// acknowledged writes default to w: 1
+		writeConcern = model.DBM{"w": 1}
+
+		if safe.W > 0 {
+			writeConcern["w"] = safe.W
+		}
+
+		if safe.WMode != "" {
+			writeConcern["w"] = safe.WMode
+		}
+
+		if safe.J {
+			writeConcern["j"] = true
+		}
+
+		if safe.WTimeout > 0 {
+			writeConcern["wtimeout"] = time.Duration(safe.WTimeout) * time.Millisecond
+		}
+	}
 
-	return d.handleStoreError(err)
+	settings["writeConcern"] = writeConcern
+
+	return settings, nil
 }
 
-func (d *mgoDriver) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
-	result := utils.Info{}
+// readPreferenceFromMode returns the read preference name equivalent to the mgo session mode,
+// following the same session consistency mapping as the official mongo driver.
+// modeFromReadPreference returns the mgo.Mode of the given read preference name (e.g. "secondaryPreferred").
+func modeFromReadPreference(readPref string) (mgo.Mode, error) {
+	switch strings.ToLower(readPref) {
+	case "primary":
+		return mgo.Primary, nil
+	case "primarypreferred":
+		return mgo.PrimaryPreferred, nil
+	case "secondary":
+		return mgo.Secondary, nil
+	case "secondarypreferred":
+		return mgo.SecondaryPreferred, nil
+	case "nearest":
+		return mgo.Nearest, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", types.ErrUnknownReadPreference, readPref)
+	}
+}
 
-	db := d.session.DB("admin")
-	err := db.Run(bson.D{{Name: "buildInfo", Value: 1}}, &result)
-	result.Type = d.lifeCycle.DBType()
+// setQueryReadPref sets the mode of sess given the "_read_pref" key of query, if set.
+func setQueryReadPref(sess *mgo.Session, query model.DBM) error {
+	readPref, ok := query["_read_pref"].(string)
+	if !ok {
+		return nil
+	}
 
-	return result, d.handleStoreError(err)
+	mode, err := modeFromReadPreference(readPref)
+	if err != nil {
+		return err
+	}
+
+	sess.SetMode(mode, true)
+
+	return nil
 }
 
-func (d *mgoDriver) GetTables(ctx context.Context) ([]string, error) {
-	return d.db.CollectionNames()
+func readPreferenceFromMode(mode mgo.Mode) string {
+	switch mode {
+	case mgo.Eventual, mgo.Nearest:
+		return "nearest"
+	case mgo.Monotonic, mgo.PrimaryPreferred:
+		return "primaryPreferred"
+	case mgo.Secondary:
+		return "secondary"
+	case mgo.SecondaryPreferred:
+		return "secondaryPreferred"
+	default:
+		return "primary"
+	}
 }
 
-func (d *mgoDriver) DropTable(ctx context.Context, collectionName string) (int, error) {
-	info, err := d.db.C(collectionName).RemoveAll(bson.M{})
+func (d *mgoDriver) ExistingIDs(ctx context.Context,
+	row model.DBObject,
+	ids []model.ObjectID,
+) ([]model.ObjectID, error) {
+	existing := make([]model.ObjectID, 0)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	sess, err := d.copySession(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return info.Removed, d.db.C(collectionName).DropCollection()
+	defer sess.Close()
+
+	col := sess.DB("").C(d.tableName(ctx, row))
+
+	// the soft deleted rows aren't skipped, as their ids are still taken
+	iter := newContextIter(ctx, col.Find(bson.M{"_id": bson.M{"$in": ids}}).Select(bson.M{"_id": 1}).Iter())
+
+	found := make(map[model.ObjectID]bool)
+
+	var result struct {
+		ID bson.ObjectId `bson:"_id"`
+	}
+
+	for iter.Next(&result) {
+		found[model.ObjectIDHex(result.ID.Hex())] = true
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, d.handleStoreError(ctx, err)
+	}
+
+	for _, id := range ids {
+		if found[id] {
+			existing = append(existing, id)
+			// only report duplicated ids once
+			delete(found, id)
+		}
+	}
+
+	return existing, nil
+}
+
+func (d *mgoDriver) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	return types.ErrTransactionsUnsupported
 }
@@ -4,12 +4,15 @@
 package mgo
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/TykTechnologies/storage/persistent/model"
 	"github.com/TykTechnologies/storage/persistent/utils"
@@ -1142,14 +1145,14 @@ func TestHandleStoreError(t *testing.T) {
 					ConnectionString:  "mongodb://host:port/invalid",
 					ConnectionTimeout: 1,
 				}
-				err := invalidMgo.handleStoreError(test.inputErr)
+				err := invalidMgo.handleStoreError(context.Background(), test.inputErr)
 				if err == nil {
 					t.Errorf("expected error to be returned when driver is nil")
 				}
 				return
 			}
 
-			gotErr := driver.handleStoreError(test.inputErr)
+			gotErr := driver.handleStoreError(context.Background(), test.inputErr)
 
 			if test.wantReconnect {
 				if sess == driver.session {
@@ -1182,7 +1185,7 @@ func TestIndexes(t *testing.T) {
 			testName:          "no index case",
 			givenIndex:        model.Index{},
 			expectedCreateErr: errors.New(types.ErrorIndexEmpty),
-			expectedGetError:  errors.New(types.ErrorCollectionNotFound),
+			expectedGetError:  types.ErrCollectionNotFound,
 		},
 		{
 			testName: "simple index case",
@@ -1265,7 +1268,7 @@ func TestIndexes(t *testing.T) {
 				TTL:        1,
 			},
 			expectedCreateErr: errors.New(types.ErrorIndexComposedTTL),
-			expectedGetError:  errors.New(types.ErrorCollectionNotFound),
+			expectedGetError:  types.ErrCollectionNotFound,
 		},
 		{
 			// cover https://www.mongodb.com/docs/drivers/go/v1.8/fundamentals/indexes/#geospatial-indexes
@@ -1325,7 +1328,34 @@ func TestPing(t *testing.T) {
 		driver.session.Close()
 		err := driver.Ping(context.Background())
 		assert.NotNil(t, err)
-		assert.Equal(t, errors.New(types.ErrorSessionClosed+" from panic"), err)
+		assert.EqualError(t, err, types.ErrorSessionClosed+" from panic")
+		assert.ErrorIs(t, err, types.ErrSessionClosed)
+	})
+}
+
+func TestHealth(t *testing.T) {
+	defer cleanDB(t)
+
+	t.Run("health ok", func(t *testing.T) {
+		driver, _ := prepareEnvironment(t)
+
+		status := driver.Health(context.Background())
+		assert.Nil(t, status.Err)
+		assert.True(t, status.Live)
+		assert.True(t, status.Ready)
+		assert.True(t, status.PrimaryAvailable)
+		assert.Greater(t, status.Latency, time.Duration(0))
+		assert.Greater(t, status.PoolSize, 0)
+		assert.False(t, status.CheckedAt.IsZero())
+	})
+	t.Run("health sess closed", func(t *testing.T) {
+		driver, _ := prepareEnvironment(t)
+		driver.Close()
+
+		status := driver.Health(context.Background())
+		assert.False(t, status.Live)
+		assert.False(t, status.Ready)
+		assert.Equal(t, errors.New(types.ErrorSessionClosed), status.Err)
 	})
 }
 
@@ -1347,7 +1377,8 @@ func TestHasTable(t *testing.T) {
 		driver.session.Close() // mock a closed session
 		result, err := driver.HasTable(context.Background(), "dummy")
 		assert.NotNil(t, err)
-		assert.Equal(t, errors.New(types.ErrorSessionClosed+" from panic"), err)
+		assert.EqualError(t, err, types.ErrorSessionClosed+" from panic")
+		assert.ErrorIs(t, err, types.ErrSessionClosed)
 		assert.False(t, result)
 	})
 
@@ -1985,3 +2016,1996 @@ func TestMongoDriver_GetTables(t *testing.T) {
 		assert.Equal(t, 0, len(collections))
 	})
 }
+
+func TestMaxTime(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	objects := []model.DBObject{}
+	for i := 0; i < 100; i++ {
+		objects = append(objects, &dummyDBObject{Name: "test" + strconv.Itoa(i), Age: i})
+	}
+
+	err := driver.Insert(ctx, objects...)
+	assert.Nil(t, err)
+
+	t.Run("query exceeding max time", func(t *testing.T) {
+		var result []dummyDBObject
+		err := driver.Query(ctx, object, &result, model.DBM{
+			"$where":    "sleep(100) || true",
+			"_max_time": time.Millisecond,
+		})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "exceeded time limit")
+	})
+
+	t.Run("aggregation exceeding max time", func(t *testing.T) {
+		// cartesian product of the collection with itself twice, 1M documents
+		_, err := driver.Aggregate(ctx, object, []model.DBM{
+			{"_max_time": time.Millisecond},
+			{"$lookup": model.DBM{"from": object.TableName(), "pipeline": []model.DBM{}, "as": "first"}},
+			{"$unwind": "$first"},
+			{"$lookup": model.DBM{"from": object.TableName(), "pipeline": []model.DBM{}, "as": "second"}},
+			{"$unwind": "$second"},
+			{"$count": "total"},
+		})
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "exceeded time limit")
+	})
+
+	t.Run("aggregation within max time", func(t *testing.T) {
+		result, err := driver.Aggregate(ctx, object, []model.DBM{
+			{"_max_time": 10 * time.Second},
+			{"$count": "total"},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []model.DBM{{"total": 100}}, result)
+	})
+}
+
+func TestExportNDJSON(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	objects := []model.DBObject{}
+	for i := 0; i < 5; i++ {
+		objects = append(objects, &dummyDBObject{
+			Name:    "test" + strconv.Itoa(i),
+			Email:   "test" + strconv.Itoa(i) + "@test.com",
+			Age:     i,
+			Country: dummyCountryField{CountryName: "test_country", Continent: "test_continent"},
+		})
+	}
+
+	err := driver.Insert(ctx, objects...)
+	assert.Nil(t, err)
+
+	t.Run("export all rows", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exported, err := driver.ExportNDJSON(ctx, object, model.DBM{"_sort": "age"}, &buf)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, exported)
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		assert.Len(t, lines, 5)
+
+		for i, line := range lines {
+			expected := objects[i].(*dummyDBObject)
+
+			var document map[string]interface{}
+			assert.Nil(t, json.Unmarshal([]byte(line), &document))
+
+			assert.Equal(t, expected.GetObjectID().Hex(), document["_id"])
+			assert.Equal(t, expected.Name, document["name"])
+			assert.Equal(t, expected.Email, document["email"])
+			assert.Equal(t, float64(expected.Age), document["age"])
+			assert.Equal(t, map[string]interface{}{
+				"country_name": expected.Country.CountryName,
+				"continent":    expected.Country.Continent,
+			}, document["country"])
+		}
+	})
+
+	t.Run("export filtered rows", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exported, err := driver.ExportNDJSON(ctx, object, model.DBM{"age": model.DBM{"$gte": 3}}, &buf)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, exported)
+		assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	})
+
+	t.Run("export without matches", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exported, err := driver.ExportNDJSON(ctx, object, model.DBM{"name": "unknown"}, &buf)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, exported)
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestExportImportCSV(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	rows := []*dummyDBObject{
+		{Name: "first", Email: "first@test.com", Age: 20, Country: dummyCountryField{CountryName: "Spain"}},
+		{Name: "second, with comma", Email: "second@test.com", Age: 30},
+	}
+
+	for _, row := range rows {
+		assert.Nil(t, driver.Insert(ctx, row))
+	}
+
+	var buf bytes.Buffer
+
+	exported, err := driver.Export(ctx, object, model.DBM{"_sort": "age"}, &buf, model.CSV)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, exported)
+	assert.True(t, strings.HasPrefix(buf.String(), "_id,age,country,email,name\n"))
+
+	_, err = driver.DropTable(ctx, object.TableName())
+	assert.Nil(t, err)
+
+	imported, err := driver.Import(ctx, object, &buf, model.CSV)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, imported)
+
+	var result []dummyDBObject
+	err = driver.Query(ctx, object, &result, model.DBM{"_sort": "age"})
+	assert.Nil(t, err)
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, *rows[0], result[0])
+	assert.Equal(t, *rows[1], result[1])
+
+	_, err = driver.Export(ctx, object, model.DBM{}, &buf, "xml")
+	assert.NotNil(t, err)
+}
+
+func TestImportNDJSON(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	existing := &dummyDBObject{Name: "existing", Email: "existing@test.com", Age: 1}
+	err := driver.Insert(ctx, existing)
+	assert.Nil(t, err)
+
+	t.Run("import with a malformed line", func(t *testing.T) {
+		defer func() {
+			_, err := driver.DropTable(ctx, object.TableName())
+			assert.Nil(t, err)
+			assert.Nil(t, driver.Insert(ctx, existing))
+		}()
+
+		input := `{"name":"first","email":"first@test.com","age":20,"country":{"country_name":"Spain","continent":"Europe"}}
+{"name":"second","email":
+{"name":"third","email":"third@test.com","age":30}
+{"_id":"` + existing.GetObjectID().Hex() + `","name":"duplicated"}
+`
+
+		imported, err := driver.ImportNDJSON(ctx, object, strings.NewReader(input))
+		assert.Equal(t, 2, imported)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "line 2: ")
+		assert.Contains(t, err.Error(), "line 4: ")
+		assert.NotContains(t, err.Error(), "line 1: ")
+		assert.NotContains(t, err.Error(), "line 3: ")
+
+		var result []dummyDBObject
+		err = driver.Query(ctx, object, &result, model.DBM{"_sort": "age"})
+		assert.Nil(t, err)
+		assert.Len(t, result, 3)
+
+		assert.Equal(t, "existing", result[0].Name)
+		assert.Equal(t, "first", result[1].Name)
+		assert.Equal(t, 20, result[1].Age)
+		assert.Equal(t, dummyCountryField{CountryName: "Spain", Continent: "Europe"}, result[1].Country)
+		assert.Equal(t, "third", result[2].Name)
+		assert.Equal(t, "third@test.com", result[2].Email)
+	})
+
+	t.Run("import in upsert mode", func(t *testing.T) {
+		input := `{"_id":"` + existing.GetObjectID().Hex() + `","name":"updated","age":1}
+{"name":"new","age":2}
+`
+
+		imported, err := driver.ImportNDJSON(ctx, object, strings.NewReader(input), model.DBM{
+			"upsert":    true,
+			"batchSize": 1,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, imported)
+
+		var result []dummyDBObject
+		err = driver.Query(ctx, object, &result, model.DBM{"_sort": "age"})
+		assert.Nil(t, err)
+		assert.Len(t, result, 2)
+
+		assert.Equal(t, existing.GetObjectID(), result[0].GetObjectID())
+		assert.Equal(t, "updated", result[0].Name)
+		assert.Equal(t, "new", result[1].Name)
+	})
+
+	t.Run("export and import round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		exported, err := driver.ExportNDJSON(ctx, object, model.DBM{}, &buf)
+		assert.Nil(t, err)
+
+		_, err = driver.DropTable(ctx, object.TableName())
+		assert.Nil(t, err)
+
+		imported, err := driver.ImportNDJSON(ctx, object, &buf)
+		assert.Nil(t, err)
+		assert.Equal(t, exported, imported)
+
+		count, err := driver.Count(ctx, object)
+		assert.Nil(t, err)
+		assert.Equal(t, exported, count)
+	})
+}
+
+func TestAggregateRegexExpressions(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx,
+		&dummyDBObject{Name: "first", Email: "first@tyk.io", Age: 1},
+		&dummyDBObject{Name: "second", Email: "second@test.com", Age: 2},
+	)
+	assert.Nil(t, err)
+
+	result, err := driver.Aggregate(ctx, object, []model.DBM{
+		{"$sort": model.DBM{"age": 1}},
+		{
+			"$project": model.DBM{
+				"_id":   0,
+				"isTyk": model.DBM{"$regexMatch": model.DBM{"input": "$email", "regex": "@tyk\\.io$"}},
+				"domain": model.DBM{
+					"$let": model.DBM{
+						"vars": model.DBM{"found": model.DBM{"$regexFind": model.DBM{"input": "$email", "regex": "[^@]+$"}}},
+						"in":   "$$found.match",
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []model.DBM{
+		{"isTyk": true, "domain": "tyk.io"},
+		{"isTyk": false, "domain": "test.com"},
+	}, result)
+}
+
+func TestQueryFieldLevelNot(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx,
+		&dummyDBObject{Name: "tyk", Age: 20},
+		&dummyDBObject{Name: "Tyk_gateway", Age: 30},
+		&dummyDBObject{Name: "dashboard", Age: 40},
+	)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		testName      string
+		query         model.DBM
+		expectedNames []string
+	}{
+		{
+			testName:      "negating $gt",
+			query:         model.DBM{"age": model.DBM{"$not": model.DBM{"$gt": 25}}},
+			expectedNames: []string{"tyk"},
+		},
+		{
+			testName:      "negating $regex",
+			query:         model.DBM{"name": model.DBM{"$not": model.DBM{"$regex": "^tyk"}}},
+			expectedNames: []string{"Tyk_gateway", "dashboard"},
+		},
+		{
+			testName:      "negating $i",
+			query:         model.DBM{"name": model.DBM{"$not": model.DBM{"$i": "TYK"}}},
+			expectedNames: []string{"Tyk_gateway", "dashboard"},
+		},
+		{
+			testName:      "negating $gt combined with another operator",
+			query:         model.DBM{"age": model.DBM{"$not": model.DBM{"$gt": 35}, "$ne": 20}},
+			expectedNames: []string{"Tyk_gateway"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			tc.query["_sort"] = "age"
+
+			var result []dummyDBObject
+			err := driver.Query(ctx, object, &result, tc.query)
+			assert.Nil(t, err)
+
+			names := []string{}
+			for _, row := range result {
+				names = append(names, row.Name)
+			}
+
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestSessionSettings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("default settings", func(t *testing.T) {
+		driver, _ := prepareEnvironment(t)
+
+		settings, err := driver.SessionSettings(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DBM{
+			"database":       "test",
+			"readPreference": "primary",
+			"readConcern":    "",
+			"writeConcern":   model.DBM{"w": 1},
+		}, settings)
+	})
+
+	t.Run("settings applied at connect time", func(t *testing.T) {
+		driver, err := NewMgoDriver(&types.ClientOpts{
+			ConnectionString:   "mongodb://localhost:27017/test",
+			SessionConsistency: "eventual",
+		})
+		assert.Nil(t, err)
+
+		defer driver.Close()
+
+		settings, err := driver.SessionSettings(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "nearest", settings["readPreference"])
+	})
+
+	t.Run("closed session", func(t *testing.T) {
+		driver := &mgoDriver{lifeCycle: &lifeCycle{}}
+
+		_, err := driver.SessionSettings(ctx)
+		assert.Equal(t, types.ErrorSessionClosed, err.Error())
+	})
+}
+
+func TestUpsertUnset(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	t.Run("$unset is ignored when inserting", func(t *testing.T) {
+		err := driver.Upsert(ctx, object, model.DBM{"age": 50}, model.DBM{
+			"$set":   model.DBM{"name": "upsert_unset", "email": "upsert@test.com"},
+			"$unset": model.DBM{"country": ""},
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, "upsert_unset", object.Name)
+		assert.Equal(t, "upsert@test.com", object.Email)
+		assert.Equal(t, 50, object.Age)
+	})
+
+	t.Run("$unset clears the field when updating", func(t *testing.T) {
+		err := driver.Upsert(ctx, object, model.DBM{"age": 50}, model.DBM{
+			"$set":   model.DBM{"name": "upsert_unset_updated"},
+			"$unset": model.DBM{"email": ""},
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, "upsert_unset_updated", object.Name)
+		assert.Empty(t, object.Email)
+		assert.Equal(t, 50, object.Age)
+
+		count, err := driver.Count(ctx, object, model.DBM{"age": 50, "email": model.DBM{"$exists": false}})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestAggregateMergeObjects(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx,
+		&dummyDBObject{Name: "first", Age: 1, Country: dummyCountryField{CountryName: "Spain", Continent: "Europe"}},
+		&dummyDBObject{Name: "second", Age: 1, Country: dummyCountryField{CountryName: "Peru"}},
+	)
+	assert.Nil(t, err)
+
+	t.Run("$mergeObjects in $project with a null operand", func(t *testing.T) {
+		result, err := driver.Aggregate(ctx, object, []model.DBM{
+			{"$match": model.DBM{"name": "first"}},
+			{
+				"$project": model.DBM{
+					"_id":    0,
+					"merged": model.DBM{"$mergeObjects": []interface{}{"$country", model.DBM{"name": "$name"}, nil}},
+				},
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []model.DBM{{
+			"merged": model.DBM{"country_name": "Spain", "continent": "Europe", "name": "first"},
+		}}, result)
+	})
+
+	t.Run("$mergeObjects as $group accumulator", func(t *testing.T) {
+		result, err := driver.Aggregate(ctx, object, []model.DBM{
+			{"$sort": model.DBM{"name": 1}},
+			{"$group": model.DBM{"_id": "$age", "merged": model.DBM{"$mergeObjects": "$country"}}},
+		})
+		assert.Nil(t, err)
+		assert.Len(t, result, 1)
+		// later documents overwrite the fields of the previous ones, even with empty values
+		assert.Equal(t, model.DBM{"country_name": "Peru", "continent": ""}, result[0]["merged"])
+	})
+}
+
+func TestExistingIDs(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	first := &dummyDBObject{ID: model.NewObjectID(), Name: "first"}
+	second := &dummyDBObject{ID: model.NewObjectID(), Name: "second"}
+	missing := model.NewObjectID()
+
+	err := driver.Insert(ctx, first, second)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		testName string
+		ids      []model.ObjectID
+		expected []model.ObjectID
+	}{
+		{
+			testName: "no ids",
+			ids:      []model.ObjectID{},
+			expected: []model.ObjectID{},
+		},
+		{
+			testName: "only missing ids",
+			ids:      []model.ObjectID{missing},
+			expected: []model.ObjectID{},
+		},
+		{
+			testName: "existing and missing ids",
+			ids:      []model.ObjectID{second.ID, missing, first.ID},
+			expected: []model.ObjectID{second.ID, first.ID},
+		},
+		{
+			testName: "duplicated ids",
+			ids:      []model.ObjectID{first.ID, first.ID},
+			expected: []model.ObjectID{first.ID},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			existing, err := driver.ExistingIDs(ctx, object, tc.ids)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, existing)
+		})
+	}
+}
+
+func TestQueryLenientDecode(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	valid := &dummyDBObject{Name: "a_valid", Age: 10}
+	drifted := &dummyDBObject{Name: "b_drifted", Email: "drifted@tyk.io", Age: 20}
+
+	err := driver.Insert(ctx, valid, drifted)
+	assert.Nil(t, err)
+
+	// simulate a type drift of the age field after a migration
+	err = driver.UpdateAll(ctx, object, model.DBM{"name": drifted.Name}, model.DBM{"$set": model.DBM{"age": "twenty"}})
+	assert.Nil(t, err)
+
+	t.Run("multiple documents", func(t *testing.T) {
+		var warnings model.DecodeWarnings
+
+		var result []dummyDBObject
+		err := driver.Query(ctx, object, &result, model.DBM{"_sort": "name", "_lenient_decode": &warnings})
+		assert.Nil(t, err)
+
+		assert.Len(t, result, 2)
+		assert.Equal(t, 10, result[0].Age)
+		assert.Equal(t, drifted.ID, result[1].ID)
+		assert.Equal(t, "drifted@tyk.io", result[1].Email)
+		assert.Equal(t, 0, result[1].Age)
+
+		assert.Len(t, warnings, 1)
+		assert.Equal(t, drifted.ID, warnings[0].ID)
+		assert.Equal(t, "age", warnings[0].Field)
+		assert.NotNil(t, warnings[0].Err)
+	})
+
+	t.Run("single document", func(t *testing.T) {
+		var warnings model.DecodeWarnings
+
+		result := &dummyDBObject{}
+		err := driver.Query(ctx, object, result, model.DBM{"name": drifted.Name, "_lenient_decode": &warnings})
+		assert.Nil(t, err)
+
+		assert.Equal(t, drifted.Name, result.Name)
+		assert.Equal(t, 0, result.Age)
+		assert.Len(t, warnings, 1)
+	})
+
+	t.Run("document not found", func(t *testing.T) {
+		var warnings model.DecodeWarnings
+
+		result := &dummyDBObject{}
+		err := driver.Query(ctx, object, result, model.DBM{"name": "unknown", "_lenient_decode": &warnings})
+		assert.Equal(t, mgo.ErrNotFound, err)
+		assert.Empty(t, warnings)
+	})
+}
+
+func TestAggregateWeightedAverage(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	rows := []struct {
+		name      string
+		continent string
+		age       int
+		weight    int
+	}{
+		{name: "first", continent: "Europe", age: 10, weight: 1},
+		{name: "second", continent: "Europe", age: 40, weight: 2},
+		{name: "third", continent: "America", age: 20, weight: 4},
+	}
+
+	for _, row := range rows {
+		err := driver.Insert(ctx, &dummyDBObject{
+			Name:    row.name,
+			Age:     row.age,
+			Country: dummyCountryField{Continent: row.continent},
+		})
+		assert.Nil(t, err)
+
+		err = driver.UpdateAll(ctx, object, model.DBM{"name": row.name}, model.DBM{"$set": model.DBM{"weight": row.weight}})
+		assert.Nil(t, err)
+	}
+
+	result, err := driver.Aggregate(ctx, object, []model.DBM{
+		{
+			"$group": model.DBM{
+				"_id":         "$country.continent",
+				"weightedSum": model.DBM{"$sum": model.DBM{"$multiply": []interface{}{"$age", "$weight"}}},
+				"totalWeight": model.DBM{"$sum": "$weight"},
+			},
+		},
+		{"$project": model.DBM{"weightedAvg": model.DBM{"$divide": []interface{}{"$weightedSum", "$totalWeight"}}}},
+		{"$sort": model.DBM{"_id": 1}},
+	})
+	assert.Nil(t, err)
+
+	// (10*1 + 40*2) / (1+2) for Europe and 20*4 / 4 for America
+	assert.Equal(t, []model.DBM{
+		{"_id": "America", "weightedAvg": 20.0},
+		{"_id": "Europe", "weightedAvg": 30.0},
+	}, result)
+}
+
+func TestAggregateStringNormalization(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx, &dummyDBObject{Name: "  Tyk Technologies  ", Email: "Info@Tyk.io"})
+	assert.Nil(t, err)
+
+	result, err := driver.Aggregate(ctx, object, []model.DBM{
+		{
+			"$project": model.DBM{
+				"_id":     0,
+				"trimmed": model.DBM{"$trim": model.DBM{"input": "$name"}},
+				"lower":   model.DBM{"$toLower": "$email"},
+				"upper":   model.DBM{"$toUpper": "$email"},
+				"display": model.DBM{"$toLower": model.DBM{"$trim": model.DBM{"input": "$name"}}},
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []model.DBM{{
+		"trimmed": "Tyk Technologies",
+		"lower":   "info@tyk.io",
+		"upper":   "INFO@TYK.IO",
+		"display": "tyk technologies",
+	}}, result)
+}
+
+func TestQueryCursor(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	for i := 0; i < 5; i++ {
+		err := driver.Insert(ctx, &dummyDBObject{Name: "name" + strconv.Itoa(i), Age: i})
+		assert.Nil(t, err)
+	}
+
+	tcs := []struct {
+		testName      string
+		query         model.DBM
+		expectedNames []string
+	}{
+		{
+			testName:      "all rows",
+			query:         model.DBM{"_sort": "age"},
+			expectedNames: []string{"name0", "name1", "name2", "name3", "name4"},
+		},
+		{
+			testName:      "filtered rows with limit and offset",
+			query:         model.DBM{"age": model.DBM{"$gte": 1}, "_sort": "-age", "_limit": 2, "_offset": 1},
+			expectedNames: []string{"name3", "name2"},
+		},
+		{
+			testName:      "no rows",
+			query:         model.DBM{"name": "unknown"},
+			expectedNames: []string{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			cursor, err := driver.QueryCursor(ctx, object, tc.query)
+			assert.Nil(t, err)
+
+			names := []string{}
+
+			for cursor.Next() {
+				var row dummyDBObject
+				assert.Nil(t, cursor.Decode(&row))
+
+				names = append(names, row.Name)
+			}
+
+			assert.Nil(t, cursor.Err())
+			assert.Nil(t, cursor.Close())
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestListPage(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	// rows with the same age are sorted by _id
+	for i := 0; i < 5; i++ {
+		err := driver.Insert(ctx, &dummyDBObject{Name: "name" + strconv.Itoa(i), Age: i / 2})
+		assert.Nil(t, err)
+	}
+
+	tcs := []struct {
+		testName      string
+		filter        model.DBM
+		page          model.PageRequest
+		expectedPages [][]string
+		expectedTotal int
+	}{
+		{
+			testName:      "sorted by _id",
+			page:          model.PageRequest{Limit: 2},
+			expectedPages: [][]string{{"name0", "name1"}, {"name2", "name3"}, {"name4"}},
+			expectedTotal: 5,
+		},
+		{
+			testName:      "sorted by age descending",
+			page:          model.PageRequest{Limit: 3, Sort: "-age"},
+			expectedPages: [][]string{{"name4", "name3", "name2"}, {"name1", "name0"}},
+			expectedTotal: 5,
+		},
+		{
+			testName:      "filtered rows",
+			filter:        model.DBM{"age": model.DBM{"$gte": 1}},
+			page:          model.PageRequest{Limit: 2, Sort: "age"},
+			expectedPages: [][]string{{"name2", "name3"}, {"name4"}},
+			expectedTotal: 3,
+		},
+		{
+			testName:      "default limit",
+			expectedPages: [][]string{{"name0", "name1", "name2", "name3", "name4"}},
+			expectedTotal: 5,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			pages := [][]string{}
+			page := tc.page
+
+			for {
+				result, err := driver.ListPage(ctx, object, tc.filter, page)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.expectedTotal, result.Total)
+
+				names := []string{}
+				for _, item := range result.Items {
+					names = append(names, item["name"].(string))
+				}
+
+				pages = append(pages, names)
+
+				if result.NextCursor == "" || len(pages) > len(tc.expectedPages) {
+					break
+				}
+
+				page.AfterCursor = result.NextCursor
+			}
+
+			assert.Equal(t, tc.expectedPages, pages)
+		})
+	}
+
+	_, err := driver.ListPage(ctx, object, nil, model.PageRequest{AfterCursor: "invalid"})
+	assert.Equal(t, errors.New(types.ErrorInvalidPageCursor), err)
+}
+
+func TestWithTransaction(t *testing.T) {
+	driver, object := prepareEnvironment(t)
+
+	called := false
+
+	err := driver.WithTransaction(context.Background(), func(tx types.PersistentStorage) error {
+		called = true
+		return tx.Insert(context.Background(), object)
+	})
+
+	assert.EqualError(t, err, types.ErrorTransactionsUnsupported)
+	assert.False(t, called)
+}
+
+func TestBulkInsert(t *testing.T) {
+	ctx := context.Background()
+
+	newRows := func(ids ...model.ObjectID) []model.DBObject {
+		rows := make([]model.DBObject, 0, len(ids))
+		for i, id := range ids {
+			rows = append(rows, &dummyDBObject{ID: id, Name: "name" + strconv.Itoa(i)})
+		}
+
+		return rows
+	}
+
+	duplicatedID := model.NewObjectID()
+
+	tcs := []struct {
+		testName         string
+		rows             []model.DBObject
+		opts             model.BulkOpts
+		expectedInserted int
+		expectedErr      bool
+	}{
+		{
+			testName:         "insert in batches",
+			rows:             newRows("", "", "", "", ""),
+			opts:             model.BulkOpts{BatchSize: 2},
+			expectedInserted: 5,
+		},
+		{
+			testName:         "stop on error",
+			rows:             newRows("", duplicatedID, duplicatedID, "", ""),
+			opts:             model.BulkOpts{BatchSize: 2},
+			expectedInserted: 2,
+			expectedErr:      true,
+		},
+		{
+			testName:         "continue on error",
+			rows:             newRows("", duplicatedID, duplicatedID, "", ""),
+			opts:             model.BulkOpts{BatchSize: 2, ContinueOnError: true},
+			expectedInserted: 4,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			driver, object := prepareEnvironment(t)
+			defer cleanDB(t)
+
+			inserted, err := driver.BulkInsert(ctx, tc.rows, tc.opts)
+			assert.Equal(t, tc.expectedInserted, inserted)
+
+			if tc.expectedErr {
+				assert.ErrorContains(t, err, "row 2:")
+			} else {
+				assert.Nil(t, err)
+			}
+
+			count, err := driver.Count(ctx, object)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedInserted, count)
+		})
+	}
+
+	t.Run("empty rows", func(t *testing.T) {
+		driver, _ := prepareEnvironment(t)
+
+		_, err := driver.BulkInsert(ctx, []model.DBObject{}, model.BulkOpts{})
+		assert.EqualError(t, err, types.ErrorEmptyRow)
+	})
+}
+
+func TestUpsertSetOnInsert(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	update := func(name, email string) model.DBM {
+		return model.DBM{
+			"$set":         model.DBM{"name": name},
+			"$setOnInsert": model.DBM{"email": email, "country": model.DBM{"country_name": "Spain"}},
+		}
+	}
+
+	t.Run("$setOnInsert is applied when inserting", func(t *testing.T) {
+		err := driver.Upsert(ctx, object, model.DBM{"age": 60}, update("seeded", "default@tyk.io"))
+		assert.Nil(t, err)
+
+		assert.Equal(t, "seeded", object.Name)
+		assert.Equal(t, "default@tyk.io", object.Email)
+		assert.Equal(t, "Spain", object.Country.CountryName)
+		assert.Equal(t, 60, object.Age)
+	})
+
+	t.Run("$setOnInsert is ignored when updating", func(t *testing.T) {
+		err := driver.Upsert(ctx, object, model.DBM{"age": 60}, update("seeded_updated", "other@tyk.io"))
+		assert.Nil(t, err)
+
+		assert.Equal(t, "seeded_updated", object.Name)
+		assert.Equal(t, "default@tyk.io", object.Email)
+		assert.Equal(t, 60, object.Age)
+	})
+
+	count, err := driver.Count(ctx, object)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestQueryReadPreference(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+
+	t.Run("secondaryPreferred falls back to the primary", func(t *testing.T) {
+		var result []dummyDBObject
+		err := driver.Query(ctx, object, &result, model.DBM{"_read_pref": "secondaryPreferred"})
+		assert.Nil(t, err)
+		assert.Len(t, result, 1)
+
+		count, err := driver.Count(ctx, object, model.DBM{"_read_pref": "nearest"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+
+		aggregation, err := driver.Aggregate(ctx, object, []model.DBM{
+			{"_read_pref": "primaryPreferred"},
+			{"$match": model.DBM{"name": object.Name}},
+		})
+		assert.Nil(t, err)
+		assert.Len(t, aggregation, 1)
+	})
+
+	t.Run("unknown read preference", func(t *testing.T) {
+		var result []dummyDBObject
+		err := driver.Query(ctx, object, &result, model.DBM{"_read_pref": "fastest"})
+		assert.EqualError(t, err, types.ErrorUnknownReadPreference+": fastest")
+	})
+}
+
+func TestQueryFields(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	object.Country = dummyCountryField{CountryName: "Spain", Continent: "Europe"}
+	object.Age = 30
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+
+	t.Run("multiple rows", func(t *testing.T) {
+		var result []dummyDBObject
+		err := driver.Query(ctx, object, &result, model.DBM{"_fields": []string{"name", "country.continent"}})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []dummyDBObject{{
+			ID:      object.ID,
+			Name:    object.Name,
+			Country: dummyCountryField{Continent: "Europe"},
+		}}, result)
+	})
+
+	t.Run("single row", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.Query(ctx, object, result, model.DBM{"_id": object.ID, "_fields": []string{"email"}})
+		assert.Nil(t, err)
+
+		assert.Equal(t, &dummyDBObject{ID: object.ID, Email: object.Email}, result)
+	})
+
+	t.Run("no fields returns the whole row", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.Query(ctx, object, result, model.DBM{"_id": object.ID, "_fields": []string{}})
+		assert.Nil(t, err)
+
+		assert.Equal(t, object, result)
+	})
+}
+
+func TestQueryDocuments(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	object.Country = dummyCountryField{CountryName: "Spain", Continent: "Europe"}
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+
+	expected := model.DBM{
+		"_id":     object.ID,
+		"name":    object.Name,
+		"country": model.DBM{"country_name": "Spain", "continent": "Europe"},
+	}
+
+	t.Run("multiple rows", func(t *testing.T) {
+		var result []model.DBM
+		err := driver.Query(ctx, object, &result, model.DBM{"_fields": []string{"name", "country"}})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []model.DBM{expected}, result)
+	})
+
+	t.Run("single row", func(t *testing.T) {
+		result := model.DBM{}
+		err := driver.Query(ctx, object, &result, model.DBM{"_id": object.ID, "_fields": []string{"name", "country"}})
+		assert.Nil(t, err)
+
+		assert.Equal(t, expected, result)
+	})
+}
+
+func TestModelValue(t *testing.T) {
+	oid := bson.NewObjectId()
+	u := model.NewUUID()
+
+	assert.Equal(t, model.ObjectID(oid), modelValue(oid))
+	assert.Equal(t, u, modelValue(bson.Binary{Kind: 0x04, Data: u[:]}))
+	assert.Equal(t, bson.Binary{Data: u[:]}, modelValue(bson.Binary{Data: u[:]}))
+	assert.Equal(t, "value", modelValue("value"))
+}
+
+func TestCountWithOpts(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	count, err := driver.CountWithOpts(ctx, object, model.CountOpts{Estimated: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	for i := 0; i < 5; i++ {
+		err := driver.Insert(ctx, &dummyDBObject{Name: "name" + strconv.Itoa(i), Age: i})
+		assert.Nil(t, err)
+	}
+
+	tcs := []struct {
+		name        string
+		givenOpts   model.CountOpts
+		givenFilter []model.DBM
+		want        int
+	}{
+		{
+			name:      "estimated",
+			givenOpts: model.CountOpts{Estimated: true},
+			want:      5,
+		},
+		{
+			name:        "estimated with read preference",
+			givenOpts:   model.CountOpts{Estimated: true},
+			givenFilter: []model.DBM{{"_read_pref": "primary"}},
+			want:        5,
+		},
+		{
+			name:        "estimated with filter counts the rows",
+			givenOpts:   model.CountOpts{Estimated: true},
+			givenFilter: []model.DBM{{"age": model.DBM{"$gte": 3}}},
+			want:        2,
+		},
+		{
+			name:        "exact",
+			givenFilter: []model.DBM{{"age": model.DBM{"$lt": 3}}},
+			want:        3,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			count, err := driver.CountWithOpts(ctx, object, tc.givenOpts, tc.givenFilter...)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, count)
+		})
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	first := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk", Email: "first@tyk.io"}
+	second := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk", Email: "second@tyk.io"}
+	third := &dummyDBObject{ID: model.NewObjectID(), Name: "gateway", Email: "third@tyk.io"}
+
+	err := driver.Insert(ctx, first, second, third)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		testName string
+		field    string
+		filter   model.DBM
+		expected []interface{}
+	}{
+		{
+			testName: "distinct values of all rows",
+			field:    "name",
+			expected: []interface{}{"tyk", "gateway"},
+		},
+		{
+			testName: "distinct values of filtered rows",
+			field:    "name",
+			filter:   model.DBM{"email": model.DBM{"$ne": "first@tyk.io"}},
+			expected: []interface{}{"tyk", "gateway"},
+		},
+		{
+			testName: "no rows matching filter",
+			field:    "name",
+			filter:   model.DBM{"email": "unknown@tyk.io"},
+			expected: []interface{}{},
+		},
+		{
+			testName: "unknown field",
+			field:    "unknown",
+			expected: []interface{}{},
+		},
+		{
+			testName: "ids are returned as model.ObjectID",
+			field:    "_id",
+			filter:   model.DBM{"name": "tyk"},
+			expected: []interface{}{first.ID, second.ID},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			values, err := driver.Distinct(ctx, object, tc.field, tc.filter)
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, tc.expected, values)
+		})
+	}
+}
+
+func TestFindOneAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	driver, _ := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	younger := &dummyDBObject{ID: model.NewObjectID(), Name: "job", Email: "younger@tyk.io", Age: 10}
+	older := &dummyDBObject{ID: model.NewObjectID(), Name: "job", Email: "older@tyk.io", Age: 20}
+
+	err := driver.Insert(ctx, younger, older)
+	assert.Nil(t, err)
+
+	t.Run("returns the row before the update", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"_id": younger.ID}, model.DBM{"$inc": model.DBM{"age": 1}})
+		assert.Nil(t, err)
+		assert.Equal(t, 10, result.Age)
+	})
+
+	t.Run("returns the row after the update", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"_id": younger.ID}, model.DBM{"$inc": model.DBM{"age": 1}},
+			model.FindOneOpts{ReturnNew: true})
+		assert.Nil(t, err)
+		assert.Equal(t, 12, result.Age)
+	})
+
+	t.Run("sort picks the row to update", func(t *testing.T) {
+		result := &dummyDBObject{}
+		claim := model.DBM{"$set": model.DBM{"name": "claimed"}}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"name": "job"}, claim,
+			model.FindOneOpts{ReturnNew: true, Sort: []string{"-age"}})
+		assert.Nil(t, err)
+		assert.Equal(t, older.ID, result.ID)
+		assert.Equal(t, "claimed", result.Name)
+	})
+
+	t.Run("no row matching the query", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"name": "unknown"}, model.DBM{"$set": model.DBM{"age": 1}})
+		assert.Equal(t, mgo.ErrNotFound, err)
+	})
+
+	t.Run("upsert a new row", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"name": "new"}, model.DBM{"$set": model.DBM{"age": 1}},
+			model.FindOneOpts{ReturnNew: true, Upsert: true})
+		assert.Nil(t, err)
+		assert.True(t, result.ID.Valid())
+		assert.Equal(t, "new", result.Name)
+		assert.Equal(t, 1, result.Age)
+	})
+
+	t.Run("upsert a new row returning the row before the update", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"name": "other"}, model.DBM{"$set": model.DBM{"age": 1}},
+			model.FindOneOpts{Upsert: true})
+		assert.Nil(t, err)
+		assert.Equal(t, &dummyDBObject{}, result)
+	})
+
+	t.Run("multiple options", func(t *testing.T) {
+		result := &dummyDBObject{}
+		err := driver.FindOneAndUpdate(ctx, result, model.DBM{"name": "job"}, model.DBM{"$set": model.DBM{"age": 1}},
+			model.FindOneOpts{}, model.FindOneOpts{})
+		assert.Equal(t, errors.New(types.ErrorMultipleFindOneOpts), err)
+	})
+}
+
+func TestDeleteWithResult(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	first := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk", Age: 10}
+	second := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk", Age: 20}
+	third := &dummyDBObject{ID: model.NewObjectID(), Name: "gateway", Age: 30}
+
+	err := driver.Insert(ctx, first, second, third)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		testName      string
+		row           model.DBObject
+		filter        model.DBM
+		expectedCount int64
+		expectedLeft  int
+	}{
+		{
+			testName:      "nothing matching the filter",
+			row:           object,
+			filter:        model.DBM{"name": "unknown"},
+			expectedCount: 0,
+			expectedLeft:  3,
+		},
+		{
+			testName:      "empty filter deletes the row by its id",
+			row:           third,
+			expectedCount: 1,
+			expectedLeft:  2,
+		},
+		{
+			testName:      "several rows matching the filter",
+			row:           object,
+			filter:        model.DBM{"name": "tyk"},
+			expectedCount: 2,
+			expectedLeft:  0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			deleted, err := driver.DeleteWithResult(ctx, tc.row, tc.filter)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedCount, deleted)
+
+			left, err := driver.Count(ctx, object)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedLeft, left)
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+
+	t.Run("query", func(t *testing.T) {
+		explain, err := driver.Explain(ctx, object, model.DBM{"name": object.Name})
+		assert.Nil(t, err)
+		assert.Contains(t, explain, "queryPlanner")
+		assert.Contains(t, explain, "executionStats")
+	})
+
+	t.Run("aggregation", func(t *testing.T) {
+		explain, err := driver.ExplainAggregate(ctx, object, []model.DBM{
+			{"$match": model.DBM{"name": object.Name}},
+			{"$group": model.DBM{"_id": "$email", "count": model.DBM{"$sum": 1}}},
+		})
+		assert.Nil(t, err)
+		assert.Contains(t, explain, "queryPlanner")
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		_, err := driver.Explain(ctx, object, model.DBM{"name": model.DBM{"$unknown": 1}})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSearchText(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.CreateIndex(ctx, object, model.Index{Name: "name_text", Keys: []model.DBM{{"name": "text"}}})
+	assert.Nil(t, err)
+
+	gateway := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk gateway", Email: "gateway@tyk.io"}
+	dashboard := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk dashboard", Email: "dashboard@tyk.io"}
+	pump := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk pump tyk", Email: "pump@tyk.io"}
+
+	err = driver.Insert(ctx, gateway, dashboard, pump)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		testName    string
+		text        string
+		filter      model.DBM
+		expectedIDs []model.ObjectID
+	}{
+		{
+			testName:    "single match",
+			text:        "gateway",
+			expectedIDs: []model.ObjectID{gateway.ID},
+		},
+		{
+			testName:    "with filter",
+			text:        "tyk",
+			filter:      model.DBM{"email": "dashboard@tyk.io"},
+			expectedIDs: []model.ObjectID{dashboard.ID},
+		},
+		{
+			testName:    "with limit",
+			text:        "tyk pump",
+			filter:      model.DBM{"_limit": 1},
+			expectedIDs: []model.ObjectID{pump.ID},
+		},
+		{
+			testName:    "no match",
+			text:        "analytics",
+			expectedIDs: []model.ObjectID{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			var result []dummyDBObject
+
+			err := driver.SearchText(ctx, object, &result, tc.text, tc.filter)
+			assert.Nil(t, err)
+
+			ids := []model.ObjectID{}
+			for _, row := range result {
+				ids = append(ids, row.ID)
+			}
+
+			assert.Equal(t, tc.expectedIDs, ids)
+		})
+	}
+
+	t.Run("sorted by relevance", func(t *testing.T) {
+		var result []dummyDBObject
+
+		err := driver.SearchText(ctx, object, &result, "tyk pump", nil)
+		assert.Nil(t, err)
+
+		// gateway and dashboard have the same score, so only the most relevant row has a fixed position
+		assert.Len(t, result, 3)
+		assert.Equal(t, *pump, result[0])
+	})
+
+	t.Run("single row", func(t *testing.T) {
+		result := &dummyDBObject{}
+
+		err := driver.SearchText(ctx, object, result, "tyk pump", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, pump, result)
+	})
+}
+
+type dummyGeoObject struct {
+	ID       model.ObjectID `bson:"_id,omitempty"`
+	Name     string         `bson:"name"`
+	Location model.GeoPoint `bson:"location"`
+}
+
+func (d *dummyGeoObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyGeoObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyGeoObject) TableName() string {
+	return "dummy_geo"
+}
+
+func TestGeoQuery(t *testing.T) {
+	ctx := context.Background()
+	driver, _ := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	object := &dummyGeoObject{}
+
+	err := driver.CreateIndex(ctx, object, model.Index{
+		Name:     "location_geo",
+		Keys:     []model.DBM{{"location": 1}},
+		GeoIndex: true,
+	})
+	assert.Nil(t, err)
+
+	indexes, err := driver.GetIndexes(ctx, object)
+	assert.Nil(t, err)
+	assert.Contains(t, indexes, model.Index{Name: "location_geo", Keys: []model.DBM{{"location": "2dsphere"}}})
+
+	london := &dummyGeoObject{ID: model.NewObjectID(), Name: "london", Location: model.NewGeoPoint(-0.1276, 51.5072)}
+	paris := &dummyGeoObject{ID: model.NewObjectID(), Name: "paris", Location: model.NewGeoPoint(2.3522, 48.8566)}
+	madrid := &dummyGeoObject{ID: model.NewObjectID(), Name: "madrid", Location: model.NewGeoPoint(-3.7038, 40.4168)}
+
+	err = driver.Insert(ctx, london, paris, madrid)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		testName      string
+		query         model.DBM
+		expectedNames []string
+	}{
+		{
+			testName: "$near sorts by distance",
+			query: model.DBM{"location": model.DBM{"$near": model.DBM{
+				"$geometry": model.NewGeoPoint(2.1734, 41.3851), // barcelona
+			}}},
+			expectedNames: []string{"madrid", "paris", "london"},
+		},
+		{
+			testName: "$near with max distance",
+			query: model.DBM{"location": model.DBM{"$near": model.DBM{
+				"$geometry":    model.NewGeoPoint(-0.1276, 51.5072),
+				"$maxDistance": 500000,
+			}}},
+			expectedNames: []string{"london", "paris"},
+		},
+		{
+			testName: "$geoWithin a sphere",
+			query: model.DBM{"location": model.DBM{"$geoWithin": model.DBM{
+				// 1000 km around madrid, in radians
+				"$centerSphere": []interface{}{[]float64{-3.7038, 40.4168}, 1000.0 / 6378.1},
+			}}},
+			expectedNames: []string{"madrid"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			var result []dummyGeoObject
+
+			err := driver.Query(ctx, object, &result, tc.query)
+			assert.Nil(t, err)
+
+			names := []string{}
+			for _, row := range result {
+				names = append(names, row.Name)
+			}
+
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	driver, object := prepareEnvironment(t)
+
+	events, err := driver.Watch(context.Background(), object, model.DBM{})
+	assert.Nil(t, events)
+	assert.Equal(t, errors.New(types.ErrorChangeStreamsUnsupported), err)
+}
+
+type dummySoftDeletableObject struct {
+	ID      model.ObjectID `bson:"_id,omitempty"`
+	Name    string         `bson:"name"`
+	Deleted time.Time      `bson:"deleted_at"`
+}
+
+func (d *dummySoftDeletableObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummySoftDeletableObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummySoftDeletableObject) TableName() string {
+	return "dummy_soft_deletable"
+}
+
+func (d *dummySoftDeletableObject) DeletedAt() time.Time {
+	return d.Deleted
+}
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	first := &dummySoftDeletableObject{ID: model.NewObjectID(), Name: "first"}
+	second := &dummySoftDeletableObject{ID: model.NewObjectID(), Name: "second"}
+	third := &dummySoftDeletableObject{ID: model.NewObjectID(), Name: "third"}
+
+	err := driver.Insert(ctx, first, second, third)
+	assert.Nil(t, err)
+
+	err = driver.Delete(ctx, first)
+	assert.Nil(t, err)
+
+	deleted, err := driver.DeleteWithResult(ctx, &dummySoftDeletableObject{}, model.DBM{"name": "second"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	t.Run("deleting a deleted row", func(t *testing.T) {
+		err := driver.Delete(ctx, first)
+		assert.True(t, utils.IsErrNoRows(err))
+	})
+
+	t.Run("queries skip the deleted rows", func(t *testing.T) {
+		var result []dummySoftDeletableObject
+
+		err := driver.Query(ctx, &dummySoftDeletableObject{}, &result, model.DBM{})
+		assert.Nil(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, third.ID, result[0].ID)
+		assert.True(t, result[0].DeletedAt().IsZero())
+
+		count, err := driver.Count(ctx, &dummySoftDeletableObject{})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("queries with the deleted rows", func(t *testing.T) {
+		var result []dummySoftDeletableObject
+
+		err := driver.Query(ctx, &dummySoftDeletableObject{}, &result, model.DBM{"_with_deleted": true, "_sort": "name"})
+		assert.Nil(t, err)
+		assert.Len(t, result, 3)
+		assert.False(t, result[0].DeletedAt().IsZero())
+		assert.False(t, result[1].DeletedAt().IsZero())
+		assert.True(t, result[2].DeletedAt().IsZero())
+	})
+
+	t.Run("other operations skip the deleted rows", func(t *testing.T) {
+		row := &dummySoftDeletableObject{}
+
+		count, err := driver.CountWithOpts(ctx, row, model.CountOpts{Estimated: true})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+
+		values, err := driver.Distinct(ctx, row, "name", model.DBM{})
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{"third"}, values)
+
+		results, err := driver.Aggregate(ctx, row, []model.DBM{{"$group": model.DBM{"_id": "$name"}}})
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+
+		results, err = driver.Aggregate(ctx, row, []model.DBM{
+			{"$group": model.DBM{"_id": "$name"}},
+			{"_with_deleted": true},
+		})
+		assert.Nil(t, err)
+		assert.Len(t, results, 3)
+
+		err = driver.CreateIndex(ctx, row, model.Index{Name: "name_text", Keys: []model.DBM{{"name": "text"}}})
+		assert.Nil(t, err)
+
+		var found []dummySoftDeletableObject
+
+		err = driver.SearchText(ctx, row, &found, "first second third", model.DBM{})
+		assert.Nil(t, err)
+		assert.Len(t, found, 1)
+
+		err = driver.UpdateAll(ctx, row, model.DBM{"_id": first.ID}, model.DBM{"$set": model.DBM{"name": "updated"}})
+		assert.True(t, utils.IsErrNoRows(err))
+
+		err = driver.FindOneAndUpdate(ctx, row, model.DBM{"_id": first.ID}, model.DBM{"$set": model.DBM{"name": "updated"}})
+		assert.True(t, utils.IsErrNoRows(err))
+
+		updated := &dummySoftDeletableObject{ID: first.ID, Name: "updated"}
+
+		err = driver.Update(ctx, updated)
+		assert.True(t, utils.IsErrNoRows(err))
+
+		err = driver.BulkUpdate(ctx, []model.DBObject{updated})
+		assert.True(t, utils.IsErrNoRows(err))
+
+		// the updates leave the deleted row deleted
+		count, err = driver.Count(ctx, row, model.DBM{"_id": first.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, count)
+
+		count, err = driver.Count(ctx, row, model.DBM{"name": "first", "_with_deleted": true})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+
+		// the ids of the soft deleted rows are still taken
+		existing, err := driver.ExistingIDs(ctx, row, []model.ObjectID{first.ID, third.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, []model.ObjectID{first.ID, third.ID}, existing)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		purged, err := driver.Purge(ctx, &dummySoftDeletableObject{}, time.Hour)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, purged)
+
+		purged, err = driver.Purge(ctx, &dummySoftDeletableObject{}, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, purged)
+
+		count, err := driver.Count(ctx, &dummySoftDeletableObject{}, model.DBM{"_with_deleted": true})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("purge a row that is not soft deletable", func(t *testing.T) {
+		_, err := driver.Purge(ctx, object, 0)
+		assert.Equal(t, errors.New(types.ErrorNotSoftDeletable), err)
+	})
+
+	t.Run("upserting a deleted row restores it", func(t *testing.T) {
+		row := &dummySoftDeletableObject{ID: model.NewObjectID(), Name: "restored"}
+
+		err := driver.Insert(ctx, row)
+		assert.Nil(t, err)
+
+		err = driver.Delete(ctx, row)
+		assert.Nil(t, err)
+
+		result := &dummySoftDeletableObject{}
+
+		err = driver.Upsert(ctx, result, model.DBM{"_id": row.ID}, model.DBM{"$set": model.DBM{"name": "upserted"}})
+		assert.Nil(t, err)
+		assert.Equal(t, row.ID, result.ID)
+		assert.Equal(t, "upserted", result.Name)
+		assert.True(t, result.DeletedAt().IsZero())
+
+		count, err := driver.Count(ctx, result, model.DBM{"_id": row.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+type dummyTimestampedObject struct {
+	ID        model.ObjectID `bson:"_id,omitempty"`
+	Name      string         `bson:"name"`
+	CreatedAt time.Time      `bson:"created_at"`
+	UpdatedAt time.Time      `bson:"updated_at"`
+}
+
+func (d *dummyTimestampedObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyTimestampedObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyTimestampedObject) TableName() string {
+	return "dummy_timestamped"
+}
+
+func (d *dummyTimestampedObject) SetCreatedAt(createdAt time.Time) {
+	d.CreatedAt = createdAt
+}
+
+func (d *dummyTimestampedObject) SetUpdatedAt(updatedAt time.Time) {
+	d.UpdatedAt = updatedAt
+}
+
+func TestTimestamps(t *testing.T) {
+	ctx := context.Background()
+	driver, _ := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	object := &dummyTimestampedObject{ID: model.NewObjectID(), Name: "tyk"}
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+	assert.False(t, object.CreatedAt.IsZero())
+	assert.Equal(t, object.CreatedAt, object.UpdatedAt)
+
+	createdAt := object.CreatedAt
+
+	time.Sleep(2 * time.Millisecond)
+
+	t.Run("update", func(t *testing.T) {
+		object.Name = "updated"
+
+		err := driver.Update(ctx, object)
+		assert.Nil(t, err)
+
+		result := &dummyTimestampedObject{}
+		err = driver.Query(ctx, result, result, model.DBM{"_id": object.ID})
+		assert.Nil(t, err)
+		assert.True(t, createdAt.Equal(result.CreatedAt))
+		assert.True(t, result.UpdatedAt.After(createdAt))
+	})
+
+	t.Run("update all", func(t *testing.T) {
+		err := driver.UpdateAll(ctx, object, model.DBM{"_id": object.ID}, model.DBM{"$set": model.DBM{"name": "all"}})
+		assert.Nil(t, err)
+
+		result := &dummyTimestampedObject{}
+		err = driver.Query(ctx, result, result, model.DBM{"_id": object.ID})
+		assert.Nil(t, err)
+		assert.True(t, createdAt.Equal(result.CreatedAt))
+		assert.True(t, result.UpdatedAt.After(createdAt))
+	})
+
+	t.Run("upsert inserting a row", func(t *testing.T) {
+		result := &dummyTimestampedObject{}
+
+		err := driver.Upsert(ctx, result, model.DBM{"name": "upserted"}, model.DBM{"$set": model.DBM{"name": "upserted"}})
+		assert.Nil(t, err)
+		assert.False(t, result.CreatedAt.IsZero())
+		assert.True(t, result.CreatedAt.Equal(result.UpdatedAt))
+	})
+}
+
+// prefixCodec encodes the values prefixing them with "encoded:".
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(value string) (string, error) {
+	return "encoded:" + value, nil
+}
+
+func (prefixCodec) Decode(value string) (string, error) {
+	if !strings.HasPrefix(value, "encoded:") {
+		return "", errors.New("not encoded")
+	}
+
+	return strings.TrimPrefix(value, "encoded:"), nil
+}
+
+func TestFieldCodec(t *testing.T) {
+	ctx := context.Background()
+	driver, _ := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	helper.RegisterFieldCodec("dummy", "email", prefixCodec{})
+	defer helper.RegisterFieldCodec("dummy", "email", nil)
+
+	object := &dummyDBObject{ID: model.NewObjectID(), Name: "tyk", Email: "secret@tyk.io"}
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret@tyk.io", object.Email)
+
+	t.Run("stored encoded", func(t *testing.T) {
+		var result []model.DBM
+
+		err := driver.Query(ctx, object, &result, model.DBM{"_id": object.ID})
+		assert.Nil(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "encoded:secret@tyk.io", result[0]["email"])
+	})
+
+	t.Run("queried decoded", func(t *testing.T) {
+		result := &dummyDBObject{}
+
+		err := driver.Query(ctx, object, result, model.DBM{"_id": object.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, object, result)
+	})
+
+	t.Run("updated encoded", func(t *testing.T) {
+		object.Email = "updated@tyk.io"
+
+		err := driver.Update(ctx, object)
+		assert.Nil(t, err)
+		assert.Equal(t, "updated@tyk.io", object.Email)
+
+		var result []dummyDBObject
+
+		err = driver.Query(ctx, object, &result, model.DBM{"email": "encoded:updated@tyk.io"})
+		assert.Nil(t, err)
+		assert.Equal(t, []dummyDBObject{*object}, result)
+	})
+
+	t.Run("read paths decoded", func(t *testing.T) {
+		var warnings model.DecodeWarnings
+
+		var result []dummyDBObject
+
+		err := driver.Query(ctx, object, &result, model.DBM{"_id": object.ID, "_lenient_decode": &warnings})
+		assert.Nil(t, err)
+		assert.Equal(t, []dummyDBObject{*object}, result)
+
+		cursor, err := driver.QueryCursor(ctx, object, model.DBM{"_id": object.ID})
+		assert.Nil(t, err)
+		assert.True(t, cursor.Next())
+
+		row := &dummyDBObject{}
+		assert.Nil(t, cursor.Decode(row))
+		assert.Equal(t, object, row)
+		assert.Nil(t, cursor.Close())
+
+		page, err := driver.ListPage(ctx, object, model.DBM{}, model.PageRequest{})
+		assert.Nil(t, err)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "updated@tyk.io", page.Items[0]["email"])
+
+		values, err := driver.Distinct(ctx, object, "email", model.DBM{})
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{"updated@tyk.io"}, values)
+
+		err = driver.CreateIndex(ctx, object, model.Index{Name: "name_text", Keys: []model.DBM{{"name": "text"}}})
+		assert.Nil(t, err)
+
+		result = nil
+
+		err = driver.SearchText(ctx, object, &result, "tyk", model.DBM{})
+		assert.Nil(t, err)
+		assert.Equal(t, []dummyDBObject{*object}, result)
+
+		var exported bytes.Buffer
+
+		_, err = driver.Export(ctx, object, model.DBM{}, &exported, model.NDJSON)
+		assert.Nil(t, err)
+		assert.Contains(t, exported.String(), "updated@tyk.io")
+		assert.NotContains(t, exported.String(), "encoded:")
+	})
+
+	t.Run("update paths encoded", func(t *testing.T) {
+		err := driver.UpdateAll(ctx, object, model.DBM{"_id": object.ID},
+			model.DBM{"$set": model.DBM{"email": "all@tyk.io"}})
+		assert.Nil(t, err)
+
+		n, err := driver.Count(ctx, object, model.DBM{"email": "encoded:all@tyk.io"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+
+		found := &dummyDBObject{}
+
+		err = driver.FindOneAndUpdate(ctx, found, model.DBM{"_id": object.ID},
+			model.DBM{"$set": model.DBM{"email": "found@tyk.io"}}, model.FindOneOpts{ReturnNew: true})
+		assert.Nil(t, err)
+		assert.Equal(t, "found@tyk.io", found.Email)
+
+		n, err = driver.Count(ctx, object, model.DBM{"email": "encoded:found@tyk.io"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+
+		upserted := &dummyDBObject{}
+
+		err = driver.Upsert(ctx, upserted, model.DBM{"name": "upserted"},
+			model.DBM{"$set": model.DBM{"email": "upserted@tyk.io"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "upserted@tyk.io", upserted.Email)
+
+		n, err = driver.Count(ctx, object, model.DBM{"email": "encoded:upserted@tyk.io"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, n)
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	driver := &mgoDriver{lifeCycle: &lifeCycle{}}
+	object := &dummyDBObject{ID: model.NewObjectID(), Name: "test"}
+	ctx, dryRun := model.WithDryRun(context.Background())
+
+	t.Run("rows left untouched", func(t *testing.T) {
+		ctx, _ := model.WithDryRun(context.Background())
+		row := &dummyTimestampedObject{Name: "untouched"}
+
+		assert.Nil(t, driver.Insert(ctx, row))
+		assert.Nil(t, driver.Update(ctx, row))
+		assert.Equal(t, &dummyTimestampedObject{Name: "untouched"}, row)
+	})
+
+	var result []dummyDBObject
+
+	assert.Nil(t, driver.Query(ctx, object, &result, model.DBM{"name": "test", "_sort": "-age", "_limit": 10}))
+	assert.Nil(t, result)
+
+	n, err := driver.Count(ctx, object, model.DBM{"name": "test"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	assert.Nil(t, driver.Insert(ctx, object))
+	assert.Nil(t, driver.Delete(ctx, object))
+
+	_, err = driver.Aggregate(ctx, object, []model.DBM{{"$match": model.DBM{"name": "test"}}})
+	assert.Nil(t, err)
+
+	assert.EqualError(t, driver.Drop(ctx, object), types.ErrorDryRunUnsupported)
+
+	assert.Equal(t, []model.DBM{
+		{"find": "dummy", "filter": bson.M{"name": "test"}, "sort": bson.D{{Name: "age", Value: -1}}, "limit": 10},
+		{"count": "dummy", "query": bson.M{"name": "test"}},
+		{"insert": "dummy", "documents": []model.DBM{{
+			"_id":     bson.ObjectId(object.ID),
+			"name":    "test",
+			"email":   "",
+			"country": model.DBM{"country_name": "", "continent": ""},
+			"age":     0,
+		}}},
+		{"delete": "dummy", "deletes": []model.DBM{{"q": bson.M{"_id": object.ID}, "limit": 0}}},
+		{
+			"aggregate":    "dummy",
+			"pipeline":     []model.DBM{{"$match": model.DBM{"name": "test"}}},
+			"cursor":       model.DBM{},
+			"allowDiskUse": true,
+		},
+	}, dryRun.Commands())
+}
+
+func TestTableNameResolver(t *testing.T) {
+	driver := &mgoDriver{lifeCycle: &lifeCycle{}, options: types.ClientOpts{
+		TableNameResolver: func(table string) string {
+			return "tenant_" + table
+		},
+	}}
+	object := &dummyDBObject{Name: "test"}
+	ctx, dryRun := model.WithDryRun(context.Background())
+
+	var result []dummyDBObject
+
+	assert.Nil(t, driver.Query(ctx, object, &result, model.DBM{"name": "test"}))
+	assert.Nil(t, driver.Query(ctx, nil, &result, model.DBM{"_collection": "other"}))
+
+	_, err := driver.Count(ctx, object)
+	assert.Nil(t, err)
+	assert.Nil(t, driver.Insert(ctx, object))
+	assert.Nil(t, driver.Delete(ctx, object))
+
+	var tables []interface{}
+	for _, command := range dryRun.Commands() {
+		for _, key := range []string{"find", "count", "insert", "delete"} {
+			if table, ok := command[key]; ok {
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	assert.Equal(t, []interface{}{"tenant_dummy", "tenant_other", "tenant_dummy", "tenant_dummy", "tenant_dummy"}, tables)
+}
+
+func TestContextDone(t *testing.T) {
+	// the operations fail before using the session, so it isn't connected
+	driver := &mgoDriver{lifeCycle: &lifeCycle{}}
+	object := &dummyDBObject{Name: "test"}
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expiredCtx, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	var result []dummyDBObject
+
+	assert.ErrorIs(t, driver.Insert(canceledCtx, object), context.Canceled)
+	assert.ErrorIs(t, driver.Query(canceledCtx, object, &result, model.DBM{}), context.Canceled)
+	assert.ErrorIs(t, driver.SearchText(canceledCtx, object, &result, "test", model.DBM{}), context.Canceled)
+
+	_, err := driver.ListPage(canceledCtx, object, model.DBM{}, model.PageRequest{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = driver.GetDatabaseInfo(canceledCtx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = driver.Count(expiredCtx, object)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = driver.QueryCursor(expiredCtx, object, model.DBM{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the socket timeouts at the deadline of the context don't reconnect
+	err = driver.handleStoreError(expiredCtx, errors.New("read tcp 127.0.0.1:27017: i/o timeout"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualError(t, err, "context deadline exceeded: read tcp 127.0.0.1:27017: i/o timeout")
+}
+
+func TestQueryCursorCanceled(t *testing.T) {
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	for i := 0; i < 5; i++ {
+		err := driver.Insert(context.Background(), &dummyDBObject{Name: "name" + strconv.Itoa(i), Age: i})
+		assert.Nil(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cursor, err := driver.QueryCursor(ctx, object, model.DBM{"_sort": "age"})
+	assert.Nil(t, err)
+	assert.True(t, cursor.Next())
+
+	cancel()
+
+	assert.False(t, cursor.Next())
+	assert.ErrorIs(t, cursor.Err(), context.Canceled)
+	assert.ErrorIs(t, cursor.Close(), context.Canceled)
+
+	_, err = driver.Aggregate(ctx, object, []model.DBM{{"$match": model.DBM{}}})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Query, SearchText and ListPage read the rows through contextIter.All
+	sess := driver.session.Copy()
+	defer sess.Close()
+
+	var result []dummyDBObject
+
+	iter := newContextIter(ctx, sess.DB("").C(object.TableName()).Find(nil).Iter())
+	assert.ErrorIs(t, iter.All(&result), context.Canceled)
+	assert.Empty(t, result)
+}
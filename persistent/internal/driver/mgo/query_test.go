@@ -136,6 +136,70 @@ func TestBuildQuery(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Test with field level $not",
+			input: model.DBM{
+				"age": model.DBM{
+					"$not": model.DBM{
+						"$gt": 30,
+					},
+				},
+			},
+			output: bson.M{
+				"age": bson.M{
+					"$not": bson.M{
+						"$gt": 30,
+					},
+				},
+			},
+		},
+		{
+			name: "Test with field level $not and other operators",
+			input: model.DBM{
+				"age": model.DBM{
+					"$not": model.DBM{
+						"$gt": 30,
+					},
+					"$ne": 10,
+				},
+			},
+			output: bson.M{
+				"age": bson.M{
+					"$not": bson.M{
+						"$gt": 30,
+					},
+					"$ne": 10,
+				},
+			},
+		},
+		{
+			name: "Test with field level $not wrapping $i",
+			input: model.DBM{
+				"name": model.DBM{
+					"$not": model.DBM{
+						"$i": "tyk",
+					},
+				},
+			},
+			output: bson.M{
+				"name": bson.M{
+					"$not": &bson.RegEx{
+						Pattern: "^tyk$",
+						Options: "i",
+					},
+				},
+			},
+		},
+		{
+			name: "Test with _lock meta key",
+			input: model.DBM{
+				"name":  "tyk",
+				"_lock": "no_key_update",
+			},
+			output: bson.M{
+				"name": "tyk",
+			},
+		},
 		{
 			name: "Test with unsupported operator",
 			input: model.DBM{
@@ -296,3 +360,29 @@ func Test_getColName(t *testing.T) {
 		})
 	}
 }
+
+func TestPageCursor(t *testing.T) {
+	id := bson.NewObjectId()
+
+	encoded, err := encodePageCursor(pageCursor{Value: "name", ID: id})
+	if err != nil {
+		t.Fatalf("encodePageCursor() error = %v", err)
+	}
+
+	cursor, err := decodePageCursor(encoded)
+	if err != nil || !reflect.DeepEqual(cursor, pageCursor{Value: "name", ID: id}) {
+		t.Errorf("decodePageCursor() = %v, %v, want the encoded cursor", cursor, err)
+	}
+
+	want := bson.M{"$or": []bson.M{
+		{"name": bson.M{"$lt": "name"}},
+		{"name": "name", "_id": bson.M{"$lt": id}},
+	}}
+	if got := buildKeysetQuery("name", true, cursor); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildKeysetQuery() = %v, want %v", got, want)
+	}
+
+	if _, err := decodePageCursor("not a cursor"); err == nil {
+		t.Errorf("decodePageCursor() expected an error for an invalid cursor")
+	}
+}
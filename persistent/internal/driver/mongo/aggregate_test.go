@@ -0,0 +1,70 @@
+//go:build mongo7 || mongo6
+// +build mongo7 mongo6
+
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// $dateDiff is only available since MongoDB 5.0.
+func TestAggregateDateDiff(t *testing.T) {
+	ctx := context.Background()
+	driver, object := prepareEnvironment(t)
+
+	defer cleanDB(t)
+
+	err := driver.Insert(ctx, object)
+	assert.Nil(t, err)
+
+	// the creation date is taken from the _id, so it is always a few moments ago
+	createdAt := model.DBM{"$toDate": "$_id"}
+
+	tcs := []struct {
+		testName string
+		endDate  interface{}
+		unit     string
+		expected int64
+	}{
+		{
+			testName: "days between a field and now",
+			endDate:  "$$NOW",
+			unit:     "day",
+			expected: 0,
+		},
+		{
+			testName: "days between a field and a date",
+			endDate:  object.Id.Timestamp().Add(72 * time.Hour),
+			unit:     "day",
+			expected: 3,
+		},
+		{
+			testName: "hours between a field and a date",
+			endDate:  object.Id.Timestamp().Add(72 * time.Hour),
+			unit:     "hour",
+			expected: 72,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			result, err := driver.Aggregate(ctx, object, []model.DBM{
+				{
+					"$project": model.DBM{
+						"_id": 0,
+						"elapsed": model.DBM{
+							"$dateDiff": model.DBM{"startDate": createdAt, "endDate": tc.endDate, "unit": tc.unit},
+						},
+					},
+				},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, []model.DBM{{"elapsed": tc.expected}}, result)
+		})
+	}
+}
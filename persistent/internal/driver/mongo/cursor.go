@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ model.Cursor = &mongoCursor{}
+
+// mongoCursor wraps a *mongo.Cursor, keeping the context of the query it comes from
+// and the table of its rows, to decode their fields with a codec registered.
+type mongoCursor struct {
+	ctx    context.Context
+	cursor *mongo.Cursor
+	table  string
+}
+
+func (c *mongoCursor) Next() bool {
+	return c.cursor.Next(c.ctx)
+}
+
+func (c *mongoCursor) Decode(result interface{}) error {
+	if err := c.cursor.Decode(result); err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(c.table, result)
+}
+
+func (c *mongoCursor) Err() error {
+	return c.cursor.Err()
+}
+
+func (c *mongoCursor) Close() error {
+	return c.cursor.Close(c.ctx)
+}
@@ -2,19 +2,27 @@ package mongo
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
 	"github.com/TykTechnologies/storage/persistent/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/description"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/tag"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 
 	"github.com/TykTechnologies/storage/persistent/internal/types"
 )
@@ -24,6 +32,18 @@ type lifeCycle struct {
 
 	connectionString string
 	database         string
+
+	// poolInUse counts the connections of the pool of client checked out, and poolSize is its maximum size.
+	poolInUse *int64
+	poolSize  int
+
+	onEvent func(model.ConnectionEvent)
+
+	// dbType is the type of the database, detected when connecting.
+	dbType utils.DBType
+
+	// refresher reconnects before the credentials of ClientOpts.CredentialsProvider expire.
+	refresher helper.CredentialsRefresher
 }
 
 var _ types.StorageLifecycle = &lifeCycle{}
@@ -33,11 +53,19 @@ const (
 	MongoSRVPrefix = "mongodb+srv://"
 )
 
-// Connect connects to the mongo database given the ClientOpts.
-func (lc *lifeCycle) Connect(opts *types.ClientOpts) error {
-	var err error
+// Connect connects to the mongo database given the ClientOpts. The credentials of the connection string
+// are removed from the errors, which may be logged.
+func (lc *lifeCycle) Connect(opts *types.ClientOpts) (err error) {
 	var client *mongo.Client
 
+	dsn := opts.ConnectionString
+	defer func() {
+		err = utils.SanitizeError(err, dsn)
+	}()
+
+	reconnecting := lc.client != nil
+	lc.onEvent = opts.OnConnectionEvent
+
 	url, cs, err := parseURL(opts.ConnectionString)
 	if err != nil {
 		return err
@@ -50,18 +78,144 @@ func (lc *lifeCycle) Connect(opts *types.ClientOpts) error {
 		return errors.New(err.Error())
 	}
 
+	creds, err := opts.FetchCredentials()
+	if err != nil {
+		lc.notifyConnect(reconnecting, utils.SanitizeError(err, dsn))
+		return err
+	}
+
+	setCredentials(connOpts, opts, creds)
+
 	// SetRegistry allow us to marshall/unmarshall old mgo ID's structures and mgo default values.
-	connOpts.SetRegistry(createCustomRegistry().Build())
+	connOpts.SetRegistry(customRegistry)
+
+	poolInUse := new(int64)
+	connOpts.SetPoolMonitor(newPoolMonitor(poolInUse))
+	connOpts.SetServerMonitor(newServerMonitor(func(primary string) {
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionFailover, Primary: primary})
+	}))
 
 	if client, err = mongo.Connect(context.Background(), connOpts); err != nil {
+		lc.notifyConnect(reconnecting, utils.SanitizeError(err, dsn))
 		return err
 	}
 
 	lc.connectionString = opts.ConnectionString
 	lc.database = cs.db
 	lc.client = client
+	lc.poolInUse = poolInUse
+	lc.poolSize = defaultMaxPoolSize
 
-	return lc.client.Ping(context.Background(), nil)
+	if connOpts.MaxPoolSize != nil {
+		lc.poolSize = int(*connOpts.MaxPoolSize)
+	}
+
+	err = lc.client.Ping(context.Background(), nil)
+	if err == nil {
+		lc.dbType = lc.detectDBType()
+	}
+
+	if creds != nil {
+		lc.refresher.Schedule(creds.Expiration, func() error {
+			return lc.refreshCredentials(opts)
+		})
+	}
+
+	lc.notifyConnect(reconnecting, utils.SanitizeError(err, dsn))
+
+	return err
+}
+
+// refreshCredentials reconnects with new credentials, and disconnects the client of the previous ones
+// once its operations are done.
+func (lc *lifeCycle) refreshCredentials(opts *types.ClientOpts) error {
+	previous := lc.client
+
+	if err := lc.Connect(opts); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), types.DEFAULT_CONN_TIMEOUT)
+	defer cancel()
+
+	return previous.Disconnect(ctx)
+}
+
+// notifyConnect sends the event of a connection attempt. A failed first attempt isn't notified,
+// as its error is returned when creating the driver.
+func (lc *lifeCycle) notifyConnect(reconnecting bool, err error) {
+	switch {
+	case err == nil && reconnecting:
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionReconnected})
+	case err == nil:
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionConnected})
+	case reconnecting:
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionDisconnected, Err: err})
+	}
+}
+
+func (lc *lifeCycle) notify(event model.ConnectionEvent) {
+	if lc.onEvent == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	lc.onEvent(event)
+}
+
+// newServerMonitor returns a monitor of the topology calling onFailover with the address of the new primary
+// when it changes.
+func newServerMonitor(onFailover func(primary string)) *event.ServerMonitor {
+	var (
+		mu          sync.Mutex
+		lastPrimary string
+	)
+
+	return &event.ServerMonitor{
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			primary := primaryAddr(e.NewDescription)
+			if primary == "" {
+				return
+			}
+
+			mu.Lock()
+			previous := lastPrimary
+			lastPrimary = primary
+			mu.Unlock()
+
+			if previous != "" && previous != primary {
+				onFailover(primary)
+			}
+		},
+	}
+}
+
+// primaryAddr returns the address of the primary of the topology, or an empty string if it has none.
+func primaryAddr(topology description.Topology) string {
+	for _, server := range topology.Servers {
+		if server.Kind == description.RSPrimary {
+			return server.Addr.String()
+		}
+	}
+
+	return ""
+}
+
+// defaultMaxPoolSize is the maximum size of the pool set by the mongo driver when it's not configured.
+const defaultMaxPoolSize = 100
+
+// newPoolMonitor returns a monitor of the connection pool counting in inUse the connections checked out.
+func newPoolMonitor(inUse *int64) *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.GetSucceeded:
+				atomic.AddInt64(inUse, 1)
+			case event.ConnectionReturned:
+				atomic.AddInt64(inUse, -1)
+			}
+		},
+	}
 }
 
 type urlInfo struct {
@@ -176,8 +330,10 @@ func extractOptions(s string, info *urlInfo) (string, error) {
 	return s, nil
 }
 
+// extractCredentials extracts the credentials ending at the last @, so the unescaped @ of a password
+// are escaped instead of being taken as the start of the hosts.
 func extractCredentials(s string, info *urlInfo) (string, error) {
-	if c := strings.Index(s, "@"); c != -1 {
+	if c := strings.LastIndex(s, "@"); c != -1 {
 		pair := strings.SplitN(s[:c], ":", 2)
 		if len(pair) > 2 || pair[0] == "" {
 			return s, errors.New("credentials must be provided as user:pass@host")
@@ -214,15 +370,27 @@ func extractDatabase(s string, info *urlInfo) (string, error) {
 
 // Close finish the session.
 func (lc *lifeCycle) Close() error {
+	lc.refresher.Stop()
+
 	if lc.client != nil {
-		return lc.client.Disconnect(context.Background())
+		if err := lc.client.Disconnect(context.Background()); err != nil {
+			return err
+		}
+
+		lc.notify(model.ConnectionEvent{Type: model.ConnectionDisconnected})
+
+		return nil
 	}
 
 	return errors.New("closing a no connected database")
 }
 
-// DBType returns the type of the registered storage driver.
+// DBType returns the type of the registered storage driver, detected when connecting.
 func (lc *lifeCycle) DBType() utils.DBType {
+	return lc.dbType
+}
+
+func (lc *lifeCycle) detectDBType() utils.DBType {
 	if helper.IsCosmosDB(lc.connectionString) {
 		return utils.CosmosDB
 	}
@@ -247,22 +415,54 @@ func (lc *lifeCycle) DBType() utils.DBType {
 func mongoOptsBuilder(opts *types.ClientOpts) (*options.ClientOptions, error) {
 	connOpts := options.Client()
 
-	if opts.UseSSL {
+	if opts.TLSConfig().Enable {
 		tlsConfig, err := opts.GetTLSConfig()
 		if err != nil {
 			return nil, err
 		}
 
+		// DocumentDB clusters before 5.0 don't support TLS 1.3
+		if helper.IsDocumentDB(opts.ConnectionString) {
+			tlsConfig.MaxVersion = tls.VersionTLS12
+		}
+
 		connOpts.SetTLSConfig(tlsConfig)
 	}
 
+	// DocumentDB and CosmosDB don't support retryable writes, and reject the writes requesting them.
+	// They can still be enabled through the connection string.
+	if helper.IsDocumentDB(opts.ConnectionString) || helper.IsCosmosDB(opts.ConnectionString) {
+		connOpts.SetRetryWrites(false)
+	}
+
 	connOpts.SetTimeout(types.DEFAULT_CONN_TIMEOUT)
 
 	if opts.ConnectionTimeout != 0 {
 		connOpts.SetTimeout(time.Duration(opts.ConnectionTimeout) * time.Second)
 	}
 
-	connOpts.SetReadPreference(getReadPrefFromConsistency(opts.SessionConsistency))
+	if opts.ReadPreference != "" {
+		readPref, err := buildReadPref(opts.ReadPreference, opts.ReadPreferenceTags)
+		if err != nil {
+			return nil, err
+		}
+
+		connOpts.SetReadPreference(readPref)
+	} else {
+		connOpts.SetReadPreference(getReadPrefFromConsistency(opts.SessionConsistency))
+	}
+
+	if opts.MaxOpenConns > 0 {
+		connOpts.SetMaxPoolSize(uint64(opts.MaxOpenConns))
+	}
+
+	if opts.MaxIdleConns > 0 {
+		connOpts.SetMinPoolSize(uint64(opts.MaxIdleConns))
+	}
+
+	if opts.ConnMaxIdleTime > 0 {
+		connOpts.SetMaxConnIdleTime(time.Duration(opts.ConnMaxIdleTime) * time.Second)
+	}
 
 	// we apply URI here so if we specify a different configuration in the URI it can be overridden
 	connOpts.ApplyURI(opts.ConnectionString)
@@ -277,6 +477,64 @@ func mongoOptsBuilder(opts *types.ClientOpts) (*options.ClientOptions, error) {
 	return connOpts, nil
 }
 
+// setCredentials sets the credentials of the connections: the ones of the provider if any, with the mechanism,
+// the source and the mechanism properties of ClientOpts, e.g. MONGODB-AWS taking the credentials of the
+// environment if there's no provider. The settings of the connection string have precedence.
+func setCredentials(connOpts *options.ClientOptions, opts *types.ClientOpts, creds *types.Credentials) {
+	if connOpts.Auth == nil && opts.AuthMechanism == "" && opts.AuthSource == "" && creds == nil {
+		return
+	}
+
+	var auth options.Credential
+	if connOpts.Auth != nil {
+		auth = *connOpts.Auth
+	}
+
+	// the source defaults to the database of the connection string, which is wrong with a mechanism of ClientOpts
+	// such as GSSAPI, so it's only kept if it's explicitly set
+	cs, err := connstring.Parse(connOpts.GetURI())
+	sourceSet := err == nil && cs.AuthSourceSet
+
+	if auth.AuthMechanism == "" && opts.AuthMechanism != "" {
+		auth.AuthMechanism = opts.AuthMechanism
+
+		if !sourceSet {
+			auth.AuthSource = ""
+		}
+	}
+
+	if !sourceSet && opts.AuthSource != "" {
+		auth.AuthSource = opts.AuthSource
+	}
+
+	properties := make(map[string]string)
+
+	for name, value := range opts.AuthMechanismProperties {
+		properties[name] = value
+	}
+
+	for name, value := range auth.AuthMechanismProperties {
+		properties[name] = value
+	}
+
+	if creds != nil {
+		auth.Username = creds.Username
+		auth.Password = creds.Password
+		auth.PasswordSet = true
+
+		if creds.SessionToken != "" {
+			properties["AWS_SESSION_TOKEN"] = creds.SessionToken
+		}
+	}
+
+	auth.AuthMechanismProperties = nil
+	if len(properties) > 0 {
+		auth.AuthMechanismProperties = properties
+	}
+
+	connOpts.SetAuth(auth)
+}
+
 // getReadPrefFromConsistency returns the equivalent of the readPreference for session consistency
 func getReadPrefFromConsistency(consistency string) *readpref.ReadPref {
 	var mode *readpref.ReadPref
@@ -292,3 +550,18 @@ func getReadPrefFromConsistency(consistency string) *readpref.ReadPref {
 
 	return mode
 }
+
+// buildReadPref returns the read preference of the given mode name (e.g. "secondaryPreferred") and tag sets.
+func buildReadPref(mode string, tagSets []map[string]string) (*readpref.ReadPref, error) {
+	readMode, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", types.ErrUnknownReadPreference, mode)
+	}
+
+	var readPrefOpts []readpref.Option
+	if len(tagSets) > 0 {
+		readPrefOpts = append(readPrefOpts, readpref.WithTagSets(tag.NewTagSetsFromMaps(tagSets)...))
+	}
+
+	return readpref.New(readMode, readPrefOpts...)
+}
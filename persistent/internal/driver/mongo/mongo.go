@@ -3,11 +3,20 @@ package mongo
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/auth"
 
 	"github.com/TykTechnologies/storage/persistent/internal/helper"
 	"github.com/TykTechnologies/storage/persistent/internal/types"
@@ -20,6 +29,9 @@ var _ types.PersistentStorage = &mongoDriver{}
 type mongoDriver struct {
 	*lifeCycle
 	options *types.ClientOpts
+	// session is the transaction session the operations of the driver run on, set on the drivers
+	// passed to the WithTransaction callbacks.
+	session mongo.Session
 }
 
 // NewMongoDriver returns an instance of the driver official mongo connected to the database.
@@ -43,11 +55,31 @@ func NewMongoDriver(opts *types.ClientOpts) (*mongoDriver, error) {
 	return newDriver, nil
 }
 
+// tableName returns the name of the collection of row, as resolved by ClientOpts.TableNameResolver.
+func (d *mongoDriver) tableName(ctx context.Context, row model.DBObject) string {
+	return d.options.ResolveTableName(helper.TableName(ctx, row))
+}
+
 func (d *mongoDriver) Insert(ctx context.Context, rows ...model.DBObject) error {
+	ctx = d.sessionContext(ctx)
+
+	if helper.IsDryRun(ctx) {
+		rows = helper.DryRunRows(rows...)
+	}
+
+	helper.SetInsertTimestamps(rows...)
+
 	if len(rows) == 0 {
-		return errors.New(types.ErrorEmptyRow)
+		return types.ErrEmptyRow
+	}
+
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return err
 	}
 
+	defer restore()
+
 	var bulkQuery []mongo.WriteModel
 
 	for _, row := range rows {
@@ -59,376 +91,1331 @@ func (d *mongoDriver) Insert(ctx context.Context, rows ...model.DBObject) error
 		bulkQuery = append(bulkQuery, model)
 	}
 
-	collection := d.client.Database(d.database).Collection(rows[0].TableName())
-	_, err := collection.BulkWrite(ctx, bulkQuery)
-
-	return d.handleStoreError(err)
-}
-
-func (d *mongoDriver) Delete(ctx context.Context, row model.DBObject, query ...model.DBM) error {
-	if len(query) > 1 {
-		return errors.New(types.ErrorMultipleQueryForSingleRow)
-	}
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		docs, err := documents(rows...)
+		if err == nil {
+			dryRun.Record(helper.InsertCommand(d.tableName(ctx, rows[0]), docs))
+		}
 
-	if len(query) == 0 {
-		query = append(query, model.DBM{"_id": row.GetObjectID()})
+		return err
 	}
 
-	collection := d.client.Database(d.database).Collection(row.TableName())
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, rows[0]))
+	_, err = collection.BulkWrite(ctx, bulkQuery)
 
-	result, err := collection.DeleteMany(ctx, buildQuery(query[0]))
+	return d.handleStoreError(err)
+}
 
-	if err == nil && result.DeletedCount == 0 {
-		return mongo.ErrNoDocuments
+func (d *mongoDriver) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
 	}
 
-	return d.handleStoreError(err)
-}
+	ctx = d.sessionContext(ctx)
 
-func (d *mongoDriver) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
-	if len(filters) > 1 {
-		return 0, errors.New(types.ErrorMultipleDBM)
+	helper.SetInsertTimestamps(rows...)
+
+	if len(rows) == 0 {
+		return 0, types.ErrEmptyRow
 	}
 
-	filter := bson.M{}
-	if len(filters) == 1 {
-		filter = buildQuery(filters[0])
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return 0, err
 	}
 
-	collection := d.client.Database(d.database).Collection(row.TableName())
+	defer restore()
 
-	count, err := collection.CountDocuments(ctx, filter)
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, rows[0]))
+	insertOpts := options.InsertMany().SetOrdered(!opts.ContinueOnError)
 
-	return int(count), d.handleStoreError(err)
-}
+	return helper.BulkInsert(rows, opts, func(batch []model.DBObject) (int, map[int]error) {
+		documents := make([]interface{}, 0, len(batch))
 
-func (d *mongoDriver) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
-	collection := d.client.Database(d.database).Collection(row.TableName())
+		for _, row := range batch {
+			if row.GetObjectID() == "" {
+				row.SetObjectID(model.NewObjectID())
+			}
 
-	search := buildQuery(query)
+			documents = append(documents, row)
+		}
 
-	findOpts := options.Find()
-	findOneOpts := options.FindOne()
+		_, err := collection.InsertMany(ctx, documents, insertOpts)
 
-	sort, sortFound := query["_sort"].(string)
-	if sortFound && sort != "" {
-		sortQuery := buildLimitQuery(sort)
-		findOpts.SetSort(sortQuery)
-		findOneOpts.SetSort(sortQuery)
-	}
+		failed := map[int]error{}
 
-	if limit, ok := query["_limit"].(int); ok && limit > 0 {
-		findOpts.SetLimit(int64(limit))
-	}
+		var bulkErr mongo.BulkWriteException
 
-	if offset, ok := query["_offset"].(int); ok && offset > 0 {
-		findOpts.SetSkip(int64(offset))
-		findOneOpts.SetSkip(int64(offset))
-	}
+		switch {
+		case errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0:
+			for _, writeErr := range bulkErr.WriteErrors {
+				failed[writeErr.Index] = writeErr
+			}
 
-	var err error
+			if !opts.ContinueOnError {
+				// an ordered insert stops at the failed row
+				return bulkErr.WriteErrors[0].Index, failed
+			}
 
-	if helper.IsSlice(result) {
-		var cursor *mongo.Cursor
+			return len(batch) - len(failed), failed
+		case err != nil:
+			err = d.handleStoreError(err)
+			for i := range batch {
+				failed[i] = err
+			}
 
-		cursor, err = collection.Find(ctx, search, findOpts)
-		if err == nil {
-			err = cursor.All(ctx, result)
-			defer cursor.Close(ctx)
+			return 0, failed
 		}
-	} else {
-		err = collection.FindOne(ctx, search, findOneOpts).Decode(result)
-	}
 
-	return d.handleStoreError(err)
+		return len(batch), failed
+	})
 }
 
-func (d *mongoDriver) Drop(ctx context.Context, row model.DBObject) error {
-	collection := d.client.Database(d.database).Collection(row.TableName())
-
-	return d.handleStoreError(collection.Drop(ctx))
-}
+func (d *mongoDriver) Delete(ctx context.Context, row model.DBObject, query ...model.DBM) error {
+	ctx = d.sessionContext(ctx)
 
-func (d *mongoDriver) Update(ctx context.Context, row model.DBObject, query ...model.DBM) error {
 	if len(query) > 1 {
-		return errors.New(types.ErrorMultipleQueryForSingleRow)
+		return types.ErrMultipleQueryForSingleRow
 	}
 
 	if len(query) == 0 {
 		query = append(query, model.DBM{"_id": row.GetObjectID()})
 	}
 
-	collection := d.client.Database(d.database).Collection(row.TableName())
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(d.deleteCommand(ctx, row, query[0]))
+		return nil
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
 
-	result, err := collection.UpdateMany(ctx, buildQuery(query[0]), bson.D{{Key: "$set", Value: row}})
-	if err == nil && result.MatchedCount == 0 {
+	if _, ok := row.(model.SoftDeletable); ok {
+		result, err := softDelete(ctx, collection, helper.SoftDeleteFilter(row, query[0]))
+		if err == nil && result == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		return d.handleStoreError(err)
+	}
+
+	result, err := collection.DeleteMany(ctx, buildQuery(query[0]))
+
+	if err == nil && result.DeletedCount == 0 {
 		return mongo.ErrNoDocuments
 	}
 
 	return d.handleStoreError(err)
 }
 
-func (d *mongoDriver) BulkUpdate(ctx context.Context, rows []model.DBObject, query ...model.DBM) error {
-	if len(query) > 0 && len(query) != len(rows) {
-		return errors.New(types.ErrorRowQueryDiffLenght)
+// softDelete marks as deleted the rows matching query, returning how many were marked.
+func softDelete(ctx context.Context, collection *mongo.Collection, query model.DBM) (int64, error) {
+	result, err := collection.UpdateMany(ctx, buildQuery(query), softDeleteUpdate())
+	if err != nil {
+		return 0, err
 	}
 
-	if len(rows) == 0 {
-		return errors.New(types.ErrorEmptyRow)
-	}
+	return result.ModifiedCount, nil
+}
 
-	var bulkQuery []mongo.WriteModel
+// softDeleteUpdate returns the update marking rows as deleted now.
+func softDeleteUpdate() bson.M {
+	return bson.M{"$set": bson.M{model.DeletedAtField: time.Now()}}
+}
 
-	for i := range rows {
-		update := mongo.NewUpdateOneModel().SetUpdate(bson.D{{Key: "$set", Value: rows[i]}})
+// deleteCommand returns the command deleting the rows of the row table matching filter, or marking them as
+// deleted if row is a model.SoftDeletable.
+func (d *mongoDriver) deleteCommand(ctx context.Context, row model.DBObject, filter model.DBM) model.DBM {
+	if _, ok := row.(model.SoftDeletable); ok {
+		return helper.UpdateCommand(d.tableName(ctx, row), buildQuery(helper.SoftDeleteFilter(row, filter)),
+			softDeleteUpdate(), true)
+	}
 
-		if len(query) == 0 {
-			update.SetFilter(model.DBM{"_id": rows[i].GetObjectID()})
-		} else {
-			update.SetFilter(buildQuery(query[i]))
-		}
+	return helper.DeleteCommand(d.tableName(ctx, row), buildQuery(filter))
+}
 
-		bulkQuery = append(bulkQuery, update)
+func (d *mongoDriver) Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
 	}
 
-	collection := d.client.Database(d.database).Collection(rows[0].TableName())
-	result, err := collection.BulkWrite(ctx, bulkQuery)
-	if err == nil && result.MatchedCount == 0 {
-		return mongo.ErrNoDocuments
+	ctx = d.sessionContext(ctx)
+
+	if _, ok := row.(model.SoftDeletable); !ok {
+		return 0, types.ErrNotSoftDeletable
 	}
 
-	return d.handleStoreError(err)
-}
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
 
-func (d *mongoDriver) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
-	collection := d.client.Database(d.database).Collection(row.TableName())
+	deletedBefore := time.Now().Add(-olderThan)
 
-	result, err := collection.UpdateMany(ctx, buildQuery(query), buildQuery(update))
-	if err == nil && result.MatchedCount == 0 {
-		return mongo.ErrNoDocuments
+	result, err := collection.DeleteMany(ctx, bson.M{
+		model.DeletedAtField: bson.M{"$gt": time.Time{}, "$lte": deletedBefore},
+	})
+	if err != nil {
+		return 0, d.handleStoreError(err)
 	}
 
-	return d.handleStoreError(err)
+	return int(result.DeletedCount), nil
 }
 
-func (d *mongoDriver) HasTable(ctx context.Context, collection string) (bool, error) {
-	if d.client == nil {
-		return false, errors.New(types.ErrorSessionClosed)
-	}
+func (d *mongoDriver) DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error) {
+	ctx = d.sessionContext(ctx)
 
-	collections, err := d.client.Database(d.database).ListCollectionNames(ctx, bson.M{"name": collection})
+	if len(filter) == 0 {
+		filter = model.DBM{"_id": row.GetObjectID()}
+	}
 
-	return len(collections) > 0, err
-}
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(d.deleteCommand(ctx, row, filter))
+		return 0, nil
+	}
 
-func (d *mongoDriver) Ping(ctx context.Context) error {
-	return d.handleStoreError(d.client.Ping(ctx, nil))
-}
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
 
-func (d *mongoDriver) handleStoreError(err error) error {
-	if err == nil {
-		return nil
+	if _, ok := row.(model.SoftDeletable); ok {
+		deleted, err := softDelete(ctx, collection, helper.SoftDeleteFilter(row, filter))
+		return deleted, d.handleStoreError(err)
 	}
 
-	// Check for a mongo.ServerError or any of its underlying wrapped errors
-	var serverErr mongo.ServerError
-	// Check if the error is a network error
-	if mongo.IsNetworkError(err) || errors.As(err, &serverErr) {
-		// Reconnect to the MongoDB instance
-		if connErr := d.Connect(d.options); connErr != nil {
-			return errors.New(types.ErrorReconnecting + ": " + connErr.Error() + " after error: " + err.Error())
-		}
+	result, err := collection.DeleteMany(ctx, buildQuery(filter))
+	if err != nil {
+		return 0, d.handleStoreError(err)
 	}
 
-	return err
+	return result.DeletedCount, nil
 }
 
-func (d *mongoDriver) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
-	if len(index.Keys) == 0 {
-		return errors.New(types.ErrorIndexEmpty)
-	} else if len(index.Keys) > 1 && index.IsTTLIndex {
-		return errors.New(types.ErrorIndexComposedTTL)
-	}
-
-	keys := bson.D{}
+func (d *mongoDriver) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
+	ctx = d.sessionContext(ctx)
 
-	for _, key := range index.Keys {
-		builtQuery := buildQuery(key)
-		for name, val := range builtQuery {
-			keys = append(keys, bson.E{Key: name, Value: val})
-		}
+	if len(filters) > 1 {
+		return 0, types.ErrMultipleDBM
 	}
 
-	opts := options.Index()
+	query := model.DBM{}
+	if len(filters) == 1 {
+		query = filters[0]
+	}
 
-	//nolint:staticcheck
-	opts.SetBackground(index.Background)
+	query = helper.SoftDeleteFilter(row, query)
 
-	if name := index.Name; name != "" {
-		opts.SetName(name)
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.CountCommand(d.tableName(ctx, row), buildQuery(query)))
+		return 0, nil
 	}
 
-	if index.IsTTLIndex {
-		opts.SetExpireAfterSeconds(int32(index.TTL))
+	collection, err := d.readCollection(ctx, row, query)
+	if err != nil {
+		return 0, err
 	}
 
-	indexModel := mongo.IndexModel{
-		Keys:    keys,
-		Options: opts,
-	}
+	count, err := collection.CountDocuments(ctx, buildQuery(query))
 
-	collection := d.client.Database(d.database).Collection(row.TableName())
+	return int(count), d.handleStoreError(err)
+}
 
-	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+func (d *mongoDriver) CountWithOpts(ctx context.Context,
+	row model.DBObject,
+	opts model.CountOpts,
+	filters ...model.DBM,
+) (int, error) {
+	query := model.DBM{}
+	if len(filters) == 1 {
+		query = filters[0]
+	}
 
-	return d.handleStoreError(err)
-}
+	// the estimation ignores the filters, so the rows are counted when they filter any field,
+	// including the deleted_at field skipping the soft deleted rows
+	if !opts.Estimated || len(filters) > 1 || len(buildQuery(helper.SoftDeleteFilter(row, query))) > 0 {
+		return d.Count(ctx, row, filters...)
+	}
 
-func (d *mongoDriver) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
-	hasTable, err := d.HasTable(ctx, row.TableName())
+	collection, err := d.readCollection(ctx, row, query)
 	if err != nil {
-		return nil, d.handleStoreError(err)
+		return 0, err
 	}
 
-	if !hasTable {
-		return nil, errors.New(types.ErrorCollectionNotFound)
-	}
+	count, err := collection.EstimatedDocumentCount(ctx)
 
-	collection := d.client.Database(d.database).Collection(row.TableName())
+	return int(count), d.handleStoreError(err)
+}
 
-	var indexes []model.Index
+func (d *mongoDriver) Distinct(ctx context.Context,
+	row model.DBObject,
+	field string,
+	filter model.DBM,
+) ([]interface{}, error) {
+	ctx = d.sessionContext(ctx)
+	filter = helper.SoftDeleteFilter(row, filter)
 
-	indexesSpec, err := collection.Indexes().ListSpecifications(ctx)
+	collection, err := d.readCollection(ctx, row, filter)
 	if err != nil {
-		return indexes, d.handleStoreError(err)
+		return nil, err
 	}
 
-	// parse from mongo IndexSpec to our model.Index again
-	for _, thisIndex := range indexesSpec {
-		bsonKeys := bson.D{}
+	values, err := collection.Distinct(ctx, field, buildQuery(filter))
+	if err != nil {
+		return nil, d.handleStoreError(err)
+	}
 
-		if errUnmarshal := bson.Unmarshal(thisIndex.KeysDocument, &bsonKeys); err != nil {
-			return indexes, errUnmarshal
+	for i, value := range values {
+		// Parsing ids from primitive.ObjectID to model.ObjectID
+		if id, ok := value.(primitive.ObjectID); ok {
+			values[i] = model.ObjectIDHex(id.Hex())
 		}
+	}
 
-		var newKeys []model.DBM
-
-		for _, v := range bsonKeys {
-			newKey := model.DBM{}
-			newKey[v.Key] = v.Value
+	if err := helper.DecodeValues(row.TableName(), field, values); err != nil {
+		return nil, err
+	}
 
-			newKeys = append(newKeys, newKey)
-		}
+	return values, nil
+}
 
-		newIndex := model.Index{
-			Name: thisIndex.Name,
-			Keys: newKeys,
-		}
+func (d *mongoDriver) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	ctx = d.sessionContext(ctx)
+	query = helper.SoftDeleteFilter(row, query)
 
-		if TTL := thisIndex.ExpireAfterSeconds; TTL != nil {
-			newIndex.TTL = int(*TTL)
-			newIndex.IsTTLIndex = true
-		}
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.FindCommand(d.tableName(ctx, row), buildQuery(query), buildSort(query), query))
+		return nil
+	}
 
-		indexes = append(indexes, newIndex)
+	collection, err := d.readCollection(ctx, row, query)
+	if err != nil {
+		return err
 	}
 
-	return indexes, nil
-}
+	search := buildQuery(query)
 
-func (d *mongoDriver) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
-	if len(opts) > 0 && len(opts) != len(rows) {
-		return errors.New(types.ErrorRowOptDiffLenght)
-	}
+	findOpts, findOneOpts := buildFindOptions(query)
 
-	for i, row := range rows {
-		has, err := d.HasTable(ctx, row.TableName())
-		if err != nil {
-			return errors.New("error looking for table: " + err.Error())
+	if warnings, ok := query["_lenient_decode"].(*model.DecodeWarnings); ok {
+		if err := lenientQuery(ctx, collection, search, findOpts, result, warnings); err != nil {
+			return d.handleStoreError(err)
 		}
 
-		if !has {
-			var err error
+		return helper.DecodeFields(row.TableName(), result)
+	}
 
-			if len(opts) > 0 {
-				opt := buildOpt(opts[i])
-				err = d.client.Database(d.database).CreateCollection(ctx, row.TableName(), opt)
-			} else {
-				err = d.client.Database(d.database).CreateCollection(ctx, row.TableName())
-			}
+	if helper.IsSlice(result) {
+		var cursor *mongo.Cursor
 
-			if err != nil {
-				return errors.New("error creating table: " + err.Error())
-			}
+		cursor, err = collection.Find(ctx, search, findOpts)
+		if err == nil {
+			err = cursor.All(ctx, result)
+			defer cursor.Close(ctx)
 		}
+	} else {
+		err = collection.FindOne(ctx, search, findOneOpts).Decode(result)
 	}
 
-	return nil
-}
+	if err != nil {
+		return d.handleStoreError(err)
+	}
 
-func (d *mongoDriver) DropDatabase(ctx context.Context) error {
-	return d.client.Database(d.database).Drop(ctx)
+	helper.NormalizeDocuments(result, modelValue)
+
+	return helper.DecodeFields(row.TableName(), result)
 }
 
-func (d *mongoDriver) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
-	var stats model.DBM
-	err := d.client.Database(d.database).RunCommand(ctx, bson.D{
-		{Key: "collStats", Value: row.TableName()},
-	}).Decode(&stats)
+func (d *mongoDriver) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	ctx = d.sessionContext(ctx)
+	query = helper.SoftDeleteFilter(row, query)
 
-	return stats, d.handleStoreError(err)
-}
+	collection, err := d.readCollection(ctx, row, query)
+	if err != nil {
+		return nil, err
+	}
 
-func (d *mongoDriver) Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error) {
-	col := d.client.Database(d.database).Collection(row.TableName())
+	findOpts, _ := buildFindOptions(query)
 
-	cursor, err := col.Aggregate(ctx, query)
+	cursor, err := collection.Find(ctx, buildQuery(query), findOpts)
 	if err != nil {
 		return nil, d.handleStoreError(err)
 	}
 
-	defer cursor.Close(ctx)
+	return &mongoCursor{ctx: ctx, cursor: cursor, table: row.TableName()}, nil
+}
 
-	resultSlice := make([]model.DBM, 0)
+func (d *mongoDriver) ListPage(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+	page model.PageRequest,
+) (model.PageResult, error) {
+	ctx = d.sessionContext(ctx)
+	filter = helper.SoftDeleteFilter(row, filter)
+
+	collection, err := d.readCollection(ctx, row, filter)
+	if err != nil {
+		return model.PageResult{}, err
+	}
+
+	search := buildQuery(filter)
+
+	total, err := collection.CountDocuments(ctx, search)
+	if err != nil {
+		return model.PageResult{}, d.handleStoreError(err)
+	}
+
+	field, descending := helper.PageSort(page.Sort)
+	limit := helper.PageLimit(page)
+
+	if page.AfterCursor != "" {
+		after, err := decodePageCursor(page.AfterCursor)
+		if err != nil {
+			return model.PageResult{}, err
+		}
+
+		search = bson.M{"$and": bson.A{search, buildKeysetQuery(field, descending, after)}}
+	}
+
+	order := 1
+	if descending {
+		order = -1
+	}
+
+	sort := bson.D{{Key: field, Value: order}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: order})
+	}
+
+	// one more row is requested to know if there is a next page
+	findOpts := options.Find().SetSort(sort).SetLimit(int64(limit + 1))
+	if maxTime, ok := helper.GetMaxTime(filter); ok {
+		findOpts.SetMaxTime(maxTime)
+	}
+
+	cursor, err := collection.Find(ctx, search, findOpts)
+	if err != nil {
+		return model.PageResult{}, d.handleStoreError(err)
+	}
+
+	items := make([]model.DBM, 0, limit)
+	if err := cursor.All(ctx, &items); err != nil {
+		return model.PageResult{}, d.handleStoreError(err)
+	}
+
+	result := model.PageResult{Items: items, Total: int(total)}
+
+	if len(items) > limit {
+		last := items[limit-1]
+
+		result.Items = items[:limit]
+		if result.NextCursor, err = encodePageCursor(pageCursor{Value: last[field], ID: last["_id"]}); err != nil {
+			return model.PageResult{}, err
+		}
+	}
+
+	// Parsing _id from primitive.ObjectID to model.ObjectID
+	for _, item := range result.Items {
+		if id, ok := item["_id"].(primitive.ObjectID); ok {
+			item["_id"] = model.ObjectIDHex(id.Hex())
+		}
+	}
+
+	// decoded once the next cursor is built, as it's compared with the stored values
+	if err := helper.DecodeFields(row.TableName(), &result.Items); err != nil {
+		return model.PageResult{}, err
+	}
+
+	return result, nil
+}
+
+func (d *mongoDriver) SearchText(ctx context.Context,
+	row model.DBObject,
+	result interface{},
+	text string,
+	filter model.DBM,
+) error {
+	ctx = d.sessionContext(ctx)
+	filter = helper.SoftDeleteFilter(row, filter)
+
+	collection, err := d.readCollection(ctx, row, filter)
+	if err != nil {
+		return err
+	}
+
+	query := helper.TextSearchQuery(text, filter)
+	findOpts, findOneOpts := buildFindOptions(query)
+
+	// the score must be projected to sort by it on versions prior to MongoDB 4.4
+	projection := bson.D{{Key: helper.TextScoreField, Value: bson.M{"$meta": "textScore"}}}
+	if fields, ok := filter["_fields"].([]string); ok {
+		for _, field := range fields {
+			projection = append(projection, primitive.E{Key: field, Value: 1})
+		}
+	}
+
+	findOpts.SetProjection(projection)
+	findOneOpts.SetProjection(projection)
+
+	if helper.IsSlice(result) {
+		var cursor *mongo.Cursor
+
+		cursor, err = collection.Find(ctx, buildQuery(query), findOpts)
+		if err == nil {
+			err = cursor.All(ctx, result)
+			defer cursor.Close(ctx)
+		}
+	} else {
+		err = collection.FindOne(ctx, buildQuery(query), findOneOpts).Decode(result)
+	}
+
+	if err != nil {
+		return d.handleStoreError(err)
+	}
+
+	return helper.DecodeFields(row.TableName(), result)
+}
+
+func (d *mongoDriver) Watch(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+) (<-chan model.ChangeEvent, error) {
+	pipeline := mongo.Pipeline{}
+	if match := buildChangeStreamMatch(filter); len(match) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
+	}
+
+	streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if token, ok := filter["_resume_after"].(model.DBM); ok {
+		streamOpts.SetResumeAfter(token)
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	stream, err := collection.Watch(ctx, pipeline, streamOpts)
+	if err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	events := make(chan model.ChangeEvent)
+
+	go func() {
+		defer close(events)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			event := changeEvent(stream)
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			select {
+			case events <- model.ChangeEvent{Err: d.handleStoreError(err)}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// changeEvent returns the model.ChangeEvent of the current event of stream.
+func changeEvent(stream *mongo.ChangeStream) model.ChangeEvent {
+	var raw struct {
+		OperationType string `bson:"operationType"`
+		DocumentKey   struct {
+			ID model.ObjectID `bson:"_id"`
+		} `bson:"documentKey"`
+		FullDocument model.DBM `bson:"fullDocument"`
+	}
+
+	if err := stream.Decode(&raw); err != nil {
+		return model.ChangeEvent{Err: err}
+	}
+
+	var token model.DBM
+	if err := bson.Unmarshal(stream.ResumeToken(), &token); err != nil {
+		return model.ChangeEvent{Err: err}
+	}
+
+	// Parsing _id from primitive.ObjectID to model.ObjectID
+	if id, ok := raw.FullDocument["_id"].(primitive.ObjectID); ok {
+		raw.FullDocument["_id"] = model.ObjectIDHex(id.Hex())
+	}
+
+	return model.ChangeEvent{
+		Operation:   raw.OperationType,
+		ID:          raw.DocumentKey.ID,
+		Document:    raw.FullDocument,
+		ResumeToken: token,
+	}
+}
+
+// buildChangeStreamMatch returns the $match stage of a change stream for filter, whose fields refer
+// to the changed documents. The _id matches every kind of event, the other fields don't match deletes
+// since the deleted document isn't available anymore.
+func buildChangeStreamMatch(filter model.DBM) bson.M {
+	match := bson.M{}
+
+	for key, value := range buildQuery(filter) {
+		switch {
+		case key == "_id":
+			match["documentKey._id"] = value
+		case strings.HasPrefix(key, "$"):
+			match[key] = value
+		default:
+			match["fullDocument."+key] = value
+		}
+	}
+
+	return match
+}
+
+// readCollection returns the collection of row, reading from the members given by the "_read_pref" key
+// of query if set.
+func (d *mongoDriver) readCollection(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+) (*mongo.Collection, error) {
+	collOpts := options.Collection()
+
+	if mode, ok := query["_read_pref"].(string); ok {
+		readPref, err := buildReadPref(mode, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		collOpts.SetReadPreference(readPref)
+	}
+
+	return d.client.Database(d.database).Collection(d.tableName(ctx, row), collOpts), nil
+}
+
+// buildFindOptions returns the find options requested through the meta keys of the query, such as _sort or _fields.
+func buildFindOptions(query model.DBM) (*options.FindOptions, *options.FindOneOptions) {
+	findOpts := options.Find()
+	findOneOpts := options.FindOne()
+
+	if sort := buildSort(query); sort != nil {
+		findOpts.SetSort(sort)
+		findOneOpts.SetSort(sort)
+	}
+
+	if limit, ok := query["_limit"].(int); ok && limit > 0 {
+		findOpts.SetLimit(int64(limit))
+	}
+
+	if offset, ok := query["_offset"].(int); ok && offset > 0 {
+		findOpts.SetSkip(int64(offset))
+		findOneOpts.SetSkip(int64(offset))
+	}
+
+	if maxTime, ok := helper.GetMaxTime(query); ok {
+		findOpts.SetMaxTime(maxTime)
+		findOneOpts.SetMaxTime(maxTime)
+	}
+
+	if fields, ok := query["_fields"].([]string); ok && len(fields) > 0 {
+		projection := bson.D{}
+		for _, field := range fields {
+			projection = append(projection, primitive.E{Key: field, Value: 1})
+		}
+
+		findOpts.SetProjection(projection)
+		findOneOpts.SetProjection(projection)
+	}
+
+	return findOpts, findOneOpts
+}
+
+// buildSort returns the sort of the _sort key of query, or nil if it has none.
+func buildSort(query model.DBM) interface{} {
+	sort, sortFound := query["_sort"].(string)
+	if !sortFound || sort == "" {
+		return nil
+	}
+
+	return buildLimitQuery(sort)
+}
+
+func (d *mongoDriver) Drop(ctx context.Context, row model.DBObject) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	return d.handleStoreError(collection.Drop(ctx))
+}
+
+func (d *mongoDriver) Update(ctx context.Context, row model.DBObject, query ...model.DBM) error {
+	ctx = d.sessionContext(ctx)
+
+	if helper.IsDryRun(ctx) {
+		row = helper.DryRunRows(row)[0]
+	}
+
+	helper.SetUpdateTimestamps(row)
+
+	if len(query) > 1 {
+		return types.ErrMultipleQueryForSingleRow
+	}
+
+	if len(query) == 0 {
+		query = append(query, model.DBM{"_id": row.GetObjectID()})
+	}
+
+	query[0] = helper.SoftDeleteFilter(row, query[0])
+
+	restore, err := helper.EncodeFields(row)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		docs, err := documents(row)
+		if err == nil {
+			dryRun.Record(helper.UpdateCommand(d.tableName(ctx, row), buildQuery(query[0]), bson.M{"$set": docs[0]}, true))
+		}
+
+		return err
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	result, err := collection.UpdateMany(ctx, buildQuery(query[0]), bson.D{{Key: "$set", Value: row}})
+	if err == nil && result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return d.handleStoreError(err)
+}
+
+func (d *mongoDriver) BulkUpdate(ctx context.Context, rows []model.DBObject, query ...model.DBM) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	ctx = d.sessionContext(ctx)
+
+	helper.SetUpdateTimestamps(rows...)
+
+	if len(query) > 0 && len(query) != len(rows) {
+		return types.ErrRowQueryDiffLength
+	}
+
+	if len(rows) == 0 {
+		return types.ErrEmptyRow
+	}
+
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	var bulkQuery []mongo.WriteModel
+
+	for i := range rows {
+		update := mongo.NewUpdateOneModel().SetUpdate(bson.D{{Key: "$set", Value: rows[i]}})
+
+		if len(query) == 0 {
+			update.SetFilter(buildQuery(helper.SoftDeleteFilter(rows[i], model.DBM{"_id": rows[i].GetObjectID()})))
+		} else {
+			update.SetFilter(buildQuery(helper.SoftDeleteFilter(rows[i], query[i])))
+		}
+
+		bulkQuery = append(bulkQuery, update)
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, rows[0]))
+	result, err := collection.BulkWrite(ctx, bulkQuery)
+	if err == nil && result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return d.handleStoreError(err)
+}
+
+func (d *mongoDriver) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	ctx = d.sessionContext(ctx)
+	query = helper.SoftDeleteFilter(row, query)
+
+	update, err := helper.EncodeUpdate(row, helper.TimestampedUpdate(row, update))
+	if err != nil {
+		return err
+	}
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.UpdateCommand(d.tableName(ctx, row), buildQuery(query), buildQuery(update), true))
+		return nil
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	result, err := collection.UpdateMany(ctx, buildQuery(query), buildQuery(update))
+	if err == nil && result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return d.handleStoreError(err)
+}
+
+func (d *mongoDriver) HasTable(ctx context.Context, collection string) (bool, error) {
+	if d.client == nil {
+		return false, types.ErrSessionClosed
+	}
+
+	filter := bson.M{"name": d.options.ResolveTableName(collection)}
+	collections, err := d.client.Database(d.database).ListCollectionNames(ctx, filter)
+
+	return len(collections) > 0, err
+}
+
+func (d *mongoDriver) Ping(ctx context.Context) error {
+	return d.handleStoreError(d.client.Ping(ctx, nil))
+}
+
+func (d *mongoDriver) Health(ctx context.Context) model.HealthStatus {
+	status := model.HealthStatus{CheckedAt: time.Now(), PoolSize: d.poolSize}
+
+	if d.poolInUse != nil {
+		status.PoolInUse = int(atomic.LoadInt64(d.poolInUse))
+	}
+
+	var result struct {
+		IsMaster bool   `bson:"ismaster"`
+		SetName  string `bson:"setName"`
+		Primary  string `bson:"primary"`
+	}
+
+	// isMaster is used instead of hello, which isn't supported before mongo 4.4.2
+	err := d.client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&result)
+	status.Latency = time.Since(status.CheckedAt)
+
+	if err != nil {
+		status.Err = d.handleStoreError(err)
+		return status
+	}
+
+	status.Live = true
+	status.ReplicaSet = result.SetName
+	status.Primary = result.Primary
+	status.PrimaryAvailable = result.IsMaster || result.Primary != ""
+	status.Ready = status.PrimaryAvailable
+
+	return status
+}
+
+func (d *mongoDriver) handleStoreError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// Check for a mongo.ServerError or any of its underlying wrapped errors
+	var serverErr mongo.ServerError
+	// Check if the error is a network error, or an authentication error with credentials which may have changed
+	if mongo.IsNetworkError(err) || errors.As(err, &serverErr) || d.isCredentialsError(err) {
+		// Reconnect to the MongoDB instance
+		if connErr := d.Connect(d.options); connErr != nil {
+			return fmt.Errorf("%w: %s after error: %s", types.ErrReconnecting, connErr.Error(), err.Error())
+		}
+	}
+
+	return err
+}
+
+// isCredentialsError reports whether err is an authentication error, with credentials of the provider of
+// ClientOpts which are fetched again on reconnect.
+func (d *mongoDriver) isCredentialsError(err error) bool {
+	var authErr *auth.Error
+
+	return d.options.CredentialsProvider != nil && errors.As(err, &authErr)
+}
+
+func (d *mongoDriver) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	if len(index.Keys) == 0 {
+		return types.ErrIndexEmpty
+	} else if len(index.Keys) > 1 && index.IsTTLIndex {
+		return types.ErrIndexComposedTTL
+	}
+
+	capabilities := d.DBType().Capabilities()
+
+	keys := bson.D{}
+
+	for _, key := range index.Keys {
+		builtQuery := buildQuery(key)
+		for name, val := range builtQuery {
+			if index.GeoIndex {
+				val = "2dsphere"
+			}
+
+			keys = append(keys, bson.E{Key: name, Value: val})
+		}
+	}
+
+	if index.IsTTLIndex && capabilities.TTLField != "" {
+		keys = bson.D{{Key: capabilities.TTLField, Value: 1}}
+	}
+
+	opts := options.Index()
+
+	if capabilities.BackgroundIndexes {
+		//nolint:staticcheck
+		opts.SetBackground(index.Background)
+	}
+
+	if name := index.Name; name != "" {
+		opts.SetName(name)
+	}
+
+	if index.IsTTLIndex {
+		opts.SetExpireAfterSeconds(int32(index.TTL))
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    keys,
+		Options: opts,
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+
+	return d.handleStoreError(err)
+}
+
+func (d *mongoDriver) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	hasTable, err := d.HasTable(ctx, helper.TableName(ctx, row))
+	if err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	if !hasTable {
+		return nil, types.ErrCollectionNotFound
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	var indexes []model.Index
+
+	indexesSpec, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return indexes, d.handleStoreError(err)
+	}
+
+	// parse from mongo IndexSpec to our model.Index again
+	for _, thisIndex := range indexesSpec {
+		bsonKeys := bson.D{}
+
+		if errUnmarshal := bson.Unmarshal(thisIndex.KeysDocument, &bsonKeys); err != nil {
+			return indexes, errUnmarshal
+		}
+
+		var newKeys []model.DBM
+
+		for _, v := range bsonKeys {
+			newKey := model.DBM{}
+			newKey[v.Key] = v.Value
+
+			newKeys = append(newKeys, newKey)
+		}
+
+		newIndex := model.Index{
+			Name: thisIndex.Name,
+			Keys: newKeys,
+		}
+
+		if TTL := thisIndex.ExpireAfterSeconds; TTL != nil {
+			newIndex.TTL = int(*TTL)
+			newIndex.IsTTLIndex = true
+		}
+
+		indexes = append(indexes, newIndex)
+	}
+
+	return indexes, nil
+}
+
+func (d *mongoDriver) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	if len(opts) > 0 && len(opts) != len(rows) {
+		return types.ErrRowOptDiffLength
+	}
+
+	for i, row := range rows {
+		has, err := d.HasTable(ctx, helper.TableName(ctx, row))
+		if err != nil {
+			return errors.New("error looking for table: " + err.Error())
+		}
+
+		if !has {
+			var err error
+
+			if len(opts) > 0 {
+				opt := buildOpt(opts[i])
+				if !d.DBType().Capabilities().Collation {
+					opt.Collation = nil
+				}
+
+				err = d.client.Database(d.database).CreateCollection(ctx, d.tableName(ctx, row), opt)
+			} else {
+				err = d.client.Database(d.database).CreateCollection(ctx, d.tableName(ctx, row))
+			}
+
+			if err != nil {
+				return errors.New("error creating table: " + err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *mongoDriver) DropDatabase(ctx context.Context) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	return d.client.Database(d.database).Drop(ctx)
+}
+
+func (d *mongoDriver) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
+	var stats model.DBM
+	err := d.client.Database(d.database).RunCommand(ctx, bson.D{
+		{Key: "collStats", Value: d.tableName(ctx, row)},
+	}).Decode(&stats)
+
+	return stats, d.handleStoreError(err)
+}
+
+func (d *mongoDriver) Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error) {
+	ctx = d.sessionContext(ctx)
+
+	pipeline, pipelineOpts := helper.SplitPipelineOptions(query)
+	if err := d.checkPipeline(pipeline); err != nil {
+		return nil, err
+	}
+
+	pipeline = helper.SoftDeletePipeline(row, pipeline, pipelineOpts)
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.AggregateCommand(d.tableName(ctx, row), pipeline, pipelineOpts))
+		return []model.DBM{}, nil
+	}
+
+	col, err := d.readCollection(ctx, row, pipelineOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregateOpts := options.Aggregate()
+	if maxTime, ok := helper.GetMaxTime(pipelineOpts); ok {
+		aggregateOpts.SetMaxTime(maxTime)
+	}
+
+	cursor, err := col.Aggregate(ctx, pipeline, aggregateOpts)
+	if err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	defer cursor.Close(ctx)
+
+	resultSlice := make([]model.DBM, 0)
 
 	for cursor.Next(ctx) {
 		var result model.DBM
 
-		err := cursor.Decode(&result)
-		if err != nil {
-			return nil, d.handleStoreError(err)
+		err := cursor.Decode(&result)
+		if err != nil {
+			return nil, d.handleStoreError(err)
+		}
+
+		// Parsing _id from primitive.ObjectID to model.ObjectID
+		if ObjectID, ok := result["_id"].(primitive.ObjectID); ok {
+			result["_id"] = model.ObjectIDHex(ObjectID.Hex())
+		}
+
+		resultSlice = append(resultSlice, result)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	return resultSlice, nil
+}
+
+func (d *mongoDriver) Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error) {
+	return d.explain(ctx, bson.D{
+		{Key: "find", Value: d.tableName(ctx, row)},
+		{Key: "filter", Value: buildQuery(filter)},
+	})
+}
+
+func (d *mongoDriver) ExplainAggregate(ctx context.Context, row model.DBObject, query []model.DBM) (model.DBM, error) {
+	pipeline, _ := helper.SplitPipelineOptions(query)
+	if err := d.checkPipeline(pipeline); err != nil {
+		return nil, err
+	}
+
+	return d.explain(ctx, bson.D{
+		{Key: "aggregate", Value: d.tableName(ctx, row)},
+		{Key: "pipeline", Value: pipeline},
+		{Key: "cursor", Value: bson.D{}},
+	})
+}
+
+// checkPipeline returns an error if pipeline has a stage the database doesn't support.
+func (d *mongoDriver) checkPipeline(pipeline []model.DBM) error {
+	if stage, found := helper.UnsupportedStage(pipeline, d.DBType().Capabilities().UnsupportedStages); found {
+		return fmt.Errorf("%w: %s", types.ErrStageUnsupported, stage)
+	}
+
+	return nil
+}
+
+// explain runs the explain command over cmd with the executionStats verbosity, so the command is executed
+// and its statistics are reported along with the plan.
+func (d *mongoDriver) explain(ctx context.Context, cmd bson.D) (model.DBM, error) {
+	ctx = d.sessionContext(ctx)
+
+	var result model.DBM
+
+	err := d.client.Database(d.database).RunCommand(ctx, bson.D{
+		{Key: "explain", Value: cmd},
+		{Key: "verbosity", Value: "executionStats"},
+	}).Decode(&result)
+	if err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	return helper.NormalizeExplain(result), nil
+}
+
+func (d *mongoDriver) CleanIndexes(ctx context.Context, row model.DBObject) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	_, err := collection.Indexes().DropAll(ctx)
+
+	return d.handleStoreError(err)
+}
+
+func (d *mongoDriver) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	ctx = d.sessionContext(ctx)
+
+	update, err := helper.EncodeUpdate(row, helper.RevivingUpdate(row, helper.TimestampedUpdate(row, update)))
+	if err != nil {
+		return err
+	}
+
+	if dryRun, ok := model.DryRunFromContext(ctx); ok {
+		dryRun.Record(helper.UpsertCommand(d.tableName(ctx, row), buildQuery(query), update))
+		return nil
+	}
+
+	coll := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	raw, err := coll.FindOneAndUpdate(ctx, buildQuery(query), update, opts).Raw()
+	if err != nil {
+		return d.handleStoreError(err)
+	}
+
+	if err := decodeZeroed(raw, row); err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(row.TableName(), row)
+}
+
+func (d *mongoDriver) FindOneAndUpdate(ctx context.Context,
+	row model.DBObject,
+	query, update model.DBM,
+	opts ...model.FindOneOpts,
+) error {
+	if helper.IsDryRun(ctx) {
+		return types.ErrDryRunUnsupported
+	}
+
+	update, err := helper.EncodeUpdate(row, helper.TimestampedUpdate(row, update))
+	if err != nil {
+		return err
+	}
+
+	if len(opts) > 1 {
+		return types.ErrMultipleFindOneOpts
+	}
+
+	ctx = d.sessionContext(ctx)
+
+	if len(opts) == 1 && opts[0].Upsert {
+		// same as Upsert, a deleted row matching query is restored
+		update = helper.RevivingUpdate(row, update)
+	} else {
+		query = helper.SoftDeleteFilter(row, query)
+	}
+
+	coll := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	findOpts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	if len(opts) == 1 {
+		if opts[0].ReturnNew {
+			findOpts.SetReturnDocument(options.After)
 		}
 
-		// Parsing _id from primitive.ObjectID to model.ObjectID
-		if ObjectID, ok := result["_id"].(primitive.ObjectID); ok {
-			result["_id"] = model.ObjectIDHex(ObjectID.Hex())
+		if len(opts[0].Sort) > 0 {
+			findOpts.SetSort(buildLimitQuery(opts[0].Sort...))
 		}
 
-		resultSlice = append(resultSlice, result)
+		findOpts.SetUpsert(opts[0].Upsert)
+	}
+
+	raw, err := coll.FindOneAndUpdate(ctx, buildQuery(query), buildQuery(update), findOpts).Raw()
+	if err != nil {
+		// an upserted row has no previous image, same as mgo we leave row as it is
+		if errors.Is(err, mongo.ErrNoDocuments) && len(opts) == 1 && opts[0].Upsert && !opts[0].ReturnNew {
+			return nil
+		}
+
+		return d.handleStoreError(err)
+	}
+
+	if err := decodeZeroed(raw, row); err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(row.TableName(), row)
+}
+
+// decodeZeroed decodes raw into result, resetting first its struct values so the fields missing
+// from the document (e.g. removed by $unset) don't keep their previous value. Same as mgo does.
+func decodeZeroed(raw bson.Raw, result interface{}) error {
+	decoder, err := newDecoder(raw)
+	if err != nil {
+		return err
+	}
+
+	decoder.ZeroStructs()
+
+	return decoder.Decode(result)
+}
+
+// newDecoder returns a decoder of the raw document using the custom registry of the driver.
+func newDecoder(raw []byte) (*bson.Decoder, error) {
+	decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder, decoder.SetRegistry(customRegistry)
+}
+
+// lenientQuery runs the query decoding the documents field by field when they don't fit the result type,
+// so the incompatible fields are left with their zero value and reported in warnings instead of failing the query.
+func lenientQuery(ctx context.Context, col *mongo.Collection, search bson.M, findOpts *options.FindOptions,
+	result interface{}, warnings *model.DecodeWarnings,
+) error {
+	isSlice := helper.IsSlice(result)
+	if !isSlice {
+		findOpts.SetLimit(1)
+	}
+
+	cursor, err := col.Find(ctx, search, findOpts)
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close(ctx)
+
+	resultValue := reflect.ValueOf(result).Elem()
+	if isSlice {
+		resultValue.Set(reflect.MakeSlice(resultValue.Type(), 0, 0))
+	}
+
+	found := false
+
+	for cursor.Next(ctx) {
+		found = true
+
+		if !isSlice {
+			lenientDecode(cursor.Current, result, warnings)
+			continue
+		}
+
+		elem := reflect.New(resultValue.Type().Elem())
+		lenientDecode(cursor.Current, elem.Interface(), warnings)
+		resultValue.Set(reflect.Append(resultValue, elem.Elem()))
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, d.handleStoreError(err)
+		return err
 	}
 
-	return resultSlice, nil
+	if !isSlice && !found {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
 }
 
-func (d *mongoDriver) CleanIndexes(ctx context.Context, row model.DBObject) error {
-	collection := d.client.Database(d.database).Collection(row.TableName())
+// lenientDecode decodes raw into result. If the document doesn't fit, it's decoded again field by field
+// and the fields that fail are added to warnings.
+func lenientDecode(raw bson.Raw, result interface{}, warnings *model.DecodeWarnings) {
+	if err := decodeZeroed(raw, result); err == nil {
+		return
+	}
 
-	_, err := collection.Indexes().DropAll(ctx)
+	var id model.ObjectID
+	if oid, ok := raw.Lookup("_id").ObjectIDOK(); ok {
+		id = model.ObjectIDHex(oid.Hex())
+	}
 
-	return d.handleStoreError(err)
+	// discard whatever the failed decode could have set
+	value := reflect.ValueOf(result).Elem()
+	value.Set(reflect.Zero(value.Type()))
+
+	elements, err := raw.Elements()
+	if err != nil {
+		warnings.Add(id, "", err)
+		return
+	}
+
+	for _, element := range elements {
+		if err := decodeElement(element, result); err != nil {
+			warnings.Add(id, element.Key(), err)
+		}
+	}
 }
 
-func (d *mongoDriver) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
-	coll := d.client.Database(d.database).Collection(row.TableName())
+// decodeElement decodes a single element of a document into result, keeping its other fields.
+func decodeElement(element bson.RawElement, result interface{}) error {
+	field, err := bson.Marshal(bson.D{{Key: element.Key(), Value: element.Value()}})
+	if err != nil {
+		return err
+	}
 
-	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
-	err := coll.FindOneAndUpdate(ctx, query, update, opts).Decode(row)
+	decoder, err := newDecoder(field)
+	if err != nil {
+		return err
+	}
 
-	return d.handleStoreError(err)
+	return decoder.Decode(result)
 }
 
 func (d *mongoDriver) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
@@ -437,6 +1424,7 @@ func (d *mongoDriver) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
 	database := d.client.Database("admin")
 	err := database.RunCommand(context.Background(), bson.D{primitive.E{Key: "buildInfo", Value: 1}}).Decode(&result)
 	result.Type = d.lifeCycle.DBType()
+	result.Capabilities = result.Type.Capabilities()
 
 	return result, d.handleStoreError(err)
 }
@@ -446,6 +1434,12 @@ func (d *mongoDriver) GetTables(ctx context.Context) ([]string, error) {
 }
 
 func (d *mongoDriver) DropTable(ctx context.Context, collectionName string) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
+	}
+
+	collectionName = d.options.ResolveTableName(collectionName)
+
 	deleteResult, err := d.client.Database(d.database).Collection(collectionName).DeleteMany(ctx, bson.M{})
 	if err != nil {
 		return 0, err
@@ -453,3 +1447,330 @@ func (d *mongoDriver) DropTable(ctx context.Context, collectionName string) (int
 
 	return int(deleteResult.DeletedCount), d.client.Database(d.database).Collection(collectionName).Drop(ctx)
 }
+
+func (d *mongoDriver) ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error) {
+	return d.Export(ctx, row, query, w, model.NDJSON)
+}
+
+func (d *mongoDriver) Export(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+	w io.Writer,
+	format model.ExportFormat,
+) (int, error) {
+	ctx = d.sessionContext(ctx)
+
+	fields, _ := query["_fields"].([]string)
+
+	encoder, err := helper.NewExportEncoder(w, format, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	findOpts, _ := buildFindOptions(query)
+
+	cursor, err := collection.Find(ctx, buildQuery(query), findOpts)
+	if err != nil {
+		return 0, d.handleStoreError(err)
+	}
+
+	defer cursor.Close(ctx)
+
+	exported := 0
+
+	for cursor.Next(ctx) {
+		var document model.DBM
+
+		if err := cursor.Decode(&document); err != nil {
+			return exported, d.handleStoreError(err)
+		}
+
+		// Parsing _id from primitive.ObjectID to model.ObjectID
+		if ObjectID, ok := document["_id"].(primitive.ObjectID); ok {
+			document["_id"] = model.ObjectIDHex(ObjectID.Hex())
+		}
+
+		if err := helper.DecodeFields(row.TableName(), &document); err != nil {
+			return exported, err
+		}
+
+		if err := encoder.Encode(document); err != nil {
+			return exported, err
+		}
+
+		exported++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return exported, d.handleStoreError(err)
+	}
+
+	return exported, encoder.Flush()
+}
+
+func (d *mongoDriver) ImportNDJSON(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	opts ...model.DBM,
+) (int, error) {
+	return d.Import(ctx, row, r, model.NDJSON, opts...)
+}
+
+func (d *mongoDriver) Import(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	format model.ExportFormat,
+	opts ...model.DBM,
+) (int, error) {
+	if helper.IsDryRun(ctx) {
+		return 0, types.ErrDryRunUnsupported
+	}
+
+	ctx = d.sessionContext(ctx)
+
+	importRows, err := helper.NewImporter(format)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(opts) > 1 {
+		return 0, types.ErrMultipleDBM
+	}
+
+	importOpts := model.DBM{}
+	if len(opts) == 1 {
+		importOpts = opts[0]
+	}
+
+	upsert, batchSize := helper.ImportOptions(importOpts)
+	collection := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	return importRows(r, batchSize, func(documents []model.DBM) (int, map[int]error) {
+		failed := map[int]error{}
+
+		var bulkQuery []mongo.WriteModel
+		// position of each write model in documents
+		var positions []int
+
+		for i, document := range documents {
+			newRow, err := decodeDocument(row, document)
+			if err != nil {
+				failed[i] = err
+				continue
+			}
+
+			// the rows are only stored, so their encoded fields aren't restored
+			if _, err := helper.EncodeFields(newRow); err != nil {
+				failed[i] = err
+				continue
+			}
+
+			if newRow.GetObjectID() == "" {
+				newRow.SetObjectID(model.NewObjectID())
+			}
+
+			if upsert {
+				bulkQuery = append(bulkQuery, mongo.NewReplaceOneModel().
+					SetFilter(bson.M{"_id": newRow.GetObjectID()}).
+					SetReplacement(newRow).
+					SetUpsert(true))
+			} else {
+				bulkQuery = append(bulkQuery, mongo.NewInsertOneModel().SetDocument(newRow))
+			}
+
+			positions = append(positions, i)
+		}
+
+		if len(bulkQuery) == 0 {
+			return 0, failed
+		}
+
+		imported := len(bulkQuery)
+
+		_, err := collection.BulkWrite(ctx, bulkQuery, options.BulkWrite().SetOrdered(false))
+
+		var bulkErr mongo.BulkWriteException
+
+		switch {
+		case errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0:
+			for _, writeErr := range bulkErr.WriteErrors {
+				failed[positions[writeErr.Index]] = writeErr
+				imported--
+			}
+		case err != nil:
+			err = d.handleStoreError(err)
+			for _, position := range positions {
+				failed[position] = err
+			}
+
+			imported = 0
+		}
+
+		return imported, failed
+	})
+}
+
+// decodeDocument converts document into a new model.DBObject of the same type as row, following its bson tags.
+func decodeDocument(row model.DBObject, document model.DBM) (model.DBObject, error) {
+	newRow, err := helper.NewDBObject(row)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := bson.MarshalWithRegistry(customRegistry, document)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bson.UnmarshalWithRegistry(customRegistry, data, newRow); err != nil {
+		return nil, err
+	}
+
+	return newRow, nil
+}
+
+// documents returns the rows as the documents sent to the database, for the dry-run mode.
+func documents(rows ...model.DBObject) ([]model.DBM, error) {
+	docs := make([]model.DBM, 0, len(rows))
+
+	for _, row := range rows {
+		data, err := bson.MarshalWithRegistry(customRegistry, row)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc model.DBM
+		if err := bson.UnmarshalWithRegistry(customRegistry, data, &doc); err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+func (d *mongoDriver) SessionSettings(ctx context.Context) (model.DBM, error) {
+	if d.client == nil {
+		return nil, types.ErrSessionClosed
+	}
+
+	db := d.client.Database(d.database)
+
+	settings := model.DBM{
+		"database":       d.database,
+		"readPreference": readpref.PrimaryMode.String(),
+		"readConcern":    "",
+	}
+
+	if readPref := db.ReadPreference(); readPref != nil {
+		settings["readPreference"] = readPref.Mode().String()
+	}
+
+	if readConcern := db.ReadConcern(); readConcern != nil {
+		settings["readConcern"] = readConcern.Level
+	}
+
+	writeConcern := model.DBM{}
+
+	if wc := db.WriteConcern(); wc != nil {
+		if wc.W != nil {
+			writeConcern["w"] = wc.W
+		}
+
+		if wc.Journal != nil {
+			writeConcern["j"] = *wc.Journal
+		}
+
+		if wc.WTimeout > 0 {
+			writeConcern["wtimeout"] = wc.WTimeout
+		}
+	}
+
+	settings["writeConcern"] = writeConcern
+
+	return settings, nil
+}
+
+func (d *mongoDriver) ExistingIDs(ctx context.Context,
+	row model.DBObject,
+	ids []model.ObjectID,
+) ([]model.ObjectID, error) {
+	ctx = d.sessionContext(ctx)
+
+	existing := make([]model.ObjectID, 0)
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	col := d.client.Database(d.database).Collection(d.tableName(ctx, row))
+
+	// the soft deleted rows aren't skipped, as their ids are still taken
+	cursor, err := col.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	defer cursor.Close(ctx)
+
+	found := make(map[model.ObjectID]bool)
+
+	for cursor.Next(ctx) {
+		var result struct {
+			ID model.ObjectID `bson:"_id"`
+		}
+
+		if err := cursor.Decode(&result); err != nil {
+			return nil, d.handleStoreError(err)
+		}
+
+		found[result.ID] = true
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, d.handleStoreError(err)
+	}
+
+	for _, id := range ids {
+		if found[id] {
+			existing = append(existing, id)
+			// only report duplicated ids once
+			delete(found, id)
+		}
+	}
+
+	return existing, nil
+}
+
+func (d *mongoDriver) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	// nested transactions aren't supported by mongo, so they join the running one
+	if d.session != nil {
+		return fn(d)
+	}
+
+	session, err := d.client.StartSession()
+	if err != nil {
+		return d.handleStoreError(err)
+	}
+
+	defer session.EndSession(ctx)
+
+	tx := &mongoDriver{lifeCycle: d.lifeCycle, options: d.options, session: session}
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(tx)
+	})
+
+	return d.handleStoreError(err)
+}
+
+// sessionContext binds ctx to the transaction session of the driver, if any, so the operations join the transaction.
+func (d *mongoDriver) sessionContext(ctx context.Context) context.Context {
+	if d.session == nil {
+		return ctx
+	}
+
+	return mongo.NewSessionContext(ctx, d.session)
+}
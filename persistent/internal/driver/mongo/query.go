@@ -1,11 +1,14 @@
 package mongo
 
 import (
+	"encoding/base64"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
 
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
 	"github.com/TykTechnologies/storage/persistent/model"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -57,14 +60,7 @@ func handleQueryValue(key string, value interface{}, search bson.M) {
 		strSlice, isStrSlice := value.([]string)
 
 		if isStrSlice && key == "_id" {
-			ObjectIDs := []model.ObjectID{}
-			for _, str := range strSlice {
-				if primitive.IsValidObjectID(str) {
-					ObjectIDs = append(ObjectIDs, model.ObjectIDHex(str))
-				}
-			}
-
-			search[key] = bson.M{"$in": ObjectIDs}
+			search[key] = bson.M{"$in": helper.ParseIDs(strSlice)}
 
 			return
 		}
@@ -100,6 +96,13 @@ func handleNestedQuery(search bson.M, key string, value interface{}) {
 			if stringValue, ok := nestedValue.(string); ok {
 				search[key] = bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(stringValue), Options: "i"}}
 			}
+		case "$not":
+			// the operator negated at field level is translated as well, e.g. {"$not": {"$i": "tyk"}}
+			negated := bson.M{}
+			handleQueryValue(key, nestedValue, negated)
+			nestedValue = negated[key]
+
+			fallthrough
 		default:
 			if v, ok := search[key]; !ok {
 				search[key] = bson.M{nestedKey: nestedValue}
@@ -120,7 +123,9 @@ func buildQuery(query model.DBM) bson.M {
 
 	for key, value := range query {
 		switch key {
-		case "_sort", "_collection", "_limit", "_offset", "_date_sharding":
+		case "_sort", "_collection", "_limit", "_offset", "_date_sharding",
+			"_max_time", "_max_time_ms", "_lock", "_lenient_decode", "_read_pref", "_fields",
+			"_resume_after", "_with_deleted":
 			continue
 		case "_id":
 			if id, ok := value.(model.ObjectID); ok {
@@ -136,3 +141,50 @@ func buildQuery(query model.DBM) bson.M {
 
 	return search
 }
+
+// pageCursor is the position of the last row of a page: its value of the sort field and its _id.
+type pageCursor struct {
+	Value interface{} `bson:"v"`
+	ID    interface{} `bson:"id"`
+}
+
+func encodePageCursor(cursor pageCursor) (string, error) {
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageCursor(encoded string) (pageCursor, error) {
+	var cursor pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, types.ErrInvalidPageCursor
+	}
+
+	if err := bson.Unmarshal(data, &cursor); err != nil {
+		return cursor, types.ErrInvalidPageCursor
+	}
+
+	return cursor, nil
+}
+
+// buildKeysetQuery returns the query of the rows following the after cursor, sorted by field and then _id.
+func buildKeysetQuery(field string, descending bool, after pageCursor) bson.M {
+	operator := "$gt"
+	if descending {
+		operator = "$lt"
+	}
+
+	if field == "_id" {
+		return bson.M{"_id": bson.M{operator: after.ID}}
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{operator: after.Value}},
+		bson.M{field: after.Value, "_id": bson.M{operator: after.ID}},
+	}}
+}
@@ -137,6 +137,70 @@ func TestBuildQuery(t *testing.T) {
 				},
 			},
 		},
+		{
+			testName: "Test with field level $not",
+			input: model.DBM{
+				"age": model.DBM{
+					"$not": model.DBM{
+						"$gt": 30,
+					},
+				},
+			},
+			output: bson.M{
+				"age": bson.M{
+					"$not": bson.M{
+						"$gt": 30,
+					},
+				},
+			},
+		},
+		{
+			testName: "Test with field level $not and other operators",
+			input: model.DBM{
+				"age": model.DBM{
+					"$not": model.DBM{
+						"$gt": 30,
+					},
+					"$ne": 10,
+				},
+			},
+			output: bson.M{
+				"age": bson.M{
+					"$not": bson.M{
+						"$gt": 30,
+					},
+					"$ne": 10,
+				},
+			},
+		},
+		{
+			testName: "Test with field level $not wrapping $i",
+			input: model.DBM{
+				"name": model.DBM{
+					"$not": model.DBM{
+						"$i": "tyk",
+					},
+				},
+			},
+			output: bson.M{
+				"name": bson.M{
+					"$not": &primitive.Regex{
+						Pattern: "^tyk$",
+						Options: "i",
+					},
+				},
+			},
+		},
+		{
+			testName: "Test with _lock meta key",
+			input: model.DBM{
+				"name":  "tyk",
+				"_lock": "no_key_update",
+			},
+			output: bson.M{
+				"name": "tyk",
+			},
+		},
 		{
 			testName: "Test with unsupported operator",
 			input: model.DBM{
@@ -305,3 +369,23 @@ func TestBuildLimitQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestPageCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	encoded, err := encodePageCursor(pageCursor{Value: "name", ID: id})
+	assert.Nil(t, err)
+
+	cursor, err := decodePageCursor(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, pageCursor{Value: "name", ID: id}, cursor)
+
+	assert.Equal(t, bson.M{"_id": bson.M{"$lt": id}}, buildKeysetQuery("_id", true, cursor))
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"name": bson.M{"$gt": "name"}},
+		bson.M{"name": "name", "_id": bson.M{"$gt": id}},
+	}}, buildKeysetQuery("name", false, cursor))
+
+	_, err = decodePageCursor("not a cursor")
+	assert.NotNil(t, err)
+}
@@ -1,13 +1,16 @@
 package mongo
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/TykTechnologies/storage/persistent/model"
 	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 	"go.mongodb.org/mongo-driver/bson/bsonoptions"
 	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/mgocompat"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -15,6 +18,15 @@ import (
 // tOID is the type of model.ObjectID
 var tOID = reflect.TypeOf(model.NewObjectID())
 
+// tUUID is the type of model.UUID
+var tUUID = reflect.TypeOf(model.UUID{})
+
+// tUUIDPointer is the type of *model.UUID
+var tUUIDPointer = reflect.PtrTo(tUUID)
+
+// uuidSubtype is the bson binary subtype of the UUIDs.
+const uuidSubtype = 0x04
+
 // toTime is the type of golang time.Time
 var toTime = reflect.TypeOf(time.Time{})
 
@@ -50,8 +62,128 @@ func ObjectIDDecodeValue(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val
 	return nil
 }
 
+// UUIDEncodeValue encodes model.UUID as a binary of the UUID subtype
+func UUIDEncodeValue(ec bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != tUUID {
+		return bsoncodec.ValueEncoderError{Name: "UUIDEncodeValue", Types: []reflect.Type{tUUID}, Received: val}
+	}
+
+	u := val.Interface().(model.UUID)
+
+	return vw.WriteBinaryWithSubtype(u[:], uuidSubtype)
+}
+
+// UUIDPointerEncodeValue encodes *model.UUID as UUIDEncodeValue, or as null if it's nil
+func UUIDPointerEncodeValue(ec bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != tUUIDPointer {
+		return bsoncodec.ValueEncoderError{
+			Name:     "UUIDPointerEncodeValue",
+			Types:    []reflect.Type{tUUIDPointer},
+			Received: val,
+		}
+	}
+
+	if val.IsNil() {
+		return vw.WriteNull()
+	}
+
+	return UUIDEncodeValue(ec, vw, val.Elem())
+}
+
+// UUIDDecodeValue decodes a binary of 16 bytes into model.UUID
+func UUIDDecodeValue(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	data, _, err := vr.ReadBinary()
+	if err != nil {
+		return err
+	}
+
+	var u model.UUID
+	if len(data) != len(u) {
+		return fmt.Errorf("cannot decode a binary of %d bytes into a model.UUID", len(data))
+	}
+
+	copy(u[:], data)
+
+	if val.CanSet() {
+		val.Set(reflect.ValueOf(u))
+	}
+
+	return nil
+}
+
+// UUIDPointerDecodeValue decodes a binary of 16 bytes into *model.UUID, or null into a nil pointer
+func UUIDPointerDecodeValue(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if vr.Type() == bsontype.Null {
+		if val.CanSet() {
+			val.Set(reflect.Zero(tUUIDPointer))
+		}
+
+		return vr.ReadNull()
+	}
+
+	u := reflect.New(tUUID)
+	if err := UUIDDecodeValue(dc, vr, u.Elem()); err != nil {
+		return err
+	}
+
+	if val.CanSet() {
+		val.Set(u)
+	}
+
+	return nil
+}
+
+// modelValue returns the ObjectIDs and the UUIDs decoded into a model.DBM, which the registry decodes as
+// primitive.ObjectID and primitive.Binary, as model.ObjectID and model.UUID.
+func modelValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return model.ObjectIDHex(v.Hex())
+	case primitive.Binary:
+		var u model.UUID
+		if v.Subtype == uuidSubtype && len(v.Data) == len(u) {
+			copy(u[:], v.Data)
+
+			return u
+		}
+	}
+
+	return value
+}
+
+// structTagParser parses the struct tags as model.FieldName names the fields: the storage tag has precedence
+// over the bson tag, which has precedence over the json tag. The options of the tags are kept.
+var structTagParser bsoncodec.StructTagParserFunc = func(sf reflect.StructField) (bsoncodec.StructTags, error) {
+	if tag, ok := sf.Tag.Lookup(model.StorageTag); ok {
+		sf.Tag = reflect.StructTag("bson:" + strconv.Quote(tag))
+	}
+
+	return bsoncodec.JSONFallbackStructTagParser(sf)
+}
+
+// structCodec is the struct codec of mgocompat, with structTagParser.
+var structCodec = newStructCodec()
+
+func newStructCodec() *bsoncodec.StructCodec {
+	codec, err := bsoncodec.NewStructCodec(structTagParser,
+		bsonoptions.StructCodec().
+			SetDecodeZeroStruct(true).
+			SetEncodeOmitDefaultStruct(true).
+			SetOverwriteDuplicatedInlinedFields(false).
+			SetAllowUnexportedFields(true))
+	if err != nil {
+		// only fails without a parser
+		panic(err)
+	}
+
+	return codec
+}
+
+// customRegistry is the *bsoncodec.Registry used by our lifeCycle mongo's client.
+var customRegistry = createCustomRegistry().Build()
+
 // createCustomRegistry creates a *bsoncodec.RegistryBuilder for our lifeCycle mongo's client using  ObjectIDDecodeValue
-// and ObjectIDEncodeValue as Type Encoder/Decoders for model.ObjectID and time.Time
+// and ObjectIDEncodeValue as Type Encoder/Decoders for model.ObjectID and time.Time, along with the ones of model.UUID
 func createCustomRegistry() *bsoncodec.RegistryBuilder {
 	// using mgocompat registry as base type registry
 	rb := mgocompat.NewRegistryBuilder()
@@ -60,6 +192,16 @@ func createCustomRegistry() *bsoncodec.RegistryBuilder {
 	rb.RegisterTypeEncoder(tOID, bsoncodec.ValueEncoderFunc(ObjectIDEncodeValue))
 	rb.RegisterTypeDecoder(tOID, bsoncodec.ValueDecoderFunc(ObjectIDDecodeValue))
 
+	// set the model.UUID encoders/decoders, which take precedence over its mgo GetBSON and SetBSON methods
+	rb.RegisterTypeEncoder(tUUID, bsoncodec.ValueEncoderFunc(UUIDEncodeValue))
+	rb.RegisterTypeDecoder(tUUID, bsoncodec.ValueDecoderFunc(UUIDDecodeValue))
+	rb.RegisterTypeEncoder(tUUIDPointer, bsoncodec.ValueEncoderFunc(UUIDPointerEncodeValue))
+	rb.RegisterTypeDecoder(tUUIDPointer, bsoncodec.ValueDecoderFunc(UUIDPointerDecodeValue))
+
+	// name the fields of the structs as model.FieldName does
+	rb.RegisterDefaultEncoder(reflect.Struct, structCodec)
+	rb.RegisterDefaultDecoder(reflect.Struct, structCodec)
+
 	// we set the default behavior to use local time zone - the same as mgo does internally.
 	UseLocalTimeZone := true
 	opts := &bsonoptions.TimeCodecOptions{UseLocalTimeZone: &UseLocalTimeZone}
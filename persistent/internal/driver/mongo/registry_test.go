@@ -12,6 +12,8 @@ import (
 
 	"github.com/TykTechnologies/storage/persistent/model"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestCreateCustomRegistry(t *testing.T) {
@@ -28,6 +30,63 @@ func TestCreateCustomRegistry(t *testing.T) {
 	assert.NotNil(t, decoder)
 }
 
+func TestUUIDCodec(t *testing.T) {
+	type row struct {
+		ID    model.UUID  `bson:"_id"`
+		Other *model.UUID `bson:"other"`
+	}
+
+	u, other := model.NewUUID(), model.NewUUID()
+
+	data, err := bson.MarshalWithRegistry(customRegistry, row{ID: u, Other: &other})
+	assert.Nil(t, err)
+
+	subtype, binary := bson.Raw(data).Lookup("_id").Binary()
+	assert.Equal(t, byte(uuidSubtype), subtype)
+	assert.Equal(t, u[:], binary)
+
+	var decoded row
+	assert.Nil(t, bson.UnmarshalWithRegistry(customRegistry, data, &decoded))
+	assert.Equal(t, u, decoded.ID)
+	assert.Equal(t, other, *decoded.Other)
+
+	data, err = bson.MarshalWithRegistry(customRegistry, row{ID: u})
+	assert.Nil(t, err)
+	assert.Nil(t, bson.UnmarshalWithRegistry(customRegistry, data, &decoded))
+	assert.Nil(t, decoded.Other)
+}
+
+func TestStructTags(t *testing.T) {
+	type row struct {
+		APIID   string `storage:"api_id" json:"apiId"`
+		OrgID   string `bson:"org_id,omitempty" json:"orgId"`
+		Path    string `json:"path"`
+		Ignored string `storage:"-" bson:"ignored"`
+		Method  string
+	}
+
+	data, err := bson.MarshalWithRegistry(customRegistry, row{APIID: "api", Path: "/", Ignored: "x", Method: "GET"})
+	assert.Nil(t, err)
+
+	var doc bson.M
+	assert.Nil(t, bson.Unmarshal(data, &doc))
+	assert.Equal(t, bson.M{"api_id": "api", "path": "/", "method": "GET"}, doc)
+
+	var decoded row
+	assert.Nil(t, bson.UnmarshalWithRegistry(customRegistry, data, &decoded))
+	assert.Equal(t, row{APIID: "api", Path: "/", Method: "GET"}, decoded)
+}
+
+func TestModelValue(t *testing.T) {
+	oid := primitive.NewObjectID()
+	u := model.NewUUID()
+
+	assert.Equal(t, model.ObjectIDHex(oid.Hex()), modelValue(oid))
+	assert.Equal(t, u, modelValue(primitive.Binary{Subtype: uuidSubtype, Data: u[:]}))
+	assert.Equal(t, primitive.Binary{Data: u[:]}, modelValue(primitive.Binary{Data: u[:]}))
+	assert.Equal(t, "value", modelValue("value"))
+}
+
 type testStruct struct {
 	Id                model.ObjectID
 	MapVal            map[string]interface{}
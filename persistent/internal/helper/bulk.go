@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+const (
+	defaultBulkBatchSize = 1000
+	errorBulkInsert      = "error inserting rows"
+)
+
+// BulkInsert splits rows in batches of opts.BatchSize rows and inserts them calling insertBatch, which returns the
+// number of inserted rows and the errors of the failed ones, keyed by their index in the batch. Unless
+// opts.ContinueOnError is set, no more batches are inserted after a failure. The errors are aggregated in the
+// returned error along with the index of the row they refer to.
+func BulkInsert(rows []model.DBObject,
+	opts model.BulkOpts,
+	insertBatch func(batch []model.DBObject) (int, map[int]error),
+) (int, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBulkBatchSize
+	}
+
+	rowErrors := map[int]error{}
+	inserted := 0
+
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		n, failed := insertBatch(rows[start:end])
+		inserted += n
+
+		for i, err := range failed {
+			rowErrors[start+i] = err
+		}
+
+		if len(failed) > 0 && !opts.ContinueOnError {
+			break
+		}
+	}
+
+	return inserted, aggregateErrors(errorBulkInsert, "row", rowErrors)
+}
@@ -0,0 +1,86 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkInsert(t *testing.T) {
+	rows := make([]model.DBObject, 5)
+	for i := range rows {
+		rows[i] = &dummyDBObject{}
+	}
+
+	tcs := []struct {
+		testName         string
+		opts             model.BulkOpts
+		failedRows       map[int]bool
+		expectedBatches  []int
+		expectedInserted int
+		expectedErr      string
+	}{
+		{
+			testName:         "default batch size",
+			opts:             model.BulkOpts{},
+			expectedBatches:  []int{5},
+			expectedInserted: 5,
+		},
+		{
+			testName:         "custom batch size",
+			opts:             model.BulkOpts{BatchSize: 2},
+			expectedBatches:  []int{2, 2, 1},
+			expectedInserted: 5,
+		},
+		{
+			testName:         "stop on error",
+			opts:             model.BulkOpts{BatchSize: 2},
+			failedRows:       map[int]bool{1: true, 3: true},
+			expectedBatches:  []int{2},
+			expectedInserted: 1,
+			expectedErr:      "error inserting rows: row 1: failed",
+		},
+		{
+			testName:         "continue on error",
+			opts:             model.BulkOpts{BatchSize: 2, ContinueOnError: true},
+			failedRows:       map[int]bool{1: true, 3: true},
+			expectedBatches:  []int{2, 2, 1},
+			expectedInserted: 3,
+			expectedErr:      "error inserting rows: row 1: failed; row 3: failed",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			var batches []int
+
+			offset := 0
+
+			inserted, err := BulkInsert(rows, tc.opts, func(batch []model.DBObject) (int, map[int]error) {
+				batches = append(batches, len(batch))
+
+				failed := map[int]error{}
+				for i := range batch {
+					if tc.failedRows[offset+i] {
+						failed[i] = errors.New("failed")
+					}
+				}
+
+				offset += len(batch)
+
+				return len(batch) - len(failed), failed
+			})
+
+			assert.Equal(t, tc.expectedBatches, batches)
+			assert.Equal(t, tc.expectedInserted, inserted)
+
+			if tc.expectedErr == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package helper
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// ErrorFieldCodecNotFound is returned when storing a field tagged with `encrypt:"true"` without a codec registered.
+// It's declared here instead of in types, as types depends on this package.
+const ErrorFieldCodecNotFound = "no codec registered for encrypted field"
+
+var fieldCodecs = struct {
+	sync.RWMutex
+	tables map[string]map[string]model.FieldCodec
+}{tables: map[string]map[string]model.FieldCodec{}}
+
+// RegisterFieldCodec sets the codec of the given field of the rows of table, identified by its bson name.
+// A nil codec removes the registered one.
+func RegisterFieldCodec(table, field string, codec model.FieldCodec) {
+	fieldCodecs.Lock()
+	defer fieldCodecs.Unlock()
+
+	if codec == nil {
+		delete(fieldCodecs.tables[table], field)
+		return
+	}
+
+	if fieldCodecs.tables[table] == nil {
+		fieldCodecs.tables[table] = map[string]model.FieldCodec{}
+	}
+
+	fieldCodecs.tables[table][field] = codec
+}
+
+func tableCodecs(table string) map[string]model.FieldCodec {
+	fieldCodecs.RLock()
+	defer fieldCodecs.RUnlock()
+
+	return fieldCodecs.tables[table]
+}
+
+// EncodeFields encodes in place the string fields of rows with a codec registered for their table,
+// returning the function that restores their original values once they are stored.
+// It fails if a field tagged with `encrypt:"true"` has no codec registered, so it's never stored in plain text.
+func EncodeFields(rows ...model.DBObject) (func(), error) {
+	var restores []func()
+
+	restore := func() {
+		for _, r := range restores {
+			r()
+		}
+	}
+
+	for _, row := range rows {
+		codecs := tableCodecs(row.TableName())
+
+		err := forEachStringField(row, func(name string, encrypted bool, value reflect.Value) error {
+			codec, ok := codecs[name]
+			if !ok {
+				if encrypted {
+					return errors.New(ErrorFieldCodecNotFound + ": " + row.TableName() + "." + name)
+				}
+
+				return nil
+			}
+
+			original := value.String()
+
+			encoded, err := codec.Encode(original)
+			if err != nil {
+				return errors.New("error encoding field " + name + ": " + err.Error())
+			}
+
+			value.SetString(encoded)
+			restores = append(restores, func() { value.SetString(original) })
+
+			return nil
+		})
+		if err != nil {
+			restore()
+			return nil, err
+		}
+	}
+
+	return restore, nil
+}
+
+// EncodeUpdate returns update with the string values of the fields with a codec registered for the table of row
+// encoded, when they are set by the $set and $setOnInsert operators or by a replacement document. Like EncodeFields,
+// it fails if such a field of row is tagged with `encrypt:"true"` without a codec registered. The given update is
+// not modified.
+func EncodeUpdate(row model.DBObject, update model.DBM) (model.DBM, error) {
+	table := row.TableName()
+	codecs := tableCodecs(table)
+
+	encrypted := map[string]bool{}
+
+	err := forEachStringField(row, func(name string, isEncrypted bool, _ reflect.Value) error {
+		encrypted[name] = isEncrypted
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encode := func(name string, value interface{}) (interface{}, error) {
+		original, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+
+		codec, ok := codecs[name]
+		if !ok {
+			if encrypted[name] {
+				return nil, errors.New(ErrorFieldCodecNotFound + ": " + table + "." + name)
+			}
+
+			return value, nil
+		}
+
+		encoded, err := codec.Encode(original)
+		if err != nil {
+			return nil, errors.New("error encoding field " + name + ": " + err.Error())
+		}
+
+		return encoded, nil
+	}
+
+	encoded := make(model.DBM, len(update))
+
+	for key, value := range update {
+		fields, isDocument := value.(model.DBM)
+
+		switch {
+		case (key == "$set" || key == "$setOnInsert") && isDocument:
+			encodedFields := make(model.DBM, len(fields))
+
+			for name, fieldValue := range fields {
+				encodedValue, err := encode(name, fieldValue)
+				if err != nil {
+					return nil, err
+				}
+
+				encodedFields[name] = encodedValue
+			}
+
+			encoded[key] = encodedFields
+		case strings.HasPrefix(key, "$"):
+			encoded[key] = value
+		default:
+			encodedValue, err := encode(key, value)
+			if err != nil {
+				return nil, err
+			}
+
+			encoded[key] = encodedValue
+		}
+	}
+
+	return encoded, nil
+}
+
+// DecodeFields decodes in place the string fields with a codec registered for table of result,
+// which can be a pointer to a struct, to a model.DBM or to a slice of them.
+func DecodeFields(table string, result interface{}) error {
+	codecs := tableCodecs(table)
+	if len(codecs) == 0 {
+		return nil
+	}
+
+	decode := func(row interface{}) error {
+		if doc, ok := row.(*model.DBM); ok {
+			return decodeDocument(codecs, *doc)
+		}
+
+		return forEachStringField(row, func(name string, _ bool, value reflect.Value) error {
+			codec, ok := codecs[name]
+			if !ok || value.String() == "" {
+				return nil
+			}
+
+			decoded, err := codec.Decode(value.String())
+			if err != nil {
+				return errors.New("error decoding field " + name + ": " + err.Error())
+			}
+
+			value.SetString(decoded)
+
+			return nil
+		})
+	}
+
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return decode(result)
+	}
+
+	rows := rv.Elem()
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		if row.Kind() != reflect.Ptr {
+			row = row.Addr()
+		}
+
+		if err := decode(row.Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeValues decodes in place the string values of field with a codec registered for table, e.g. its distinct values.
+func DecodeValues(table, field string, values []interface{}) error {
+	codec, ok := tableCodecs(table)[field]
+	if !ok {
+		return nil
+	}
+
+	for i, value := range values {
+		encoded, ok := value.(string)
+		if !ok || encoded == "" {
+			continue
+		}
+
+		decoded, err := codec.Decode(encoded)
+		if err != nil {
+			return errors.New("error decoding field " + field + ": " + err.Error())
+		}
+
+		values[i] = decoded
+	}
+
+	return nil
+}
+
+// decodeDocument decodes in place the string values of doc with a codec registered for their key.
+func decodeDocument(codecs map[string]model.FieldCodec, doc model.DBM) error {
+	for name, codec := range codecs {
+		value, ok := doc[name].(string)
+		if !ok || value == "" {
+			continue
+		}
+
+		decoded, err := codec.Decode(value)
+		if err != nil {
+			return errors.New("error decoding field " + name + ": " + err.Error())
+		}
+
+		doc[name] = decoded
+	}
+
+	return nil
+}
+
+// forEachStringField calls fn with the stored name, the encrypt tag and the settable value of the
+// exported string fields of row, when it's a pointer to a struct.
+func forEachStringField(row interface{}, fn func(name string, encrypted bool, value reflect.Value) error) error {
+	rv := reflect.ValueOf(row)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		name, ok := model.FieldName(field)
+		if !ok {
+			continue
+		}
+
+		if err := fn(name, field.Tag.Get("encrypt") == "true", rv.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
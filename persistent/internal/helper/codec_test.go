@@ -0,0 +1,210 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// upperCodec upper-cases the values, failing to decode the ones that aren't.
+type upperCodec struct{}
+
+func (upperCodec) Encode(value string) (string, error) {
+	if value == "fail" {
+		return "", errors.New("test")
+	}
+
+	return strings.ToUpper(value), nil
+}
+
+func (upperCodec) Decode(value string) (string, error) {
+	if strings.ToUpper(value) != value {
+		return "", errors.New("not encoded")
+	}
+
+	return strings.ToLower(value), nil
+}
+
+type dummySecretObject struct {
+	ID     model.ObjectID `bson:"_id"`
+	Name   string         `bson:"name"`
+	Secret string         `bson:"secret" encrypt:"true"`
+	Other  string
+}
+
+func (d *dummySecretObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummySecretObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummySecretObject) TableName() string {
+	return "dummy_secret"
+}
+
+func TestEncodeFields(t *testing.T) {
+	t.Run("encrypted field without codec", func(t *testing.T) {
+		_, err := EncodeFields(&dummySecretObject{Secret: "secret"})
+		assert.Equal(t, errors.New(ErrorFieldCodecNotFound+": dummy_secret.secret"), err)
+	})
+
+	RegisterFieldCodec("dummy_secret", "secret", upperCodec{})
+	RegisterFieldCodec("dummy_secret", "other", upperCodec{})
+
+	defer func() {
+		RegisterFieldCodec("dummy_secret", "secret", nil)
+		RegisterFieldCodec("dummy_secret", "other", nil)
+	}()
+
+	t.Run("fields encoded and restored", func(t *testing.T) {
+		first := &dummySecretObject{Name: "first", Secret: "secret", Other: "other"}
+		second := &dummySecretObject{Name: "second", Secret: "another"}
+
+		restore, err := EncodeFields(first, second, &dummyDBObject{Name: "not registered"})
+		assert.Nil(t, err)
+		assert.Equal(t, &dummySecretObject{Name: "first", Secret: "SECRET", Other: "OTHER"}, first)
+		assert.Equal(t, &dummySecretObject{Name: "second", Secret: "ANOTHER"}, second)
+
+		restore()
+		assert.Equal(t, &dummySecretObject{Name: "first", Secret: "secret", Other: "other"}, first)
+		assert.Equal(t, &dummySecretObject{Name: "second", Secret: "another"}, second)
+	})
+
+	t.Run("fields restored when encoding fails", func(t *testing.T) {
+		first := &dummySecretObject{Secret: "secret"}
+		second := &dummySecretObject{Secret: "fail"}
+
+		_, err := EncodeFields(first, second)
+		assert.Equal(t, errors.New("error encoding field secret: test"), err)
+		assert.Equal(t, &dummySecretObject{Secret: "secret"}, first)
+	})
+}
+
+func TestDecodeFields(t *testing.T) {
+	RegisterFieldCodec("dummy_secret", "secret", upperCodec{})
+	defer RegisterFieldCodec("dummy_secret", "secret", nil)
+
+	t.Run("single row", func(t *testing.T) {
+		row := &dummySecretObject{Name: "NAME", Secret: "SECRET"}
+
+		err := DecodeFields("dummy_secret", row)
+		assert.Nil(t, err)
+		assert.Equal(t, &dummySecretObject{Name: "NAME", Secret: "secret"}, row)
+	})
+
+	t.Run("slice of rows", func(t *testing.T) {
+		rows := []dummySecretObject{{Secret: "FIRST"}, {Secret: ""}}
+
+		err := DecodeFields("dummy_secret", &rows)
+		assert.Nil(t, err)
+		assert.Equal(t, []dummySecretObject{{Secret: "first"}, {Secret: ""}}, rows)
+	})
+
+	t.Run("slice of pointers", func(t *testing.T) {
+		rows := []*dummySecretObject{{Secret: "FIRST"}}
+
+		err := DecodeFields("dummy_secret", &rows)
+		assert.Nil(t, err)
+		assert.Equal(t, []*dummySecretObject{{Secret: "first"}}, rows)
+	})
+
+	t.Run("decoding fails", func(t *testing.T) {
+		err := DecodeFields("dummy_secret", &dummySecretObject{Secret: "plain"})
+		assert.Equal(t, errors.New("error decoding field secret: not encoded"), err)
+	})
+
+	t.Run("table without codecs", func(t *testing.T) {
+		row := &dummySecretObject{Secret: "SECRET"}
+
+		err := DecodeFields("dummy", row)
+		assert.Nil(t, err)
+		assert.Equal(t, &dummySecretObject{Secret: "SECRET"}, row)
+	})
+
+	t.Run("document", func(t *testing.T) {
+		row := model.DBM{"name": "NAME", "secret": "SECRET"}
+
+		err := DecodeFields("dummy_secret", &row)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DBM{"name": "NAME", "secret": "secret"}, row)
+	})
+
+	t.Run("slice of documents", func(t *testing.T) {
+		rows := []model.DBM{{"secret": "FIRST"}, {"secret": 1}, {}}
+
+		err := DecodeFields("dummy_secret", &rows)
+		assert.Nil(t, err)
+		assert.Equal(t, []model.DBM{{"secret": "first"}, {"secret": 1}, {}}, rows)
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		rows := []string{"SECRET"}
+
+		err := DecodeFields("dummy_secret", &rows)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"SECRET"}, rows)
+	})
+}
+
+func TestEncodeUpdate(t *testing.T) {
+	t.Run("encrypted field without codec", func(t *testing.T) {
+		_, err := EncodeUpdate(&dummySecretObject{}, model.DBM{"$set": model.DBM{"secret": "secret"}})
+		assert.Equal(t, errors.New(ErrorFieldCodecNotFound+": dummy_secret.secret"), err)
+	})
+
+	RegisterFieldCodec("dummy_secret", "secret", upperCodec{})
+	defer RegisterFieldCodec("dummy_secret", "secret", nil)
+
+	t.Run("operators", func(t *testing.T) {
+		update := model.DBM{
+			"$set":         model.DBM{"name": "name", "secret": "secret"},
+			"$setOnInsert": model.DBM{"secret": "inserted"},
+			"$unset":       model.DBM{"secret": ""},
+		}
+
+		encoded, err := EncodeUpdate(&dummySecretObject{}, update)
+		assert.Nil(t, err)
+		assert.Equal(t, model.DBM{
+			"$set":         model.DBM{"name": "name", "secret": "SECRET"},
+			"$setOnInsert": model.DBM{"secret": "INSERTED"},
+			"$unset":       model.DBM{"secret": ""},
+		}, encoded)
+		assert.Equal(t, model.DBM{"name": "name", "secret": "secret"}, update["$set"])
+	})
+
+	t.Run("replacement document", func(t *testing.T) {
+		encoded, err := EncodeUpdate(&dummySecretObject{}, model.DBM{"name": "name", "secret": "secret", "other": 1})
+		assert.Nil(t, err)
+		assert.Equal(t, model.DBM{"name": "name", "secret": "SECRET", "other": 1}, encoded)
+	})
+
+	t.Run("encoding fails", func(t *testing.T) {
+		_, err := EncodeUpdate(&dummySecretObject{}, model.DBM{"$set": model.DBM{"secret": "fail"}})
+		assert.Equal(t, errors.New("error encoding field secret: test"), err)
+	})
+}
+
+func TestDecodeValues(t *testing.T) {
+	RegisterFieldCodec("dummy_secret", "secret", upperCodec{})
+	defer RegisterFieldCodec("dummy_secret", "secret", nil)
+
+	values := []interface{}{"FIRST", "", 1}
+
+	err := DecodeValues("dummy_secret", "secret", values)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"first", "", 1}, values)
+
+	values = []interface{}{"NAME"}
+
+	err = DecodeValues("dummy_secret", "name", values)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"NAME"}, values)
+
+	err = DecodeValues("dummy_secret", "secret", []interface{}{"plain"})
+	assert.Equal(t, errors.New("error decoding field secret: not encoded"), err)
+}
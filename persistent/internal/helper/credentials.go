@@ -0,0 +1,80 @@
+package helper
+
+import (
+	"sync"
+	"time"
+)
+
+// credentialsRefreshWindow is how long before their expiration the credentials are refreshed
+const credentialsRefreshWindow = time.Minute
+
+// minCredentialsRefreshDelay is the minimum delay between two refreshes, e.g. while they fail
+var minCredentialsRefreshDelay = 5 * time.Second
+
+// CredentialsRefresher refreshes the credentials of a connection shortly before they expire, until it's stopped.
+type CredentialsRefresher struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+// Schedule runs refresh shortly before expiration, instead of the refresh scheduled before, if any.
+// The refresh is retried while it fails. Nothing is scheduled if expiration is zero.
+func (r *CredentialsRefresher) Schedule(expiration time.Time, refresh func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+
+	if r.stopped || expiration.IsZero() {
+		return
+	}
+
+	r.timer = time.AfterFunc(CredentialsRefreshDelay(expiration, time.Now()), func() {
+		r.mu.Lock()
+		stopped := r.stopped
+		r.mu.Unlock()
+
+		if stopped {
+			return
+		}
+
+		// the credentials may still be valid for a while, so the refresh is retried until they expire, and after
+		if err := refresh(); err != nil {
+			r.Schedule(expiration, refresh)
+		}
+	})
+}
+
+// Stop cancels the scheduled refresh, and prevents the next ones.
+func (r *CredentialsRefresher) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopped = true
+
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+// CredentialsRefreshDelay returns the delay from now after which the credentials expiring at expiration
+// are refreshed: a minute before they expire, or halfway through if they expire sooner.
+func CredentialsRefreshDelay(expiration, now time.Time) time.Duration {
+	remaining := expiration.Sub(now)
+
+	delay := remaining - credentialsRefreshWindow
+	if delay < remaining/2 {
+		delay = remaining / 2
+	}
+
+	if delay < minCredentialsRefreshDelay {
+		delay = minCredentialsRefreshDelay
+	}
+
+	return delay
+}
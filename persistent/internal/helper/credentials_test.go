@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialsRefreshDelay(t *testing.T) {
+	now := time.Now()
+
+	tcs := []struct {
+		name       string
+		expiration time.Time
+		expected   time.Duration
+	}{
+		{
+			name:       "a minute before expiration",
+			expiration: now.Add(15 * time.Minute),
+			expected:   14 * time.Minute,
+		},
+		{
+			name:       "halfway through a short expiration",
+			expiration: now.Add(time.Minute),
+			expected:   30 * time.Second,
+		},
+		{
+			name:       "expired",
+			expiration: now.Add(-time.Minute),
+			expected:   minCredentialsRefreshDelay,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, CredentialsRefreshDelay(tc.expiration, now))
+		})
+	}
+}
+
+func TestCredentialsRefresher(t *testing.T) {
+	defer func(delay time.Duration) { minCredentialsRefreshDelay = delay }(minCredentialsRefreshDelay)
+
+	minCredentialsRefreshDelay = 10 * time.Millisecond
+
+	var refreshes int32
+
+	refresher := &CredentialsRefresher{}
+	refresher.Schedule(time.Now(), func() error {
+		atomic.AddInt32(&refreshes, 1)
+
+		return nil
+	})
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&refreshes) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// a failed refresh is retried, until the refresher is stopped
+	refresher.Schedule(time.Now(), func() error {
+		atomic.AddInt32(&refreshes, 1)
+
+		return errors.New("unavailable")
+	})
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&refreshes) >= 3
+	}, time.Second, 10*time.Millisecond)
+
+	refresher.Stop()
+
+	// a refresh may be running while the refresher is stopped
+	time.Sleep(minCredentialsRefreshDelay)
+
+	stopped := atomic.LoadInt32(&refreshes)
+
+	time.Sleep(5 * minCredentialsRefreshDelay)
+	assert.Equal(t, stopped, atomic.LoadInt32(&refreshes))
+}
@@ -0,0 +1,45 @@
+package helper
+
+import "github.com/TykTechnologies/storage/persistent/model"
+
+// NormalizeDocuments converts in place the values of the documents of result, when it's a *model.DBM or
+// a *[]model.DBM, so they are decoded the same way by all the drivers: convert is called with every value
+// that isn't a document nor an array, e.g. to return the ObjectIDs of the driver as model.ObjectID.
+func NormalizeDocuments(result interface{}, convert func(value interface{}) interface{}) {
+	switch docs := result.(type) {
+	case *model.DBM:
+		normalizeDocument(*docs, convert)
+	case *[]model.DBM:
+		for _, doc := range *docs {
+			normalizeDocument(doc, convert)
+		}
+	}
+}
+
+func normalizeDocument(doc model.DBM, convert func(value interface{}) interface{}) {
+	for key, value := range doc {
+		doc[key] = normalizeValue(value, convert)
+	}
+}
+
+func normalizeValue(value interface{}, convert func(value interface{}) interface{}) interface{} {
+	switch v := value.(type) {
+	case model.DBM:
+		normalizeDocument(v, convert)
+
+		return v
+	case map[string]interface{}:
+		doc := model.DBM(v)
+		normalizeDocument(doc, convert)
+
+		return doc
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = normalizeValue(elem, convert)
+		}
+
+		return v
+	default:
+		return convert(value)
+	}
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// rawID is an ID as decoded by a driver, converted to model.ObjectID by convertRawID.
+type rawID string
+
+func convertRawID(value interface{}) interface{} {
+	if id, ok := value.(rawID); ok {
+		return model.ObjectID(id)
+	}
+
+	return value
+}
+
+func TestNormalizeDocuments(t *testing.T) {
+	t.Run("document", func(t *testing.T) {
+		doc := model.DBM{
+			"_id":    rawID("id"),
+			"nested": model.DBM{"ref": rawID("ref")},
+			"map":    map[string]interface{}{"ref": rawID("ref")},
+			"array":  []interface{}{rawID("elem"), model.DBM{"ref": rawID("ref")}},
+			"name":   "name",
+		}
+
+		NormalizeDocuments(&doc, convertRawID)
+		assert.Equal(t, model.DBM{
+			"_id":    model.ObjectID("id"),
+			"nested": model.DBM{"ref": model.ObjectID("ref")},
+			"map":    model.DBM{"ref": model.ObjectID("ref")},
+			"array":  []interface{}{model.ObjectID("elem"), model.DBM{"ref": model.ObjectID("ref")}},
+			"name":   "name",
+		}, doc)
+	})
+
+	t.Run("slice of documents", func(t *testing.T) {
+		docs := []model.DBM{{"_id": rawID("first")}, {"_id": rawID("second")}}
+
+		NormalizeDocuments(&docs, convertRawID)
+		assert.Equal(t, []model.DBM{{"_id": model.ObjectID("first")}, {"_id": model.ObjectID("second")}}, docs)
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		row := &dummySecretObject{ID: "id"}
+
+		NormalizeDocuments(row, convertRawID)
+		assert.Equal(t, &dummySecretObject{ID: "id"}, row)
+	})
+}
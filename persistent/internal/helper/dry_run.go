@@ -0,0 +1,116 @@
+package helper
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// IsDryRun reports whether ctx was returned by model.WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	_, ok := model.DryRunFromContext(ctx)
+	return ok
+}
+
+// DryRunRows returns shallow copies of rows, so a dry run doesn't set their ids or timestamps
+// while they aren't stored. The rows that aren't pointers to a struct are returned as they are.
+func DryRunRows(rows ...model.DBObject) []model.DBObject {
+	copies := make([]model.DBObject, len(rows))
+
+	for i, row := range rows {
+		copies[i] = row
+
+		rv := reflect.ValueOf(row)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			continue
+		}
+
+		copied := reflect.New(rv.Elem().Type())
+		copied.Elem().Set(rv.Elem())
+
+		if copiedRow, ok := copied.Interface().(model.DBObject); ok {
+			copies[i] = copiedRow
+		}
+	}
+
+	return copies
+}
+
+// InsertCommand returns the insert command of documents into table.
+func InsertCommand(table string, documents []model.DBM) model.DBM {
+	return model.DBM{"insert": table, "documents": documents}
+}
+
+// UpdateCommand returns the update command applying update to the rows of table matching filter,
+// or only to the first of them if multi isn't set.
+func UpdateCommand(table string, filter, update interface{}, multi bool) model.DBM {
+	return model.DBM{
+		"update":  table,
+		"updates": []model.DBM{{"q": filter, "u": update, "multi": multi}},
+	}
+}
+
+// DeleteCommand returns the delete command of all the rows of table matching filter.
+func DeleteCommand(table string, filter interface{}) model.DBM {
+	return model.DBM{
+		"delete":  table,
+		"deletes": []model.DBM{{"q": filter, "limit": 0}},
+	}
+}
+
+// UpsertCommand returns the findAndModify command applying update to the first row of table matching filter,
+// inserting it if there's none, and returning it as it is after the update.
+func UpsertCommand(table string, filter, update interface{}) model.DBM {
+	return model.DBM{"findAndModify": table, "query": filter, "update": update, "upsert": true, "new": true}
+}
+
+// CountCommand returns the count command of the rows of table matching filter.
+func CountCommand(table string, filter interface{}) model.DBM {
+	return model.DBM{"count": table, "query": filter}
+}
+
+// FindCommand returns the find command of the rows of table matching filter, sorted by sort if it's not nil,
+// with the _limit, _offset, _max_time and _fields meta keys of query.
+func FindCommand(table string, filter, sort interface{}, query model.DBM) model.DBM {
+	command := model.DBM{"find": table, "filter": filter}
+
+	if sort != nil {
+		command["sort"] = sort
+	}
+
+	if limit, ok := query["_limit"].(int); ok && limit > 0 {
+		command["limit"] = limit
+	}
+
+	if offset, ok := query["_offset"].(int); ok && offset > 0 {
+		command["skip"] = offset
+	}
+
+	if maxTime, ok := GetMaxTime(query); ok {
+		command["maxTimeMS"] = maxTime.Milliseconds()
+	}
+
+	if fields, ok := query["_fields"].([]string); ok && len(fields) > 0 {
+		projection := model.DBM{}
+		for _, field := range fields {
+			projection[field] = 1
+		}
+
+		command["projection"] = projection
+	}
+
+	return command
+}
+
+// AggregateCommand returns the aggregate command running pipeline over table, with the _max_time meta key
+// of opts, as returned by SplitPipelineOptions.
+func AggregateCommand(table string, pipeline []model.DBM, opts model.DBM) model.DBM {
+	command := model.DBM{"aggregate": table, "pipeline": pipeline, "cursor": model.DBM{}}
+
+	if maxTime, ok := GetMaxTime(opts); ok {
+		command["maxTimeMS"] = maxTime.Milliseconds()
+	}
+
+	return command
+}
@@ -0,0 +1,61 @@
+package helper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDryRun(t *testing.T) {
+	ctx, _ := model.WithDryRun(context.Background())
+
+	assert.True(t, IsDryRun(ctx))
+	assert.False(t, IsDryRun(context.Background()))
+}
+
+func TestDryRunRows(t *testing.T) {
+	row := &dummyDBObject{Name: "test"}
+
+	copies := DryRunRows(row)
+	assert.Len(t, copies, 1)
+	assert.Equal(t, row, copies[0])
+
+	copies[0].SetObjectID(model.NewObjectID())
+	assert.Empty(t, row.GetObjectID())
+}
+
+func TestFindCommand(t *testing.T) {
+	filter := model.DBM{"name": "test"}
+
+	assert.Equal(t, model.DBM{"find": "users", "filter": filter}, FindCommand("users", filter, nil, model.DBM{}))
+
+	command := FindCommand("users", filter, model.DBM{"name": 1}, model.DBM{
+		"_limit":    10,
+		"_offset":   20,
+		"_max_time": time.Second,
+		"_fields":   []string{"name"},
+	})
+	assert.Equal(t, model.DBM{
+		"find":       "users",
+		"filter":     filter,
+		"sort":       model.DBM{"name": 1},
+		"limit":      10,
+		"skip":       20,
+		"maxTimeMS":  int64(1000),
+		"projection": model.DBM{"name": 1},
+	}, command)
+}
+
+func TestAggregateCommand(t *testing.T) {
+	pipeline := []model.DBM{{"$match": model.DBM{"name": "test"}}}
+
+	assert.Equal(t, model.DBM{
+		"aggregate": "users",
+		"pipeline":  pipeline,
+		"cursor":    model.DBM{},
+		"maxTimeMS": int64(5),
+	}, AggregateCommand("users", pipeline, model.DBM{"_max_time_ms": 5}))
+}
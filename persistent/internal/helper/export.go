@@ -0,0 +1,258 @@
+package helper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// ExportEncoder writes the exported rows in a model.ExportFormat.
+type ExportEncoder interface {
+	// Encode writes a row.
+	Encode(document model.DBM) error
+	// Flush writes the buffered rows, if any.
+	Flush() error
+}
+
+// NewExportEncoder returns the ExportEncoder writing to w the rows in format. The CSV columns are the given fields
+// along with _id or, if there are none, the fields of the first row.
+func NewExportEncoder(w io.Writer, format model.ExportFormat, fields []string) (ExportEncoder, error) {
+	switch format {
+	case model.NDJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+
+		return &ndjsonEncoder{encoder: encoder}, nil
+	case model.CSV:
+		encoder := &csvEncoder{writer: csv.NewWriter(w)}
+		if len(fields) > 0 {
+			encoder.setColumns(fields)
+		}
+
+		return encoder, nil
+	default:
+		return nil, fmt.Errorf("unknown export format: %q", format)
+	}
+}
+
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(document model.DBM) error {
+	return e.encoder.Encode(document)
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+type csvEncoder struct {
+	writer      *csv.Writer
+	columns     []string
+	wroteHeader bool
+}
+
+// setColumns sets the columns of the CSV, sorted by name with _id first.
+func (e *csvEncoder) setColumns(fields []string) {
+	columns := []string{"_id"}
+
+	for _, field := range fields {
+		if field != "_id" {
+			columns = append(columns, field)
+		}
+	}
+
+	sort.Strings(columns[1:])
+
+	e.columns = columns
+}
+
+func (e *csvEncoder) Encode(document model.DBM) error {
+	if e.columns == nil {
+		fields := make([]string, 0, len(document))
+		for field := range document {
+			fields = append(fields, field)
+		}
+
+		e.setColumns(fields)
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(e.columns))
+
+	for i, column := range e.columns {
+		cell, err := csvCell(document[column])
+		if err != nil {
+			return err
+		}
+
+		record[i] = cell
+	}
+
+	return e.writer.Write(record)
+}
+
+// writeHeader writes the columns once, before the first row.
+func (e *csvEncoder) writeHeader() error {
+	if e.wroteHeader || e.columns == nil {
+		return nil
+	}
+
+	e.wroteHeader = true
+
+	return e.writer.Write(e.columns)
+}
+
+// Flush writes the header even if there were no rows, when the columns are known.
+func (e *csvEncoder) Flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	e.writer.Flush()
+
+	return e.writer.Error()
+}
+
+// csvCell returns the CSV representation of value: strings as they are, dates in RFC 3339 and anything else
+// as JSON.
+func csvCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case model.ObjectID:
+		return v.Hex(), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
+
+// ImportCSV reads the CSV rows of r, whose first line is the header with the fields of the rows, and calls
+// importBatch with batches of up to batchSize documents as ImportNDJSON does. The cells are decoded as JSON
+// when possible, so numbers, booleans and nested documents keep their type, and are kept as strings otherwise.
+// Empty cells are skipped, and the hex _id are parsed into model.ObjectID.
+func ImportCSV(r io.Reader,
+	batchSize int,
+	importBatch ImportBatch,
+) (int, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	lineErrors := map[int]error{}
+	imported := 0
+
+	var lines []int
+	var documents []model.DBM
+
+	flush := func() {
+		n, failed := importBatch(documents)
+		imported += n
+
+		for i, err := range failed {
+			lineErrors[lines[i]] = err
+		}
+
+		lines, documents = nil, nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		var parseErr *csv.ParseError
+
+		switch {
+		case errors.As(err, &parseErr):
+			lineErrors[parseErr.Line] = parseErr.Err
+		case err != nil:
+			return imported, err
+		default:
+			lines = append(lines, line)
+			documents = append(documents, decodeCSVRecord(header, record))
+		}
+
+		if len(documents) == batchSize {
+			flush()
+		}
+	}
+
+	if len(documents) > 0 {
+		flush()
+	}
+
+	return imported, aggregateErrors(errorImport, "line", lineErrors)
+}
+
+func decodeCSVRecord(header, record []string) model.DBM {
+	document := model.DBM{}
+
+	for i, field := range header {
+		if i >= len(record) || record[i] == "" {
+			continue
+		}
+
+		cell := record[i]
+
+		// the hex of an ObjectID could be a valid JSON number
+		if field == "_id" && model.IsObjectIDHex(cell) {
+			document[field] = model.ObjectIDHex(cell)
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(cell), &value); err != nil {
+			value = cell
+		}
+
+		document[field] = value
+	}
+
+	return document
+}
+
+// ImportBatch imports a batch of documents, returning the number of imported documents and the errors
+// of the failed ones, keyed by their index in the batch.
+type ImportBatch func(documents []model.DBM) (int, map[int]error)
+
+// Importer reads the rows of r and imports them in batches of up to batchSize rows through importBatch,
+// such as ImportNDJSON or ImportCSV.
+type Importer func(r io.Reader, batchSize int, importBatch ImportBatch) (int, error)
+
+// NewImporter returns the Importer of the rows in format.
+func NewImporter(format model.ExportFormat) (Importer, error) {
+	switch format {
+	case model.NDJSON:
+		return ImportNDJSON, nil
+	case model.CSV:
+		return ImportCSV, nil
+	default:
+		return nil, fmt.Errorf("unknown export format: %q", format)
+	}
+}
@@ -0,0 +1,106 @@
+package helper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExportEncoder(t *testing.T) {
+	id := model.NewObjectID()
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		encoder, err := NewExportEncoder(&buf, model.NDJSON, nil)
+		assert.Nil(t, err)
+
+		assert.Nil(t, encoder.Encode(model.DBM{"_id": id, "name": "<first>"}))
+		assert.Nil(t, encoder.Flush())
+		assert.Equal(t, `{"_id":"`+id.Hex()+`","name":"<first>"}`+"\n", buf.String())
+	})
+
+	t.Run("csv with the fields of the first row", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		encoder, err := NewExportEncoder(&buf, model.CSV, nil)
+		assert.Nil(t, err)
+
+		assert.Nil(t, encoder.Encode(model.DBM{"_id": id, "name": "first, second", "age": 10, "tags": []string{"a"}}))
+		assert.Nil(t, encoder.Encode(model.DBM{"name": "third", "meta": model.DBM{"a": 1}}))
+		assert.Nil(t, encoder.Flush())
+
+		assert.Equal(t, "_id,age,name,tags\n"+
+			id.Hex()+`,10,"first, second","[""a""]"`+"\n"+
+			",,third,\n", buf.String())
+	})
+
+	t.Run("csv with fields and no rows", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		encoder, err := NewExportEncoder(&buf, model.CSV, []string{"name", "age"})
+		assert.Nil(t, err)
+		assert.Nil(t, encoder.Flush())
+		assert.Equal(t, "_id,age,name\n", buf.String())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := NewExportEncoder(&bytes.Buffer{}, "xml", nil)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestImportCSV(t *testing.T) {
+	id := model.NewObjectID()
+
+	t.Run("batches and malformed lines", func(t *testing.T) {
+		input := "_id,name,age,meta\n" +
+			id.Hex() + `,first,10,"{""a"":1}"` + "\n" +
+			",second,,\n" +
+			"third,with,too,many,fields\n" +
+			",fourth,true,\n"
+
+		var batches [][]model.DBM
+
+		imported, err := ImportCSV(strings.NewReader(input), 2, func(documents []model.DBM) (int, map[int]error) {
+			batches = append(batches, documents)
+			return len(documents), nil
+		})
+
+		assert.Equal(t, 3, imported)
+		assert.NotNil(t, err)
+		assert.Equal(t, "error importing rows: line 4: wrong number of fields", err.Error())
+
+		assert.Equal(t, [][]model.DBM{
+			{
+				{"_id": id, "name": "first", "age": float64(10), "meta": map[string]interface{}{"a": float64(1)}},
+				{"name": "second"},
+			},
+			{{"name": "fourth", "age": true}},
+		}, batches)
+	})
+
+	t.Run("failed documents report their line", func(t *testing.T) {
+		input := "name\nfirst\nsecond\n"
+
+		imported, err := ImportCSV(strings.NewReader(input), 10, func(documents []model.DBM) (int, map[int]error) {
+			return len(documents) - 1, map[int]error{1: assert.AnError}
+		})
+
+		assert.Equal(t, 1, imported)
+		assert.Equal(t, "error importing rows: line 3: "+assert.AnError.Error(), err.Error())
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		imported, err := ImportCSV(strings.NewReader(""), 10, func(documents []model.DBM) (int, map[int]error) {
+			t.Fatal("importBatch must not be called without documents")
+			return 0, nil
+		})
+
+		assert.Equal(t, 0, imported)
+		assert.Nil(t, err)
+	})
+}
@@ -1,9 +1,15 @@
 package helper
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
 )
 
 func IsSlice(o interface{}) bool {
@@ -24,3 +30,303 @@ func IsCosmosDB(connectionString string) bool {
 		strings.Contains(connectionString, "AccountEndpoint=") ||
 		strings.Contains(connectionString, "AccountKey=")
 }
+
+// IsDocumentDB reports whether the connection string points to an AWS DocumentDB cluster,
+// including the elastic ones.
+func IsDocumentDB(connectionString string) bool {
+	return strings.Contains(connectionString, ".docdb.amazonaws.com") ||
+		strings.Contains(connectionString, ".docdb-elastic.amazonaws.com")
+}
+
+// UnsupportedStage returns the first stage of pipeline whose operator is one of the unsupported ones.
+func UnsupportedStage(pipeline []model.DBM, unsupported []string) (string, bool) {
+	for _, stage := range pipeline {
+		for _, operator := range unsupported {
+			if _, ok := stage[operator]; ok {
+				return operator, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ParseIDs parses the ObjectID hexes and UUIDs of ids, skipping the invalid ones, to match them in a filter.
+// It returns a []model.ObjectID when there's no UUID, and a []interface{} with both kinds of IDs otherwise.
+func ParseIDs(ids []string) interface{} {
+	objectIDs := []model.ObjectID{}
+	parsed := []interface{}{}
+	hasUUID := false
+
+	for _, id := range ids {
+		switch {
+		case model.IsObjectIDHex(id):
+			objectIDs = append(objectIDs, model.ObjectIDHex(id))
+			parsed = append(parsed, model.ObjectIDHex(id))
+		case model.IsUUID(id):
+			u, _ := model.ParseUUID(id)
+			parsed = append(parsed, u)
+			hasUUID = true
+		}
+	}
+
+	if hasUUID {
+		return parsed
+	}
+
+	return objectIDs
+}
+
+// PageSort returns the field and the order of the sort of a model.PageRequest, _id by default.
+func PageSort(sort string) (field string, descending bool) {
+	switch {
+	case strings.HasPrefix(sort, "-"):
+		field, descending = sort[1:], true
+	case strings.HasPrefix(sort, "+"):
+		field = sort[1:]
+	default:
+		field = sort
+	}
+
+	if field == "" {
+		field = "_id"
+	}
+
+	return field, descending
+}
+
+// PageLimit returns the limit of a model.PageRequest, model.DefaultPageLimit by default.
+func PageLimit(page model.PageRequest) int {
+	if page.Limit > 0 {
+		return page.Limit
+	}
+
+	return model.DefaultPageLimit
+}
+
+// GetMaxTime returns the server-side execution limit requested through the "_max_time" key of the query,
+// or else through the "_max_time_ms" key, given as a number of milliseconds.
+// Limits under a millisecond are rounded up, since a zero maxTimeMS means no limit at all for the server.
+func GetMaxTime(query model.DBM) (time.Duration, bool) {
+	maxTime, ok := query["_max_time"].(time.Duration)
+	if !ok {
+		switch maxTimeMS := query["_max_time_ms"].(type) {
+		case int:
+			maxTime = time.Duration(maxTimeMS) * time.Millisecond
+		case int64:
+			maxTime = time.Duration(maxTimeMS) * time.Millisecond
+		}
+	}
+
+	if maxTime <= 0 {
+		return 0, false
+	}
+
+	if maxTime < time.Millisecond {
+		maxTime = time.Millisecond
+	}
+
+	return maxTime, true
+}
+
+// SplitPipelineOptions removes from an aggregation pipeline the stages made only of meta keys (prefixed with "_"),
+// returning the remaining stages and the meta keys merged into a single model.DBM.
+// For example, []model.DBM{{"$match": ...}, {"_max_time": time.Second}} returns the $match stage
+// and the _max_time option.
+func SplitPipelineOptions(pipeline []model.DBM) ([]model.DBM, model.DBM) {
+	stages := make([]model.DBM, 0, len(pipeline))
+	opts := model.DBM{}
+
+	for _, stage := range pipeline {
+		if !isMetaStage(stage) {
+			stages = append(stages, stage)
+			continue
+		}
+
+		for key, value := range stage {
+			opts[key] = value
+		}
+	}
+
+	return stages, opts
+}
+
+func isMetaStage(stage model.DBM) bool {
+	if len(stage) == 0 {
+		return false
+	}
+
+	for key := range stage {
+		if !strings.HasPrefix(key, "_") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// BSONField returns the settable value of the exported field of row stored as name, as named by model.FieldName,
+// when row is a pointer to a struct.
+func BSONField(row interface{}, name string) (reflect.Value, bool) {
+	rv := reflect.ValueOf(row)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		if fieldName, ok := model.FieldName(rt.Field(i)); ok && fieldName == name {
+			return rv.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// SoftDeleteFilter returns query along with the condition skipping the deleted rows when row is
+// a model.SoftDeletable, unless query sets "_with_deleted" or filters by the deleted_at field itself.
+// The rows whose deleted_at is missing, null or the zero time are the not deleted ones.
+// The given query is not modified.
+func SoftDeleteFilter(row model.DBObject, query model.DBM) model.DBM {
+	if _, ok := row.(model.SoftDeletable); !ok {
+		return query
+	}
+
+	if withDeleted, _ := query["_with_deleted"].(bool); withDeleted {
+		return query
+	}
+
+	if _, ok := query[model.DeletedAtField]; ok {
+		return query
+	}
+
+	filter := model.DBM{}
+	for key, value := range query {
+		filter[key] = value
+	}
+
+	filter[model.DeletedAtField] = model.DBM{"$not": model.DBM{"$gt": time.Time{}}}
+
+	return filter
+}
+
+// SoftDeletePipeline returns pipeline along with the $match stage skipping the deleted rows when row is
+// a model.SoftDeletable, unless the pipeline options set "_with_deleted". The stage is added first, or right
+// after a $geoNear or $search stage as they must be the first one. The given pipeline is not modified.
+func SoftDeletePipeline(row model.DBObject, pipeline []model.DBM, opts model.DBM) []model.DBM {
+	if withDeleted, _ := opts["_with_deleted"].(bool); withDeleted {
+		return pipeline
+	}
+
+	filter := SoftDeleteFilter(row, model.DBM{})
+	if len(filter) == 0 {
+		return pipeline
+	}
+
+	position := 0
+
+	if len(pipeline) > 0 {
+		for _, stage := range []string{"$geoNear", "$search"} {
+			if _, ok := pipeline[0][stage]; ok {
+				position = 1
+			}
+		}
+	}
+
+	stages := make([]model.DBM, 0, len(pipeline)+1)
+	stages = append(stages, pipeline[:position]...)
+	stages = append(stages, model.DBM{"$match": filter})
+
+	return append(stages, pipeline[position:]...)
+}
+
+// RevivingUpdate returns update along with the $set clearing the deleted_at field when row is
+// a model.SoftDeletable, so upserting a deleted row restores it instead of inserting it again.
+// The field is left as it is when update already sets it. The given update is not modified.
+func RevivingUpdate(row model.DBObject, update model.DBM) model.DBM {
+	if _, ok := row.(model.SoftDeletable); !ok {
+		return update
+	}
+
+	reviving := model.DBM{}
+	for key, value := range update {
+		reviving[key] = value
+	}
+
+	addUpdateField(reviving, "$set", model.DeletedAtField, time.Time{})
+
+	return reviving
+}
+
+// TextScoreField is the field where the relevance of the rows matched by a text search is projected.
+const TextScoreField = "_text_score"
+
+// TextSearchQuery returns filter along with the $text condition searching text,
+// and sorted by relevance through the TextScoreField. The given filter is not modified.
+func TextSearchQuery(text string, filter model.DBM) model.DBM {
+	query := model.DBM{}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	query["$text"] = model.DBM{"$search": text}
+	query["_sort"] = "$textScore:" + TextScoreField
+
+	return query
+}
+
+// NormalizeExplain returns the output of an explain command with the queryPlanner and executionStats sections
+// at the top level. The explain of an aggregation whose first stage is run as a query nests them in
+// the $cursor stage instead, so this lets callers read the plan of a query and an aggregation the same way.
+func NormalizeExplain(explain model.DBM) model.DBM {
+	if _, ok := explain["queryPlanner"]; ok {
+		return explain
+	}
+
+	stages := reflect.ValueOf(explain["stages"])
+	if stages.Kind() != reflect.Slice || stages.Len() == 0 {
+		return explain
+	}
+
+	firstStage, ok := stages.Index(0).Interface().(model.DBM)
+	if !ok {
+		return explain
+	}
+
+	cursor, ok := firstStage["$cursor"].(model.DBM)
+	if !ok {
+		return explain
+	}
+
+	for _, section := range []string{"queryPlanner", "executionStats"} {
+		if value, ok := cursor[section]; ok {
+			explain[section] = value
+		}
+	}
+
+	return explain
+}
+
+// aggregateErrors joins errs in a single error with the given message, sorted by their key
+// and prefixed with it along with the given unit (e.g. "line 3: ...").
+func aggregateErrors(message, unit string, errs map[int]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	keys := make([]int, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+
+	sort.Ints(keys)
+
+	messages := make([]string, 0, len(keys))
+	for _, key := range keys {
+		messages = append(messages, fmt.Sprintf("%s %d: %s", unit, key, errs[key]))
+	}
+
+	return errors.New(message + ": " + strings.Join(messages, "; "))
+}
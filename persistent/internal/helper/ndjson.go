@@ -0,0 +1,113 @@
+package helper
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+const (
+	defaultImportBatchSize = 1000
+	errorImport            = "error importing rows"
+)
+
+// ImportOptions returns the options of an import given the "upsert" and "batchSize" keys of opts.
+// If upsert is true, rows whose _id already exists are replaced instead of failing.
+func ImportOptions(opts model.DBM) (upsert bool, batchSize int) {
+	upsert, _ = opts["upsert"].(bool)
+
+	batchSize, ok := opts["batchSize"].(int)
+	if !ok || batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	return upsert, batchSize
+}
+
+// ImportNDJSON reads the newline-delimited JSON documents of r and calls importBatch with batches of up to batchSize
+// documents. importBatch returns the number of imported documents and the errors of the failed ones, keyed by their
+// index in the batch. Malformed lines are skipped, and all the errors are aggregated in the returned error along with
+// the line they refer to.
+func ImportNDJSON(r io.Reader,
+	batchSize int,
+	importBatch ImportBatch,
+) (int, error) {
+	reader := bufio.NewReader(r)
+	lineErrors := map[int]error{}
+	imported := 0
+
+	var lines []int
+	var documents []model.DBM
+
+	flush := func() {
+		n, failed := importBatch(documents)
+		imported += n
+
+		for i, err := range failed {
+			lineErrors[lines[i]] = err
+		}
+
+		lines, documents = nil, nil
+	}
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return imported, readErr
+		}
+
+		if len(strings.TrimSpace(string(line))) > 0 {
+			document, err := decodeNDJSONLine(line)
+			if err != nil {
+				lineErrors[lineNumber] = err
+			} else {
+				lines = append(lines, lineNumber)
+				documents = append(documents, document)
+			}
+		}
+
+		if len(documents) == batchSize || (errors.Is(readErr, io.EOF) && len(documents) > 0) {
+			flush()
+		}
+
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+	}
+
+	return imported, aggregateErrors(errorImport, "line", lineErrors)
+}
+
+// decodeNDJSONLine decodes a JSON document, parsing its hex _id into a model.ObjectID.
+func decodeNDJSONLine(line []byte) (model.DBM, error) {
+	var document model.DBM
+	if err := json.Unmarshal(line, &document); err != nil {
+		return nil, err
+	}
+
+	if id, ok := document["_id"].(string); ok && model.IsObjectIDHex(id) {
+		document["_id"] = model.ObjectIDHex(id)
+	}
+
+	return document, nil
+}
+
+// NewDBObject returns a new zero value of the same type as row, which must be a pointer.
+func NewDBObject(row model.DBObject) (model.DBObject, error) {
+	rowType := reflect.TypeOf(row)
+	if rowType == nil || rowType.Kind() != reflect.Ptr {
+		return nil, errors.New("row must be a pointer to a model.DBObject")
+	}
+
+	newRow, ok := reflect.New(rowType.Elem()).Interface().(model.DBObject)
+	if !ok {
+		return nil, errors.New("row must be a pointer to a model.DBObject")
+	}
+
+	return newRow, nil
+}
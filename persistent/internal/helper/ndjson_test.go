@@ -0,0 +1,96 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type dummyDBObject struct {
+	ID   model.ObjectID `bson:"_id"`
+	Name string         `bson:"name"`
+}
+
+func (d *dummyDBObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyDBObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyDBObject) TableName() string {
+	return "dummy"
+}
+
+func TestImportOptions(t *testing.T) {
+	upsert, batchSize := ImportOptions(model.DBM{})
+	assert.False(t, upsert)
+	assert.Equal(t, defaultImportBatchSize, batchSize)
+
+	upsert, batchSize = ImportOptions(model.DBM{"upsert": true, "batchSize": 10})
+	assert.True(t, upsert)
+	assert.Equal(t, 10, batchSize)
+}
+
+func TestImportNDJSON(t *testing.T) {
+	id := model.NewObjectID()
+
+	t.Run("batches and malformed lines", func(t *testing.T) {
+		input := `{"_id":"` + id.Hex() + `","name":"first"}
+{"name":"second"}
+
+{"name":
+{"name":"third"}`
+
+		var batches [][]model.DBM
+
+		imported, err := ImportNDJSON(strings.NewReader(input), 2, func(documents []model.DBM) (int, map[int]error) {
+			batches = append(batches, documents)
+			return len(documents), nil
+		})
+
+		assert.Equal(t, 3, imported)
+		assert.NotNil(t, err)
+		assert.Equal(t, "error importing rows: line 4: unexpected end of JSON input", err.Error())
+
+		assert.Equal(t, [][]model.DBM{
+			{{"_id": id, "name": "first"}, {"name": "second"}},
+			{{"name": "third"}},
+		}, batches)
+	})
+
+	t.Run("failed documents report their line", func(t *testing.T) {
+		input := "{\"name\":\"first\"}\n{\"name\":\"second\"}\n{\"name\":\"third\"}\n"
+
+		imported, err := ImportNDJSON(strings.NewReader(input), 10, func(documents []model.DBM) (int, map[int]error) {
+			return len(documents) - 1, map[int]error{1: errors.New("duplicate key")}
+		})
+
+		assert.Equal(t, 2, imported)
+		assert.NotNil(t, err)
+		assert.Equal(t, "error importing rows: line 2: duplicate key", err.Error())
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		imported, err := ImportNDJSON(strings.NewReader(""), 10, func(documents []model.DBM) (int, map[int]error) {
+			t.Fatal("importBatch must not be called without documents")
+			return 0, nil
+		})
+
+		assert.Equal(t, 0, imported)
+		assert.Nil(t, err)
+	})
+}
+
+func TestNewDBObject(t *testing.T) {
+	row, err := NewDBObject(&dummyDBObject{Name: "test"})
+	assert.Nil(t, err)
+	assert.Equal(t, &dummyDBObject{}, row)
+
+	_, err = NewDBObject(nil)
+	assert.NotNil(t, err)
+}
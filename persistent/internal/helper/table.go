@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"context"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+type tableKey struct{}
+
+// WithTable returns a copy of ctx running the operations of the drivers on table instead of the table of their rows,
+// e.g. on a shard of it.
+func WithTable(ctx context.Context, table string) context.Context {
+	return context.WithValue(ctx, tableKey{}, table)
+}
+
+// TableName returns the table the operations on row run on: the one set on ctx by WithTable, or the table of row.
+func TableName(ctx context.Context, row model.DBObject) string {
+	if table, ok := ctx.Value(tableKey{}).(string); ok {
+		return table
+	}
+
+	return row.TableName()
+}
@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// SetInsertTimestamps sets the creation and update time of the rows that are model.Timestamped.
+func SetInsertTimestamps(rows ...model.DBObject) {
+	now := timestamp()
+
+	for _, row := range rows {
+		if timestamped, ok := row.(model.Timestamped); ok {
+			timestamped.SetCreatedAt(now)
+			timestamped.SetUpdatedAt(now)
+		}
+	}
+}
+
+// SetUpdateTimestamps sets the update time of the rows that are model.Timestamped.
+func SetUpdateTimestamps(rows ...model.DBObject) {
+	now := timestamp()
+
+	for _, row := range rows {
+		if timestamped, ok := row.(model.Timestamped); ok {
+			timestamped.SetUpdatedAt(now)
+		}
+	}
+}
+
+// TimestampedUpdate returns update along with the $set of the update time and the $setOnInsert of the
+// creation time, when row is model.Timestamped. The fields already updated by update are left as they are.
+// The given update is not modified.
+func TimestampedUpdate(row model.DBObject, update model.DBM) model.DBM {
+	if _, ok := row.(model.Timestamped); !ok {
+		return update
+	}
+
+	timestamped := model.DBM{}
+	for key, value := range update {
+		timestamped[key] = value
+	}
+
+	now := timestamp()
+
+	addUpdateField(timestamped, "$set", model.UpdatedAtField, now)
+	addUpdateField(timestamped, "$setOnInsert", model.CreatedAtField, now)
+
+	return timestamped
+}
+
+// addUpdateField sets field to value through the op update operator, unless any operator of update
+// already updates field, as an update can't set a field twice.
+func addUpdateField(update model.DBM, op, field string, value interface{}) {
+	for _, fields := range update {
+		if fields, ok := fields.(model.DBM); ok {
+			if _, found := fields[field]; found {
+				return
+			}
+		}
+	}
+
+	fields, found := update[op]
+	if !found {
+		update[op] = model.DBM{field: value}
+		return
+	}
+
+	if fields, ok := fields.(model.DBM); ok {
+		opFields := model.DBM{field: value}
+		for key, value := range fields {
+			opFields[key] = value
+		}
+
+		update[op] = opFields
+	}
+}
+
+// timestamp returns the current time as stored by the database, in UTC and with millisecond precision.
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
@@ -0,0 +1,98 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type dummyTimestampedObject struct {
+	dummyDBObject
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func (d *dummyTimestampedObject) SetCreatedAt(createdAt time.Time) {
+	d.CreatedAt = createdAt
+}
+
+func (d *dummyTimestampedObject) SetUpdatedAt(updatedAt time.Time) {
+	d.UpdatedAt = updatedAt
+}
+
+func TestSetTimestamps(t *testing.T) {
+	before := time.Now().UTC().Truncate(time.Millisecond)
+
+	timestamped := &dummyTimestampedObject{}
+	notTimestamped := &dummyDBObject{}
+
+	SetInsertTimestamps(timestamped, notTimestamped)
+	assert.Equal(t, timestamped.CreatedAt, timestamped.UpdatedAt)
+	assert.False(t, timestamped.CreatedAt.Before(before))
+	assert.Equal(t, time.UTC, timestamped.CreatedAt.Location())
+	assert.Equal(t, 0, timestamped.CreatedAt.Nanosecond()%int(time.Millisecond))
+
+	createdAt := timestamped.CreatedAt
+
+	time.Sleep(2 * time.Millisecond)
+	SetUpdateTimestamps(timestamped, notTimestamped)
+	assert.Equal(t, createdAt, timestamped.CreatedAt)
+	assert.True(t, timestamped.UpdatedAt.After(createdAt))
+	assert.Equal(t, &dummyDBObject{}, notTimestamped)
+}
+
+func TestTimestampedUpdate(t *testing.T) {
+	tcs := []struct {
+		testName string
+		row      model.DBObject
+		update   model.DBM
+		expected func(now time.Time) model.DBM
+	}{
+		{
+			testName: "not timestamped row",
+			row:      &dummyDBObject{},
+			update:   model.DBM{"$set": model.DBM{"name": "tyk"}},
+			expected: func(now time.Time) model.DBM {
+				return model.DBM{"$set": model.DBM{"name": "tyk"}}
+			},
+		},
+		{
+			testName: "timestamped row",
+			row:      &dummyTimestampedObject{},
+			update:   model.DBM{"$set": model.DBM{"name": "tyk"}, "$inc": model.DBM{"age": 1}},
+			expected: func(now time.Time) model.DBM {
+				return model.DBM{
+					"$set":         model.DBM{"name": "tyk", "updated_at": now},
+					"$setOnInsert": model.DBM{"created_at": now},
+					"$inc":         model.DBM{"age": 1},
+				}
+			},
+		},
+		{
+			testName: "timestamps already updated",
+			row:      &dummyTimestampedObject{},
+			update:   model.DBM{"$set": model.DBM{"created_at": "given"}, "$unset": model.DBM{"updated_at": ""}},
+			expected: func(now time.Time) model.DBM {
+				return model.DBM{"$set": model.DBM{"created_at": "given"}, "$unset": model.DBM{"updated_at": ""}}
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			given := model.DBM{}
+			for key, value := range tc.update {
+				given[key] = value
+			}
+
+			result := TimestampedUpdate(tc.row, given)
+
+			// the time is taken from the result, as it's set by TimestampedUpdate
+			now, _ := result["$set"].(model.DBM)["updated_at"].(time.Time)
+			assert.Equal(t, tc.expected(now), result)
+			assert.Equal(t, tc.update, given)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+const defaultCircuitOpenTimeout = 30 * time.Second
+
+type circuitBreaker struct {
+	opts types.CircuitBreakerOpts
+	// now returns the current time, replaced in the tests.
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    types.CircuitState
+	failures int
+	// successes is the number of consecutive successful probes while half-open.
+	successes int
+	openedAt  time.Time
+	// probing is set while a probe runs, so only one of them runs at once.
+	probing bool
+}
+
+// CircuitBreaker returns a Handler failing the operations with types.ErrCircuitOpen while the circuit
+// is open, as set by opts. The operations run within a transaction aren't counted on their own,
+// the whole transaction is.
+func CircuitBreaker(opts types.CircuitBreakerOpts) Handler {
+	return newCircuitBreaker(opts).handle
+}
+
+func newCircuitBreaker(opts types.CircuitBreakerOpts) *circuitBreaker {
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = defaultCircuitOpenTimeout
+	}
+
+	if opts.SuccessThreshold <= 0 {
+		opts.SuccessThreshold = 1
+	}
+
+	if opts.IsFailure == nil {
+		opts.IsFailure = utils.IsTransientError
+	}
+
+	return &circuitBreaker{opts: opts, now: time.Now}
+}
+
+func (b *circuitBreaker) handle(ctx context.Context, op Operation, run func(ctx context.Context) error) error {
+	if op.InTransaction {
+		return run(ctx)
+	}
+
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = run(ctx)
+	b.done(probe, err != nil && b.opts.IsFailure(err))
+
+	return err
+}
+
+// allow returns an error if the operation can't run, and whether it runs as a probe of the half-open circuit.
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+
+	from := b.state
+
+	if b.state == types.CircuitOpen && b.now().Sub(b.openedAt) >= b.opts.OpenTimeout {
+		b.state = types.CircuitHalfOpen
+		b.successes = 0
+	}
+
+	var (
+		probe bool
+		err   error
+	)
+
+	switch {
+	case b.state == types.CircuitOpen, b.state == types.CircuitHalfOpen && b.probing:
+		err = types.ErrCircuitOpen
+	case b.state == types.CircuitHalfOpen:
+		b.probing = true
+		probe = true
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return probe, err
+}
+
+// done records the result of an operation allowed to run.
+func (b *circuitBreaker) done(probe, failed bool) {
+	b.mu.Lock()
+
+	from := b.state
+
+	if probe {
+		b.probing = false
+	}
+
+	switch {
+	case failed && (b.state == types.CircuitHalfOpen || b.failures+1 >= b.opts.FailureThreshold):
+		b.state = types.CircuitOpen
+		b.openedAt = b.now()
+		b.failures = 0
+	case failed:
+		b.failures++
+	case b.state == types.CircuitHalfOpen && probe:
+		b.successes++
+		if b.successes >= b.opts.SuccessThreshold {
+			b.state = types.CircuitClosed
+		}
+	default:
+		b.failures = 0
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// notify calls the OnStateChange callback if the state changed. It's called without holding the lock,
+// so the callback can use the storage.
+func (b *circuitBreaker) notify(from, to types.CircuitState) {
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
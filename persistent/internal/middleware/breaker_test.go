@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	errFailure := errors.New("failure")
+	errCircuitOpen := errors.New(types.ErrorCircuitOpen)
+
+	var changes []string
+
+	b := newCircuitBreaker(types.CircuitBreakerOpts{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		SuccessThreshold: 2,
+		IsFailure: func(err error) bool {
+			return errors.Is(err, errFailure)
+		},
+		OnStateChange: func(from, to types.CircuitState) {
+			changes = append(changes, from.String()+" -> "+to.String())
+		},
+	})
+
+	now := time.Now()
+	b.now = func() time.Time {
+		return now
+	}
+
+	runs := 0
+	run := func(err error) error {
+		return b.handle(ctx, Operation{}, func(context.Context) error {
+			runs++
+			return err
+		})
+	}
+
+	// errors which aren't failures don't open the circuit, and a success resets the failures
+	assert.Equal(t, errFailure, run(errFailure))
+	assert.Equal(t, errors.New("not found"), run(errors.New("not found")))
+	assert.Nil(t, run(nil))
+	assert.Equal(t, errFailure, run(errFailure))
+	assert.Empty(t, changes)
+
+	// the circuit opens after the consecutive failures and fails fast
+	assert.Equal(t, errFailure, run(errFailure))
+	assert.Equal(t, []string{"closed -> open"}, changes)
+
+	runs = 0
+	assert.Equal(t, errCircuitOpen, run(nil))
+	assert.Equal(t, 0, runs)
+
+	// a failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	assert.Equal(t, errFailure, run(errFailure))
+	assert.Equal(t, []string{"closed -> open", "open -> half-open", "half-open -> open"}, changes)
+	assert.Equal(t, errCircuitOpen, run(nil))
+
+	// successful probes close the circuit
+	now = now.Add(time.Minute)
+	assert.Nil(t, run(nil))
+	assert.Nil(t, run(nil))
+	assert.Equal(t, types.CircuitClosed, b.state)
+	assert.Equal(t, []string{
+		"closed -> open", "open -> half-open", "half-open -> open", "open -> half-open", "half-open -> closed",
+	}, changes)
+
+	// the operations within a transaction aren't counted
+	for i := 0; i < 3; i++ {
+		err := b.handle(ctx, Operation{InTransaction: true}, func(context.Context) error {
+			return errFailure
+		})
+		assert.Equal(t, errFailure, err)
+	}
+
+	assert.Equal(t, types.CircuitClosed, b.state)
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	b := newCircuitBreaker(types.CircuitBreakerOpts{FailureThreshold: 1})
+	b.state = types.CircuitHalfOpen
+
+	err := b.handle(context.Background(), Operation{}, func(ctx context.Context) error {
+		// another operation can't run while probing
+		return b.handle(ctx, Operation{}, func(context.Context) error {
+			return nil
+		})
+	})
+	assert.Equal(t, errors.New(types.ErrorCircuitOpen), err)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/TykTechnologies/storage/persistent/utils"
+	storagetypes "github.com/TykTechnologies/storage/types"
+)
+
+// Errors returns a Handler wrapping the errors of the operations in a *storagetypes.StorageError, categorized
+// by utils.ErrorCode, along with the driver, the name of the operation and its table. The message of the errors
+// is kept, and the errors of the driver can still be checked with errors.Is and errors.As.
+func Errors(driver string) Handler {
+	return func(ctx context.Context, op Operation, run func(ctx context.Context) error) error {
+		err := run(ctx)
+		if err == nil {
+			return nil
+		}
+
+		return &storagetypes.StorageError{
+			Code:   utils.ErrorCode(err),
+			Driver: driver,
+			Op:     op.Name,
+			Table:  op.Table,
+			Err:    err,
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+
+	storagetypes "github.com/TykTechnologies/storage/types"
+)
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	handler := Errors("mongo-go")
+	op := Operation{Name: "Query", Table: "dummy"}
+
+	err := handler(ctx, op, func(context.Context) error {
+		return nil
+	})
+	assert.Nil(t, err)
+
+	err = handler(ctx, op, func(context.Context) error {
+		return mgo.ErrNotFound
+	})
+
+	var storageErr *storagetypes.StorageError
+	assert.True(t, errors.As(err, &storageErr))
+	assert.Equal(t, storagetypes.CodeNotFound, storageErr.Code)
+	assert.Equal(t, "mongo-go", storageErr.Driver)
+	assert.Equal(t, "Query", storageErr.Op)
+	assert.Equal(t, "dummy", storageErr.Table)
+	assert.EqualError(t, err, mgo.ErrNotFound.Error())
+	assert.ErrorIs(t, err, mgo.ErrNotFound)
+	assert.ErrorIs(t, err, storagetypes.ErrNotFound)
+
+	errFailure := errors.New("failure")
+	err = handler(ctx, op, func(context.Context) error {
+		return errFailure
+	})
+	assert.ErrorIs(t, err, errFailure)
+	assert.False(t, errors.Is(err, storagetypes.ErrNotFound))
+}
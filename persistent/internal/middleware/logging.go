@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+// sanitizedValue replaces the values of the filters in the logs, so no sensitive data is logged.
+const sanitizedValue = "?"
+
+// Logging returns a Handler reporting to logger the operations failing with an error other than not found,
+// and the operations taking longer than threshold, if it's greater than 0. The values of their filters
+// are sanitized, only the fields, the operators and the meta keys such as _sort or _limit are logged.
+func Logging(logger types.Logger, driver string, threshold time.Duration) Handler {
+	return func(ctx context.Context, op Operation, run func(ctx context.Context) error) error {
+		start := time.Now()
+		err := run(ctx)
+		duration := time.Since(start)
+
+		slow := threshold > 0 && duration > threshold
+		failed := err != nil && !utils.IsErrNoRows(err)
+
+		if !slow && !failed {
+			return err
+		}
+
+		fields := map[string]interface{}{
+			"driver":    driver,
+			"operation": op.Name,
+			"duration":  duration,
+		}
+
+		if op.Table != "" {
+			fields["table"] = op.Table
+		}
+
+		if op.Filter != nil {
+			fields["filter"] = Sanitize(op.Filter)
+		}
+
+		if op.Pipeline != nil {
+			pipeline := make([]model.DBM, 0, len(op.Pipeline))
+			for _, stage := range op.Pipeline {
+				pipeline = append(pipeline, Sanitize(stage))
+			}
+
+			fields["pipeline"] = pipeline
+		}
+
+		if failed {
+			fields["error"] = err.Error()
+			logger.Error("persistent storage operation failed", fields)
+
+			return err
+		}
+
+		logger.Warn("slow persistent storage operation", fields)
+
+		return err
+	}
+}
+
+// Sanitize returns a copy of filter whose values are replaced with "?", keeping its fields, operators
+// and meta keys, so it can be logged without leaking the data it's filtering by.
+func Sanitize(filter model.DBM) model.DBM {
+	sanitized := make(model.DBM, len(filter))
+
+	for key, value := range filter {
+		if strings.HasPrefix(key, "_") && key != "_id" {
+			sanitized[key] = value
+			continue
+		}
+
+		sanitized[key] = sanitizeValue(value)
+	}
+
+	return sanitized
+}
+
+func sanitizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case model.DBM:
+		return Sanitize(v)
+	case map[string]interface{}:
+		return Sanitize(v)
+	case []model.DBM:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, Sanitize(item))
+		}
+
+		return values
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, sanitizeValue(item))
+		}
+
+		return values
+	default:
+		return sanitizedValue
+	}
+}
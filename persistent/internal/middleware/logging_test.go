@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+type fakeLogger struct {
+	entries []logEntry
+}
+
+func (l *fakeLogger) Warn(msg string, fields map[string]interface{}) {
+	l.entries = append(l.entries, logEntry{level: "warn", msg: msg, fields: fields})
+}
+
+func (l *fakeLogger) Error(msg string, fields map[string]interface{}) {
+	l.entries = append(l.entries, logEntry{level: "error", msg: msg, fields: fields})
+}
+
+func TestLogging(t *testing.T) {
+	ctx := context.Background()
+	op := Operation{Name: "Query", Table: "dummy", Filter: model.DBM{"email": "test@tyk.io"}}
+
+	tcs := []struct {
+		testName      string
+		threshold     time.Duration
+		runErr        error
+		expectedLevel string
+		expectedMsg   string
+	}{
+		{
+			testName:  "fast operation",
+			threshold: time.Hour,
+		},
+		{
+			testName:      "slow operation",
+			threshold:     time.Nanosecond,
+			expectedLevel: "warn",
+			expectedMsg:   "slow persistent storage operation",
+		},
+		{
+			testName: "slow operations not logged without threshold",
+		},
+		{
+			testName:      "failed operation",
+			runErr:        errors.New("test"),
+			expectedLevel: "error",
+			expectedMsg:   "persistent storage operation failed",
+		},
+		{
+			testName: "not found error",
+			runErr:   mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			logger := &fakeLogger{}
+
+			err := Logging(logger, "mongo-go", tc.threshold)(ctx, op, func(context.Context) error {
+				time.Sleep(time.Millisecond)
+				return tc.runErr
+			})
+			assert.Equal(t, tc.runErr, err)
+
+			if tc.expectedLevel == "" {
+				assert.Empty(t, logger.entries)
+				return
+			}
+
+			assert.Len(t, logger.entries, 1)
+			assert.Equal(t, tc.expectedLevel, logger.entries[0].level)
+			assert.Equal(t, tc.expectedMsg, logger.entries[0].msg)
+
+			fields := logger.entries[0].fields
+			assert.Equal(t, "mongo-go", fields["driver"])
+			assert.Equal(t, "Query", fields["operation"])
+			assert.Equal(t, "dummy", fields["table"])
+			assert.Equal(t, model.DBM{"email": "?"}, fields["filter"])
+			assert.GreaterOrEqual(t, fields["duration"], time.Millisecond)
+
+			if tc.runErr != nil {
+				assert.Equal(t, tc.runErr.Error(), fields["error"])
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tcs := []struct {
+		testName string
+		given    model.DBM
+		expected model.DBM
+	}{
+		{
+			testName: "values",
+			given:    model.DBM{"_id": "id", "email": "test@tyk.io", "age": 10},
+			expected: model.DBM{"_id": "?", "email": "?", "age": "?"},
+		},
+		{
+			testName: "operators",
+			given:    model.DBM{"age": model.DBM{"$gt": 10, "$lt": 20}, "name": model.DBM{"$in": []interface{}{"a", "b"}}},
+			expected: model.DBM{"age": model.DBM{"$gt": "?", "$lt": "?"}, "name": model.DBM{"$in": []interface{}{"?", "?"}}},
+		},
+		{
+			testName: "logical operators",
+			given:    model.DBM{"$or": []model.DBM{{"email": "test@tyk.io"}, {"name": "tyk"}}},
+			expected: model.DBM{"$or": []interface{}{model.DBM{"email": "?"}, model.DBM{"name": "?"}}},
+		},
+		{
+			testName: "meta keys",
+			given:    model.DBM{"email": "test@tyk.io", "_sort": "-name", "_limit": 10},
+			expected: model.DBM{"email": "?", "_sort": "-name", "_limit": 10},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Sanitize(tc.given))
+		})
+	}
+}
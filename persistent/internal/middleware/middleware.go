@@ -0,0 +1,518 @@
+// Package middleware wraps a persistent storage so each of its operations runs through a Handler,
+// which can observe, alter or short-circuit it.
+package middleware
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+// Operation describes an operation of the storage.
+type Operation struct {
+	// Name is the name of the PersistentStorage method, e.g. "Query".
+	Name string
+	// Table is the table the operation runs against, empty if it doesn't run against one.
+	Table string
+	// Filter is the filter or query of the operation, if any.
+	Filter model.DBM
+	// Pipeline is the aggregation pipeline of the operation, if any.
+	Pipeline []model.DBM
+	// InTransaction is set for the operations run within WithTransaction.
+	InTransaction bool
+}
+
+// Handler runs the operation op by calling run, with the same or a derived context.
+// It returns the error of run, or its own error if it doesn't call it.
+type Handler func(ctx context.Context, op Operation, run func(ctx context.Context) error) error
+
+type storage struct {
+	next          types.PersistentStorage
+	handler       Handler
+	inTransaction bool
+}
+
+// Wrap returns a persistent storage running the operations of next through handler.
+// The operations run within WithTransaction go through handler as well.
+func Wrap(next types.PersistentStorage, handler Handler) types.PersistentStorage {
+	return &storage{next: next, handler: handler}
+}
+
+func (s *storage) handle(ctx context.Context, op Operation, run func(ctx context.Context) error) error {
+	op.InTransaction = s.inTransaction
+	return s.handler(ctx, op, run)
+}
+
+// tableName returns the table of the first of rows, or an empty string if there's none.
+func tableName(rows ...model.DBObject) string {
+	if len(rows) == 0 || rows[0] == nil {
+		return ""
+	}
+
+	return rows[0].TableName()
+}
+
+// first returns the first of filters, or nil if there's none.
+func first(filters []model.DBM) model.DBM {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return filters[0]
+}
+
+// Health reports the error of the handler in the status if it doesn't run the check, e.g. while a circuit is open.
+func (s *storage) Health(ctx context.Context) model.HealthStatus {
+	var status model.HealthStatus
+
+	checked := false
+
+	err := s.handle(ctx, Operation{Name: "Health"}, func(ctx context.Context) error {
+		status = s.next.Health(ctx)
+		checked = true
+
+		return status.Err
+	})
+	if err != nil && !checked {
+		status = model.HealthStatus{CheckedAt: time.Now(), Err: err}
+	}
+
+	return status
+}
+
+func (s *storage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	return s.handle(ctx, Operation{Name: "Insert", Table: tableName(rows...)}, func(ctx context.Context) error {
+		return s.next.Insert(ctx, rows...)
+	})
+}
+
+func (s *storage) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	var n int
+
+	err := s.handle(ctx, Operation{Name: "BulkInsert", Table: tableName(rows...)}, func(ctx context.Context) (err error) {
+		n, err = s.next.BulkInsert(ctx, rows, opts)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) Delete(ctx context.Context, row model.DBObject, query ...model.DBM) error {
+	op := Operation{Name: "Delete", Table: tableName(row), Filter: first(query)}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.Delete(ctx, row, query...)
+	})
+}
+
+func (s *storage) Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error) {
+	var n int
+
+	err := s.handle(ctx, Operation{Name: "Purge", Table: tableName(row)}, func(ctx context.Context) (err error) {
+		n, err = s.next.Purge(ctx, row, olderThan)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error) {
+	var n int64
+
+	op := Operation{Name: "DeleteWithResult", Table: tableName(row), Filter: filter}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		n, err = s.next.DeleteWithResult(ctx, row, filter)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) Update(ctx context.Context, row model.DBObject, query ...model.DBM) error {
+	op := Operation{Name: "Update", Table: tableName(row), Filter: first(query)}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.Update(ctx, row, query...)
+	})
+}
+
+func (s *storage) CountWithOpts(ctx context.Context,
+	row model.DBObject,
+	opts model.CountOpts,
+	filter ...model.DBM,
+) (int, error) {
+	var n int
+
+	op := Operation{Name: "CountWithOpts", Table: tableName(row), Filter: first(filter)}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		n, err = s.next.CountWithOpts(ctx, row, opts, filter...)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) Count(ctx context.Context, row model.DBObject, filter ...model.DBM) (int, error) {
+	var n int
+
+	op := Operation{Name: "Count", Table: tableName(row), Filter: first(filter)}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		n, err = s.next.Count(ctx, row, filter...)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	op := Operation{Name: "Query", Table: tableName(row), Filter: query}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.Query(ctx, row, result, query)
+	})
+}
+
+func (s *storage) SearchText(ctx context.Context,
+	row model.DBObject,
+	result interface{},
+	text string,
+	filter model.DBM,
+) error {
+	op := Operation{Name: "SearchText", Table: tableName(row), Filter: filter}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.SearchText(ctx, row, result, text, filter)
+	})
+}
+
+// Watch only runs the start of the watch through the handler, not the events sent afterwards.
+func (s *storage) Watch(ctx context.Context, row model.DBObject, filter model.DBM) (<-chan model.ChangeEvent, error) {
+	var events <-chan model.ChangeEvent
+
+	op := Operation{Name: "Watch", Table: tableName(row), Filter: filter}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		events, err = s.next.Watch(ctx, row, filter)
+		return err
+	})
+
+	return events, err
+}
+
+func (s *storage) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	var cursor model.Cursor
+
+	op := Operation{Name: "QueryCursor", Table: tableName(row), Filter: query}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		cursor, err = s.next.QueryCursor(ctx, row, query)
+		return err
+	})
+
+	return cursor, err
+}
+
+func (s *storage) ListPage(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+	page model.PageRequest,
+) (model.PageResult, error) {
+	var result model.PageResult
+
+	op := Operation{Name: "ListPage", Table: tableName(row), Filter: filter}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		result, err = s.next.ListPage(ctx, row, filter, page)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *storage) Distinct(ctx context.Context,
+	row model.DBObject,
+	field string,
+	filter model.DBM,
+) ([]interface{}, error) {
+	var values []interface{}
+
+	op := Operation{Name: "Distinct", Table: tableName(row), Filter: filter}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		values, err = s.next.Distinct(ctx, row, field, filter)
+		return err
+	})
+
+	return values, err
+}
+
+func (s *storage) BulkUpdate(ctx context.Context, rows []model.DBObject, query ...model.DBM) error {
+	op := Operation{Name: "BulkUpdate", Table: tableName(rows...), Filter: first(query)}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.BulkUpdate(ctx, rows, query...)
+	})
+}
+
+func (s *storage) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	op := Operation{Name: "UpdateAll", Table: tableName(row), Filter: query}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.UpdateAll(ctx, row, query, update)
+	})
+}
+
+func (s *storage) Drop(ctx context.Context, row model.DBObject) error {
+	return s.handle(ctx, Operation{Name: "Drop", Table: tableName(row)}, func(ctx context.Context) error {
+		return s.next.Drop(ctx, row)
+	})
+}
+
+func (s *storage) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	return s.handle(ctx, Operation{Name: "CreateIndex", Table: tableName(row)}, func(ctx context.Context) error {
+		return s.next.CreateIndex(ctx, row, index)
+	})
+}
+
+func (s *storage) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	var indexes []model.Index
+
+	err := s.handle(ctx, Operation{Name: "GetIndexes", Table: tableName(row)}, func(ctx context.Context) (err error) {
+		indexes, err = s.next.GetIndexes(ctx, row)
+		return err
+	})
+
+	return indexes, err
+}
+
+func (s *storage) Ping(ctx context.Context) error {
+	return s.handle(ctx, Operation{Name: "Ping"}, s.next.Ping)
+}
+
+func (s *storage) HasTable(ctx context.Context, name string) (bool, error) {
+	var found bool
+
+	err := s.handle(ctx, Operation{Name: "HasTable", Table: name}, func(ctx context.Context) (err error) {
+		found, err = s.next.HasTable(ctx, name)
+		return err
+	})
+
+	return found, err
+}
+
+func (s *storage) DropDatabase(ctx context.Context) error {
+	return s.handle(ctx, Operation{Name: "DropDatabase"}, s.next.DropDatabase)
+}
+
+func (s *storage) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
+	return s.handle(ctx, Operation{Name: "Migrate", Table: tableName(rows...)}, func(ctx context.Context) error {
+		return s.next.Migrate(ctx, rows, opts...)
+	})
+}
+
+func (s *storage) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
+	var stats model.DBM
+
+	err := s.handle(ctx, Operation{Name: "DBTableStats", Table: tableName(row)}, func(ctx context.Context) (err error) {
+		stats, err = s.next.DBTableStats(ctx, row)
+		return err
+	})
+
+	return stats, err
+}
+
+func (s *storage) Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error) {
+	var result []model.DBM
+
+	op := Operation{Name: "Aggregate", Table: tableName(row), Pipeline: query}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		result, err = s.next.Aggregate(ctx, row, query)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *storage) Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error) {
+	var result model.DBM
+
+	op := Operation{Name: "Explain", Table: tableName(row), Filter: filter}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		result, err = s.next.Explain(ctx, row, filter)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *storage) ExplainAggregate(ctx context.Context, row model.DBObject, query []model.DBM) (model.DBM, error) {
+	var result model.DBM
+
+	op := Operation{Name: "ExplainAggregate", Table: tableName(row), Pipeline: query}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		result, err = s.next.ExplainAggregate(ctx, row, query)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *storage) CleanIndexes(ctx context.Context, row model.DBObject) error {
+	return s.handle(ctx, Operation{Name: "CleanIndexes", Table: tableName(row)}, func(ctx context.Context) error {
+		return s.next.CleanIndexes(ctx, row)
+	})
+}
+
+func (s *storage) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	op := Operation{Name: "Upsert", Table: tableName(row), Filter: query}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.Upsert(ctx, row, query, update)
+	})
+}
+
+func (s *storage) FindOneAndUpdate(ctx context.Context,
+	row model.DBObject,
+	query, update model.DBM,
+	opts ...model.FindOneOpts,
+) error {
+	op := Operation{Name: "FindOneAndUpdate", Table: tableName(row), Filter: query}
+
+	return s.handle(ctx, op, func(ctx context.Context) error {
+		return s.next.FindOneAndUpdate(ctx, row, query, update, opts...)
+	})
+}
+
+func (s *storage) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
+	var info utils.Info
+
+	err := s.handle(ctx, Operation{Name: "GetDatabaseInfo"}, func(ctx context.Context) (err error) {
+		info, err = s.next.GetDatabaseInfo(ctx)
+		return err
+	})
+
+	return info, err
+}
+
+func (s *storage) GetTables(ctx context.Context) ([]string, error) {
+	var tables []string
+
+	err := s.handle(ctx, Operation{Name: "GetTables"}, func(ctx context.Context) (err error) {
+		tables, err = s.next.GetTables(ctx)
+		return err
+	})
+
+	return tables, err
+}
+
+func (s *storage) DropTable(ctx context.Context, name string) (int, error) {
+	var n int
+
+	err := s.handle(ctx, Operation{Name: "DropTable", Table: name}, func(ctx context.Context) (err error) {
+		n, err = s.next.DropTable(ctx, name)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error) {
+	var n int
+
+	op := Operation{Name: "ExportNDJSON", Table: tableName(row), Filter: query}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		n, err = s.next.ExportNDJSON(ctx, row, query, w)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) ImportNDJSON(ctx context.Context, row model.DBObject, r io.Reader, opts ...model.DBM) (int, error) {
+	var n int
+
+	err := s.handle(ctx, Operation{Name: "ImportNDJSON", Table: tableName(row)}, func(ctx context.Context) (err error) {
+		n, err = s.next.ImportNDJSON(ctx, row, r, opts...)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) Export(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+	w io.Writer,
+	format model.ExportFormat,
+) (int, error) {
+	var n int
+
+	op := Operation{Name: "Export", Table: tableName(row), Filter: query}
+
+	err := s.handle(ctx, op, func(ctx context.Context) (err error) {
+		n, err = s.next.Export(ctx, row, query, w, format)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) Import(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	format model.ExportFormat,
+	opts ...model.DBM,
+) (int, error) {
+	var n int
+
+	err := s.handle(ctx, Operation{Name: "Import", Table: tableName(row)}, func(ctx context.Context) (err error) {
+		n, err = s.next.Import(ctx, row, r, format, opts...)
+		return err
+	})
+
+	return n, err
+}
+
+func (s *storage) SessionSettings(ctx context.Context) (model.DBM, error) {
+	var settings model.DBM
+
+	err := s.handle(ctx, Operation{Name: "SessionSettings"}, func(ctx context.Context) (err error) {
+		settings, err = s.next.SessionSettings(ctx)
+		return err
+	})
+
+	return settings, err
+}
+
+func (s *storage) ExistingIDs(ctx context.Context,
+	row model.DBObject,
+	ids []model.ObjectID,
+) ([]model.ObjectID, error) {
+	var existing []model.ObjectID
+
+	err := s.handle(ctx, Operation{Name: "ExistingIDs", Table: tableName(row)}, func(ctx context.Context) (err error) {
+		existing, err = s.next.ExistingIDs(ctx, row, ids)
+		return err
+	})
+
+	return existing, err
+}
+
+func (s *storage) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	return s.handle(ctx, Operation{Name: "WithTransaction"}, func(ctx context.Context) error {
+		return s.next.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+			return fn(&storage{next: tx, handler: s.handler, inTransaction: true})
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+type dummyDBObject struct {
+	ID model.ObjectID `bson:"_id,omitempty"`
+}
+
+func (d *dummyDBObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyDBObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyDBObject) TableName() string {
+	return "dummy"
+}
+
+// fakeStorage returns err from the operations used by the tests. Calling any other method panics.
+type fakeStorage struct {
+	types.PersistentStorage
+	count int
+	err   error
+}
+
+func (s *fakeStorage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	return s.err
+}
+
+func (s *fakeStorage) Count(ctx context.Context, row model.DBObject, filter ...model.DBM) (int, error) {
+	return s.count, s.err
+}
+
+func (s *fakeStorage) Health(ctx context.Context) model.HealthStatus {
+	return model.HealthStatus{Live: s.err == nil, Err: s.err}
+}
+
+func (s *fakeStorage) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	return fn(s)
+}
+
+// recorder returns a Handler recording the operations it runs.
+func recorder(ops *[]Operation) Handler {
+	return func(ctx context.Context, op Operation, run func(ctx context.Context) error) error {
+		*ops = append(*ops, op)
+		return run(ctx)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("runs the operations through the handler", func(t *testing.T) {
+		var ops []Operation
+
+		storage := Wrap(&fakeStorage{count: 3, err: errors.New("test")}, recorder(&ops))
+
+		count, err := storage.Count(ctx, &dummyDBObject{}, model.DBM{"name": "tyk"})
+		assert.Equal(t, errors.New("test"), err)
+		assert.Equal(t, 3, count)
+		assert.Equal(t, []Operation{{Name: "Count", Table: "dummy", Filter: model.DBM{"name": "tyk"}}}, ops)
+	})
+
+	t.Run("short-circuits the operations", func(t *testing.T) {
+		storage := Wrap(&fakeStorage{count: 3}, func(context.Context, Operation, func(context.Context) error) error {
+			return errors.New("rejected")
+		})
+
+		count, err := storage.Count(ctx, &dummyDBObject{})
+		assert.Equal(t, errors.New("rejected"), err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("reports the error of the handler in the health status", func(t *testing.T) {
+		storage := Wrap(&fakeStorage{}, func(context.Context, Operation, func(context.Context) error) error {
+			return errors.New("rejected")
+		})
+
+		status := storage.Health(ctx)
+		assert.False(t, status.Live)
+		assert.Equal(t, errors.New("rejected"), status.Err)
+
+		storage = Wrap(&fakeStorage{}, recorder(&[]Operation{}))
+		assert.True(t, storage.Health(ctx).Live)
+	})
+
+	t.Run("runs the operations within a transaction through the handler", func(t *testing.T) {
+		var ops []Operation
+
+		storage := Wrap(&fakeStorage{}, recorder(&ops))
+
+		err := storage.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+			return tx.Insert(ctx, &dummyDBObject{})
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []Operation{
+			{Name: "WithTransaction"},
+			{Name: "Insert", Table: "dummy", InTransaction: true},
+		}, ops)
+	})
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+const (
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// idempotentOperations are the operations which can run again after an ambiguous failure, such as a network error
+// raised once a write was applied, with the same outcome: the reads, and the writes setting fixed values.
+var idempotentOperations = map[string]bool{
+	"Aggregate":        true,
+	"BulkUpdate":       true,
+	"CleanIndexes":     true,
+	"Count":            true,
+	"CountWithOpts":    true,
+	"CreateIndex":      true,
+	"DBTableStats":     true,
+	"Distinct":         true,
+	"ExistingIDs":      true,
+	"Explain":          true,
+	"ExplainAggregate": true,
+	"GetDatabaseInfo":  true,
+	"GetIndexes":       true,
+	"GetTables":        true,
+	"HasTable":         true,
+	"Health":           true,
+	"ListPage":         true,
+	"Ping":             true,
+	"Query":            true,
+	"QueryCursor":      true,
+	"SearchText":       true,
+	"SessionSettings":  true,
+	"Update":           true,
+}
+
+// Retry returns a Handler running again the operations failed with a retryable error, as set by opts.
+// The operations run within a transaction aren't retried on their own, the whole transaction is.
+// The operations which aren't idempotent, such as Insert, Upsert or UpdateAll, are only retried on the errors
+// known to leave them unapplied, as reported by utils.IsUnappliedError, so they aren't applied twice.
+func Retry(opts types.RetryOpts) Handler {
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = defaultRetryBaseDelay
+	}
+
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = defaultRetryMaxDelay
+	}
+
+	if opts.IsRetryable == nil {
+		opts.IsRetryable = utils.IsTransientError
+	}
+
+	return func(ctx context.Context, op Operation, run func(ctx context.Context) error) error {
+		err := run(ctx)
+
+		if op.InTransaction {
+			return err
+		}
+
+		for attempt := 1; attempt < opts.MaxAttempts && err != nil && canRetry(opts, op, err); attempt++ {
+			timer := time.NewTimer(retryDelay(opts, attempt))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+
+			err = run(ctx)
+		}
+
+		return err
+	}
+}
+
+// canRetry reports whether op, failed with err, can run again.
+func canRetry(opts types.RetryOpts, op Operation, err error) bool {
+	if !opts.IsRetryable(err) {
+		return false
+	}
+
+	return isIdempotent(op) || utils.IsUnappliedError(err)
+}
+
+// isIdempotent reports whether op can run again with the same outcome. The aggregations merging their results
+// into another table aren't.
+func isIdempotent(op Operation) bool {
+	if op.Name == "Aggregate" {
+		_, merges := helper.UnsupportedStage(op.Pipeline, []string{"$merge"})
+		return !merges
+	}
+
+	return idempotentOperations[op.Name]
+}
+
+// retryDelay returns the delay before the given retry, starting at 1.
+func retryDelay(opts types.RetryOpts, retry int) time.Duration {
+	delay := opts.BaseDelay
+	for i := 1; i < retry && delay < opts.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > opts.MaxDelay {
+		delay = opts.MaxDelay
+	}
+
+	if opts.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * opts.Jitter * float64(delay))
+	}
+
+	return delay
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	errPermanent := errors.New("permanent")
+	errThrottled := mongo.CommandError{Code: 16500, Message: "Request rate is large"}
+	query := Operation{Name: "Query"}
+
+	opts := types.RetryOpts{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		IsRetryable: func(err error) bool {
+			return errors.Is(err, errTransient) || utils.IsThrottlingError(err)
+		},
+	}
+
+	tcs := []struct {
+		testName         string
+		op               Operation
+		errs             []error
+		expectedErr      error
+		expectedAttempts int
+	}{
+		{
+			testName:         "successful operation",
+			op:               query,
+			errs:             []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			testName:         "succeeds after retrying",
+			op:               query,
+			errs:             []error{errTransient, errTransient, nil},
+			expectedAttempts: 3,
+		},
+		{
+			testName:         "gives up after max attempts",
+			op:               query,
+			errs:             []error{errTransient, errTransient, errTransient, nil},
+			expectedErr:      errTransient,
+			expectedAttempts: 3,
+		},
+		{
+			testName:         "not retryable error",
+			op:               query,
+			errs:             []error{errPermanent, nil},
+			expectedErr:      errPermanent,
+			expectedAttempts: 1,
+		},
+		{
+			testName:         "operation within a transaction",
+			op:               Operation{Name: "Query", InTransaction: true},
+			errs:             []error{errTransient, nil},
+			expectedErr:      errTransient,
+			expectedAttempts: 1,
+		},
+		{
+			testName:         "write which isn't idempotent",
+			op:               Operation{Name: "Insert"},
+			errs:             []error{errTransient, nil},
+			expectedErr:      errTransient,
+			expectedAttempts: 1,
+		},
+		{
+			testName:         "write which isn't idempotent left unapplied",
+			op:               Operation{Name: "Upsert"},
+			errs:             []error{errThrottled, nil},
+			expectedAttempts: 2,
+		},
+		{
+			testName:         "aggregation merging into another table",
+			op:               Operation{Name: "Aggregate", Pipeline: []model.DBM{{"$merge": "other"}}},
+			errs:             []error{errTransient, nil},
+			expectedErr:      errTransient,
+			expectedAttempts: 1,
+		},
+		{
+			testName:         "aggregation",
+			op:               Operation{Name: "Aggregate", Pipeline: []model.DBM{{"$match": model.DBM{}}}},
+			errs:             []error{errTransient, nil},
+			expectedAttempts: 2,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			attempts := 0
+
+			err := Retry(opts)(context.Background(), tc.op, func(context.Context) error {
+				attempts++
+				return tc.errs[attempts-1]
+			})
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+		})
+	}
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+
+		err := Retry(types.RetryOpts{MaxAttempts: 3, BaseDelay: time.Hour, IsRetryable: opts.IsRetryable})(ctx,
+			query, func(context.Context) error {
+				attempts++
+				cancel()
+
+				return errTransient
+			})
+		assert.Equal(t, errTransient, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	opts := types.RetryOpts{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, retryDelay(opts, 1))
+	assert.Equal(t, 200*time.Millisecond, retryDelay(opts, 2))
+	assert.Equal(t, 400*time.Millisecond, retryDelay(opts, 3))
+	assert.Equal(t, time.Second, retryDelay(opts, 5))
+	assert.Equal(t, time.Second, retryDelay(opts, 100))
+
+	opts.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := retryDelay(opts, 1)
+		assert.GreaterOrEqual(t, delay, 50*time.Millisecond)
+		assert.LessOrEqual(t, delay, 150*time.Millisecond)
+	}
+}
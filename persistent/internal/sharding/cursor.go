@@ -0,0 +1,82 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// cursor iterates over the rows of the shards one after the other, skipping the first skip rows
+// and stopping after limit rows if it's set.
+type cursor struct {
+	ctx      context.Context
+	open     func(ctx context.Context) (model.Cursor, error)
+	shards   []string
+	current  model.Cursor
+	skip     int
+	limit    int
+	returned int
+	err      error
+}
+
+var _ model.Cursor = &cursor{}
+
+func (c *cursor) Next() bool {
+	for c.err == nil && (c.limit <= 0 || c.returned < c.limit) {
+		if c.current == nil {
+			if len(c.shards) == 0 {
+				return false
+			}
+
+			c.current, c.err = c.open(helper.WithTable(c.ctx, c.shards[0]))
+			c.shards = c.shards[1:]
+
+			continue
+		}
+
+		if c.current.Next() {
+			if c.skip > 0 {
+				c.skip--
+				continue
+			}
+
+			c.returned++
+
+			return true
+		}
+
+		c.err = c.current.Err()
+		if err := c.current.Close(); c.err == nil {
+			c.err = err
+		}
+
+		c.current = nil
+	}
+
+	return false
+}
+
+func (c *cursor) Decode(result interface{}) error {
+	if c.current == nil {
+		return errors.New("no current row to decode")
+	}
+
+	return c.current.Decode(result)
+}
+
+func (c *cursor) Err() error {
+	return c.err
+}
+
+func (c *cursor) Close() error {
+	if c.current == nil {
+		return nil
+	}
+
+	err := c.current.Close()
+	c.current = nil
+
+	return err
+}
@@ -0,0 +1,1061 @@
+// Package sharding wraps a persistent storage so the rows of the tables sharded by date are stored in a table
+// per period, and the operations on those tables run over their shards, pruned by the time range of their filters.
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+type storage struct {
+	next types.PersistentStorage
+	// tables is the sharding of the sharded tables, by name.
+	tables map[string]model.DateSharding
+	// resolve returns the name in the database of a table, as listed by GetTables.
+	resolve func(table string) string
+	indexes *indexes
+}
+
+// indexes are the indexes created on the sharded tables, which are created on their new shards as well.
+type indexes struct {
+	mu sync.Mutex
+	// created are the indexes created on each sharded table.
+	created map[string][]model.Index
+	// shards are the shards known to have the indexes of their table.
+	shards map[string]bool
+}
+
+// New returns a persistent storage sharding the given tables of next by date. resolve returns the name of a table
+// in the database, as listed by GetTables, and is nil if it's the name of the table itself.
+func New(next types.PersistentStorage,
+	tables map[string]model.DateSharding,
+	resolve func(table string) string,
+) types.PersistentStorage {
+	if resolve == nil {
+		resolve = func(table string) string {
+			return table
+		}
+	}
+
+	return &storage{
+		next:    next,
+		tables:  tables,
+		resolve: resolve,
+		indexes: &indexes{created: map[string][]model.Index{}, shards: map[string]bool{}},
+	}
+}
+
+// sharding returns the sharding of the table of row, if it's sharded.
+func (s *storage) sharding(row model.DBObject) (model.DateSharding, bool) {
+	if row == nil {
+		return model.DateSharding{}, false
+	}
+
+	sharding, ok := s.tables[row.TableName()]
+
+	return sharding, ok
+}
+
+// ensureIndexes creates on shard the indexes created on its table through the storage, unless it has them already.
+func (s *storage) ensureIndexes(ctx context.Context, row model.DBObject, shard string) error {
+	if helper.IsDryRun(ctx) {
+		return nil
+	}
+
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+
+	if s.indexes.shards[shard] {
+		return nil
+	}
+
+	for _, index := range s.indexes.created[row.TableName()] {
+		if err := s.next.CreateIndex(helper.WithTable(ctx, shard), row, index); err != nil {
+			return err
+		}
+	}
+
+	s.indexes.shards[shard] = true
+
+	return nil
+}
+
+// rowShard returns the shard of row given the time of its shard key field.
+func rowShard(row model.DBObject, sharding model.DateSharding) (string, error) {
+	t, err := shardTime(row, sharding.Field)
+	if err != nil {
+		return "", err
+	}
+
+	return sharding.ShardName(row.TableName(), t), nil
+}
+
+// firstFilter returns the first of filters, or nil if there's none.
+func firstFilter(filters []model.DBM) model.DBM {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return filters[0]
+}
+
+// leadingMatch returns the filter of the $match stage starting pipeline, if any.
+func leadingMatch(pipeline []model.DBM) model.DBM {
+	stages, _ := helper.SplitPipelineOptions(pipeline)
+	if len(stages) == 0 {
+		return nil
+	}
+
+	match, _ := stages[0]["$match"].(model.DBM)
+
+	return match
+}
+
+// unionPipeline returns pipeline running over the rows of all the shards: it runs on the first one and reads
+// the others with $unionWith stages, which require MongoDB 4.4. The $match stage starting pipeline, if any,
+// is run on each shard before the union.
+func (s *storage) unionPipeline(shards []string, pipeline []model.DBM) []model.DBM {
+	if len(shards) < 2 {
+		return pipeline
+	}
+
+	match := leadingMatch(pipeline)
+	union := make([]model.DBM, 0, len(shards)+len(pipeline))
+
+	if match != nil {
+		union = append(union, model.DBM{"$match": match})
+	}
+
+	for _, shard := range shards[1:] {
+		spec := model.DBM{"coll": s.resolve(shard)}
+		if match != nil {
+			spec["pipeline"] = []model.DBM{{"$match": match}}
+		}
+
+		union = append(union, model.DBM{"$unionWith": spec})
+	}
+
+	return append(union, pipeline...)
+}
+
+// sortShards sorts the shards in the order of the rows of query, which is descending when they are sorted
+// by the shard key field in descending order.
+func sortShards(shards []string, query model.DBM, sharding model.DateSharding) {
+	sort, ok := query["_sort"].(string)
+	if !ok {
+		return
+	}
+
+	if field, descending := helper.PageSort(sort); field == sharding.Field && descending {
+		for i, j := 0, len(shards)-1; i < j; i, j = i+1, j-1 {
+			shards[i], shards[j] = shards[j], shards[i]
+		}
+	}
+}
+
+// queryShards runs a query on the shards. The rows of the shards are returned one shard after the other,
+// so they are only sorted across shards when sorted by the shard key field. The _offset and _limit keys apply
+// to the rows of all the shards, counting the rows of the skipped shards with count.
+// If result isn't a slice, it's decoded from the first shard having a matching row.
+func queryShards(ctx context.Context,
+	shards []string,
+	result interface{},
+	query model.DBM,
+	run func(ctx context.Context, result interface{}, query model.DBM) error,
+	count func(ctx context.Context, query model.DBM) (int, error),
+) error {
+	resultValue := reflect.ValueOf(result)
+	if resultValue.Kind() != reflect.Ptr || resultValue.Elem().Kind() != reflect.Slice {
+		return first(ctx, shards, func(ctx context.Context) error {
+			return run(ctx, result, query)
+		})
+	}
+
+	if len(shards) == 1 {
+		return run(helper.WithTable(ctx, shards[0]), result, query)
+	}
+
+	offset, _ := query["_offset"].(int)
+	limit, _ := query["_limit"].(int)
+
+	if offset > 0 && count == nil {
+		return types.ErrShardingUnsupported
+	}
+
+	rows := reflect.MakeSlice(resultValue.Elem().Type(), 0, 0)
+
+	for _, shard := range shards {
+		shardCtx := helper.WithTable(ctx, shard)
+
+		shardQuery := model.DBM{}
+		for key, value := range query {
+			shardQuery[key] = value
+		}
+
+		delete(shardQuery, "_offset")
+
+		if offset > 0 {
+			n, err := count(shardCtx, query)
+			if err != nil {
+				return err
+			}
+
+			if n <= offset {
+				offset -= n
+				continue
+			}
+
+			shardQuery["_offset"] = offset
+			offset = 0
+		}
+
+		if limit > 0 {
+			shardQuery["_limit"] = limit - rows.Len()
+		}
+
+		shardRows := reflect.New(resultValue.Elem().Type())
+		if err := run(shardCtx, shardRows.Interface(), shardQuery); err != nil {
+			return err
+		}
+
+		rows = reflect.AppendSlice(rows, shardRows.Elem())
+
+		if limit > 0 && rows.Len() >= limit {
+			break
+		}
+	}
+
+	resultValue.Elem().Set(rows)
+
+	return nil
+}
+
+func (s *storage) Health(ctx context.Context) model.HealthStatus {
+	return s.next.Health(ctx)
+}
+
+// Insert inserts the rows of the sharded tables into the shard of the time of their shard key field,
+// which must be set.
+func (s *storage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	sharding, ok := s.sharding(firstRow(rows))
+	if !ok {
+		return s.next.Insert(ctx, rows...)
+	}
+
+	shards, groups, err := group(rows[0].TableName(), sharding, rows)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if err := s.ensureIndexes(ctx, rows[0], shard); err != nil {
+			return err
+		}
+
+		if err := s.next.Insert(helper.WithTable(ctx, shard), pick(rows, groups[shard])...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storage) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	sharding, ok := s.sharding(firstRow(rows))
+	if !ok {
+		return s.next.BulkInsert(ctx, rows, opts)
+	}
+
+	shards, groups, err := group(rows[0].TableName(), sharding, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	inserted := 0
+
+	for _, shard := range shards {
+		if err := s.ensureIndexes(ctx, rows[0], shard); err != nil {
+			return inserted, err
+		}
+
+		n, err := s.next.BulkInsert(helper.WithTable(ctx, shard), pick(rows, groups[shard]), opts)
+		inserted += n
+
+		if err != nil {
+			return inserted, err
+		}
+	}
+
+	return inserted, nil
+}
+
+// firstRow returns the first of rows, or nil if there's none.
+func firstRow(rows []model.DBObject) model.DBObject {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return rows[0]
+}
+
+// pick returns the rows at the given indexes.
+func pick(rows []model.DBObject, indexes []int) []model.DBObject {
+	picked := make([]model.DBObject, len(indexes))
+	for i, index := range indexes {
+		picked[i] = rows[index]
+	}
+
+	return picked
+}
+
+// Delete deletes row from its shard, or the rows matching the filter from every shard.
+func (s *storage) Delete(ctx context.Context, row model.DBObject, filters ...model.DBM) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Delete(ctx, row, filters...)
+	}
+
+	if len(filters) == 0 {
+		shard, err := rowShard(row, sharding)
+		if err != nil {
+			return err
+		}
+
+		return s.next.Delete(helper.WithTable(ctx, shard), row)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, filters[0])
+	if err != nil {
+		return err
+	}
+
+	return each(ctx, shards, func(ctx context.Context) error {
+		return s.next.Delete(ctx, row, filters...)
+	})
+}
+
+func (s *storage) Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Purge(ctx, row, olderThan)
+	}
+
+	shards, err := s.shards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return sum(ctx, shards, func(ctx context.Context) (int, error) {
+		return s.next.Purge(ctx, row, olderThan)
+	})
+}
+
+func (s *storage) DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.DeleteWithResult(ctx, row, filter)
+	}
+
+	shards, err := s.shards(ctx, row.TableName(), sharding, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := sum(ctx, shards, func(ctx context.Context) (int, error) {
+		n, err := s.next.DeleteWithResult(ctx, row, filter)
+		return int(n), err
+	})
+
+	return int64(deleted), err
+}
+
+// Update updates row in the shard of the time of its shard key field.
+func (s *storage) Update(ctx context.Context, row model.DBObject, filters ...model.DBM) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Update(ctx, row, filters...)
+	}
+
+	shard, err := rowShard(row, sharding)
+	if err != nil {
+		return err
+	}
+
+	return s.next.Update(helper.WithTable(ctx, shard), row, filters...)
+}
+
+func (s *storage) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Count(ctx, row, filters...)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, firstFilter(filters))
+	if err != nil {
+		return 0, err
+	}
+
+	return sum(ctx, shards, func(ctx context.Context) (int, error) {
+		return s.next.Count(ctx, row, filters...)
+	})
+}
+
+func (s *storage) CountWithOpts(ctx context.Context,
+	row model.DBObject,
+	opts model.CountOpts,
+	filters ...model.DBM,
+) (int, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.CountWithOpts(ctx, row, opts, filters...)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, firstFilter(filters))
+	if err != nil {
+		return 0, err
+	}
+
+	return sum(ctx, shards, func(ctx context.Context) (int, error) {
+		return s.next.CountWithOpts(ctx, row, opts, filters...)
+	})
+}
+
+// Query runs the query on the shards of the time range of its shard key field condition. The rows are returned
+// one shard after the other, in ascending order unless the query is sorted by the shard key field in descending
+// order, so the rows are only sorted across shards when sorted by the shard key field.
+func (s *storage) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Query(ctx, row, result, query)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, query)
+	if err != nil {
+		return err
+	}
+
+	sortShards(shards, query, sharding)
+
+	return queryShards(ctx, shards, result, query,
+		func(ctx context.Context, result interface{}, query model.DBM) error {
+			return s.next.Query(ctx, row, result, query)
+		},
+		func(ctx context.Context, query model.DBM) (int, error) {
+			return s.next.Count(ctx, row, query)
+		},
+	)
+}
+
+// SearchText searches the shards one after the other, so the rows are only sorted by relevance within a shard.
+// The _offset key isn't supported across several shards.
+func (s *storage) SearchText(ctx context.Context,
+	row model.DBObject,
+	result interface{},
+	text string,
+	filter model.DBM,
+) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.SearchText(ctx, row, result, text, filter)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, filter)
+	if err != nil {
+		return err
+	}
+
+	return queryShards(ctx, shards, result, filter,
+		func(ctx context.Context, result interface{}, filter model.DBM) error {
+			return s.next.SearchText(ctx, row, result, text, filter)
+		},
+		nil,
+	)
+}
+
+// Watch isn't supported on the sharded tables.
+func (s *storage) Watch(ctx context.Context, row model.DBObject, filter model.DBM) (<-chan model.ChangeEvent, error) {
+	if _, ok := s.sharding(row); ok {
+		return nil, types.ErrShardingUnsupported
+	}
+
+	return s.next.Watch(ctx, row, filter)
+}
+
+// QueryCursor iterates over the shards one after the other, as Query returns their rows.
+func (s *storage) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.QueryCursor(ctx, row, query)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sortShards(shards, query, sharding)
+
+	if len(shards) == 1 {
+		return s.next.QueryCursor(helper.WithTable(ctx, shards[0]), row, query)
+	}
+
+	offset, _ := query["_offset"].(int)
+	limit, _ := query["_limit"].(int)
+
+	shardQuery := model.DBM{}
+	for key, value := range query {
+		shardQuery[key] = value
+	}
+
+	delete(shardQuery, "_offset")
+
+	if limit > 0 {
+		shardQuery["_limit"] = offset + limit
+	}
+
+	return &cursor{
+		ctx: ctx,
+		open: func(ctx context.Context) (model.Cursor, error) {
+			return s.next.QueryCursor(ctx, row, shardQuery)
+		},
+		shards: shards,
+		skip:   offset,
+		limit:  limit,
+	}, nil
+}
+
+// ListPage isn't supported across several shards, so the filter must match the time range of a single one.
+func (s *storage) ListPage(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+	page model.PageRequest,
+) (model.PageResult, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.ListPage(ctx, row, filter, page)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, filter)
+	if err != nil {
+		return model.PageResult{}, err
+	}
+
+	if len(shards) > 1 {
+		return model.PageResult{}, types.ErrShardingUnsupported
+	}
+
+	return s.next.ListPage(helper.WithTable(ctx, shards[0]), row, filter, page)
+}
+
+func (s *storage) Distinct(ctx context.Context,
+	row model.DBObject,
+	field string,
+	filter model.DBM,
+) ([]interface{}, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Distinct(ctx, row, field, filter)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{}
+	seen := map[string]bool{}
+
+	for _, shard := range shards {
+		shardValues, err := s.next.Distinct(helper.WithTable(ctx, shard), row, field, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range shardValues {
+			key := fmt.Sprintf("%T:%v", value, value)
+			if !seen[key] {
+				seen[key] = true
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// BulkUpdate updates each row in the shard of the time of its shard key field.
+func (s *storage) BulkUpdate(ctx context.Context, rows []model.DBObject, filters ...model.DBM) error {
+	sharding, ok := s.sharding(firstRow(rows))
+	if !ok {
+		return s.next.BulkUpdate(ctx, rows, filters...)
+	}
+
+	if len(filters) > 0 && len(filters) != len(rows) {
+		return types.ErrRowQueryDiffLength
+	}
+
+	shards, groups, err := group(rows[0].TableName(), sharding, rows)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		var shardFilters []model.DBM
+
+		if len(filters) > 0 {
+			for _, i := range groups[shard] {
+				shardFilters = append(shardFilters, filters[i])
+			}
+		}
+
+		if err := s.next.BulkUpdate(helper.WithTable(ctx, shard), pick(rows, groups[shard]), shardFilters...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storage) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.UpdateAll(ctx, row, query, update)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, query)
+	if err != nil {
+		return err
+	}
+
+	return each(ctx, shards, func(ctx context.Context) error {
+		return s.next.UpdateAll(ctx, row, query, update)
+	})
+}
+
+// Drop drops every shard of the sharded tables.
+func (s *storage) Drop(ctx context.Context, row model.DBObject) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Drop(ctx, row)
+	}
+
+	shards, err := s.shards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if err := s.next.Drop(helper.WithTable(ctx, shard), row); err != nil {
+			return err
+		}
+
+		s.forgetShard(shard)
+	}
+
+	return nil
+}
+
+// forgetShard forgets that shard has the indexes of its table, once dropped.
+func (s *storage) forgetShard(shard string) {
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+
+	delete(s.indexes.shards, shard)
+}
+
+// CreateIndex creates the index on every shard of the sharded tables, and on their shards created afterwards
+// by the storage.
+func (s *storage) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.CreateIndex(ctx, row, index)
+	}
+
+	shards, err := s.shards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if err := s.next.CreateIndex(helper.WithTable(ctx, shard), row, index); err != nil {
+			return err
+		}
+	}
+
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+
+	for _, created := range s.indexes.created[row.TableName()] {
+		if reflect.DeepEqual(created, index) {
+			return nil
+		}
+	}
+
+	s.indexes.created[row.TableName()] = append(s.indexes.created[row.TableName()], index)
+
+	return nil
+}
+
+// GetIndexes returns the indexes of the latest shard of the sharded tables.
+func (s *storage) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.GetIndexes(ctx, row)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.GetIndexes(helper.WithTable(ctx, shards[len(shards)-1]), row)
+}
+
+func (s *storage) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+// HasTable reports whether a sharded table has a shard.
+func (s *storage) HasTable(ctx context.Context, table string) (bool, error) {
+	sharding, ok := s.tables[table]
+	if !ok {
+		return s.next.HasTable(ctx, table)
+	}
+
+	shards, err := s.shards(ctx, table, sharding, nil)
+
+	return len(shards) > 0, err
+}
+
+func (s *storage) DropDatabase(ctx context.Context) error {
+	s.indexes.mu.Lock()
+	s.indexes.shards = map[string]bool{}
+	s.indexes.mu.Unlock()
+
+	return s.next.DropDatabase(ctx)
+}
+
+// Migrate skips the sharded tables, whose shards are created when inserting their rows.
+func (s *storage) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
+	var (
+		migrated     []model.DBObject
+		migratedOpts []model.DBM
+	)
+
+	for i, row := range rows {
+		if _, ok := s.sharding(row); ok {
+			continue
+		}
+
+		migrated = append(migrated, row)
+
+		if len(opts) == len(rows) {
+			migratedOpts = append(migratedOpts, opts[i])
+		}
+	}
+
+	if len(migrated) == 0 && len(rows) > 0 {
+		return nil
+	}
+
+	if len(opts) != len(rows) {
+		migratedOpts = opts
+	}
+
+	return s.next.Migrate(ctx, migrated, migratedOpts...)
+}
+
+// DBTableStats returns the stats of the shards of the sharded tables in "shards", by shard, along with
+// their total number of rows in "count".
+func (s *storage) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.DBTableStats(ctx, row)
+	}
+
+	shards, err := s.shards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	shardStats := model.DBM{}
+
+	for _, shard := range shards {
+		stats, err := s.next.DBTableStats(helper.WithTable(ctx, shard), row)
+		if err != nil {
+			return nil, err
+		}
+
+		switch n := stats["count"].(type) {
+		case int:
+			count += n
+		case int32:
+			count += int(n)
+		case int64:
+			count += int(n)
+		case float64:
+			count += int(n)
+		}
+
+		shardStats[shard] = stats
+	}
+
+	return model.DBM{"ns": row.TableName(), "count": count, "shards": shardStats}, nil
+}
+
+// Aggregate runs the pipeline over the shards of the time range of the shard key field condition of its leading
+// $match stage. Running it over several shards requires MongoDB 4.4, as they are read with $unionWith stages.
+func (s *storage) Aggregate(ctx context.Context, row model.DBObject, pipeline []model.DBM) ([]model.DBM, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Aggregate(ctx, row, pipeline)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, leadingMatch(pipeline))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.Aggregate(helper.WithTable(ctx, shards[0]), row, s.unionPipeline(shards, pipeline))
+}
+
+// Explain explains the query on the latest of its shards.
+func (s *storage) Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Explain(ctx, row, filter)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.Explain(helper.WithTable(ctx, shards[len(shards)-1]), row, filter)
+}
+
+func (s *storage) ExplainAggregate(ctx context.Context, row model.DBObject, pipeline []model.DBM) (model.DBM, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.ExplainAggregate(ctx, row, pipeline)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, leadingMatch(pipeline))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.ExplainAggregate(helper.WithTable(ctx, shards[0]), row, s.unionPipeline(shards, pipeline))
+}
+
+func (s *storage) CleanIndexes(ctx context.Context, row model.DBObject) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.CleanIndexes(ctx, row)
+	}
+
+	shards, err := s.shards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if err := s.next.CleanIndexes(helper.WithTable(ctx, shard), row); err != nil {
+			return err
+		}
+	}
+
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+
+	delete(s.indexes.created, row.TableName())
+
+	return nil
+}
+
+// Upsert runs in the shard of the time of the shard key field equality condition of the query, which is required.
+func (s *storage) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Upsert(ctx, row, query, update)
+	}
+
+	from, to := timeRange(query, sharding.Field)
+	if from.IsZero() || !from.Equal(to) {
+		return types.ErrShardKeyMissing
+	}
+
+	shard := sharding.ShardName(row.TableName(), from)
+	if err := s.ensureIndexes(ctx, row, shard); err != nil {
+		return err
+	}
+
+	return s.next.Upsert(helper.WithTable(ctx, shard), row, query, update)
+}
+
+// FindOneAndUpdate updates the first row matching the query in its shards, from the oldest one.
+func (s *storage) FindOneAndUpdate(ctx context.Context,
+	row model.DBObject,
+	query, update model.DBM,
+	opts ...model.FindOneOpts,
+) error {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.FindOneAndUpdate(ctx, row, query, update, opts...)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, query)
+	if err != nil {
+		return err
+	}
+
+	return first(ctx, shards, func(ctx context.Context) error {
+		return s.next.FindOneAndUpdate(ctx, row, query, update, opts...)
+	})
+}
+
+func (s *storage) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
+	return s.next.GetDatabaseInfo(ctx)
+}
+
+func (s *storage) GetTables(ctx context.Context) ([]string, error) {
+	return s.next.GetTables(ctx)
+}
+
+// DropTable drops every shard of the sharded tables, returning the number of removed rows.
+func (s *storage) DropTable(ctx context.Context, name string) (int, error) {
+	sharding, ok := s.tables[name]
+	if !ok {
+		return s.next.DropTable(ctx, name)
+	}
+
+	shards, err := s.shards(ctx, name, sharding, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, shard := range shards {
+		n, err := s.next.DropTable(ctx, shard)
+		removed += n
+
+		if err != nil {
+			return removed, err
+		}
+
+		s.forgetShard(shard)
+	}
+
+	return removed, nil
+}
+
+func (s *storage) ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error) {
+	return s.Export(ctx, row, query, w, model.NDJSON)
+}
+
+// Export writes the rows of the shards one after the other. The CSV format isn't supported across several shards.
+func (s *storage) Export(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+	w io.Writer,
+	format model.ExportFormat,
+) (int, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.Export(ctx, row, query, w, format)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, query)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(shards) > 1 && format != model.NDJSON {
+		return 0, types.ErrShardingUnsupported
+	}
+
+	return sum(ctx, shards, func(ctx context.Context) (int, error) {
+		return s.next.Export(ctx, row, query, w, format)
+	})
+}
+
+// ImportNDJSON isn't supported on the sharded tables.
+func (s *storage) ImportNDJSON(ctx context.Context, row model.DBObject, r io.Reader, opts ...model.DBM) (int, error) {
+	if _, ok := s.sharding(row); ok {
+		return 0, types.ErrShardingUnsupported
+	}
+
+	return s.next.ImportNDJSON(ctx, row, r, opts...)
+}
+
+// Import isn't supported on the sharded tables.
+func (s *storage) Import(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	format model.ExportFormat,
+	opts ...model.DBM,
+) (int, error) {
+	if _, ok := s.sharding(row); ok {
+		return 0, types.ErrShardingUnsupported
+	}
+
+	return s.next.Import(ctx, row, r, format, opts...)
+}
+
+func (s *storage) SessionSettings(ctx context.Context) (model.DBM, error) {
+	return s.next.SessionSettings(ctx)
+}
+
+func (s *storage) ExistingIDs(ctx context.Context, row model.DBObject, ids []model.ObjectID) ([]model.ObjectID, error) {
+	sharding, ok := s.sharding(row)
+	if !ok {
+		return s.next.ExistingIDs(ctx, row, ids)
+	}
+
+	shards, err := s.readShards(ctx, row.TableName(), sharding, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[model.ObjectID]bool{}
+
+	for _, shard := range shards {
+		existing, err := s.next.ExistingIDs(helper.WithTable(ctx, shard), row, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range existing {
+			found[id] = true
+		}
+	}
+
+	var existing []model.ObjectID
+
+	for _, id := range ids {
+		if found[id] {
+			existing = append(existing, id)
+			// an ID given twice is returned once
+			delete(found, id)
+		}
+	}
+
+	return existing, nil
+}
+
+func (s *storage) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	return s.next.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+		return fn(&storage{next: tx, tables: s.tables, resolve: s.resolve, indexes: s.indexes})
+	})
+}
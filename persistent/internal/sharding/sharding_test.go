@@ -0,0 +1,270 @@
+package sharding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/mock"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+type record struct {
+	ID        model.ObjectID `bson:"_id,omitempty"`
+	Path      string         `bson:"path"`
+	TimeStamp time.Time      `bson:"timestamp"`
+}
+
+func (r *record) GetObjectID() model.ObjectID {
+	return r.ID
+}
+
+func (r *record) SetObjectID(id model.ObjectID) {
+	r.ID = id
+}
+
+func (r *record) TableName() string {
+	return "analytics"
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 12, 0, 0, 0, time.UTC)
+}
+
+// seed returns a storage sharding the analytics table by day, with a row per path of each of the days.
+func seed(t *testing.T, days []int, paths ...string) (types.PersistentStorage, *mock.Storage) {
+	t.Helper()
+
+	store := mock.New()
+	sharded := New(store, map[string]model.DateSharding{
+		"analytics": {Field: "timestamp", Period: model.ShardByDay},
+	}, nil)
+
+	var rows []model.DBObject
+
+	for _, d := range days {
+		for _, path := range paths {
+			rows = append(rows, &record{Path: path, TimeStamp: day(d)})
+		}
+	}
+
+	if len(rows) > 0 {
+		assert.Nil(t, sharded.Insert(context.Background(), rows...))
+	}
+
+	return sharded, store
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	sharded, store := seed(t, []int{1, 2}, "/a", "/b")
+
+	tables, err := store.GetTables(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"analytics_20240101", "analytics_20240102"}, tables)
+
+	hasTable, err := sharded.HasTable(ctx, "analytics")
+	assert.Nil(t, err)
+	assert.True(t, hasTable)
+
+	assert.EqualError(t, sharded.Insert(ctx, &record{Path: "/c"}), types.ErrorShardKeyMissing)
+
+	n, err := sharded.BulkInsert(ctx, []model.DBObject{
+		&record{Path: "/c", TimeStamp: day(2)},
+		&record{Path: "/c", TimeStamp: day(3)},
+	}, model.BulkOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = sharded.Count(ctx, &record{})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, n)
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	sharded, _ := seed(t, []int{1, 2, 3}, "/a", "/b")
+
+	tests := []struct {
+		name     string
+		query    model.DBM
+		expected []time.Time
+	}{
+		{
+			name:     "all shards",
+			query:    model.DBM{"path": "/a"},
+			expected: []time.Time{day(1), day(2), day(3)},
+		},
+		{
+			name:     "pruned shards",
+			query:    model.DBM{"path": "/a", "timestamp": model.DBM{"$gte": day(2), "$lt": day(4)}},
+			expected: []time.Time{day(2), day(3)},
+		},
+		{
+			name:     "sorted in descending order",
+			query:    model.DBM{"path": "/a", "_sort": "-timestamp"},
+			expected: []time.Time{day(3), day(2), day(1)},
+		},
+		{
+			name:     "offset and limit across shards",
+			query:    model.DBM{"_sort": "timestamp", "_offset": 3, "_limit": 2},
+			expected: []time.Time{day(2), day(3)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var rows []record
+
+			assert.Nil(t, sharded.Query(ctx, &record{}, &rows, test.query))
+
+			var times []time.Time
+			for _, row := range rows {
+				times = append(times, row.TimeStamp.UTC())
+			}
+
+			assert.Equal(t, test.expected, times)
+		})
+	}
+
+	var row record
+
+	assert.Nil(t, sharded.Query(ctx, &record{}, &row, model.DBM{"path": "/b", "timestamp": day(2)}))
+	assert.Equal(t, day(2), row.TimeStamp.UTC())
+	assert.True(t, utils.IsErrNoRows(sharded.Query(ctx, &record{}, &row, model.DBM{"path": "/c"})))
+
+	cursor, err := sharded.QueryCursor(ctx, &record{}, model.DBM{"_offset": 1, "_limit": 3})
+	assert.Nil(t, err)
+
+	var paths []string
+
+	for cursor.Next() {
+		assert.Nil(t, cursor.Decode(&row))
+		paths = append(paths, row.Path)
+	}
+
+	assert.Nil(t, cursor.Err())
+	assert.Nil(t, cursor.Close())
+	assert.Equal(t, []string{"/b", "/a", "/b"}, paths)
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	sharded, _ := seed(t, []int{1, 2}, "/a", "/b")
+
+	var row record
+	assert.Nil(t, sharded.Query(ctx, &record{}, &row, model.DBM{"path": "/a", "timestamp": day(2)}))
+
+	row.Path = "/c"
+	assert.Nil(t, sharded.Update(ctx, &row))
+
+	assert.Nil(t, sharded.UpdateAll(ctx, &record{}, model.DBM{"path": "/b"}, model.DBM{"$set": model.DBM{"path": "/d"}}))
+
+	values, err := sharded.Distinct(ctx, &record{}, "path", model.DBM{})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []interface{}{"/a", "/c", "/d"}, values)
+
+	assert.Nil(t, sharded.Delete(ctx, &row))
+
+	n, err := sharded.DeleteWithResult(ctx, &record{}, model.DBM{"path": "/d"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.True(t, utils.IsErrNoRows(sharded.Delete(ctx, &record{}, model.DBM{"path": "/d"})))
+
+	upsert := model.DBM{"$set": model.DBM{"path": "/e"}}
+
+	err = sharded.Upsert(ctx, &record{}, model.DBM{"path": "/e"}, upsert)
+	assert.EqualError(t, err, types.ErrorShardKeyMissing)
+
+	err = sharded.Upsert(ctx, &record{}, model.DBM{"path": "/e", "timestamp": day(5)}, upsert)
+	assert.Nil(t, err)
+
+	count, err := sharded.Count(ctx, &record{}, model.DBM{"timestamp": model.DBM{"$gte": day(5)}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	removed, err := sharded.DropTable(ctx, "analytics")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, removed)
+
+	hasTable, err := sharded.HasTable(ctx, "analytics")
+	assert.Nil(t, err)
+	assert.False(t, hasTable)
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	sharded, _ := seed(t, []int{1, 2, 3}, "/a", "/b")
+
+	result, err := sharded.Aggregate(ctx, &record{}, []model.DBM{
+		{"$match": model.DBM{"timestamp": model.DBM{"$gte": day(2)}}},
+		{"$group": model.DBM{"_id": "$path", "total": model.DBM{"$sum": 1}}},
+		{"$sort": model.DBM{"_id": 1}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.DBM{{"_id": "/a", "total": 2}, {"_id": "/b", "total": 2}}, result)
+
+	s := &storage{resolve: func(table string) string {
+		return "org_" + table
+	}}
+	match := model.DBM{"$match": model.DBM{"path": "/a"}}
+	assert.Equal(t, []model.DBM{
+		match,
+		{"$unionWith": model.DBM{"coll": "org_analytics_20240102", "pipeline": []model.DBM{match}}},
+		match,
+		{"$count": "total"},
+	}, s.unionPipeline([]string{"analytics_20240101", "analytics_20240102"}, []model.DBM{match, {"$count": "total"}}))
+}
+
+func TestIndexes(t *testing.T) {
+	ctx := context.Background()
+	sharded, store := seed(t, []int{1}, "/a")
+
+	index := model.Index{Name: "path", Keys: []model.DBM{{"path": 1}}}
+	assert.Nil(t, sharded.CreateIndex(ctx, &record{}, index))
+	assert.Nil(t, sharded.Insert(ctx, &record{Path: "/a", TimeStamp: day(2)}))
+
+	tables, err := store.GetTables(ctx)
+	assert.Nil(t, err)
+
+	for _, table := range tables {
+		hasIndex := false
+
+		indexes, err := mockIndexes(ctx, store, table)
+		assert.Nil(t, err)
+
+		for _, created := range indexes {
+			hasIndex = hasIndex || created.Name == "path"
+		}
+
+		assert.True(t, hasIndex, table)
+	}
+}
+
+// mockIndexes returns the indexes of a table of the mock storage.
+func mockIndexes(ctx context.Context, store *mock.Storage, table string) ([]model.Index, error) {
+	return store.GetIndexes(helper.WithTable(ctx, table), &record{})
+}
+
+func TestShardsResolved(t *testing.T) {
+	ctx := context.Background()
+	store := mock.New()
+
+	tables := []string{"org_analytics_20240101", "org_analytics_2024010", "org_other_20240101", "analytics_20240102"}
+	for _, table := range tables {
+		assert.Nil(t, store.Migrate(helper.WithTable(ctx, table), []model.DBObject{&record{}}))
+	}
+
+	s := &storage{next: store, resolve: func(table string) string {
+		return "org_" + table
+	}}
+
+	shards, err := s.shards(ctx, "analytics", model.DateSharding{Field: "timestamp"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"analytics_20240101"}, shards)
+}
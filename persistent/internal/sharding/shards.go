@@ -0,0 +1,214 @@
+package sharding
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+// shardMarker stands for the suffix of a shard name when resolving it, to find the shards among the tables.
+const shardMarker = "\x00"
+
+type shard struct {
+	name  string
+	start time.Time
+}
+
+// shards returns the existing shards of table which can hold the rows matching filter, sorted by time.
+// The shards out of the time range of filter on the shard key field are pruned.
+func (s *storage) shards(ctx context.Context,
+	table string,
+	sharding model.DateSharding,
+	filter model.DBM,
+) ([]string, error) {
+	tables, err := s.next.GetTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tables are listed by their resolved names, which are the same as the one of the marker
+	// with the shard suffix in its place
+	resolved := s.resolve(table + "_" + shardMarker)
+
+	i := strings.Index(resolved, shardMarker)
+	if i < 0 {
+		return nil, nil
+	}
+
+	prefix, suffix := resolved[:i], resolved[i+len(shardMarker):]
+	from, to := timeRange(filter, sharding.Field)
+
+	var found []shard
+
+	for _, name := range tables {
+		if len(name) < len(prefix)+len(suffix) || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		shardSuffix := name[len(prefix) : len(name)-len(suffix)]
+
+		start, ok := sharding.ShardStart(shardSuffix)
+		if !ok {
+			continue
+		}
+
+		if (!from.IsZero() && !sharding.ShardEnd(start).After(from)) || (!to.IsZero() && start.After(to)) {
+			continue
+		}
+
+		found = append(found, shard{name: table + "_" + shardSuffix, start: start})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].start.Before(found[j].start)
+	})
+
+	names := make([]string, len(found))
+	for i, shard := range found {
+		names[i] = shard.name
+	}
+
+	return names, nil
+}
+
+// readShards is the same as shards, returning the table itself when there's no shard,
+// so the operations behave as on an empty table.
+func (s *storage) readShards(ctx context.Context,
+	table string,
+	sharding model.DateSharding,
+	filter model.DBM,
+) ([]string, error) {
+	shards, err := s.shards(ctx, table, sharding, filter)
+	if err == nil && len(shards) == 0 {
+		shards = []string{table}
+	}
+
+	return shards, err
+}
+
+// timeRange returns the bounds of the times of field matched by filter, which are zero when unbounded.
+func timeRange(filter model.DBM, field string) (from, to time.Time) {
+	switch value := filter[field].(type) {
+	case time.Time:
+		return value, value
+	case model.DBM:
+		for operator, bound := range value {
+			t, ok := bound.(time.Time)
+			if !ok {
+				continue
+			}
+
+			if (operator == "$gt" || operator == "$gte" || operator == "$eq") && (from.IsZero() || t.After(from)) {
+				from = t
+			}
+
+			if (operator == "$lt" || operator == "$lte" || operator == "$eq") && (to.IsZero() || t.Before(to)) {
+				to = t
+			}
+		}
+	}
+
+	return from, to
+}
+
+// shardTime returns the time of the shard key field of row.
+func shardTime(row model.DBObject, field string) (time.Time, error) {
+	if value, ok := helper.BSONField(row, field); ok {
+		switch t := value.Interface().(type) {
+		case time.Time:
+			if !t.IsZero() {
+				return t, nil
+			}
+		case *time.Time:
+			if t != nil && !t.IsZero() {
+				return *t, nil
+			}
+		}
+	}
+
+	return time.Time{}, types.ErrShardKeyMissing
+}
+
+// group returns the shards of rows, in the order of their first row, along with the indexes of their rows.
+func group(table string, sharding model.DateSharding, rows []model.DBObject) ([]string, map[string][]int, error) {
+	var shards []string
+
+	groups := map[string][]int{}
+
+	for i, row := range rows {
+		t, err := shardTime(row, sharding.Field)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := sharding.ShardName(table, t)
+		if _, ok := groups[name]; !ok {
+			shards = append(shards, name)
+		}
+
+		groups[name] = append(groups[name], i)
+	}
+
+	return shards, groups, nil
+}
+
+// first runs op on the shards until it doesn't fail with a not found error.
+func first(ctx context.Context, shards []string, op func(ctx context.Context) error) error {
+	var err error
+
+	for _, shard := range shards {
+		if err = op(helper.WithTable(ctx, shard)); !utils.IsErrNoRows(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// each runs op on every shard. The rows not found in a shard aren't an error, unless they're found in none.
+func each(ctx context.Context, shards []string, op func(ctx context.Context) error) error {
+	var notFound error
+
+	found := false
+
+	for _, shard := range shards {
+		err := op(helper.WithTable(ctx, shard))
+
+		switch {
+		case utils.IsErrNoRows(err):
+			notFound = err
+		case err != nil:
+			return err
+		default:
+			found = true
+		}
+	}
+
+	if !found {
+		return notFound
+	}
+
+	return nil
+}
+
+// sum runs op on every shard, returning the sum of its results.
+func sum(ctx context.Context, shards []string, op func(ctx context.Context) (int, error)) (int, error) {
+	total := 0
+
+	for _, shard := range shards {
+		n, err := op(helper.WithTable(ctx, shard))
+		total += n
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,633 @@
+// Package tenant wraps a persistent storage so its operations only see and change the rows of a tenant,
+// identified by the value of a field of the rows.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+// crossTableStages are the aggregation stages reading or writing other tables, which can't be scoped to the tenant.
+var crossTableStages = []string{"$lookup", "$graphLookup", "$unionWith", "$out", "$merge"}
+
+// leadingStages are the aggregation stages that must be the first one of a pipeline,
+// so the tenant condition is matched right after them.
+var leadingStages = []string{"$geoNear", "$search", "$searchMeta"}
+
+type storage struct {
+	next  types.PersistentStorage
+	field string
+	id    interface{}
+}
+
+// New returns a persistent storage running the operations of next on the rows whose field is id.
+func New(next types.PersistentStorage, field string, id interface{}) types.PersistentStorage {
+	return &storage{next: next, field: field, id: id}
+}
+
+// scope returns filter along with the tenant condition. The given filter is not modified.
+// It fails if filter sets a condition on the tenant field other than the tenant itself.
+func (s *storage) scope(filter model.DBM) (model.DBM, error) {
+	scoped := model.DBM{s.field: s.id}
+
+	for key, value := range filter {
+		if key == s.field && !reflect.DeepEqual(value, s.id) {
+			return nil, types.ErrTenantMismatch
+		}
+
+		scoped[key] = value
+	}
+
+	return scoped, nil
+}
+
+// scopeFirst is the same as scope for the optional filter of an operation.
+func (s *storage) scopeFirst(filters []model.DBM) (model.DBM, error) {
+	if len(filters) > 1 {
+		return nil, types.ErrMultipleDBM
+	}
+
+	if len(filters) == 0 {
+		return s.scope(nil)
+	}
+
+	return s.scope(filters[0])
+}
+
+// scopeRow returns the filter of row, defaulting to its _id, along with the tenant condition.
+func (s *storage) scopeRow(row model.DBObject, filters []model.DBM) (model.DBM, error) {
+	if len(filters) > 1 {
+		return nil, types.ErrMultipleQueryForSingleRow
+	}
+
+	if len(filters) == 0 {
+		return s.scope(model.DBM{"_id": row.GetObjectID()})
+	}
+
+	return s.scope(filters[0])
+}
+
+// setTenant sets the tenant field of the rows, failing if one of them belongs to another tenant.
+func (s *storage) setTenant(rows ...model.DBObject) error {
+	for _, row := range rows {
+		field, err := s.tenantField(row)
+		if err != nil {
+			return err
+		}
+
+		if field.IsZero() {
+			field.Set(reflect.ValueOf(s.id))
+		} else if !reflect.DeepEqual(field.Interface(), s.id) {
+			return types.ErrTenantMismatch
+		}
+	}
+
+	return nil
+}
+
+// tenantField returns the settable value of the field of row whose bson name is the tenant field.
+func (s *storage) tenantField(row model.DBObject) (reflect.Value, error) {
+	field, ok := helper.BSONField(row, s.field)
+	if !ok || !reflect.TypeOf(s.id).AssignableTo(field.Type()) {
+		return reflect.Value{}, types.ErrTenantField
+	}
+
+	return field, nil
+}
+
+// checkUpdate fails if update changes the tenant field to another tenant, or removes it: with an operator other
+// than $set and $setOnInsert, through a dotted path within or above the tenant field, or by renaming another field
+// to it with $rename.
+func (s *storage) checkUpdate(update model.DBM) error {
+	for key, value := range update {
+		if !strings.HasPrefix(key, "$") {
+			if !s.keepsTenant(key, value, true) {
+				return types.ErrTenantMismatch
+			}
+
+			continue
+		}
+
+		fields := reflect.ValueOf(value)
+		if fields.Kind() != reflect.Map || fields.Type().Key().Kind() != reflect.String {
+			continue
+		}
+
+		setsValue := key == "$set" || key == "$setOnInsert"
+
+		iter := fields.MapRange()
+		for iter.Next() {
+			if !s.keepsTenant(iter.Key().String(), iter.Value().Interface(), setsValue) {
+				return types.ErrTenantMismatch
+			}
+
+			// $rename sets the field named by its value
+			if target, ok := iter.Value().Interface().(string); ok && key == "$rename" && s.touchesTenant(target) {
+				return types.ErrTenantMismatch
+			}
+		}
+	}
+
+	return nil
+}
+
+// keepsTenant reports whether updating path with value keeps the tenant field: path doesn't touch it, or sets it
+// to the tenant ID if setsValue is set.
+func (s *storage) keepsTenant(path string, value interface{}, setsValue bool) bool {
+	if path == s.field {
+		return setsValue && reflect.DeepEqual(value, s.id)
+	}
+
+	return !s.touchesTenant(path)
+}
+
+// touchesTenant reports whether updating path changes the tenant field: path is the tenant field, a field within
+// it, or a document containing it.
+func (s *storage) touchesTenant(path string) bool {
+	return path == s.field || strings.HasPrefix(path, s.field+".") || strings.HasPrefix(s.field, path+".")
+}
+
+// scopePipeline returns pipeline along with a $match stage of the tenant condition.
+// It fails if pipeline has a stage reading or writing another table.
+func (s *storage) scopePipeline(pipeline []model.DBM) ([]model.DBM, error) {
+	if stage, ok := crossTableStage(pipeline); ok {
+		return nil, fmt.Errorf("%w: %s", types.ErrTenantUnsupported, stage)
+	}
+
+	match := model.DBM{"$match": model.DBM{s.field: s.id}}
+
+	position := 0
+	if len(pipeline) > 0 {
+		if _, ok := helper.UnsupportedStage(pipeline[:1], leadingStages); ok {
+			position = 1
+		}
+	}
+
+	scoped := make([]model.DBM, 0, len(pipeline)+1)
+	scoped = append(scoped, pipeline[:position]...)
+	scoped = append(scoped, match)
+	scoped = append(scoped, pipeline[position:]...)
+
+	return scoped, nil
+}
+
+// crossTableStage returns the first stage of pipeline, or of its $facet sub-pipelines,
+// which reads or writes another table. A $facet which isn't made of documents keyed by strings,
+// e.g. a bson.D, is returned as well, since its sub-pipelines can't be checked.
+func crossTableStage(pipeline []model.DBM) (string, bool) {
+	if stage, ok := helper.UnsupportedStage(pipeline, crossTableStages); ok {
+		return stage, true
+	}
+
+	for _, stage := range pipeline {
+		value, ok := stage["$facet"]
+		if !ok {
+			continue
+		}
+
+		facets, ok := document(value)
+		if !ok {
+			return "$facet", true
+		}
+
+		for _, facet := range facets {
+			subPipeline, ok := documents(facet)
+			if !ok {
+				return "$facet", true
+			}
+
+			if stage, ok := crossTableStage(subPipeline); ok {
+				return stage, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// document returns value as a model.DBM if it's a map keyed by strings, e.g. a bson.M or a map[string]interface{}.
+func document(value interface{}) (model.DBM, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	doc := make(model.DBM, v.Len())
+
+	iter := v.MapRange()
+	for iter.Next() {
+		doc[iter.Key().String()] = iter.Value().Interface()
+	}
+
+	return doc, true
+}
+
+// documents returns value as a []model.DBM if it's a list of documents, e.g. a []bson.M or a []interface{}.
+func documents(value interface{}) ([]model.DBM, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	docs := make([]model.DBM, 0, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		doc, ok := document(v.Index(i).Interface())
+		if !ok {
+			return nil, false
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, true
+}
+
+func (s *storage) Health(ctx context.Context) model.HealthStatus {
+	return s.next.Health(ctx)
+}
+
+func (s *storage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	if err := s.setTenant(rows...); err != nil {
+		return err
+	}
+
+	return s.next.Insert(ctx, rows...)
+}
+
+func (s *storage) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	if err := s.setTenant(rows...); err != nil {
+		return 0, err
+	}
+
+	return s.next.BulkInsert(ctx, rows, opts)
+}
+
+func (s *storage) Delete(ctx context.Context, row model.DBObject, filters ...model.DBM) error {
+	filter, err := s.scopeRow(row, filters)
+	if err != nil {
+		return err
+	}
+
+	return s.next.Delete(ctx, row, filter)
+}
+
+// Purge isn't supported, as it can't be scoped to the tenant.
+func (s *storage) Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error) {
+	return 0, types.ErrTenantUnsupported
+}
+
+func (s *storage) DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error) {
+	scoped, err := s.scope(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.next.DeleteWithResult(ctx, row, scoped)
+}
+
+func (s *storage) Update(ctx context.Context, row model.DBObject, filters ...model.DBM) error {
+	if err := s.setTenant(row); err != nil {
+		return err
+	}
+
+	filter, err := s.scopeRow(row, filters)
+	if err != nil {
+		return err
+	}
+
+	return s.next.Update(ctx, row, filter)
+}
+
+func (s *storage) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
+	filter, err := s.scopeFirst(filters)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.next.Count(ctx, row, filter)
+}
+
+func (s *storage) CountWithOpts(ctx context.Context,
+	row model.DBObject,
+	opts model.CountOpts,
+	filters ...model.DBM,
+) (int, error) {
+	filter, err := s.scopeFirst(filters)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.next.CountWithOpts(ctx, row, opts, filter)
+}
+
+func (s *storage) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	scoped, err := s.scope(query)
+	if err != nil {
+		return err
+	}
+
+	return s.next.Query(ctx, row, result, scoped)
+}
+
+func (s *storage) SearchText(ctx context.Context,
+	row model.DBObject,
+	result interface{},
+	text string,
+	filter model.DBM,
+) error {
+	scoped, err := s.scope(filter)
+	if err != nil {
+		return err
+	}
+
+	return s.next.SearchText(ctx, row, result, text, scoped)
+}
+
+// Watch only sends the deletes of the rows whose _id is in filter, as the tenant of the deleted rows isn't known.
+func (s *storage) Watch(ctx context.Context, row model.DBObject, filter model.DBM) (<-chan model.ChangeEvent, error) {
+	scoped, err := s.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.Watch(ctx, row, scoped)
+}
+
+func (s *storage) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	scoped, err := s.scope(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.QueryCursor(ctx, row, scoped)
+}
+
+func (s *storage) ListPage(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+	page model.PageRequest,
+) (model.PageResult, error) {
+	scoped, err := s.scope(filter)
+	if err != nil {
+		return model.PageResult{}, err
+	}
+
+	return s.next.ListPage(ctx, row, scoped, page)
+}
+
+func (s *storage) Distinct(ctx context.Context,
+	row model.DBObject,
+	field string,
+	filter model.DBM,
+) ([]interface{}, error) {
+	scoped, err := s.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.Distinct(ctx, row, field, scoped)
+}
+
+func (s *storage) BulkUpdate(ctx context.Context, rows []model.DBObject, filters ...model.DBM) error {
+	if len(filters) > 0 && len(filters) != len(rows) {
+		return types.ErrRowQueryDiffLength
+	}
+
+	if err := s.setTenant(rows...); err != nil {
+		return err
+	}
+
+	scoped := make([]model.DBM, len(rows))
+
+	for i, row := range rows {
+		var rowFilters []model.DBM
+		if len(filters) > 0 {
+			rowFilters = filters[i : i+1]
+		}
+
+		filter, err := s.scopeRow(row, rowFilters)
+		if err != nil {
+			return err
+		}
+
+		scoped[i] = filter
+	}
+
+	return s.next.BulkUpdate(ctx, rows, scoped...)
+}
+
+func (s *storage) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	if err := s.checkUpdate(update); err != nil {
+		return err
+	}
+
+	scoped, err := s.scope(query)
+	if err != nil {
+		return err
+	}
+
+	return s.next.UpdateAll(ctx, row, scoped, update)
+}
+
+// Drop isn't supported, as it drops the rows of every tenant.
+func (s *storage) Drop(ctx context.Context, row model.DBObject) error {
+	return types.ErrTenantUnsupported
+}
+
+func (s *storage) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	return s.next.CreateIndex(ctx, row, index)
+}
+
+func (s *storage) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	return s.next.GetIndexes(ctx, row)
+}
+
+func (s *storage) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+func (s *storage) HasTable(ctx context.Context, table string) (bool, error) {
+	return s.next.HasTable(ctx, table)
+}
+
+// DropDatabase isn't supported, as it drops the rows of every tenant.
+func (s *storage) DropDatabase(ctx context.Context) error {
+	return types.ErrTenantUnsupported
+}
+
+func (s *storage) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
+	return s.next.Migrate(ctx, rows, opts...)
+}
+
+func (s *storage) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
+	return s.next.DBTableStats(ctx, row)
+}
+
+func (s *storage) Aggregate(ctx context.Context, row model.DBObject, pipeline []model.DBM) ([]model.DBM, error) {
+	scoped, err := s.scopePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.Aggregate(ctx, row, scoped)
+}
+
+func (s *storage) Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error) {
+	scoped, err := s.scope(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.Explain(ctx, row, scoped)
+}
+
+func (s *storage) ExplainAggregate(ctx context.Context, row model.DBObject, pipeline []model.DBM) (model.DBM, error) {
+	scoped, err := s.scopePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.next.ExplainAggregate(ctx, row, scoped)
+}
+
+func (s *storage) CleanIndexes(ctx context.Context, row model.DBObject) error {
+	return s.next.CleanIndexes(ctx, row)
+}
+
+// Upsert inserts the row with the tenant field, which is part of the query equality conditions.
+func (s *storage) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	if err := s.checkUpdate(update); err != nil {
+		return err
+	}
+
+	scoped, err := s.scope(query)
+	if err != nil {
+		return err
+	}
+
+	return s.next.Upsert(ctx, row, scoped, update)
+}
+
+func (s *storage) FindOneAndUpdate(ctx context.Context,
+	row model.DBObject,
+	query, update model.DBM,
+	opts ...model.FindOneOpts,
+) error {
+	if err := s.checkUpdate(update); err != nil {
+		return err
+	}
+
+	scoped, err := s.scope(query)
+	if err != nil {
+		return err
+	}
+
+	return s.next.FindOneAndUpdate(ctx, row, scoped, update, opts...)
+}
+
+func (s *storage) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
+	return s.next.GetDatabaseInfo(ctx)
+}
+
+func (s *storage) GetTables(ctx context.Context) ([]string, error) {
+	return s.next.GetTables(ctx)
+}
+
+// DropTable isn't supported, as it drops the rows of every tenant.
+func (s *storage) DropTable(ctx context.Context, name string) (int, error) {
+	return 0, types.ErrTenantUnsupported
+}
+
+func (s *storage) ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error) {
+	scoped, err := s.scope(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.next.ExportNDJSON(ctx, row, scoped, w)
+}
+
+// ImportNDJSON isn't supported, as the tenant field of the imported rows can't be checked.
+func (s *storage) ImportNDJSON(ctx context.Context, row model.DBObject, r io.Reader, opts ...model.DBM) (int, error) {
+	return 0, types.ErrTenantUnsupported
+}
+
+func (s *storage) Export(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+	w io.Writer,
+	format model.ExportFormat,
+) (int, error) {
+	scoped, err := s.scope(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.next.Export(ctx, row, scoped, w, format)
+}
+
+// Import isn't supported, as the tenant field of the imported rows can't be checked.
+func (s *storage) Import(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	format model.ExportFormat,
+	opts ...model.DBM,
+) (int, error) {
+	return 0, types.ErrTenantUnsupported
+}
+
+func (s *storage) SessionSettings(ctx context.Context) (model.DBM, error) {
+	return s.next.SessionSettings(ctx)
+}
+
+func (s *storage) ExistingIDs(ctx context.Context, row model.DBObject, ids []model.ObjectID) ([]model.ObjectID, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query, err := s.scope(model.DBM{"_id": model.DBM{"$in": ids}, "_fields": []string{"_id"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID model.ObjectID `bson:"_id"`
+	}
+
+	if err := s.next.Query(ctx, row, &rows, query); err != nil {
+		return nil, err
+	}
+
+	found := make(map[model.ObjectID]bool, len(rows))
+	for _, row := range rows {
+		found[row.ID] = true
+	}
+
+	var existing []model.ObjectID
+
+	for _, id := range ids {
+		if found[id] {
+			existing = append(existing, id)
+			// an ID given twice is returned once
+			delete(found, id)
+		}
+	}
+
+	return existing, nil
+}
+
+func (s *storage) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	return s.next.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+		return fn(&storage{next: tx, field: s.field, id: s.id})
+	})
+}
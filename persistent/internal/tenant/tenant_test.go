@@ -0,0 +1,248 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/mock"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+type dummyDBObject struct {
+	ID     model.ObjectID `bson:"_id,omitempty"`
+	Name   string         `bson:"name"`
+	OrgID  string         `bson:"org_id"`
+	Active bool           `bson:"active"`
+}
+
+func (d *dummyDBObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyDBObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyDBObject) TableName() string {
+	return "dummy"
+}
+
+// seed inserts a row named name for each of the tenants org1 and org2, returning the storages of both tenants.
+func seed(t *testing.T, names ...string) (org1, org2 types.PersistentStorage) {
+	t.Helper()
+
+	store := mock.New()
+	org1 = New(store, "org_id", "org1")
+	org2 = New(store, "org_id", "org2")
+
+	for _, name := range names {
+		assert.Nil(t, org1.Insert(context.Background(), &dummyDBObject{Name: name}))
+		assert.Nil(t, org2.Insert(context.Background(), &dummyDBObject{Name: name}))
+	}
+
+	return org1, org2
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	org1, _ := seed(t)
+
+	row := &dummyDBObject{Name: "alice"}
+	assert.Nil(t, org1.Insert(ctx, row))
+	assert.Equal(t, "org1", row.OrgID)
+
+	assert.EqualError(t, org1.Insert(ctx, &dummyDBObject{Name: "bob", OrgID: "org2"}), types.ErrorTenantMismatch)
+
+	type untenanted struct {
+		dummyDBObject
+	}
+
+	assert.EqualError(t, org1.Insert(ctx, &untenanted{}), types.ErrorTenantField)
+	assert.EqualError(t, New(mock.New(), "org_id", 1).Insert(ctx, &dummyDBObject{}), types.ErrorTenantField)
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	org1, org2 := seed(t, "alice", "bob")
+
+	var rows []dummyDBObject
+
+	assert.Nil(t, org1.Query(ctx, &dummyDBObject{}, &rows, model.DBM{"_sort": "name"}))
+	assert.Len(t, rows, 2)
+
+	for _, row := range rows {
+		assert.Equal(t, "org1", row.OrgID)
+	}
+
+	n, err := org2.Count(ctx, &dummyDBObject{}, model.DBM{"name": "alice"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	err = org1.Query(ctx, &dummyDBObject{}, &rows, model.DBM{"org_id": "org2"})
+	assert.EqualError(t, err, types.ErrorTenantMismatch)
+
+	ids := []model.ObjectID{rows[0].ID, model.NewObjectID()}
+	existing, err := org2.ExistingIDs(ctx, &dummyDBObject{}, ids)
+	assert.Nil(t, err)
+	assert.Empty(t, existing)
+
+	existing, err = org1.ExistingIDs(ctx, &dummyDBObject{}, ids)
+	assert.Nil(t, err)
+	assert.Equal(t, ids[:1], existing)
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	org1, org2 := seed(t, "alice")
+
+	var row dummyDBObject
+	assert.Nil(t, org1.Query(ctx, &dummyDBObject{}, &row, model.DBM{"name": "alice"}))
+
+	// the row of org1 can't be updated through org2, nor moved to org2
+	row.Active = true
+	assert.EqualError(t, org2.Update(ctx, &row), types.ErrorTenantMismatch)
+	assert.EqualError(t, org1.Update(ctx, &dummyDBObject{ID: row.ID, OrgID: "org2"}), types.ErrorTenantMismatch)
+	assert.NotNil(t, org2.Update(ctx, &dummyDBObject{ID: row.ID, Name: "alice"}))
+	assert.Nil(t, org1.Update(ctx, &row))
+
+	update := model.DBM{"$set": model.DBM{"org_id": "org2"}}
+	assert.EqualError(t, org1.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, update), types.ErrorTenantMismatch)
+
+	update = model.DBM{"$unset": model.DBM{"org_id": ""}}
+	assert.EqualError(t, org1.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, update), types.ErrorTenantMismatch)
+
+	update = model.DBM{"$set": model.DBM{"active": true}}
+	assert.Nil(t, org2.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, update))
+
+	n, err := org1.Count(ctx, &dummyDBObject{}, model.DBM{"active": true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	// the upserted row belongs to the tenant
+	update = model.DBM{"$set": model.DBM{"active": true}}
+	assert.Nil(t, org2.Upsert(ctx, &dummyDBObject{}, model.DBM{"name": "carol"}, update))
+	assert.Nil(t, org2.Query(ctx, &dummyDBObject{}, &row, model.DBM{"name": "carol"}))
+	assert.Equal(t, "org2", row.OrgID)
+}
+
+func TestUpdateTenantPaths(t *testing.T) {
+	ctx := context.Background()
+	org1, _ := seed(t, "alice")
+
+	updates := []model.DBM{
+		{"$rename": model.DBM{"name": "org_id"}},
+		{"$rename": model.DBM{"name": "org_id.name"}},
+		{"$rename": model.DBM{"org_id": "name"}},
+		{"$set": model.DBM{"org_id.name": "org2"}},
+		{"$unset": model.DBM{"org_id.name": ""}},
+		{"org_id.name": "org2"},
+	}
+
+	for _, update := range updates {
+		err := org1.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, update)
+		assert.EqualError(t, err, types.ErrorTenantMismatch, "%v", update)
+	}
+
+	// the mock doesn't support $rename, but the guard lets it through
+	err := org1.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, model.DBM{"$rename": model.DBM{"name": "nick"}})
+	assert.NotErrorIs(t, err, types.ErrTenantMismatch)
+
+	// the documents containing a nested tenant field can't be replaced
+	nested := New(mock.New(), "meta.org_id", "org1")
+
+	update := model.DBM{"$set": model.DBM{"meta": model.DBM{"org_id": "org2"}}}
+	assert.EqualError(t, nested.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, update), types.ErrorTenantMismatch)
+
+	update = model.DBM{"$set": model.DBM{"meta.org_id": "org1", "metadata": "value"}}
+	assert.NotErrorIs(t, nested.UpdateAll(ctx, &dummyDBObject{}, model.DBM{}, update), types.ErrTenantMismatch)
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	org1, org2 := seed(t, "alice", "bob")
+
+	var row dummyDBObject
+	assert.Nil(t, org1.Query(ctx, &dummyDBObject{}, &row, model.DBM{"name": "alice"}))
+
+	n, err := org2.DeleteWithResult(ctx, &dummyDBObject{}, model.DBM{"_id": row.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+
+	n, err = org2.DeleteWithResult(ctx, &dummyDBObject{}, model.DBM{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	count, err := org1.Count(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.EqualError(t, org1.Drop(ctx, &dummyDBObject{}), types.ErrorTenantUnsupported)
+
+	_, err = org1.DropTable(ctx, "dummy")
+	assert.EqualError(t, err, types.ErrorTenantUnsupported)
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	org1, _ := seed(t, "alice", "bob")
+
+	result, err := org1.Aggregate(ctx, &dummyDBObject{}, []model.DBM{
+		{"$group": model.DBM{"_id": "$org_id", "total": model.DBM{"$sum": 1}}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.DBM{{"_id": "org1", "total": 2}}, result)
+
+	_, err = org1.Aggregate(ctx, &dummyDBObject{}, []model.DBM{
+		{"$facet": model.DBM{"others": []model.DBM{{"$lookup": model.DBM{"from": "other"}}}}},
+	})
+	assert.EqualError(t, err, types.ErrorTenantUnsupported+": $lookup")
+
+	_, err = org1.Aggregate(ctx, &dummyDBObject{}, []model.DBM{
+		{"$facet": bson.M{"others": []interface{}{bson.M{"$unionWith": "other"}}}},
+	})
+	assert.EqualError(t, err, types.ErrorTenantUnsupported+": $unionWith")
+
+	_, err = org1.Aggregate(ctx, &dummyDBObject{}, []model.DBM{
+		{"$facet": map[string]interface{}{
+			"count":  []bson.M{{"$count": "total"}},
+			"others": []interface{}{map[string]interface{}{"$lookup": bson.M{"from": "other"}}},
+		}},
+	})
+	assert.EqualError(t, err, types.ErrorTenantUnsupported+": $lookup")
+
+	_, err = org1.Aggregate(ctx, &dummyDBObject{}, []model.DBM{
+		{"$facet": bson.D{{Key: "others", Value: bson.A{bson.M{"$lookup": bson.M{"from": "other"}}}}}},
+	})
+	assert.EqualError(t, err, types.ErrorTenantUnsupported+": $facet")
+
+	_, err = org1.Aggregate(ctx, &dummyDBObject{}, []model.DBM{
+		{"$facet": bson.M{"others": []interface{}{bson.D{{Key: "$lookup", Value: bson.M{"from": "other"}}}}}},
+	})
+	assert.EqualError(t, err, types.ErrorTenantUnsupported+": $facet")
+
+	_, ok := crossTableStage([]model.DBM{{"$facet": bson.M{"total": []interface{}{bson.M{"$count": "total"}}}}})
+	assert.False(t, ok)
+
+	s := &storage{field: "org_id", id: "org1"}
+	pipeline, err := s.scopePipeline([]model.DBM{{"$geoNear": model.DBM{}}, {"$limit": 1}})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.DBM{
+		{"$geoNear": model.DBM{}},
+		{"$match": model.DBM{"org_id": "org1"}},
+		{"$limit": 1},
+	}, pipeline)
+}
+
+func TestWithTransaction(t *testing.T) {
+	ctx := context.Background()
+	org1, _ := seed(t)
+
+	err := org1.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+		return tx.Insert(ctx, &dummyDBObject{Name: "alice", OrgID: "org2"})
+	})
+	assert.EqualError(t, err, types.ErrorTenantMismatch)
+}
@@ -1,13 +1,15 @@
 package types
 
 import (
+	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
-	"os"
+	"fmt"
 	"time"
 
-	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+	storagetypes "github.com/TykTechnologies/storage/types"
 )
 
 const (
@@ -18,19 +20,55 @@ type ClientOpts struct {
 	// ConnectionString is the expression used to connect to a storage db server.
 	// It contains parameters such as username, hostname, password and port
 	ConnectionString string
+	// TLS is the TLS configuration of the connection. It has precedence over the SSL options below.
+	TLS *storagetypes.TLSConfig
 	// UseSSL is SSL connection is required to connect
+	//
+	// Deprecated: use TLS.Enable.
 	UseSSL bool
 	// This setting allows the use of self-signed certificates when connecting to an encrypted storage database.
+	//
+	// Deprecated: use TLS.InsecureSkipVerify.
 	SSLInsecureSkipVerify bool
 	// Ignore hostname check when it differs from the original (for example with SSH tunneling).
 	// The rest of the TLS verification will still be performed
+	//
+	// Deprecated: use TLS.AllowInvalidHostnames.
 	SSLAllowInvalidHostnames bool
 	// Path to the PEM file with trusted root certificates
+	//
+	// Deprecated: use TLS.CAFile.
 	SSLCAFile string
 	// Path to the PEM file which contains both client certificate and private key. This is required for Mutual TLS.
+	//
+	// Deprecated: use TLS.CertFile.
 	SSLPEMKeyfile string
+	// AuthMechanism is the mechanism authenticating the connections, e.g. MONGODB-AWS for the IAM authentication
+	// of DocumentDB, which takes the AWS credentials of the environment if CredentialsProvider isn't set,
+	// GSSAPI for Kerberos or PLAIN for LDAP. The mechanism of the connection string has precedence.
+	// GSSAPI requires the mongo driver to be built with the gssapi tag, and mgo with the sasl tag.
+	AuthMechanism string
+	// AuthSource is the database the credentials are checked against, e.g. $external for Kerberos and LDAP,
+	// which the drivers default to with these mechanisms. The source of the connection string has precedence.
+	AuthSource string
+	// AuthMechanismProperties are the properties of the mechanism, e.g. the SERVICE_NAME, SERVICE_REALM,
+	// SERVICE_HOST and CANONICALIZE_HOST_NAME of GSSAPI. mgo only supports SERVICE_NAME and SERVICE_HOST.
+	// The properties of the connection string have precedence.
+	AuthMechanismProperties map[string]string
+	// CredentialsProvider returns the credentials of the connections instead of the ones of the connection string,
+	// e.g. an IAM auth token or a secret of Vault or Kubernetes, so no password has to be stored in plain config.
+	// They are fetched on connect and when the authentication fails, and the storage reconnects with new ones
+	// shortly before they expire.
+	CredentialsProvider CredentialsProvider
 	// Sets the session consistency for the storage connection
 	SessionConsistency string
+	// ReadPreference sets the members of the replica set the reads are sent to: primary, primaryPreferred,
+	// secondary, secondaryPreferred or nearest. It has precedence over SessionConsistency.
+	// It can be overridden per query with the "_read_pref" key of the query.
+	ReadPreference string
+	// ReadPreferenceTags are the tag sets used to select the members to read from, in order of preference.
+	// They can't be used with the primary read preference.
+	ReadPreferenceTags []map[string]string
 	// Sets the connection timeout to the database. Defaults to 10s.
 	ConnectionTimeout int
 	// DirectConnection informs whether to establish connections only with the specified seed servers,
@@ -39,96 +77,166 @@ type ClientOpts struct {
 	// and won't attempt to discover other hosts in the cluster. Useful when network restrictions
 	// prevent discovery, such as with SSH tunneling. Default is false.
 	DirectConnection bool
+	// MaxOpenConns is the maximum number of connections kept in the pool (per server for mongo).
+	// Defaults to the driver default when 0.
+	MaxOpenConns int
+	// MaxIdleConns is the number of idle connections the pool keeps open. For mongo, it's the minimum pool size.
+	MaxIdleConns int
+	// ConnMaxIdleTime is the number of seconds a connection can stay idle in the pool before being closed.
+	ConnMaxIdleTime int
+	// ConnMaxLifetime is the maximum number of seconds a connection can be reused.
+	// Not supported by the mongo drivers, which keep the connections until they are idle for too long.
+	ConnMaxLifetime int
+	// SlowQueryThreshold is the number of milliseconds after which an operation is logged as slow
+	// by the logger set with WithLogger. Slow operations aren't logged when 0.
+	SlowQueryThreshold int
+	// Retry is the policy to retry the operations failed with a transient error, such as a network error.
+	// The writes which aren't idempotent, such as inserts, are only retried on the errors leaving them unapplied.
+	// The operations aren't retried by default.
+	Retry RetryOpts
+	// CircuitBreaker is the circuit breaker making the operations fail fast while the database is down.
+	// It's disabled by default.
+	CircuitBreaker CircuitBreakerOpts
+	// OnConnectionEvent is called when the connection to the database changes: on connect, disconnect, reconnect
+	// and failover, e.g. to log it or to flush caches. It's called synchronously by the driver, so it shouldn't
+	// block nor use the storage.
+	OnConnectionEvent func(event model.ConnectionEvent)
+	// TableNameResolver maps the table names of the rows, as returned by DBObject.TableName, and the ones given
+	// to the operations such as HasTable or DropTable, to the names of the tables of the database,
+	// e.g. to prefix them with a tenant ID so several tenants can share a database. The names are used as is when nil.
+	// GetTables still returns the names of the tables of the database.
+	TableNameResolver func(table string) string
+	// DateSharding is the sharding by date of the sharded tables, by table name. The rows of a sharded table
+	// are inserted into a table per period of time, e.g. a day, and the operations on the table run on the shards
+	// of the time range of their filter on the shard key field. The TableNameResolver must keep the suffix
+	// of the shard names, e.g. by adding a prefix or a suffix to them, so the shards can be found.
+	DateSharding map[string]model.DateSharding
 	// type of database/driver
 	Type string
 }
 
-// GetTLSConfig returns the TLS config given the configuration specified in ClientOpts. It loads certificates if necessary.
-func (opts *ClientOpts) GetTLSConfig() (*tls.Config, error) {
-	tlsConfig := &tls.Config{}
-
-	if !opts.UseSSL {
-		return tlsConfig, errors.New("error getting tls config when ssl is disabled")
-	}
+// RetryOpts is a policy retrying the failed operations with an exponential backoff.
+type RetryOpts struct {
+	// MaxAttempts is the maximum number of times an operation is run, the first one included.
+	// The operations aren't retried when it's lower than 2.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled before each of the next ones. Defaults to 100ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts. Defaults to 5s.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay, between 0 and 1, randomly added or removed from it,
+	// so the clients retrying at once don't hit the database at the same time.
+	Jitter float64
+	// IsRetryable reports whether an operation failed with err can be retried. Defaults to utils.IsTransientError.
+	IsRetryable func(err error) bool
+}
 
-	if opts.SSLInsecureSkipVerify {
-		tlsConfig.InsecureSkipVerify = true
-	}
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
 
-	if opts.SSLCAFile != "" {
-		if err := opts.loadCACertificates(tlsConfig); err != nil {
-			return tlsConfig, err
-		}
-	}
-
-	if opts.SSLAllowInvalidHostnames {
-		tlsConfig.InsecureSkipVerify = true
-		opts.verifyPeerCertificate(tlsConfig)
-	}
+const (
+	// CircuitClosed lets the operations run.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails the operations without running them.
+	CircuitOpen
+	// CircuitHalfOpen lets a single operation run at once to probe whether the database is back.
+	CircuitHalfOpen
+)
 
-	if opts.SSLPEMKeyfile != "" {
-		if err := opts.loadClientCertificates(tlsConfig); err != nil {
-			return tlsConfig, err
-		}
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
 	}
+}
 
-	return tlsConfig, nil
+// CircuitBreakerOpts configures a circuit breaker, which opens after FailureThreshold consecutive failures
+// so the operations fail fast with ErrorCircuitOpen instead of waiting for the database to time out.
+// After OpenTimeout, it turns half-open and lets probing operations run, closing once SuccessThreshold
+// of them succeed in a row or opening again on the first failure.
+type CircuitBreakerOpts struct {
+	// FailureThreshold is the number of consecutive failures opening the circuit. The breaker is disabled when 0.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before turning half-open. Defaults to 30s.
+	OpenTimeout time.Duration
+	// SuccessThreshold is the number of consecutive successful probes closing the circuit. Defaults to 1.
+	SuccessThreshold int
+	// IsFailure reports whether err counts as a failure of the database. Defaults to utils.IsTransientError,
+	// so errors such as not found or duplicated keys don't open the circuit.
+	IsFailure func(err error) bool
+	// OnStateChange is called when the state of the circuit changes, e.g. to log it or to raise an alert.
+	OnStateChange func(from, to CircuitState)
 }
 
-func (opts *ClientOpts) loadCACertificates(tlsConfig *tls.Config) error {
-	caCert, err := os.ReadFile(opts.SSLCAFile)
-	if err != nil {
-		return errors.New("can't load CA certificates:" + err.Error())
+// ResolveTableName returns the name of the table of the database given the name of a table,
+// as resolved by TableNameResolver.
+func (opts *ClientOpts) ResolveTableName(table string) string {
+	if opts == nil || opts.TableNameResolver == nil {
+		return table
 	}
 
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCert)
-	tlsConfig.RootCAs = caCertPool
-
-	return nil
+	return opts.TableNameResolver(table)
 }
 
-func (opts *ClientOpts) verifyPeerCertificate(tlsConfig *tls.Config) {
-	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
-		certs := make([]*x509.Certificate, len(rawCerts))
+// FetchCredentials returns the credentials of CredentialsProvider, or nil if it isn't set.
+// The provider is given the connection timeout.
+func (opts *ClientOpts) FetchCredentials() (*Credentials, error) {
+	if opts.CredentialsProvider == nil {
+		return nil, nil
+	}
 
-		for i, asn1Data := range rawCerts {
-			cert, err := x509.ParseCertificate(asn1Data)
-			if err != nil {
-				return err
-			}
+	timeout := DEFAULT_CONN_TIMEOUT
+	if opts.ConnectionTimeout != 0 {
+		timeout = time.Duration(opts.ConnectionTimeout) * time.Second
+	}
 
-			certs[i] = cert
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-		opts := x509.VerifyOptions{
-			Roots:         tlsConfig.RootCAs,
-			CurrentTime:   time.Now(),
-			DNSName:       "",
-			Intermediates: x509.NewCertPool(),
-		}
+	creds, err := opts.CredentialsProvider.Credentials(ctx)
+	if err != nil {
+		return nil, errors.New("can't fetch credentials: " + err.Error())
+	}
 
-		for i, cert := range certs {
-			if i == 0 {
-				continue
-			}
+	return &creds, nil
+}
 
-			opts.Intermediates.AddCert(cert)
-		}
+// String returns the options without their credentials, so they can be logged: the connection string is sanitized
+// with utils.SanitizeDSN, and the providers and callbacks aren't printed.
+func (opts *ClientOpts) String() string {
+	return fmt.Sprintf("{Type: %s, ConnectionString: %s, TLS: %t, AuthMechanism: %s, AuthSource: %s, "+
+		"ReadPreference: %s, ConnectionTimeout: %d, MaxOpenConns: %d}",
+		opts.Type, utils.SanitizeDSN(opts.ConnectionString), opts.TLSConfig().Enable, opts.AuthMechanism,
+		opts.AuthSource, opts.ReadPreference, opts.ConnectionTimeout, opts.MaxOpenConns)
+}
 
-		_, err := certs[0].Verify(opts)
+// TLSConfig returns the TLS configuration of the connection: TLS if set, or else the one of the SSL options.
+func (opts *ClientOpts) TLSConfig() *storagetypes.TLSConfig {
+	if opts.TLS != nil {
+		return opts.TLS
+	}
 
-		return err
+	return &storagetypes.TLSConfig{
+		Enable:                opts.UseSSL,
+		InsecureSkipVerify:    opts.SSLInsecureSkipVerify,
+		AllowInvalidHostnames: opts.SSLAllowInvalidHostnames,
+		CAFile:                opts.SSLCAFile,
+		CertFile:              opts.SSLPEMKeyfile,
 	}
 }
 
-func (opts *ClientOpts) loadClientCertificates(tlsConfig *tls.Config) error {
-	cert, err := helper.LoadCertificateAndKeyFromFile(opts.SSLPEMKeyfile)
-	if err != nil {
-		return err
+// GetTLSConfig returns the TLS config given the configuration specified in ClientOpts. It loads certificates if necessary.
+func (opts *ClientOpts) GetTLSConfig() (*tls.Config, error) {
+	cfg := opts.TLSConfig()
+	if !cfg.Enable {
+		return &tls.Config{}, errors.New("error getting tls config when ssl is disabled")
 	}
 
-	tlsConfig.Certificates = []tls.Certificate{*cert}
-
-	return nil
+	return cfg.Build()
 }
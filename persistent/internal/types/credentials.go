@@ -0,0 +1,95 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// Credentials are the credentials authenticating the connections to the database.
+type Credentials struct {
+	// Username is the user, or the AWS access key ID with the MONGODB-AWS mechanism.
+	Username string
+	// Password is the password or the auth token, or the AWS secret access key with the MONGODB-AWS mechanism.
+	Password string
+	// SessionToken is the AWS session token of temporary credentials, with the MONGODB-AWS mechanism.
+	SessionToken string
+	// Expiration is the time the credentials expire at. They don't expire if it's zero.
+	Expiration time.Time
+}
+
+// CredentialsProvider returns the credentials of the connections to the database, e.g. from a secrets store
+// such as Vault, or from a cloud IAM service.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is a function implementing CredentialsProvider.
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials returns the credentials of f.
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// EnvCredentials reads the credentials from environment variables.
+type EnvCredentials struct {
+	// UsernameVar is the variable holding the username. There's no username if it's empty.
+	UsernameVar string
+	// PasswordVar is the variable holding the password, which must be set.
+	PasswordVar string
+}
+
+// Credentials returns the credentials of the environment variables.
+func (e EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	password, ok := os.LookupEnv(e.PasswordVar)
+	if !ok {
+		return Credentials{}, errors.New("environment variable " + e.PasswordVar + " is not set")
+	}
+
+	var username string
+	if e.UsernameVar != "" {
+		username = os.Getenv(e.UsernameVar)
+	}
+
+	return Credentials{Username: username, Password: password}, nil
+}
+
+// FileCredentials reads the credentials from files, such as the keys of a Kubernetes secret mounted as a volume.
+// The files are read on every connect, so the rotations of the secret are taken into account.
+type FileCredentials struct {
+	// UsernameFile is the path of the file holding the username. There's no username if it's empty.
+	UsernameFile string
+	// PasswordFile is the path of the file holding the password.
+	PasswordFile string
+}
+
+// Credentials returns the credentials of the files, without their trailing newlines.
+func (f FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	password, err := readSecretFile(f.PasswordFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var username string
+
+	if f.UsernameFile != "" {
+		username, err = readSecretFile(f.UsernameFile)
+		if err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	return Credentials{Username: username, Password: password}, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.New("can't read credentials file: " + err.Error())
+	}
+
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
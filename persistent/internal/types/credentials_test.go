@@ -0,0 +1,62 @@
+package types
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	provider := EnvCredentials{UsernameVar: "TEST_STORAGE_USERNAME", PasswordVar: "TEST_STORAGE_PASSWORD"}
+
+	if _, err := provider.Credentials(context.Background()); err == nil {
+		t.Error("Expected an error when the password variable isn't set")
+	}
+
+	t.Setenv("TEST_STORAGE_USERNAME", "tyk")
+	t.Setenv("TEST_STORAGE_PASSWORD", "secret")
+
+	creds, err := provider.Credentials(context.Background())
+	if err != nil || creds != (Credentials{Username: "tyk", Password: "secret"}) {
+		t.Errorf("Expected the credentials of the environment, got %+v, %v", creds, err)
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	dir := t.TempDir()
+	provider := FileCredentials{
+		UsernameFile: filepath.Join(dir, "username"),
+		PasswordFile: filepath.Join(dir, "password"),
+	}
+
+	if _, err := provider.Credentials(context.Background()); err == nil ||
+		!strings.HasPrefix(err.Error(), "can't read credentials file") {
+		t.Errorf("Expected an error reading the missing files, got %v", err)
+	}
+
+	writeFile(t, provider.UsernameFile, "tyk\n")
+	writeFile(t, provider.PasswordFile, "secret\n")
+
+	creds, err := provider.Credentials(context.Background())
+	if err != nil || creds != (Credentials{Username: "tyk", Password: "secret"}) {
+		t.Errorf("Expected the credentials of the files, got %+v, %v", creds, err)
+	}
+
+	// the secret is rotated
+	writeFile(t, provider.PasswordFile, "rotated")
+
+	creds, err = provider.Credentials(context.Background())
+	if err != nil || creds.Password != "rotated" {
+		t.Errorf("Expected the rotated password, got %+v, %v", creds, err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
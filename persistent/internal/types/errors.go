@@ -1,5 +1,11 @@
 package types
 
+import (
+	"errors"
+
+	storagetypes "github.com/TykTechnologies/storage/types"
+)
+
 const (
 	ErrorRowQueryDiffLenght        = "only one query per row is allowed"
 	ErrorEmptyRow                  = "rows cannot be empty"
@@ -12,4 +18,49 @@ const (
 	ErrorSessionClosed             = "session closed"
 	ErrorRowOptDiffLenght          = "only one options per row is allowed"
 	ErrorCollectionNotFound        = "collection not found"
+	ErrorTransactionsUnsupported   = "transactions are not supported by this driver"
+	ErrorUnknownReadPreference     = "unknown read preference"
+	ErrorChangeStreamsUnsupported  = "change streams are not supported by this driver"
+	ErrorNotSoftDeletable          = "row is not soft deletable"
+	ErrorMultipleFindOneOpts       = "only one find options is supported"
+	ErrorCircuitOpen               = "circuit breaker is open"
+	ErrorSRVUnsupported            = "mongodb+srv connection strings are not supported by this driver"
+	ErrorStageUnsupported          = "aggregation stage not supported by the database"
+	ErrorInvalidPageCursor         = "invalid page cursor"
+	ErrorDryRunUnsupported         = "operation not supported in dry-run mode"
+	ErrorTenantMismatch            = "the row or filter belongs to another tenant"
+	ErrorTenantField               = "the row has no tenant field of the type of the tenant ID"
+	ErrorTenantUnsupported         = "operation not supported on a tenant storage"
+	ErrorShardKeyMissing           = "the shard key field of the row is not set"
+	ErrorShardingUnsupported       = "operation not supported across the shards of a table"
+)
+
+// The sentinel errors of the messages above, to be checked with errors.Is instead of comparing the messages.
+var (
+	ErrRowQueryDiffLength        = errors.New(ErrorRowQueryDiffLenght)
+	ErrEmptyRow                  = errors.New(ErrorEmptyRow)
+	ErrMultipleQueryForSingleRow = errors.New(ErrorMultipleQueryForSingleRow)
+	ErrMultipleDBM               = errors.New(ErrorMultipleDBM)
+	ErrReconnecting              = errors.New(ErrorReconnecting)
+	ErrIndexEmpty                = errors.New(ErrorIndexEmpty)
+	ErrIndexAlreadyExist         = errors.New(ErrorIndexAlreadyExist)
+	ErrIndexComposedTTL          = errors.New(ErrorIndexComposedTTL)
+	ErrSessionClosed             = errors.New(ErrorSessionClosed)
+	ErrRowOptDiffLength          = errors.New(ErrorRowOptDiffLenght)
+	ErrCollectionNotFound        = storagetypes.NewError(storagetypes.CodeNotFound, ErrorCollectionNotFound)
+	ErrTransactionsUnsupported   = errors.New(ErrorTransactionsUnsupported)
+	ErrUnknownReadPreference     = errors.New(ErrorUnknownReadPreference)
+	ErrChangeStreamsUnsupported  = errors.New(ErrorChangeStreamsUnsupported)
+	ErrNotSoftDeletable          = errors.New(ErrorNotSoftDeletable)
+	ErrMultipleFindOneOpts       = errors.New(ErrorMultipleFindOneOpts)
+	ErrCircuitOpen               = errors.New(ErrorCircuitOpen)
+	ErrSRVUnsupported            = errors.New(ErrorSRVUnsupported)
+	ErrStageUnsupported          = errors.New(ErrorStageUnsupported)
+	ErrInvalidPageCursor         = errors.New(ErrorInvalidPageCursor)
+	ErrDryRunUnsupported         = errors.New(ErrorDryRunUnsupported)
+	ErrTenantMismatch            = errors.New(ErrorTenantMismatch)
+	ErrTenantField               = errors.New(ErrorTenantField)
+	ErrTenantUnsupported         = errors.New(ErrorTenantUnsupported)
+	ErrShardKeyMissing           = errors.New(ErrorShardKeyMissing)
+	ErrShardingUnsupported       = errors.New(ErrorShardingUnsupported)
 )
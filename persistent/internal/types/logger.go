@@ -0,0 +1,8 @@
+package types
+
+// Logger is the structured logger the storage reports to. fields holds the context of the message,
+// such as the operation, the table or the error.
+type Logger interface {
+	Warn(msg string, fields map[string]interface{})
+	Error(msg string, fields map[string]interface{})
+}
@@ -2,16 +2,35 @@ package types
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/TykTechnologies/storage/persistent/model"
 	"github.com/TykTechnologies/storage/persistent/utils"
 )
 
+// HealthChecker reports the health of the database in more detail than Ping.
+type HealthChecker interface {
+	// Health checks the database, reporting its latency, the state of its replica set and the usage of
+	// the connection pool. A database that can't be reached is reported as not live, with the error of the check.
+	Health(ctx context.Context) model.HealthStatus
+}
+
 type PersistentStorage interface {
+	HealthChecker
 	// Insert a DbObject into the database
 	Insert(context.Context, ...model.DBObject) error
-	// Delete a DbObject from the database
+	// BulkInsert inserts the rows in batches of opts.BatchSize rows, returning the number of inserted rows.
+	// The errors of the failed rows are aggregated in the returned error along with their index in rows.
+	BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error)
+	// Delete a DbObject from the database. If it's a model.SoftDeletable, it's marked as deleted instead.
 	Delete(context.Context, model.DBObject, ...model.DBM) error
+	// Purge permanently removes the model.SoftDeletable rows of the row collection deleted before olderThan ago,
+	// returning how many were removed.
+	Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error)
+	// DeleteWithResult deletes the rows matching filter and returns how many were deleted. Unlike Delete,
+	// deleting nothing isn't an error. If filter is empty, the row model.DBObject is deleted by its ID.
+	DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error)
 	// Update a DbObject in the database
 	Update(context.Context, model.DBObject, ...model.DBM) error
 	// Count counts all rows for a DBTable if no filter model.DBM given.
@@ -19,8 +38,43 @@ type PersistentStorage interface {
 	// If multiple filters model.DBM are specified, it will return an error.
 	// In case of an error, the count result is going to be 0.
 	Count(ctx context.Context, row model.DBObject, filter ...model.DBM) (count int, error error)
-	// Query one or multiple DBObjects from the database
+	// CountWithOpts is the same as Count, configured by opts. Set CountOpts.Estimated to get a fast estimation
+	// of the number of rows of the table when no filter is given.
+	CountWithOpts(ctx context.Context, row model.DBObject, opts model.CountOpts, filter ...model.DBM) (int, error)
+	// Query one or multiple DBObjects from the database.
+	// The result can also be a *model.DBM or a *[]model.DBM, to read rows without defining their type. Their nested
+	// documents are decoded as model.DBM, their arrays as []interface{}, and their IDs as model.ObjectID or model.UUID.
+	// The "_max_time" key of the query (time.Duration) bounds the execution time of the operation on the server.
+	// It can also be given in milliseconds with the "_max_time_ms" key (int).
+	// The "_fields" key ([]string) restricts the returned fields to the given ones, along with _id.
+	// The "_read_pref" key (e.g. "secondaryPreferred") overrides the read preference of the connection for the query.
+	// The "_lenient_decode" key (*model.DecodeWarnings) enables the lenient decode mode: fields that don't fit the
+	// result type are left with their zero value and reported in the given model.DecodeWarnings instead of failing.
+	// The "_lock" key (e.g. "no_key_update") is accepted for row locking backends.
+	// mongo has no equivalent and ignores it.
 	Query(context.Context, model.DBObject, interface{}, model.DBM) error
+	// SearchText finds the rows of the row model.DBObject collection matching filter whose text index contains text,
+	// sorted by relevance. The collection must have a text index, created with CreateIndex and a key of value "text",
+	// e.g. model.DBM{"name": "text"}. The _limit, _offset, _max_time, _fields and _read_pref keys are supported
+	// as in Query. If result is a slice all the matched rows are returned, otherwise only the most relevant one.
+	SearchText(ctx context.Context, row model.DBObject, result interface{}, text string, filter model.DBM) error
+	// Watch sends through the returned channel the changes of the rows of the row model.DBObject collection
+	// matching filter, until ctx is done. The channel is closed then, or after sending an event with Err set.
+	// The filter fields refer to the changed rows. Only filters on _id match deletes, as the deleted row
+	// isn't available anymore. Set the "_resume_after" key to the ResumeToken of an event to watch the changes
+	// made after it. It requires a replica set and isn't supported by the mgo driver.
+	Watch(ctx context.Context, row model.DBObject, filter model.DBM) (<-chan model.ChangeEvent, error)
+	// QueryCursor returns a model.Cursor over the rows matching the query model.DBM, so they can be streamed
+	// instead of loaded at once. The _sort, _limit, _offset, _max_time and _fields keys are supported as in Query.
+	// The cursor must be closed by the caller.
+	QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error)
+	// ListPage returns a page of the rows of the row model.DBObject table matching filter, sorted by page.Sort.
+	// The pages are found by their keys instead of skipping the previous rows, so large tables are listed
+	// efficiently and the rows inserted meanwhile don't shift the pages. The next page is requested by setting
+	// the NextCursor of the result as the AfterCursor of the request. Cursors are specific to each driver.
+	ListPage(ctx context.Context, row model.DBObject, filter model.DBM, page model.PageRequest) (model.PageResult, error)
+	// Distinct returns the distinct values of field among the rows of the row model.DBObject table matching filter.
+	Distinct(ctx context.Context, row model.DBObject, field string, filter model.DBM) ([]interface{}, error)
 	// BulkUpdate updates multiple rows
 	BulkUpdate(context.Context, []model.DBObject, ...model.DBM) error
 	// UpdateAll executes the update query model.DBM over
@@ -48,19 +102,64 @@ type PersistentStorage interface {
 	DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error)
 	// Aggregate performs an aggregation query on the row model.DBObject collection
 	// query is the aggregation pipeline to be executed
-	// it returns the aggregation result and an error if any
+	// it returns the aggregation result and an error if any.
+	// Stages containing only meta keys, such as model.DBM{"_max_time": time.Second}, are applied as options of the
+	// aggregation instead of being sent as part of the pipeline.
 	Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error)
+	// Explain returns the plan chosen by the database to find the rows of the row model.DBObject collection
+	// matching filter, along with the statistics of its execution. The queryPlanner and executionStats sections
+	// are always at the top level of the result.
+	Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error)
+	// ExplainAggregate is the same as Explain for an aggregation pipeline, as given to Aggregate.
+	ExplainAggregate(ctx context.Context, row model.DBObject, query []model.DBM) (model.DBM, error)
 	// CleanIndexes removes all the indexes from the row model.DBObject collection
 	CleanIndexes(ctx context.Context, row model.DBObject) error
 	// Upsert performs an upsert operation on the row model.DBObject collection
 	// query is the filter to be used to find the document to update
 	// update is the update to be applied to the document
 	// row is modified with the result of the operation
+	// Update operators such as $setOnInsert are honored, so defaults can be seeded only when the document is created.
 	Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error
+	// FindOneAndUpdate atomically applies the update to the first row of the row model.DBObject collection matching
+	// query, and sets row with the row as it was before the update, or after it if FindOneOpts.ReturnNew is set.
+	// It's useful for counters or to claim a row so no one else processes it.
+	// When a row is inserted by FindOneOpts.Upsert without ReturnNew, row is left untouched as there was no previous row.
+	FindOneAndUpdate(ctx context.Context, row model.DBObject, query, update model.DBM, opts ...model.FindOneOpts) error
 	// GetDatabaseInfo returns information of the database to which the driver is connecting to
 	GetDatabaseInfo(ctx context.Context) (utils.Info, error)
 	// GetTables return the list of collections for a given database
 	GetTables(ctx context.Context) ([]string, error)
 	// DropTable drops a table/collection from the database. Returns the number of affected rows and error
 	DropTable(ctx context.Context, name string) (int, error)
+	// ExportNDJSON streams the rows of the row model.DBObject table matching the query model.DBM
+	// to w as newline-delimited JSON, one row per line. Returns the number of exported rows.
+	ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error)
+	// ImportNDJSON reads the newline-delimited JSON rows of r and inserts them in batches into the row model.DBObject
+	// table, returning the number of imported rows. Malformed or rejected rows don't stop the import: their errors
+	// are aggregated in the returned error, along with the line they come from.
+	// The "upsert" option replaces the rows whose _id already exists, and "batchSize" sets the number of rows
+	// inserted per batch (1000 by default).
+	ImportNDJSON(ctx context.Context, row model.DBObject, r io.Reader, opts ...model.DBM) (int, error)
+	// Export is the same as ExportNDJSON, writing the rows in the given model.ExportFormat.
+	// The "_fields" key of the query sets the columns of the CSV format, which are the fields of the first row
+	// otherwise.
+	Export(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer, format model.ExportFormat) (int, error)
+	// Import is the same as ImportNDJSON, reading the rows in the given model.ExportFormat. The CSV cells are decoded
+	// as JSON when possible, so the numbers, booleans and nested documents keep their type.
+	Import(ctx context.Context,
+		row model.DBObject,
+		r io.Reader,
+		format model.ExportFormat,
+		opts ...model.DBM,
+	) (int, error)
+	// SessionSettings returns the effective settings of the current session, for debugging purposes:
+	// database, readPreference, readConcern and writeConcern.
+	SessionSettings(ctx context.Context) (model.DBM, error)
+	// ExistingIDs returns the subset of ids that exist in the row model.DBObject table, keeping the order of ids.
+	// The ids of the soft deleted rows are returned as well, as they are still taken.
+	ExistingIDs(ctx context.Context, row model.DBObject, ids []model.ObjectID) ([]model.ObjectID, error)
+	// WithTransaction runs fn in a transaction, committing it if fn returns nil and aborting it otherwise.
+	// The operations must be done through the tx PersistentStorage given to fn to be part of the transaction.
+	// fn can be retried on transient errors, so it shouldn't have side effects out of the transaction.
+	WithTransaction(ctx context.Context, fn func(tx PersistentStorage) error) error
 }
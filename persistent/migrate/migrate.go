@@ -0,0 +1,233 @@
+// Package migrate copies the tables of a persistent storage into another one, e.g. to move the data
+// from the mgo driver to the official mongo one, or between clusters:
+//
+//	err := migrate.Copy(ctx, src, dst, []string{"tyk_apis", "tyk_policies"}, migrate.CopyOpts{})
+//
+// The rows are streamed from src and inserted in batches into dst, so the tables don't need to fit in memory.
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+const (
+	defaultBatchSize = 1000
+	// idIndexName is the name of the index of _id, which every table has.
+	idIndexName = "_id_"
+)
+
+// Progress reports how many rows of a table have been copied.
+type Progress struct {
+	Table string
+	// Copied is the number of rows copied so far.
+	Copied int
+	// Total is the number of rows of the table in the source storage when the copy started.
+	Total int
+}
+
+// CopyOpts configures a Copy.
+type CopyOpts struct {
+	// BatchSize is the number of rows inserted at once. Defaults to 1000.
+	BatchSize int
+	// DropExisting drops the tables from the destination storage before copying them.
+	// Otherwise, the rows are added to the existing ones, failing on duplicated _id.
+	DropExisting bool
+	// SkipIndexes doesn't create the indexes of the source tables in the destination storage.
+	SkipIndexes bool
+	// OnProgress is called after each inserted batch.
+	OnProgress func(progress Progress)
+}
+
+// Copy copies the rows and the indexes of the tables from src to dst, one table after another. The ObjectIDs
+// are translated between the drivers, at any depth of the rows. It stops at the first error, reporting the table
+// it happened in. The tables are copied as they are while the copy runs, so the rows written meanwhile
+// may or may not be copied.
+func Copy(ctx context.Context, src, dst types.PersistentStorage, tables []string, opts CopyOpts) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	for _, table := range tables {
+		if err := copyTable(ctx, src, dst, table, opts); err != nil {
+			return errors.New("error copying table " + table + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func copyTable(ctx context.Context, src, dst types.PersistentStorage, table string, opts CopyOpts) error {
+	row := &document{table: table}
+
+	if opts.DropExisting {
+		if err := dst.Drop(ctx, row); err != nil {
+			return err
+		}
+	}
+
+	if !opts.SkipIndexes {
+		if err := copyIndexes(ctx, src, dst, row); err != nil {
+			return err
+		}
+	}
+
+	total, err := src.Count(ctx, row)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := src.QueryCursor(ctx, row, model.DBM{"_sort": "_id"})
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close()
+
+	progress := Progress{Table: table, Total: total}
+	batch := make([]model.DBObject, 0, opts.BatchSize)
+
+	insert := func() error {
+		n, err := dst.BulkInsert(ctx, batch, model.BulkOpts{BatchSize: opts.BatchSize})
+		progress.Copied += n
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+
+		batch = batch[:0]
+
+		return err
+	}
+
+	for cursor.Next() {
+		var fields model.DBM
+		if err := cursor.Decode(&fields); err != nil {
+			return err
+		}
+
+		batch = append(batch, &document{table: table, fields: translate(fields).(model.DBM)})
+
+		if len(batch) == opts.BatchSize {
+			if err := insert(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return insert()
+	}
+
+	return nil
+}
+
+// copyIndexes creates in dst the indexes of the row table of src, but the _id one.
+func copyIndexes(ctx context.Context, src, dst types.PersistentStorage, row model.DBObject) error {
+	hasTable, err := src.HasTable(ctx, row.TableName())
+	if err != nil || !hasTable {
+		return err
+	}
+
+	indexes, err := src.GetIndexes(ctx, row)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		if index.Name == idIndexName {
+			continue
+		}
+
+		if err := dst.CreateIndex(ctx, row, index); err != nil {
+			return errors.New("error creating index " + index.Name + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// translate returns value with the ObjectIDs of both drivers replaced with model.ObjectID, and the nested
+// documents with model.DBM, so it can be inserted through any driver.
+func translate(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return model.ObjectIDHex(v.Hex())
+	case bson.ObjectId:
+		return model.ObjectIDHex(v.Hex())
+	case model.DBM:
+		return translateDocument(v)
+	case map[string]interface{}:
+		return translateDocument(v)
+	case primitive.M:
+		return translateDocument(v)
+	case bson.M:
+		return translateDocument(v)
+	case primitive.D:
+		document := make(model.DBM, len(v))
+		for _, e := range v {
+			document[e.Key] = translate(e.Value)
+		}
+
+		return document
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = translate(item)
+		}
+
+		return values
+	case primitive.A:
+		return translate([]interface{}(v))
+	default:
+		return value
+	}
+}
+
+func translateDocument(document map[string]interface{}) model.DBM {
+	translated := make(model.DBM, len(document))
+	for key, value := range document {
+		translated[key] = translate(value)
+	}
+
+	return translated
+}
+
+// document is a row of any table, copied with all its fields as they are stored.
+type document struct {
+	table  string
+	fields model.DBM
+}
+
+func (d *document) GetObjectID() model.ObjectID {
+	id, _ := d.fields["_id"].(model.ObjectID)
+	return id
+}
+
+// SetObjectID sets the _id of the document unless it has one, as the drivers set a new one
+// to the rows whose _id isn't an ObjectID.
+func (d *document) SetObjectID(id model.ObjectID) {
+	if _, ok := d.fields["_id"]; !ok {
+		d.fields["_id"] = id
+	}
+}
+
+func (d *document) TableName() string {
+	return d.table
+}
+
+// GetBSON encodes the document as its fields, for both the mgo driver and the mgo compatible encoding
+// of the official mongo driver.
+func (d *document) GetBSON() (interface{}, error) {
+	return d.fields, nil
+}
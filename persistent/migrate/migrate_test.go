@@ -0,0 +1,202 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// memoryCursor iterates over rows.
+type memoryCursor struct {
+	rows []model.DBM
+	next int
+}
+
+func (c *memoryCursor) Next() bool {
+	c.next++
+	return c.next <= len(c.rows)
+}
+
+func (c *memoryCursor) Decode(result interface{}) error {
+	fields, ok := result.(*model.DBM)
+	if !ok {
+		return errors.New("unexpected result")
+	}
+
+	*fields = c.rows[c.next-1]
+
+	return nil
+}
+
+func (c *memoryCursor) Err() error {
+	return nil
+}
+
+func (c *memoryCursor) Close() error {
+	return nil
+}
+
+// memoryStorage keeps the tables in memory. Calling any method other than the ones used by Copy panics.
+type memoryStorage struct {
+	types.PersistentStorage
+	tables    map[string][]model.DBM
+	indexes   map[string][]model.Index
+	insertErr error
+	inserts   int
+}
+
+func (s *memoryStorage) HasTable(ctx context.Context, table string) (bool, error) {
+	_, ok := s.tables[table]
+	return ok, nil
+}
+
+func (s *memoryStorage) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	return s.indexes[row.TableName()], nil
+}
+
+func (s *memoryStorage) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	s.indexes[row.TableName()] = append(s.indexes[row.TableName()], index)
+	return nil
+}
+
+func (s *memoryStorage) Drop(ctx context.Context, row model.DBObject) error {
+	delete(s.tables, row.TableName())
+	delete(s.indexes, row.TableName())
+
+	return nil
+}
+
+func (s *memoryStorage) Count(ctx context.Context, row model.DBObject, filter ...model.DBM) (int, error) {
+	return len(s.tables[row.TableName()]), nil
+}
+
+func (s *memoryStorage) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	return &memoryCursor{rows: s.tables[row.TableName()]}, nil
+}
+
+func (s *memoryStorage) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	s.inserts++
+
+	if s.insertErr != nil {
+		return 0, s.insertErr
+	}
+
+	for _, row := range rows {
+		d, ok := row.(*document)
+		if !ok {
+			return 0, errors.New("unexpected row")
+		}
+
+		s.tables[d.table] = append(s.tables[d.table], d.fields)
+	}
+
+	return len(rows), nil
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{tables: map[string][]model.DBM{}, indexes: map[string][]model.Index{}}
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	id := model.NewObjectID()
+	mongoID, _ := primitive.ObjectIDFromHex(id.Hex())
+
+	src := newMemoryStorage()
+	src.tables["apis"] = []model.DBM{
+		{"_id": mongoID, "name": "a"},
+		{"_id": bson.ObjectIdHex(model.NewObjectID().Hex()), "name": "b"},
+		{"_id": model.NewObjectID(), "name": "c"},
+	}
+	src.tables["policies"] = []model.DBM{{"_id": model.NewObjectID()}}
+	src.indexes["apis"] = []model.Index{
+		{Name: idIndexName, Keys: []model.DBM{{"_id": 1}}},
+		{Name: "name_1", Keys: []model.DBM{{"name": 1}}},
+	}
+
+	t.Run("copies tables and indexes", func(t *testing.T) {
+		dst := newMemoryStorage()
+
+		var progress []Progress
+
+		err := Copy(ctx, src, dst, []string{"apis", "policies"}, CopyOpts{
+			BatchSize:  2,
+			OnProgress: func(p Progress) { progress = append(progress, p) },
+		})
+		assert.Nil(t, err)
+
+		assert.Len(t, dst.tables["apis"], 3)
+		assert.Len(t, dst.tables["policies"], 1)
+		assert.Equal(t, id, dst.tables["apis"][0]["_id"])
+		assert.IsType(t, model.ObjectID(""), dst.tables["apis"][1]["_id"])
+		assert.Equal(t, []model.Index{{Name: "name_1", Keys: []model.DBM{{"name": 1}}}}, dst.indexes["apis"])
+		assert.Equal(t, []Progress{
+			{Table: "apis", Copied: 2, Total: 3},
+			{Table: "apis", Copied: 3, Total: 3},
+			{Table: "policies", Copied: 1, Total: 1},
+		}, progress)
+	})
+
+	t.Run("skips indexes and drops existing tables", func(t *testing.T) {
+		dst := newMemoryStorage()
+		dst.tables["apis"] = []model.DBM{{"_id": model.NewObjectID()}}
+
+		err := Copy(ctx, src, dst, []string{"apis"}, CopyOpts{SkipIndexes: true, DropExisting: true})
+		assert.Nil(t, err)
+
+		assert.Len(t, dst.tables["apis"], 3)
+		assert.Empty(t, dst.indexes["apis"])
+		assert.Equal(t, 1, dst.inserts)
+	})
+
+	t.Run("reports the failing table", func(t *testing.T) {
+		dst := newMemoryStorage()
+		dst.insertErr = errors.New("duplicated key")
+
+		err := Copy(ctx, src, dst, []string{"apis"}, CopyOpts{})
+		assert.EqualError(t, err, "error copying table apis: duplicated key")
+	})
+}
+
+func TestDocumentSetObjectID(t *testing.T) {
+	d := &document{fields: model.DBM{"_id": "custom"}}
+	d.SetObjectID(model.NewObjectID())
+	assert.Equal(t, "custom", d.fields["_id"])
+
+	id := model.NewObjectID()
+	d = &document{fields: model.DBM{}}
+	d.SetObjectID(id)
+	assert.Equal(t, id, d.GetObjectID())
+}
+
+func TestTranslate(t *testing.T) {
+	id := model.NewObjectID()
+	mongoID, _ := primitive.ObjectIDFromHex(id.Hex())
+
+	translated := translate(model.DBM{
+		"mongo": mongoID,
+		"mgo":   bson.ObjectId(id),
+		"nested": primitive.M{
+			"ids": primitive.A{mongoID, "value"},
+		},
+		"doc":  primitive.D{{Key: "id", Value: bson.ObjectId(id)}},
+		"list": []interface{}{bson.M{"id": bson.ObjectId(id)}},
+		"n":    1,
+	})
+
+	assert.Equal(t, model.DBM{
+		"mongo":  id,
+		"mgo":    id,
+		"nested": model.DBM{"ids": []interface{}{id, "value"}},
+		"doc":    model.DBM{"id": id},
+		"list":   []interface{}{model.DBM{"id": id}},
+		"n":      1,
+	}, translated)
+}
@@ -0,0 +1,191 @@
+// Package migration keeps track of the migrations applied to a persistent storage, so each of them
+// runs only once and in order, e.g.
+//
+//	migrator := migration.New(storage)
+//	err := migrator.Apply(ctx, []migration.Migration{{ID: "add_org_index", Up: addOrgIndex, Down: dropOrgIndex}})
+//
+// The IDs of the applied migrations are recorded in the tyk_migrations table.
+package migration
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+const (
+	ErrorEmptyID        = "migration id cannot be empty"
+	ErrorDuplicatedID   = "duplicated migration id"
+	ErrorMissingUp      = "migration has no up function"
+	ErrorMissingDown    = "migration has no down function"
+	ErrorNothingApplied = "no applied migration to roll back"
+)
+
+// TableName is the name of the table where the applied migrations are recorded.
+const TableName = "tyk_migrations"
+
+// Migration is a change of the storage identified by ID, which must be unique and never change once applied.
+// Up applies the change and Down reverts it. Both receive the storage they run against, whose
+// GetDatabaseInfo can be used to run different code depending on the database.
+type Migration struct {
+	ID   string
+	Up   func(ctx context.Context, storage types.PersistentStorage) error
+	Down func(ctx context.Context, storage types.PersistentStorage) error
+}
+
+// record is a migration applied to the storage.
+type record struct {
+	ID          model.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	MigrationID string         `bson:"migration_id" json:"migration_id"`
+	AppliedAt   time.Time      `bson:"applied_at" json:"applied_at"`
+}
+
+func (r *record) GetObjectID() model.ObjectID {
+	return r.ID
+}
+
+func (r *record) SetObjectID(id model.ObjectID) {
+	r.ID = id
+}
+
+func (r *record) TableName() string {
+	return TableName
+}
+
+// Migrator applies and rolls back migrations over a storage.
+type Migrator struct {
+	storage types.PersistentStorage
+}
+
+// New returns a Migrator of the given storage.
+func New(storage types.PersistentStorage) *Migrator {
+	return &Migrator{storage: storage}
+}
+
+// Applied returns the IDs of the applied migrations, in the order they were applied.
+func (m *Migrator) Applied(ctx context.Context) ([]string, error) {
+	records, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.MigrationID)
+	}
+
+	return ids, nil
+}
+
+// Apply runs in order the Up function of the migrations that weren't applied yet, recording each of them
+// once it succeeds. It stops at the first failed migration, so the next Apply retries it.
+func (m *Migrator) Apply(ctx context.Context, migrations []Migration) error {
+	if err := validate(migrations); err != nil {
+		return err
+	}
+
+	records, err := m.records(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, r := range records {
+		applied[r.MigrationID] = true
+	}
+
+	for _, migration := range migrations {
+		if applied[migration.ID] {
+			continue
+		}
+
+		if migration.Up == nil {
+			return errors.New(ErrorMissingUp + ": " + migration.ID)
+		}
+
+		if err := migration.Up(ctx, m.storage); err != nil {
+			return errors.New("error applying migration " + migration.ID + ": " + err.Error())
+		}
+
+		r := &record{ID: model.NewObjectID(), MigrationID: migration.ID, AppliedAt: time.Now()}
+		if err := m.storage.Insert(ctx, r); err != nil {
+			return errors.New("error recording migration " + migration.ID + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Rollback runs the Down function of the last applied migration among the given ones, and removes its record.
+// It returns the ID of the rolled back migration.
+func (m *Migrator) Rollback(ctx context.Context, migrations []Migration) (string, error) {
+	if err := validate(migrations); err != nil {
+		return "", err
+	}
+
+	records, err := m.records(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	byID := make(map[string]Migration, len(migrations))
+	for _, migration := range migrations {
+		byID[migration.ID] = migration
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		migration, ok := byID[records[i].MigrationID]
+		if !ok {
+			continue
+		}
+
+		if migration.Down == nil {
+			return "", errors.New(ErrorMissingDown + ": " + migration.ID)
+		}
+
+		if err := migration.Down(ctx, m.storage); err != nil {
+			return "", errors.New("error rolling back migration " + migration.ID + ": " + err.Error())
+		}
+
+		if err := m.storage.Delete(ctx, records[i]); err != nil {
+			return "", errors.New("error removing record of migration " + migration.ID + ": " + err.Error())
+		}
+
+		return migration.ID, nil
+	}
+
+	return "", errors.New(ErrorNothingApplied)
+}
+
+// records returns the applied migrations, sorted by their ID so the first applied comes first.
+func (m *Migrator) records(ctx context.Context) ([]*record, error) {
+	var records []*record
+
+	err := m.storage.Query(ctx, &record{}, &records, model.DBM{"_sort": "_id"})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func validate(migrations []Migration) error {
+	ids := make(map[string]bool, len(migrations))
+
+	for _, migration := range migrations {
+		if migration.ID == "" {
+			return errors.New(ErrorEmptyID)
+		}
+
+		if ids[migration.ID] {
+			return errors.New(ErrorDuplicatedID + ": " + migration.ID)
+		}
+
+		ids[migration.ID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStorage keeps the migration records in memory. Calling any method other than the ones
+// used by the Migrator panics.
+type memoryStorage struct {
+	types.PersistentStorage
+	records []*record
+}
+
+func (s *memoryStorage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	for _, row := range rows {
+		r, ok := row.(*record)
+		if !ok {
+			return errors.New("unexpected row")
+		}
+
+		s.records = append(s.records, r)
+	}
+
+	return nil
+}
+
+func (s *memoryStorage) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	records, ok := result.(*[]*record)
+	if !ok {
+		return errors.New("unexpected result")
+	}
+
+	*records = append(*records, s.records...)
+
+	return nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, row model.DBObject, query ...model.DBM) error {
+	for i, r := range s.records {
+		if r.ID == row.GetObjectID() {
+			s.records = append(s.records[:i], s.records[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("not found")
+}
+
+// step returns a migration appending its id to steps when applied, and removing it when rolled back.
+func step(id string, steps *[]string) Migration {
+	return Migration{
+		ID: id,
+		Up: func(ctx context.Context, storage types.PersistentStorage) error {
+			*steps = append(*steps, id)
+			return nil
+		},
+		Down: func(ctx context.Context, storage types.PersistentStorage) error {
+			*steps = (*steps)[:len(*steps)-1]
+			return nil
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("applies the migrations once and in order", func(t *testing.T) {
+		var steps []string
+
+		migrator := New(&memoryStorage{})
+
+		err := migrator.Apply(ctx, []Migration{step("first", &steps), step("second", &steps)})
+		assert.Nil(t, err)
+
+		err = migrator.Apply(ctx, []Migration{step("first", &steps), step("second", &steps), step("third", &steps)})
+		assert.Nil(t, err)
+
+		assert.Equal(t, []string{"first", "second", "third"}, steps)
+
+		applied, err := migrator.Applied(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"first", "second", "third"}, applied)
+	})
+
+	t.Run("stops at the first failed migration", func(t *testing.T) {
+		var steps []string
+
+		failing := Migration{
+			ID: "failing",
+			Up: func(ctx context.Context, storage types.PersistentStorage) error {
+				return errors.New("test")
+			},
+		}
+
+		migrator := New(&memoryStorage{})
+
+		err := migrator.Apply(ctx, []Migration{step("first", &steps), failing, step("second", &steps)})
+		assert.Equal(t, errors.New("error applying migration failing: test"), err)
+		assert.Equal(t, []string{"first"}, steps)
+
+		applied, err := migrator.Applied(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"first"}, applied)
+	})
+
+	tcs := []struct {
+		testName      string
+		migrations    []Migration
+		expectedError error
+	}{
+		{
+			testName:      "empty id",
+			migrations:    []Migration{{Up: func(context.Context, types.PersistentStorage) error { return nil }}},
+			expectedError: errors.New(ErrorEmptyID),
+		},
+		{
+			testName:      "duplicated id",
+			migrations:    []Migration{step("first", &[]string{}), step("first", &[]string{})},
+			expectedError: errors.New(ErrorDuplicatedID + ": first"),
+		},
+		{
+			testName:      "missing up function",
+			migrations:    []Migration{{ID: "first"}},
+			expectedError: errors.New(ErrorMissingUp + ": first"),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := New(&memoryStorage{}).Apply(ctx, tc.migrations)
+			assert.Equal(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+
+	var steps []string
+
+	migrations := []Migration{step("first", &steps), step("second", &steps)}
+	migrator := New(&memoryStorage{})
+
+	_, err := migrator.Rollback(ctx, migrations)
+	assert.Equal(t, errors.New(ErrorNothingApplied), err)
+
+	err = migrator.Apply(ctx, migrations)
+	assert.Nil(t, err)
+
+	id, err := migrator.Rollback(ctx, migrations)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", id)
+	assert.Equal(t, []string{"first"}, steps)
+
+	applied, err := migrator.Applied(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first"}, applied)
+
+	_, err = migrator.Rollback(ctx, []Migration{{ID: "first"}})
+	assert.Equal(t, errors.New(ErrorMissingDown+": first"), err)
+
+	id, err = migrator.Rollback(ctx, migrations)
+	assert.Nil(t, err)
+	assert.Equal(t, "first", id)
+	assert.Empty(t, steps)
+}
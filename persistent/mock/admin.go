@@ -0,0 +1,426 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+// idIndex is the index of the _id of every table, which can't be dropped.
+const idIndex = "_id_"
+
+func (s *Storage) CreateIndex(ctx context.Context, row model.DBObject, index model.Index) error {
+	if len(index.Keys) == 0 {
+		return types.ErrIndexEmpty
+	} else if len(index.Keys) > 1 && index.IsTTLIndex {
+		return types.ErrIndexComposedTTL
+	}
+
+	keys := make([]model.DBM, 0, len(index.Keys))
+
+	var names []string
+
+	for _, key := range index.Keys {
+		fields := make([]string, 0, len(key))
+		for field := range key {
+			fields = append(fields, field)
+		}
+
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			value := key[field]
+
+			switch n, isNumber := toFloat(value); {
+			case index.GeoIndex:
+				value = "2dsphere"
+			case isNumber && n < 0:
+				value = int32(-1)
+			case isNumber:
+				value = int32(1)
+			}
+
+			keys = append(keys, model.DBM{field: value})
+			names = append(names, field+"_"+fmt.Sprint(value))
+		}
+	}
+
+	newIndex := model.Index{Name: index.Name, Keys: keys, Background: index.Background}
+	if newIndex.Name == "" {
+		newIndex.Name = strings.Join(names, "_")
+	}
+
+	if index.IsTTLIndex {
+		newIndex.IsTTLIndex = true
+		newIndex.TTL = index.TTL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(helper.TableName(ctx, row))
+
+	for _, existing := range t.indexes {
+		sameKeys := reflect.DeepEqual(existing.Keys, newIndex.Keys)
+
+		switch {
+		case sameKeys && existing.Name == newIndex.Name:
+			return nil
+		case sameKeys:
+			return types.ErrIndexAlreadyExist
+		case existing.Name == newIndex.Name:
+			return errors.New("index " + newIndex.Name + " already exists with different keys")
+		}
+	}
+
+	t.indexes = append(t.indexes, newIndex)
+
+	return nil
+}
+
+func (s *Storage) GetIndexes(ctx context.Context, row model.DBObject) ([]model.Index, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tables[helper.TableName(ctx, row)]
+	if !ok {
+		return nil, types.ErrCollectionNotFound
+	}
+
+	indexes := []model.Index{{Name: idIndex, Keys: []model.DBM{{"_id": int32(1)}}}}
+
+	for _, index := range t.indexes {
+		keys := make([]model.DBM, len(index.Keys))
+		for i, key := range index.Keys {
+			keys[i] = copyDocument(key)
+		}
+
+		index.Keys = keys
+		indexes = append(indexes, index)
+	}
+
+	return indexes, nil
+}
+
+func (s *Storage) CleanIndexes(ctx context.Context, row model.DBObject) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.tables[helper.TableName(ctx, row)]; ok {
+		t.indexes = nil
+	}
+
+	return nil
+}
+
+func (s *Storage) Drop(ctx context.Context, row model.DBObject) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tables, helper.TableName(ctx, row))
+
+	return nil
+}
+
+func (s *Storage) DropTable(ctx context.Context, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := len(s.rows(name))
+	delete(s.tables, name)
+
+	return removed, nil
+}
+
+func (s *Storage) DropDatabase(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tables = map[string]*table{}
+
+	return nil
+}
+
+func (s *Storage) HasTable(ctx context.Context, name string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.tables[name]
+
+	return ok, nil
+}
+
+func (s *Storage) GetTables(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.tables))
+	for name := range s.tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Migrate creates the tables of rows. The options, such as capped tables, are ignored.
+func (s *Storage) Migrate(ctx context.Context, rows []model.DBObject, opts ...model.DBM) error {
+	if len(opts) > 0 && len(opts) != len(rows) {
+		return types.ErrRowOptDiffLength
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, row := range rows {
+		s.table(helper.TableName(ctx, row))
+	}
+
+	return nil
+}
+
+func (s *Storage) DBTableStats(ctx context.Context, row model.DBObject) (model.DBM, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	size := 0
+
+	for _, doc := range s.rows(helper.TableName(ctx, row)) {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		size += len(data)
+	}
+
+	nIndexes := 1
+	if t, ok := s.tables[helper.TableName(ctx, row)]; ok {
+		nIndexes += len(t.indexes)
+	}
+
+	return model.DBM{
+		"ns":       helper.TableName(ctx, row),
+		"count":    len(s.rows(helper.TableName(ctx, row))),
+		"size":     size,
+		"nindexes": nIndexes,
+		"capped":   false,
+	}, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Storage) Health(ctx context.Context) model.HealthStatus {
+	return model.HealthStatus{Live: true, Ready: true, PrimaryAvailable: true, CheckedAt: time.Now()}
+}
+
+func (s *Storage) GetDatabaseInfo(ctx context.Context) (utils.Info, error) {
+	capabilities := utils.StandardMongo.Capabilities()
+	capabilities.ChangeStreams = false
+
+	return utils.Info{Type: utils.StandardMongo, Capabilities: capabilities}, nil
+}
+
+func (s *Storage) SessionSettings(ctx context.Context) (model.DBM, error) {
+	return model.DBM{
+		"database":       "",
+		"readPreference": "primary",
+		"readConcern":    "",
+		"writeConcern":   model.DBM{"w": 1},
+	}, nil
+}
+
+func (s *Storage) ExistingIDs(ctx context.Context,
+	row model.DBObject,
+	ids []model.ObjectID,
+) ([]model.ObjectID, error) {
+	existing := make([]model.ObjectID, 0)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tables[helper.TableName(ctx, row)]
+	if !ok {
+		return existing, nil
+	}
+
+	reported := map[model.ObjectID]bool{}
+
+	// the soft deleted rows aren't skipped, as their ids are still taken
+	for _, id := range ids {
+		// only report duplicated ids once
+		if !reported[id] && t.indexOf(id) >= 0 {
+			existing = append(existing, id)
+			reported[id] = true
+		}
+	}
+
+	return existing, nil
+}
+
+func (s *Storage) ExportNDJSON(ctx context.Context, row model.DBObject, query model.DBM, w io.Writer) (int, error) {
+	return s.Export(ctx, row, query, w, model.NDJSON)
+}
+
+func (s *Storage) Export(ctx context.Context,
+	row model.DBObject,
+	query model.DBM,
+	w io.Writer,
+	format model.ExportFormat,
+) (int, error) {
+	fields, _ := query["_fields"].([]string)
+
+	encoder, err := helper.NewExportEncoder(w, format, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	docs, err := s.find(helper.TableName(ctx, row), query)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, doc := range docs {
+		if err := helper.DecodeFields(row.TableName(), &doc); err != nil {
+			return i, err
+		}
+
+		if err := encoder.Encode(doc); err != nil {
+			return i, err
+		}
+	}
+
+	return len(docs), encoder.Flush()
+}
+
+func (s *Storage) ImportNDJSON(ctx context.Context, row model.DBObject, r io.Reader, opts ...model.DBM) (int, error) {
+	return s.Import(ctx, row, r, model.NDJSON, opts...)
+}
+
+func (s *Storage) Import(ctx context.Context,
+	row model.DBObject,
+	r io.Reader,
+	format model.ExportFormat,
+	opts ...model.DBM,
+) (int, error) {
+	importRows, err := helper.NewImporter(format)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(opts) > 1 {
+		return 0, types.ErrMultipleDBM
+	}
+
+	importOpts := model.DBM{}
+	if len(opts) == 1 {
+		importOpts = opts[0]
+	}
+
+	upsert, batchSize := helper.ImportOptions(importOpts)
+
+	return importRows(r, batchSize, func(documents []model.DBM) (int, map[int]error) {
+		failed := map[int]error{}
+		imported := 0
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		t := s.table(helper.TableName(ctx, row))
+
+		for i, document := range documents {
+			doc, err := importedDocument(row, document)
+			if err != nil {
+				failed[i] = err
+				continue
+			}
+
+			position := t.indexOf(doc["_id"])
+
+			switch {
+			case position < 0:
+				t.rows = append(t.rows, doc)
+			case upsert:
+				t.rows[position] = doc
+			default:
+				failed[i] = duplicateKeyError(helper.TableName(ctx, row), doc["_id"])
+				continue
+			}
+
+			imported++
+		}
+
+		return imported, failed
+	})
+}
+
+// importedDocument returns document as it's stored once decoded into a model.DBObject of the same type as row,
+// following its bson tags.
+func importedDocument(row model.DBObject, document model.DBM) (model.DBM, error) {
+	newRow, err := helper.NewDBObject(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decode(document, newRow); err != nil {
+		return nil, err
+	}
+
+	if newRow.GetObjectID() == "" {
+		newRow.SetObjectID(model.NewObjectID())
+	}
+
+	// the row is only stored, so its encoded fields aren't restored
+	if _, err := helper.EncodeFields(newRow); err != nil {
+		return nil, err
+	}
+
+	docs, err := documents(newRow)
+	if err != nil {
+		return nil, err
+	}
+
+	return docs[0], nil
+}
+
+// WithTransaction runs fn with the storage itself, restoring the tables as they were before fn if it fails.
+// The operations run meanwhile out of fn are rolled back as well.
+func (s *Storage) WithTransaction(ctx context.Context, fn func(tx types.PersistentStorage) error) error {
+	s.mu.RLock()
+	snapshot := make(map[string]*table, len(s.tables))
+
+	for name, t := range s.tables {
+		rows := make([]model.DBM, len(t.rows))
+		for i, doc := range t.rows {
+			rows[i] = copyDocument(doc)
+		}
+
+		snapshot[name] = &table{rows: rows, indexes: append([]model.Index(nil), t.indexes...)}
+	}
+	s.mu.RUnlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.tables = snapshot
+		s.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,722 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// aggregate runs the stages of pipeline over docs, which aren't modified. The $unionWith stages read
+// the rows of the other tables through rows.
+func aggregate(docs []model.DBM, pipeline []model.DBM, rows func(table string) []model.DBM) ([]model.DBM, error) {
+	result := make([]model.DBM, len(docs))
+	for i, doc := range docs {
+		result[i] = copyDocument(doc)
+	}
+
+	for _, stage := range pipeline {
+		if len(stage) != 1 {
+			return nil, errors.New("an aggregation stage must have a single operator")
+		}
+
+		for operator, spec := range stage {
+			var err error
+			if operator == "$unionWith" {
+				result, err = unionWithStage(result, spec, rows)
+			} else {
+				result, err = runStage(result, operator, spec)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// unionWithStage appends to docs the rows of the table of spec, either its name or a document
+// with its name in "coll" and an optional "pipeline" run over its rows.
+func unionWithStage(docs []model.DBM, spec interface{}, rows func(table string) []model.DBM) ([]model.DBM, error) {
+	var (
+		table    string
+		pipeline []model.DBM
+	)
+
+	switch spec := spec.(type) {
+	case string:
+		table = spec
+	case model.DBM:
+		table, _ = spec["coll"].(string)
+		pipeline, _ = spec["pipeline"].([]model.DBM)
+	}
+
+	if table == "" {
+		return nil, errors.New("$unionWith expects a table name")
+	}
+
+	union, err := aggregate(rows(table), pipeline, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(docs, union...), nil
+}
+
+func runStage(docs []model.DBM, operator string, spec interface{}) ([]model.DBM, error) {
+	// the sort keys are read before encoding the stage, which would lose their order
+	if operator == "$sort" {
+		keys, err := stageSortKeys(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		sortDocuments(docs, keys)
+
+		return docs, nil
+	}
+
+	spec, err := encodeValue(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case "$match":
+		return matchStage(docs, spec)
+	case "$limit", "$skip":
+		n, ok := toFloat(spec)
+		if !ok || n < 0 {
+			return nil, errors.New(operator + " expects a positive number")
+		}
+
+		if int(n) > len(docs) {
+			n = float64(len(docs))
+		}
+
+		if operator == "$limit" {
+			return docs[:int(n)], nil
+		}
+
+		return docs[int(n):], nil
+	case "$project":
+		return projectStage(docs, spec)
+	case "$addFields", "$set":
+		return addFieldsStage(docs, spec)
+	case "$unset":
+		return unsetStage(docs, spec)
+	case "$group":
+		return groupStage(docs, spec)
+	case "$count":
+		field, ok := spec.(string)
+		if !ok || field == "" {
+			return nil, errors.New("$count expects a field name")
+		}
+
+		if len(docs) == 0 {
+			return docs, nil
+		}
+
+		return []model.DBM{{field: len(docs)}}, nil
+	case "$unwind":
+		return unwindStage(docs, spec)
+	default:
+		return nil, errors.New(ErrorUnsupportedStage + ": " + operator)
+	}
+}
+
+// stageSortKeys returns the keys of a $sort stage. The keys of an unordered document are sorted by name.
+func stageSortKeys(spec interface{}) ([]sortKey, error) {
+	var fields []string
+
+	order := func(field string, value interface{}) error {
+		n, ok := toFloat(value)
+		if !ok || (n != 1 && n != -1) {
+			return errors.New("$sort expects 1 or -1 for " + field)
+		}
+
+		if n < 0 {
+			field = "-" + field
+		}
+
+		fields = append(fields, field)
+
+		return nil
+	}
+
+	switch s := spec.(type) {
+	case bson.D:
+		for _, e := range s {
+			if err := order(e.Name, e.Value); err != nil {
+				return nil, err
+			}
+		}
+	case primitive.D:
+		for _, e := range s {
+			if err := order(e.Key, e.Value); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		doc, ok := normalize(spec).(model.DBM)
+		if !ok {
+			return nil, errors.New("$sort expects a document")
+		}
+
+		names := make([]string, 0, len(doc))
+		for name := range doc {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			if err := order(name, doc[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return parseSort(fields...), nil
+}
+
+func matchStage(docs []model.DBM, spec interface{}) ([]model.DBM, error) {
+	filter, ok := spec.(model.DBM)
+	if !ok {
+		return nil, errors.New("$match expects a document")
+	}
+
+	matched := make([]model.DBM, 0, len(docs))
+
+	for _, doc := range docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+
+	return matched, nil
+}
+
+func projectStage(docs []model.DBM, spec interface{}) ([]model.DBM, error) {
+	projection, ok := spec.(model.DBM)
+	if !ok {
+		return nil, errors.New("$project expects a document")
+	}
+
+	// an exclusion projection only has 0 or false values, _id aside
+	exclusion := true
+
+	for field, value := range projection {
+		if field != "_id" && !isExclusion(value) {
+			exclusion = false
+		}
+	}
+
+	projected := make([]model.DBM, 0, len(docs))
+
+	for _, doc := range docs {
+		if exclusion {
+			for field := range projection {
+				unsetPath(doc, field)
+			}
+
+			projected = append(projected, doc)
+
+			continue
+		}
+
+		result := model.DBM{}
+		if id, ok := doc["_id"]; ok && !isExclusion(projection["_id"]) {
+			result["_id"] = id
+		}
+
+		for field, value := range projection {
+			if isExclusion(value) {
+				continue
+			}
+
+			if isInclusion(value) {
+				if v, found := lookup(doc, field); found {
+					if err := setPath(result, field, v); err != nil {
+						return nil, err
+					}
+				}
+
+				continue
+			}
+
+			v, err := evaluate(doc, value)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := setPath(result, field, v); err != nil {
+				return nil, err
+			}
+		}
+
+		projected = append(projected, result)
+	}
+
+	return projected, nil
+}
+
+func isExclusion(value interface{}) bool {
+	if b, ok := value.(bool); ok {
+		return !b
+	}
+
+	n, ok := toFloat(value)
+
+	return ok && n == 0
+}
+
+func isInclusion(value interface{}) bool {
+	if b, ok := value.(bool); ok {
+		return b
+	}
+
+	n, ok := toFloat(value)
+
+	return ok && n != 0
+}
+
+func addFieldsStage(docs []model.DBM, spec interface{}) ([]model.DBM, error) {
+	fields, ok := spec.(model.DBM)
+	if !ok {
+		return nil, errors.New("$addFields expects a document")
+	}
+
+	for _, doc := range docs {
+		values := model.DBM{}
+
+		// the expressions refer to the fields of the input document
+		for field, expression := range fields {
+			value, err := evaluate(doc, expression)
+			if err != nil {
+				return nil, err
+			}
+
+			values[field] = value
+		}
+
+		for field, value := range values {
+			if err := setPath(doc, field, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return docs, nil
+}
+
+func unsetStage(docs []model.DBM, spec interface{}) ([]model.DBM, error) {
+	var fields []interface{}
+
+	switch s := spec.(type) {
+	case string:
+		fields = []interface{}{s}
+	case []interface{}:
+		fields = s
+	default:
+		return nil, errors.New("$unset expects a field name or a list of them")
+	}
+
+	for _, doc := range docs {
+		for _, field := range fields {
+			name, ok := field.(string)
+			if !ok {
+				return nil, errors.New("$unset expects a field name or a list of them")
+			}
+
+			unsetPath(doc, name)
+		}
+	}
+
+	return docs, nil
+}
+
+func unwindStage(docs []model.DBM, spec interface{}) ([]model.DBM, error) {
+	path, _ := spec.(string)
+	preserve := false
+
+	if options, ok := spec.(model.DBM); ok {
+		path, _ = options["path"].(string)
+		preserve = truthy(options["preserveNullAndEmptyArrays"])
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New("$unwind expects a field path prefixed with $")
+	}
+
+	path = path[1:]
+
+	unwound := make([]model.DBM, 0, len(docs))
+
+	for _, doc := range docs {
+		value, found := lookup(doc, path)
+
+		array, isArray := value.([]interface{})
+
+		switch {
+		case isArray && len(array) > 0:
+			for _, elem := range array {
+				unwoundDoc := copyDocument(doc)
+				if err := setPath(unwoundDoc, path, elem); err != nil {
+					return nil, err
+				}
+
+				unwound = append(unwound, unwoundDoc)
+			}
+		case found && value != nil && !isArray:
+			unwound = append(unwound, doc)
+		case preserve:
+			if isArray {
+				unsetPath(doc, path)
+			}
+
+			unwound = append(unwound, doc)
+		}
+	}
+
+	return unwound, nil
+}
+
+type group struct {
+	id     interface{}
+	values map[string]*accumulator
+}
+
+func groupStage(docs []model.DBM, spec interface{}) ([]model.DBM, error) {
+	fields, ok := spec.(model.DBM)
+	if !ok {
+		return nil, errors.New("$group expects a document")
+	}
+
+	idExpression, ok := fields["_id"]
+	if !ok {
+		return nil, errors.New("$group requires an _id")
+	}
+
+	// the groups are returned in the order of their first row
+	var groups []*group
+
+	for _, doc := range docs {
+		id, err := evaluate(doc, idExpression)
+		if err != nil {
+			return nil, err
+		}
+
+		var current *group
+
+		for _, g := range groups {
+			if equal(g.id, id) {
+				current = g
+				break
+			}
+		}
+
+		if current == nil {
+			current = &group{id: id, values: map[string]*accumulator{}}
+			groups = append(groups, current)
+		}
+
+		for field, expression := range fields {
+			if field == "_id" {
+				continue
+			}
+
+			if err := accumulate(current, doc, field, expression); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := make([]model.DBM, 0, len(groups))
+
+	for _, g := range groups {
+		doc := model.DBM{"_id": g.id}
+		for field, acc := range g.values {
+			doc[field] = acc.result()
+		}
+
+		result = append(result, doc)
+	}
+
+	return result, nil
+}
+
+// accumulator computes the value of a field of a $group stage.
+type accumulator struct {
+	operator string
+	values   []interface{}
+}
+
+func accumulate(g *group, doc model.DBM, field string, expression interface{}) error {
+	spec, ok := expression.(model.DBM)
+	if !ok || len(spec) != 1 {
+		return errors.New("$group expects an accumulator for " + field)
+	}
+
+	for operator, argument := range spec {
+		switch operator {
+		case "$sum", "$avg", "$min", "$max", "$first", "$last", "$push", "$addToSet", "$count":
+		default:
+			return errors.New(ErrorUnsupportedOperator + ": " + operator)
+		}
+
+		acc, ok := g.values[field]
+		if !ok {
+			acc = &accumulator{operator: operator}
+			g.values[field] = acc
+		}
+
+		if operator == "$count" {
+			acc.values = append(acc.values, 1)
+			continue
+		}
+
+		value, err := evaluate(doc, argument)
+		if err != nil {
+			return err
+		}
+
+		acc.values = append(acc.values, value)
+	}
+
+	return nil
+}
+
+func (a *accumulator) result() interface{} {
+	switch a.operator {
+	case "$sum", "$count":
+		var sum interface{} = 0
+		for _, value := range a.values {
+			if _, ok := toFloat(value); ok {
+				sum = add(sum, value)
+			}
+		}
+
+		return sum
+	case "$avg":
+		sum, n := 0.0, 0
+		for _, value := range a.values {
+			if f, ok := toFloat(value); ok {
+				sum += f
+				n++
+			}
+		}
+
+		if n == 0 {
+			return nil
+		}
+
+		return sum / float64(n)
+	case "$min", "$max":
+		var result interface{}
+		for _, value := range a.values {
+			if value == nil {
+				continue
+			}
+
+			c := compareValues(value, result)
+			if result == nil || a.operator == "$min" && c < 0 || a.operator == "$max" && c > 0 {
+				result = value
+			}
+		}
+
+		return result
+	case "$first":
+		return a.values[0]
+	case "$last":
+		return a.values[len(a.values)-1]
+	case "$push":
+		return a.values
+	default:
+		set := make([]interface{}, 0, len(a.values))
+		for _, value := range a.values {
+			if !anyCandidate(set, func(v interface{}) bool { return equal(v, value) }) {
+				set = append(set, value)
+			}
+		}
+
+		return set
+	}
+}
+
+// evaluate returns the value of an aggregation expression for doc: a field path such as "$name",
+// a document of expressions, an operator or a literal value.
+func evaluate(doc model.DBM, expression interface{}) (interface{}, error) {
+	switch e := expression.(type) {
+	case string:
+		if strings.HasPrefix(e, "$") {
+			value, _ := lookup(doc, e[1:])
+			return value, nil
+		}
+
+		return e, nil
+	case []interface{}:
+		values := make([]interface{}, len(e))
+
+		for i, item := range e {
+			value, err := evaluate(doc, item)
+			if err != nil {
+				return nil, err
+			}
+
+			values[i] = value
+		}
+
+		return values, nil
+	case model.DBM:
+		if len(e) == 1 && isOperatorDocument(e) {
+			for operator, argument := range e {
+				return evaluateOperator(doc, operator, argument)
+			}
+		}
+
+		values := model.DBM{}
+
+		for field, item := range e {
+			value, err := evaluate(doc, item)
+			if err != nil {
+				return nil, err
+			}
+
+			values[field] = value
+		}
+
+		return values, nil
+	default:
+		return expression, nil
+	}
+}
+
+func evaluateOperator(doc model.DBM, operator string, argument interface{}) (interface{}, error) {
+	if operator == "$literal" {
+		return argument, nil
+	}
+
+	value, err := evaluate(doc, argument)
+	if err != nil {
+		return nil, err
+	}
+
+	args, isList := value.([]interface{})
+	if !isList {
+		args = []interface{}{value}
+	}
+
+	switch operator {
+	case "$toLower", "$toUpper":
+		s, _ := args[0].(string)
+		if operator == "$toLower" {
+			return strings.ToLower(s), nil
+		}
+
+		return strings.ToUpper(s), nil
+	case "$concat":
+		var sb strings.Builder
+
+		for _, arg := range args {
+			s, ok := arg.(string)
+			if !ok {
+				return nil, nil
+			}
+
+			sb.WriteString(s)
+		}
+
+		return sb.String(), nil
+	case "$add", "$multiply":
+		return arithmetic(operator, args)
+	case "$subtract", "$divide":
+		if len(args) != 2 {
+			return nil, errors.New(operator + " expects two arguments")
+		}
+
+		return arithmetic(operator, args)
+	case "$size":
+		array, ok := args[0].([]interface{})
+		if !ok || !isList && value == nil {
+			return nil, errors.New("$size expects an array")
+		}
+
+		return len(array), nil
+	case "$ifNull":
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+
+		return nil, nil
+	default:
+		return nil, errors.New(ErrorUnsupportedOperator + ": " + operator)
+	}
+}
+
+// arithmetic applies operator to the numbers of args, returning nil if any of them is missing.
+func arithmetic(operator string, args []interface{}) (interface{}, error) {
+	var result interface{}
+
+	for i, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+
+		if _, ok := toFloat(arg); !ok {
+			return nil, fmt.Errorf("%s only supports numbers, got %T", operator, arg)
+		}
+
+		if i == 0 {
+			result = arg
+			continue
+		}
+
+		x, _ := toFloat(result)
+		y, _ := toFloat(arg)
+
+		switch operator {
+		case "$add":
+			result = add(result, arg)
+		case "$subtract":
+			result = add(result, negate(arg))
+		case "$multiply":
+			if isInteger(result) && isInteger(arg) {
+				result = int(x) * int(y)
+			} else {
+				result = x * y
+			}
+		case "$divide":
+			if y == 0 {
+				return nil, errors.New("can't $divide by zero")
+			}
+
+			result = x / y
+		}
+	}
+
+	return result, nil
+}
+
+func negate(value interface{}) interface{} {
+	f, _ := toFloat(value)
+	if isInteger(value) {
+		return -int(f)
+	}
+
+	return -f
+}
@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	tests := []struct {
+		name     string
+		pipeline []model.DBM
+		expected []model.DBM
+	}{
+		{
+			name: "group",
+			pipeline: []model.DBM{
+				{"$group": model.DBM{
+					"_id":   "$country.continent",
+					"total": model.DBM{"$sum": 1},
+					"age":   model.DBM{"$avg": "$age"},
+					"names": model.DBM{"$push": "$name"},
+				}},
+				{"$sort": bson.D{{Name: "total", Value: -1}, {Name: "_id", Value: 1}}},
+				{"_max_time": time.Second},
+			},
+			expected: []model.DBM{
+				{"_id": "Europe", "total": 2, "age": 32.5, "names": []interface{}{"alice", "Carol"}},
+				{"_id": "America", "total": 1, "age": 25.0, "names": []interface{}{"dave"}},
+				{"_id": "Asia", "total": 1, "age": 25.0, "names": []interface{}{"bob"}},
+			},
+		},
+		{
+			name: "match, project and limit",
+			pipeline: []model.DBM{
+				{"$match": model.DBM{"age": model.DBM{"$gte": 30}}},
+				{"$sort": model.DBM{"age": 1}},
+				{"$project": model.DBM{"_id": 0, "name": model.DBM{"$toUpper": "$name"}, "next": model.DBM{
+					"$add": []interface{}{"$age", 1},
+				}}},
+				{"$limit": 1},
+			},
+			expected: []model.DBM{{"name": "ALICE", "next": 31}},
+		},
+		{
+			name: "unwind and count",
+			pipeline: []model.DBM{
+				{"$unwind": "$tags"},
+				{"$match": model.DBM{"tags": "dev"}},
+				{"$count": "devs"},
+			},
+			expected: []model.DBM{{"devs": 2}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := storage.Aggregate(ctx, &dummyDBObject{}, test.pipeline)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	_, err := storage.Aggregate(ctx, &dummyDBObject{}, []model.DBM{{"$lookup": model.DBM{}}})
+	assert.EqualError(t, err, ErrorUnsupportedStage+": $lookup")
+}
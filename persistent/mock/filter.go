@@ -0,0 +1,688 @@
+package mock
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// metaKeys are the keys of the queries configuring the operations instead of filtering the rows.
+var metaKeys = map[string]bool{
+	"_sort": true, "_collection": true, "_limit": true, "_offset": true, "_date_sharding": true,
+	"_max_time": true, "_max_time_ms": true, "_lock": true, "_lenient_decode": true, "_read_pref": true,
+	"_fields": true, "_resume_after": true, "_with_deleted": true,
+}
+
+// buildFilter returns the filter of query as the drivers build it: without its meta keys, with the slices
+// matched with $in and the $i and $text operators translated into regular expressions. The values are encoded
+// as they are stored.
+func buildFilter(query model.DBM) (model.DBM, error) {
+	filter := model.DBM{}
+
+	for key, value := range query {
+		if metaKeys[key] {
+			continue
+		}
+
+		translated, err := translateCondition(key, value)
+		if err != nil {
+			return nil, err
+		}
+
+		filter[key] = translated
+	}
+
+	return encodeDocument(filter)
+}
+
+func translateCondition(key string, value interface{}) (interface{}, error) {
+	switch key {
+	case "$or", "$and", "$nor":
+		filters, err := filterList(value)
+		if err != nil {
+			return nil, err
+		}
+
+		translated := make([]interface{}, 0, len(filters))
+
+		for _, filter := range filters {
+			f, err := buildFilter(filter)
+			if err != nil {
+				return nil, err
+			}
+
+			translated = append(translated, f)
+		}
+
+		return translated, nil
+	}
+
+	if nested, ok := value.(model.DBM); ok {
+		condition := model.DBM{}
+
+		for operator, operand := range nested {
+			switch operator {
+			case "$i":
+				if s, ok := operand.(string); ok {
+					condition["$regex"] = "^" + regexp.QuoteMeta(s) + "$"
+					condition["$options"] = "i"
+				}
+			case "$text":
+				if s, ok := operand.(string); ok {
+					condition["$regex"] = regexp.QuoteMeta(s)
+					condition["$options"] = "i"
+				}
+			case "$not":
+				negated, err := translateCondition(key, operand)
+				if err != nil {
+					return nil, err
+				}
+
+				condition[operator] = negated
+			default:
+				condition[operator] = operand
+			}
+		}
+
+		return condition, nil
+	}
+
+	if value != nil && reflect.ValueOf(value).Kind() == reflect.Slice {
+		if ids, ok := value.([]string); ok && key == "_id" {
+			return model.DBM{"$in": helper.ParseIDs(ids)}, nil
+		}
+
+		return model.DBM{"$in": value}, nil
+	}
+
+	return value, nil
+}
+
+// filterList returns the filters of a $or, $and or $nor operator.
+func filterList(value interface{}) ([]model.DBM, error) {
+	switch v := value.(type) {
+	case []model.DBM:
+		return v, nil
+	case []interface{}:
+		filters := make([]model.DBM, 0, len(v))
+
+		for _, item := range v {
+			filter, ok := normalize(item).(model.DBM)
+			if !ok {
+				return nil, errors.New("logical operators expect a list of filters")
+			}
+
+			filters = append(filters, filter)
+		}
+
+		return filters, nil
+	default:
+		return nil, errors.New("logical operators expect a list of filters")
+	}
+}
+
+// matches reports whether doc matches filter, which must be built by buildFilter.
+func matches(doc, filter model.DBM) (bool, error) {
+	for key, condition := range filter {
+		ok, err := matchKey(doc, key, condition)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchKey(doc model.DBM, key string, condition interface{}) (bool, error) {
+	switch key {
+	case "$and", "$or", "$nor":
+		filters, err := filterList(condition)
+		if err != nil {
+			return false, err
+		}
+
+		for _, filter := range filters {
+			ok, err := matches(doc, filter)
+			if err != nil {
+				return false, err
+			}
+
+			switch {
+			case key == "$and" && !ok:
+				return false, nil
+			case key == "$or" && ok:
+				return true, nil
+			case key == "$nor" && ok:
+				return false, nil
+			}
+		}
+
+		return key != "$or", nil
+	}
+
+	if strings.HasPrefix(key, "$") {
+		return false, errors.New(ErrorUnsupportedOperator + ": " + key)
+	}
+
+	value, found := lookup(doc, key)
+
+	return matchCondition(value, found, condition)
+}
+
+// matchCondition reports whether the value of a field, which may be missing, matches the condition on it:
+// a document of operators, a regular expression or a value the field must be equal to.
+func matchCondition(value interface{}, found bool, condition interface{}) (bool, error) {
+	if operators, ok := condition.(model.DBM); ok && isOperatorDocument(operators) {
+		for operator, operand := range operators {
+			ok, err := matchOperator(value, found, operator, operand, operators)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	if re, ok, err := toRegexp(condition, ""); ok && !isString(condition) {
+		if err != nil {
+			return false, err
+		}
+
+		return anyCandidate(value, func(v interface{}) bool { return matchRegexp(re, v) }), nil
+	}
+
+	if condition == nil {
+		return !found || value == nil, nil
+	}
+
+	return anyCandidate(value, func(v interface{}) bool { return equal(v, condition) }), nil
+}
+
+func matchOperator(value interface{},
+	found bool,
+	operator string,
+	operand interface{},
+	operators model.DBM,
+) (bool, error) {
+	switch operator {
+	case "$eq":
+		return matchCondition(value, found, operand)
+	case "$ne":
+		ok, err := matchCondition(value, found, operand)
+		return !ok, err
+	case "$gt", "$gte", "$lt", "$lte":
+		return anyCandidate(value, func(v interface{}) bool {
+			c, ok := compare(v, operand)
+			if !ok {
+				return false
+			}
+
+			switch operator {
+			case "$gt":
+				return c > 0
+			case "$gte":
+				return c >= 0
+			case "$lt":
+				return c < 0
+			default:
+				return c <= 0
+			}
+		}), nil
+	case "$in", "$nin":
+		in, err := matchIn(value, found, operand)
+		return in == (operator == "$in"), err
+	case "$exists":
+		return found == truthy(operand), nil
+	case "$regex":
+		options, _ := operators["$options"].(string)
+
+		re, ok, err := toRegexp(operand, options)
+		if !ok || err != nil {
+			return false, errors.New("invalid $regex")
+		}
+
+		return anyCandidate(value, func(v interface{}) bool { return matchRegexp(re, v) }), nil
+	case "$options":
+		// applied along with $regex
+		return true, nil
+	case "$not":
+		ok, err := matchCondition(value, found, operand)
+		return !ok, err
+	case "$size":
+		array, ok := value.([]interface{})
+		size, isNumber := toFloat(operand)
+
+		return ok && isNumber && float64(len(array)) == size, nil
+	case "$all":
+		return matchAll(value, operand)
+	case "$elemMatch":
+		return matchElem(value, operand)
+	default:
+		return false, errors.New(ErrorUnsupportedOperator + ": " + operator)
+	}
+}
+
+func matchIn(value interface{}, found bool, operand interface{}) (bool, error) {
+	list, ok := operand.([]interface{})
+	if !ok {
+		return false, errors.New("$in and $nin expect a list")
+	}
+
+	for _, item := range list {
+		ok, err := matchCondition(value, found, item)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+func matchAll(value interface{}, operand interface{}) (bool, error) {
+	list, ok := operand.([]interface{})
+	if !ok {
+		return false, errors.New("$all expects a list")
+	}
+
+	for _, item := range list {
+		if !anyCandidate(value, func(v interface{}) bool { return equal(v, item) }) {
+			return false, nil
+		}
+	}
+
+	return len(list) > 0, nil
+}
+
+func matchElem(value interface{}, operand interface{}) (bool, error) {
+	array, ok := value.([]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	condition, ok := operand.(model.DBM)
+	if !ok {
+		return false, errors.New("$elemMatch expects a document")
+	}
+
+	for _, elem := range array {
+		var (
+			ok  bool
+			err error
+		)
+
+		if doc, isDoc := elem.(model.DBM); isDoc && !isOperatorDocument(condition) {
+			ok, err = matches(doc, condition)
+		} else {
+			ok, err = matchCondition(elem, true, condition)
+		}
+
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// isOperatorDocument reports whether all the keys of doc are operators.
+func isOperatorDocument(doc model.DBM) bool {
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+
+	return len(doc) > 0
+}
+
+// anyCandidate reports whether value, or any of its elements if it's an array, satisfies fn.
+func anyCandidate(value interface{}, fn func(v interface{}) bool) bool {
+	if fn(value) {
+		return true
+	}
+
+	if array, ok := value.([]interface{}); ok {
+		for _, elem := range array {
+			if fn(elem) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func toRegexp(value interface{}, options string) (*regexp.Regexp, bool, error) {
+	var pattern string
+
+	switch v := value.(type) {
+	case bson.RegEx:
+		pattern, options = v.Pattern, v.Options
+	case *bson.RegEx:
+		pattern, options = v.Pattern, v.Options
+	case string:
+		pattern = v
+	default:
+		return nil, false, nil
+	}
+
+	flags := ""
+
+	for _, option := range options {
+		if strings.ContainsRune("ims", option) {
+			flags += string(option)
+		}
+	}
+
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+
+	return re, true, err
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func matchRegexp(re *regexp.Regexp, value interface{}) bool {
+	s, ok := value.(string)
+	return ok && re.MatchString(s)
+}
+
+// lookup returns the value of the field at path, given in dot notation, and whether it was found.
+// The path traverses the arrays of documents, returning the values of their elements.
+func lookup(doc model.DBM, path string) (interface{}, bool) {
+	key, rest, nested := strings.Cut(path, ".")
+
+	value, found := doc[key]
+	if !found || !nested {
+		return value, found
+	}
+
+	switch v := value.(type) {
+	case model.DBM:
+		return lookup(v, rest)
+	case []interface{}:
+		index, after, hasMore := strings.Cut(rest, ".")
+		if i, err := strconv.Atoi(index); err == nil {
+			if i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			if !hasMore {
+				return v[i], true
+			}
+
+			sub, ok := v[i].(model.DBM)
+			if !ok {
+				return nil, false
+			}
+
+			return lookup(sub, after)
+		}
+
+		var values []interface{}
+
+		for _, elem := range v {
+			if sub, ok := elem.(model.DBM); ok {
+				if value, found := lookup(sub, rest); found {
+					values = append(values, value)
+				}
+			}
+		}
+
+		return values, len(values) > 0
+	default:
+		return nil, false
+	}
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case nil:
+		return false
+	default:
+		if f, ok := toFloat(v); ok {
+			return f != 0
+		}
+
+		return true
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	if t, ok := a.(time.Time); ok {
+		u, ok := b.(time.Time)
+		return ok && t.Equal(u)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares a and b if they are of the same kind: numbers, strings, ObjectIDs, times or booleans.
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	case model.ObjectID:
+		y, ok := b.(model.ObjectID)
+		return strings.Compare(string(x), string(y)), ok
+	case model.UUID:
+		y, ok := b.(model.UUID)
+		return bytes.Compare(x[:], y[:]), ok
+	case time.Time:
+		y, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		default:
+			return 0, true
+		}
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case x == y:
+			return 0, true
+		case y:
+			return -1, true
+		default:
+			return 1, true
+		}
+	default:
+		return 0, false
+	}
+}
+
+// typeOrder returns the position of the type of value in the sort order of mongo.
+func typeOrder(value interface{}) int {
+	if _, ok := toFloat(value); ok {
+		return 1
+	}
+
+	switch value.(type) {
+	case nil:
+		return 0
+	case string:
+		return 2
+	case model.DBM:
+		return 3
+	case []interface{}:
+		return 4
+	case model.UUID:
+		return 5
+	case model.ObjectID:
+		return 6
+	case bool:
+		return 7
+	case time.Time:
+		return 8
+	default:
+		return 9
+	}
+}
+
+// compareValues compares a and b in the sort order of mongo, where the values of different types are ordered
+// by their type.
+func compareValues(a, b interface{}) int {
+	if c, ok := compare(a, b); ok {
+		return c
+	}
+
+	return typeOrder(a) - typeOrder(b)
+}
+
+type sortKey struct {
+	field      string
+	descending bool
+}
+
+// parseSort returns the sort keys of fields, prefixed with "-" for the descending order. The
+// "$textScore:field" fields sort the rows by the score of the text search, projected into field.
+func parseSort(fields ...string) []sortKey {
+	keys := make([]sortKey, 0, len(fields))
+
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+
+		if kind, name, found := strings.Cut(field, ":"); found && kind == "$textScore" {
+			keys = append(keys, sortKey{field: name, descending: true})
+			continue
+		}
+
+		switch field[0] {
+		case '+':
+			keys = append(keys, sortKey{field: field[1:]})
+		case '-':
+			keys = append(keys, sortKey{field: field[1:], descending: true})
+		default:
+			keys = append(keys, sortKey{field: field})
+		}
+	}
+
+	return keys
+}
+
+// sortDocuments sorts docs by keys, keeping the order of the rows with the same values.
+func sortDocuments(docs []model.DBM, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			a, _ := lookup(docs[i], key.field)
+			b, _ := lookup(docs[j], key.field)
+
+			c := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+
+			if key.descending {
+				return c > 0
+			}
+
+			return c < 0
+		}
+
+		return false
+	})
+}
+
+// project returns doc with only its _id and the given fields.
+func project(doc model.DBM, fields []string) model.DBM {
+	projected := model.DBM{}
+
+	if id, ok := doc["_id"]; ok {
+		projected["_id"] = id
+	}
+
+	for _, field := range fields {
+		if value, ok := lookup(doc, field); ok {
+			_ = setPath(projected, field, value)
+		}
+	}
+
+	return projected
+}
@@ -0,0 +1,603 @@
+// Package mock is an in-memory persistent storage, so the code using a storage can be unit tested without
+// a database, e.g.
+//
+//	storage := mock.New()
+//	err := storage.Insert(ctx, &user)
+//
+// It follows the semantics of the mongo drivers: the rows are stored as they are encoded by their bson tags,
+// the filters support the comparison, logical, element and array operators along with the $i and $text ones,
+// and the updates support the field and array operators. Aggregate supports the $match, $sort, $limit, $skip,
+// $project, $addFields, $set, $unset, $group, $count, $unwind and $unionWith stages. The operators it doesn't
+// support fail with ErrorUnsupportedOperator or ErrorUnsupportedStage.
+//
+// The transactions are rolled back on error, but aren't isolated from the operations run meanwhile.
+// Watch isn't supported, and the dry-run mode isn't either: the operations are always applied.
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+const (
+	// ErrorUnsupportedOperator is returned when a query or an update uses an operator the mock doesn't support.
+	ErrorUnsupportedOperator = "operator not supported by the mock storage"
+	// ErrorUnsupportedStage is returned when an aggregation pipeline has a stage the mock doesn't support.
+	ErrorUnsupportedStage = "aggregation stage not supported by the mock storage"
+)
+
+// duplicateKeyCode is the code of the errors of the writes violating the unique _id.
+const duplicateKeyCode = 11000
+
+var _ types.PersistentStorage = &Storage{}
+
+// Storage is an in-memory types.PersistentStorage. It's safe for concurrent use.
+type Storage struct {
+	mu     sync.RWMutex
+	tables map[string]*table
+}
+
+type table struct {
+	rows    []model.DBM
+	indexes []model.Index
+}
+
+// New returns an empty Storage.
+func New() *Storage {
+	return &Storage{tables: map[string]*table{}}
+}
+
+// table returns the table called name, creating it if it doesn't exist. The caller must hold the write lock.
+func (s *Storage) table(name string) *table {
+	t, ok := s.tables[name]
+	if !ok {
+		t = &table{}
+		s.tables[name] = t
+	}
+
+	return t
+}
+
+// rows returns the rows of the table called name, which aren't copied. The caller must hold the lock.
+func (s *Storage) rows(name string) []model.DBM {
+	if t, ok := s.tables[name]; ok {
+		return t.rows
+	}
+
+	return nil
+}
+
+// indexOf returns the position of the row of t whose _id is id, or -1 if there's none.
+func (t *table) indexOf(id interface{}) int {
+	for i, row := range t.rows {
+		if equal(row["_id"], id) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func duplicateKeyError(table string, id interface{}) error {
+	return &mgo.LastError{
+		Code: duplicateKeyCode,
+		Err:  "E11000 duplicate key error collection: " + table + " index: _id_ dup key: " + describeID(id),
+	}
+}
+
+func describeID(id interface{}) string {
+	if objectID, ok := id.(model.ObjectID); ok {
+		return "ObjectId('" + objectID.Hex() + "')"
+	}
+
+	return fmt.Sprint(id)
+}
+
+func (s *Storage) Insert(ctx context.Context, rows ...model.DBObject) error {
+	helper.SetInsertTimestamps(rows...)
+
+	if len(rows) == 0 {
+		return types.ErrEmptyRow
+	}
+
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	for _, row := range rows {
+		if row.GetObjectID() == "" {
+			row.SetObjectID(model.NewObjectID())
+		}
+	}
+
+	docs, err := documents(rows...)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := helper.TableName(ctx, rows[0])
+	t := s.table(name)
+
+	// the rows are inserted at once, so none is inserted if any of them is a duplicate
+	ids := make([]interface{}, 0, len(docs))
+
+	for _, doc := range docs {
+		if t.indexOf(doc["_id"]) >= 0 || anyCandidate(ids, func(id interface{}) bool { return equal(id, doc["_id"]) }) {
+			return duplicateKeyError(name, doc["_id"])
+		}
+
+		ids = append(ids, doc["_id"])
+	}
+
+	t.rows = append(t.rows, docs...)
+
+	return nil
+}
+
+func (s *Storage) BulkInsert(ctx context.Context, rows []model.DBObject, opts model.BulkOpts) (int, error) {
+	helper.SetInsertTimestamps(rows...)
+
+	if len(rows) == 0 {
+		return 0, types.ErrEmptyRow
+	}
+
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return 0, err
+	}
+
+	defer restore()
+
+	return helper.BulkInsert(rows, opts, func(batch []model.DBObject) (int, map[int]error) {
+		failed := map[int]error{}
+		inserted := 0
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		name := helper.TableName(ctx, batch[0])
+		t := s.table(name)
+
+		for i, row := range batch {
+			if row.GetObjectID() == "" {
+				row.SetObjectID(model.NewObjectID())
+			}
+
+			docs, err := documents(row)
+			if err == nil && t.indexOf(docs[0]["_id"]) >= 0 {
+				err = duplicateKeyError(name, docs[0]["_id"])
+			}
+
+			if err != nil {
+				failed[i] = err
+
+				if !opts.ContinueOnError {
+					// an ordered insert stops at the failed row
+					break
+				}
+
+				continue
+			}
+
+			t.rows = append(t.rows, docs[0])
+			inserted++
+		}
+
+		return inserted, failed
+	})
+}
+
+func (s *Storage) Delete(ctx context.Context, row model.DBObject, queries ...model.DBM) error {
+	if len(queries) > 1 {
+		return types.ErrMultipleQueryForSingleRow
+	}
+
+	if len(queries) == 0 {
+		queries = append(queries, model.DBM{"_id": row.GetObjectID()})
+	}
+
+	deleted, err := s.delete(ctx, row, queries[0])
+	if err == nil && deleted == 0 {
+		return mgo.ErrNotFound
+	}
+
+	return err
+}
+
+func (s *Storage) DeleteWithResult(ctx context.Context, row model.DBObject, filter model.DBM) (int64, error) {
+	if len(filter) == 0 {
+		filter = model.DBM{"_id": row.GetObjectID()}
+	}
+
+	deleted, err := s.delete(ctx, row, filter)
+
+	return int64(deleted), err
+}
+
+// delete removes the rows of the row table matching query, or marks them as deleted if row is
+// a model.SoftDeletable, returning how many were deleted.
+func (s *Storage) delete(ctx context.Context, row model.DBObject, query model.DBM) (int, error) {
+	if _, ok := row.(model.SoftDeletable); ok {
+		return s.updateAll(helper.TableName(ctx, row), helper.SoftDeleteFilter(row, query),
+			model.DBM{"$set": model.DBM{model.DeletedAtField: time.Now()}})
+	}
+
+	return s.remove(helper.TableName(ctx, row), query)
+}
+
+// remove removes the rows of table matching query, returning how many were removed.
+func (s *Storage) remove(table string, query model.DBM) (int, error) {
+	filter, err := buildFilter(query)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tables[table]
+	if !ok {
+		return 0, nil
+	}
+
+	kept := make([]model.DBM, 0, len(t.rows))
+
+	for _, doc := range t.rows {
+		matched, err := matches(doc, filter)
+		if err != nil {
+			return 0, err
+		}
+
+		if !matched {
+			kept = append(kept, doc)
+		}
+	}
+
+	removed := len(t.rows) - len(kept)
+	t.rows = kept
+
+	return removed, nil
+}
+
+func (s *Storage) Purge(ctx context.Context, row model.DBObject, olderThan time.Duration) (int, error) {
+	if _, ok := row.(model.SoftDeletable); !ok {
+		return 0, types.ErrNotSoftDeletable
+	}
+
+	return s.remove(helper.TableName(ctx, row), model.DBM{
+		model.DeletedAtField: model.DBM{"$gt": time.Time{}, "$lte": time.Now().Add(-olderThan)},
+	})
+}
+
+func (s *Storage) Update(ctx context.Context, row model.DBObject, queries ...model.DBM) error {
+	helper.SetUpdateTimestamps(row)
+
+	if len(queries) > 1 {
+		return types.ErrMultipleQueryForSingleRow
+	}
+
+	if len(queries) == 0 {
+		queries = append(queries, model.DBM{"_id": row.GetObjectID()})
+	}
+
+	queries[0] = helper.SoftDeleteFilter(row, queries[0])
+
+	restore, err := helper.EncodeFields(row)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	updated, err := s.updateRow(ctx, row, queries[0])
+	if err == nil && !updated {
+		return mgo.ErrNotFound
+	}
+
+	return err
+}
+
+// updateRow sets the fields of the first row of the row table matching query with the ones of row,
+// reporting whether a row matched.
+func (s *Storage) updateRow(ctx context.Context, row model.DBObject, query model.DBM) (bool, error) {
+	docs, err := documents(row)
+	if err != nil {
+		return false, err
+	}
+
+	// the _id of a row can't be updated
+	delete(docs[0], "_id")
+
+	filter, err := buildFilter(query)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tables[helper.TableName(ctx, row)]
+	if !ok {
+		return false, nil
+	}
+
+	for i, doc := range t.rows {
+		matched, err := matches(doc, filter)
+		if err != nil {
+			return false, err
+		}
+
+		if !matched {
+			continue
+		}
+
+		updated := copyDocument(doc)
+		if err := applyUpdate(updated, model.DBM{"$set": docs[0]}, false); err != nil {
+			return false, err
+		}
+
+		t.rows[i] = updated
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (s *Storage) BulkUpdate(ctx context.Context, rows []model.DBObject, query ...model.DBM) error {
+	helper.SetUpdateTimestamps(rows...)
+
+	if len(rows) == 0 {
+		return types.ErrEmptyRow
+	}
+
+	restore, err := helper.EncodeFields(rows...)
+	if err != nil {
+		return err
+	}
+
+	defer restore()
+
+	if len(rows) != len(query) && len(query) != 0 {
+		return types.ErrRowQueryDiffLength
+	}
+
+	modified := 0
+
+	for i, row := range rows {
+		rowQuery := model.DBM{"_id": row.GetObjectID()}
+		if len(query) > 0 {
+			rowQuery = query[i]
+		}
+
+		updated, err := s.updateRow(ctx, row, helper.SoftDeleteFilter(row, rowQuery))
+		if err != nil {
+			return err
+		}
+
+		if updated {
+			modified++
+		}
+	}
+
+	if modified == 0 {
+		return mgo.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) UpdateAll(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	update, err := helper.EncodeUpdate(row, helper.TimestampedUpdate(row, update))
+	if err != nil {
+		return err
+	}
+
+	matched, err := s.updateAll(helper.TableName(ctx, row), helper.SoftDeleteFilter(row, query), update)
+	if err == nil && matched == 0 {
+		return mgo.ErrNotFound
+	}
+
+	return err
+}
+
+// updateAll applies update to the rows of table matching query, returning how many matched.
+func (s *Storage) updateAll(table string, query, update model.DBM) (int, error) {
+	filter, err := buildFilter(query)
+	if err != nil {
+		return 0, err
+	}
+
+	encodedUpdate, err := encodeDocument(update)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tables[table]
+	if !ok {
+		return 0, nil
+	}
+
+	// the rows are only replaced once all of them are updated, so a failed update changes none
+	updated := make(map[int]model.DBM)
+
+	for i, doc := range t.rows {
+		matched, err := matches(doc, filter)
+		if err != nil {
+			return 0, err
+		}
+
+		if !matched {
+			continue
+		}
+
+		updatedDoc := copyDocument(doc)
+		if err := applyUpdate(updatedDoc, encodedUpdate, false); err != nil {
+			return 0, err
+		}
+
+		updated[i] = updatedDoc
+	}
+
+	for i, doc := range updated {
+		t.rows[i] = doc
+	}
+
+	return len(updated), nil
+}
+
+func (s *Storage) Upsert(ctx context.Context, row model.DBObject, query, update model.DBM) error {
+	update, err := helper.EncodeUpdate(row, helper.RevivingUpdate(row, helper.TimestampedUpdate(row, update)))
+	if err != nil {
+		return err
+	}
+
+	doc, _, err := s.findAndModify(helper.TableName(ctx, row), query, update, nil, true)
+	if err != nil {
+		return err
+	}
+
+	if err := decode(doc, row); err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(row.TableName(), row)
+}
+
+func (s *Storage) FindOneAndUpdate(ctx context.Context,
+	row model.DBObject,
+	query, update model.DBM,
+	opts ...model.FindOneOpts,
+) error {
+	update, err := helper.EncodeUpdate(row, helper.TimestampedUpdate(row, update))
+	if err != nil {
+		return err
+	}
+
+	if len(opts) > 1 {
+		return types.ErrMultipleFindOneOpts
+	}
+
+	var findOpts model.FindOneOpts
+	if len(opts) == 1 {
+		findOpts = opts[0]
+	}
+
+	if findOpts.Upsert {
+		// same as Upsert, a deleted row matching query is restored
+		update = helper.RevivingUpdate(row, update)
+	} else {
+		query = helper.SoftDeleteFilter(row, query)
+	}
+
+	updated, previous, err := s.findAndModify(helper.TableName(ctx, row), query, update,
+		parseSort(findOpts.Sort...), findOpts.Upsert)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case updated == nil:
+		return mgo.ErrNotFound
+	case findOpts.ReturnNew:
+		err = decode(updated, row)
+	case previous == nil:
+		// the row was inserted, so there's no previous row to return
+		return nil
+	default:
+		err = decode(previous, row)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(row.TableName(), row)
+}
+
+// findAndModify applies update to the first row of table matching query, sorted by sort, or inserts it
+// if there's none and upsert is set. It returns the row after the update, or nil if no row was updated,
+// and the row before it, or nil if it was inserted.
+func (s *Storage) findAndModify(table string,
+	query, update model.DBM,
+	sort []sortKey,
+	upsert bool,
+) (model.DBM, model.DBM, error) {
+	filter, err := buildFilter(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encodedUpdate, err := encodeDocument(update)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table(table)
+
+	matched := make([]model.DBM, 0)
+
+	for _, doc := range t.rows {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+
+	if len(matched) > 0 {
+		sortDocuments(matched, sort)
+
+		position := t.indexOf(matched[0]["_id"])
+		previous := t.rows[position]
+
+		updated := copyDocument(previous)
+		if err := applyUpdate(updated, encodedUpdate, false); err != nil {
+			return nil, nil, err
+		}
+
+		t.rows[position] = updated
+
+		return copyDocument(updated), copyDocument(previous), nil
+	}
+
+	if !upsert {
+		return nil, nil, nil
+	}
+
+	inserted, err := insertedDocument(filter, encodedUpdate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t.indexOf(inserted["_id"]) >= 0 {
+		return nil, nil, duplicateKeyError(table, inserted["_id"])
+	}
+
+	t.rows = append(t.rows, inserted)
+
+	return copyDocument(inserted), nil, nil
+}
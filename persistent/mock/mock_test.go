@@ -0,0 +1,703 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/storage/persistent/utils"
+)
+
+type dummyDBObject struct {
+	ID      model.ObjectID `bson:"_id,omitempty"`
+	Name    string         `bson:"name"`
+	Age     int            `bson:"age"`
+	Tags    []string       `bson:"tags,omitempty"`
+	Country dummyCountry   `bson:"country"`
+}
+
+type dummyCountry struct {
+	CountryName string `bson:"country_name"`
+	Continent   string `bson:"continent"`
+}
+
+func (d *dummyDBObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyDBObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyDBObject) TableName() string {
+	return "dummy"
+}
+
+type softDeletableObject struct {
+	ID          model.ObjectID `bson:"_id,omitempty"`
+	Name        string         `bson:"name"`
+	DeletedTime time.Time      `bson:"deleted_at,omitempty"`
+}
+
+func (d *softDeletableObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *softDeletableObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *softDeletableObject) TableName() string {
+	return "soft"
+}
+
+func (d *softDeletableObject) DeletedAt() time.Time {
+	return d.DeletedTime
+}
+
+func seed(t *testing.T, storage *Storage) []*dummyDBObject {
+	t.Helper()
+
+	rows := []*dummyDBObject{
+		{Name: "alice", Age: 30, Tags: []string{"admin", "dev"}, Country: dummyCountry{"Spain", "Europe"}},
+		{Name: "bob", Age: 25, Tags: []string{"dev"}, Country: dummyCountry{"Japan", "Asia"}},
+		{Name: "Carol", Age: 35, Country: dummyCountry{"France", "Europe"}},
+		{Name: "dave", Age: 25, Country: dummyCountry{"Chile", "America"}},
+	}
+
+	objects := make([]model.DBObject, len(rows))
+	for i, row := range rows {
+		objects[i] = row
+	}
+
+	assert.Nil(t, storage.Insert(context.Background(), objects...))
+
+	return rows
+}
+
+func names(rows []dummyDBObject) []string {
+	result := make([]string, len(rows))
+	for i, row := range rows {
+		result[i] = row.Name
+	}
+
+	return result
+}
+
+func TestInsertAndQuery(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	rows := seed(t, storage)
+
+	assert.True(t, rows[0].ID.Valid())
+
+	var found dummyDBObject
+	assert.Nil(t, storage.Query(ctx, &dummyDBObject{}, &found, model.DBM{"_id": rows[1].ID}))
+	assert.Equal(t, *rows[1], found)
+
+	err := storage.Query(ctx, &dummyDBObject{}, &found, model.DBM{"name": "nobody"})
+	assert.True(t, utils.IsErrNoRows(err))
+
+	err = storage.Insert(ctx, &dummyDBObject{ID: rows[0].ID, Name: "duplicate"})
+	assert.True(t, mgo.IsDup(err))
+
+	count, err := storage.Count(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestQueryFilters(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	rows := seed(t, storage)
+
+	tests := []struct {
+		name     string
+		query    model.DBM
+		expected []string
+	}{
+		{"equal", model.DBM{"age": 25}, []string{"bob", "dave"}},
+		{"comparison", model.DBM{"age": model.DBM{"$gt": 25, "$lte": 35}}, []string{"alice", "Carol"}},
+		{"nested field", model.DBM{"country.continent": "Europe"}, []string{"alice", "Carol"}},
+		{"slice as $in", model.DBM{"name": []string{"bob", "dave"}}, []string{"bob", "dave"}},
+		{"ids as $in", model.DBM{"_id": []string{rows[0].ID.Hex()}}, []string{"alice"}},
+		{"array element", model.DBM{"tags": "dev"}, []string{"alice", "bob"}},
+		{"$all", model.DBM{"tags": model.DBM{"$all": []string{"dev", "admin"}}}, []string{"alice"}},
+		{"$exists", model.DBM{"tags": model.DBM{"$exists": false}}, []string{"Carol", "dave"}},
+		{"$or", model.DBM{"$or": []model.DBM{{"age": 35}, {"name": "bob"}}}, []string{"bob", "Carol"}},
+		{"$i", model.DBM{"name": model.DBM{"$i": "CAROL"}}, []string{"Carol"}},
+		{"$text", model.DBM{"name": model.DBM{"$text": "A"}}, []string{"alice", "Carol", "dave"}},
+		{"$not", model.DBM{"name": model.DBM{"$not": model.DBM{"$text": "a"}}}, []string{"bob"}},
+		{"$nin", model.DBM{"age": model.DBM{"$nin": []int{25, 30}}}, []string{"Carol"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result []dummyDBObject
+
+			query := model.DBM{"_sort": "name"}
+			for key, value := range test.query {
+				query[key] = value
+			}
+
+			assert.Nil(t, storage.Query(ctx, &dummyDBObject{}, &result, query))
+			assert.ElementsMatch(t, test.expected, names(result))
+		})
+	}
+
+	var result []dummyDBObject
+	err := storage.Query(ctx, &dummyDBObject{}, &result, model.DBM{"$where": "true"})
+	assert.EqualError(t, err, ErrorUnsupportedOperator+": $where")
+}
+
+func TestQuerySortAndPaginate(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	var result []dummyDBObject
+	assert.Nil(t, storage.Query(ctx, &dummyDBObject{}, &result, model.DBM{"_sort": "-age"}))
+	assert.Equal(t, []string{"Carol", "alice", "bob", "dave"}, names(result))
+
+	assert.Nil(t, storage.Query(ctx, &dummyDBObject{}, &result, model.DBM{"_sort": "name", "_offset": 1, "_limit": 2}))
+	assert.Equal(t, []string{"alice", "bob"}, names(result))
+
+	var projected []model.DBM
+	assert.Nil(t, storage.Query(ctx, &dummyDBObject{}, &projected, model.DBM{"name": "bob", "_fields": []string{"age"}}))
+	assert.Len(t, projected, 1)
+	assert.Equal(t, 25, projected[0]["age"])
+	assert.NotContains(t, projected[0], "name")
+	assert.Contains(t, projected[0], "_id")
+}
+
+func TestUpdates(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	rows := seed(t, storage)
+
+	rows[0].Age = 31
+	assert.Nil(t, storage.Update(ctx, rows[0]))
+
+	err := storage.Update(ctx, &dummyDBObject{ID: model.NewObjectID()})
+	assert.True(t, utils.IsErrNoRows(err))
+
+	err = storage.UpdateAll(ctx, &dummyDBObject{}, model.DBM{"age": 25},
+		model.DBM{"$inc": model.DBM{"age": 1}, "$push": model.DBM{"tags": "new"}})
+	assert.Nil(t, err)
+
+	var result []dummyDBObject
+	assert.Nil(t, storage.Query(ctx, &dummyDBObject{}, &result, model.DBM{"_sort": "name"}))
+	// the uppercase letters are sorted first
+	assert.Equal(t, []string{"Carol", "alice", "bob", "dave"}, names(result))
+	assert.Equal(t, 31, result[1].Age)
+	assert.Equal(t, 26, result[2].Age)
+	assert.Equal(t, []string{"dev", "new"}, result[2].Tags)
+	assert.Equal(t, []string{"new"}, result[3].Tags)
+
+	upserted := &dummyDBObject{}
+	err = storage.Upsert(ctx, upserted, model.DBM{"name": "erin"},
+		model.DBM{"$set": model.DBM{"age": 40}, "$setOnInsert": model.DBM{"country.continent": "Africa"}})
+	assert.Nil(t, err)
+	assert.True(t, upserted.ID.Valid())
+	assert.Equal(t, "erin", upserted.Name)
+	assert.Equal(t, 40, upserted.Age)
+	assert.Equal(t, "Africa", upserted.Country.Continent)
+
+	err = storage.Upsert(ctx, upserted, model.DBM{"name": "erin"},
+		model.DBM{"$set": model.DBM{"age": 41}, "$setOnInsert": model.DBM{"country.continent": "Oceania"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 41, upserted.Age)
+	assert.Equal(t, "Africa", upserted.Country.Continent)
+
+	count, err := storage.Count(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestFindOneAndUpdate(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	previous := &dummyDBObject{}
+	err := storage.FindOneAndUpdate(ctx, previous, model.DBM{"age": 25}, model.DBM{"$set": model.DBM{"age": 50}},
+		model.FindOneOpts{Sort: []string{"-name"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "dave", previous.Name)
+	assert.Equal(t, 25, previous.Age)
+
+	updated := &dummyDBObject{}
+	err = storage.FindOneAndUpdate(ctx, updated, model.DBM{"age": 25}, model.DBM{"$set": model.DBM{"age": 50}},
+		model.FindOneOpts{ReturnNew: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", updated.Name)
+	assert.Equal(t, 50, updated.Age)
+
+	err = storage.FindOneAndUpdate(ctx, &dummyDBObject{}, model.DBM{"age": 25}, model.DBM{"$set": model.DBM{"age": 1}})
+	assert.True(t, utils.IsErrNoRows(err))
+
+	inserted := &dummyDBObject{}
+	err = storage.FindOneAndUpdate(ctx, inserted, model.DBM{"name": "frank"}, model.DBM{"$set": model.DBM{"age": 20}},
+		model.FindOneOpts{Upsert: true})
+	assert.Nil(t, err)
+	assert.Equal(t, dummyDBObject{}, *inserted)
+
+	count, err := storage.Count(ctx, &dummyDBObject{}, model.DBM{"name": "frank", "age": 20})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	rows := seed(t, storage)
+
+	assert.Nil(t, storage.Delete(ctx, rows[0]))
+	assert.True(t, utils.IsErrNoRows(storage.Delete(ctx, rows[0])))
+
+	deleted, err := storage.DeleteWithResult(ctx, &dummyDBObject{}, model.DBM{"age": 25})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	count, err := storage.Count(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+
+	row := &softDeletableObject{Name: "soft"}
+	assert.Nil(t, storage.Insert(ctx, row, &softDeletableObject{Name: "kept"}))
+	assert.Nil(t, storage.Delete(ctx, row))
+
+	count, err := storage.Count(ctx, row)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = storage.Count(ctx, row, model.DBM{"_with_deleted": true})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	purged, err := storage.Purge(ctx, row, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = storage.Purge(ctx, &dummyDBObject{}, 0)
+	assert.EqualError(t, err, types.ErrorNotSoftDeletable)
+}
+
+func TestSoftDeleteOperations(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+
+	deleted := &softDeletableObject{Name: "soft"}
+	kept := &softDeletableObject{Name: "kept"}
+	assert.Nil(t, storage.Insert(ctx, deleted, kept))
+	assert.Nil(t, storage.Delete(ctx, deleted))
+
+	t.Run("reads", func(t *testing.T) {
+		count, err := storage.CountWithOpts(ctx, deleted, model.CountOpts{Estimated: true})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+
+		count, err = storage.CountWithOpts(ctx, deleted, model.CountOpts{Estimated: true}, model.DBM{"_with_deleted": true})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, count)
+
+		values, err := storage.Distinct(ctx, deleted, "name", model.DBM{})
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{"kept"}, values)
+
+		values, err = storage.Distinct(ctx, deleted, "name", model.DBM{"_with_deleted": true})
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, []interface{}{"soft", "kept"}, values)
+
+		assert.Nil(t, storage.CreateIndex(ctx, deleted, model.Index{Keys: []model.DBM{{"name": "text"}}}))
+
+		var found []softDeletableObject
+		assert.Nil(t, storage.SearchText(ctx, deleted, &found, "soft kept", model.DBM{}))
+		assert.Len(t, found, 1)
+		assert.Equal(t, "kept", found[0].Name)
+
+		grouped, err := storage.Aggregate(ctx, deleted, []model.DBM{{"$group": model.DBM{"_id": "$name"}}})
+		assert.Nil(t, err)
+		assert.Equal(t, []model.DBM{{"_id": "kept"}}, grouped)
+
+		grouped, err = storage.Aggregate(ctx, deleted, []model.DBM{
+			{"$group": model.DBM{"_id": "$name"}},
+			{"_with_deleted": true},
+		})
+		assert.Nil(t, err)
+		assert.Len(t, grouped, 2)
+
+		// the ids of the soft deleted rows are still taken
+		existing, err := storage.ExistingIDs(ctx, deleted, []model.ObjectID{deleted.ID, kept.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, []model.ObjectID{deleted.ID, kept.ID}, existing)
+	})
+
+	t.Run("updates", func(t *testing.T) {
+		err := storage.UpdateAll(ctx, deleted, model.DBM{"_id": deleted.ID}, model.DBM{"$set": model.DBM{"name": "x"}})
+		assert.True(t, utils.IsErrNoRows(err))
+
+		err = storage.FindOneAndUpdate(ctx, &softDeletableObject{}, model.DBM{"_id": deleted.ID},
+			model.DBM{"$set": model.DBM{"name": "x"}})
+		assert.True(t, utils.IsErrNoRows(err))
+
+		updated := &softDeletableObject{ID: deleted.ID, Name: "updated"}
+		assert.True(t, utils.IsErrNoRows(storage.Update(ctx, updated)))
+		assert.True(t, utils.IsErrNoRows(storage.BulkUpdate(ctx, []model.DBObject{updated})))
+
+		// the updates leave the deleted row deleted
+		count, err := storage.Count(ctx, deleted, model.DBM{"_id": deleted.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, 0, count)
+
+		count, err = storage.Count(ctx, deleted, model.DBM{"name": "soft", "_with_deleted": true})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+
+		err = storage.UpdateAll(ctx, deleted, model.DBM{"_id": deleted.ID, "_with_deleted": true},
+			model.DBM{"$set": model.DBM{"name": "renamed"}})
+		assert.Nil(t, err)
+	})
+
+	t.Run("upserts", func(t *testing.T) {
+		// upserting a deleted row restores it
+		restored := &softDeletableObject{}
+		err := storage.Upsert(ctx, restored, model.DBM{"_id": deleted.ID}, model.DBM{"$set": model.DBM{"name": "restored"}})
+		assert.Nil(t, err)
+		assert.Equal(t, deleted.ID, restored.ID)
+		assert.Equal(t, "restored", restored.Name)
+		assert.True(t, restored.DeletedAt().IsZero())
+
+		count, err := storage.Count(ctx, deleted, model.DBM{"_id": deleted.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+
+		assert.Nil(t, storage.Delete(ctx, restored))
+
+		err = storage.FindOneAndUpdate(ctx, &softDeletableObject{}, model.DBM{"_id": deleted.ID},
+			model.DBM{"$set": model.DBM{"name": "restored"}}, model.FindOneOpts{Upsert: true})
+		assert.Nil(t, err)
+
+		count, err = storage.Count(ctx, deleted, model.DBM{"_with_deleted": true})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, count)
+
+		count, err = storage.Count(ctx, deleted)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, count)
+	})
+}
+
+func TestListPage(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	var listed []string
+
+	page := model.PageRequest{Limit: 3, Sort: "-age"}
+
+	for {
+		result, err := storage.ListPage(ctx, &dummyDBObject{}, model.DBM{}, page)
+		assert.Nil(t, err)
+		assert.Equal(t, 4, result.Total)
+
+		for _, item := range result.Items {
+			name, _ := item["name"].(string)
+			listed = append(listed, name)
+		}
+
+		if result.NextCursor == "" {
+			break
+		}
+
+		page.AfterCursor = result.NextCursor
+	}
+
+	assert.Len(t, listed, 4)
+	assert.Equal(t, []string{"Carol", "alice"}, listed[:2])
+	assert.ElementsMatch(t, []string{"bob", "dave"}, listed[2:])
+
+	_, err := storage.ListPage(ctx, &dummyDBObject{}, model.DBM{}, model.PageRequest{AfterCursor: "invalid"})
+	assert.EqualError(t, err, types.ErrorInvalidPageCursor)
+}
+
+func TestQueryCursorAndDistinct(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	cursor, err := storage.QueryCursor(ctx, &dummyDBObject{}, model.DBM{"_sort": "age", "_limit": 2})
+	assert.Nil(t, err)
+
+	var ages []int
+
+	for cursor.Next() {
+		var row dummyDBObject
+		assert.Nil(t, cursor.Decode(&row))
+
+		ages = append(ages, row.Age)
+	}
+
+	assert.Nil(t, cursor.Err())
+	assert.Nil(t, cursor.Close())
+	assert.Equal(t, []int{25, 25}, ages)
+
+	values, err := storage.Distinct(ctx, &dummyDBObject{}, "tags", model.DBM{})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []interface{}{"admin", "dev"}, values)
+}
+
+func TestSearchText(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	var result []dummyDBObject
+	err := storage.SearchText(ctx, &dummyDBObject{}, &result, "europe", model.DBM{})
+	assert.EqualError(t, err, errorTextIndexRequired)
+
+	index := model.Index{Keys: []model.DBM{{"country.continent": "text"}, {"name": "text"}}}
+	assert.Nil(t, storage.CreateIndex(ctx, &dummyDBObject{}, index))
+
+	assert.Nil(t, storage.SearchText(ctx, &dummyDBObject{}, &result, "Europe carol", model.DBM{}))
+	assert.Equal(t, []string{"Carol", "alice"}, names(result))
+}
+
+func TestIndexes(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+
+	_, err := storage.GetIndexes(ctx, &dummyDBObject{})
+	assert.EqualError(t, err, types.ErrorCollectionNotFound)
+
+	assert.EqualError(t, storage.CreateIndex(ctx, &dummyDBObject{}, model.Index{}), types.ErrorIndexEmpty)
+	assert.Nil(t, storage.CreateIndex(ctx, &dummyDBObject{}, model.Index{Keys: []model.DBM{{"name": 1}, {"age": -1}}}))
+
+	index := model.Index{Name: "other", Keys: []model.DBM{{"name": 1}, {"age": -1}}}
+	err = storage.CreateIndex(ctx, &dummyDBObject{}, index)
+	assert.EqualError(t, err, types.ErrorIndexAlreadyExist)
+
+	indexes, err := storage.GetIndexes(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Index{
+		{Name: idIndex, Keys: []model.DBM{{"_id": int32(1)}}},
+		{Name: "name_1_age_-1", Keys: []model.DBM{{"name": int32(1)}, {"age": int32(-1)}}},
+	}, indexes)
+
+	assert.Nil(t, storage.CleanIndexes(ctx, &dummyDBObject{}))
+
+	indexes, err = storage.GetIndexes(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Len(t, indexes, 1)
+}
+
+func TestTables(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	assert.Nil(t, storage.Migrate(ctx, []model.DBObject{&softDeletableObject{}}))
+
+	tables, err := storage.GetTables(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dummy", "soft"}, tables)
+
+	dropped, err := storage.DropTable(ctx, "dummy")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, dropped)
+
+	hasTable, err := storage.HasTable(ctx, "dummy")
+	assert.Nil(t, err)
+	assert.False(t, hasTable)
+
+	assert.Nil(t, storage.DropDatabase(ctx))
+
+	tables, err = storage.GetTables(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, tables)
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	rows := seed(t, storage)
+
+	var buf bytes.Buffer
+
+	exported, err := storage.ExportNDJSON(ctx, &dummyDBObject{}, model.DBM{"_sort": "name"}, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, exported)
+	assert.Equal(t, 4, strings.Count(buf.String(), "\n"))
+
+	imported := New()
+
+	n, err := imported.ImportNDJSON(ctx, &dummyDBObject{}, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, n)
+
+	var found dummyDBObject
+	assert.Nil(t, imported.Query(ctx, &dummyDBObject{}, &found, model.DBM{"_id": rows[0].ID}))
+	assert.Equal(t, *rows[0], found)
+
+	existing, err := imported.ExistingIDs(ctx, &dummyDBObject{}, []model.ObjectID{model.NewObjectID(), rows[2].ID})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.ObjectID{rows[2].ID}, existing)
+}
+
+// prefixCodec encodes the values prefixing them with "encoded:".
+type prefixCodec struct{}
+
+func (prefixCodec) Encode(value string) (string, error) {
+	return "encoded:" + value, nil
+}
+
+func (prefixCodec) Decode(value string) (string, error) {
+	if !strings.HasPrefix(value, "encoded:") {
+		return "", errors.New("not encoded")
+	}
+
+	return strings.TrimPrefix(value, "encoded:"), nil
+}
+
+func TestFieldCodec(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+
+	helper.RegisterFieldCodec("dummy", "name", prefixCodec{})
+	defer helper.RegisterFieldCodec("dummy", "name", nil)
+
+	row := &dummyDBObject{Name: "alice", Age: 30}
+	assert.Nil(t, storage.Insert(ctx, row))
+
+	t.Run("read paths decoded", func(t *testing.T) {
+		cursor, err := storage.QueryCursor(ctx, row, model.DBM{})
+		assert.Nil(t, err)
+		assert.True(t, cursor.Next())
+
+		var found dummyDBObject
+		assert.Nil(t, cursor.Decode(&found))
+		assert.Equal(t, *row, found)
+
+		page, err := storage.ListPage(ctx, row, model.DBM{}, model.PageRequest{})
+		assert.Nil(t, err)
+		assert.Equal(t, "alice", page.Items[0]["name"])
+
+		values, err := storage.Distinct(ctx, row, "name", model.DBM{})
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{"alice"}, values)
+
+		index := model.Index{Keys: []model.DBM{{"name": "text"}}}
+		assert.Nil(t, storage.CreateIndex(ctx, row, index))
+
+		var result []dummyDBObject
+		assert.Nil(t, storage.SearchText(ctx, row, &result, "encoded", model.DBM{}))
+		assert.Equal(t, []string{"alice"}, names(result))
+
+		var buf bytes.Buffer
+
+		_, err = storage.ExportNDJSON(ctx, row, model.DBM{}, &buf)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), `"alice"`)
+		assert.NotContains(t, buf.String(), "encoded:")
+
+		imported := New()
+
+		_, err = imported.ImportNDJSON(ctx, row, &buf)
+		assert.Nil(t, err)
+		assert.Equal(t, "encoded:alice", imported.rows("dummy")[0]["name"])
+	})
+
+	t.Run("update paths encoded", func(t *testing.T) {
+		err := storage.UpdateAll(ctx, row, model.DBM{"_id": row.ID}, model.DBM{"$set": model.DBM{"name": "bob"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "encoded:bob", storage.rows("dummy")[0]["name"])
+
+		var found dummyDBObject
+
+		err = storage.FindOneAndUpdate(ctx, &found, model.DBM{"_id": row.ID},
+			model.DBM{"$set": model.DBM{"name": "carol"}}, model.FindOneOpts{ReturnNew: true})
+		assert.Nil(t, err)
+		assert.Equal(t, "carol", found.Name)
+		assert.Equal(t, "encoded:carol", storage.rows("dummy")[0]["name"])
+
+		upserted := &dummyDBObject{}
+
+		err = storage.Upsert(ctx, upserted, model.DBM{"age": 40}, model.DBM{"$set": model.DBM{"name": "dave"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "dave", upserted.Name)
+		assert.Equal(t, "encoded:dave", storage.rows("dummy")[1]["name"])
+	})
+}
+
+func TestWithTransaction(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+	seed(t, storage)
+
+	failure := errors.New("failure")
+
+	err := storage.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+		if err := tx.Insert(ctx, &dummyDBObject{Name: "rolled back"}); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	err = storage.WithTransaction(ctx, func(tx types.PersistentStorage) error {
+		return tx.Insert(ctx, &dummyDBObject{Name: "committed"})
+	})
+	assert.Nil(t, err)
+
+	count, err := storage.Count(ctx, &dummyDBObject{})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, count)
+}
+
+type uuidObject struct {
+	ID   model.UUID `bson:"_id"`
+	Name string     `bson:"name"`
+}
+
+func (d *uuidObject) GetObjectID() model.ObjectID {
+	return ""
+}
+
+// SetObjectID is a no-op, as the rows are identified by their UUID.
+func (d *uuidObject) SetObjectID(id model.ObjectID) {}
+
+func (d *uuidObject) TableName() string {
+	return "uuid"
+}
+
+func TestUUIDs(t *testing.T) {
+	ctx := context.Background()
+	storage := New()
+
+	first, second := &uuidObject{ID: model.NewUUID(), Name: "first"}, &uuidObject{ID: model.NewUUID(), Name: "second"}
+	assert.Nil(t, storage.Insert(ctx, first, second))
+
+	var found uuidObject
+	assert.Nil(t, storage.Query(ctx, &uuidObject{}, &found, model.DBM{"_id": second.ID}))
+	assert.Equal(t, *second, found)
+
+	var result []uuidObject
+	assert.Nil(t, storage.Query(ctx, &uuidObject{}, &result, model.DBM{"_id": []string{first.ID.String()}}))
+	assert.Equal(t, []uuidObject{*first}, result)
+}
@@ -0,0 +1,564 @@
+package mock
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+	"github.com/TykTechnologies/storage/persistent/internal/types"
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+const errorTextIndexRequired = "text index required for $text query"
+
+// find returns copies of the rows of table matching query, applying its _sort, _offset, _limit and _fields keys.
+func (s *Storage) find(table string, query model.DBM) ([]model.DBM, error) {
+	filter, err := buildFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return findRows(s.rows(table), filter, query)
+}
+
+func findRows(rows []model.DBM, filter, query model.DBM) ([]model.DBM, error) {
+	docs := make([]model.DBM, 0)
+
+	for _, doc := range rows {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			docs = append(docs, copyDocument(doc))
+		}
+	}
+
+	if sort, ok := query["_sort"].(string); ok {
+		sortDocuments(docs, parseSort(sort))
+	}
+
+	if offset, ok := query["_offset"].(int); ok && offset > 0 {
+		if offset > len(docs) {
+			offset = len(docs)
+		}
+
+		docs = docs[offset:]
+	}
+
+	if limit, ok := query["_limit"].(int); ok && limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+
+	if fields, ok := query["_fields"].([]string); ok && len(fields) > 0 {
+		for i, doc := range docs {
+			docs[i] = project(doc, fields)
+		}
+	}
+
+	return docs, nil
+}
+
+func (s *Storage) Count(ctx context.Context, row model.DBObject, filters ...model.DBM) (int, error) {
+	if len(filters) > 1 {
+		return 0, types.ErrMultipleDBM
+	}
+
+	query := model.DBM{}
+	if len(filters) == 1 {
+		query = filters[0]
+	}
+
+	// the meta keys paginating the rows don't apply to a count
+	filter, err := buildFilter(helper.SoftDeleteFilter(row, query))
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs, err := findRows(s.rows(helper.TableName(ctx, row)), filter, model.DBM{})
+
+	return len(docs), err
+}
+
+func (s *Storage) CountWithOpts(ctx context.Context,
+	row model.DBObject,
+	opts model.CountOpts,
+	filters ...model.DBM,
+) (int, error) {
+	if !opts.Estimated || len(filters) > 1 {
+		return s.Count(ctx, row, filters...)
+	}
+
+	query := model.DBM{}
+	if len(filters) == 1 {
+		query = filters[0]
+	}
+
+	filter, err := buildFilter(helper.SoftDeleteFilter(row, query))
+	if err != nil {
+		return 0, err
+	}
+
+	// the estimation ignores the filters, so the rows are counted when they filter any field,
+	// including the deleted_at field skipping the soft deleted rows
+	if len(filter) > 0 {
+		return s.Count(ctx, row, filters...)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.rows(helper.TableName(ctx, row))), nil
+}
+
+func (s *Storage) Query(ctx context.Context, row model.DBObject, result interface{}, query model.DBM) error {
+	query = helper.SoftDeleteFilter(row, query)
+
+	table := helper.TableName(ctx, row)
+	if collection, ok := query["_collection"].(string); ok {
+		table = collection
+	}
+
+	docs, err := s.find(table, query)
+	if err != nil {
+		return err
+	}
+
+	if helper.IsSlice(result) {
+		err = decodeAll(docs, result)
+	} else {
+		if len(docs) == 0 {
+			return mgo.ErrNotFound
+		}
+
+		err = decode(docs[0], result)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(row.TableName(), result)
+}
+
+// SearchText matches the words of text with the words of the fields of the text indexes of the table,
+// ignoring the case. The relevance of a row is the number of its words matching one of text.
+func (s *Storage) SearchText(ctx context.Context,
+	row model.DBObject,
+	result interface{},
+	text string,
+	filter model.DBM,
+) error {
+	search, err := buildFilter(helper.SoftDeleteFilter(row, filter))
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+
+	var fields []string
+
+	if t, ok := s.tables[helper.TableName(ctx, row)]; ok {
+		for _, index := range t.indexes {
+			for _, key := range index.Keys {
+				for field, kind := range key {
+					if kind == "text" {
+						fields = append(fields, field)
+					}
+				}
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		s.mu.RUnlock()
+		return errors.New(errorTextIndexRequired)
+	}
+
+	docs, err := findRows(s.rows(helper.TableName(ctx, row)), search, model.DBM{})
+
+	s.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	words := map[string]bool{}
+	for _, word := range textWords(text) {
+		words[word] = true
+	}
+
+	scored := make([]model.DBM, 0, len(docs))
+
+	for _, doc := range docs {
+		score := 0
+
+		for _, field := range fields {
+			value, _ := lookup(doc, field)
+
+			anyCandidate(value, func(v interface{}) bool {
+				if str, ok := v.(string); ok {
+					for _, word := range textWords(str) {
+						if words[word] {
+							score++
+						}
+					}
+				}
+
+				return false
+			})
+		}
+
+		if score > 0 {
+			doc[helper.TextScoreField] = float64(score)
+			scored = append(scored, doc)
+		}
+	}
+
+	query := model.DBM{"_sort": "$textScore:" + helper.TextScoreField}
+	for _, key := range []string{"_offset", "_limit"} {
+		if value, ok := filter[key]; ok {
+			query[key] = value
+		}
+	}
+
+	if fields, ok := filter["_fields"].([]string); ok && len(fields) > 0 {
+		query["_fields"] = append([]string{helper.TextScoreField}, fields...)
+	}
+
+	docs, err = findRows(scored, model.DBM{}, query)
+	if err != nil {
+		return err
+	}
+
+	if helper.IsSlice(result) {
+		err = decodeAll(docs, result)
+	} else {
+		if len(docs) == 0 {
+			return mgo.ErrNotFound
+		}
+
+		err = decode(docs[0], result)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(row.TableName(), result)
+}
+
+// textWords returns the lowercase words of text.
+func textWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func (s *Storage) Watch(ctx context.Context, row model.DBObject, filter model.DBM) (<-chan model.ChangeEvent, error) {
+	return nil, types.ErrChangeStreamsUnsupported
+}
+
+func (s *Storage) QueryCursor(ctx context.Context, row model.DBObject, query model.DBM) (model.Cursor, error) {
+	query = helper.SoftDeleteFilter(row, query)
+
+	table := helper.TableName(ctx, row)
+	if collection, ok := query["_collection"].(string); ok {
+		table = collection
+	}
+
+	docs, err := s.find(table, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cursor{docs: docs, position: -1, table: row.TableName()}, nil
+}
+
+// cursor iterates over rows found beforehand, so the rows inserted meanwhile aren't returned.
+type cursor struct {
+	docs     []model.DBM
+	position int
+	table    string
+}
+
+func (c *cursor) Next() bool {
+	if c.position < len(c.docs) {
+		c.position++
+	}
+
+	return c.position < len(c.docs)
+}
+
+func (c *cursor) Decode(result interface{}) error {
+	if c.position < 0 || c.position >= len(c.docs) {
+		return errors.New("no current row to decode")
+	}
+
+	if err := decode(c.docs[c.position], result); err != nil {
+		return err
+	}
+
+	return helper.DecodeFields(c.table, result)
+}
+
+func (c *cursor) Err() error {
+	return nil
+}
+
+func (c *cursor) Close() error {
+	c.position = len(c.docs)
+	return nil
+}
+
+// pageCursor is the position of the last row of a page: its value of the sort field and its _id.
+type pageCursor struct {
+	Value interface{} `bson:"v"`
+	ID    interface{} `bson:"id"`
+}
+
+func (s *Storage) ListPage(ctx context.Context,
+	row model.DBObject,
+	filter model.DBM,
+	page model.PageRequest,
+) (model.PageResult, error) {
+	search, err := buildFilter(helper.SoftDeleteFilter(row, filter))
+	if err != nil {
+		return model.PageResult{}, err
+	}
+
+	s.mu.RLock()
+	docs, err := findRows(s.rows(helper.TableName(ctx, row)), search, model.DBM{})
+	s.mu.RUnlock()
+
+	if err != nil {
+		return model.PageResult{}, err
+	}
+
+	field, descending := helper.PageSort(page.Sort)
+	limit := helper.PageLimit(page)
+	result := model.PageResult{Items: make([]model.DBM, 0, limit), Total: len(docs)}
+
+	if page.AfterCursor != "" {
+		keyset, err := keysetFilter(field, descending, page.AfterCursor)
+		if err != nil {
+			return model.PageResult{}, err
+		}
+
+		if docs, err = findRows(docs, keyset, model.DBM{}); err != nil {
+			return model.PageResult{}, err
+		}
+	}
+
+	keys := []sortKey{{field: field, descending: descending}}
+	if field != "_id" {
+		keys = append(keys, sortKey{field: "_id", descending: descending})
+	}
+
+	sortDocuments(docs, keys)
+
+	if len(docs) > limit {
+		last := docs[limit-1]
+
+		data, err := bson.Marshal(pageCursor{Value: last[field], ID: last["_id"]})
+		if err != nil {
+			return model.PageResult{}, err
+		}
+
+		result.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+		docs = docs[:limit]
+	}
+
+	result.Items = append(result.Items, docs...)
+
+	return result, helper.DecodeFields(row.TableName(), &result.Items)
+}
+
+// keysetFilter returns the filter of the rows following the encoded cursor, sorted by field and then _id.
+func keysetFilter(field string, descending bool, encoded string) (model.DBM, error) {
+	var after pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, types.ErrInvalidPageCursor
+	}
+
+	if err := bson.Unmarshal(data, &after); err != nil {
+		return nil, types.ErrInvalidPageCursor
+	}
+
+	operator := "$gt"
+	if descending {
+		operator = "$lt"
+	}
+
+	if field == "_id" {
+		return encodeDocument(model.DBM{"_id": model.DBM{operator: after.ID}})
+	}
+
+	return encodeDocument(model.DBM{"$or": []model.DBM{
+		{field: model.DBM{operator: after.Value}},
+		{field: after.Value, "_id": model.DBM{operator: after.ID}},
+	}})
+}
+
+func (s *Storage) Distinct(ctx context.Context,
+	row model.DBObject,
+	field string,
+	filter model.DBM,
+) ([]interface{}, error) {
+	docs, err := s.find(helper.TableName(ctx, row), helper.SoftDeleteFilter(row, filter))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0)
+
+	for _, doc := range docs {
+		value, found := lookup(doc, field)
+		if !found {
+			continue
+		}
+
+		// the elements of the arrays are distinct values on their own
+		elems, ok := value.([]interface{})
+		if !ok {
+			elems = []interface{}{value}
+		}
+
+		for _, elem := range elems {
+			if !anyCandidate(values, func(v interface{}) bool { return equal(v, elem) }) {
+				values = append(values, elem)
+			}
+		}
+	}
+
+	return values, helper.DecodeValues(row.TableName(), field, values)
+}
+
+func (s *Storage) Aggregate(ctx context.Context, row model.DBObject, query []model.DBM) ([]model.DBM, error) {
+	pipeline, opts := helper.SplitPipelineOptions(query)
+	pipeline = helper.SoftDeletePipeline(row, pipeline, opts)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return aggregate(s.rows(helper.TableName(ctx, row)), pipeline, s.rows)
+}
+
+// Explain reports a collection scan, as the mock has no indexes to run the queries with.
+func (s *Storage) Explain(ctx context.Context, row model.DBObject, filter model.DBM) (model.DBM, error) {
+	docs, err := s.find(helper.TableName(ctx, row), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.explain(helper.TableName(ctx, row), len(docs)), nil
+}
+
+func (s *Storage) ExplainAggregate(ctx context.Context, row model.DBObject, query []model.DBM) (model.DBM, error) {
+	docs, err := s.Aggregate(ctx, row, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.explain(helper.TableName(ctx, row), len(docs)), nil
+}
+
+func (s *Storage) explain(table string, returned int) model.DBM {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return model.DBM{
+		"queryPlanner": model.DBM{
+			"namespace":   table,
+			"winningPlan": model.DBM{"stage": "COLLSCAN"},
+		},
+		"executionStats": model.DBM{
+			"nReturned":           returned,
+			"executionTimeMillis": 0,
+			"totalKeysExamined":   0,
+			"totalDocsExamined":   len(s.rows(table)),
+		},
+	}
+}
+
+// documents returns the rows as they are stored.
+func documents(rows ...model.DBObject) ([]model.DBM, error) {
+	docs := make([]model.DBM, 0, len(rows))
+
+	for _, row := range rows {
+		doc, err := encodeDocument(row)
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// decode decodes doc into result following its bson tags. A *model.DBM result is set with a copy of doc.
+func decode(doc model.DBM, result interface{}) error {
+	if dbm, ok := result.(*model.DBM); ok {
+		*dbm = copyDocument(doc)
+		return nil
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(data, result)
+}
+
+// decodeAll decodes docs into result, which must be a pointer to a slice.
+func decodeAll(docs []model.DBM, result interface{}) error {
+	resultValue := reflect.ValueOf(result)
+	if resultValue.Kind() != reflect.Ptr || resultValue.Elem().Kind() != reflect.Slice {
+		return errors.New("result must be a pointer to a slice")
+	}
+
+	slice := reflect.MakeSlice(resultValue.Elem().Type(), 0, len(docs))
+	elemType := slice.Type().Elem()
+
+	for _, doc := range docs {
+		if elemType.Kind() == reflect.Ptr {
+			elem := reflect.New(elemType.Elem())
+			if err := decode(doc, elem.Interface()); err != nil {
+				return err
+			}
+
+			slice = reflect.Append(slice, elem)
+
+			continue
+		}
+
+		elem := reflect.New(elemType)
+		if err := decode(doc, elem.Interface()); err != nil {
+			return err
+		}
+
+		slice = reflect.Append(slice, elem.Elem())
+	}
+
+	resultValue.Elem().Set(slice)
+
+	return nil
+}
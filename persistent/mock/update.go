@@ -0,0 +1,328 @@
+package mock
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// encodeDocument returns doc as the mgo driver stores it, with the nested documents as model.DBM and
+// the ObjectIDs as model.ObjectID, so the rows, the filters and the updates are compared the same way.
+func encodeDocument(doc interface{}) (model.DBM, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded bson.M
+	if err := bson.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	return normalize(encoded).(model.DBM), nil
+}
+
+// encodeValue is the same as encodeDocument for a single value.
+func encodeValue(value interface{}) (interface{}, error) {
+	doc, err := encodeDocument(bson.M{"v": value})
+	if err != nil {
+		return nil, err
+	}
+
+	return doc["v"], nil
+}
+
+// normalize returns value with its documents as model.DBM, its arrays as []interface{}, its ObjectIDs as
+// model.ObjectID and its UUIDs as model.UUID, at any depth. The documents and arrays are copied.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case model.DBM:
+		return normalizeMap(v)
+	case bson.M:
+		return normalizeMap(v)
+	case map[string]interface{}:
+		return normalizeMap(v)
+	case bson.D:
+		doc := make(model.DBM, len(v))
+		for _, e := range v {
+			doc[e.Name] = normalize(e.Value)
+		}
+
+		return doc
+	case []interface{}:
+		array := make([]interface{}, len(v))
+		for i, elem := range v {
+			array[i] = normalize(elem)
+		}
+
+		return array
+	case bson.ObjectId:
+		return model.ObjectID(v)
+	case bson.Binary:
+		var u model.UUID
+		if v.Kind == 0x04 && len(v.Data) == len(u) {
+			copy(u[:], v.Data)
+			return u
+		}
+
+		return value
+	default:
+		return value
+	}
+}
+
+func normalizeMap(m map[string]interface{}) model.DBM {
+	doc := make(model.DBM, len(m))
+	for key, value := range m {
+		doc[key] = normalize(value)
+	}
+
+	return doc
+}
+
+// copyDocument returns a deep copy of doc.
+func copyDocument(doc model.DBM) model.DBM {
+	return normalizeMap(doc)
+}
+
+// setPath sets the field at path, given in dot notation, creating the missing documents along the path.
+func setPath(doc model.DBM, path string, value interface{}) error {
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		doc[key] = value
+		return nil
+	}
+
+	switch v := doc[key].(type) {
+	case model.DBM:
+		return setPath(v, rest, value)
+	case []interface{}:
+		index, after, hasMore := strings.Cut(rest, ".")
+
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(v) {
+			return errors.New("cannot set " + path + ": invalid array index")
+		}
+
+		if !hasMore {
+			v[i] = value
+			return nil
+		}
+
+		sub, ok := v[i].(model.DBM)
+		if !ok {
+			return errors.New("cannot set " + path + ": not a document")
+		}
+
+		return setPath(sub, after, value)
+	case nil:
+		sub := model.DBM{}
+		doc[key] = sub
+
+		return setPath(sub, rest, value)
+	default:
+		return errors.New("cannot set " + path + ": not a document")
+	}
+}
+
+// unsetPath removes the field at path, given in dot notation, if it exists.
+func unsetPath(doc model.DBM, path string) {
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		delete(doc, key)
+		return
+	}
+
+	if sub, ok := doc[key].(model.DBM); ok {
+		unsetPath(sub, rest)
+	}
+}
+
+// applyUpdate applies the update operators of update to doc. $setOnInsert is only applied when inserting.
+// An update without operators replaces the fields of doc, but its _id.
+func applyUpdate(doc, update model.DBM, inserting bool) error {
+	if !isOperatorDocument(update) {
+		id, hasID := doc["_id"]
+
+		for key := range doc {
+			delete(doc, key)
+		}
+
+		for key, value := range update {
+			doc[key] = value
+		}
+
+		if hasID {
+			doc["_id"] = id
+		}
+
+		return nil
+	}
+
+	for operator, operand := range update {
+		fields, ok := operand.(model.DBM)
+		if !ok {
+			return errors.New(operator + " expects a document")
+		}
+
+		for path, value := range fields {
+			if err := applyOperator(doc, operator, path, value, inserting); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func applyOperator(doc model.DBM, operator, path string, value interface{}, inserting bool) error {
+	current, found := lookup(doc, path)
+
+	switch operator {
+	case "$set":
+		return setPath(doc, path, value)
+	case "$setOnInsert":
+		if inserting {
+			return setPath(doc, path, value)
+		}
+	case "$unset":
+		unsetPath(doc, path)
+	case "$inc":
+		return setPath(doc, path, add(current, value))
+	case "$min", "$max":
+		c, comparable := compare(value, current)
+		if !found || comparable && (operator == "$min" && c < 0 || operator == "$max" && c > 0) {
+			return setPath(doc, path, value)
+		}
+	case "$currentDate":
+		return setPath(doc, path, time.Now())
+	case "$push", "$addToSet":
+		array, ok := current.([]interface{})
+		if found && !ok {
+			return errors.New("cannot apply " + operator + " to the non-array field " + path)
+		}
+
+		for _, elem := range eachValue(value) {
+			if operator == "$addToSet" && anyCandidate(array, func(v interface{}) bool { return equal(v, elem) }) {
+				continue
+			}
+
+			array = append(array, elem)
+		}
+
+		return setPath(doc, path, array)
+	case "$pull":
+		array, ok := current.([]interface{})
+		if !ok {
+			return nil
+		}
+
+		kept := make([]interface{}, 0, len(array))
+
+		for _, elem := range array {
+			matched, err := matchPull(elem, value)
+			if err != nil {
+				return err
+			}
+
+			if !matched {
+				kept = append(kept, elem)
+			}
+		}
+
+		return setPath(doc, path, kept)
+	default:
+		return errors.New(ErrorUnsupportedOperator + ": " + operator)
+	}
+
+	return nil
+}
+
+// eachValue returns the values added by $push or $addToSet: the ones of the $each modifier, or value itself.
+func eachValue(value interface{}) []interface{} {
+	if modifiers, ok := value.(model.DBM); ok {
+		if each, ok := modifiers["$each"].([]interface{}); ok {
+			return each
+		}
+	}
+
+	return []interface{}{value}
+}
+
+// matchPull reports whether elem is removed by the condition of $pull.
+func matchPull(elem, condition interface{}) (bool, error) {
+	doc, isDoc := elem.(model.DBM)
+	filter, isFilter := condition.(model.DBM)
+
+	if isDoc && isFilter && !isOperatorDocument(filter) {
+		return matches(doc, filter)
+	}
+
+	return matchCondition(elem, true, condition)
+}
+
+// add returns the sum of two numbers, keeping them integer if both are. A missing value counts as 0.
+func add(a, b interface{}) interface{} {
+	x, aIsNumber := toFloat(a)
+	y, _ := toFloat(b)
+
+	if (!aIsNumber || isInteger(a)) && isInteger(b) {
+		return int(x) + int(y)
+	}
+
+	return x + y
+}
+
+func isInteger(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// insertedDocument returns the row inserted by an upsert matching no row: the fields filter is equal to,
+// updated with update.
+func insertedDocument(filter, update model.DBM) (model.DBM, error) {
+	doc := model.DBM{}
+
+	for key, value := range filter {
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+
+		if condition, ok := value.(model.DBM); ok && isOperatorDocument(condition) {
+			if eq, ok := condition["$eq"]; ok {
+				value = eq
+			} else {
+				continue
+			}
+		}
+
+		if err := setPath(doc, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyUpdate(doc, update, true); err != nil {
+		return nil, err
+	}
+
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = model.NewObjectID()
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,10 @@
+package model
+
+// BulkOpts configures a BulkInsert.
+type BulkOpts struct {
+	// BatchSize is the number of rows sent to the database at once. Defaults to 1000.
+	BatchSize int
+	// ContinueOnError keeps inserting the remaining rows when some of them fail, instead of stopping at the first
+	// failed row. In both cases, the errors of the failed rows are aggregated in the returned error.
+	ContinueOnError bool
+}
@@ -0,0 +1,16 @@
+package model
+
+// ChangeEvent is a change of a row reported by Watch.
+type ChangeEvent struct {
+	// Operation is the kind of change: insert, update, replace or delete.
+	Operation string
+	// ID is the ID of the changed row.
+	ID ObjectID
+	// Document is the row after the change. It's empty for deletes.
+	Document DBM
+	// ResumeToken identifies the event. Pass it in the "_resume_after" key of the Watch filter
+	// to watch the changes made after this one, e.g. after a restart.
+	ResumeToken DBM
+	// Err is set when watching stopped because of an error. It's the last event sent.
+	Err error
+}
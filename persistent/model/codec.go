@@ -0,0 +1,8 @@
+package model
+
+// FieldCodec transforms the value of a string field when it's stored and loaded, e.g. to encrypt it.
+// Decode must revert Encode. To be able to filter by an encoded field, Encode must be deterministic.
+type FieldCodec interface {
+	Encode(value string) (string, error)
+	Decode(value string) (string, error)
+}
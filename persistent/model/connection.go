@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ConnectionEventType is the type of a change of the connection of a driver to the database.
+type ConnectionEventType string
+
+const (
+	// ConnectionConnected is sent when the driver connects to the database for the first time.
+	ConnectionConnected ConnectionEventType = "connect"
+	// ConnectionDisconnected is sent when the driver is closed, or loses the connection and can't reconnect.
+	ConnectionDisconnected ConnectionEventType = "disconnect"
+	// ConnectionReconnected is sent when the driver connects again after a connection error.
+	ConnectionReconnected ConnectionEventType = "reconnect"
+	// ConnectionFailover is sent when a new primary is elected in the replica set. Only sent by the mongo-go driver.
+	ConnectionFailover ConnectionEventType = "failover"
+)
+
+// ConnectionEvent is a change of the connection of a driver to the database.
+type ConnectionEvent struct {
+	Type ConnectionEventType
+	// Primary is the address of the new primary of a ConnectionFailover event.
+	Primary string
+	// Err is the error which caused a ConnectionDisconnected event, nil if the driver was closed.
+	Err  error
+	Time time.Time
+}
@@ -0,0 +1,9 @@
+package model
+
+// CountOpts configures a CountWithOpts.
+type CountOpts struct {
+	// Estimated returns the number of rows of the table from its metadata instead of counting them, which is
+	// much faster on large tables at the cost of precision. It's only applied without filter, so the rows of a
+	// SoftDeletable are counted instead unless the "_with_deleted" key is set to true.
+	Estimated bool
+}
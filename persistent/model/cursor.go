@@ -0,0 +1,14 @@
+package model
+
+// Cursor iterates over the rows matched by a query, decoding them one at a time
+// instead of loading the whole result set in memory.
+type Cursor interface {
+	// Next prepares the next row for Decode. It returns false when there are no more rows or an error happened.
+	Next() bool
+	// Decode decodes the current row into result.
+	Decode(result interface{}) error
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+	// Close releases the resources of the cursor. It must always be called.
+	Close() error
+}
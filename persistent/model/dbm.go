@@ -1,3 +1,9 @@
 package model
 
+// DBM is the map used to express queries, updates and aggregation stages.
+// Besides the operators of the database, every driver supports the following field operators:
+//   - $i matches the field case-insensitively, e.g. DBM{"name": DBM{"$i": "tyk"}}
+//   - $text matches the fields containing the value case-insensitively, e.g. DBM{"name": DBM{"$text": "ty"}}
+//
+// Both can be negated with $not, e.g. DBM{"name": DBM{"$not": DBM{"$i": "tyk"}}}.
 type DBM map[string]interface{}
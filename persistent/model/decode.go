@@ -0,0 +1,17 @@
+package model
+
+// DecodeWarning reports a field of a stored document that couldn't be decoded into the result type,
+// e.g. because its type drifted after a migration. The field is left with its zero value.
+type DecodeWarning struct {
+	ID    ObjectID
+	Field string
+	Err   error
+}
+
+// DecodeWarnings collects the fields skipped while decoding the result of a lenient query.
+// Pass a pointer to it in the "_lenient_decode" key of the query to enable the lenient decode mode.
+type DecodeWarnings []DecodeWarning
+
+func (w *DecodeWarnings) Add(id ObjectID, field string, err error) {
+	*w = append(*w, DecodeWarning{ID: id, Field: field, Err: err})
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"context"
+	"sync"
+)
+
+type dryRunKey struct{}
+
+// DryRun collects the commands the storage would have run for the operations called with a context returned
+// by WithDryRun.
+type DryRun struct {
+	mu       sync.Mutex
+	commands []DBM
+}
+
+// WithDryRun returns a context making the storage operations record the database command they would run
+// in the returned DryRun instead of running it, e.g. to audit the queries or to test the translation of filters
+// without a database. The commands are recorded as sent to mongo, e.g. DBM{"find": "users", "filter": ...}.
+//
+// Insert, Update, UpdateAll, Upsert, Delete, DeleteWithResult, Count, Query and Aggregate are supported:
+// they return no error and no result, and leave the given rows untouched. The other writes fail with an error
+// without running, while the other reads run as usual.
+func WithDryRun(ctx context.Context) (context.Context, *DryRun) {
+	dryRun := &DryRun{}
+	return context.WithValue(ctx, dryRunKey{}, dryRun), dryRun
+}
+
+// DryRunFromContext returns the DryRun of ctx, if it was returned by WithDryRun.
+func DryRunFromContext(ctx context.Context) (*DryRun, bool) {
+	dryRun, ok := ctx.Value(dryRunKey{}).(*DryRun)
+	return dryRun, ok
+}
+
+// Record adds command to the recorded commands.
+func (d *DryRun) Record(command DBM) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.commands = append(d.commands, command)
+}
+
+// Commands returns the recorded commands, in the order they were recorded.
+func (d *DryRun) Commands() []DBM {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]DBM(nil), d.commands...)
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDryRun(t *testing.T) {
+	_, ok := DryRunFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx, dryRun := WithDryRun(context.Background())
+
+	fromCtx, ok := DryRunFromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, dryRun, fromCtx)
+
+	dryRun.Record(DBM{"count": "users"})
+	dryRun.Record(DBM{"find": "users"})
+
+	commands := dryRun.Commands()
+	assert.Equal(t, []DBM{{"count": "users"}, {"find": "users"}}, commands)
+
+	commands[0] = nil
+	assert.Equal(t, DBM{"count": "users"}, dryRun.Commands()[0])
+}
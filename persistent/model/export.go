@@ -0,0 +1,12 @@
+package model
+
+// ExportFormat is the format of the rows written by Export and read by Import.
+type ExportFormat string
+
+const (
+	// NDJSON writes a JSON document per line.
+	NDJSON ExportFormat = "ndjson"
+	// CSV writes a header line with the fields of the rows, followed by a line per row. The nested documents
+	// and the arrays are written as JSON.
+	CSV ExportFormat = "csv"
+)
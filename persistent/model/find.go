@@ -0,0 +1,11 @@
+package model
+
+// FindOneOpts configures a FindOneAndUpdate.
+type FindOneOpts struct {
+	// ReturnNew returns the row as it is after the update. By default, the row is returned as it was before it.
+	ReturnNew bool
+	// Upsert inserts a new row when none matches the query.
+	Upsert bool
+	// Sort picks the row to update when several match the query. Prefix a field with "-" to sort it descending.
+	Sort []string
+}
@@ -0,0 +1,13 @@
+package model
+
+// GeoPoint is a GeoJSON point, which can be stored in a field covered by a geospatial index (see Index.GeoIndex)
+// and used as the $geometry of the $near and $geoWithin operators.
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPoint returns the GeoPoint of the given longitude and latitude.
+func NewGeoPoint(longitude, latitude float64) GeoPoint {
+	return GeoPoint{Type: "Point", Coordinates: []float64{longitude, latitude}}
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// HealthStatus is the health of the database as seen by the driver.
+type HealthStatus struct {
+	// Live is set when the database answered the check, so the process is connected to it.
+	Live bool
+	// Ready is set when the database is live and has a primary available, so it can take writes.
+	Ready bool
+	// Latency is the round trip time of the check.
+	Latency time.Duration
+	// ReplicaSet is the name of the replica set of the database, empty if it isn't part of one.
+	ReplicaSet string
+	// Primary is the address of the primary of the replica set, empty if it has none or isn't part of one.
+	Primary string
+	// PrimaryAvailable is set when the writes can be sent to a primary, or to a standalone server or mongos.
+	PrimaryAvailable bool
+	// PoolSize is the maximum number of connections of the pool, per server. 0 if the driver doesn't report it.
+	PoolSize int
+	// PoolInUse is the number of connections of the pool in use. Only reported by the mongo-go driver.
+	PoolInUse int
+	// CheckedAt is the time of the check.
+	CheckedAt time.Time
+	// Err is the error of the check, if the database couldn't be reached.
+	Err error
+}
+
+// PoolSaturation returns the fraction of the pool in use, between 0 and 1, or 0 if it's unknown.
+func (s HealthStatus) PoolSaturation() float64 {
+	if s.PoolSize <= 0 {
+		return 0
+	}
+
+	return float64(s.PoolInUse) / float64(s.PoolSize)
+}
@@ -0,0 +1,12 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthStatusPoolSaturation(t *testing.T) {
+	assert.Equal(t, 0.25, HealthStatus{PoolSize: 100, PoolInUse: 25}.PoolSaturation())
+	assert.Equal(t, float64(0), HealthStatus{PoolInUse: 25}.PoolSaturation())
+}
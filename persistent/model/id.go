@@ -0,0 +1,32 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ID is the identifier of a row: either an ObjectID or a UUID. Both are sorted by creation time.
+type ID interface {
+	// String returns the hex form of an ObjectID, or the canonical form of a UUID.
+	String() string
+	// Time returns the creation time of the ID.
+	Time() time.Time
+}
+
+var (
+	_ ID = ObjectID("")
+	_ ID = UUID{}
+)
+
+// ParseID parses the string form of an ID: an ObjectID hex or a UUID.
+func ParseID(s string) (ID, error) {
+	if IsObjectIDHex(s) {
+		return ObjectIDHex(s), nil
+	}
+
+	if u, err := ParseUUID(s); err == nil {
+		return u, nil
+	}
+
+	return nil, errors.New("invalid ID: " + s)
+}
@@ -6,4 +6,7 @@ type Index struct {
 	Keys       []DBM
 	IsTTLIndex bool
 	TTL        int
+	// GeoIndex creates a 2dsphere index over the keys, whose fields must hold GeoJSON objects such as GeoPoint.
+	// Their values in Keys are ignored. GetIndexes reports the geospatial keys with the "2dsphere" value.
+	GeoIndex bool
 }
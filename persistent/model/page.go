@@ -0,0 +1,26 @@
+package model
+
+// DefaultPageLimit is the number of rows of a page when PageRequest.Limit isn't set.
+const DefaultPageLimit = 100
+
+// PageRequest requests a page of rows to ListPage.
+type PageRequest struct {
+	// Limit is the maximum number of rows of the page. Defaults to DefaultPageLimit.
+	Limit int
+	// AfterCursor is the NextCursor of the previous page, or empty for the first page.
+	AfterCursor string
+	// Sort is the top level field the rows are sorted by, prefixed with "-" for the descending order.
+	// The rows with the same value are sorted by _id, which is the default field.
+	// The rows missing the field aren't listed once a page is requested after a cursor.
+	Sort string
+}
+
+// PageResult is a page of rows returned by ListPage.
+type PageResult struct {
+	// Items are the rows of the page.
+	Items []DBM
+	// NextCursor is the cursor to request the next page, or empty on the last page.
+	NextCursor string
+	// Total is the number of rows matching the filter, all the pages included.
+	Total int
+}
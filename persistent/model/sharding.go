@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// ShardPeriod is the period of time covered by each shard of a table sharded by date.
+type ShardPeriod string
+
+const (
+	ShardByDay   ShardPeriod = "day"
+	ShardByMonth ShardPeriod = "month"
+	ShardByYear  ShardPeriod = "year"
+)
+
+// DateSharding shards a table by date: its rows are stored in a table per period, named after the table and
+// the start of the period in UTC, e.g. "tyk_analytics_20240131" with ShardByDay, "tyk_analytics_202401" with
+// ShardByMonth and "tyk_analytics_2024" with ShardByYear, according to the time of their Field.
+type DateSharding struct {
+	// Field is the bson name of the time.Time field of the rows their shard is chosen by.
+	Field string
+	// Period is the period of time covered by each shard. Defaults to ShardByDay.
+	Period ShardPeriod
+}
+
+// layout returns the time layout of the shard names suffix.
+func (s DateSharding) layout() string {
+	switch s.Period {
+	case ShardByMonth:
+		return "200601"
+	case ShardByYear:
+		return "2006"
+	default:
+		return "20060102"
+	}
+}
+
+// ShardName returns the name of the shard of table storing the rows of time t.
+func (s DateSharding) ShardName(table string, t time.Time) string {
+	return table + "_" + t.UTC().Format(s.layout())
+}
+
+// ShardStart parses the suffix of a shard name, returning the start of the period of the shard.
+func (s DateSharding) ShardStart(suffix string) (time.Time, bool) {
+	layout := s.layout()
+	if len(suffix) != len(layout) {
+		return time.Time{}, false
+	}
+
+	start, err := time.ParseInLocation(layout, suffix, time.UTC)
+
+	return start, err == nil
+}
+
+// ShardEnd returns the end of the period of the shard starting at start, which is excluded from it.
+func (s DateSharding) ShardEnd(start time.Time) time.Time {
+	switch s.Period {
+	case ShardByMonth:
+		return start.AddDate(0, 1, 0)
+	case ShardByYear:
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
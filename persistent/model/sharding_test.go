@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateSharding(t *testing.T) {
+	at := time.Date(2024, time.January, 31, 23, 30, 0, 0, time.FixedZone("UTC-1", -3600))
+
+	tests := []struct {
+		period ShardPeriod
+		name   string
+		start  time.Time
+		end    time.Time
+	}{
+		{
+			period: ShardByDay,
+			name:   "analytics_20240201",
+			start:  time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			period: ShardByMonth,
+			name:   "analytics_202402",
+			start:  time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			period: ShardByYear,
+			name:   "analytics_2024",
+			start:  time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.period), func(t *testing.T) {
+			sharding := DateSharding{Field: "timestamp", Period: test.period}
+
+			name := sharding.ShardName("analytics", at)
+			assert.Equal(t, test.name, name)
+
+			start, ok := sharding.ShardStart(name[len("analytics_"):])
+			assert.True(t, ok)
+			assert.Equal(t, test.start, start)
+			assert.Equal(t, test.end, sharding.ShardEnd(start))
+		})
+	}
+
+	_, ok := DateSharding{Period: ShardByMonth}.ShardStart("20240201")
+	assert.False(t, ok)
+
+	_, ok = DateSharding{}.ShardStart("2024xx01")
+	assert.False(t, ok)
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// DeletedAtField is the field where the deletion time of the SoftDeletable rows is stored.
+const DeletedAtField = "deleted_at"
+
+// SoftDeletable is implemented by the rows that are marked as deleted instead of removed.
+// Deleting them sets their deleted_at field with the deletion time, and the queries, counts, updates and
+// aggregations over them skip the deleted ones unless the "_with_deleted" key is set to true, in a meta stage
+// of the pipeline for Aggregate. Upserts, as Upsert or FindOneAndUpdate with the Upsert option, restore
+// instead a deleted row matching their query by clearing its deleted_at field. ExistingIDs still reports them,
+// as their ids are taken. Purge removes them permanently.
+// The type must store DeletedAt in the deleted_at field, e.g. with the `bson:"deleted_at"` tag.
+type SoftDeletable interface {
+	DeletedAt() time.Time
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// StorageTag is the struct tag naming the field a struct field is stored under, for all the drivers.
+// It has precedence over the bson and json tags, e.g. `storage:"api_id" bson:"api_id" json:"apiId"`.
+// mgo only reads the bson tags, so with mgo the storage name must be the bson one, which ValidateTags checks.
+// The json tag names the fields that have no storage nor bson tag.
+const StorageTag = "storage"
+
+const errorInvalidTags = "invalid storage tags"
+
+// fieldTags are the tags naming the stored fields, by precedence.
+var fieldTags = []string{StorageTag, "bson", "json"}
+
+// FieldName returns the name the struct field is stored under: the name of its storage tag, or else of its bson
+// tag, or else of its json tag, or else its lowercased name, as the bson encoders do. It returns false if the
+// field isn't stored, because it's unexported or tagged "-".
+func FieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	for _, key := range fieldTags {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		switch name {
+		case "-":
+			return "", false
+		case "":
+			// the tag only sets options, such as omitempty
+			return strings.ToLower(field.Name), true
+		default:
+			return name, true
+		}
+	}
+
+	return strings.ToLower(field.Name), true
+}
+
+// ValidateTags checks that the fields of row, a struct or a pointer to a struct, are stored under the same name
+// by all the drivers. It fails if a field has bson and json names that differ without a storage tag, if its storage
+// name differs from its bson name, if it's named by a storage or json tag without a bson one, or if several fields
+// are stored under the same name.
+func ValidateTags(row interface{}) error {
+	rt := reflect.TypeOf(row)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return errors.New(errorInvalidTags + ": not a struct")
+	}
+
+	var issues []string
+
+	fields := make(map[string]string)
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		storageName, hasStorage := tagName(field, StorageTag)
+		bsonName, hasBSON := tagName(field, "bson")
+		jsonName, hasJSON := tagName(field, "json")
+
+		switch {
+		case hasStorage && hasBSON && storageName != bsonName:
+			issues = append(issues, field.Name+" has the storage name "+storageName+" and the bson name "+bsonName)
+		case !hasStorage && hasBSON && hasJSON && bsonName != jsonName:
+			issues = append(issues, field.Name+" has the bson name "+bsonName+" and the json name "+jsonName+
+				" without a storage tag")
+		case !hasBSON && (hasStorage || hasJSON):
+			issues = append(issues, field.Name+" has no bson name, which mgo requires")
+		}
+
+		name, ok := FieldName(field)
+		if !ok {
+			continue
+		}
+
+		if other, ok := fields[name]; ok {
+			issues = append(issues, field.Name+" and "+other+" are both stored as "+name)
+		}
+
+		fields[name] = field.Name
+	}
+
+	if len(issues) > 0 {
+		return errors.New(errorInvalidTags + ": " + strings.Join(issues, "; "))
+	}
+
+	return nil
+}
+
+// tagName returns the name set by the tag of the field, if any.
+func tagName(field reflect.StructField, key string) (string, bool) {
+	tag, ok := field.Tag.Lookup(key)
+	if !ok {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+
+	return name, name != ""
+}
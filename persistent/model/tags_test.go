@@ -0,0 +1,75 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldName(t *testing.T) {
+	type row struct {
+		APIID    string `storage:"api_id" bson:"apiid" json:"apiId"`
+		OrgID    string `bson:"org_id,omitempty" json:"orgId"`
+		Path     string `json:"path"`
+		Options  string `bson:",omitempty" json:"options"`
+		Method   string
+		Ignored  string `bson:"-"`
+		JSONOnly string `json:"-"`
+		internal string
+	}
+
+	tests := []struct {
+		field string
+		name  string
+		ok    bool
+	}{
+		{field: "APIID", name: "api_id", ok: true},
+		{field: "OrgID", name: "org_id", ok: true},
+		{field: "Path", name: "path", ok: true},
+		{field: "Options", name: "options", ok: true},
+		{field: "Method", name: "method", ok: true},
+		{field: "Ignored"},
+		{field: "JSONOnly"},
+		{field: "internal"},
+	}
+
+	rt := reflect.TypeOf(row{})
+
+	for _, test := range tests {
+		t.Run(test.field, func(t *testing.T) {
+			field, _ := rt.FieldByName(test.field)
+
+			name, ok := FieldName(field)
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.ok, ok)
+		})
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	type valid struct {
+		ID     ObjectID `storage:"_id" bson:"_id" json:"id"`
+		APIID  string   `bson:"api_id" json:"api_id"`
+		Method string
+	}
+
+	assert.Nil(t, ValidateTags(valid{}))
+	assert.Nil(t, ValidateTags(&valid{}))
+
+	type invalid struct {
+		ID     ObjectID `bson:"_id" json:"id"`
+		APIID  string   `storage:"api_id" bson:"apiid"`
+		Path   string   `json:"path"`
+		Method string   `bson:"method"`
+		Verb   string   `bson:"method"`
+	}
+
+	err := ValidateTags(&invalid{})
+	assert.EqualError(t, err, "invalid storage tags: "+
+		"ID has the bson name _id and the json name id without a storage tag; "+
+		"APIID has the storage name api_id and the bson name apiid; Path has no bson name, which mgo requires; "+
+		"Verb and Method are both stored as method")
+
+	assert.EqualError(t, ValidateTags("row"), "invalid storage tags: not a struct")
+}
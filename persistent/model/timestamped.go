@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	// CreatedAtField is the field where the creation time of the Timestamped rows is stored.
+	CreatedAtField = "created_at"
+	// UpdatedAtField is the field where the last update time of the Timestamped rows is stored.
+	UpdatedAtField = "updated_at"
+)
+
+// Timestamped is implemented by the rows whose creation and last update time are kept by the drivers.
+// Insert sets both of them, while Update, BulkUpdate, UpdateAll, Upsert and FindOneAndUpdate set the update time,
+// along with the creation time of the rows inserted by an upsert. The times are in UTC with millisecond precision.
+// The type must store them in the created_at and updated_at fields, e.g. with the `bson:"created_at"` tag.
+type Timestamped interface {
+	SetCreatedAt(createdAt time.Time)
+	SetUpdatedAt(updatedAt time.Time)
+}
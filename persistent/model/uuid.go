@@ -0,0 +1,185 @@
+package model
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// uuidSubtype is the bson binary subtype of the UUIDs.
+const uuidSubtype = 0x04
+
+const errorInvalidUUID = "invalid UUID"
+
+// UUID is a universally unique identifier, which can be used instead of an ObjectID to identify the rows.
+// The mongo drivers store it as a bson binary of the UUID subtype, while SQL drivers store its string form.
+type UUID [16]byte
+
+// NewUUID returns a new version 7 UUID, which starts with its creation time in milliseconds,
+// so the UUIDs are sorted by creation time like the ObjectIDs.
+func NewUUID() UUID {
+	return NewUUIDWithTime(time.Now())
+}
+
+// NewUUIDWithTime returns a new version 7 UUID with the given creation time.
+func NewUUIDWithTime(t time.Time) UUID {
+	var u UUID
+
+	if _, err := rand.Read(u[6:]); err != nil {
+		panic(fmt.Errorf("cannot generate UUID: %w", err))
+	}
+
+	ms := uint64(t.UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(u[2:6], uint32(ms))
+
+	// version 7 and RFC 4122 variant
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+
+	return u
+}
+
+// ParseUUID parses the canonical form of a UUID, e.g. "0190163d-8694-739b-aea5-966c26f8ad91".
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errors.New(errorInvalidUUID + ": " + s)
+	}
+
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return u, errors.New(errorInvalidUUID + ": " + s)
+	}
+
+	return u, nil
+}
+
+// IsUUID reports whether s is the canonical form of a UUID.
+func IsUUID(s string) bool {
+	_, err := ParseUUID(s)
+	return err == nil
+}
+
+// Valid returns true if u isn't the nil UUID.
+func (u UUID) Valid() bool {
+	return u != UUID{}
+}
+
+// Version returns the version of u, e.g. 7 for the UUIDs returned by NewUUID.
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+
+	return string(buf)
+}
+
+// Time returns the creation time of a version 7 UUID, or the zero time for the other versions.
+func (u UUID) Time() time.Time {
+	if u.Version() != 7 {
+		return time.Time{}
+	}
+
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(binary.BigEndian.Uint32(u[2:6]))
+
+	return time.UnixMilli(ms)
+}
+
+func (u UUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
+
+func (u *UUID) UnmarshalJSON(buf []byte) error {
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+
+	if s == "" {
+		*u = UUID{}
+		return nil
+	}
+
+	parsed, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+
+	*u = parsed
+
+	return nil
+}
+
+// GetBSON only used by mgo
+func (u UUID) GetBSON() (interface{}, error) {
+	return bson.Binary{Kind: uuidSubtype, Data: u[:]}, nil
+}
+
+// SetBSON only used by mgo
+func (u *UUID) SetBSON(raw bson.Raw) error {
+	var binary bson.Binary
+	if err := raw.Unmarshal(&binary); err != nil {
+		return err
+	}
+
+	return u.setBytes(binary.Data)
+}
+
+// Value is being used by SQL drivers
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func (u *UUID) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) == len(u) {
+			return u.setBytes(v)
+		}
+
+		return u.Scan(string(v))
+	case string:
+		parsed, err := ParseUUID(v)
+		if err != nil {
+			return err
+		}
+
+		*u = parsed
+
+		return nil
+	default:
+		return fmt.Errorf("failed to scan UUID value: %v", value)
+	}
+}
+
+func (u *UUID) setBytes(data []byte) error {
+	if len(data) != len(u) {
+		return fmt.Errorf("%s: %d bytes", errorInvalidUUID, len(data))
+	}
+
+	copy(u[:], data)
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestNewUUID(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 10, 30, 0, 123000000, time.UTC)
+
+	u := NewUUIDWithTime(createdAt)
+	assert.True(t, u.Valid())
+	assert.Equal(t, 7, u.Version())
+	assert.True(t, createdAt.Equal(u.Time()))
+	assert.Equal(t, byte(0x80), u[8]&0xc0)
+
+	assert.NotEqual(t, NewUUID(), NewUUID())
+	assert.False(t, UUID{}.Valid())
+}
+
+func TestParseUUID(t *testing.T) {
+	u, err := ParseUUID("0190163d-8694-739b-aea5-966c26f8ad91")
+	assert.Nil(t, err)
+	assert.Equal(t, "0190163d-8694-739b-aea5-966c26f8ad91", u.String())
+	assert.Equal(t, 7, u.Version())
+
+	for _, invalid := range []string{"", "0190163d8694739baea5966c26f8ad91", "0190163d-8694-739b-aea5-966c26f8ad9z"} {
+		_, err := ParseUUID(invalid)
+		assert.NotNil(t, err, invalid)
+		assert.False(t, IsUUID(invalid), invalid)
+	}
+}
+
+func TestUUIDEncoding(t *testing.T) {
+	u := NewUUID()
+
+	data, err := json.Marshal(u)
+	assert.Nil(t, err)
+	assert.Equal(t, `"`+u.String()+`"`, string(data))
+
+	var decoded UUID
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, u, decoded)
+
+	type row struct {
+		ID UUID `bson:"_id"`
+	}
+
+	data, err = bson.Marshal(row{ID: u})
+	assert.Nil(t, err)
+
+	var raw bson.M
+	assert.Nil(t, bson.Unmarshal(data, &raw))
+	assert.Equal(t, bson.Binary{Kind: uuidSubtype, Data: u[:]}, raw["_id"])
+
+	var decodedRow row
+	assert.Nil(t, bson.Unmarshal(data, &decodedRow))
+	assert.Equal(t, u, decodedRow.ID)
+
+	value, err := u.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, u.String(), value)
+
+	var scanned UUID
+	assert.Nil(t, scanned.Scan(u.String()))
+	assert.Equal(t, u, scanned)
+	assert.Nil(t, scanned.Scan(u[:]))
+	assert.Equal(t, u, scanned)
+	assert.NotNil(t, scanned.Scan(42))
+}
+
+func TestParseID(t *testing.T) {
+	objectID := NewObjectID()
+	u := NewUUID()
+
+	id, err := ParseID(objectID.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, objectID, id)
+
+	id, err = ParseID(u.String())
+	assert.Nil(t, err)
+	assert.Equal(t, u, id)
+	assert.Equal(t, u.Time(), id.Time())
+
+	_, err = ParseID("invalid")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,242 @@
+// Package query provides a fluent builder of the model.DBM queries accepted by the persistent storage, e.g.
+//
+//	query.Eq("name", "tyk").Gt("age", 10).Sort("-created_at").Limit(50).DBM()
+//
+// so the meta keys and operators don't have to be typed by hand in map literals.
+package query
+
+import (
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+)
+
+// Builder builds a model.DBM query. The zero value is not usable, use New or any of the package level functions.
+type Builder struct {
+	filter model.DBM
+	meta   model.DBM
+}
+
+// New returns an empty Builder, which matches every row.
+func New() *Builder {
+	return &Builder{filter: model.DBM{}, meta: model.DBM{}}
+}
+
+// Eq returns a Builder matching the rows whose field is equal to value.
+func Eq(field string, value interface{}) *Builder {
+	return New().Eq(field, value)
+}
+
+// Ne returns a Builder matching the rows whose field is not equal to value.
+func Ne(field string, value interface{}) *Builder {
+	return New().Ne(field, value)
+}
+
+// Gt returns a Builder matching the rows whose field is greater than value.
+func Gt(field string, value interface{}) *Builder {
+	return New().Gt(field, value)
+}
+
+// Gte returns a Builder matching the rows whose field is greater than or equal to value.
+func Gte(field string, value interface{}) *Builder {
+	return New().Gte(field, value)
+}
+
+// Lt returns a Builder matching the rows whose field is less than value.
+func Lt(field string, value interface{}) *Builder {
+	return New().Lt(field, value)
+}
+
+// Lte returns a Builder matching the rows whose field is less than or equal to value.
+func Lte(field string, value interface{}) *Builder {
+	return New().Lte(field, value)
+}
+
+// In returns a Builder matching the rows whose field is equal to any of values.
+func In(field string, values ...interface{}) *Builder {
+	return New().In(field, values...)
+}
+
+// Nin returns a Builder matching the rows whose field is equal to none of values.
+func Nin(field string, values ...interface{}) *Builder {
+	return New().Nin(field, values...)
+}
+
+// Exists returns a Builder matching the rows which have, or don't have, field.
+func Exists(field string, exists bool) *Builder {
+	return New().Exists(field, exists)
+}
+
+// I returns a Builder matching the rows whose field is equal to value, case-insensitively.
+func I(field, value string) *Builder {
+	return New().I(field, value)
+}
+
+// Text returns a Builder matching the rows whose field contains value, case-insensitively.
+func Text(field, value string) *Builder {
+	return New().Text(field, value)
+}
+
+// Or returns a Builder matching the rows matched by any of queries.
+func Or(queries ...*Builder) *Builder {
+	return New().Or(queries...)
+}
+
+// Eq adds the condition of field being equal to value.
+func (b *Builder) Eq(field string, value interface{}) *Builder {
+	if ops, ok := b.filter[field].(model.DBM); ok {
+		ops["$eq"] = value
+		return b
+	}
+
+	b.filter[field] = value
+
+	return b
+}
+
+// Ne adds the condition of field not being equal to value.
+func (b *Builder) Ne(field string, value interface{}) *Builder {
+	return b.operator(field, "$ne", value)
+}
+
+// Gt adds the condition of field being greater than value.
+func (b *Builder) Gt(field string, value interface{}) *Builder {
+	return b.operator(field, "$gt", value)
+}
+
+// Gte adds the condition of field being greater than or equal to value.
+func (b *Builder) Gte(field string, value interface{}) *Builder {
+	return b.operator(field, "$gte", value)
+}
+
+// Lt adds the condition of field being less than value.
+func (b *Builder) Lt(field string, value interface{}) *Builder {
+	return b.operator(field, "$lt", value)
+}
+
+// Lte adds the condition of field being less than or equal to value.
+func (b *Builder) Lte(field string, value interface{}) *Builder {
+	return b.operator(field, "$lte", value)
+}
+
+// In adds the condition of field being equal to any of values.
+func (b *Builder) In(field string, values ...interface{}) *Builder {
+	return b.operator(field, "$in", values)
+}
+
+// Nin adds the condition of field being equal to none of values.
+func (b *Builder) Nin(field string, values ...interface{}) *Builder {
+	return b.operator(field, "$nin", values)
+}
+
+// Exists adds the condition of the row having, or not having, field.
+func (b *Builder) Exists(field string, exists bool) *Builder {
+	return b.operator(field, "$exists", exists)
+}
+
+// I adds the condition of field being equal to value, case-insensitively.
+func (b *Builder) I(field, value string) *Builder {
+	return b.operator(field, "$i", value)
+}
+
+// Text adds the condition of field containing value, case-insensitively.
+func (b *Builder) Text(field, value string) *Builder {
+	return b.operator(field, "$text", value)
+}
+
+// Or adds the condition of the row being matched by any of queries. Only their conditions are used,
+// the options such as Sort or Limit set in queries are ignored.
+func (b *Builder) Or(queries ...*Builder) *Builder {
+	filters := make([]model.DBM, 0, len(queries))
+	for _, q := range queries {
+		filters = append(filters, q.buildFilter())
+	}
+
+	b.filter["$or"] = filters
+
+	return b
+}
+
+// Sort orders the result by field, descending if it's prefixed with "-".
+func (b *Builder) Sort(field string) *Builder {
+	b.meta["_sort"] = field
+	return b
+}
+
+// Limit limits the result to n rows.
+func (b *Builder) Limit(n int) *Builder {
+	b.meta["_limit"] = n
+	return b
+}
+
+// Offset skips the first n rows of the result.
+func (b *Builder) Offset(n int) *Builder {
+	b.meta["_offset"] = n
+	return b
+}
+
+// Fields restricts the fields of the rows returned to the given ones and their ID.
+func (b *Builder) Fields(fields ...string) *Builder {
+	b.meta["_fields"] = fields
+	return b
+}
+
+// MaxTime limits the time the database can spend running the query.
+func (b *Builder) MaxTime(d time.Duration) *Builder {
+	b.meta["_max_time"] = d
+	return b
+}
+
+// DBM returns the built query. The Builder can keep being used afterwards, as the returned model.DBM is a copy.
+func (b *Builder) DBM() model.DBM {
+	query := b.buildFilter()
+
+	for key, value := range b.meta {
+		query[key] = value
+	}
+
+	return query
+}
+
+// buildFilter returns a copy of the conditions of the query, without its options.
+func (b *Builder) buildFilter() model.DBM {
+	filter := model.DBM{}
+
+	for key, value := range b.filter {
+		if ops, ok := value.(model.DBM); ok {
+			value = copyDBM(ops)
+		}
+
+		filter[key] = value
+	}
+
+	return filter
+}
+
+// operator adds op over field, along with the other operators already set for it.
+func (b *Builder) operator(field, op string, value interface{}) *Builder {
+	ops, ok := b.filter[field].(model.DBM)
+	if !ok {
+		ops = model.DBM{}
+
+		// a previous Eq is kept as an operator, since field can't hold a value and operators at once
+		if eq, found := b.filter[field]; found {
+			ops["$eq"] = eq
+		}
+
+		b.filter[field] = ops
+	}
+
+	ops[op] = value
+
+	return b
+}
+
+func copyDBM(dbm model.DBM) model.DBM {
+	c := make(model.DBM, len(dbm))
+	for key, value := range dbm {
+		c[key] = value
+	}
+
+	return c
+}
@@ -0,0 +1,106 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	tcs := []struct {
+		testName string
+		given    *Builder
+		expected model.DBM
+	}{
+		{
+			testName: "empty query",
+			given:    New(),
+			expected: model.DBM{},
+		},
+		{
+			testName: "equality",
+			given:    Eq("name", "tyk"),
+			expected: model.DBM{"name": "tyk"},
+		},
+		{
+			testName: "operators over different fields",
+			given:    Eq("name", "tyk").Gt("age", 10).Ne("email", "").Exists("country", true),
+			expected: model.DBM{
+				"name":    "tyk",
+				"age":     model.DBM{"$gt": 10},
+				"email":   model.DBM{"$ne": ""},
+				"country": model.DBM{"$exists": true},
+			},
+		},
+		{
+			testName: "operators over the same field are merged",
+			given:    Gte("age", 10).Lt("age", 20),
+			expected: model.DBM{"age": model.DBM{"$gte": 10, "$lt": 20}},
+		},
+		{
+			testName: "equality and operators over the same field",
+			given:    Eq("age", 10).Lte("age", 20),
+			expected: model.DBM{"age": model.DBM{"$eq": 10, "$lte": 20}},
+		},
+		{
+			testName: "operators and equality over the same field",
+			given:    Lte("age", 20).Eq("age", 10),
+			expected: model.DBM{"age": model.DBM{"$eq": 10, "$lte": 20}},
+		},
+		{
+			testName: "in and not in",
+			given:    In("name", "tyk", "gateway").Nin("age", 1, 2),
+			expected: model.DBM{
+				"name": model.DBM{"$in": []interface{}{"tyk", "gateway"}},
+				"age":  model.DBM{"$nin": []interface{}{1, 2}},
+			},
+		},
+		{
+			testName: "case-insensitive operators",
+			given:    I("name", "tyk").Text("email", "tyk.io"),
+			expected: model.DBM{
+				"name":  model.DBM{"$i": "tyk"},
+				"email": model.DBM{"$text": "tyk.io"},
+			},
+		},
+		{
+			testName: "or ignores the options of the queries",
+			given:    Or(Eq("name", "tyk").Limit(1), Lt("age", 10)).Eq("email", "tyk@tyk.io"),
+			expected: model.DBM{
+				"$or":   []model.DBM{{"name": "tyk"}, {"age": model.DBM{"$lt": 10}}},
+				"email": "tyk@tyk.io",
+			},
+		},
+		{
+			testName: "options",
+			given: Eq("name", "tyk").Sort("-created_at").Limit(50).Offset(10).Fields("name", "email").
+				MaxTime(time.Second),
+			expected: model.DBM{
+				"name":      "tyk",
+				"_sort":     "-created_at",
+				"_limit":    50,
+				"_offset":   10,
+				"_fields":   []string{"name", "email"},
+				"_max_time": time.Second,
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.given.DBM())
+		})
+	}
+}
+
+func TestBuilderDBMIsACopy(t *testing.T) {
+	builder := Gt("age", 10)
+	query := builder.DBM()
+
+	builder.Lt("age", 20)
+
+	assert.Equal(t, model.DBM{"age": model.DBM{"$gt": 10}}, query)
+	assert.Equal(t, model.DBM{"age": model.DBM{"$gt": 10, "$lt": 20}}, builder.DBM())
+}
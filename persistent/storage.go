@@ -2,12 +2,27 @@ package persistent
 
 import (
 	"errors"
+	"time"
 
 	"github.com/TykTechnologies/storage/persistent/internal/driver/mongo"
 
 	"github.com/TykTechnologies/storage/persistent/internal/driver/mgo"
 
 	"github.com/TykTechnologies/storage/persistent/internal/types"
+
+	"github.com/TykTechnologies/storage/persistent/internal/helper"
+
+	"github.com/TykTechnologies/storage/persistent/internal/middleware"
+
+	"github.com/TykTechnologies/storage/persistent/internal/sharding"
+
+	"github.com/TykTechnologies/storage/persistent/internal/tenant"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+
+	"github.com/TykTechnologies/storage/persistent/utils"
+
+	storagetypes "github.com/TykTechnologies/storage/types"
 )
 
 const (
@@ -18,17 +33,156 @@ const (
 type (
 	ClientOpts        types.ClientOpts
 	PersistentStorage types.PersistentStorage
+	Logger            types.Logger
+)
+
+type (
+	// RetryOpts is the retry policy set in ClientOpts.Retry.
+	RetryOpts = types.RetryOpts
+	// CircuitBreakerOpts is the circuit breaker set in ClientOpts.CircuitBreaker.
+	CircuitBreakerOpts = types.CircuitBreakerOpts
+	// CircuitState is the state of the circuit breaker given to CircuitBreakerOpts.OnStateChange.
+	CircuitState = types.CircuitState
+	// Credentials are the credentials returned by ClientOpts.CredentialsProvider.
+	Credentials = types.Credentials
+	// CredentialsProvider returns the credentials of the connections, set in ClientOpts.CredentialsProvider.
+	CredentialsProvider = types.CredentialsProvider
+	// CredentialsProviderFunc is a function implementing CredentialsProvider.
+	CredentialsProviderFunc = types.CredentialsProviderFunc
+	// EnvCredentials is a CredentialsProvider reading the credentials from environment variables.
+	EnvCredentials = types.EnvCredentials
+	// FileCredentials is a CredentialsProvider reading the credentials from files, e.g. of a Kubernetes secret.
+	FileCredentials = types.FileCredentials
+	// TLSConfig is the TLS configuration set in ClientOpts.TLS.
+	TLSConfig = storagetypes.TLSConfig
+	// StorageError is the error of a failed operation of the storages set WithStorageErrors, along with its
+	// category, driver and table.
+	StorageError = storagetypes.StorageError
+)
+
+const (
+	CircuitClosed   = types.CircuitClosed
+	CircuitOpen     = types.CircuitOpen
+	CircuitHalfOpen = types.CircuitHalfOpen
 )
 
+type options struct {
+	logger        Logger
+	storageErrors bool
+}
+
+// Option configures the persistent storage returned by NewPersistentStorage.
+type Option func(*options)
+
+// WithLogger sets the logger the storage reports to the failed operations, and the operations slower than
+// ClientOpts.SlowQueryThreshold, along with their table, duration and sanitized filter.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithStorageErrors wraps the errors of the operations of the storage in a *StorageError, along with their
+// category, driver, operation and table. Their message is kept and the errors of the driver can still be checked
+// with errors.Is and errors.As, but no longer by comparing them with == or with mgo.IsDup.
+func WithStorageErrors() Option {
+	return func(o *options) {
+		o.storageErrors = true
+	}
+}
+
+// cosmosThrottlingRetry is the retry policy of the CosmosDB throttled operations, when no other is configured.
+// The throttled operations aren't applied, so the writes which aren't idempotent are retried as well.
+var cosmosThrottlingRetry = RetryOpts{
+	MaxAttempts: 5,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Jitter:      0.2,
+	IsRetryable: utils.IsThrottlingError,
+}
+
+// String returns the options without their credentials, so they can be logged.
+func (opts *ClientOpts) String() string {
+	clientOpts := types.ClientOpts(*opts)
+
+	return clientOpts.String()
+}
+
 // NewPersistentStorage returns a persistent storage object that uses the given driver
-func NewPersistentStorage(opts *ClientOpts) (types.PersistentStorage, error) {
+func NewPersistentStorage(opts *ClientOpts, storageOpts ...Option) (types.PersistentStorage, error) {
+	o := &options{}
+	for _, opt := range storageOpts {
+		opt(o)
+	}
+
 	clientOpts := types.ClientOpts(*opts)
+
+	var (
+		storage types.PersistentStorage
+		err     error
+	)
+
 	switch opts.Type {
 	case OfficialMongo:
-		return mongo.NewMongoDriver(&clientOpts)
+		storage, err = mongo.NewMongoDriver(&clientOpts)
 	case Mgo:
-		return mgo.NewMgoDriver(&clientOpts)
+		storage, err = mgo.NewMgoDriver(&clientOpts)
 	default:
 		return nil, errors.New("invalid driver")
 	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if o.storageErrors {
+		// the errors are categorized first, so the other middlewares can check them as well
+		storage = middleware.Wrap(storage, middleware.Errors(opts.Type))
+	}
+
+	switch {
+	case opts.Retry.MaxAttempts > 1:
+		storage = middleware.Wrap(storage, middleware.Retry(opts.Retry))
+	case helper.IsCosmosDB(opts.ConnectionString):
+		// CosmosDB throttles the requests exceeding the request units of the database, which must be retried
+		storage = middleware.Wrap(storage, middleware.Retry(cosmosThrottlingRetry))
+	}
+
+	if opts.CircuitBreaker.FailureThreshold > 0 {
+		storage = middleware.Wrap(storage, middleware.CircuitBreaker(opts.CircuitBreaker))
+	}
+
+	if len(opts.DateSharding) > 0 {
+		storage = sharding.New(storage, opts.DateSharding, clientOpts.ResolveTableName)
+	}
+
+	if o.logger != nil {
+		threshold := time.Duration(opts.SlowQueryThreshold) * time.Millisecond
+		storage = middleware.Wrap(storage, middleware.Logging(o.logger, opts.Type, threshold))
+	}
+
+	return storage, nil
+}
+
+// RegisterFieldCodec sets the codec used by every driver to encode the given field of the rows of table,
+// identified by its bson name, when they are inserted, imported or updated, including the values set by the $set and
+// $setOnInsert operators, and to decode it when they are queried, iterated, listed or exported.
+// The fields tagged with `encrypt:"true"` can't be stored without a codec, so they are never stored in plain text.
+// The filters, the text searches and Aggregate work with the encoded values.
+func RegisterFieldCodec(table, field string, codec model.FieldCodec) {
+	helper.RegisterFieldCodec(table, field, codec)
+}
+
+// WithTenant returns a persistent storage running the operations of store only on the rows whose tenantField,
+// identified by its bson name, is tenantID:
+//   - the tenant condition is added to every filter and aggregation pipeline, and the filters setting
+//     another one fail with an error;
+//   - the tenant field of the inserted and updated rows is set, and the rows of another tenant are refused,
+//     as well as the updates changing the tenant field;
+//   - the operations that can't be scoped to a tenant, such as Drop, DropTable, DropDatabase, Purge, the imports
+//     and the pipeline stages reading or writing other tables, such as $lookup, fail with an error.
+//
+// The rows must have an exported field of the type of tenantID with the tenantField bson name.
+func WithTenant(store types.PersistentStorage, tenantField string, tenantID interface{}) types.PersistentStorage {
+	return tenant.New(store, tenantField, tenantID)
 }
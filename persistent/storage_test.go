@@ -1,12 +1,35 @@
 package persistent
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/TykTechnologies/storage/persistent/model"
+	storagetypes "github.com/TykTechnologies/storage/types"
 )
 
+type dummyDBObject struct {
+	ID   model.ObjectID `bson:"_id,omitempty"`
+	Name string         `bson:"name"`
+}
+
+func (d *dummyDBObject) GetObjectID() model.ObjectID {
+	return d.ID
+}
+
+func (d *dummyDBObject) SetObjectID(id model.ObjectID) {
+	d.ID = id
+}
+
+func (d *dummyDBObject) TableName() string {
+	return "dummy"
+}
+
 func TestNewPersistentStorage(t *testing.T) {
 	testCases := []string{Mgo, OfficialMongo, "unvalid"}
 
@@ -31,3 +54,31 @@ func TestNewPersistentStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestWithStorageErrors(t *testing.T) {
+	ctx := context.Background()
+	opts := &ClientOpts{
+		ConnectionString: "mongodb://localhost:27017/test",
+		Type:             OfficialMongo,
+	}
+	query := model.DBM{"_id": model.NewObjectID()}
+
+	storage, err := NewPersistentStorage(opts)
+	assert.Nil(t, err)
+
+	// the errors of the driver are returned as they are by default
+	err = storage.Query(ctx, &dummyDBObject{}, &dummyDBObject{}, query)
+	assert.Equal(t, mongo.ErrNoDocuments, err)
+
+	storage, err = NewPersistentStorage(opts, WithStorageErrors())
+	assert.Nil(t, err)
+
+	err = storage.Query(ctx, &dummyDBObject{}, &dummyDBObject{}, query)
+
+	var storageErr *StorageError
+	assert.True(t, errors.As(err, &storageErr))
+	assert.Equal(t, storagetypes.CodeNotFound, storageErr.Code)
+	assert.Equal(t, OfficialMongo, storageErr.Driver)
+	assert.Equal(t, "Query", storageErr.Op)
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+}
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	mongoScheme    = "mongodb"
+	mongoSRVScheme = "mongodb+srv"
+)
+
+// ConnectionInfo is the structured information of a mongo connection string, for diagnostics.
+// The password isn't part of it, so it can be logged safely.
+type ConnectionInfo struct {
+	// Scheme is either "mongodb" or "mongodb+srv".
+	Scheme string
+	// SRV is set for mongodb+srv connection strings, whose only host is resolved through DNS into the hosts
+	// of the cluster.
+	SRV bool
+	// Hosts are the host:port pairs of the connection string, as given.
+	Hosts []string
+	// Username is the decoded user of the credentials, if any.
+	Username string
+	// HasPassword reports whether the credentials include a password.
+	HasPassword bool
+	// Database is the default database of the connection string.
+	Database string
+	// ReplicaSet is the name of the replica set given by the replicaSet option.
+	ReplicaSet string
+	// TLS reports whether the connection uses TLS, given by the tls or ssl options.
+	// It's enabled by default for mongodb+srv connection strings.
+	TLS bool
+	// AuthSource is the database the user is authenticated against: the authSource option, or else
+	// the default database, or "admin". It's empty without credentials.
+	AuthSource string
+	// AuthMechanism is the authMechanism option, if any.
+	AuthMechanism string
+	// Options are the decoded options of the connection string, by their name as given.
+	Options map[string]string
+}
+
+// ParseConnectionString parses and validates a mongo connection string, with either the mongodb:// or
+// the mongodb+srv:// scheme, several hosts and URL-encoded credentials. mongodb+srv connection strings must have
+// a single host without port. No DNS lookup is made.
+func ParseConnectionString(connectionString string) (*ConnectionInfo, error) {
+	scheme, rest, found := strings.Cut(connectionString, "://")
+	if !found || (scheme != mongoScheme && scheme != mongoSRVScheme) {
+		return nil, errors.New("invalid connection string, it must start with mongodb:// or mongodb+srv://")
+	}
+
+	info := &ConnectionInfo{Scheme: scheme, SRV: scheme == mongoSRVScheme, Options: map[string]string{}}
+
+	rest, query, _ := strings.Cut(rest, "?")
+	if err := info.parseOptions(query); err != nil {
+		return nil, err
+	}
+
+	// the credentials end at the last @, so unescaped @ in the password don't split them
+	if i := strings.LastIndex(rest, "@"); i != -1 {
+		if err := info.parseCredentials(rest[:i]); err != nil {
+			return nil, err
+		}
+
+		rest = rest[i+1:]
+	}
+
+	hosts, database, _ := strings.Cut(rest, "/")
+
+	var err error
+	if info.Database, err = url.PathUnescape(database); err != nil {
+		return nil, errors.New("cannot unescape database in connection string")
+	}
+
+	if err := info.parseHosts(hosts); err != nil {
+		return nil, err
+	}
+
+	info.ReplicaSet = info.option("replicaSet")
+	info.AuthMechanism = info.option("authMechanism")
+	info.TLS = info.SRV
+
+	for _, name := range []string{"tls", "ssl"} {
+		if value := info.option(name); value != "" {
+			info.TLS = strings.EqualFold(value, "true")
+		}
+	}
+
+	if info.Username != "" {
+		info.AuthSource = info.option("authSource")
+		if info.AuthSource == "" {
+			info.AuthSource = info.Database
+		}
+
+		if info.AuthSource == "" {
+			info.AuthSource = "admin"
+		}
+	}
+
+	return info, nil
+}
+
+func (info *ConnectionInfo) parseOptions(query string) error {
+	for _, pair := range strings.FieldsFunc(query, func(c rune) bool { return c == '&' || c == ';' }) {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" || value == "" {
+			return errors.New("connection option must be key=value: " + pair)
+		}
+
+		decoded, err := url.QueryUnescape(value)
+		if err != nil {
+			return errors.New("cannot unescape connection option: " + key)
+		}
+
+		info.Options[key] = decoded
+	}
+
+	return nil
+}
+
+func (info *ConnectionInfo) parseCredentials(credentials string) error {
+	user, password, hasPassword := strings.Cut(credentials, ":")
+	if user == "" {
+		return errors.New("credentials must be provided as user:pass@host")
+	}
+
+	var err error
+	if info.Username, err = url.QueryUnescape(user); err != nil {
+		return errors.New("cannot unescape username in connection string")
+	}
+
+	if _, err := url.QueryUnescape(password); err != nil {
+		return errors.New("cannot unescape password in connection string")
+	}
+
+	info.HasPassword = hasPassword && password != ""
+
+	return nil
+}
+
+func (info *ConnectionInfo) parseHosts(hosts string) error {
+	if hosts == "" {
+		return errors.New("connection string must have at least one host")
+	}
+
+	info.Hosts = strings.Split(hosts, ",")
+
+	if info.SRV && len(info.Hosts) > 1 {
+		return errors.New("mongodb+srv connection string must have a single host")
+	}
+
+	for _, host := range info.Hosts {
+		if host == "" {
+			return errors.New("connection string has an empty host")
+		}
+
+		_, port, err := net.SplitHostPort(host)
+		if err != nil {
+			// the host has no port
+			continue
+		}
+
+		if info.SRV {
+			return errors.New("mongodb+srv connection string host cannot have a port: " + host)
+		}
+
+		if number, err := strconv.Atoi(port); err != nil || number <= 0 || number > 65535 {
+			return errors.New("invalid port in connection string host: " + host)
+		}
+	}
+
+	return nil
+}
+
+// option returns the value of the name option, whose name isn't case-sensitive.
+func (info *ConnectionInfo) option(name string) string {
+	for key, value := range info.Options {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/mgo.v2"
+
+	storagetypes "github.com/TykTechnologies/storage/types"
+)
+
+// ErrorCode returns the category of err: of the errors of the mongo, mgo, postgres and redis drivers, which aren't
+// categorized, as well as of the storage errors, such as the ones of the temporal storages.
+func ErrorCode(err error) storagetypes.ErrorCode {
+	switch {
+	case err == nil:
+		return storagetypes.CodeUnknown
+	case IsErrNoRows(err) || errors.Is(err, storagetypes.ErrNotFound):
+		return storagetypes.CodeNotFound
+	case isDuplicateKeyError(err):
+		return storagetypes.CodeDuplicateKey
+	case isTimeoutError(err):
+		return storagetypes.CodeTimeout
+	default:
+		return storagetypes.CodeUnknown
+	}
+}
+
+// IsNotFound reports whether err is an error of a row, key or table not found, whatever the driver is.
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == storagetypes.CodeNotFound
+}
+
+// IsDuplicateKey reports whether err is an error of a row or key that already exists, whatever the driver is.
+func IsDuplicateKey(err error) bool {
+	return ErrorCode(err) == storagetypes.CodeDuplicateKey
+}
+
+// IsTimeout reports whether err is an error of an operation that timed out, whatever the driver is.
+func IsTimeout(err error) bool {
+	return ErrorCode(err) == storagetypes.CodeTimeout
+}
+
+// isTimeoutError reports whether err is an error of an operation that timed out, including the network timeouts
+// and the deadlines of the contexts.
+func isTimeoutError(err error) bool {
+	if mongo.IsTimeout(err) || errors.Is(err, storagetypes.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// duplicateKeySQLState is the SQLSTATE of the unique violations of postgres.
+const duplicateKeySQLState = "23505"
+
+// duplicateKeyErrorMessages are the substrings of the messages of the duplicate key errors which aren't typed,
+// such as the ones of mongo returned as plain strings, of postgres, or of redis when a group already exists.
+var duplicateKeyErrorMessages = []string{
+	"E11000",
+	"duplicate key value violates unique constraint",
+	"BUSYGROUP",
+}
+
+// isDuplicateKeyError reports whether err is raised when inserting a row or key that already exists, whatever
+// the database is: the E11000 errors of mongo and mgo, the unique violations (SQLSTATE 23505) of postgres,
+// the BUSYGROUP errors of redis, and the storage errors of the duplicate key code, such as temperr.GroupExists.
+func isDuplicateKeyError(err error) bool {
+	if mongo.IsDuplicateKeyError(err) || isMgoDuplicateKeyError(err) || errors.Is(err, storagetypes.ErrDuplicateKey) {
+		return true
+	}
+
+	// the errors of the postgres drivers, lib/pq and pgx, expose their SQLSTATE
+	var sqlErr interface{ SQLState() string }
+	if errors.As(err, &sqlErr) && sqlErr.SQLState() == duplicateKeySQLState {
+		return true
+	}
+
+	for _, msg := range duplicateKeyErrorMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isMgoDuplicateKeyError reports whether err is a duplicate key error of mgo. mgo.IsDup doesn't unwrap
+// the errors, so they are unwrapped first.
+func isMgoDuplicateKeyError(err error) bool {
+	var (
+		lastErr  *mgo.LastError
+		queryErr *mgo.QueryError
+		bulkErr  *mgo.BulkError
+	)
+
+	switch {
+	case errors.As(err, &lastErr):
+		return mgo.IsDup(lastErr)
+	case errors.As(err, &queryErr):
+		return mgo.IsDup(queryErr)
+	case errors.As(err, &bulkErr):
+		return mgo.IsDup(bulkErr)
+	default:
+		return false
+	}
+}
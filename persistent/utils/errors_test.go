@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/mgo.v2"
+
+	storagetypes "github.com/TykTechnologies/storage/types"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want storagetypes.ErrorCode
+	}{
+		{name: "nil", err: nil, want: storagetypes.CodeUnknown},
+		{name: "unknown", err: errors.New("error"), want: storagetypes.CodeUnknown},
+		{name: "mgo not found", err: mgo.ErrNotFound, want: storagetypes.CodeNotFound},
+		{name: "mongo no documents", err: mongo.ErrNoDocuments, want: storagetypes.CodeNotFound},
+		{
+			name: "storage not found",
+			err:  storagetypes.NewError(storagetypes.CodeNotFound, "key not found"),
+			want: storagetypes.CodeNotFound,
+		},
+		{
+			name: "mgo duplicate key",
+			err:  fmt.Errorf("insert: %w", &mgo.LastError{Code: 11000, Err: "E11000 duplicate key error"}),
+			want: storagetypes.CodeDuplicateKey,
+		},
+		{
+			name: "mongo duplicate key",
+			err:  mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}},
+			want: storagetypes.CodeDuplicateKey,
+		},
+		{
+			name: "storage duplicate key",
+			err:  storagetypes.NewError(storagetypes.CodeDuplicateKey, "group exists"),
+			want: storagetypes.CodeDuplicateKey,
+		},
+		{name: "context deadline", err: context.DeadlineExceeded, want: storagetypes.CodeTimeout},
+		{
+			name: "wrapped context deadline",
+			err:  fmt.Errorf("query: %w", context.DeadlineExceeded),
+			want: storagetypes.CodeTimeout,
+		},
+		{name: "context canceled", err: context.Canceled, want: storagetypes.CodeUnknown},
+		{name: "network timeout", err: &net.DNSError{IsTimeout: true}, want: storagetypes.CodeTimeout},
+		{
+			name: "storage timeout",
+			err:  storagetypes.NewError(storagetypes.CodeTimeout, "timed out"),
+			want: storagetypes.CodeTimeout,
+		},
+		{
+			name: "wrapped storage error",
+			err:  &storagetypes.StorageError{Code: storagetypes.CodeUnknown, Err: mgo.ErrNotFound},
+			want: storagetypes.CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ErrorCode(tt.err))
+			assert.Equal(t, tt.want == storagetypes.CodeNotFound, IsNotFound(tt.err))
+			assert.Equal(t, tt.want == storagetypes.CodeDuplicateKey, IsDuplicateKey(tt.err))
+			assert.Equal(t, tt.want == storagetypes.CodeTimeout, IsTimeout(tt.err))
+		})
+	}
+}
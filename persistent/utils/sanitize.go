@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the credentials removed from the connection strings and the errors.
+const Redacted = "xxxxx"
+
+var (
+	// secretOptionPattern matches the options holding a secret, in URLs or in key=value connection strings
+	// such as the ones of CosmosDB or postgres, along with their value, which may be quoted
+	secretOptionPattern = regexp.MustCompile(
+		`(?i)\b(password|pwd|passwd|secret|accountkey|sharedaccesskey|token|[a-z]*password)(\s*=\s*)('[^']*'|[^\s&;]*)`)
+	// sessionTokenPattern matches the AWS session token of the authMechanismProperties option
+	sessionTokenPattern = regexp.MustCompile(`(?i)(AWS_SESSION_TOKEN(?::|%3A))([^,&;]*)`)
+)
+
+// SanitizeDSN returns the connection string without its credentials, so it can be logged: the password
+// of a URL, and the value of the options holding a secret, such as password=, AccountKey= or the
+// AWS_SESSION_TOKEN of authMechanismProperties, are replaced with Redacted. The username is kept.
+func SanitizeDSN(dsn string) string {
+	if scheme, rest, found := strings.Cut(dsn, "://"); found {
+		path, query, hasQuery := strings.Cut(rest, "?")
+
+		// the credentials end at the last @, so unescaped @ in the password don't split them
+		if i := strings.LastIndex(path, "@"); i != -1 {
+			if user, _, hasPassword := strings.Cut(path[:i], ":"); hasPassword {
+				path = user + ":" + Redacted + path[i:]
+			}
+		}
+
+		dsn = scheme + "://" + path
+		if hasQuery {
+			dsn += "?" + query
+		}
+	}
+
+	dsn = secretOptionPattern.ReplaceAllString(dsn, "${1}${2}"+Redacted)
+
+	return sessionTokenPattern.ReplaceAllString(dsn, "${1}"+Redacted)
+}
+
+// SanitizeError returns err with the credentials of the connection string dsn removed from its message,
+// as done by SanitizeDSN. It still wraps err, so it can be checked with errors.Is and errors.As.
+func SanitizeError(err error, dsn string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	sanitized := strings.ReplaceAll(msg, dsn, SanitizeDSN(dsn))
+
+	for _, secret := range dsnSecrets(dsn) {
+		sanitized = strings.ReplaceAll(sanitized, secret, Redacted)
+	}
+
+	if sanitized == msg {
+		return err
+	}
+
+	return &sanitizedError{msg: sanitized, err: err}
+}
+
+// dsnSecrets returns the secrets of the connection string, both as given and unescaped.
+func dsnSecrets(dsn string) []string {
+	var secrets []string
+
+	add := func(secret string) {
+		secret = strings.Trim(secret, "'")
+		if secret == "" {
+			return
+		}
+
+		secrets = append(secrets, secret)
+
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			secrets = append(secrets, escaped)
+		}
+
+		if unescaped, err := url.QueryUnescape(secret); err == nil && unescaped != secret {
+			secrets = append(secrets, unescaped)
+		}
+	}
+
+	if _, rest, found := strings.Cut(dsn, "://"); found {
+		path, _, _ := strings.Cut(rest, "?")
+		if i := strings.LastIndex(path, "@"); i != -1 {
+			if _, password, hasPassword := strings.Cut(path[:i], ":"); hasPassword {
+				add(password)
+			}
+		}
+	}
+
+	for _, match := range secretOptionPattern.FindAllStringSubmatch(dsn, -1) {
+		add(match[3])
+	}
+
+	for _, match := range sessionTokenPattern.FindAllStringSubmatch(dsn, -1) {
+		add(match[2])
+	}
+
+	return secrets
+}
+
+// sanitizedError is an error whose message has no credentials, wrapping the original one.
+type sanitizedError struct {
+	msg string
+	err error
+}
+
+func (e *sanitizedError) Error() string {
+	return e.msg
+}
+
+func (e *sanitizedError) Unwrap() error {
+	return e.err
+}
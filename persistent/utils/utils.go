@@ -1,15 +1,20 @@
 package utils
 
 import (
+	"context"
 	"errors"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
 	"gopkg.in/mgo.v2"
 )
 
 type Info struct {
 	Type    DBType
 	Version string
+	// Capabilities are the features supported by the database, given its Type.
+	Capabilities Capabilities `bson:"-"`
 }
 
 type DBType string
@@ -20,6 +25,56 @@ const (
 	CosmosDB      DBType = "cosmosdb"
 )
 
+// Capabilities are the optional features supported by a database.
+type Capabilities struct {
+	// Collation reports whether the collation options of the collections are supported.
+	Collation bool
+	// RetryableWrites reports whether the writes can be retried by the server on transient errors.
+	RetryableWrites bool
+	// ChangeStreams reports whether the changes of the collections can be watched.
+	ChangeStreams bool
+	// Transactions reports whether multi-document transactions are supported.
+	Transactions bool
+	// BackgroundIndexes reports whether the indexes can be built in the background.
+	BackgroundIndexes bool
+	// TTLField is the field the TTL indexes must be built on, if the database requires one.
+	TTLField string
+	// UnsupportedStages are the aggregation stages rejected by the database.
+	UnsupportedStages []string
+}
+
+// Capabilities returns the features supported by the t database. The features of AWS DocumentDB and
+// Azure CosmosDB are the ones supported by all their versions.
+func (t DBType) Capabilities() Capabilities {
+	switch t {
+	case AWSDocumentDB:
+		return Capabilities{
+			ChangeStreams:     true,
+			Transactions:      true,
+			BackgroundIndexes: true,
+			UnsupportedStages: []string{
+				"$bucketAuto", "$densify", "$facet", "$fill", "$graphLookup", "$setWindowFields", "$unionWith",
+			},
+		}
+	case CosmosDB:
+		return Capabilities{
+			ChangeStreams: true,
+			Transactions:  true,
+			// CosmosDB expires the documents from their last modification time, kept in the _ts field.
+			TTLField:          "_ts",
+			UnsupportedStages: []string{"$densify", "$fill", "$setWindowFields", "$unionWith"},
+		}
+	default:
+		return Capabilities{
+			Collation:         true,
+			RetryableWrites:   true,
+			ChangeStreams:     true,
+			Transactions:      true,
+			BackgroundIndexes: true,
+		}
+	}
+}
+
 func IsErrNoRows(err error) bool {
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return true
@@ -31,3 +86,129 @@ func IsErrNoRows(err error) bool {
 
 	return false
 }
+
+// IsErrDuplicateKey is the same as IsDuplicateKey.
+func IsErrDuplicateKey(err error) bool {
+	return IsDuplicateKey(err)
+}
+
+// transientErrorCodes are the codes of the mongo server errors raised while the replica set is electing
+// a new primary, or the server is unreachable or shutting down.
+var transientErrorCodes = []int{6, 7, 89, 91, 189, 9001, 10107, 11600, 11602, 13435, 13436}
+
+// throttlingErrorCode is the code of the errors raised by CosmosDB when the request units of the database
+// are exhausted, the equivalent of the 429 HTTP status.
+const throttlingErrorCode = 16500
+
+// transientErrorMessages are the substrings of the messages of the transient errors of mgo,
+// which aren't typed.
+var transientErrorMessages = []string{
+	"EOF",
+	"Closed explicitly",
+	"reset by peer",
+	"no reachable servers",
+	"i/o timeout",
+	"not master",
+	"server selection error",
+}
+
+// throttlingErrorMessages are the substrings of the messages of the CosmosDB throttling errors.
+var throttlingErrorMessages = []string{"Request rate is large", "TooManyRequests"}
+
+// IsTransientError reports whether err is a transient failure of the database, such as a network error or
+// the election of a new primary, so the operation can be retried. Errors of canceled contexts aren't transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) || IsThrottlingError(err) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		if serverErr.HasErrorLabel("RetryableWriteError") || serverErr.HasErrorLabel("TransientTransactionError") {
+			return true
+		}
+
+		for _, code := range transientErrorCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+
+	for _, msg := range transientErrorMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notPrimaryErrorCodes are the codes of the mongo server errors rejecting a write sent to a server which isn't
+// the primary anymore, before applying it.
+var notPrimaryErrorCodes = []int{10107, 13435, 13436}
+
+// unappliedErrorMessages are the substrings of the messages of the errors raised before sending an operation,
+// when no server can be selected.
+var unappliedErrorMessages = []string{"no reachable servers", "server selection error"}
+
+// IsUnappliedError reports whether err means that the operation was rejected without being applied, so it can be
+// retried even if it isn't idempotent, such as an insert: the CosmosDB throttling errors, the errors of writes sent
+// to a former primary, and the errors raised when no server can be selected to send the operation to.
+// The network errors and timeouts aren't, since the operation may have been applied before the failure.
+func IsUnappliedError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if IsThrottlingError(err) || errors.Is(err, mongo.ErrClientDisconnected) {
+		return true
+	}
+
+	var selectionErr topology.ServerSelectionError
+	if errors.As(err, &selectionErr) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		for _, code := range notPrimaryErrorCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+	}
+
+	for _, msg := range unappliedErrorMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsThrottlingError reports whether err is raised by CosmosDB when the request units of the database are exhausted.
+// The operation can be retried after a short delay.
+func IsThrottlingError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(throttlingErrorCode) {
+		return true
+	}
+
+	for _, msg := range throttlingErrorMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
 	"gopkg.in/mgo.v2"
+
+	storagetypes "github.com/TykTechnologies/storage/types"
 )
 
 func TestIsErrNoRows(t *testing.T) {
@@ -43,3 +48,250 @@ func TestIsErrNoRows(t *testing.T) {
 		})
 	}
 }
+
+// sqlError is an error of a postgres driver, exposing its SQLSTATE as lib/pq and pgx do.
+type sqlError struct {
+	state string
+}
+
+func (e *sqlError) Error() string {
+	return "ERROR: (SQLSTATE " + e.state + ")"
+}
+
+func (e *sqlError) SQLState() string {
+	return e.state
+}
+
+func TestIsErrDuplicateKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input error
+		want  bool
+	}{
+		{
+			name:  "mongo error",
+			input: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}},
+			want:  true,
+		},
+		{
+			name:  "mgo error",
+			input: fmt.Errorf("insert: %w", &mgo.LastError{Code: 11000, Err: "duplicate key"}),
+			want:  true,
+		},
+		{
+			name:  "mongo error message",
+			input: errors.New("E11000 duplicate key error collection: tyk.apis index: _id_"),
+			want:  true,
+		},
+		{
+			name:  "postgres unique violation",
+			input: fmt.Errorf("insert: %w", &sqlError{state: "23505"}),
+			want:  true,
+		},
+		{
+			name:  "postgres other error",
+			input: &sqlError{state: "23503"},
+			want:  false,
+		},
+		{
+			name:  "redis group exists",
+			input: errors.New("BUSYGROUP Consumer Group name already exists"),
+			want:  true,
+		},
+		{
+			name:  "storage error",
+			input: storagetypes.NewError(storagetypes.CodeDuplicateKey, "group already exists"),
+			want:  true,
+		},
+		{
+			name:  "other error",
+			input: errors.New("other error"),
+			want:  false,
+		},
+		{
+			name:  "nil error",
+			input: nil,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsErrDuplicateKey(tt.input); got != tt.want {
+				t.Errorf("IsErrDuplicateKey() = %v, want %v", got, tt.want)
+			}
+
+			if got := ErrorCode(tt.input) == storagetypes.CodeDuplicateKey; got != tt.want {
+				t.Errorf("ErrorCode() = %v, want the duplicate key code: %v", ErrorCode(tt.input), tt.want)
+			}
+		})
+	}
+}
+
+func TestDBTypeCapabilities(t *testing.T) {
+	mongoCapabilities := StandardMongo.Capabilities()
+	if !mongoCapabilities.Collation || !mongoCapabilities.RetryableWrites ||
+		len(mongoCapabilities.UnsupportedStages) > 0 {
+		t.Errorf("StandardMongo.Capabilities() = %+v, want all the features", mongoCapabilities)
+	}
+
+	docDBCapabilities := AWSDocumentDB.Capabilities()
+	if docDBCapabilities.Collation || docDBCapabilities.RetryableWrites || len(docDBCapabilities.UnsupportedStages) == 0 {
+		t.Errorf("AWSDocumentDB.Capabilities() = %+v, want collation, retryable writes and some stages disabled",
+			docDBCapabilities)
+	}
+
+	cosmosCapabilities := CosmosDB.Capabilities()
+	if cosmosCapabilities.BackgroundIndexes || cosmosCapabilities.RetryableWrites ||
+		cosmosCapabilities.TTLField != "_ts" {
+		t.Errorf("CosmosDB.Capabilities() = %+v, want TTL indexes on _ts and background indexes disabled",
+			cosmosCapabilities)
+	}
+}
+
+func TestIsThrottlingError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input error
+		want  bool
+	}{
+		{
+			name:  "mongo throttling error",
+			input: mongo.CommandError{Code: 16500, Message: "Request rate is large"},
+			want:  true,
+		},
+		{
+			name:  "mgo throttling error",
+			input: errors.New("Error=16500, RetryAfterMs=12, Details='Response status code: TooManyRequests'"),
+			want:  true,
+		},
+		{
+			name:  "mongo network error",
+			input: mongo.CommandError{Labels: []string{"NetworkError"}},
+			want:  false,
+		},
+		{
+			name:  "nil error",
+			input: nil,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsThrottlingError(tt.input); got != tt.want {
+				t.Errorf("IsThrottlingError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnappliedError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input error
+		want  bool
+	}{
+		{
+			name:  "throttling error",
+			input: mongo.CommandError{Code: 16500, Message: "Request rate is large"},
+			want:  true,
+		},
+		{
+			name:  "not primary error",
+			input: mongo.CommandError{Code: 10107, Message: "not primary"},
+			want:  true,
+		},
+		{
+			name:  "server selection error",
+			input: fmt.Errorf("insert: %w", topology.ServerSelectionError{Wrapped: context.DeadlineExceeded}),
+			want:  true,
+		},
+		{
+			name:  "mgo no reachable servers",
+			input: errors.New("no reachable servers"),
+			want:  true,
+		},
+		{
+			name:  "network error",
+			input: mongo.CommandError{Labels: []string{"NetworkError"}},
+			want:  false,
+		},
+		{
+			name:  "mgo timeout",
+			input: errors.New("read tcp 127.0.0.1:27017: i/o timeout"),
+			want:  false,
+		},
+		{
+			name:  "nil error",
+			input: nil,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnappliedError(tt.input); got != tt.want {
+				t.Errorf("IsUnappliedError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input error
+		want  bool
+	}{
+		{
+			name:  "mongo network error",
+			input: mongo.CommandError{Labels: []string{"NetworkError"}},
+			want:  true,
+		},
+		{
+			name:  "mongo primary stepped down",
+			input: mongo.CommandError{Code: 189},
+			want:  true,
+		},
+		{
+			name:  "mongo retryable write error",
+			input: mongo.WriteException{Labels: []string{"RetryableWriteError"}},
+			want:  true,
+		},
+		{
+			name:  "cosmosdb throttling error",
+			input: mongo.CommandError{Code: 16500},
+			want:  true,
+		},
+		{
+			name:  "mongo duplicate key error",
+			input: mongo.CommandError{Code: 11000},
+			want:  false,
+		},
+		{
+			name:  "mgo no reachable servers",
+			input: errors.New("no reachable servers"),
+			want:  true,
+		},
+		{
+			name:  "canceled context",
+			input: context.Canceled,
+			want:  false,
+		},
+		{
+			name:  "other error",
+			input: errors.New("other error"),
+			want:  false,
+		},
+		{
+			name:  "nil error",
+			input: nil,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.input); got != tt.want {
+				t.Errorf("IsTransientError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,20 +1,29 @@
 package connector
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
 )
 
-var WithRedisConfig = model.WithRedisConfig
+var (
+	WithRedisConfig  = model.WithRedisConfig
+	WithMemoryConfig = model.WithMemoryConfig
+)
 
-var _ model.Connector = (*redisv9.RedisV9)(nil)
+var (
+	_ model.Connector = (*redisv9.RedisV9)(nil)
+	_ model.Connector = (*memory.Memory)(nil)
+)
 
 // NewConnector returns a new connector based on the type. You have to specify the connector Configuration as an Option.
 func NewConnector(connType string, options ...model.Option) (model.Connector, error) {
 	switch connType {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithOpts(options...)
+	case model.MemoryType:
+		return memory.NewMemoryWithOpts(options...)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
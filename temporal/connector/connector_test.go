@@ -4,8 +4,10 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/TykTechnologies/storage/temporal/model"
@@ -123,3 +125,54 @@ func TestNewConnector_WithOnConnect(t *testing.T) {
 		assert.False(t, called)
 	})
 }
+
+func TestNewConnector_WithPoolOptions(t *testing.T) {
+	tcs := []struct {
+		name                    string
+		opts                    *model.RedisOptions
+		expectedPoolSize        int
+		expectedMaxIdleConns    int
+		expectedConnMaxIdleTime time.Duration
+		expectedConnMaxLifetime time.Duration
+	}{
+		{
+			name:                    "default pool",
+			opts:                    &model.RedisOptions{Addrs: []string{"localhost:6379"}},
+			expectedPoolSize:        500,
+			expectedConnMaxIdleTime: 240 * 5 * time.Second,
+		},
+		{
+			name: "custom pool",
+			opts: &model.RedisOptions{
+				Addrs:           []string{"localhost:6379"},
+				MaxActive:       100,
+				MaxIdle:         10,
+				ConnMaxIdleTime: 60,
+				ConnMaxLifetime: 3600,
+			},
+			expectedPoolSize:        100,
+			expectedMaxIdleConns:    10,
+			expectedConnMaxIdleTime: time.Minute,
+			expectedConnMaxLifetime: time.Hour,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			connector, err := NewConnector(model.RedisV9Type, WithRedisConfig(tc.opts))
+			assert.NoError(t, err)
+
+			var client redis.UniversalClient
+			assert.True(t, connector.As(&client))
+
+			simpleClient, ok := client.(*redis.Client)
+			assert.True(t, ok)
+
+			opts := simpleClient.Options()
+			assert.Equal(t, tc.expectedPoolSize, opts.PoolSize)
+			assert.Equal(t, tc.expectedMaxIdleConns, opts.MaxIdleConns)
+			assert.Equal(t, tc.expectedConnMaxIdleTime, opts.ConnMaxIdleTime)
+			assert.Equal(t, tc.expectedConnMaxLifetime, opts.ConnMaxLifetime)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package flusher
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
@@ -12,6 +13,8 @@ func NewFlusher(conn model.Connector) (Flusher, error) {
 	switch conn.Type() {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
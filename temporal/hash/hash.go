@@ -0,0 +1,27 @@
+package hash
+
+import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
+	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+type Hash = model.Hash
+
+var (
+	_ Hash = (*redisv9.RedisV9)(nil)
+	_ Hash = (*memory.Memory)(nil)
+)
+
+// NewHash returns a new model.Hash storage based on the type of the connector.
+func NewHash(conn model.Connector) (Hash, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
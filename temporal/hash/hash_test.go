@@ -0,0 +1,196 @@
+package hash
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TykTechnologies/storage/temporal/flusher"
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash_SetFields(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	tcs := []struct {
+		name           string
+		key            string
+		fields         map[string]interface{}
+		setup          func(db Hash)
+		expectedAdded  int64
+		expectedFields map[string]string
+		expectedErr    error
+	}{
+		{
+			name:           "new_hash",
+			key:            "session",
+			fields:         map[string]interface{}{"org": "default", "rate": 10},
+			expectedAdded:  2,
+			expectedFields: map[string]string{"org": "default", "rate": "10"},
+		},
+		{
+			name:   "existing_hash",
+			key:    "session",
+			fields: map[string]interface{}{"rate": 20, "per": 60},
+			setup: func(db Hash) {
+				_, err := db.SetFields(context.Background(), "session", map[string]interface{}{"org": "default", "rate": 10})
+				assert.Nil(t, err)
+			},
+			expectedAdded:  1,
+			expectedFields: map[string]string{"org": "default", "rate": "20", "per": "60"},
+		},
+		{
+			name:           "no_fields",
+			key:            "session",
+			expectedFields: map[string]string{},
+		},
+		{
+			name: "not_a_hash",
+			key:  "session",
+			setup: func(db Hash) {
+				kv, ok := db.(model.KeyValue)
+				assert.True(t, ok)
+				assert.Nil(t, kv.Set(context.Background(), "session", "value", 0))
+			},
+			fields:      map[string]interface{}{"org": "default"},
+			expectedErr: temperr.KeyMisstype,
+		},
+		{
+			name:        "empty_key",
+			key:         "",
+			fields:      map[string]interface{}{"org": "default"},
+			expectedErr: temperr.KeyEmpty,
+		},
+	}
+
+	for _, connector := range connectors {
+		for _, tc := range tcs {
+			t.Run(connector.Type()+"_"+tc.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				hash, err := NewHash(connector)
+				assert.Nil(t, err)
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				if tc.setup != nil {
+					tc.setup(hash)
+				}
+
+				added, err := hash.SetFields(ctx, tc.key, tc.fields)
+				assert.Equal(t, tc.expectedErr, err)
+				assert.Equal(t, tc.expectedAdded, added)
+
+				if tc.expectedErr == nil {
+					fields, err := hash.GetAllFields(ctx, tc.key)
+					assert.Nil(t, err)
+					assert.Equal(t, tc.expectedFields, fields)
+				}
+			})
+		}
+	}
+}
+
+func TestHash_DeleteFields(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	tcs := []struct {
+		name            string
+		key             string
+		fields          []string
+		expectedDeleted int64
+		expectedFields  map[string]string
+		expectedErr     error
+	}{
+		{
+			name:            "existing_fields",
+			key:             "session",
+			fields:          []string{"org", "rate", "missing"},
+			expectedDeleted: 2,
+			expectedFields:  map[string]string{"per": "60"},
+		},
+		{
+			name:            "all_fields",
+			key:             "session",
+			fields:          []string{"org", "rate", "per"},
+			expectedDeleted: 3,
+			expectedFields:  map[string]string{},
+		},
+		{
+			name:           "missing_key",
+			key:            "missing",
+			fields:         []string{"org"},
+			expectedFields: map[string]string{},
+		},
+		{
+			name:        "empty_key",
+			key:         "",
+			fields:      []string{"org"},
+			expectedErr: temperr.KeyEmpty,
+		},
+	}
+
+	for _, connector := range connectors {
+		for _, tc := range tcs {
+			t.Run(connector.Type()+"_"+tc.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				hash, err := NewHash(connector)
+				assert.Nil(t, err)
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				_, err = hash.SetFields(ctx, "session", map[string]interface{}{"org": "default", "rate": 10, "per": 60})
+				assert.Nil(t, err)
+
+				deleted, err := hash.DeleteFields(ctx, tc.key, tc.fields...)
+				assert.Equal(t, tc.expectedErr, err)
+				assert.Equal(t, tc.expectedDeleted, deleted)
+
+				if tc.expectedErr == nil {
+					fields, err := hash.GetAllFields(ctx, tc.key)
+					assert.Nil(t, err)
+					assert.Equal(t, tc.expectedFields, fields)
+				}
+			})
+		}
+	}
+}
+
+func TestHash_ClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			hash, err := NewHash(connector)
+			assert.Nil(t, err)
+
+			_, err = hash.SetFields(ctx, "session", map[string]interface{}{"org": "default"})
+			assert.Equal(t, temperr.ClosedConnection, err)
+
+			_, err = hash.GetAllFields(ctx, "session")
+			assert.Equal(t, temperr.ClosedConnection, err)
+
+			_, err = hash.DeleteFields(ctx, "session", "org")
+			assert.Equal(t, temperr.ClosedConnection, err)
+		})
+	}
+}
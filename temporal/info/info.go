@@ -0,0 +1,27 @@
+package info
+
+import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
+	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+type Info = model.Info
+
+var (
+	_ Info = (*redisv9.RedisV9)(nil)
+	_ Info = (*memory.Memory)(nil)
+)
+
+// NewInfo returns a new model.Info based on the type of the connector.
+func NewInfo(conn model.Connector) (Info, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
@@ -0,0 +1,61 @@
+package info
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo_GetConnectionInfo(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			info, err := NewInfo(connector)
+			assert.Nil(t, err)
+
+			connInfo, err := info.GetConnectionInfo(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, connInfo.Type.Capabilities(connInfo.Version), connInfo.Capabilities)
+
+			if connector.Type() == model.MemoryType {
+				assert.Equal(t, model.MemoryBackend, connInfo.Type)
+
+				return
+			}
+
+			assert.NotEmpty(t, connInfo.Version)
+			assert.True(t, connInfo.Capabilities.Scripts)
+		})
+	}
+}
+
+func TestInfo_ClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			info, err := NewInfo(connector)
+			assert.Nil(t, err)
+
+			_, err = info.GetConnectionInfo(ctx)
+			assert.Equal(t, temperr.ClosedConnection, err)
+		})
+	}
+}
+
+func TestNewInfo(t *testing.T) {
+	_, err := NewInfo(&testutil.StubConnector{})
+	assert.Equal(t, temperr.InvalidHandlerType, err)
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+)
+
+// Disconnect closes the store of the connector: its keys are dropped, and its subscriptions closed.
+func (m *Memory) Disconnect(ctx context.Context) error {
+	m.store.close()
+
+	return nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return m.store.do(func() error { return nil })
+}
+
+func (m *Memory) Type() string {
+	return model.MemoryType
+}
+
+// As converts i to driver-specific types.
+// memory connector supports only its internal store, shared with the instances created from the connector.
+// Same concept as https://gocloud.dev/concepts/as/ but for connectors.
+func (m *Memory) As(i interface{}) bool {
+	if x, ok := i.(**store); ok {
+		*x = m.store
+
+		return true
+	}
+
+	return false
+}
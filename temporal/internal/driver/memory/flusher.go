@@ -0,0 +1,12 @@
+package memory
+
+import "context"
+
+// FlushAll deletes all the keys of the store.
+func (m *Memory) FlushAll(ctx context.Context) error {
+	return m.store.do(func() error {
+		m.store.keys = make(map[string]*item)
+
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package memory
+
+// match reports whether key matches the glob-style pattern of the Redis KEYS and SCAN commands:
+// "*" matches any sequence of characters, "?" any character, "[abc]", "[^abc]" and "[a-z]" a character
+// of a set, and "\" escapes the next character.
+func match(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 1 {
+				return true
+			}
+
+			for i := 0; i <= len(key); i++ {
+				if match(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+
+			var matched bool
+
+			matched, pattern = matchClass(pattern[1:], key[0])
+			if !matched {
+				return false
+			}
+
+			key = key[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+
+			key = key[1:]
+			pattern = pattern[1:]
+		}
+	}
+
+	return len(key) == 0
+}
+
+// matchAll is match, except that an empty pattern matches all the keys, as with the Redis client.
+func matchAll(pattern, key string) bool {
+	return pattern == "" || match(pattern, key)
+}
+
+// matchClass reports whether c is in the character class at the start of pattern, right after its "[",
+// and returns the rest of the pattern after the class.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			low, high := pattern[0], pattern[2]
+			if low > high {
+				low, high = high, low
+			}
+
+			matched = matched || (c >= low && c <= high)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+
+	// an unterminated class ends with the pattern, as with Redis
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return matched != negate, pattern
+}
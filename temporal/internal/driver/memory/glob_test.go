@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tcs := []struct {
+		pattern  string
+		key      string
+		expected bool
+	}{
+		{pattern: "*", key: "", expected: true},
+		{pattern: "*", key: "key", expected: true},
+		{pattern: "key", key: "key", expected: true},
+		{pattern: "key", key: "key1", expected: false},
+		{pattern: "key*", key: "key:1", expected: true},
+		{pattern: "*:1", key: "key:1", expected: true},
+		{pattern: "*:1", key: "key:12", expected: false},
+		{pattern: "k**y", key: "key", expected: true},
+		{pattern: "h?llo", key: "hello", expected: true},
+		{pattern: "h?llo", key: "hllo", expected: false},
+		{pattern: "h[ae]llo", key: "hallo", expected: true},
+		{pattern: "h[ae]llo", key: "hillo", expected: false},
+		{pattern: "h[^e]llo", key: "hallo", expected: true},
+		{pattern: "h[^e]llo", key: "hello", expected: false},
+		{pattern: "h[a-b]llo", key: "hbllo", expected: true},
+		{pattern: "h[b-a]llo", key: "hallo", expected: true},
+		{pattern: "h[a-b]llo", key: "hcllo", expected: false},
+		{pattern: `h\*llo`, key: "h*llo", expected: true},
+		{pattern: `h\*llo`, key: "hello", expected: false},
+		{pattern: `h[\]]llo`, key: "h]llo", expected: true},
+		{pattern: "h[el", key: "he", expected: true},
+		{pattern: "/path/*", key: "/path/to/key", expected: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.pattern+"_"+tc.key, func(t *testing.T) {
+			assert.Equal(t, tc.expected, match(tc.pattern, tc.key))
+		})
+	}
+}
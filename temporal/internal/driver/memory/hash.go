@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// SetFields sets the values of fields of a hash, formatted as Redis does.
+func (m *Memory) SetFields(ctx context.Context, key string, fields map[string]interface{}) (int64, error) {
+	if key == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	var added int64
+
+	err := m.store.do(func() error {
+		h, err := m.store.lookupHash(key)
+		if err != nil {
+			return err
+		}
+
+		if h == nil {
+			h = hash{}
+		}
+
+		for field, value := range fields {
+			if _, ok := h[field]; !ok {
+				added++
+			}
+
+			h[field] = format(value)
+		}
+
+		m.store.putAggregate(key, h, len(h))
+
+		return nil
+	})
+
+	return added, err
+}
+
+// GetAllFields returns the fields of a hash along with their values.
+func (m *Memory) GetAllFields(ctx context.Context, key string) (map[string]string, error) {
+	if key == "" {
+		return nil, temperr.KeyEmpty
+	}
+
+	fields := make(map[string]string)
+
+	err := m.store.do(func() error {
+		h, err := m.store.lookupHash(key)
+		for field, value := range h {
+			fields[field] = value
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// DeleteFields removes fields of a hash.
+func (m *Memory) DeleteFields(ctx context.Context, key string, fields ...string) (int64, error) {
+	if key == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+
+	err := m.store.do(func() error {
+		h, err := m.store.lookupHash(key)
+		if err != nil || h == nil {
+			return err
+		}
+
+		for _, field := range fields {
+			if _, ok := h[field]; ok {
+				delete(h, field)
+				deleted++
+			}
+		}
+
+		m.store.putAggregate(key, h, len(h))
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// format formats a value as Redis stores it, e.g. a bool as "1" or "0".
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+
+		return "0"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+)
+
+// GetConnectionInfo returns the information of the memory connector, which has no version.
+func (m *Memory) GetConnectionInfo(ctx context.Context) (model.ConnectionInfo, error) {
+	if err := m.Ping(ctx); err != nil {
+		return model.ConnectionInfo{}, err
+	}
+
+	return model.ConnectionInfo{
+		Type:         model.MemoryBackend,
+		Capabilities: model.MemoryBackend.Capabilities(""),
+	}, nil
+}
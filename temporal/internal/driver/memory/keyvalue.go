@@ -0,0 +1,439 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// node is the name of the single node of the cursors of the scans.
+const node = "memory"
+
+// defaultScanCount is the number of keys scanned per page if the count isn't set, as with Redis.
+const defaultScanCount = 10
+
+// Get retrieves the value for a given key
+func (m *Memory) Get(ctx context.Context, key string) (string, error) {
+	if key == "" {
+		return "", temperr.KeyEmpty
+	}
+
+	var value string
+
+	err := m.store.do(func() error {
+		var err error
+
+		value, err = m.store.get(key)
+
+		return err
+	})
+
+	return value, err
+}
+
+// Set sets the string value of a key. The key doesn't expire if ttl is 0, and keeps its ttl if ttl is negative.
+func (m *Memory) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	return m.store.do(func() error {
+		m.store.set(key, value, ttl)
+
+		return nil
+	})
+}
+
+// SetIfNotExist sets the string value of a key if the key does not exist.
+func (m *Memory) SetIfNotExist(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	if key == "" {
+		return false, temperr.KeyEmpty
+	}
+
+	var set bool
+
+	err := m.store.do(func() error {
+		set = m.store.setIfNotExist(key, value, expiration)
+
+		return nil
+	})
+
+	return set, err
+}
+
+// Delete removes the specified key
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	return m.store.do(func() error {
+		m.store.delete(key)
+
+		return nil
+	})
+}
+
+// Increment atomically increments the integer value of a key by one
+func (m *Memory) Increment(ctx context.Context, key string) (int64, error) {
+	if key == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	var value int64
+
+	err := m.store.do(func() error {
+		var err error
+
+		value, err = m.store.incrementBy(key, 1)
+
+		return err
+	})
+
+	return value, err
+}
+
+// Decrement atomically decrements the integer value of a key by one
+func (m *Memory) Decrement(ctx context.Context, key string) (int64, error) {
+	if key == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	var value int64
+
+	err := m.store.do(func() error {
+		var err error
+
+		value, err = m.store.incrementBy(key, -1)
+
+		return err
+	})
+
+	return value, err
+}
+
+// Exists checks if a key exists
+func (m *Memory) Exists(ctx context.Context, key string) (bool, error) {
+	if key == "" {
+		return false, temperr.KeyEmpty
+	}
+
+	var exists bool
+
+	err := m.store.do(func() error {
+		exists = m.store.lookup(key) != nil
+
+		return nil
+	})
+
+	return exists, err
+}
+
+// Expire sets a timeout on key. As with Redis, the key is removed if ttl isn't positive.
+func (m *Memory) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	return m.store.do(func() error {
+		m.store.expireKey(key, ttl)
+
+		return nil
+	})
+}
+
+// TTL returns the remaining time to live of a key in seconds, -1 if the key doesn't expire,
+// or -2 if it doesn't exist.
+func (m *Memory) TTL(ctx context.Context, key string) (int64, error) {
+	if key == "" {
+		return -2, temperr.KeyEmpty
+	}
+
+	var ttl int64
+
+	err := m.store.do(func() error {
+		ttl = m.store.ttl(key)
+
+		return nil
+	})
+
+	return ttl, err
+}
+
+// DeleteKeys removes the specified keys. A key is ignored if it does not exist
+func (m *Memory) DeleteKeys(ctx context.Context, keys []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, temperr.KeyEmpty
+	}
+
+	var deleted int64
+
+	err := m.store.do(func() error {
+		for _, key := range keys {
+			deleted += m.store.delete(key)
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// DeleteScanMatch deletes all keys matching the given pattern
+func (m *Memory) DeleteScanMatch(ctx context.Context, pattern string) (int64, error) {
+	var deleted int64
+
+	err := m.store.do(func() error {
+		for _, key := range m.store.sortedKeys(pattern) {
+			deleted += m.store.delete(key)
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// Keys returns all keys matching the given pattern
+func (m *Memory) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+
+	err := m.store.do(func() error {
+		keys = m.store.sortedKeys(pattern)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetMulti returns the values of all specified keys, with nil for the keys which don't exist or aren't strings
+func (m *Memory) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
+	values := make([]interface{}, len(keys))
+
+	err := m.store.do(func() error {
+		for i, key := range keys {
+			if value, ok, err := m.store.lookupString(key); ok && err == nil {
+				values[i] = value
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// GetKeysAndValuesWithFilter returns all keys and their values for a given pattern
+func (m *Memory) GetKeysAndValuesWithFilter(ctx context.Context, pattern string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	err := m.store.do(func() error {
+		for _, key := range m.store.sortedKeys(pattern) {
+			result[key] = nil
+
+			if value, ok, err := m.store.lookupString(key); ok && err == nil {
+				result[key] = value
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetKeysWithOpts performs a paginated scan of the keys, with the cursor of the single node under "memory".
+func (m *Memory) GetKeysWithOpts(ctx context.Context,
+	searchStr string,
+	cursor map[string]uint64,
+	count int64,
+) ([]string, map[string]uint64, bool, error) {
+	if cursor == nil {
+		cursor = make(map[string]uint64)
+	}
+
+	var keys []string
+
+	err := m.store.do(func() error {
+		var next uint64
+
+		keys, next = m.store.scan(searchStr, cursor[node], count)
+		cursor[node] = next
+
+		return nil
+	})
+	if err != nil {
+		return nil, cursor, false, err
+	}
+
+	return keys, cursor, cursor[node] != 0, nil
+}
+
+// ScanKeys returns a page of the keys matching pattern, with the position of the single node under "memory".
+// The keys are scanned by creation, so the keys present during the whole iteration are returned exactly once.
+func (m *Memory) ScanKeys(ctx context.Context,
+	pattern string,
+	cursor model.Cursor,
+	count int64,
+) ([]string, model.Cursor, error) {
+	if cursor.Done() {
+		return nil, cursor, nil
+	}
+
+	var keys []string
+
+	next := model.Cursor{Nodes: make(map[string]uint64)}
+
+	err := m.store.do(func() error {
+		keys, next.Nodes[node] = m.store.scan(pattern, cursor.Nodes[node], count)
+
+		return nil
+	})
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	return keys, next, nil
+}
+
+func (s *store) get(key string) (string, error) {
+	value, ok, err := s.lookupString(key)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", temperr.KeyNotFound
+	}
+
+	return value, nil
+}
+
+func (s *store) set(key, value string, ttl time.Duration) {
+	if ttl < 0 {
+		s.put(key, value)
+
+		return
+	}
+
+	s.expire(s.put(key, value), ttl)
+}
+
+func (s *store) setIfNotExist(key, value string, ttl time.Duration) bool {
+	if s.lookup(key) != nil {
+		return false
+	}
+
+	s.expire(s.put(key, value), ttl)
+
+	return true
+}
+
+// delete removes key, returning 1 if it existed.
+func (s *store) delete(key string) int64 {
+	if s.lookup(key) == nil {
+		return 0
+	}
+
+	delete(s.keys, key)
+
+	return 1
+}
+
+// incrementBy increments the integer value of key, keeping its ttl. A missing key counts as 0.
+func (s *store) incrementBy(key string, increment int64) (int64, error) {
+	value, ok, err := s.lookupString(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+
+	if ok {
+		n, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, temperr.KeyMisstype
+		}
+	}
+
+	n += increment
+	s.put(key, strconv.FormatInt(n, 10))
+
+	return n, nil
+}
+
+func (s *store) expireKey(key string, ttl time.Duration) {
+	it := s.lookup(key)
+	if it == nil {
+		return
+	}
+
+	if ttl <= 0 {
+		delete(s.keys, key)
+
+		return
+	}
+
+	s.expire(it, ttl)
+}
+
+func (s *store) ttl(key string) int64 {
+	it := s.lookup(key)
+
+	switch {
+	case it == nil:
+		return -2
+	case it.expiresAt.IsZero():
+		return -1
+	default:
+		// rounded to the nearest second, as with Redis
+		return (it.expiresAt.Sub(s.nowFunc()).Milliseconds() + 500) / 1000
+	}
+}
+
+// scan returns the keys matching pattern among the count keys created from position, along with the position
+// of the next page, which is 0 once all the keys were scanned. The positions are the creation sequence numbers
+// of the keys, which start at 1.
+func (s *store) scan(pattern string, position uint64, count int64) ([]string, uint64) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	all := s.sortedKeys("")
+	start := sort.Search(len(all), func(i int) bool {
+		return s.keys[all[i]].seq >= position
+	})
+
+	end := start + int(count)
+	if end > len(all) {
+		end = len(all)
+	}
+
+	keys := []string{}
+
+	for _, key := range all[start:end] {
+		if matchAll(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	if end == len(all) {
+		return keys, 0
+	}
+
+	return keys, s.keys[all[end]].seq
+}
@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+)
+
+// Remove removes the first count occurrences of element from the list stored at key, from the head to the tail
+// if count is positive, from the tail to the head if it's negative, or all of them if it's 0.
+// It returns the number of elements removed.
+func (m *Memory) Remove(ctx context.Context, key string, count int64, element interface{}) (int64, error) {
+	var removed int64
+
+	err := m.store.do(func() error {
+		l, err := m.store.lookupList(key)
+		if err != nil || l == nil {
+			return err
+		}
+
+		value := format(element)
+		kept := make(list, 0, len(l))
+
+		if count < 0 {
+			for i := len(l) - 1; i >= 0; i-- {
+				if l[i] == value && removed < -count {
+					removed++
+
+					continue
+				}
+
+				kept = append(list{l[i]}, kept...)
+			}
+		} else {
+			for _, v := range l {
+				if v == value && (count == 0 || removed < count) {
+					removed++
+
+					continue
+				}
+
+				kept = append(kept, v)
+			}
+		}
+
+		m.store.putAggregate(key, kept, len(kept))
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// Range returns the elements of the list stored at key from start to stop, both included.
+// As with Redis, negative offsets start from the end of the list, -1 being its last element.
+func (m *Memory) Range(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	var values []string
+
+	err := m.store.do(func() error {
+		l, err := m.store.lookupList(key)
+		values = l.slice(start, stop)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Length returns the length of the list stored at key, 0 if the key does not exist.
+// It errors if the key is not a list.
+func (m *Memory) Length(ctx context.Context, key string) (int64, error) {
+	var length int64
+
+	err := m.store.do(func() error {
+		l, err := m.store.lookupList(key)
+		length = int64(len(l))
+
+		return err
+	})
+
+	return length, err
+}
+
+// Prepend inserts the values at the head of the list stored at key, one after the other, creating the list if
+// the key does not exist. The values are always inserted at once, so pipelined is ignored.
+func (m *Memory) Prepend(ctx context.Context, pipelined bool, key string, values ...[]byte) error {
+	return m.store.do(func() error {
+		l, err := m.store.lookupList(key)
+		if err != nil {
+			return err
+		}
+
+		prepended := make(list, 0, len(l)+len(values))
+		for i := len(values) - 1; i >= 0; i-- {
+			prepended = append(prepended, string(values[i]))
+		}
+
+		prepended = append(prepended, l...)
+		m.store.putAggregate(key, prepended, len(prepended))
+
+		return nil
+	})
+}
+
+// Append inserts the values at the tail of the list stored at key, creating the list if the key does not exist.
+// The values are always inserted at once, so pipelined is ignored.
+func (m *Memory) Append(ctx context.Context, pipelined bool, key string, values ...[]byte) error {
+	return m.store.do(func() error {
+		l, err := m.store.lookupList(key)
+		if err != nil {
+			return err
+		}
+
+		for _, value := range values {
+			l = append(l, string(value))
+		}
+
+		m.store.putAggregate(key, l, len(l))
+
+		return nil
+	})
+}
+
+// Pop removes and returns the first stop elements of the list stored at key.
+// If stop is -1, all the elements of the list are removed and returned.
+func (m *Memory) Pop(ctx context.Context, key string, stop int64) ([]string, error) {
+	var values []string
+
+	err := m.store.do(func() error {
+		l, err := m.store.lookupList(key)
+		if err != nil {
+			return err
+		}
+
+		search := stop
+		if search > 0 {
+			search--
+		}
+
+		values = l.slice(0, search)
+
+		if stop == -1 {
+			delete(m.store.keys, key)
+
+			return nil
+		}
+
+		trimmed := l.slice(stop, -1)
+		m.store.putAggregate(key, list(trimmed), len(trimmed))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// slice returns a copy of the elements of the list from start to stop, both included, as LRANGE does.
+func (l list) slice(start, stop int64) []string {
+	length := int64(len(l))
+
+	if start < 0 {
+		start += length
+	}
+
+	if stop < 0 {
+		stop += length
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	if stop >= length {
+		stop = length - 1
+	}
+
+	if start > stop {
+		return []string{}
+	}
+
+	return append([]string{}, l[start:stop+1]...)
+}
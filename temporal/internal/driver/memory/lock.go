@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// Acquire acquires a lock by setting the key of the lock to a random token if the key does not exist.
+func (m *Memory) Acquire(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	if err := validateLock(name, ttl); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = m.store.do(func() error {
+		if !m.store.setIfNotExist(name, token, ttl) {
+			return temperr.LockNotAcquired
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Refresh extends a lock if the key of the lock is still set to the token.
+func (m *Memory) Refresh(ctx context.Context, name, token string, ttl time.Duration) error {
+	if err := validateLock(name, ttl); err != nil {
+		return err
+	}
+
+	return m.store.do(func() error {
+		if !m.store.holds(name, token) {
+			return temperr.LockNotHeld
+		}
+
+		m.store.expire(m.store.lookup(name), ttl)
+
+		return nil
+	})
+}
+
+// Release releases a lock if the key of the lock is still set to the token.
+func (m *Memory) Release(ctx context.Context, name, token string) error {
+	if name == "" {
+		return temperr.KeyEmpty
+	}
+
+	return m.store.do(func() error {
+		if !m.store.holds(name, token) {
+			return temperr.LockNotHeld
+		}
+
+		m.store.delete(name)
+
+		return nil
+	})
+}
+
+// holds returns true if the lock of the given name is held with token.
+func (s *store) holds(name, token string) bool {
+	value, ok, err := s.lookupString(name)
+
+	return ok && err == nil && value == token
+}
+
+// randomToken returns a random token, e.g. identifying the holder of a lock.
+func randomToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+func validateLock(name string, ttl time.Duration) error {
+	if name == "" {
+		return temperr.KeyEmpty
+	}
+
+	if ttl <= 0 {
+		return temperr.InvalidTTL
+	}
+
+	return nil
+}
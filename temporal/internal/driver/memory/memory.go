@@ -0,0 +1,281 @@
+// Package memory implements the temporal storage in memory, with the semantics of the redisv9 driver,
+// e.g. for single-node installations and tests without Redis. The keys are lost when the process stops.
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+const defaultCleanupInterval = time.Minute
+
+// Memory is the in-memory temporal storage. The instances created with NewMemoryWithConnection share the keys
+// of their connector.
+type Memory struct {
+	store *store
+}
+
+// NewMemoryWithOpts returns a new Memory instance, holding its own keys.
+func NewMemoryWithOpts(options ...model.Option) (*Memory, error) {
+	baseConfig := &model.BaseConfig{}
+	for _, opt := range options {
+		opt.Apply(baseConfig)
+	}
+
+	cleanupInterval := defaultCleanupInterval
+	if baseConfig.MemoryConfig != nil && baseConfig.MemoryConfig.CleanupInterval > 0 {
+		cleanupInterval = time.Duration(baseConfig.MemoryConfig.CleanupInterval) * time.Second
+	}
+
+	s := newStore()
+
+	go s.cleanup(cleanupInterval)
+
+	return &Memory{store: s}, nil
+}
+
+// NewMemoryWithConnection returns a new Memory instance sharing the keys of the connector.
+func NewMemoryWithConnection(conn model.Connector) (*Memory, error) {
+	var s *store
+	if conn == nil || !conn.As(&s) {
+		return nil, temperr.InvalidConnector
+	}
+
+	return &Memory{store: s}, nil
+}
+
+// item is the value of a key: a string, a list, a set, a sorted set or a hash.
+type item struct {
+	value interface{}
+	// expiresAt is zero if the key doesn't expire
+	expiresAt time.Time
+	// seq orders the keys by creation, so the scans can resume where they stopped
+	seq uint64
+}
+
+type (
+	list      []string
+	set       map[string]struct{}
+	sortedSet map[string]float64
+	hash      map[string]string
+)
+
+// store holds the keys and the subscriptions of a connector.
+type store struct {
+	mu      sync.Mutex
+	keys    map[string]*item
+	seq     uint64
+	closed  bool
+	stop    chan struct{}
+	pubsub  map[*subscription]struct{}
+	nowFunc func() time.Time
+}
+
+func newStore() *store {
+	return &store{
+		keys:    make(map[string]*item),
+		stop:    make(chan struct{}),
+		pubsub:  make(map[*subscription]struct{}),
+		nowFunc: time.Now,
+	}
+}
+
+// do runs fn with the lock of the store held, or returns temperr.ClosedConnection if the store is closed.
+func (s *store) do(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return temperr.ClosedConnection
+	}
+
+	return fn()
+}
+
+// cleanup removes the expired keys every interval, until the store is closed.
+func (s *store) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+
+			for key := range s.keys {
+				s.lookup(key)
+			}
+
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *store) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	s.keys = make(map[string]*item)
+	close(s.stop)
+
+	for sub := range s.pubsub {
+		sub.close()
+	}
+}
+
+// lookup returns the item of key, or nil if the key doesn't exist or expired, removing it.
+func (s *store) lookup(key string) *item {
+	it, ok := s.keys[key]
+	if !ok {
+		return nil
+	}
+
+	if !it.expiresAt.IsZero() && !s.nowFunc().Before(it.expiresAt) {
+		delete(s.keys, key)
+
+		return nil
+	}
+
+	return it
+}
+
+// put sets the value of key, keeping its expiration if the key exists.
+func (s *store) put(key string, value interface{}) *item {
+	if it := s.lookup(key); it != nil {
+		it.value = value
+
+		return it
+	}
+
+	s.seq++
+	it := &item{value: value, seq: s.seq}
+	s.keys[key] = it
+
+	return it
+}
+
+// expire sets the expiration of the item to ttl from now, or removes its expiration if ttl is 0.
+func (s *store) expire(it *item, ttl time.Duration) {
+	if ttl > 0 {
+		it.expiresAt = s.nowFunc().Add(ttl)
+
+		return
+	}
+
+	it.expiresAt = time.Time{}
+}
+
+// lookupString returns the string value of key, false if the key doesn't exist,
+// or temperr.KeyMisstype if it isn't a string.
+func (s *store) lookupString(key string) (string, bool, error) {
+	it := s.lookup(key)
+	if it == nil {
+		return "", false, nil
+	}
+
+	value, ok := it.value.(string)
+	if !ok {
+		return "", false, temperr.KeyMisstype
+	}
+
+	return value, true, nil
+}
+
+// The lookups of the aggregates return nil if the key doesn't exist, or temperr.KeyMisstype if its value is of
+// another type. As with Redis, the empty aggregates are removed, so they never exist.
+
+func (s *store) lookupList(key string) (list, error) {
+	it := s.lookup(key)
+	if it == nil {
+		return nil, nil
+	}
+
+	value, ok := it.value.(list)
+	if !ok {
+		return nil, temperr.KeyMisstype
+	}
+
+	return value, nil
+}
+
+func (s *store) lookupSet(key string) (set, error) {
+	it := s.lookup(key)
+	if it == nil {
+		return nil, nil
+	}
+
+	value, ok := it.value.(set)
+	if !ok {
+		return nil, temperr.KeyMisstype
+	}
+
+	return value, nil
+}
+
+func (s *store) lookupSortedSet(key string) (sortedSet, error) {
+	it := s.lookup(key)
+	if it == nil {
+		return nil, nil
+	}
+
+	value, ok := it.value.(sortedSet)
+	if !ok {
+		return nil, temperr.KeyMisstype
+	}
+
+	return value, nil
+}
+
+func (s *store) lookupHash(key string) (hash, error) {
+	it := s.lookup(key)
+	if it == nil {
+		return nil, nil
+	}
+
+	value, ok := it.value.(hash)
+	if !ok {
+		return nil, temperr.KeyMisstype
+	}
+
+	return value, nil
+}
+
+// putAggregate sets the aggregate value of key, or removes the key if the aggregate is empty.
+func (s *store) putAggregate(key string, value interface{}, size int) {
+	if size == 0 {
+		delete(s.keys, key)
+
+		return
+	}
+
+	s.put(key, value)
+}
+
+// sortedKeys returns the keys which didn't expire matching pattern, or all of them if pattern is empty,
+// ordered by creation.
+func (s *store) sortedKeys(pattern string) []string {
+	keys := make([]string, 0, len(s.keys))
+
+	for key := range s.keys {
+		if s.lookup(key) != nil && matchAll(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return s.keys[keys[i]].seq < s.keys[keys[j]].seq
+	})
+
+	return keys
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMemory returns a Memory whose clock is set by the returned function.
+func newTestMemory(t *testing.T) (*Memory, func(time.Duration)) {
+	t.Helper()
+
+	m, err := NewMemoryWithOpts()
+	assert.Nil(t, err)
+
+	now := time.Now()
+	m.store.nowFunc = func() time.Time { return now }
+
+	return m, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemory_Expiration(t *testing.T) {
+	ctx := context.Background()
+	m, advance := newTestMemory(t)
+
+	defer func() {
+		assert.Nil(t, m.Disconnect(ctx))
+	}()
+
+	assert.Nil(t, m.Set(ctx, "key", "value", 2*time.Second))
+	assert.Nil(t, m.Set(ctx, "persistent", "value", 0))
+
+	ttl, err := m.TTL(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), ttl)
+
+	// the ttl of a key is kept by the increments
+	assert.Nil(t, m.Set(ctx, "counter", "1", 2*time.Second))
+
+	_, err = m.Increment(ctx, "counter")
+	assert.Nil(t, err)
+
+	advance(2 * time.Second)
+
+	_, err = m.Get(ctx, "key")
+	assert.Equal(t, temperr.KeyNotFound, err)
+
+	_, err = m.Get(ctx, "counter")
+	assert.Equal(t, temperr.KeyNotFound, err)
+
+	keys, err := m.Keys(ctx, "*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"persistent"}, keys)
+}
+
+func TestMemory_Disconnect(t *testing.T) {
+	ctx := context.Background()
+	m, _ := newTestMemory(t)
+
+	sub := m.Subscribe(ctx, "channel")
+
+	_, err := sub.Receive(ctx)
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.Disconnect(ctx))
+
+	_, err = sub.Receive(ctx)
+	assert.Equal(t, temperr.ClosedConnection, err)
+
+	assert.Equal(t, temperr.ClosedConnection, m.Ping(ctx))
+
+	_, err = m.Get(ctx, "key")
+	assert.Equal(t, temperr.ClosedConnection, err)
+}
+
+func TestParseScore(t *testing.T) {
+	tcs := []struct {
+		bound     string
+		score     float64
+		exclusive bool
+		err       error
+	}{
+		{bound: "1.5", score: 1.5},
+		{bound: "(1.5", score: 1.5, exclusive: true},
+		{bound: "-inf", score: math.Inf(-1)},
+		{bound: "+inf", score: math.Inf(1)},
+		{bound: "(+inf", score: math.Inf(1), exclusive: true},
+		{bound: "score", err: errInvalidScore},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.bound, func(t *testing.T) {
+			score, exclusive, err := parseScore(tc.bound)
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.score, score)
+			assert.Equal(t, tc.exclusive, exclusive)
+		})
+	}
+}
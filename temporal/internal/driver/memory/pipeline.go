@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// pipeline implements model.Pipeline by running its commands on the store once the pipeline is executed.
+type pipeline struct {
+	store    *store
+	commands []pipelineCommand
+}
+
+type pipelineCommand struct {
+	result *model.PipelineResult
+	run    func(s *store) (interface{}, error)
+}
+
+// Pipeline returns a new model.Pipeline. Its commands are executed atomically, as with TxPipeline.
+func (m *Memory) Pipeline() model.Pipeline {
+	return &pipeline{store: m.store}
+}
+
+// TxPipeline returns a new model.Pipeline executing its commands atomically.
+func (m *Memory) TxPipeline() model.Pipeline {
+	return &pipeline{store: m.store}
+}
+
+func (p *pipeline) Get(key string) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		value, err := s.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return value, nil
+	})
+}
+
+func (p *pipeline) Set(key, value string, ttl time.Duration) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		s.set(key, value, ttl)
+
+		return nil, nil
+	})
+}
+
+func (p *pipeline) SetIfNotExist(key, value string, expiration time.Duration) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		return s.setIfNotExist(key, value, expiration), nil
+	})
+}
+
+func (p *pipeline) Delete(key string) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		s.delete(key)
+
+		return nil, nil
+	})
+}
+
+func (p *pipeline) Increment(key string) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		return s.incrementBy(key, 1)
+	})
+}
+
+func (p *pipeline) Decrement(key string) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		return s.incrementBy(key, -1)
+	})
+}
+
+func (p *pipeline) Exists(key string) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		return s.lookup(key) != nil, nil
+	})
+}
+
+func (p *pipeline) Expire(key string, ttl time.Duration) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		s.expireKey(key, ttl)
+
+		return nil, nil
+	})
+}
+
+func (p *pipeline) TTL(key string) *model.PipelineResult {
+	return p.queue(key, func(s *store) (interface{}, error) {
+		return s.ttl(key), nil
+	})
+}
+
+func (p *pipeline) Len() int {
+	return len(p.commands)
+}
+
+// Exec runs the queued commands with the lock of the store held, so no other command runs in between.
+func (p *pipeline) Exec(ctx context.Context) error {
+	commands := p.commands
+	p.commands = nil
+
+	var firstErr error
+
+	err := p.store.do(func() error {
+		for _, c := range commands {
+			if c.run != nil {
+				c.result.Value, c.result.Err = c.run(p.store)
+			}
+
+			if firstErr == nil && c.result.Err != nil && !errors.Is(c.result.Err, temperr.KeyNotFound) {
+				firstErr = c.result.Err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		for _, c := range commands {
+			if c.run != nil {
+				c.result.Err = err
+			}
+		}
+
+		return err
+	}
+
+	return firstErr
+}
+
+// Discard empties the pipeline, leaving the results of its commands to temperr.PipelineNotExecuted.
+func (p *pipeline) Discard() {
+	p.commands = nil
+}
+
+// queue queues run, or rejects the command with temperr.KeyEmpty if key is empty.
+func (p *pipeline) queue(key string, run func(s *store) (interface{}, error)) *model.PipelineResult {
+	if key == "" {
+		result := &model.PipelineResult{Err: temperr.KeyEmpty}
+		p.commands = append(p.commands, pipelineCommand{result: result})
+
+		return result
+	}
+
+	result := &model.PipelineResult{Err: temperr.PipelineNotExecuted}
+	p.commands = append(p.commands, pipelineCommand{result: result, run: run})
+
+	return result
+}
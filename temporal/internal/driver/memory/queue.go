@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// message implements model.Message for the messages and the subscription confirmations.
+type message struct {
+	typ     string
+	channel string
+	payload string
+}
+
+func (m *message) Type() string {
+	return m.typ
+}
+
+func (m *message) Channel() (string, error) {
+	return m.channel, nil
+}
+
+func (m *message) Payload() (string, error) {
+	return m.payload, nil
+}
+
+// subscription implements model.Subscription, queuing the messages published to its channels until they are
+// received, so publishing never waits for the subscribers.
+type subscription struct {
+	store    *store
+	channels map[string]struct{}
+
+	mu       sync.Mutex
+	messages []model.Message
+	notify   chan struct{}
+	closed   bool
+}
+
+// Receive waits for and returns the next message from the subscription, until ctx is done.
+func (sub *subscription) Receive(ctx context.Context) (model.Message, error) {
+	for {
+		sub.mu.Lock()
+
+		if sub.closed {
+			sub.mu.Unlock()
+
+			return nil, temperr.ClosedConnection
+		}
+
+		if len(sub.messages) > 0 {
+			msg := sub.messages[0]
+			sub.messages = sub.messages[1:]
+			sub.mu.Unlock()
+
+			return msg, nil
+		}
+
+		sub.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-sub.notify:
+		}
+	}
+}
+
+// Close closes the subscription, which no longer receives the messages published to its channels.
+func (sub *subscription) Close() error {
+	sub.store.mu.Lock()
+	defer sub.store.mu.Unlock()
+
+	delete(sub.store.pubsub, sub)
+	sub.close()
+
+	return nil
+}
+
+func (sub *subscription) push(msg model.Message) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.messages = append(sub.messages, msg)
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *subscription) close() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.closed = true
+	sub.messages = nil
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Publish sends a message to the subscriptions of the channel.
+func (m *Memory) Publish(ctx context.Context, channel, payload string) (int64, error) {
+	var receivers int64
+
+	err := m.store.do(func() error {
+		for sub := range m.store.pubsub {
+			if _, ok := sub.channels[channel]; ok {
+				sub.push(&message{typ: model.MessageTypeMessage, channel: channel, payload: payload})
+				receivers++
+			}
+		}
+
+		return nil
+	})
+
+	return receivers, err
+}
+
+// Subscribe subscribes to the channels. As with Redis, the subscription first receives a confirmation
+// per channel.
+func (m *Memory) Subscribe(ctx context.Context, channels ...string) model.Subscription {
+	sub := &subscription{
+		store:    m.store,
+		channels: make(map[string]struct{}, len(channels)),
+		notify:   make(chan struct{}, 1),
+	}
+
+	err := m.store.do(func() error {
+		for _, channel := range channels {
+			if _, ok := sub.channels[channel]; !ok {
+				sub.channels[channel] = struct{}{}
+				sub.push(&message{typ: model.MessageTypeSubscription, channel: channel, payload: "subscribe"})
+			}
+		}
+
+		m.store.pubsub[sub] = struct{}{}
+
+		return nil
+	})
+	if err != nil {
+		sub.close()
+	}
+
+	return sub
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// Allow counts a request against a sliding window logging the requests in a sorted set scored by their time,
+// in milliseconds, as the redisv9 driver does.
+func (m *Memory) Allow(ctx context.Context,
+	key string,
+	limit int64,
+	window time.Duration,
+) (model.RateLimitResult, error) {
+	if err := validateRateLimit(key, limit, window); err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	// the members of the sorted set are unique, so the requests of the same millisecond are all logged
+	member, err := randomToken()
+	if err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	var result model.RateLimitResult
+
+	err = m.store.do(func() error {
+		requests, err := m.store.lookupSortedSet(key)
+		if err != nil {
+			return err
+		}
+
+		if requests == nil {
+			requests = sortedSet{}
+		}
+
+		now := float64(m.store.nowFunc().UnixMilli())
+		windowMs := float64(window.Milliseconds())
+		oldest := math.Inf(1)
+
+		for request, score := range requests {
+			if score <= now-windowMs {
+				delete(requests, request)
+			} else if score < oldest {
+				oldest = score
+			}
+		}
+
+		count := int64(len(requests))
+		if count >= limit {
+			m.store.putAggregate(key, requests, len(requests))
+
+			result = model.RateLimitResult{RetryAfter: time.Duration(oldest+windowMs-now) * time.Millisecond}
+
+			return nil
+		}
+
+		requests[strconv.FormatFloat(now, 'f', -1, 64)+"-"+member] = now
+		m.store.putAggregate(key, requests, len(requests))
+		m.store.expire(m.store.lookup(key), window)
+
+		result = model.RateLimitResult{Allowed: true, Remaining: limit - count - 1}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// AllowTokenBucket counts a request against a token bucket storing its tokens and the time they were counted at,
+// in milliseconds, in a hash, as the redisv9 driver does.
+func (m *Memory) AllowTokenBucket(ctx context.Context,
+	key string,
+	limit int64,
+	window time.Duration,
+) (model.RateLimitResult, error) {
+	if err := validateRateLimit(key, limit, window); err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	var result model.RateLimitResult
+
+	err := m.store.do(func() error {
+		bucket, err := m.store.lookupHash(key)
+		if err != nil {
+			return err
+		}
+
+		now := float64(m.store.nowFunc().UnixMilli())
+		rate := float64(limit) / float64(window.Milliseconds())
+
+		// a missing bucket is full
+		tokens := float64(limit)
+
+		if bucket != nil {
+			counted, tokensErr := strconv.ParseFloat(bucket["tokens"], 64)
+			timestamp, timestampErr := strconv.ParseFloat(bucket["timestamp"], 64)
+
+			if tokensErr == nil && timestampErr == nil {
+				tokens = math.Min(float64(limit), counted+math.Max(0, now-timestamp)*rate)
+			}
+		}
+
+		if tokens >= 1 {
+			tokens--
+			result = model.RateLimitResult{Allowed: true}
+		} else {
+			result = model.RateLimitResult{RetryAfter: time.Duration(math.Ceil((1-tokens)/rate)) * time.Millisecond}
+		}
+
+		result.Remaining = int64(math.Floor(tokens))
+
+		bucket = hash{"tokens": format(tokens), "timestamp": format(now)}
+		m.store.putAggregate(key, bucket, len(bucket))
+		// the key expires once the bucket would be full again
+		m.store.expire(m.store.lookup(key), time.Duration(math.Ceil((float64(limit)-tokens)/rate))*time.Millisecond)
+
+		return nil
+	})
+
+	return result, err
+}
+
+func validateRateLimit(key string, limit int64, window time.Duration) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	if limit <= 0 || window.Milliseconds() <= 0 {
+		return temperr.InvalidRateLimit
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// Members returns all the members of the set stored at key, sorted.
+func (m *Memory) Members(ctx context.Context, key string) ([]string, error) {
+	if key == "" {
+		return []string{}, temperr.KeyEmpty
+	}
+
+	members := []string{}
+
+	err := m.store.do(func() error {
+		s, err := m.store.lookupSet(key)
+		for member := range s {
+			members = append(members, member)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// AddMember adds the member to the set stored at key, creating the set if the key does not exist.
+// It errors if the key is not a set.
+func (m *Memory) AddMember(ctx context.Context, key, member string) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	return m.store.do(func() error {
+		s, err := m.store.lookupSet(key)
+		if err != nil {
+			return err
+		}
+
+		if s == nil {
+			s = set{}
+		}
+
+		s[member] = struct{}{}
+		m.store.putAggregate(key, s, len(s))
+
+		return nil
+	})
+}
+
+// RemoveMember removes the member from the set stored at key. It errors if the key is not a set.
+func (m *Memory) RemoveMember(ctx context.Context, key, member string) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	return m.store.do(func() error {
+		s, err := m.store.lookupSet(key)
+		if err != nil || s == nil {
+			return err
+		}
+
+		delete(s, member)
+		m.store.putAggregate(key, s, len(s))
+
+		return nil
+	})
+}
+
+// IsMember returns if member is a member of the set stored at key.
+func (m *Memory) IsMember(ctx context.Context, key, member string) (bool, error) {
+	if key == "" {
+		return false, temperr.KeyEmpty
+	}
+
+	var isMember bool
+
+	err := m.store.do(func() error {
+		s, err := m.store.lookupSet(key)
+		_, isMember = s[member]
+
+		return err
+	})
+
+	return isMember, err
+}
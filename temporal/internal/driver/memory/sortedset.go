@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errInvalidScore = errors.New("min or max is not a float")
+
+// AddScoredMember adds a member with a specific score to a sorted set, or updates its score.
+// It returns the number of elements added to the sorted set, which is either 0 or 1.
+func (m *Memory) AddScoredMember(ctx context.Context, key, member string, score float64) (int64, error) {
+	var added int64
+
+	err := m.store.do(func() error {
+		members, err := m.store.lookupSortedSet(key)
+		if err != nil {
+			return err
+		}
+
+		if members == nil {
+			members = sortedSet{}
+		}
+
+		if _, ok := members[member]; !ok {
+			added = 1
+		}
+
+		members[member] = score
+		m.store.putAggregate(key, members, len(members))
+
+		return nil
+	})
+
+	return added, err
+}
+
+// GetMembersByScoreRange retrieves the members of a sorted set and their scores within the given score range,
+// ordered by score, then by member. The bounds are inclusive, unless prefixed with "(", and can be "-inf" and
+// "+inf", as with Redis.
+func (m *Memory) GetMembersByScoreRange(ctx context.Context, key, min, max string) ([]interface{}, []float64, error) {
+	var (
+		members []interface{}
+		scores  []float64
+	)
+
+	err := m.store.do(func() error {
+		inRange, err := scoreRange(min, max)
+		if err != nil {
+			return err
+		}
+
+		set, err := m.store.lookupSortedSet(key)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range set.sorted() {
+			if score := set[member]; inRange(score) {
+				members = append(members, member)
+				scores = append(scores, score)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if members == nil {
+		return []interface{}{}, []float64{}, nil
+	}
+
+	return members, scores, nil
+}
+
+// RemoveMembersByScoreRange removes the members of a sorted set within a specified score range.
+// It returns the number of members removed from the sorted set.
+func (m *Memory) RemoveMembersByScoreRange(ctx context.Context, key, min, max string) (int64, error) {
+	var removed int64
+
+	err := m.store.do(func() error {
+		inRange, err := scoreRange(min, max)
+		if err != nil {
+			return err
+		}
+
+		set, err := m.store.lookupSortedSet(key)
+		if err != nil || set == nil {
+			return err
+		}
+
+		for member, score := range set {
+			if inRange(score) {
+				delete(set, member)
+				removed++
+			}
+		}
+
+		m.store.putAggregate(key, set, len(set))
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// sorted returns the members of the sorted set ordered by score, then by member.
+func (z sortedSet) sorted() []string {
+	members := make([]string, 0, len(z))
+	for member := range z {
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if z[members[i]] != z[members[j]] {
+			return z[members[i]] < z[members[j]]
+		}
+
+		return members[i] < members[j]
+	})
+
+	return members
+}
+
+// scoreRange returns a function reporting whether a score is within the range of min and max.
+func scoreRange(min, max string) (func(score float64) bool, error) {
+	minScore, minExclusive, err := parseScore(min)
+	if err != nil {
+		return nil, err
+	}
+
+	maxScore, maxExclusive, err := parseScore(max)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(score float64) bool {
+		if score < minScore || (minExclusive && score == minScore) {
+			return false
+		}
+
+		return score < maxScore || (!maxExclusive && score == maxScore)
+	}, nil
+}
+
+// parseScore parses a bound of a score range, e.g. "1.5", "(1.5", "-inf" or "+inf".
+func parseScore(bound string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(bound, "(")
+	bound = strings.TrimPrefix(bound, "(")
+
+	switch strings.ToLower(bound) {
+	case "-inf":
+		return math.Inf(-1), exclusive, nil
+	case "+inf", "inf":
+		return math.Inf(1), exclusive, nil
+	}
+
+	score, err := strconv.ParseFloat(bound, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false, errInvalidScore
+	}
+
+	return score, exclusive, nil
+}
@@ -0,0 +1,445 @@
+package redisv9
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheInstrumentationName is the name of the meter used to record the metrics of the client-side cache.
+const CacheInstrumentationName = "github.com/TykTechnologies/storage/temporal"
+
+const (
+	// CacheHitsMetric is the counter of the values read from the client-side cache.
+	CacheHitsMetric = "temporal.cache.hits"
+	// CacheMissesMetric is the counter of the values read from Redis because they weren't cached.
+	CacheMissesMetric = "temporal.cache.misses"
+	// CacheEvictionsMetric is the counter of the values evicted because the cache was full.
+	CacheEvictionsMetric = "temporal.cache.evictions"
+	// CacheInvalidationsMetric is the counter of the keys invalidated by Redis.
+	CacheInvalidationsMetric = "temporal.cache.invalidations"
+)
+
+const (
+	defaultCacheMaxKeys = 10000
+	// trackingPoolSize is the number of connections reading the values of the cached keys
+	trackingPoolSize = 10
+	// invalidateChannel is the channel the invalidations are sent to, with the RESP2 protocol
+	invalidateChannel = "__redis__:invalidate"
+	invalidateBackoff = 100 * time.Millisecond
+)
+
+const (
+	cacheStarting int32 = iota
+	cacheEnabled
+	cacheDisabled
+)
+
+// get reads the value of key through the client-side cache, if enabled.
+func (r *RedisV9) get(ctx context.Context, key string) (string, error) {
+	if r.cache != nil {
+		return r.cache.get(ctx, key)
+	}
+
+	return r.client.Get(ctx, key).Result()
+}
+
+// evict removes the keys written by the client from the client-side cache, if enabled.
+func (r *RedisV9) evict(keys ...string) {
+	if r.cache != nil {
+		r.cache.evict(keys...)
+	}
+}
+
+// clientCache caches the values of the keys read by Get, with the client-side caching of Redis 6: the values are
+// read on connections tracked by Redis, which sends the invalidations of the keys modified since to a connection
+// subscribed to __redis__:invalidate. Both connections use RESP2, since go-redis doesn't handle the invalidations
+// pushed with RESP3. Redis forgets the keys tracked on a connection once it's closed, so the cache is flushed
+// whenever a connection is lost. It's started on the first read, once the server is known to support it.
+type clientCache struct {
+	client   *redis.Client
+	maxKeys  int
+	prefixes []string
+
+	state   int32
+	startMu sync.Mutex
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// lru is the sentinel of the list of the entries, from the most recently used one
+	lru cacheEntry
+	// epoch changes on every invalidation, so the values read before aren't cached
+	epoch        uint64
+	redirect     int64
+	tracking     *redis.Client
+	invalidation *redis.Client
+	pubsub       *redis.PubSub
+
+	hits          instrument.Int64Counter
+	misses        instrument.Int64Counter
+	evictions     instrument.Int64Counter
+	invalidations instrument.Int64Counter
+}
+
+type cacheEntry struct {
+	key   string
+	value string
+
+	prev *cacheEntry
+	next *cacheEntry
+}
+
+func newClientCache(client *redis.Client,
+	opts *model.ClientSideCacheOptions,
+	provider metric.MeterProvider,
+) (*clientCache, error) {
+	if provider == nil {
+		provider = global.MeterProvider()
+	}
+
+	maxKeys := defaultCacheMaxKeys
+	if opts.MaxKeys > 0 {
+		maxKeys = opts.MaxKeys
+	}
+
+	c := &clientCache{
+		client:   client,
+		maxKeys:  maxKeys,
+		prefixes: opts.Prefixes,
+	}
+
+	c.flushLocked()
+
+	meter := provider.Meter(CacheInstrumentationName)
+
+	counters := []struct {
+		counter     *instrument.Int64Counter
+		name        string
+		description string
+	}{
+		{&c.hits, CacheHitsMetric, "Number of values read from the client-side cache"},
+		{&c.misses, CacheMissesMetric, "Number of values read from Redis because they weren't cached"},
+		{&c.evictions, CacheEvictionsMetric, "Number of values evicted because the client-side cache was full"},
+		{&c.invalidations, CacheInvalidationsMetric, "Number of keys invalidated by Redis"},
+	}
+
+	for _, counter := range counters {
+		instr, err := meter.Int64Counter(counter.name, instrument.WithDescription(counter.description))
+		if err != nil {
+			return nil, err
+		}
+
+		*counter.counter = instr
+	}
+
+	return c, nil
+}
+
+// get returns the value of key, from the cache if it's cached.
+func (c *clientCache) get(ctx context.Context, key string) (string, error) {
+	if !c.cacheable(key) || !c.start(ctx) {
+		return c.client.Get(ctx, key).Result()
+	}
+
+	c.mu.Lock()
+
+	if value, ok := c.lookup(key); ok {
+		c.mu.Unlock()
+
+		c.hits.Add(ctx, 1)
+
+		return value, nil
+	}
+
+	tracking := c.trackingClient()
+	epoch := c.epoch
+	c.mu.Unlock()
+
+	c.misses.Add(ctx, 1)
+
+	value, err := tracking.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	if err != nil {
+		// the keys tracked on the connection may be lost along with it
+		c.flush()
+
+		return c.client.Get(ctx, key).Result()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epoch == epoch && c.tracking == tracking {
+		c.add(ctx, key, value)
+	}
+
+	return value, nil
+}
+
+// evict removes the keys modified by this client from the cache, without waiting for their invalidation.
+func (c *clientCache) evict(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+
+	for _, key := range keys {
+		if e, ok := c.entries[key]; ok {
+			c.unlink(e)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *clientCache) cacheable(key string) bool {
+	if len(c.prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// start subscribes to the invalidations if the server supports the client-side caching.
+// It returns false if the cache is disabled, or couldn't be started yet.
+func (c *clientCache) start(ctx context.Context) bool {
+	switch atomic.LoadInt32(&c.state) {
+	case cacheEnabled:
+		return true
+	case cacheDisabled:
+		return false
+	}
+
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	if state := atomic.LoadInt32(&c.state); state != cacheStarting {
+		return state == cacheEnabled
+	}
+
+	server, err := c.client.Info(ctx, "server").Result()
+	if err != nil {
+		return false
+	}
+
+	if !parseServerInfo(server).Capabilities.ClientSideCaching {
+		atomic.StoreInt32(&c.state, cacheDisabled)
+
+		return false
+	}
+
+	opts := c.connectionOptions()
+	opts.PoolSize = 1
+	opts.OnConnect = func(ctx context.Context, conn *redis.Conn) error {
+		id, err := conn.ClientID(ctx).Result()
+		if err != nil {
+			return err
+		}
+
+		c.setRedirect(id)
+
+		return nil
+	}
+
+	invalidation := redis.NewClient(opts)
+	pubsub := invalidation.Subscribe(ctx, invalidateChannel)
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		invalidation.Close()
+
+		return false
+	}
+
+	c.mu.Lock()
+	c.invalidation = invalidation
+	c.pubsub = pubsub
+	c.mu.Unlock()
+
+	go c.listen(pubsub)
+
+	atomic.StoreInt32(&c.state, cacheEnabled)
+
+	return true
+}
+
+// listen invalidates the keys received on pubsub, until it's closed.
+func (c *clientCache) listen(pubsub *redis.PubSub) {
+	ctx := context.Background()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if errors.Is(err, redis.ErrClosed) {
+			return
+		}
+
+		if err != nil {
+			// the invalidations may be lost, e.g. the nil payload of FLUSHALL can't be parsed
+			c.flush()
+			time.Sleep(invalidateBackoff)
+
+			continue
+		}
+
+		if m, ok := msg.(*redis.Message); ok {
+			keys := m.PayloadSlice
+			if m.Payload != "" {
+				keys = append(keys, m.Payload)
+			}
+
+			c.invalidations.Add(ctx, int64(len(keys)))
+			c.evict(keys...)
+		}
+	}
+}
+
+// trackingClient returns the client reading the values of the cached keys, tracked by Redis with the
+// invalidations redirected to the current connection of the invalidations. It must be called with mu held.
+func (c *clientCache) trackingClient() *redis.Client {
+	if c.tracking != nil {
+		return c.tracking
+	}
+
+	redirect := c.redirect
+
+	opts := c.connectionOptions()
+	opts.PoolSize = trackingPoolSize
+	opts.OnConnect = func(ctx context.Context, conn *redis.Conn) error {
+		cmd := redis.NewStatusCmd(ctx, "CLIENT", "TRACKING", "ON", "REDIRECT", redirect)
+		if err := conn.Process(ctx, cmd); err != nil {
+			return err
+		}
+
+		// the keys tracked on a lost connection aren't invalidated anymore
+		c.flush()
+
+		return nil
+	}
+
+	c.tracking = redis.NewClient(opts)
+
+	return c.tracking
+}
+
+// connectionOptions returns the options of the connections of the cache, which use RESP2 and aren't closed
+// while they are idle, since their tracking would be lost.
+func (c *clientCache) connectionOptions() *redis.Options {
+	opts := *c.client.Options()
+	opts.Protocol = 2
+	opts.MinIdleConns = 0
+	opts.MaxIdleConns = 0
+	opts.ConnMaxIdleTime = -1
+	opts.ConnMaxLifetime = 0
+
+	return &opts
+}
+
+// setRedirect sets the ID of the connection of the invalidations, which changes when it reconnects.
+// The cache is flushed, and the tracking client replaced, since it redirects the invalidations to the old one.
+func (c *clientCache) setRedirect(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.redirect = id
+	c.flushLocked()
+
+	if c.tracking != nil {
+		c.tracking.Close()
+		c.tracking = nil
+	}
+}
+
+func (c *clientCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flushLocked()
+}
+
+func (c *clientCache) flushLocked() {
+	c.epoch++
+	c.entries = make(map[string]*cacheEntry)
+	c.lru.prev = &c.lru
+	c.lru.next = &c.lru
+}
+
+// lookup returns the cached value of key, marking it as the most recently used one. It must be called with mu held.
+func (c *clientCache) lookup(key string) (string, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	c.unlink(e)
+	c.pushFront(e)
+
+	return e.value, true
+}
+
+// add caches the value of key, evicting the least recently used value if the cache is full.
+// It must be called with mu held.
+func (c *clientCache) add(ctx context.Context, key, value string) {
+	e := &cacheEntry{key: key, value: value}
+	c.entries[key] = e
+	c.pushFront(e)
+
+	if len(c.entries) > c.maxKeys {
+		oldest := c.lru.prev
+		c.unlink(oldest)
+		delete(c.entries, oldest.key)
+
+		c.evictions.Add(ctx, 1)
+	}
+}
+
+func (c *clientCache) pushFront(e *cacheEntry) {
+	e.prev = &c.lru
+	e.next = c.lru.next
+	c.lru.next.prev = e
+	c.lru.next = e
+}
+
+func (c *clientCache) unlink(e *cacheEntry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+}
+
+func (c *clientCache) close() {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	atomic.StoreInt32(&c.state, cacheDisabled)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flushLocked()
+
+	if c.tracking != nil {
+		c.tracking.Close()
+		c.tracking = nil
+	}
+
+	if c.pubsub != nil {
+		c.pubsub.Close()
+		c.invalidation.Close()
+	}
+}
@@ -0,0 +1,98 @@
+package redisv9
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestClientCache_LRU(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	c, err := newClientCache(client, &model.ClientSideCacheOptions{MaxKeys: 2},
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	assert.Nil(t, err)
+
+	c.add(ctx, "a", "1")
+	c.add(ctx, "b", "2")
+
+	value, ok := c.lookup("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	// b is the least recently used key
+	c.add(ctx, "c", "3")
+
+	_, ok = c.lookup("b")
+	assert.False(t, ok)
+
+	c.evict("a")
+
+	_, ok = c.lookup("a")
+	assert.False(t, ok)
+
+	value, ok = c.lookup("c")
+	assert.True(t, ok)
+	assert.Equal(t, "3", value)
+
+	c.flush()
+
+	_, ok = c.lookup("c")
+	assert.False(t, ok)
+
+	var rm metricdata.ResourceMetrics
+
+	assert.Nil(t, reader.Collect(ctx, &rm))
+	assert.Len(t, rm.ScopeMetrics, 1)
+
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == CacheEvictionsMetric {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			assert.True(t, ok)
+			assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+		}
+	}
+}
+
+func TestClientCache_Cacheable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	c, err := newClientCache(client, &model.ClientSideCacheOptions{}, nil)
+	assert.Nil(t, err)
+	assert.True(t, c.cacheable("any"))
+
+	c, err = newClientCache(client, &model.ClientSideCacheOptions{Prefixes: []string{"apis:", "keys:"}}, nil)
+	assert.Nil(t, err)
+	assert.True(t, c.cacheable("apis:1"))
+	assert.True(t, c.cacheable("keys:1"))
+	assert.False(t, c.cacheable("quota:1"))
+}
+
+func TestNewRedisV9WithOpts_ClientSideCache(t *testing.T) {
+	cache := &model.ClientSideCacheOptions{Enable: true}
+
+	_, err := NewRedisV9WithOpts(model.WithRedisConfig(&model.RedisOptions{EnableCluster: true, ClientSideCache: cache}))
+	assert.Equal(t, temperr.InvalidConfiguration, err)
+
+	driver, err := NewRedisV9WithOpts(model.WithRedisConfig(&model.RedisOptions{ClientSideCache: cache}))
+	assert.Nil(t, err)
+	assert.NotNil(t, driver.cache)
+
+	// the instances of the connector share its cache
+	instance, err := NewRedisV9WithConnection(driver)
+	assert.Nil(t, err)
+	assert.Equal(t, driver.cache, instance.cache)
+
+	assert.Nil(t, driver.Disconnect(context.Background()))
+}
@@ -8,6 +8,10 @@ import (
 )
 
 func (h *RedisV9) Disconnect(ctx context.Context) error {
+	if h.cache != nil {
+		h.cache.close()
+	}
+
 	return h.client.Close()
 }
 
@@ -20,7 +24,7 @@ func (h *RedisV9) Type() string {
 }
 
 // As converts i to driver-specific types.
-// redisv9 connector supports only *redis.UniversalClient.
+// redisv9 connector supports only *redis.UniversalClient, along with its client-side cache, if enabled.
 // Same concept as https://gocloud.dev/concepts/as/ but for connectors.
 func (h *RedisV9) As(i interface{}) bool {
 	if x, ok := i.(*redis.UniversalClient); ok {
@@ -28,5 +32,11 @@ func (h *RedisV9) As(i interface{}) bool {
 		return true
 	}
 
+	if x, ok := i.(**clientCache); ok && h.cache != nil {
+		*x = h.cache
+
+		return true
+	}
+
 	return false
 }
@@ -0,0 +1,68 @@
+package redisv9
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+)
+
+// SetFields sets the values of fields of a Redis hash with HSET.
+func (r *RedisV9) SetFields(ctx context.Context, key string, fields map[string]interface{}) (int64, error) {
+	if key == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	added, err := r.client.HSet(ctx, key, fields).Result()
+
+	return added, hashError(err)
+}
+
+// GetAllFields returns the fields of a Redis hash along with their values with HGETALL.
+func (r *RedisV9) GetAllFields(ctx context.Context, key string) (map[string]string, error) {
+	if key == "" {
+		return nil, temperr.KeyEmpty
+	}
+
+	fields, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, hashError(err)
+	}
+
+	return fields, nil
+}
+
+// DeleteFields removes fields of a Redis hash with HDEL.
+func (r *RedisV9) DeleteFields(ctx context.Context, key string, fields ...string) (int64, error) {
+	if key == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	deleted, err := r.client.HDel(ctx, key, fields...).Result()
+
+	return deleted, hashError(err)
+}
+
+// hashError converts the errors of the hash commands to temperr errors.
+func hashError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, redis.ErrClosed):
+		return temperr.ClosedConnection
+	case strings.HasPrefix(err.Error(), "WRONGTYPE"):
+		return temperr.KeyMisstype
+	default:
+		return err
+	}
+}
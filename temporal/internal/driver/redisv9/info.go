@@ -0,0 +1,68 @@
+package redisv9
+
+import (
+	"context"
+	"strings"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// GetConnectionInfo detects the server from the server section of INFO: Valkey reports its own version
+// along with the Redis version it's compatible with, and KeyDB is told apart by its executable.
+// The information is detected once, on a random node of a cluster.
+func (r *RedisV9) GetConnectionInfo(ctx context.Context) (model.ConnectionInfo, error) {
+	r.infoMu.Lock()
+	defer r.infoMu.Unlock()
+
+	if r.info != nil {
+		return *r.info, nil
+	}
+
+	server, err := r.client.Info(ctx, "server").Result()
+	if err != nil {
+		return model.ConnectionInfo{}, connectionError(err)
+	}
+
+	info := parseServerInfo(server)
+	r.info = &info
+
+	return info, nil
+}
+
+// supports returns temperr.UnsupportedFeature if the server doesn't support the feature.
+// If the server can't be detected, e.g. because INFO is disabled, the feature is assumed to be supported,
+// so the command fails on its own if it isn't.
+func (r *RedisV9) supports(ctx context.Context, feature func(model.Capabilities) bool) error {
+	info, err := r.GetConnectionInfo(ctx)
+	if err != nil || feature(info.Capabilities) {
+		return nil
+	}
+
+	return temperr.UnsupportedFeature
+}
+
+// parseServerInfo returns the information of the server from the server section of INFO.
+func parseServerInfo(server string) model.ConnectionInfo {
+	fields := make(map[string]string)
+
+	for _, line := range strings.Split(server, "\n") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+			fields[name] = value
+		}
+	}
+
+	info := model.ConnectionInfo{Type: model.RedisBackend, Version: fields["redis_version"]}
+
+	switch {
+	case fields["server_name"] == "valkey" || fields["valkey_version"] != "":
+		info.Type = model.ValkeyBackend
+		info.Version = fields["valkey_version"]
+	case strings.Contains(strings.ToLower(fields["executable"]), "keydb"):
+		info.Type = model.KeyDBBackend
+	}
+
+	info.Capabilities = info.Type.Capabilities(info.Version)
+
+	return info
+}
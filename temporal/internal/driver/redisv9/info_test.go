@@ -0,0 +1,46 @@
+package redisv9
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServerInfo(t *testing.T) {
+	tcs := []struct {
+		name    string
+		server  string
+		backend model.BackendType
+		version string
+	}{
+		{
+			name:    "redis",
+			server:  "# Server\r\nredis_version:7.4.1\r\nredis_mode:standalone\r\nexecutable:/data/redis-server\r\n",
+			backend: model.RedisBackend,
+			version: "7.4.1",
+		},
+		{
+			name: "valkey",
+			server: "# Server\r\nredis_version:7.2.4\r\nserver_name:valkey\r\nvalkey_version:8.0.1\r\n" +
+				"executable:/data/valkey-server\r\n",
+			backend: model.ValkeyBackend,
+			version: "8.0.1",
+		},
+		{
+			name:    "keydb",
+			server:  "# Server\r\nredis_version:6.3.4\r\nexecutable:/usr/local/bin/keydb-server\r\n",
+			backend: model.KeyDBBackend,
+			version: "6.3.4",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			info := parseServerInfo(tc.server)
+			assert.Equal(t, tc.backend, info.Type)
+			assert.Equal(t, tc.version, info.Version)
+			assert.Equal(t, tc.backend.Capabilities(tc.version), info.Capabilities)
+		})
+	}
+}
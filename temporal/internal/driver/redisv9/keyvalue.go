@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
 	"github.com/redis/go-redis/v9"
 )
@@ -17,7 +18,7 @@ func (r *RedisV9) Get(ctx context.Context, key string) (string, error) {
 		return "", temperr.KeyEmpty
 	}
 
-	result, err := r.client.Get(ctx, key).Result()
+	result, err := r.get(ctx, key)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return "", temperr.KeyNotFound
@@ -35,6 +36,8 @@ func (r *RedisV9) Set(ctx context.Context, key, value string, expiration time.Du
 		return temperr.KeyEmpty
 	}
 
+	defer r.evict(key)
+
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
@@ -44,6 +47,8 @@ func (r *RedisV9) Delete(ctx context.Context, key string) error {
 		return temperr.KeyEmpty
 	}
 
+	defer r.evict(key)
+
 	_, err := r.client.Del(ctx, key).Result()
 
 	return err
@@ -55,12 +60,14 @@ func (r *RedisV9) Increment(ctx context.Context, key string) (int64, error) {
 		return 0, temperr.KeyEmpty
 	}
 
+	defer r.evict(key)
+
 	res, err := r.client.Incr(ctx, key).Result()
-	if err != nil && strings.EqualFold(err.Error(), "ERR value is not an integer or out of range") {
-		return 0, temperr.KeyMisstype
+	if err != nil {
+		return 0, integerError(err)
 	}
 
-	return res, err
+	return res, nil
 }
 
 // Decrement atomically decrements the integer value of a key by one
@@ -69,12 +76,14 @@ func (r *RedisV9) Decrement(ctx context.Context, key string) (int64, error) {
 		return 0, temperr.KeyEmpty
 	}
 
+	defer r.evict(key)
+
 	res, err := r.client.Decr(ctx, key).Result()
-	if err != nil && strings.EqualFold(err.Error(), "ERR value is not an integer or out of range") {
-		return 0, temperr.KeyMisstype
+	if err != nil {
+		return 0, integerError(err)
 	}
 
-	return res, err
+	return res, nil
 }
 
 // Exists checks if a key exists
@@ -94,6 +103,8 @@ func (r *RedisV9) Expire(ctx context.Context, key string, expiration time.Durati
 		return temperr.KeyEmpty
 	}
 
+	defer r.evict(key)
+
 	return r.client.Expire(ctx, key, expiration).Err()
 }
 
@@ -108,13 +119,28 @@ func (r *RedisV9) TTL(ctx context.Context, key string) (int64, error) {
 		return 0, err
 	}
 
+	return ttlSeconds(duration), nil
+}
+
+// ttlSeconds converts the ttl returned by redis to seconds, or -1 if the key has no expiration,
+// or -2 if it doesn't exist.
+func ttlSeconds(duration time.Duration) int64 {
 	// since redis-go v8.3.1, if there's no expiration or the key doesn't exists,
 	// the ttl returned is measured in nanoseconds
 	if duration.Nanoseconds() == -1 || duration.Nanoseconds() == -2 {
-		return duration.Nanoseconds(), nil
+		return duration.Nanoseconds()
+	}
+
+	return int64(duration.Seconds())
+}
+
+// integerError converts the error of incrementing a value which isn't an integer to temperr.KeyMisstype.
+func integerError(err error) error {
+	if err != nil && strings.EqualFold(err.Error(), "ERR value is not an integer or out of range") {
+		return temperr.KeyMisstype
 	}
 
-	return int64(duration.Seconds()), nil
+	return err
 }
 
 // DeleteKeys removes the specified keys. A key is ignored if it does not exist
@@ -123,6 +149,8 @@ func (r *RedisV9) DeleteKeys(ctx context.Context, keys []string) (int64, error)
 		return 0, temperr.KeyEmpty
 	}
 
+	defer r.evict(keys...)
+
 	switch v := r.client.(type) {
 	case *redis.ClusterClient:
 		return r.deleteKeysCluster(ctx, v, keys)
@@ -459,11 +487,78 @@ func (r *RedisV9) GetKeysWithOpts(ctx context.Context,
 	return keys, cursor, continueScan, nil
 }
 
+// ScanKeys returns a page of the keys matching pattern, resuming the iteration at cursor with the SCAN command.
+// On a cluster, every master is scanned from its own position in cursor: the masters already fully scanned are
+// skipped, and the masters not in cursor, e.g. after a failover, are scanned from the start. As with SCAN,
+// the keys present during the whole iteration are returned at least once, and can be returned more than once.
+func (r *RedisV9) ScanKeys(ctx context.Context,
+	pattern string,
+	cursor model.Cursor,
+	count int64,
+) ([]string, model.Cursor, error) {
+	if cursor.Done() {
+		return nil, cursor, nil
+	}
+
+	var keys []string
+	var mutex sync.Mutex
+
+	next := model.Cursor{Nodes: make(map[string]uint64)}
+
+	scanNode := func(ctx context.Context, client *redis.Client) error {
+		addr := client.Options().Addr
+
+		position, scanned := cursor.Nodes[addr]
+		if scanned && position == 0 {
+			mutex.Lock()
+			next.Nodes[addr] = 0
+			mutex.Unlock()
+
+			return nil
+		}
+
+		nodeKeys, nodeCursor, err := fetchKeysWithCursor(ctx, client, pattern, position, count)
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		keys = append(keys, nodeKeys...)
+		next.Nodes[addr] = nodeCursor
+		mutex.Unlock()
+
+		return nil
+	}
+
+	var err error
+
+	switch client := r.client.(type) {
+	case *redis.ClusterClient:
+		err = client.ForEachMaster(ctx, scanNode)
+	case *redis.Client:
+		err = scanNode(ctx, client)
+	default:
+		return nil, cursor, temperr.InvalidRedisClient
+	}
+
+	if err != nil {
+		if errors.Is(err, redis.ErrClosed) {
+			return nil, cursor, temperr.ClosedConnection
+		}
+
+		return nil, cursor, err
+	}
+
+	return keys, next, nil
+}
+
 func (r *RedisV9) SetIfNotExist(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
 	if key == "" {
 		return false, temperr.KeyEmpty
 	}
 
+	defer r.evict(key)
+
 	res := r.client.SetNX(ctx, key, value, expiration)
 	if res.Err() != nil {
 		return false, res.Err()
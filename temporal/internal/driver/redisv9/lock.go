@@ -0,0 +1,359 @@
+package redisv9
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+)
+
+const clusterSlots = 16384
+
+var (
+	// refreshLockScript extends the ttl of a lock only if it's still held with the token.
+	refreshLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	// releaseLockScript deletes a lock only if it's still held with the token.
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Acquire acquires a lock with SET NX PX, storing a random token as value of the key of the lock.
+func (r *RedisV9) Acquire(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	if err := validateLock(name, ttl); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	acquired, err := acquireLock(ctx, r.client, name, token, ttl)
+	if err != nil {
+		return "", connectionError(err)
+	}
+
+	if !acquired {
+		return "", temperr.LockNotAcquired
+	}
+
+	return token, nil
+}
+
+// Refresh extends a lock with a script checking the token of the lock before setting its ttl.
+func (r *RedisV9) Refresh(ctx context.Context, name, token string, ttl time.Duration) error {
+	if err := validateLock(name, ttl); err != nil {
+		return err
+	}
+
+	refreshed, err := runLockScript(ctx, r.client, refreshLockScript, name, token, ttl.Milliseconds())
+	if err != nil {
+		return connectionError(err)
+	}
+
+	if !refreshed {
+		return temperr.LockNotHeld
+	}
+
+	return nil
+}
+
+// Release releases a lock with a script checking the token of the lock before deleting it.
+func (r *RedisV9) Release(ctx context.Context, name, token string) error {
+	if name == "" {
+		return temperr.KeyEmpty
+	}
+
+	released, err := runLockScript(ctx, r.client, releaseLockScript, name, token)
+	if err != nil {
+		return connectionError(err)
+	}
+
+	if !released {
+		return temperr.LockNotHeld
+	}
+
+	return nil
+}
+
+// Redlock implements model.Lock with the Redlock algorithm on the masters of a Redis Cluster: a lock is held
+// when it's acquired on a majority of the masters, so it survives the failure of a minority of them.
+// The key of the lock on each master is prefixed with a hash tag mapping it to a slot of the master.
+// On a client which isn't a cluster client, the locks are acquired on the single node as with RedisV9.
+type Redlock struct {
+	redis *RedisV9
+}
+
+// NewRedlockWithConnection returns a new Redlock instance with a custom redis connection.
+func NewRedlockWithConnection(conn model.Connector) (*Redlock, error) {
+	driver, err := NewRedisV9WithConnection(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Redlock{redis: driver}, nil
+}
+
+// Acquire acquires the lock on every master, and holds it if it was acquired on a majority of them before
+// it expired, accounting for the clock drift. Otherwise, the lock is released on every master.
+func (l *Redlock) Acquire(ctx context.Context, name string, ttl time.Duration) (string, error) {
+	cluster, ok := l.redis.client.(*redis.ClusterClient)
+	if !ok {
+		return l.redis.Acquire(ctx, name, ttl)
+	}
+
+	if err := validateLock(name, ttl); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+
+	acquired, masters, err := forEachLockMaster(ctx, cluster, name,
+		func(ctx context.Context, client *redis.Client, key string) (bool, error) {
+			return acquireLock(ctx, client, key, token, ttl)
+		})
+
+	drift := ttl/100 + 2*time.Millisecond
+	if acquired > masters/2 && time.Since(start)+drift < ttl {
+		return token, nil
+	}
+
+	// the lock wasn't acquired on a majority of the masters in time, so it's released where it was acquired
+	_, _, releaseErr := forEachLockMaster(ctx, cluster, name,
+		func(ctx context.Context, client *redis.Client, key string) (bool, error) {
+			return runLockScript(ctx, client, releaseLockScript, key, token)
+		})
+	if err == nil {
+		err = releaseErr
+	}
+
+	if err != nil {
+		return "", connectionError(err)
+	}
+
+	return "", temperr.LockNotAcquired
+}
+
+// Refresh extends the lock on every master, and succeeds if it's still held on a majority of them.
+func (l *Redlock) Refresh(ctx context.Context, name, token string, ttl time.Duration) error {
+	cluster, ok := l.redis.client.(*redis.ClusterClient)
+	if !ok {
+		return l.redis.Refresh(ctx, name, token, ttl)
+	}
+
+	if err := validateLock(name, ttl); err != nil {
+		return err
+	}
+
+	refreshed, masters, err := forEachLockMaster(ctx, cluster, name,
+		func(ctx context.Context, client *redis.Client, key string) (bool, error) {
+			return runLockScript(ctx, client, refreshLockScript, key, token, ttl.Milliseconds())
+		})
+	if refreshed > masters/2 {
+		return nil
+	}
+
+	if err != nil {
+		return connectionError(err)
+	}
+
+	return temperr.LockNotHeld
+}
+
+// Release releases the lock on every master, and succeeds if it was held on any of them.
+func (l *Redlock) Release(ctx context.Context, name, token string) error {
+	cluster, ok := l.redis.client.(*redis.ClusterClient)
+	if !ok {
+		return l.redis.Release(ctx, name, token)
+	}
+
+	if name == "" {
+		return temperr.KeyEmpty
+	}
+
+	released, _, err := forEachLockMaster(ctx, cluster, name,
+		func(ctx context.Context, client *redis.Client, key string) (bool, error) {
+			return runLockScript(ctx, client, releaseLockScript, key, token)
+		})
+	if released > 0 {
+		return nil
+	}
+
+	if err != nil {
+		return connectionError(err)
+	}
+
+	return temperr.LockNotHeld
+}
+
+// forEachLockMaster runs op on every master of the cluster with the key of the lock on the master.
+// It returns the number of masters on which op succeeded, the number of masters, and the last error of op.
+func forEachLockMaster(ctx context.Context,
+	cluster *redis.ClusterClient,
+	name string,
+	op func(ctx context.Context, client *redis.Client, key string) (bool, error),
+) (int, int, error) {
+	slots, err := cluster.ClusterSlots(ctx).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tags := masterHashTags(slots)
+
+	var mutex sync.Mutex
+
+	var succeeded, masters int
+
+	var lastErr error
+
+	err = cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		var ok bool
+
+		var opErr error
+
+		// a master missing from the slots, e.g. after a failover, counts as a failure
+		if tag, found := tags[client.Options().Addr]; found {
+			ok, opErr = op(ctx, client, "{"+tag+"}"+name)
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		masters++
+
+		if ok {
+			succeeded++
+		}
+
+		if opErr != nil {
+			lastErr = opErr
+		}
+
+		// the failure of a master doesn't prevent a quorum on the others
+		return nil
+	})
+	if err != nil {
+		return succeeded, masters, err
+	}
+
+	return succeeded, masters, lastErr
+}
+
+// masterHashTags returns a hash tag per master address, whose keys are mapped to a slot served by the master.
+func masterHashTags(slots []redis.ClusterSlot) map[string]string {
+	masters := make(map[string]bool)
+
+	for _, s := range slots {
+		if len(s.Nodes) > 0 {
+			masters[s.Nodes[0].Addr] = true
+		}
+	}
+
+	tags := make(map[string]string, len(masters))
+
+	// the slots are evenly spread over the masters, so a few tags are tried for each of them
+	for i := 0; len(tags) < len(masters) && i < 100*clusterSlots; i++ {
+		tag := strconv.Itoa(i)
+		slot := int(crc16(tag) % clusterSlots)
+
+		for _, s := range slots {
+			if len(s.Nodes) == 0 || slot < s.Start || slot > s.End {
+				continue
+			}
+
+			if _, ok := tags[s.Nodes[0].Addr]; !ok {
+				tags[s.Nodes[0].Addr] = tag
+			}
+		}
+	}
+
+	return tags
+}
+
+// crc16 is the CRC16-CCITT (XMODEM) checksum Redis Cluster maps the keys to their slots with.
+func crc16(s string) uint16 {
+	var crc uint16
+
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+func acquireLock(ctx context.Context, client redis.Cmdable, key, token string, ttl time.Duration) (bool, error) {
+	return client.SetNX(ctx, key, token, ttl).Result()
+}
+
+func runLockScript(ctx context.Context,
+	client redis.Scripter,
+	script *redis.Script,
+	key, token string,
+	args ...interface{},
+) (bool, error) {
+	n, err := script.Run(ctx, client, []string{key}, append([]interface{}{token}, args...)...).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// randomToken returns a random token, e.g. identifying the holder of a lock.
+func randomToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+func validateLock(name string, ttl time.Duration) error {
+	if name == "" {
+		return temperr.KeyEmpty
+	}
+
+	if ttl <= 0 {
+		return temperr.InvalidTTL
+	}
+
+	return nil
+}
+
+// connectionError converts the errors of a closed connection to temperr.ClosedConnection.
+func connectionError(err error) error {
+	if errors.Is(err, redis.ErrClosed) {
+		return temperr.ClosedConnection
+	}
+
+	return err
+}
@@ -0,0 +1,40 @@
+package redisv9
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRC16(t *testing.T) {
+	assert.Equal(t, uint16(0x31c3), crc16("123456789"))
+	// CLUSTER KEYSLOT foo
+	assert.Equal(t, uint16(12182), crc16("foo")%clusterSlots)
+}
+
+func TestMasterHashTags(t *testing.T) {
+	slots := []redis.ClusterSlot{
+		{Start: 0, End: 5460, Nodes: []redis.ClusterNode{{Addr: "node1:7000"}, {Addr: "replica1:7003"}}},
+		{Start: 5461, End: 10922, Nodes: []redis.ClusterNode{{Addr: "node2:7001"}}},
+		{Start: 10923, End: 12000, Nodes: []redis.ClusterNode{{Addr: "node3:7002"}}},
+		{Start: 12001, End: 16383, Nodes: []redis.ClusterNode{{Addr: "node3:7002"}}},
+	}
+
+	tags := masterHashTags(slots)
+	assert.Len(t, tags, 3)
+
+	for addr, tag := range tags {
+		slot := int(crc16(tag) % clusterSlots)
+
+		served := false
+
+		for _, s := range slots {
+			served = served || (s.Nodes[0].Addr == addr && slot >= s.Start && slot <= s.End)
+		}
+
+		assert.True(t, served, addr)
+	}
+
+	assert.Empty(t, masterHashTags(nil))
+}
@@ -0,0 +1,211 @@
+package redisv9
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+)
+
+// pipeline implements model.Pipeline with a redis.Pipeliner, converting the result of each command
+// once the pipeline was executed.
+type pipeline struct {
+	pipe     redis.Pipeliner
+	commands []pipelineCommand
+}
+
+type pipelineCommand struct {
+	result *model.PipelineResult
+	value  func() (interface{}, error)
+	// rejected is true for the commands which weren't sent to Redis, e.g. with an empty key
+	rejected bool
+}
+
+// Pipeline returns a new model.Pipeline sending its commands to Redis in a single round trip.
+// On a cluster, the commands are sent to each node in a single round trip.
+func (r *RedisV9) Pipeline() model.Pipeline {
+	return &pipeline{pipe: r.client.Pipeline()}
+}
+
+// TxPipeline returns a new model.Pipeline wrapping its commands in MULTI/EXEC.
+// On a cluster, the commands are wrapped per slot, so only the commands of keys of the same slot,
+// e.g. with the same hash tag, are executed atomically.
+func (r *RedisV9) TxPipeline() model.Pipeline {
+	return &pipeline{pipe: r.client.TxPipeline()}
+}
+
+func (p *pipeline) Get(key string) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Get(context.Background(), key)
+
+	return p.queue(func() (interface{}, error) {
+		value, err := cmd.Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, temperr.KeyNotFound
+		}
+
+		return value, err
+	})
+}
+
+func (p *pipeline) Set(key, value string, ttl time.Duration) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Set(context.Background(), key, value, ttl)
+
+	return p.queue(func() (interface{}, error) {
+		return nil, cmd.Err()
+	})
+}
+
+func (p *pipeline) SetIfNotExist(key, value string, expiration time.Duration) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.SetNX(context.Background(), key, value, expiration)
+
+	return p.queue(func() (interface{}, error) {
+		return cmd.Result()
+	})
+}
+
+func (p *pipeline) Delete(key string) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Del(context.Background(), key)
+
+	return p.queue(func() (interface{}, error) {
+		return nil, cmd.Err()
+	})
+}
+
+func (p *pipeline) Increment(key string) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Incr(context.Background(), key)
+
+	return p.queue(func() (interface{}, error) {
+		return cmd.Val(), integerError(cmd.Err())
+	})
+}
+
+func (p *pipeline) Decrement(key string) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Decr(context.Background(), key)
+
+	return p.queue(func() (interface{}, error) {
+		return cmd.Val(), integerError(cmd.Err())
+	})
+}
+
+func (p *pipeline) Exists(key string) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Exists(context.Background(), key)
+
+	return p.queue(func() (interface{}, error) {
+		return cmd.Val() > 0, cmd.Err()
+	})
+}
+
+func (p *pipeline) Expire(key string, ttl time.Duration) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.Expire(context.Background(), key, ttl)
+
+	return p.queue(func() (interface{}, error) {
+		return nil, cmd.Err()
+	})
+}
+
+func (p *pipeline) TTL(key string) *model.PipelineResult {
+	if key == "" {
+		return p.reject(temperr.KeyEmpty)
+	}
+
+	cmd := p.pipe.TTL(context.Background(), key)
+
+	return p.queue(func() (interface{}, error) {
+		duration, err := cmd.Result()
+		if err != nil {
+			return nil, err
+		}
+
+		return ttlSeconds(duration), nil
+	})
+}
+
+func (p *pipeline) Len() int {
+	return len(p.commands)
+}
+
+// Exec executes the queued commands with redis.Pipeliner.Exec, and converts their results.
+func (p *pipeline) Exec(ctx context.Context) error {
+	commands := p.commands
+	p.commands = nil
+
+	// the errors of the commands are converted below, so only the error of the pipeline matters here
+	if _, err := p.pipe.Exec(ctx); errors.Is(err, redis.ErrClosed) {
+		for _, c := range commands {
+			if !c.rejected {
+				c.result.Err = temperr.ClosedConnection
+			}
+		}
+
+		return temperr.ClosedConnection
+	}
+
+	var firstErr error
+
+	for _, c := range commands {
+		if !c.rejected {
+			c.result.Value, c.result.Err = c.value()
+		}
+
+		if firstErr == nil && c.result.Err != nil && !errors.Is(c.result.Err, temperr.KeyNotFound) {
+			firstErr = c.result.Err
+		}
+	}
+
+	return firstErr
+}
+
+// Discard empties the pipeline, leaving the results of its commands to temperr.PipelineNotExecuted.
+func (p *pipeline) Discard() {
+	p.pipe.Discard()
+	p.commands = nil
+}
+
+func (p *pipeline) queue(value func() (interface{}, error)) *model.PipelineResult {
+	result := &model.PipelineResult{Err: temperr.PipelineNotExecuted}
+	p.commands = append(p.commands, pipelineCommand{result: result, value: value})
+
+	return result
+}
+
+func (p *pipeline) reject(err error) *model.PipelineResult {
+	result := &model.PipelineResult{Err: err}
+	p.commands = append(p.commands, pipelineCommand{result: result, rejected: true})
+
+	return result
+}
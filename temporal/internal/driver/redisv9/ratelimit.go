@@ -0,0 +1,141 @@
+package redisv9
+
+import (
+	"context"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// slidingWindowScript logs the allowed requests in a sorted set scored by their time, in milliseconds.
+	// The time is the one of the server, so the instances sharing the key don't depend on their clocks.
+	// It returns whether the request is allowed, the remaining requests, and the milliseconds to wait.
+	slidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+local count = redis.call("ZCARD", KEYS[1])
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, now .. "-" .. ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], window)
+
+	return {1, limit - count - 1, 0}
+end
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+
+return {0, 0, tonumber(oldest[2]) + window - now}`)
+
+	// tokenBucketScript stores the tokens of the bucket along with the time they were counted at, in milliseconds,
+	// and refills the bucket for the time elapsed since then. A missing bucket is full, so the key expires once
+	// the bucket would be full again. It returns whether the request is allowed, the remaining tokens,
+	// and the milliseconds to wait.
+	tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local rate = limit / window
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "timestamp")
+local tokens = tonumber(bucket[1])
+local timestamp = tonumber(bucket[2])
+
+if tokens == nil or timestamp == nil then
+	tokens = limit
+else
+	tokens = math.min(limit, tokens + math.max(0, now - timestamp) * rate)
+end
+
+local allowed = 0
+local retry = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "timestamp", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((limit - tokens) / rate))
+
+return {allowed, math.floor(tokens), retry}`)
+)
+
+// Allow counts a request against a sliding window with a script logging the requests in a sorted set,
+// which holds up to limit members.
+func (r *RedisV9) Allow(ctx context.Context,
+	key string,
+	limit int64,
+	window time.Duration,
+) (model.RateLimitResult, error) {
+	if err := validateRateLimit(key, limit, window); err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	// the members of the sorted set are unique, so the requests of the same millisecond are all logged
+	member, err := randomToken()
+	if err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	return r.runRateLimitScript(ctx, slidingWindowScript, key, limit, window.Milliseconds(), member)
+}
+
+// AllowTokenBucket counts a request against a token bucket with a script storing the bucket in a hash.
+func (r *RedisV9) AllowTokenBucket(ctx context.Context,
+	key string,
+	limit int64,
+	window time.Duration,
+) (model.RateLimitResult, error) {
+	if err := validateRateLimit(key, limit, window); err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	return r.runRateLimitScript(ctx, tokenBucketScript, key, limit, window.Milliseconds())
+}
+
+func (r *RedisV9) runRateLimitScript(ctx context.Context,
+	script *redis.Script,
+	key string,
+	args ...interface{},
+) (model.RateLimitResult, error) {
+	result, err := script.Run(ctx, r.client, []string{key}, args...).Int64Slice()
+	if err != nil {
+		return model.RateLimitResult{}, connectionError(err)
+	}
+
+	if len(result) != 3 {
+		return model.RateLimitResult{}, temperr.UnexpectedScriptResult
+	}
+
+	return model.RateLimitResult{
+		Allowed:    result[0] == 1,
+		Remaining:  result[1],
+		RetryAfter: time.Duration(result[2]) * time.Millisecond,
+	}, nil
+}
+
+func validateRateLimit(key string, limit int64, window time.Duration) error {
+	if key == "" {
+		return temperr.KeyEmpty
+	}
+
+	if limit <= 0 || window.Milliseconds() <= 0 {
+		return temperr.InvalidRateLimit
+	}
+
+	return nil
+}
@@ -3,10 +3,10 @@ package redisv9
 import (
 	"context"
 	"crypto/tls"
+	"sync"
 	"time"
 
 	"github.com/TykTechnologies/storage/temporal/internal/helper"
-	"github.com/TykTechnologies/storage/temporal/internal/tlsconfig"
 	"github.com/TykTechnologies/storage/temporal/temperr"
 
 	"github.com/TykTechnologies/storage/temporal/model"
@@ -20,6 +20,13 @@ type RedisV9 struct {
 	cfg       *model.RedisOptions
 	onConnect func(context.Context) error
 	retryCfg  *model.RetryOptions
+
+	// cache is the client-side cache of the values read by Get, shared with the instances of the connector
+	cache *clientCache
+
+	// info is the information of the server, detected once by GetConnectionInfo
+	infoMu sync.Mutex
+	info   *model.ConnectionInfo
 }
 
 // NewList returns a new RedisV9 instance.
@@ -45,11 +52,16 @@ func NewRedisV9WithOpts(options ...model.Option) (*RedisV9, error) {
 		timeout = time.Duration(opts.Timeout) * time.Second
 	}
 
+	connMaxIdleTime := 240 * timeout
+	if opts.ConnMaxIdleTime > 0 {
+		connMaxIdleTime = time.Duration(opts.ConnMaxIdleTime) * time.Second
+	}
+
 	var err error
 	var tlsConfig *tls.Config
 
 	if baseConfig.TLS != nil && baseConfig.TLS.Enable {
-		tlsConfig, err = tlsconfig.HandleTLS(baseConfig.TLS)
+		tlsConfig, err = baseConfig.TLS.Build()
 		if err != nil {
 			return nil, err
 		}
@@ -67,8 +79,10 @@ func NewRedisV9WithOpts(options ...model.Option) (*RedisV9, error) {
 		DialTimeout:      timeout,
 		ReadTimeout:      timeout,
 		WriteTimeout:     timeout,
-		ConnMaxIdleTime:  240 * timeout,
+		ConnMaxIdleTime:  connMaxIdleTime,
+		ConnMaxLifetime:  time.Duration(opts.ConnMaxLifetime) * time.Second,
 		PoolSize:         poolSize,
+		MaxIdleConns:     opts.MaxIdle,
 		TLSConfig:        tlsConfig,
 	}
 
@@ -101,6 +115,18 @@ func NewRedisV9WithOpts(options ...model.Option) (*RedisV9, error) {
 
 	driver.client = client
 
+	if opts.ClientSideCache != nil && opts.ClientSideCache.Enable {
+		simple, ok := client.(*redis.Client)
+		if !ok {
+			return nil, temperr.InvalidConfiguration
+		}
+
+		driver.cache, err = newClientCache(simple, opts.ClientSideCache, baseConfig.MeterProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return driver, nil
 }
 
@@ -111,5 +137,8 @@ func NewRedisV9WithConnection(conn model.Connector) (*RedisV9, error) {
 		return nil, temperr.InvalidConnector
 	}
 
-	return &RedisV9{connector: conn, client: client}, nil
+	driver := &RedisV9{connector: conn, client: client}
+	conn.As(&driver.cache)
+
+	return driver, nil
 }
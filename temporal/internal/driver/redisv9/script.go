@@ -0,0 +1,101 @@
+package redisv9
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+)
+
+// RunScript runs a Lua script with EVALSHA, falling back to EVAL when the script isn't cached by the node yet,
+// e.g. after a restart or a failover. EVAL caches the script, so the next runs are by SHA1 again.
+// On a cluster, the script is routed to the node serving the slot of its keys.
+func (r *RedisV9) RunScript(ctx context.Context,
+	script *model.Script,
+	keys []string,
+	args ...interface{},
+) (interface{}, error) {
+	if _, ok := r.client.(*redis.ClusterClient); ok && !sameSlot(keys) {
+		return nil, temperr.CrossSlot
+	}
+
+	result, err := r.client.EvalSha(ctx, script.Hash(), keys, args...).Result()
+	if redis.HasErrorPrefix(err, "NOSCRIPT") {
+		result, err = r.client.Eval(ctx, script.Source(), keys, args...).Result()
+	}
+
+	if err != nil {
+		return nil, scriptError(err)
+	}
+
+	return result, nil
+}
+
+// LoadScripts loads Lua scripts with SCRIPT LOAD. On a cluster, they are loaded on every node.
+func (r *RedisV9) LoadScripts(ctx context.Context, scripts ...*model.Script) error {
+	load := func(ctx context.Context, client redis.Scripter) error {
+		for _, script := range scripts {
+			if err := client.ScriptLoad(ctx, script.Source()).Err(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var err error
+
+	switch client := r.client.(type) {
+	case *redis.ClusterClient:
+		err = client.ForEachShard(ctx, func(ctx context.Context, client *redis.Client) error {
+			return load(ctx, client)
+		})
+	case *redis.Client:
+		err = load(ctx, client)
+	default:
+		return temperr.InvalidRedisClient
+	}
+
+	return scriptError(err)
+}
+
+// sameSlot returns true if the keys map to the same cluster slot.
+func sameSlot(keys []string) bool {
+	for i := 1; i < len(keys); i++ {
+		if keySlot(keys[i]) != keySlot(keys[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// keySlot returns the cluster slot of a key, hashing only its hash tag if it has one, e.g. "user1" for
+// "{user1}:sessions".
+func keySlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return crc16(key) % clusterSlots
+}
+
+// scriptError converts the errors of the script commands to temperr errors.
+// A script returning nil, e.g. Lua false, isn't an error.
+func scriptError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+		return nil
+	case errors.Is(err, redis.ErrClosed):
+		return temperr.ClosedConnection
+	case redis.HasErrorPrefix(err, "CROSSSLOT"):
+		return temperr.CrossSlot
+	default:
+		return err
+	}
+}
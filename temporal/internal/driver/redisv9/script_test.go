@@ -0,0 +1,25 @@
+package redisv9
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySlot(t *testing.T) {
+	// CLUSTER KEYSLOT
+	assert.Equal(t, uint16(12182), keySlot("foo"))
+	assert.Equal(t, uint16(5061), keySlot("bar"))
+	assert.Equal(t, keySlot("foo"), keySlot("{foo}:sessions"))
+	assert.Equal(t, keySlot("user1000"), keySlot("{user1000}.following"))
+	// only the first hash tag counts, and an empty one is ignored
+	assert.Equal(t, keySlot("foo"), keySlot("{foo}{bar}"))
+	assert.Equal(t, crc16("{}foo")%clusterSlots, keySlot("{}foo"))
+}
+
+func TestSameSlot(t *testing.T) {
+	assert.True(t, sameSlot(nil))
+	assert.True(t, sameSlot([]string{"foo"}))
+	assert.True(t, sameSlot([]string{"{user1}:quota", "{user1}:rate"}))
+	assert.False(t, sameSlot([]string{"foo", "bar"}))
+}
@@ -0,0 +1,265 @@
+package redisv9
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/redis/go-redis/v9"
+)
+
+// AddMessage appends a message to a Redis stream with XADD.
+// The stream is trimmed with an approximate MAXLEN if maxLen is greater than 0.
+func (r *RedisV9) AddMessage(ctx context.Context,
+	stream string,
+	values map[string]interface{},
+	maxLen int64,
+) (string, error) {
+	if stream == "" {
+		return "", temperr.KeyEmpty
+	}
+
+	id, err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: maxLen > 0,
+		Values: values,
+	}).Result()
+
+	return id, streamError(err)
+}
+
+// StreamLength returns the number of messages of a Redis stream with XLEN.
+func (r *RedisV9) StreamLength(ctx context.Context, stream string) (int64, error) {
+	if stream == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	length, err := r.client.XLen(ctx, stream).Result()
+
+	return length, streamError(err)
+}
+
+// CreateGroup creates a consumer group of a Redis stream with XGROUP CREATE, creating the stream if needed.
+func (r *RedisV9) CreateGroup(ctx context.Context, stream, group, start string) error {
+	if stream == "" {
+		return temperr.KeyEmpty
+	}
+
+	return streamError(r.client.XGroupCreateMkStream(ctx, stream, group, start).Err())
+}
+
+// DestroyGroup removes a consumer group of a Redis stream with XGROUP DESTROY.
+func (r *RedisV9) DestroyGroup(ctx context.Context, stream, group string) error {
+	if stream == "" {
+		return temperr.KeyEmpty
+	}
+
+	return streamError(r.client.XGroupDestroy(ctx, stream, group).Err())
+}
+
+// RemoveConsumer removes a consumer from a group with XGROUP DELCONSUMER.
+func (r *RedisV9) RemoveConsumer(ctx context.Context, stream, group, consumer string) (int64, error) {
+	if stream == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	pending, err := r.client.XGroupDelConsumer(ctx, stream, group, consumer).Result()
+
+	return pending, streamError(err)
+}
+
+// ReadGroup delivers the new messages of a Redis stream to a consumer with XREADGROUP.
+func (r *RedisV9) ReadGroup(ctx context.Context,
+	stream, group, consumer string,
+	count int64,
+	block time.Duration,
+) ([]model.StreamMessage, error) {
+	if stream == "" {
+		return nil, temperr.KeyEmpty
+	}
+
+	// a negative duration omits the BLOCK option, while 0 would wait forever
+	if block <= 0 {
+		block = -1
+	}
+
+	streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{stream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		return nil, streamError(err)
+	}
+
+	var messages []model.StreamMessage
+
+	for _, s := range streams {
+		messages = append(messages, streamMessages(s.Messages)...)
+	}
+
+	return messages, nil
+}
+
+// Acknowledge acknowledges messages of a group with XACK.
+func (r *RedisV9) Acknowledge(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	if stream == "" {
+		return 0, temperr.KeyEmpty
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	acknowledged, err := r.client.XAck(ctx, stream, group, ids...).Result()
+
+	return acknowledged, streamError(err)
+}
+
+// Pending returns the pending messages of a group with XPENDING.
+// Filtering the messages by minIdle requires Redis 6.2 or later.
+func (r *RedisV9) Pending(ctx context.Context,
+	stream, group string,
+	minIdle time.Duration,
+	count int64,
+) ([]model.PendingMessage, error) {
+	if stream == "" {
+		return nil, temperr.KeyEmpty
+	}
+
+	if minIdle > 0 {
+		if err := r.supports(ctx, autoClaimSupported); err != nil {
+			return nil, err
+		}
+	}
+
+	pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  group,
+		Idle:   minIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, streamError(err)
+	}
+
+	messages := make([]model.PendingMessage, len(pending))
+
+	for i, p := range pending {
+		messages[i] = model.PendingMessage{
+			ID:         p.ID,
+			Consumer:   p.Consumer,
+			Idle:       p.Idle,
+			Deliveries: p.RetryCount,
+		}
+	}
+
+	return messages, nil
+}
+
+// Claim transfers pending messages of a group to a consumer with XCLAIM.
+func (r *RedisV9) Claim(ctx context.Context,
+	stream, group, consumer string,
+	minIdle time.Duration,
+	ids ...string,
+) ([]model.StreamMessage, error) {
+	if stream == "" {
+		return nil, temperr.KeyEmpty
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	claimed, err := r.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, streamError(err)
+	}
+
+	return streamMessages(claimed), nil
+}
+
+// AutoClaim transfers the pending messages of a group idle for at least minIdle to a consumer with XAUTOCLAIM,
+// which requires Redis 6.2 or later.
+func (r *RedisV9) AutoClaim(ctx context.Context,
+	stream, group, consumer string,
+	minIdle time.Duration,
+	start string,
+	count int64,
+) ([]model.StreamMessage, string, error) {
+	if stream == "" {
+		return nil, "", temperr.KeyEmpty
+	}
+
+	if err := r.supports(ctx, autoClaimSupported); err != nil {
+		return nil, "", err
+	}
+
+	if start == "" {
+		start = "0-0"
+	}
+
+	claimed, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    start,
+		Count:    count,
+	}).Result()
+	if err != nil {
+		return nil, "", streamError(err)
+	}
+
+	return streamMessages(claimed), next, nil
+}
+
+// autoClaimSupported reports whether XAUTOCLAIM and the IDLE filter of XPENDING are supported, since Redis 6.2.
+func autoClaimSupported(c model.Capabilities) bool {
+	return c.StreamAutoClaim
+}
+
+// streamMessages converts the messages of a Redis stream to model.StreamMessage.
+func streamMessages(messages []redis.XMessage) []model.StreamMessage {
+	converted := make([]model.StreamMessage, len(messages))
+
+	for i, msg := range messages {
+		converted[i] = model.StreamMessage{ID: msg.ID, Values: msg.Values}
+	}
+
+	return converted
+}
+
+// streamError converts the errors of the stream commands to temperr errors.
+func streamError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, redis.ErrClosed):
+		return temperr.ClosedConnection
+	case strings.HasPrefix(err.Error(), "BUSYGROUP"):
+		return temperr.GroupExists
+	case strings.HasPrefix(err.Error(), "NOGROUP"):
+		return temperr.GroupNotFound
+	default:
+		return err
+	}
+}
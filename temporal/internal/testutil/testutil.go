@@ -41,14 +41,21 @@ func TestConnectors(t *testing.T) []model.Connector {
 	connectors := []model.Connector{}
 
 	// redisv9 list
-	redisConnector := newRedisConnector(t)
+	redisConnector := NewRedisConnector(t)
 
 	connectors = append(connectors, redisConnector)
 
+	// memory
+	memoryConnector, err := connector.NewConnector(model.MemoryType)
+	assert.Nil(t, err)
+
+	connectors = append(connectors, memoryConnector)
+
 	return connectors
 }
 
-func newRedisConnector(t *testing.T) model.Connector {
+// NewRedisConnector returns a redisv9 connector to the Redis of the tests, with its options modified by configure.
+func NewRedisConnector(t *testing.T, configure ...func(*model.RedisOptions)) model.Connector {
 	t.Helper()
 
 	addrs := []string{}
@@ -82,8 +89,13 @@ func newRedisConnector(t *testing.T) model.Connector {
 		tlsConfig.InsecureSkipVerify = os.Getenv("TEST_TLS_INSECURE_SKIP_VERIFY") == "true"
 	}
 
+	redisOptions := &model.RedisOptions{Addrs: addrs, EnableCluster: enableCluster}
+	for _, fn := range configure {
+		fn(redisOptions)
+	}
+
 	redisConnector, err := connector.NewConnector(
-		"redisv9", model.WithRedisConfig(&model.RedisOptions{Addrs: addrs, EnableCluster: enableCluster}),
+		"redisv9", model.WithRedisConfig(redisOptions),
 		model.WithTLS(tlsConfig))
 	assert.Nil(t, err)
 
@@ -1,6 +1,7 @@
 package temporal
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
@@ -8,13 +9,18 @@ import (
 
 type KeyValue = model.KeyValue
 
-var _ KeyValue = (*redisv9.RedisV9)(nil)
+var (
+	_ KeyValue = (*redisv9.RedisV9)(nil)
+	_ KeyValue = (*memory.Memory)(nil)
+)
 
 // NewKeyValue returns a new model.KeyValue storage based on the type of the connector.
 func NewKeyValue(conn model.Connector) (KeyValue, error) {
 	switch conn.Type() {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
@@ -1351,3 +1351,473 @@ func TestKeyValue_SetIfNotExist(t *testing.T) {
 		}
 	}
 }
+
+func TestKeyValue_ScanKeys(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	tcs := []struct {
+		name          string
+		keys          int
+		otherKeys     int
+		cursor        model.Cursor
+		count         int64
+		firstPages    int // number of pages scanned before the iteration is stopped and resumed from its token
+		expectedEmpty bool
+	}{
+		{
+			name:      "full_iteration_small_pages",
+			keys:      100,
+			otherKeys: 10,
+			count:     1,
+		},
+		{
+			name:  "full_iteration_large_pages",
+			keys:  100,
+			count: 1000,
+		},
+		{
+			name:          "no_matching_keys",
+			otherKeys:     10,
+			count:         10,
+			expectedEmpty: true,
+		},
+		{
+			name:       "partial_iteration_resumed",
+			keys:       200,
+			count:      5,
+			firstPages: 1,
+		},
+		{
+			name:       "partial_iteration_resumed_twice",
+			keys:       200,
+			otherKeys:  50,
+			count:      5,
+			firstPages: 3,
+		},
+		{
+			// the nodes which are gone are dropped from the cursor, and the new nodes are scanned from the start
+			name:   "unknown_node_dropped",
+			keys:   50,
+			cursor: model.Cursor{Nodes: map[string]uint64{"gone:6379": 42}},
+			count:  10,
+		},
+	}
+
+	for _, connector := range connectors {
+		for _, tc := range tcs {
+			t.Run(connector.Type()+"_"+tc.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func(ctx context.Context) {
+					err := flusher.FlushAll(ctx)
+					assert.Nil(t, err)
+				}(ctx)
+
+				kv, err := NewKeyValue(connector)
+				assert.Nil(t, err)
+
+				expected := setScanKeys(t, kv, "scankey", tc.keys)
+				setScanKeys(t, kv, "otherkey", tc.otherKeys)
+
+				var keys []string
+
+				cursor := tc.cursor
+
+				for pages := 0; pages < tc.firstPages; pages++ {
+					pageKeys, next, err := kv.ScanKeys(ctx, "scankey*", cursor, tc.count)
+					assert.Nil(t, err)
+					assert.True(t, next.Started())
+
+					keys = append(keys, pageKeys...)
+					cursor = next
+				}
+
+				// the iteration is resumed from the token of the cursor, as a client paginating the keys would
+				resumed, err := model.ParseCursor(cursor.String())
+				assert.Nil(t, err)
+				assert.Equal(t, cursor, resumed)
+
+				keys = append(keys, scanAllKeys(t, kv, "scankey*", resumed, tc.count)...)
+
+				if tc.expectedEmpty {
+					assert.Empty(t, keys)
+					return
+				}
+
+				assert.ElementsMatch(t, expected, dedupKeys(keys))
+			})
+		}
+	}
+
+	for _, connector := range connectors {
+		t.Run(connector.Type()+"_page_retried", func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func(ctx context.Context) {
+				err := flusher.FlushAll(ctx)
+				assert.Nil(t, err)
+			}(ctx)
+
+			kv, err := NewKeyValue(connector)
+			assert.Nil(t, err)
+
+			setScanKeys(t, kv, "scankey", 200)
+
+			_, cursor, err := kv.ScanKeys(ctx, "scankey*", model.Cursor{}, 5)
+			assert.Nil(t, err)
+
+			keys, next, err := kv.ScanKeys(ctx, "scankey*", cursor, 5)
+			assert.Nil(t, err)
+
+			retriedKeys, retriedNext, err := kv.ScanKeys(ctx, "scankey*", cursor, 5)
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, keys, retriedKeys)
+			assert.Equal(t, next, retriedNext)
+		})
+
+		t.Run(connector.Type()+"_done_cursor", func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func(ctx context.Context) {
+				err := flusher.FlushAll(ctx)
+				assert.Nil(t, err)
+			}(ctx)
+
+			kv, err := NewKeyValue(connector)
+			assert.Nil(t, err)
+
+			setScanKeys(t, kv, "scankey", 10)
+
+			_, cursor, err := kv.ScanKeys(ctx, "scankey*", model.Cursor{}, 1000)
+			assert.Nil(t, err)
+			assert.True(t, cursor.Done())
+
+			keys, next, err := kv.ScanKeys(ctx, "scankey*", cursor, 1000)
+			assert.Nil(t, err)
+			assert.Empty(t, keys)
+			assert.Equal(t, cursor, next)
+		})
+
+		t.Run(connector.Type()+"_closed_connection", func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			kv, err := NewKeyValue(connector)
+			assert.Nil(t, err)
+
+			cursor := model.Cursor{Nodes: map[string]uint64{"node:6379": 42}}
+
+			keys, next, err := kv.ScanKeys(ctx, "scankey*", cursor, 10)
+			assert.Equal(t, temperr.ClosedConnection, err)
+			assert.Nil(t, keys)
+			assert.Equal(t, cursor, next)
+		})
+	}
+}
+
+// setScanKeys sets n keys named after prefix, returning their names.
+func setScanKeys(t *testing.T, kv model.KeyValue, prefix string, n int) []string {
+	t.Helper()
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s%d", prefix, i)
+		assert.NoError(t, kv.Set(context.Background(), keys[i], "value", 0))
+	}
+
+	return keys
+}
+
+// scanAllKeys scans the keys matching pattern from cursor until the iteration is done.
+func scanAllKeys(t *testing.T, kv model.KeyValue, pattern string, cursor model.Cursor, count int64) []string {
+	t.Helper()
+
+	var keys []string
+
+	for pages := 0; !cursor.Done(); pages++ {
+		if !assert.Less(t, pages, 1000, "the iteration never ends") {
+			break
+		}
+
+		pageKeys, next, err := kv.ScanKeys(context.Background(), pattern, cursor, count)
+		if !assert.Nil(t, err) {
+			break
+		}
+
+		keys = append(keys, pageKeys...)
+		cursor = next
+	}
+
+	return keys
+}
+
+// dedupKeys removes the duplicates of keys, which SCAN can return more than once.
+func dedupKeys(keys []string) []string {
+	seen := map[string]bool{}
+
+	var unique []string
+
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	return unique
+}
+
+func TestKeyValue_Pipeline(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	pipelines := map[string]func(kv KeyValue) model.Pipeline{
+		"pipeline":    KeyValue.Pipeline,
+		"tx_pipeline": KeyValue.TxPipeline,
+	}
+
+	for _, connector := range connectors {
+		for name, newPipeline := range pipelines {
+			t.Run(connector.Type()+"_"+name, func(t *testing.T) {
+				ctx := context.Background()
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				kv, err := NewKeyValue(connector)
+				assert.Nil(t, err)
+
+				assert.Nil(t, kv.Set(ctx, "{session}:text", "text", 0))
+
+				pipe := newPipeline(kv)
+
+				sets := make([]*model.PipelineResult, 100)
+				for i := range sets {
+					sets[i] = pipe.Set(fmt.Sprintf("{session}:%d", i), fmt.Sprint(i), time.Minute)
+				}
+
+				get := pipe.Get("{session}:1")
+				missing := pipe.Get("{session}:missing")
+				incremented := pipe.Increment("{session}:counter")
+				decremented := pipe.Decrement("{session}:counter")
+				misstyped := pipe.Increment("{session}:text")
+				setNX := pipe.SetIfNotExist("{session}:1", "other", 0)
+				exists := pipe.Exists("{session}:2")
+				ttl := pipe.TTL("{session}:2")
+				expire := pipe.Expire("{session}:3", 0)
+				deleted := pipe.Delete("{session}:4")
+				empty := pipe.Get("")
+
+				assert.Equal(t, 111, pipe.Len())
+
+				_, err = get.StringValue()
+				assert.Equal(t, temperr.PipelineNotExecuted, err)
+
+				// the first error of the commands is returned, while the other ones are executed
+				assert.Equal(t, temperr.KeyMisstype, pipe.Exec(ctx))
+				assert.Equal(t, 0, pipe.Len())
+
+				for _, set := range sets {
+					assert.Nil(t, set.Err)
+				}
+
+				value, err := get.StringValue()
+				assert.Nil(t, err)
+				assert.Equal(t, "1", value)
+
+				_, err = missing.StringValue()
+				assert.Equal(t, temperr.KeyNotFound, err)
+
+				n, err := incremented.IntValue()
+				assert.Nil(t, err)
+				assert.Equal(t, int64(1), n)
+
+				n, err = decremented.IntValue()
+				assert.Nil(t, err)
+				assert.Equal(t, int64(0), n)
+
+				assert.Equal(t, temperr.KeyMisstype, misstyped.Err)
+
+				set, err := setNX.BoolValue()
+				assert.Nil(t, err)
+				assert.False(t, set)
+
+				found, err := exists.BoolValue()
+				assert.Nil(t, err)
+				assert.True(t, found)
+
+				seconds, err := ttl.IntValue()
+				assert.Nil(t, err)
+				assert.Greater(t, seconds, int64(0))
+
+				assert.Nil(t, expire.Err)
+				assert.Nil(t, deleted.Err)
+				assert.Equal(t, temperr.KeyEmpty, empty.Err)
+
+				for _, key := range []string{"{session}:3", "{session}:4"} {
+					found, err := kv.Exists(ctx, key)
+					assert.Nil(t, err)
+					assert.False(t, found)
+				}
+
+				// the pipeline is empty once executed, so it can be reused
+				get = pipe.Get("{session}:5")
+				assert.Nil(t, pipe.Exec(ctx))
+
+				value, err = get.StringValue()
+				assert.Nil(t, err)
+				assert.Equal(t, "5", value)
+
+				discarded := pipe.Set("{session}:discarded", "value", 0)
+				pipe.Discard()
+				assert.Nil(t, pipe.Exec(ctx))
+				assert.Equal(t, temperr.PipelineNotExecuted, discarded.Err)
+
+				_, err = kv.Get(ctx, "{session}:discarded")
+				assert.Equal(t, temperr.KeyNotFound, err)
+			})
+		}
+	}
+}
+
+func TestKeyValue_PipelineClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			kv, err := NewKeyValue(connector)
+			assert.Nil(t, err)
+
+			pipe := kv.Pipeline()
+			set := pipe.Set("key", "value", 0)
+
+			assert.Equal(t, temperr.ClosedConnection, pipe.Exec(ctx))
+			assert.Equal(t, temperr.ClosedConnection, set.Err)
+		})
+	}
+}
+
+func TestKeyValue_SortedSetAndHash(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func() {
+				assert.Nil(t, flusher.FlushAll(ctx))
+			}()
+
+			kv, err := NewKeyValue(connector)
+			assert.Nil(t, err)
+
+			// analytics records are buffered in a sorted set scored by their timestamp
+			for i, record := range []string{"record1", "record2", "record3"} {
+				added, err := kv.AddScoredMember(ctx, "analytics", record, float64(i+1))
+				assert.Nil(t, err)
+				assert.Equal(t, int64(1), added)
+			}
+
+			removed, err := kv.RemoveMembersByScoreRange(ctx, "analytics", "-inf", "1")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), removed)
+
+			members, scores, err := kv.GetMembersByScoreRange(ctx, "analytics", "-inf", "+inf")
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{"record2", "record3"}, members)
+			assert.Equal(t, []float64{2, 3}, scores)
+
+			// the metadata of a session is stored in a hash
+			added, err := kv.SetFields(ctx, "session", map[string]interface{}{"org": "default", "expires": 60})
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), added)
+
+			deleted, err := kv.DeleteFields(ctx, "session", "expires")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), deleted)
+
+			fields, err := kv.GetAllFields(ctx, "session")
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"org": "default"}, fields)
+		})
+	}
+}
+
+func TestKeyValue_ClientSideCache(t *testing.T) {
+	if os.Getenv("TEST_ENABLE_CLUSTER") == "true" {
+		t.Skip("client-side caching isn't supported with Redis Cluster")
+	}
+
+	cached := testutil.NewRedisConnector(t, func(opts *model.RedisOptions) {
+		opts.ClientSideCache = &model.ClientSideCacheOptions{Enable: true, Prefixes: []string{"apis:"}}
+	})
+	writer := testutil.NewRedisConnector(t)
+
+	defer testutil.CloseConnectors(t, []model.Connector{cached, writer})
+
+	ctx := context.Background()
+
+	kv, err := NewKeyValue(cached)
+	assert.Nil(t, err)
+
+	other, err := NewKeyValue(writer)
+	assert.Nil(t, err)
+
+	defer func() {
+		flusher, err := flusher.NewFlusher(writer)
+		assert.Nil(t, err)
+		assert.Nil(t, flusher.FlushAll(ctx))
+	}()
+
+	_, err = kv.Get(ctx, "apis:1")
+	assert.Equal(t, temperr.KeyNotFound, err)
+
+	assert.Nil(t, other.Set(ctx, "apis:1", "v1", 0))
+
+	for i := 0; i < 3; i++ {
+		value, err := kv.Get(ctx, "apis:1")
+		assert.Nil(t, err)
+		assert.Equal(t, "v1", value)
+	}
+
+	// the value written by another client is invalidated by Redis
+	assert.Nil(t, other.Set(ctx, "apis:1", "v2", 0))
+	assert.Eventually(t, func() bool {
+		value, err := kv.Get(ctx, "apis:1")
+		return err == nil && value == "v2"
+	}, time.Second, 10*time.Millisecond)
+
+	// the value written by the client itself is evicted right away
+	assert.Nil(t, kv.Set(ctx, "apis:1", "v3", 0))
+
+	value, err := kv.Get(ctx, "apis:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "v3", value)
+
+	assert.Nil(t, kv.Delete(ctx, "apis:1"))
+
+	_, err = kv.Get(ctx, "apis:1")
+	assert.Equal(t, temperr.KeyNotFound, err)
+}
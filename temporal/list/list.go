@@ -1,6 +1,7 @@
 package list
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
@@ -8,12 +9,17 @@ import (
 
 type List = model.List
 
-var _ List = (*redisv9.RedisV9)(nil)
+var (
+	_ List = (*redisv9.RedisV9)(nil)
+	_ List = (*memory.Memory)(nil)
+)
 
 func NewList(conn model.Connector) (List, error) {
 	switch conn.Type() {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
@@ -0,0 +1,43 @@
+package lock
+
+import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
+	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+type Lock = model.Lock
+
+var (
+	_ Lock = (*redisv9.RedisV9)(nil)
+	_ Lock = (*redisv9.Redlock)(nil)
+	_ Lock = (*memory.Memory)(nil)
+)
+
+// NewLock returns a new model.Lock storage based on the type of the connector.
+// The lock of a name is stored in a single key.
+func NewLock(conn model.Connector) (Lock, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
+
+// NewRedlock returns a new model.Lock storage based on the type of the connector, holding the locks on
+// a majority of the masters of a Redis Cluster with the Redlock algorithm, so they survive the failure of
+// a minority of the masters. On a connector which isn't a cluster, it's the same as NewLock.
+func NewRedlock(conn model.Connector) (Lock, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedlockWithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
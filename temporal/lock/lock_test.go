@@ -0,0 +1,99 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/flusher"
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	constructors := []struct {
+		name string
+		new  func(conn model.Connector) (Lock, error)
+	}{
+		{name: "lock", new: NewLock},
+		{name: "redlock", new: NewRedlock},
+	}
+
+	for _, connector := range connectors {
+		for _, constructor := range constructors {
+			t.Run(connector.Type()+"_"+constructor.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				lock, err := constructor.new(connector)
+				assert.Nil(t, err)
+
+				_, err = lock.Acquire(ctx, "", time.Second)
+				assert.Equal(t, temperr.KeyEmpty, err)
+
+				_, err = lock.Acquire(ctx, "job", 0)
+				assert.Equal(t, temperr.InvalidTTL, err)
+
+				token, err := lock.Acquire(ctx, "job", time.Second)
+				assert.Nil(t, err)
+				assert.NotEmpty(t, token)
+
+				_, err = lock.Acquire(ctx, "job", time.Second)
+				assert.Equal(t, temperr.LockNotAcquired, err)
+
+				other, err := lock.Acquire(ctx, "other_job", time.Second)
+				assert.Nil(t, err)
+				assert.NotEqual(t, token, other)
+
+				assert.Equal(t, temperr.LockNotHeld, lock.Refresh(ctx, "job", other, time.Second))
+				assert.Equal(t, temperr.LockNotHeld, lock.Release(ctx, "job", other))
+
+				assert.Nil(t, lock.Refresh(ctx, "job", token, time.Minute))
+				assert.Nil(t, lock.Release(ctx, "job", token))
+				assert.Equal(t, temperr.LockNotHeld, lock.Release(ctx, "job", token))
+
+				// the lock is acquired again once released
+				token, err = lock.Acquire(ctx, "job", 100*time.Millisecond)
+				assert.Nil(t, err)
+
+				// and once expired, after which it can't be refreshed by its previous holder
+				time.Sleep(200 * time.Millisecond)
+
+				assert.Equal(t, temperr.LockNotHeld, lock.Refresh(ctx, "job", token, time.Second))
+
+				_, err = lock.Acquire(ctx, "job", time.Second)
+				assert.Nil(t, err)
+			})
+		}
+	}
+}
+
+func TestLock_ClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			lock, err := NewLock(connector)
+			assert.Nil(t, err)
+
+			_, err = lock.Acquire(ctx, "job", time.Second)
+			assert.Equal(t, temperr.ClosedConnection, err)
+			assert.Equal(t, temperr.ClosedConnection, lock.Release(ctx, "job", "token"))
+		})
+	}
+}
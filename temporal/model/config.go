@@ -3,13 +3,20 @@ package model
 import (
 	"context"
 	"time"
+
+	"github.com/TykTechnologies/storage/types"
+	"go.opentelemetry.io/otel/metric"
 )
 
 type BaseConfig struct {
-	RedisConfig *RedisOptions
-	RetryConfig *RetryOptions
-	OnConnect   func(context.Context) error
-	TLS         *TLS
+	RedisConfig  *RedisOptions
+	MemoryConfig *MemoryOptions
+	RetryConfig  *RetryOptions
+	OnConnect    func(context.Context) error
+	TLS          *TLS
+	// MeterProvider is the provider of the meters recording the metrics of the connector,
+	// the global one if nil.
+	MeterProvider metric.MeterProvider
 }
 
 // RedisOptions contains options specific to Redis storage.
@@ -36,8 +43,35 @@ type RedisOptions struct {
 	// Set the number of maximum connections in the Redis connection pool, which defaults to 500
 	// Set to a higher value if you are expecting more traffic.
 	MaxActive int `json:"optimisation_max_active"`
+	// Set the maximum number of idle connections kept in the Redis connection pool. No limit by default.
+	MaxIdle int `json:"optimisation_max_idle"`
+	// Set the number of seconds a connection can stay idle in the pool before being closed.
+	// Defaults to 240 times the Timeout.
+	ConnMaxIdleTime int `json:"conn_max_idle_time"`
+	// Set the maximum number of seconds a connection can be reused. Connections are not closed due to age by default.
+	ConnMaxLifetime int `json:"conn_max_lifetime"`
 	// Enable Redis Cluster support
 	EnableCluster bool `json:"enable_cluster"`
+	// Client-side caching of the values of the keys read by Get. Disabled by default.
+	ClientSideCache *ClientSideCacheOptions `json:"client_side_cache"`
+}
+
+// ClientSideCacheOptions contains the options of the client-side caching of the values of the keys read by Get,
+// invalidated by Redis when the keys are modified. It's not supported with Redis Cluster.
+type ClientSideCacheOptions struct {
+	// Enable the client-side caching. It's ignored if the server doesn't support it, e.g. before Redis 6.
+	Enable bool `json:"enable"`
+	// Set the maximum number of keys cached, evicting the least recently used ones. Defaults to 10000.
+	MaxKeys int `json:"max_keys"`
+	// Set the prefixes of the keys cached, e.g. the ones of the hot keys. All the keys are cached by default.
+	Prefixes []string `json:"prefixes"`
+}
+
+// MemoryOptions contains options specific to the in-memory storage.
+type MemoryOptions struct {
+	// Set the number of seconds between the removals of the expired keys, which are never returned anyway.
+	// Defaults to 60 seconds.
+	CleanupInterval int `json:"cleanup_interval"`
 }
 
 type RetryOptions struct {
@@ -49,24 +83,5 @@ type RetryOptions struct {
 	MaxRetryBackoff time.Duration
 }
 
-type TLS struct {
-	// Flag that can be used to enable TLS. Defaults to false (disabled).
-	Enable bool `json:"enable"`
-	// Flag that can be used to skip TLS verification if TLS is enabled.
-	// Defaults to false.
-	InsecureSkipVerify bool `json:"insecure_skip_verify"`
-	// Path to the CA file.
-	CAFile string `json:"ca_file"`
-	// Path to the cert file.
-	CertFile string `json:"cert_file"`
-	// Path to the key file.
-	KeyFile string `json:"key_file"`
-	// Maximum TLS version that is supported.
-	// Options: ["1.0", "1.1", "1.2", "1.3"].
-	// Defaults to "1.3".
-	MaxVersion string `json:"max_version"`
-	// Minimum TLS version that is supported.
-	// Options: ["1.0", "1.1", "1.2", "1.3"].
-	// Defaults to "1.2".
-	MinVersion string `json:"min_version"`
-}
+// TLS is the TLS configuration of the connection, shared with the persistent storage.
+type TLS = types.TLSConfig
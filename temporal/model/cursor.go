@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// Cursor is the position of a ScanKeys iteration. The zero Cursor starts a new iteration.
+// On a cluster, the position is tracked per node, so the nodes are scanned independently of each other.
+type Cursor struct {
+	// Nodes maps the address of each node scanned to its SCAN cursor, which is 0 once the node was fully scanned.
+	Nodes map[string]uint64 `json:"nodes,omitempty"`
+}
+
+// Started returns true if the iteration was started, i.e. the cursor was returned by ScanKeys.
+func (c Cursor) Started() bool {
+	return len(c.Nodes) > 0
+}
+
+// Done returns true if the iteration is over, i.e. every node was fully scanned.
+func (c Cursor) Done() bool {
+	if !c.Started() {
+		return false
+	}
+
+	for _, position := range c.Nodes {
+		if position != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String encodes the cursor as an opaque token which can be handed to clients, and parsed back with ParseCursor.
+// The zero Cursor is encoded as an empty string.
+func (c Cursor) String() string {
+	if !c.Started() {
+		return ""
+	}
+
+	// the keys of the maps are marshaled in order, so the same cursors have the same tokens
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor parses a token returned by Cursor.String. An empty token is parsed as the zero Cursor.
+func ParseCursor(token string) (Cursor, error) {
+	var cursor Cursor
+
+	if token == "" {
+		return cursor, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, temperr.InvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil || !cursor.Started() {
+		return Cursor{}, temperr.InvalidCursor
+	}
+
+	return cursor, nil
+}
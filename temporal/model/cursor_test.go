@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	tcs := []struct {
+		name            string
+		cursor          Cursor
+		expectedStarted bool
+		expectedDone    bool
+	}{
+		{
+			name: "zero",
+		},
+		{
+			name:   "empty_nodes",
+			cursor: Cursor{Nodes: map[string]uint64{}},
+		},
+		{
+			name:            "in_progress",
+			cursor:          Cursor{Nodes: map[string]uint64{"node1:6379": 0, "node2:6379": 12}},
+			expectedStarted: true,
+		},
+		{
+			name:            "done",
+			cursor:          Cursor{Nodes: map[string]uint64{"node1:6379": 0, "node2:6379": 0}},
+			expectedStarted: true,
+			expectedDone:    true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedStarted, tc.cursor.Started())
+			assert.Equal(t, tc.expectedDone, tc.cursor.Done())
+
+			parsed, err := ParseCursor(tc.cursor.String())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStarted, parsed.Started())
+			assert.Equal(t, tc.expectedDone, parsed.Done())
+
+			if tc.expectedStarted {
+				assert.Equal(t, tc.cursor, parsed)
+			}
+		})
+	}
+}
+
+func TestCursor_String(t *testing.T) {
+	cursor := Cursor{Nodes: map[string]uint64{"node2:6379": 7, "node1:6379": 3, "[::1]:6379": 0}}
+	assert.Equal(t, cursor.String(), Cursor{Nodes: map[string]uint64{"[::1]:6379": 0, "node1:6379": 3,
+		"node2:6379": 7}}.String())
+	assert.Empty(t, Cursor{}.String())
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "e30", "eyJub2RlcyI6e319"} {
+		_, err := ParseCursor(token)
+		assert.Equal(t, temperr.InvalidCursor, err, token)
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// BackendType is the server a connector is connected to.
+type BackendType string
+
+const (
+	RedisBackend  BackendType = "redis"
+	ValkeyBackend BackendType = "valkey"
+	KeyDBBackend  BackendType = "keydb"
+	MemoryBackend BackendType = "memory"
+)
+
+// ConnectionInfo is the information of the server a connector is connected to.
+type ConnectionInfo struct {
+	Type BackendType
+	// Version is the version of the server, e.g. the Valkey version for Valkey, or empty for the memory connector.
+	Version string
+	// Capabilities are the features supported by the server, given its Type and Version.
+	Capabilities Capabilities
+}
+
+// Capabilities are the optional features supported by a server.
+type Capabilities struct {
+	// Scripts reports whether the Lua scripts can be run, e.g. with EVAL.
+	Scripts bool
+	// Functions reports whether the server functions can be loaded and called, e.g. with FUNCTION LOAD and FCALL.
+	Functions bool
+	// Streams reports whether the streams and their consumer groups are supported.
+	Streams bool
+	// StreamAutoClaim reports whether the pending messages of streams can be filtered by idle time and
+	// automatically claimed, e.g. with XAUTOCLAIM.
+	StreamAutoClaim bool
+	// ShardedPubSub reports whether the messages can be published to the shards of a cluster, e.g. with SPUBLISH.
+	ShardedPubSub bool
+	// ClientSideCaching reports whether the server tracks the keys cached by the clients, e.g. with CLIENT TRACKING.
+	ClientSideCaching bool
+	// HashFieldExpiration reports whether the fields of hashes can expire, e.g. with HEXPIRE.
+	HashFieldExpiration bool
+}
+
+// Capabilities returns the features supported by the version of the t server. KeyDB supports the features of
+// the Redis versions it's based on, up to Redis 6.2, and the memory connector none of them.
+func (t BackendType) Capabilities(version string) Capabilities {
+	switch t {
+	case ValkeyBackend:
+		// Valkey was forked from Redis 7.2
+		return Capabilities{
+			Scripts:             true,
+			Functions:           true,
+			Streams:             true,
+			StreamAutoClaim:     true,
+			ShardedPubSub:       true,
+			ClientSideCaching:   true,
+			HashFieldExpiration: versionAtLeast(version, 9, 0),
+		}
+	case KeyDBBackend:
+		return Capabilities{
+			Scripts:           true,
+			Streams:           versionAtLeast(version, 5, 0),
+			StreamAutoClaim:   versionAtLeast(version, 6, 2),
+			ClientSideCaching: versionAtLeast(version, 6, 0),
+		}
+	case MemoryBackend:
+		return Capabilities{}
+	default:
+		return Capabilities{
+			Scripts:             true,
+			Functions:           versionAtLeast(version, 7, 0),
+			Streams:             versionAtLeast(version, 5, 0),
+			StreamAutoClaim:     versionAtLeast(version, 6, 2),
+			ShardedPubSub:       versionAtLeast(version, 7, 0),
+			ClientSideCaching:   versionAtLeast(version, 6, 0),
+			HashFieldExpiration: versionAtLeast(version, 7, 4),
+		}
+	}
+}
+
+// versionAtLeast reports whether version, e.g. "7.2.4", is at least major.minor.
+// It returns false if the version can't be parsed.
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	versionMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+
+	versionMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+
+	return versionMajor > major || (versionMajor == major && versionMinor >= minor)
+}
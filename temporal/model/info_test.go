@@ -0,0 +1,92 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackendType_Capabilities(t *testing.T) {
+	tcs := []struct {
+		name     string
+		backend  BackendType
+		version  string
+		expected Capabilities
+	}{
+		{
+			name:     "redis_6.0",
+			backend:  RedisBackend,
+			version:  "6.0.20",
+			expected: Capabilities{Scripts: true, Streams: true, ClientSideCaching: true},
+		},
+		{
+			name:    "redis_7.2",
+			backend: RedisBackend,
+			version: "7.2.4",
+			expected: Capabilities{
+				Scripts: true, Functions: true, Streams: true, StreamAutoClaim: true, ShardedPubSub: true,
+				ClientSideCaching: true,
+			},
+		},
+		{
+			name:    "redis_7.4",
+			backend: RedisBackend,
+			version: "7.4.0",
+			expected: Capabilities{
+				Scripts: true, Functions: true, Streams: true, StreamAutoClaim: true, ShardedPubSub: true,
+				ClientSideCaching: true, HashFieldExpiration: true,
+			},
+		},
+		{
+			name:     "redis_unknown_version",
+			backend:  RedisBackend,
+			version:  "",
+			expected: Capabilities{Scripts: true},
+		},
+		{
+			name:    "valkey_8.0",
+			backend: ValkeyBackend,
+			version: "8.0.1",
+			expected: Capabilities{
+				Scripts: true, Functions: true, Streams: true, StreamAutoClaim: true, ShardedPubSub: true,
+				ClientSideCaching: true,
+			},
+		},
+		{
+			name:    "valkey_9.0",
+			backend: ValkeyBackend,
+			version: "9.0.0",
+			expected: Capabilities{
+				Scripts: true, Functions: true, Streams: true, StreamAutoClaim: true, ShardedPubSub: true,
+				ClientSideCaching: true, HashFieldExpiration: true,
+			},
+		},
+		{
+			name:     "keydb_6.3",
+			backend:  KeyDBBackend,
+			version:  "6.3.4",
+			expected: Capabilities{Scripts: true, Streams: true, StreamAutoClaim: true, ClientSideCaching: true},
+		},
+		{
+			name:     "memory",
+			backend:  MemoryBackend,
+			expected: Capabilities{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.backend.Capabilities(tc.version))
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("7.4.0", 7, 4))
+	assert.True(t, versionAtLeast("10.0.0", 7, 4))
+	assert.True(t, versionAtLeast("8.0", 7, 4))
+	assert.False(t, versionAtLeast("7.2.4", 7, 4))
+	assert.False(t, versionAtLeast("6.9.9", 7, 0))
+	assert.False(t, versionAtLeast("", 7, 0))
+	assert.False(t, versionAtLeast("unstable", 7, 0))
+}
@@ -1,6 +1,10 @@
 package model
 
-import "context"
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+)
 
 type Option interface {
 	Apply(*BaseConfig)
@@ -23,6 +27,15 @@ func WithRedisConfig(config *RedisOptions) Option {
 	}
 }
 
+// WithMemoryConfig is a helper function to create a ConnectionOption for the in-memory storage.
+func WithMemoryConfig(config *MemoryOptions) Option {
+	return &opts{
+		fn: func(bcfg *BaseConfig) {
+			bcfg.MemoryConfig = config
+		},
+	}
+}
+
 // WithNoopConfig is a helper function to avoid creating a connection - useful for testing.
 func WithNoopConfig() Option {
 	return &opts{
@@ -59,3 +72,12 @@ func WithTLS(config *TLS) Option {
 		},
 	}
 }
+
+// WithMeterProvider is a helper function to set the provider of the meters recording the metrics of the connector.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return &opts{
+		fn: func(bcfg *BaseConfig) {
+			bcfg.MeterProvider = provider
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric"
 )
 
 func TestOptions(t *testing.T) {
@@ -50,6 +51,13 @@ func TestOptions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "WithMemoryConfig",
+			givenOption: WithMemoryConfig(&MemoryOptions{CleanupInterval: 10}),
+			expectedBaseCfg: &BaseConfig{
+				MemoryConfig: &MemoryOptions{CleanupInterval: 10},
+			},
+		},
 		{
 			name:        "WithNoopConfig",
 			givenOption: WithNoopConfig(),
@@ -72,6 +80,13 @@ func TestOptions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:        "WithMeterProvider",
+			givenOption: WithMeterProvider(metric.NewNoopMeterProvider()),
+			expectedBaseCfg: &BaseConfig{
+				MeterProvider: metric.NewNoopMeterProvider(),
+			},
+		},
 		{
 			name:        "WithOnConnect",
 			givenOption: WithOnConnect(nil),
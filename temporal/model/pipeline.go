@@ -0,0 +1,55 @@
+package model
+
+import "github.com/TykTechnologies/storage/temporal/temperr"
+
+// PipelineResult is the result of a command queued in a Pipeline, set once the pipeline was executed.
+// Until then, Err is temperr.PipelineNotExecuted, unless the command was rejected when queued, e.g. with an empty key.
+type PipelineResult struct {
+	// Value is the value returned by the command: a string, an int64 or a bool,
+	// as returned by the KeyValue method of the same name, or nil for the methods returning only an error.
+	Value interface{}
+	// Err is the error of the command.
+	Err error
+}
+
+// StringValue returns the value of a string result, or the error of the command.
+func (r *PipelineResult) StringValue() (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+
+	value, ok := r.Value.(string)
+	if !ok {
+		return "", temperr.KeyMisstype
+	}
+
+	return value, nil
+}
+
+// IntValue returns the value of an integer result, or the error of the command.
+func (r *PipelineResult) IntValue() (int64, error) {
+	if r.Err != nil {
+		return 0, r.Err
+	}
+
+	value, ok := r.Value.(int64)
+	if !ok {
+		return 0, temperr.KeyMisstype
+	}
+
+	return value, nil
+}
+
+// BoolValue returns the value of a boolean result, or the error of the command.
+func (r *PipelineResult) BoolValue() (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+
+	value, ok := r.Value.(bool)
+	if !ok {
+		return false, temperr.KeyMisstype
+	}
+
+	return value, nil
+}
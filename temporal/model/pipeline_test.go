@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineResult(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		value, err := (&PipelineResult{Value: "value"}).StringValue()
+		assert.Nil(t, err)
+		assert.Equal(t, "value", value)
+
+		_, err = (&PipelineResult{Value: int64(1)}).StringValue()
+		assert.Equal(t, temperr.KeyMisstype, err)
+	})
+
+	t.Run("int", func(t *testing.T) {
+		value, err := (&PipelineResult{Value: int64(3)}).IntValue()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), value)
+
+		_, err = (&PipelineResult{Value: "3"}).IntValue()
+		assert.Equal(t, temperr.KeyMisstype, err)
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		value, err := (&PipelineResult{Value: true}).BoolValue()
+		assert.Nil(t, err)
+		assert.True(t, value)
+
+		_, err = (&PipelineResult{}).BoolValue()
+		assert.Equal(t, temperr.KeyMisstype, err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		result := &PipelineResult{Value: "value", Err: temperr.PipelineNotExecuted}
+
+		_, err := result.StringValue()
+		assert.Equal(t, temperr.PipelineNotExecuted, err)
+
+		_, err = result.IntValue()
+		assert.Equal(t, temperr.PipelineNotExecuted, err)
+
+		_, err = result.BoolValue()
+		assert.Equal(t, temperr.PipelineNotExecuted, err)
+	})
+}
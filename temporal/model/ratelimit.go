@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RateLimitResult is the result of counting a request against a rate limit.
+type RateLimitResult struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+	// Remaining is the number of requests which would be allowed right after this one.
+	Remaining int64
+	// RetryAfter is the time to wait before a request is allowed, if this one was not.
+	RetryAfter time.Duration
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// Script is a Lua script, identified by the SHA1 digest of its source. The digest is computed once,
+// so the script can be run by digest, e.g. with EVALSHA, without sending its source each time.
+type Script struct {
+	source string
+	hash   string
+}
+
+// NewScript returns a new Script with the given Lua source.
+func NewScript(source string) *Script {
+	digest := sha1.Sum([]byte(source))
+
+	return &Script{source: source, hash: hex.EncodeToString(digest[:])}
+}
+
+// Source returns the Lua source of the script.
+func (s *Script) Source() string {
+	return s.source
+}
+
+// Hash returns the hex-encoded SHA1 digest of the source of the script.
+func (s *Script) Hash() string {
+	return s.hash
+}
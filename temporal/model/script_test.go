@@ -0,0 +1,16 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScript(t *testing.T) {
+	script := NewScript("return 1")
+
+	assert.Equal(t, "return 1", script.Source())
+	// SCRIPT LOAD "return 1"
+	assert.Equal(t, "e0e1f9fabfc9d4800c877a703b823ac0578ff8db", script.Hash())
+	assert.NotEqual(t, script.Hash(), NewScript("return 2").Hash())
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// StreamMessage is a message of a stream.
+type StreamMessage struct {
+	// ID is the ID of the message in the stream, e.g. "1526919030474-55".
+	ID string
+	// Values are the field-value pairs of the message.
+	Values map[string]interface{}
+}
+
+// PendingMessage is a message delivered to a consumer of a group which wasn't acknowledged yet.
+type PendingMessage struct {
+	// ID is the ID of the message in the stream.
+	ID string
+	// Consumer is the consumer the message was delivered to.
+	Consumer string
+	// Idle is the time elapsed since the message was last delivered.
+	Idle time.Duration
+	// Deliveries is the number of times the message was delivered.
+	Deliveries int64
+}
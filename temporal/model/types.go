@@ -7,6 +7,7 @@ import (
 
 const (
 	RedisV9Type = "redisv9"
+	MemoryType  = "memory"
 )
 
 type Connector interface {
@@ -50,7 +51,12 @@ type List interface {
 	Pop(ctx context.Context, key string, stop int64) ([]string, error)
 }
 
+// KeyValue interface represents the string values of keys, along with the sorted sets and hashes also stored
+// under keys, e.g. to buffer analytics records and to store the metadata of sessions.
 type KeyValue interface {
+	SortedSet
+	Hash
+
 	// Get retrieves the value for a given key
 	Get(ctx context.Context, key string) (value string, err error)
 	// Set sets the string value of a key
@@ -83,6 +89,46 @@ type KeyValue interface {
 	// GetKeysWithOpts retrieves keys with options like filter, cursor, and count
 	GetKeysWithOpts(ctx context.Context, searchStr string, cursors map[string]uint64,
 		count int64) (keys []string, updatedCursor map[string]uint64, continueScan bool, err error)
+	// ScanKeys returns a page of the keys matching the given pattern, starting at cursor, along with the cursor
+	// of the next page. Count is a hint of the number of keys per page, which can be more or less.
+	// The iteration is over when next.Done() is true. On error, the given cursor is returned so the page can be retried.
+	ScanKeys(ctx context.Context, pattern string, cursor Cursor, count int64) (keys []string, next Cursor, err error)
+	// Pipeline returns a new Pipeline, executing its commands in a single round trip
+	Pipeline() Pipeline
+	// TxPipeline returns a new Pipeline, executing its commands in a single round trip as a transaction:
+	// the commands are executed atomically, without commands of other clients in between
+	TxPipeline() Pipeline
+}
+
+// Pipeline interface represents a batch of KeyValue commands, queued to be executed together by Exec,
+// e.g. to write hundreds of keys in a single round trip. Each command returns its PipelineResult,
+// set once the pipeline was executed. The commands have the semantics of the KeyValue methods of the same name.
+type Pipeline interface {
+	// Get queues the retrieval of the value of a key.
+	Get(key string) *PipelineResult
+	// Set queues the setting of the string value of a key.
+	Set(key, value string, ttl time.Duration) *PipelineResult
+	// SetIfNotExist queues the setting of the string value of a key if the key does not exist.
+	SetIfNotExist(key, value string, expiration time.Duration) *PipelineResult
+	// Delete queues the removal of a key.
+	Delete(key string) *PipelineResult
+	// Increment queues the increment of the integer value of a key by one.
+	Increment(key string) *PipelineResult
+	// Decrement queues the decrement of the integer value of a key by one.
+	Decrement(key string) *PipelineResult
+	// Exists queues the check of the existence of a key.
+	Exists(key string) *PipelineResult
+	// Expire queues the setting of a timeout on a key.
+	Expire(key string, ttl time.Duration) *PipelineResult
+	// TTL queues the retrieval of the remaining time to live of a key.
+	TTL(key string) *PipelineResult
+	// Len returns the number of commands queued.
+	Len() int
+	// Exec executes the queued commands and sets their results, emptying the pipeline so it can be reused.
+	// Returns the first error of the commands, except temperr.KeyNotFound, which is only set in the results.
+	Exec(ctx context.Context) error
+	// Discard empties the pipeline without executing its commands.
+	Discard()
 }
 
 type Flusher interface {
@@ -105,6 +151,21 @@ type SortedSet interface {
 	RemoveMembersByScoreRange(ctx context.Context, key, minScore, maxScore string) (int64, error)
 }
 
+// Hash interface represents the hashes of fields and their values stored under keys.
+type Hash interface {
+	// SetFields sets the values of the fields of the hash stored at key, creating the hash if it does not exist.
+	// Returns the number of fields added, not counting the fields which already existed.
+	SetFields(ctx context.Context, key string, fields map[string]interface{}) (int64, error)
+
+	// GetAllFields returns the fields of the hash stored at key along with their values,
+	// or an empty map if the key does not exist.
+	GetAllFields(ctx context.Context, key string) (map[string]string, error)
+
+	// DeleteFields removes the specified fields from the hash stored at key.
+	// Returns the number of fields removed, not counting the fields which did not exist.
+	DeleteFields(ctx context.Context, key string, fields ...string) (int64, error)
+}
+
 type Set interface {
 	// Returns all the members of the set value stored at key.
 	Members(ctx context.Context, key string) ([]string, error)
@@ -122,6 +183,116 @@ type Set interface {
 	IsMember(ctx context.Context, key, member string) (bool, error)
 }
 
+// Stream interface represents an append-only log of messages read by consumer groups, for a reliable delivery:
+// each message is delivered to a single consumer of a group, and stays pending until the consumer acknowledges it.
+type Stream interface {
+	// AddMessage appends a message with the given values to the stream, creating the stream if it does not exist.
+	// If maxLen is greater than 0, the oldest messages are trimmed to keep about maxLen messages.
+	// Returns the ID of the message.
+	AddMessage(ctx context.Context, stream string, values map[string]interface{}, maxLen int64) (string, error)
+
+	// StreamLength returns the number of messages of the stream.
+	StreamLength(ctx context.Context, stream string) (int64, error)
+
+	// CreateGroup creates a consumer group of the stream, creating the stream if it does not exist.
+	// The group reads the messages after the start ID: "$" for the new messages only, "0" for all of them.
+	// Returns temperr.GroupExists if the group already exists.
+	CreateGroup(ctx context.Context, stream, group, start string) error
+
+	// DestroyGroup removes a consumer group of the stream, along with its pending messages.
+	DestroyGroup(ctx context.Context, stream, group string) error
+
+	// RemoveConsumer removes a consumer from a group.
+	// Returns the number of messages which were pending for the consumer, and are no longer pending.
+	RemoveConsumer(ctx context.Context, stream, group, consumer string) (int64, error)
+
+	// ReadGroup delivers up to count messages of the stream never delivered to the group to the consumer.
+	// If there are none, it waits for new ones up to block, and returns no message if none was added.
+	// It doesn't wait if block is 0. Returns temperr.GroupNotFound if the group does not exist.
+	ReadGroup(ctx context.Context,
+		stream, group, consumer string,
+		count int64,
+		block time.Duration,
+	) ([]StreamMessage, error)
+
+	// Acknowledge removes the messages with the given IDs from the pending messages of the group.
+	// Returns the number of messages acknowledged.
+	Acknowledge(ctx context.Context, stream, group string, ids ...string) (int64, error)
+
+	// Pending returns up to count pending messages of the group, from the oldest one,
+	// which were delivered at least minIdle ago. Returns temperr.UnsupportedFeature if minIdle is set
+	// and the server doesn't support Capabilities.StreamAutoClaim.
+	Pending(ctx context.Context, stream, group string, minIdle time.Duration, count int64) ([]PendingMessage, error)
+
+	// Claim delivers the pending messages with the given IDs to the consumer, if they were delivered at least
+	// minIdle ago, e.g. to process the messages of a consumer which failed. Returns the messages claimed.
+	Claim(ctx context.Context,
+		stream, group, consumer string,
+		minIdle time.Duration,
+		ids ...string,
+	) ([]StreamMessage, error)
+
+	// AutoClaim delivers up to count pending messages of the group to the consumer, from the start ID, if they
+	// were delivered at least minIdle ago. Returns the messages claimed, along with the start ID of the next call,
+	// which is "0-0" once all the pending messages were scanned.
+	// Returns temperr.UnsupportedFeature if the server doesn't support Capabilities.StreamAutoClaim.
+	AutoClaim(ctx context.Context,
+		stream, group, consumer string,
+		minIdle time.Duration,
+		start string,
+		count int64,
+	) ([]StreamMessage, string, error)
+}
+
+// Lock interface represents distributed locks with a time to live, so the lock of an instance which stopped
+// without releasing it expires after its TTL.
+type Lock interface {
+	// Acquire acquires the lock of the given name for ttl.
+	// Returns the token of the lock, needed to refresh and release it,
+	// or temperr.LockNotAcquired if the lock is already held.
+	Acquire(ctx context.Context, name string, ttl time.Duration) (token string, err error)
+
+	// Refresh extends the lock of the given name held with token, so it expires after ttl.
+	// Returns temperr.LockNotHeld if the lock expired or is held by another token.
+	Refresh(ctx context.Context, name, token string, ttl time.Duration) error
+
+	// Release releases the lock of the given name held with token.
+	// Returns temperr.LockNotHeld if the lock expired or is held by another token.
+	Release(ctx context.Context, name, token string) error
+}
+
+// RateLimit interface represents rate limiters shared by the instances using the same keys.
+type RateLimit interface {
+	// Allow counts a request against the limit of the given key with a sliding window:
+	// the request is allowed if less than limit requests were allowed during the last window.
+	Allow(ctx context.Context, key string, limit int64, window time.Duration) (RateLimitResult, error)
+
+	// AllowTokenBucket counts a request against the limit of the given key with a token bucket:
+	// the bucket holds up to limit tokens, refilled at the rate of limit tokens per window,
+	// and the request is allowed if it can take a token from the bucket. It allows bursts of up to limit requests.
+	AllowTokenBucket(ctx context.Context, key string, limit int64, window time.Duration) (RateLimitResult, error)
+}
+
+// Scripter interface represents the Lua scripts run by the server, e.g. to update several keys atomically.
+type Scripter interface {
+	// RunScript runs the script with the given keys and arguments, and returns its result, or nil if the script
+	// returned nil. The script is run by its SHA1, and its source is sent only if the server doesn't cache it yet.
+	// On a cluster, the keys must map to the same slot, e.g. with a hash tag,
+	// otherwise temperr.CrossSlot is returned.
+	RunScript(ctx context.Context, script *Script, keys []string, args ...interface{}) (interface{}, error)
+
+	// LoadScripts loads the scripts into the cache of the server, e.g. on startup,
+	// so their first run doesn't need to send their source.
+	LoadScripts(ctx context.Context, scripts ...*Script) error
+}
+
+// Info interface represents the information of the server a connector is connected to, so the features it doesn't
+// support can be avoided instead of failing when they are used.
+type Info interface {
+	// GetConnectionInfo returns the type and version of the server, along with the features it supports.
+	GetConnectionInfo(ctx context.Context) (ConnectionInfo, error)
+}
+
 // Queue interface represents a pub/sub queue with methods to publish messages
 // and subscribe to channels.
 type Queue interface {
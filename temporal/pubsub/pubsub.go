@@ -0,0 +1,354 @@
+// Package pubsub publishes messages to channels and delivers the messages of subscriptions on Go channels,
+// subscribing again when the connection is lost, e.g. on a failover, e.g.
+//
+//	ps, err := pubsub.NewPubSub(conn)
+//	...
+//	messages, err := ps.Subscribe(ctx, "notifications")
+//	...
+//	for msg := range messages {
+//		payload, _ := msg.Payload()
+//		...
+//	}
+//
+// As with Redis pub/sub, the messages are delivered at most once: the messages published while subscribing again
+// are lost. The published, received and dropped messages, the errors and the resubscriptions are counted with
+// OpenTelemetry metrics.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/queue"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+// InstrumentationName is the name of the meter used to record the metrics.
+const InstrumentationName = "github.com/TykTechnologies/storage/temporal/pubsub"
+
+const (
+	// PublishedMetric is the counter of the messages published.
+	PublishedMetric = "temporal.pubsub.published"
+	// ReceivedMetric is the counter of the messages received by the subscriptions.
+	ReceivedMetric = "temporal.pubsub.received"
+	// DroppedMetric is the counter of the messages received and dropped because the buffer was full.
+	DroppedMetric = "temporal.pubsub.dropped"
+	// ErrorsMetric is the counter of the errors publishing and receiving messages.
+	ErrorsMetric = "temporal.pubsub.errors"
+	// ResubscriptionsMetric is the counter of the subscriptions made again after an error.
+	ResubscriptionsMetric = "temporal.pubsub.resubscriptions"
+)
+
+// ChannelKey is the attribute of the metrics set to the channel of the message.
+const ChannelKey = attribute.Key("messaging.destination")
+
+const (
+	defaultBuffer  = 100
+	defaultBackoff = 100 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+type config struct {
+	buffer        int
+	dropWhenFull  bool
+	backoff       time.Duration
+	meterProvider metric.MeterProvider
+}
+
+// Option configures the PubSub.
+type Option func(*config)
+
+// WithBuffer sets the number of messages buffered by each subscription for its reader. Defaults to 100.
+func WithBuffer(size int) Option {
+	return func(c *config) {
+		c.buffer = size
+	}
+}
+
+// WithDropWhenFull drops the messages received when the buffer of the subscription is full, instead of waiting for
+// the reader, which can get the connection closed by Redis when too many messages are waiting to be received.
+func WithDropWhenFull() Option {
+	return func(c *config) {
+		c.dropWhenFull = true
+	}
+}
+
+// WithResubscribeBackoff sets the time waited before subscribing again after an error, which is doubled after
+// each failed attempt, up to 30 seconds. Defaults to 100 milliseconds.
+func WithResubscribeBackoff(backoff time.Duration) Option {
+	return func(c *config) {
+		c.backoff = backoff
+	}
+}
+
+// WithMeterProvider sets the provider of the meter recording the metrics. The global one is used by default.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// PubSub publishes and subscribes to channels through a model.Queue.
+type PubSub struct {
+	queue model.Queue
+	cfg   config
+
+	published       instrument.Int64Counter
+	received        instrument.Int64Counter
+	dropped         instrument.Int64Counter
+	errors          instrument.Int64Counter
+	resubscriptions instrument.Int64Counter
+}
+
+// NewPubSub returns a new PubSub based on the type of the connector.
+func NewPubSub(conn model.Connector, opts ...Option) (*PubSub, error) {
+	q, err := queue.NewQueue(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(q, opts...)
+}
+
+// New returns a new PubSub publishing and subscribing through q.
+func New(q model.Queue, opts ...Option) (*PubSub, error) {
+	cfg := config{buffer: defaultBuffer, backoff: defaultBackoff}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.buffer < 0 || cfg.backoff <= 0 {
+		return nil, temperr.InvalidConfiguration
+	}
+
+	if cfg.meterProvider == nil {
+		cfg.meterProvider = global.MeterProvider()
+	}
+
+	p := &PubSub{queue: q, cfg: cfg}
+	meter := cfg.meterProvider.Meter(InstrumentationName)
+
+	counters := []struct {
+		counter     *instrument.Int64Counter
+		name        string
+		description string
+	}{
+		{&p.published, PublishedMetric, "Number of messages published"},
+		{&p.received, ReceivedMetric, "Number of messages received by the subscriptions"},
+		{&p.dropped, DroppedMetric, "Number of messages dropped because the buffer of the subscription was full"},
+		{&p.errors, ErrorsMetric, "Number of errors publishing and receiving messages"},
+		{&p.resubscriptions, ResubscriptionsMetric, "Number of subscriptions made again after an error"},
+	}
+
+	for _, c := range counters {
+		counter, err := meter.Int64Counter(c.name, instrument.WithDescription(c.description))
+		if err != nil {
+			return nil, err
+		}
+
+		*c.counter = counter
+	}
+
+	return p, nil
+}
+
+// Publish sends a message to the channel. It returns the number of subscribers which received the message.
+func (p *PubSub) Publish(ctx context.Context, channel, payload string) (int64, error) {
+	receivers, err := p.queue.Publish(ctx, channel, payload)
+	if err != nil {
+		p.errors.Add(ctx, 1, ChannelKey.String(channel))
+
+		return receivers, err
+	}
+
+	p.published.Add(ctx, 1, ChannelKey.String(channel))
+
+	return receivers, nil
+}
+
+// Subscribe subscribes to the channels, and returns the Go channel their messages are delivered on.
+// It returns an error if the subscription can't be made. Once made, it's made again after an error until ctx is
+// done, or the connection of the connector is closed, after which the Go channel is closed.
+// Only the messages of type model.MessageTypeMessage are delivered.
+func (p *PubSub) Subscribe(ctx context.Context, channels ...string) (<-chan model.Message, error) {
+	sub, err := p.subscribe(ctx, channels)
+	if err != nil {
+		p.errors.Add(ctx, 1)
+
+		return nil, err
+	}
+
+	s := &subscription{
+		pubsub:   p,
+		channels: channels,
+		current:  sub,
+		messages: make(chan model.Message, p.cfg.buffer),
+	}
+
+	go s.run(ctx)
+
+	return s.messages, nil
+}
+
+// subscribe subscribes to the channels, waiting for the confirmation of the subscription.
+func (p *PubSub) subscribe(ctx context.Context, channels []string) (model.Subscription, error) {
+	sub := p.queue.Subscribe(ctx, channels...)
+
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// subscription delivers the messages of a subscription on its Go channel, and subscribes again after an error.
+type subscription struct {
+	pubsub   *PubSub
+	channels []string
+	messages chan model.Message
+
+	mu      sync.Mutex
+	current model.Subscription
+	closed  bool
+}
+
+func (s *subscription) run(ctx context.Context) {
+	defer close(s.messages)
+	defer s.close()
+
+	// closing the subscription interrupts Receive, which doesn't return when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.close()
+		case <-done:
+		}
+	}()
+
+	for {
+		msg, err := s.subscription().Receive(ctx)
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			s.pubsub.errors.Add(ctx, 1)
+
+			if !s.resubscribe(ctx, err) {
+				return
+			}
+		case msg.Type() == model.MessageTypeMessage:
+			s.pubsub.received.Add(ctx, 1, messageAttributes(msg)...)
+
+			if !s.deliver(ctx, msg) {
+				return
+			}
+		}
+	}
+}
+
+// deliver sends msg on the Go channel, or drops it if the buffer is full and the messages are dropped.
+// It returns false if ctx is done.
+func (s *subscription) deliver(ctx context.Context, msg model.Message) bool {
+	if s.pubsub.cfg.dropWhenFull {
+		select {
+		case s.messages <- msg:
+		default:
+			s.pubsub.dropped.Add(ctx, 1, messageAttributes(msg)...)
+		}
+
+		return true
+	}
+
+	select {
+	case s.messages <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// resubscribe subscribes again after the error err of the subscription, with a backoff between the attempts.
+// It returns false if ctx is done or the connection of the connector is closed.
+func (s *subscription) resubscribe(ctx context.Context, err error) bool {
+	backoff := s.pubsub.cfg.backoff
+
+	for !errors.Is(err, temperr.ClosedConnection) {
+		s.subscription().Close()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		var sub model.Subscription
+
+		sub, err = s.pubsub.subscribe(ctx, s.channels)
+		if err != nil {
+			s.pubsub.errors.Add(ctx, 1)
+
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+
+			continue
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.closed {
+			sub.Close()
+
+			return false
+		}
+
+		s.current = sub
+		s.pubsub.resubscriptions.Add(ctx, 1)
+
+		return true
+	}
+
+	return false
+}
+
+func (s *subscription) subscription() model.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current
+}
+
+func (s *subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		s.current.Close()
+	}
+}
+
+// messageAttributes returns the attributes of the metrics of msg.
+func messageAttributes(msg model.Message) []attribute.KeyValue {
+	channel, err := msg.Channel()
+	if err != nil {
+		return nil
+	}
+
+	return []attribute.KeyValue{ChannelKey.String(channel)}
+}
@@ -0,0 +1,327 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var errConnectionReset = errors.New("connection reset")
+
+type fakeMessage struct {
+	typ     string
+	channel string
+	payload string
+}
+
+func (m fakeMessage) Type() string {
+	return m.typ
+}
+
+func (m fakeMessage) Channel() (string, error) {
+	return m.channel, nil
+}
+
+func (m fakeMessage) Payload() (string, error) {
+	return m.payload, nil
+}
+
+// fakeSubscription receives the messages sent on its channel, and fails with errConnectionReset once the
+// channel is closed, as a subscription does when its connection is lost.
+type fakeSubscription struct {
+	messages chan model.Message
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func newFakeSubscription(messages ...model.Message) *fakeSubscription {
+	s := &fakeSubscription{
+		messages: make(chan model.Message, 10),
+		closed:   make(chan struct{}),
+	}
+
+	s.messages <- fakeMessage{typ: model.MessageTypeSubscription}
+
+	for _, msg := range messages {
+		s.messages <- msg
+	}
+
+	return s
+}
+
+func (s *fakeSubscription) Receive(ctx context.Context) (model.Message, error) {
+	select {
+	case msg, ok := <-s.messages:
+		if !ok {
+			return nil, errConnectionReset
+		}
+
+		return msg, nil
+	case <-s.closed:
+		return nil, temperr.ClosedConnection
+	}
+}
+
+func (s *fakeSubscription) Close() error {
+	s.once.Do(func() { close(s.closed) })
+
+	return nil
+}
+
+// fakeQueue hands out its subscriptions in order, failing with errConnectionReset once they are all used.
+type fakeQueue struct {
+	subscriptions chan *fakeSubscription
+	publishErr    error
+}
+
+func (q *fakeQueue) Publish(ctx context.Context, channel, message string) (int64, error) {
+	if q.publishErr != nil {
+		return 0, q.publishErr
+	}
+
+	return 1, nil
+}
+
+func (q *fakeQueue) Subscribe(ctx context.Context, channels ...string) model.Subscription {
+	select {
+	case sub := <-q.subscriptions:
+		return sub
+	default:
+		failed := newFakeSubscription()
+		failed.messages = make(chan model.Message)
+		close(failed.messages)
+
+		return failed
+	}
+}
+
+func newFakeQueue(subscriptions ...*fakeSubscription) *fakeQueue {
+	q := &fakeQueue{subscriptions: make(chan *fakeSubscription, len(subscriptions))}
+	for _, sub := range subscriptions {
+		q.subscriptions <- sub
+	}
+
+	return q
+}
+
+func newTestPubSub(t *testing.T, q model.Queue, opts ...Option) (*PubSub, sdkmetric.Reader) {
+	t.Helper()
+
+	reader := sdkmetric.NewManualReader()
+	opts = append(opts,
+		WithResubscribeBackoff(time.Millisecond),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+
+	ps, err := New(q, opts...)
+	assert.Nil(t, err)
+
+	return ps, reader
+}
+
+// counters returns the values of the counters recorded by reader, by name.
+func counters(t *testing.T, reader sdkmetric.Reader) map[string]int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+
+	err := reader.Collect(context.Background(), &rm)
+	assert.Nil(t, err)
+
+	values := map[string]int64{}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+
+			for _, dp := range sum.DataPoints {
+				values[m.Name] += dp.Value
+			}
+		}
+	}
+
+	return values
+}
+
+// payloads reads n messages from messages, returning their payloads.
+func payloads(t *testing.T, messages <-chan model.Message, n int) []string {
+	t.Helper()
+
+	var received []string
+
+	for len(received) < n {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return received
+			}
+
+			payload, err := msg.Payload()
+			assert.Nil(t, err)
+
+			received = append(received, payload)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d messages, expected %d", len(received), n)
+		}
+	}
+
+	return received
+}
+
+func message(payload string) model.Message {
+	return fakeMessage{typ: model.MessageTypeMessage, channel: "channel", payload: payload}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(newFakeQueue(), WithBuffer(-1))
+	assert.Equal(t, temperr.InvalidConfiguration, err)
+
+	_, err = New(newFakeQueue(), WithResubscribeBackoff(0))
+	assert.Equal(t, temperr.InvalidConfiguration, err)
+
+	ps, err := New(newFakeQueue())
+	assert.Nil(t, err)
+	assert.Equal(t, defaultBuffer, ps.cfg.buffer)
+}
+
+func TestPubSub_Publish(t *testing.T) {
+	ctx := context.Background()
+	q := newFakeQueue()
+	ps, reader := newTestPubSub(t, q)
+
+	receivers, err := ps.Publish(ctx, "channel", "message")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), receivers)
+
+	q.publishErr = temperr.ClosedConnection
+
+	_, err = ps.Publish(ctx, "channel", "message")
+	assert.Equal(t, temperr.ClosedConnection, err)
+
+	metrics := counters(t, reader)
+	assert.Equal(t, int64(1), metrics[PublishedMetric])
+	assert.Equal(t, int64(1), metrics[ErrorsMetric])
+}
+
+func TestPubSub_Subscribe(t *testing.T) {
+	t.Run("delivered_messages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sub := newFakeSubscription(message("1"), fakeMessage{typ: "pong"}, message("2"))
+		ps, reader := newTestPubSub(t, newFakeQueue(sub))
+
+		messages, err := ps.Subscribe(ctx, "channel")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1", "2"}, payloads(t, messages, 2))
+
+		// the subscription is closed and the messages channel too once ctx is done
+		cancel()
+
+		_, ok := <-messages
+		assert.False(t, ok)
+		assert.Equal(t, int64(2), counters(t, reader)[ReceivedMetric])
+	})
+
+	t.Run("failed_subscription", func(t *testing.T) {
+		ps, reader := newTestPubSub(t, newFakeQueue())
+
+		_, err := ps.Subscribe(context.Background(), "channel")
+		assert.Equal(t, errConnectionReset, err)
+		assert.Equal(t, int64(1), counters(t, reader)[ErrorsMetric])
+	})
+
+	t.Run("resubscription", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		first := newFakeSubscription(message("1"))
+		second := newFakeSubscription(message("2"))
+		ps, reader := newTestPubSub(t, newFakeQueue(first, second))
+
+		messages, err := ps.Subscribe(ctx, "channel")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1"}, payloads(t, messages, 1))
+
+		// the connection of the first subscription is lost, so the channels are subscribed again
+		close(first.messages)
+
+		assert.Equal(t, []string{"2"}, payloads(t, messages, 1))
+
+		metrics := counters(t, reader)
+		assert.Equal(t, int64(1), metrics[ResubscriptionsMetric])
+		assert.Equal(t, int64(1), metrics[ErrorsMetric])
+	})
+
+	t.Run("closed_connection", func(t *testing.T) {
+		sub := newFakeSubscription()
+		ps, reader := newTestPubSub(t, newFakeQueue(sub))
+
+		messages, err := ps.Subscribe(context.Background(), "channel")
+		assert.Nil(t, err)
+
+		// the connection of the connector is closed, so the channels aren't subscribed again
+		assert.Nil(t, sub.Close())
+
+		_, ok := <-messages
+		assert.False(t, ok)
+		assert.Equal(t, int64(0), counters(t, reader)[ResubscriptionsMetric])
+	})
+
+	t.Run("dropped_messages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sub := newFakeSubscription(message("1"), message("2"), message("3"))
+		ps, reader := newTestPubSub(t, newFakeQueue(sub), WithBuffer(1), WithDropWhenFull())
+
+		messages, err := ps.Subscribe(ctx, "channel")
+		assert.Nil(t, err)
+
+		assert.Eventually(t, func() bool {
+			return counters(t, reader)[ReceivedMetric] == 3
+		}, time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, []string{"1"}, payloads(t, messages, 1))
+		assert.Equal(t, int64(2), counters(t, reader)[DroppedMetric])
+	})
+}
+
+func TestPubSub_Connectors(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ps, err := NewPubSub(connector)
+			assert.Nil(t, err)
+
+			messages, err := ps.Subscribe(ctx, "notifications")
+			assert.Nil(t, err)
+
+			receivers, err := ps.Publish(ctx, "notifications", "hello")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), receivers)
+
+			assert.Equal(t, []string{"hello"}, payloads(t, messages, 1))
+
+			cancel()
+
+			_, ok := <-messages
+			assert.False(t, ok)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
@@ -8,12 +9,17 @@ import (
 
 type Queue = model.Queue
 
-var _ Queue = (*redisv9.RedisV9)(nil)
+var (
+	_ Queue = (*redisv9.RedisV9)(nil)
+	_ Queue = (*memory.Memory)(nil)
+)
 
 func NewQueue(conn model.Connector) (Queue, error) {
 	switch conn.Type() {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
@@ -0,0 +1,27 @@
+package ratelimit
+
+import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
+	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+type RateLimit = model.RateLimit
+
+var (
+	_ RateLimit = (*redisv9.RedisV9)(nil)
+	_ RateLimit = (*memory.Memory)(nil)
+)
+
+// NewRateLimit returns a new model.RateLimit storage based on the type of the connector.
+func NewRateLimit(conn model.Connector) (RateLimit, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/flusher"
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+type allowFunc func(ctx context.Context, key string, limit int64, window time.Duration) (model.RateLimitResult, error)
+
+func TestRateLimit(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	limiters := []struct {
+		name  string
+		allow func(rateLimit RateLimit) allowFunc
+	}{
+		{
+			name: "sliding_window",
+			allow: func(rateLimit RateLimit) allowFunc {
+				return rateLimit.Allow
+			},
+		},
+		{
+			name: "token_bucket",
+			allow: func(rateLimit RateLimit) allowFunc {
+				return rateLimit.AllowTokenBucket
+			},
+		},
+	}
+
+	for _, connector := range connectors {
+		for _, limiter := range limiters {
+			t.Run(connector.Type()+"_"+limiter.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				rateLimit, err := NewRateLimit(connector)
+				assert.Nil(t, err)
+
+				allow := limiter.allow(rateLimit)
+
+				_, err = allow(ctx, "", 5, time.Second)
+				assert.Equal(t, temperr.KeyEmpty, err)
+
+				_, err = allow(ctx, "key", 0, time.Second)
+				assert.Equal(t, temperr.InvalidRateLimit, err)
+
+				_, err = allow(ctx, "key", 5, 0)
+				assert.Equal(t, temperr.InvalidRateLimit, err)
+
+				for i := int64(0); i < 5; i++ {
+					result, err := allow(ctx, "key", 5, 500*time.Millisecond)
+					assert.Nil(t, err)
+					assert.True(t, result.Allowed)
+					assert.Equal(t, 4-i, result.Remaining)
+					assert.Zero(t, result.RetryAfter)
+				}
+
+				result, err := allow(ctx, "key", 5, 500*time.Millisecond)
+				assert.Nil(t, err)
+				assert.False(t, result.Allowed)
+				assert.Zero(t, result.Remaining)
+				assert.Greater(t, result.RetryAfter, time.Duration(0))
+				assert.LessOrEqual(t, result.RetryAfter, 500*time.Millisecond)
+
+				// the limits of the keys are independent
+				result, err = allow(ctx, "other_key", 5, 500*time.Millisecond)
+				assert.Nil(t, err)
+				assert.True(t, result.Allowed)
+
+				// the requests are allowed again once the window elapsed
+				time.Sleep(600 * time.Millisecond)
+
+				result, err = allow(ctx, "key", 5, 500*time.Millisecond)
+				assert.Nil(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, int64(4), result.Remaining)
+			})
+		}
+	}
+}
+
+func TestRateLimit_Concurrent(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func() {
+				assert.Nil(t, flusher.FlushAll(ctx))
+			}()
+
+			rateLimit, err := NewRateLimit(connector)
+			assert.Nil(t, err)
+
+			var wg sync.WaitGroup
+
+			var mutex sync.Mutex
+
+			allowed := map[string]int{}
+
+			// the requests are counted atomically, so exactly limit requests are allowed
+			for i := 0; i < 50; i++ {
+				wg.Add(2)
+
+				go func() {
+					defer wg.Done()
+
+					result, err := rateLimit.Allow(ctx, "sliding", 20, time.Minute)
+					assert.Nil(t, err)
+
+					mutex.Lock()
+					defer mutex.Unlock()
+
+					if result.Allowed {
+						allowed["sliding"]++
+					}
+				}()
+
+				go func() {
+					defer wg.Done()
+
+					result, err := rateLimit.AllowTokenBucket(ctx, "bucket", 20, time.Hour)
+					assert.Nil(t, err)
+
+					mutex.Lock()
+					defer mutex.Unlock()
+
+					if result.Allowed {
+						allowed["bucket"]++
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			assert.Equal(t, map[string]int{"sliding": 20, "bucket": 20}, allowed)
+		})
+	}
+}
+
+func TestRateLimit_ClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			rateLimit, err := NewRateLimit(connector)
+			assert.Nil(t, err)
+
+			_, err = rateLimit.Allow(ctx, "key", 5, time.Second)
+			assert.Equal(t, temperr.ClosedConnection, err)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package script
+
+import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+type Scripter = model.Scripter
+
+var _ Scripter = (*redisv9.RedisV9)(nil)
+
+// NewScripter returns a new model.Scripter based on the type of the connector.
+func NewScripter(conn model.Connector) (Scripter, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedisV9WithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
@@ -0,0 +1,123 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/flusher"
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+)
+
+// quotaScript increments a quota and returns the remaining requests, or nil once the quota is exceeded.
+var quotaScript = model.NewScript(`
+local used = redis.call("INCR", KEYS[1])
+if used > tonumber(ARGV[1]) then
+	return nil
+end
+return tonumber(ARGV[1]) - used`)
+
+func TestScripter_RunScript(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		// scripts aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func() {
+				assert.Nil(t, flusher.FlushAll(ctx))
+			}()
+
+			scripter, err := NewScripter(connector)
+			assert.Nil(t, err)
+
+			for _, expected := range []interface{}{int64(1), int64(0), nil} {
+				result, err := scripter.RunScript(ctx, quotaScript, []string{"{key}:quota"}, 2)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, result)
+			}
+
+			// a script never run nor loaded is sent to the server on its first run
+			unique := model.NewScript(fmt.Sprintf("return %q", time.Now().String()))
+
+			result, err := scripter.RunScript(ctx, unique, nil)
+			assert.Nil(t, err)
+			assert.NotEmpty(t, result)
+
+			_, err = scripter.RunScript(ctx, model.NewScript("return redis.call('UNKNOWN')"), nil)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestScripter_LoadScripts(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		// scripts aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func() {
+				assert.Nil(t, flusher.FlushAll(ctx))
+			}()
+
+			scripter, err := NewScripter(connector)
+			assert.Nil(t, err)
+
+			assert.Nil(t, scripter.LoadScripts(ctx, quotaScript, model.NewScript("return 1")))
+
+			result, err := scripter.RunScript(ctx, quotaScript, []string{"{key}:quota"}, 2)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), result)
+
+			assert.NotNil(t, scripter.LoadScripts(ctx, model.NewScript("return (")))
+		})
+	}
+}
+
+func TestScripter_ClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		// scripts aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			scripter, err := NewScripter(connector)
+			assert.Nil(t, err)
+
+			_, err = scripter.RunScript(ctx, quotaScript, []string{"quota"}, 2)
+			assert.Equal(t, temperr.ClosedConnection, err)
+
+			assert.Equal(t, temperr.ClosedConnection, scripter.LoadScripts(ctx, quotaScript))
+		})
+	}
+}
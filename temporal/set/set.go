@@ -1,6 +1,7 @@
 package set
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
@@ -8,12 +9,17 @@ import (
 
 type Set = model.Set
 
-var _ Set = (*redisv9.RedisV9)(nil)
+var (
+	_ Set = (*redisv9.RedisV9)(nil)
+	_ Set = (*memory.Memory)(nil)
+)
 
 func NewSet(conn model.Connector) (Set, error) {
 	switch conn.Type() {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
@@ -1,6 +1,7 @@
 package sortedset
 
 import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/memory"
 	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
 	"github.com/TykTechnologies/storage/temporal/model"
 	"github.com/TykTechnologies/storage/temporal/temperr"
@@ -8,12 +9,17 @@ import (
 
 type SortedSet = model.SortedSet
 
-var _ SortedSet = (*redisv9.RedisV9)(nil)
+var (
+	_ SortedSet = (*redisv9.RedisV9)(nil)
+	_ SortedSet = (*memory.Memory)(nil)
+)
 
 func NewSortedSet(conn model.Connector) (SortedSet, error) {
 	switch conn.Type() {
 	case model.RedisV9Type:
 		return redisv9.NewRedisV9WithConnection(conn)
+	case model.MemoryType:
+		return memory.NewMemoryWithConnection(conn)
 	default:
 		return nil, temperr.InvalidHandlerType
 	}
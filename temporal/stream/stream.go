@@ -0,0 +1,21 @@
+package stream
+
+import (
+	"github.com/TykTechnologies/storage/temporal/internal/driver/redisv9"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+)
+
+type Stream = model.Stream
+
+var _ Stream = (*redisv9.RedisV9)(nil)
+
+// NewStream returns a new model.Stream storage based on the type of the connector.
+func NewStream(conn model.Connector) (Stream, error) {
+	switch conn.Type() {
+	case model.RedisV9Type:
+		return redisv9.NewRedisV9WithConnection(conn)
+	default:
+		return nil, temperr.InvalidHandlerType
+	}
+}
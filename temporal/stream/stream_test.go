@@ -0,0 +1,386 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TykTechnologies/storage/temporal/flusher"
+	"github.com/TykTechnologies/storage/temporal/internal/testutil"
+	"github.com/TykTechnologies/storage/temporal/model"
+	"github.com/TykTechnologies/storage/temporal/temperr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream_AddMessage(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	tcs := []struct {
+		name           string
+		stream         string
+		messages       int
+		maxLen         int64
+		expectedLength int64
+		expectedErr    error
+	}{
+		{
+			name:           "new_stream",
+			stream:         "events",
+			messages:       3,
+			expectedLength: 3,
+		},
+		{
+			// the stream is trimmed approximately, so it keeps at least maxLen messages
+			name:     "trimmed_stream",
+			stream:   "events",
+			messages: 500,
+			maxLen:   100,
+		},
+		{
+			name:        "empty_stream",
+			stream:      "",
+			messages:    1,
+			expectedErr: temperr.KeyEmpty,
+		},
+	}
+
+	for _, connector := range connectors {
+		// streams aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		for _, tc := range tcs {
+			t.Run(connector.Type()+"_"+tc.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				stream, err := NewStream(connector)
+				assert.Nil(t, err)
+
+				var lastID string
+
+				for i := 0; i < tc.messages; i++ {
+					id, err := stream.AddMessage(ctx, tc.stream, map[string]interface{}{"n": i}, tc.maxLen)
+					assert.Equal(t, tc.expectedErr, err)
+
+					if err == nil {
+						assert.NotEmpty(t, id)
+						assert.NotEqual(t, lastID, id)
+					}
+
+					lastID = id
+				}
+
+				if tc.expectedErr != nil {
+					return
+				}
+
+				length, err := stream.StreamLength(ctx, tc.stream)
+				assert.Nil(t, err)
+
+				if tc.maxLen > 0 {
+					assert.GreaterOrEqual(t, length, tc.maxLen)
+					assert.Less(t, length, int64(tc.messages))
+
+					return
+				}
+
+				assert.Equal(t, tc.expectedLength, length)
+			})
+		}
+	}
+}
+
+func TestStream_CreateGroup(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	tcs := []struct {
+		name        string
+		setup       func(stream Stream)
+		group       string
+		expectedErr error
+	}{
+		{
+			name:  "new_stream",
+			group: "group",
+		},
+		{
+			name: "existing_stream",
+			setup: func(stream Stream) {
+				_, err := stream.AddMessage(context.Background(), "events", map[string]interface{}{"n": 1}, 0)
+				assert.Nil(t, err)
+			},
+			group: "group",
+		},
+		{
+			name: "existing_group",
+			setup: func(stream Stream) {
+				assert.Nil(t, stream.CreateGroup(context.Background(), "events", "group", "$"))
+			},
+			group:       "group",
+			expectedErr: temperr.GroupExists,
+		},
+	}
+
+	for _, connector := range connectors {
+		// streams aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		for _, tc := range tcs {
+			t.Run(connector.Type()+"_"+tc.name, func(t *testing.T) {
+				ctx := context.Background()
+
+				flusher, err := flusher.NewFlusher(connector)
+				assert.Nil(t, err)
+
+				defer func() {
+					assert.Nil(t, flusher.FlushAll(ctx))
+				}()
+
+				stream, err := NewStream(connector)
+				assert.Nil(t, err)
+
+				if tc.setup != nil {
+					tc.setup(stream)
+				}
+
+				assert.Equal(t, tc.expectedErr, stream.CreateGroup(ctx, "events", tc.group, "0"))
+			})
+		}
+	}
+}
+
+func TestStream_ReadGroup(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		// streams aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func() {
+				assert.Nil(t, flusher.FlushAll(ctx))
+			}()
+
+			stream, err := NewStream(connector)
+			assert.Nil(t, err)
+
+			_, err = stream.ReadGroup(ctx, "events", "group", "consumer1", 10, 0)
+			assert.Equal(t, temperr.GroupNotFound, err)
+
+			assert.Nil(t, stream.CreateGroup(ctx, "events", "group", "$"))
+
+			ids := addMessages(t, stream, "events", 5)
+
+			// each message is delivered to a single consumer of the group
+			first, err := stream.ReadGroup(ctx, "events", "group", "consumer1", 3, 0)
+			require.NoError(t, err)
+			require.Len(t, first, 3)
+			assert.Equal(t, ids[:3], messageIDs(first))
+			assert.Equal(t, "0", first[0].Values["n"])
+
+			second, err := stream.ReadGroup(ctx, "events", "group", "consumer2", 10, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, ids[3:], messageIDs(second))
+
+			// there is no new message, so it waits up to block before returning none
+			start := time.Now()
+			none, err := stream.ReadGroup(ctx, "events", "group", "consumer1", 10, 100*time.Millisecond)
+			assert.Nil(t, err)
+			assert.Empty(t, none)
+			assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+			pending, err := stream.Pending(ctx, "events", "group", 0, 10)
+			require.NoError(t, err)
+			require.Len(t, pending, 5)
+			assert.Equal(t, ids[0], pending[0].ID)
+			assert.Equal(t, "consumer1", pending[0].Consumer)
+			assert.Equal(t, int64(1), pending[0].Deliveries)
+
+			acknowledged, err := stream.Acknowledge(ctx, "events", "group", ids[:3]...)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(3), acknowledged)
+
+			acknowledged, err = stream.Acknowledge(ctx, "events", "group", ids[0])
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), acknowledged)
+
+			pending, err = stream.Pending(ctx, "events", "group", 0, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, ids[3:], pendingIDs(pending))
+
+			removed, err := stream.RemoveConsumer(ctx, "events", "group", "consumer2")
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), removed)
+
+			pending, err = stream.Pending(ctx, "events", "group", 0, 10)
+			assert.Nil(t, err)
+			assert.Empty(t, pending)
+
+			assert.Nil(t, stream.DestroyGroup(ctx, "events", "group"))
+
+			_, err = stream.ReadGroup(ctx, "events", "group", "consumer1", 10, 0)
+			assert.Equal(t, temperr.GroupNotFound, err)
+		})
+	}
+}
+
+func TestStream_Claim(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		// streams aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+
+			flusher, err := flusher.NewFlusher(connector)
+			assert.Nil(t, err)
+
+			defer func() {
+				assert.Nil(t, flusher.FlushAll(ctx))
+			}()
+
+			stream, err := NewStream(connector)
+			assert.Nil(t, err)
+
+			assert.Nil(t, stream.CreateGroup(ctx, "events", "group", "$"))
+
+			ids := addMessages(t, stream, "events", 4)
+
+			_, err = stream.ReadGroup(ctx, "events", "group", "failed", 10, 0)
+			assert.Nil(t, err)
+
+			// the messages weren't idle long enough to be claimed
+			claimed, err := stream.Claim(ctx, "events", "group", "consumer", time.Hour, ids[0])
+			assert.Nil(t, err)
+			assert.Empty(t, claimed)
+
+			time.Sleep(50 * time.Millisecond)
+
+			claimed, err = stream.Claim(ctx, "events", "group", "consumer", 10*time.Millisecond, ids[0])
+			assert.Nil(t, err)
+			assert.Equal(t, ids[:1], messageIDs(claimed))
+
+			pending, err := stream.Pending(ctx, "events", "group", 0, 10)
+			require.NoError(t, err)
+			require.Len(t, pending, 4)
+			assert.Equal(t, "consumer", pending[0].Consumer)
+			assert.Equal(t, int64(2), pending[0].Deliveries)
+
+			// the remaining messages are claimed page by page
+			var autoClaimed []string
+
+			start := "0-0"
+
+			for {
+				claimed, next, err := stream.AutoClaim(ctx, "events", "group", "consumer", 10*time.Millisecond, start, 2)
+				if !assert.Nil(t, err) {
+					break
+				}
+
+				autoClaimed = append(autoClaimed, messageIDs(claimed)...)
+
+				if next == "0-0" {
+					break
+				}
+
+				start = next
+			}
+
+			assert.Equal(t, ids[1:], autoClaimed)
+
+			pending, err = stream.Pending(ctx, "events", "group", 0, 10)
+			assert.Nil(t, err)
+
+			for _, p := range pending {
+				assert.Equal(t, "consumer", p.Consumer)
+			}
+		})
+	}
+}
+
+func TestStream_ClosedConnection(t *testing.T) {
+	connectors := testutil.TestConnectors(t)
+	defer testutil.CloseConnectors(t, connectors)
+
+	for _, connector := range connectors {
+		// streams aren't supported by the memory connector
+		if connector.Type() == model.MemoryType {
+			continue
+		}
+
+		t.Run(connector.Type(), func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, connector.Disconnect(ctx))
+
+			stream, err := NewStream(connector)
+			assert.Nil(t, err)
+
+			_, err = stream.AddMessage(ctx, "events", map[string]interface{}{"n": 1}, 0)
+			assert.Equal(t, temperr.ClosedConnection, err)
+
+			_, err = stream.ReadGroup(ctx, "events", "group", "consumer", 10, 0)
+			assert.Equal(t, temperr.ClosedConnection, err)
+		})
+	}
+}
+
+// addMessages adds n messages to the stream, with their index as value of "n", returning their IDs.
+func addMessages(t *testing.T, stream Stream, name string, n int) []string {
+	t.Helper()
+
+	ids := make([]string, n)
+
+	for i := range ids {
+		id, err := stream.AddMessage(context.Background(), name, map[string]interface{}{"n": fmt.Sprint(i)}, 0)
+		assert.Nil(t, err)
+
+		ids[i] = id
+	}
+
+	return ids
+}
+
+func messageIDs(messages []model.StreamMessage) []string {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	return ids
+}
+
+func pendingIDs(messages []model.PendingMessage) []string {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	return ids
+}
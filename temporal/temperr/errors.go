@@ -1,6 +1,10 @@
 package temperr
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/TykTechnologies/storage/types"
+)
 
 var (
 	// Connection related errors
@@ -9,31 +13,46 @@ var (
 	InvalidHandlerType   = errors.New("invalid handler type")
 	InvalidConfiguration = errors.New("invalid configuration")
 	ClosedConnection     = errors.New("connection closed")
+	UnsupportedFeature   = errors.New("feature not supported by the server")
 
-	// Key related errors
-	KeyNotFound = errors.New("key not found")
+	// Key related errors. KeyNotFound matches types.ErrNotFound.
+	KeyNotFound = types.NewError(types.CodeNotFound, "key not found")
 	KeyEmpty    = errors.New("key cannot be empty")
 	KeyMisstype = errors.New("invalid operation for key type")
 
+	// Cursor related errors
+	InvalidCursor = errors.New("invalid cursor")
+
+	// Pipeline related errors
+	PipelineNotExecuted = errors.New("pipeline not executed")
+
+	// Stream related errors. GroupExists matches types.ErrDuplicateKey, and GroupNotFound types.ErrNotFound.
+	GroupExists   = types.NewError(types.CodeDuplicateKey, "consumer group already exists")
+	GroupNotFound = types.NewError(types.CodeNotFound, "consumer group not found")
+
+	// Lock related errors
+	LockNotAcquired = errors.New("lock already held")
+	LockNotHeld     = errors.New("lock not held")
+	InvalidTTL      = errors.New("ttl must be greater than 0")
+
+	// Rate limit related errors
+	InvalidRateLimit = errors.New("rate limit and window must be greater than 0")
+
+	// Script related errors
+	CrossSlot = errors.New("keys must map to the same cluster slot")
+
 	// Redis related errors
 	InvalidRedisClient = errors.New("invalid redis client")
 
 	// TLS related errors
-	// TLS related errors
-	InvalidTLSMaxVersion = errors.New(
-		"invalid MaxVersion specified. Please specify a valid TLS version: " +
-			"1.0, 1.1, 1.2, or 1.3",
-	)
-	InvalidTLSMinVersion = errors.New(
-		"invalid MinVersion specified. Please specify a valid TLS version: " +
-			"1.0, 1.1, 1.2, or 1.3",
-	)
-	InvalidTLSVersion = errors.New(
-		"MinVersion is higher than MaxVersion. Please specify a valid " +
-			"MinVersion that is lower or equal to MaxVersion",
-	)
-	AppendCertsFromPEM = errors.New("failed to add CA certificate")
+	InvalidTLSMaxVersion  = types.ErrInvalidTLSMaxVersion
+	InvalidTLSMinVersion  = types.ErrInvalidTLSMinVersion
+	InvalidTLSVersion     = types.ErrInvalidTLSVersion
+	InvalidTLSCipherSuite = types.ErrInvalidTLSCipherSuite
+	TLSKeyWithoutCert     = types.ErrTLSKeyWithoutCert
+	AppendCertsFromPEM    = types.ErrAppendCertsFromPEM
 
 	// Others
-	UnknownMessageType = errors.New("unknown message type")
+	UnknownMessageType     = errors.New("unknown message type")
+	UnexpectedScriptResult = errors.New("unexpected script result")
 )
@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 
+	model "github.com/TykTechnologies/storage/temporal/model"
 	mock "github.com/stretchr/testify/mock"
 
 	time "time"
@@ -15,6 +16,34 @@ type KeyValue struct {
 	mock.Mock
 }
 
+// AddScoredMember provides a mock function with given fields: ctx, key, member, score
+func (_m *KeyValue) AddScoredMember(ctx context.Context, key string, member string, score float64) (int64, error) {
+	ret := _m.Called(ctx, key, member, score)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddScoredMember")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, float64) (int64, error)); ok {
+		return rf(ctx, key, member, score)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, float64) int64); ok {
+		r0 = rf(ctx, key, member, score)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, float64) error); ok {
+		r1 = rf(ctx, key, member, score)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Decrement provides a mock function with given fields: ctx, key
 func (_m *KeyValue) Decrement(ctx context.Context, key string) (int64, error) {
 	ret := _m.Called(ctx, key)
@@ -61,6 +90,41 @@ func (_m *KeyValue) Delete(ctx context.Context, key string) error {
 	return r0
 }
 
+// DeleteFields provides a mock function with given fields: ctx, key, fields
+func (_m *KeyValue) DeleteFields(ctx context.Context, key string, fields ...string) (int64, error) {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteFields")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) (int64, error)); ok {
+		return rf(ctx, key, fields...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...string) int64); ok {
+		r0 = rf(ctx, key, fields...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, ...string) error); ok {
+		r1 = rf(ctx, key, fields...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteKeys provides a mock function with given fields: ctx, keys
 func (_m *KeyValue) DeleteKeys(ctx context.Context, keys []string) (int64, error) {
 	ret := _m.Called(ctx, keys)
@@ -191,6 +255,36 @@ func (_m *KeyValue) Get(ctx context.Context, key string) (string, error) {
 	return r0, r1
 }
 
+// GetAllFields provides a mock function with given fields: ctx, key
+func (_m *KeyValue) GetAllFields(ctx context.Context, key string) (map[string]string, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllFields")
+	}
+
+	var r0 map[string]string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (map[string]string, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) map[string]string); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetKeysAndValuesWithFilter provides a mock function with given fields: ctx, pattern
 func (_m *KeyValue) GetKeysAndValuesWithFilter(ctx context.Context, pattern string) (map[string]interface{}, error) {
 	ret := _m.Called(ctx, pattern)
@@ -267,6 +361,45 @@ func (_m *KeyValue) GetKeysWithOpts(ctx context.Context, searchStr string, curso
 	return r0, r1, r2, r3
 }
 
+// GetMembersByScoreRange provides a mock function with given fields: ctx, key, minScore, maxScore
+func (_m *KeyValue) GetMembersByScoreRange(ctx context.Context, key string, minScore string, maxScore string) ([]interface{}, []float64, error) {
+	ret := _m.Called(ctx, key, minScore, maxScore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMembersByScoreRange")
+	}
+
+	var r0 []interface{}
+	var r1 []float64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) ([]interface{}, []float64, error)); ok {
+		return rf(ctx, key, minScore, maxScore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) []interface{}); ok {
+		r0 = rf(ctx, key, minScore, maxScore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]interface{})
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) []float64); ok {
+		r1 = rf(ctx, key, minScore, maxScore)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]float64)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, string) error); ok {
+		r2 = rf(ctx, key, minScore, maxScore)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetMulti provides a mock function with given fields: ctx, keys
 func (_m *KeyValue) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
 	ret := _m.Called(ctx, keys)
@@ -355,6 +488,91 @@ func (_m *KeyValue) Keys(ctx context.Context, pattern string) ([]string, error)
 	return r0, r1
 }
 
+// Pipeline provides a mock function with given fields:
+func (_m *KeyValue) Pipeline() model.Pipeline {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Pipeline")
+	}
+
+	var r0 model.Pipeline
+	if rf, ok := ret.Get(0).(func() model.Pipeline); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(model.Pipeline)
+		}
+	}
+
+	return r0
+}
+
+// RemoveMembersByScoreRange provides a mock function with given fields: ctx, key, minScore, maxScore
+func (_m *KeyValue) RemoveMembersByScoreRange(ctx context.Context, key string, minScore string, maxScore string) (int64, error) {
+	ret := _m.Called(ctx, key, minScore, maxScore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RemoveMembersByScoreRange")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (int64, error)); ok {
+		return rf(ctx, key, minScore, maxScore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) int64); ok {
+		r0 = rf(ctx, key, minScore, maxScore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, key, minScore, maxScore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScanKeys provides a mock function with given fields: ctx, pattern, cursor, count
+func (_m *KeyValue) ScanKeys(ctx context.Context, pattern string, cursor model.Cursor, count int64) ([]string, model.Cursor, error) {
+	ret := _m.Called(ctx, pattern, cursor, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ScanKeys")
+	}
+
+	var r0 []string
+	var r1 model.Cursor
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.Cursor, int64) ([]string, model.Cursor, error)); ok {
+		return rf(ctx, pattern, cursor, count)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.Cursor, int64) []string); ok {
+		r0 = rf(ctx, pattern, cursor, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, model.Cursor, int64) model.Cursor); ok {
+		r1 = rf(ctx, pattern, cursor, count)
+	} else {
+		r1 = ret.Get(1).(model.Cursor)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, model.Cursor, int64) error); ok {
+		r2 = rf(ctx, pattern, cursor, count)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // Set provides a mock function with given fields: ctx, key, value, ttl
 func (_m *KeyValue) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
 	ret := _m.Called(ctx, key, value, ttl)
@@ -373,6 +591,34 @@ func (_m *KeyValue) Set(ctx context.Context, key string, value string, ttl time.
 	return r0
 }
 
+// SetFields provides a mock function with given fields: ctx, key, fields
+func (_m *KeyValue) SetFields(ctx context.Context, key string, fields map[string]interface{}) (int64, error) {
+	ret := _m.Called(ctx, key, fields)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetFields")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]interface{}) (int64, error)); ok {
+		return rf(ctx, key, fields)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]interface{}) int64); ok {
+		r0 = rf(ctx, key, fields)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, map[string]interface{}) error); ok {
+		r1 = rf(ctx, key, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetIfNotExist provides a mock function with given fields: ctx, key, value, expiration
 func (_m *KeyValue) SetIfNotExist(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
 	ret := _m.Called(ctx, key, value, expiration)
@@ -429,6 +675,26 @@ func (_m *KeyValue) TTL(ctx context.Context, key string) (int64, error) {
 	return r0, r1
 }
 
+// TxPipeline provides a mock function with given fields:
+func (_m *KeyValue) TxPipeline() model.Pipeline {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for TxPipeline")
+	}
+
+	var r0 model.Pipeline
+	if rf, ok := ret.Get(0).(func() model.Pipeline); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(model.Pipeline)
+		}
+	}
+
+	return r0
+}
+
 // NewKeyValue creates a new instance of KeyValue. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewKeyValue(t interface {
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"crypto/tls"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var (
+	// sighupGeneration is incremented on every SIGHUP, so the reloaders know their certificate is stale
+	sighupGeneration uint64
+	watchSIGHUPOnce  sync.Once
+)
+
+// certificateReloader reloads the client certificate from its files when it's stale: once the reload interval
+// elapsed since it was loaded, or once the process received SIGHUP. The certificate is reloaded on the next
+// handshake, so a failed reload, e.g. while the files are being rotated, keeps the current one until the next.
+type certificateReloader struct {
+	cfg      TLSConfig
+	interval time.Duration
+	nowFunc  func() time.Time
+
+	mu         sync.Mutex
+	cert       *tls.Certificate
+	loadedAt   time.Time
+	generation uint64
+}
+
+func newCertificateReloader(cfg *TLSConfig, cert *tls.Certificate, nowFunc func() time.Time) *certificateReloader {
+	if cfg.ReloadCertOnSIGHUP {
+		watchSIGHUPOnce.Do(watchSIGHUP)
+	}
+
+	return &certificateReloader{
+		cfg:        *cfg,
+		interval:   time.Duration(cfg.CertReloadInterval) * time.Second,
+		nowFunc:    nowFunc,
+		cert:       cert,
+		loadedAt:   nowFunc(),
+		generation: atomic.LoadUint64(&sighupGeneration),
+	}
+}
+
+// getClientCertificate returns the client certificate, reloading it first if it's stale.
+func (r *certificateReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.nowFunc()
+	generation := atomic.LoadUint64(&sighupGeneration)
+
+	expired := r.interval > 0 && now.Sub(r.loadedAt) >= r.interval
+	signaled := r.cfg.ReloadCertOnSIGHUP && generation != r.generation
+
+	if !expired && !signaled {
+		return r.cert, nil
+	}
+
+	if cert, err := r.cfg.loadClientCertificate(); err == nil {
+		r.cert = cert
+	}
+
+	r.loadedAt = now
+	r.generation = generation
+
+	return r.cert, nil
+}
+
+// watchSIGHUP marks the certificates of the reloaders as stale whenever the process receives SIGHUP.
+func watchSIGHUP() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			atomic.AddUint64(&sighupGeneration, 1)
+		}
+	}()
+}
@@ -0,0 +1,174 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate with the common name and its key to certFile and keyFile.
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(t *testing.T, getCert func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) string {
+	t.Helper()
+
+	cert, err := getCert(&tls.CertificateRequestInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestCertificateReloader_Interval(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &TLSConfig{
+		CertFile:           filepath.Join(dir, "cert.pem"),
+		KeyFile:            filepath.Join(dir, "key.pem"),
+		CertReloadInterval: 60,
+	}
+
+	writeCertificate(t, cfg.CertFile, cfg.KeyFile, "first")
+
+	cert, err := cfg.loadClientCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	reloader := newCertificateReloader(cfg, cert, func() time.Time { return now })
+
+	writeCertificate(t, cfg.CertFile, cfg.KeyFile, "second")
+
+	if name := commonName(t, reloader.getClientCertificate); name != "first" {
+		t.Errorf("Expected the certificate not to be reloaded before the interval, got %s", name)
+	}
+
+	now = now.Add(time.Minute)
+
+	if name := commonName(t, reloader.getClientCertificate); name != "second" {
+		t.Errorf("Expected the certificate to be reloaded after the interval, got %s", name)
+	}
+
+	// a certificate being rotated can't be loaded, so the current one is kept
+	if err := os.WriteFile(cfg.KeyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+
+	if name := commonName(t, reloader.getClientCertificate); name != "second" {
+		t.Errorf("Expected the current certificate to be kept, got %s", name)
+	}
+}
+
+func TestCertificateReloader_SIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &TLSConfig{
+		Enable:             true,
+		CertFile:           filepath.Join(dir, "cert.pem"),
+		KeyFile:            filepath.Join(dir, "key.pem"),
+		ReloadCertOnSIGHUP: true,
+	}
+
+	writeCertificate(t, cfg.CertFile, cfg.KeyFile, "first")
+
+	tlsConfig, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tlsConfig.Certificates) != 0 || tlsConfig.GetClientCertificate == nil {
+		t.Fatal("Expected the certificate to be reloadable")
+	}
+
+	writeCertificate(t, cfg.CertFile, cfg.KeyFile, "second")
+
+	if name := commonName(t, tlsConfig.GetClientCertificate); name != "first" {
+		t.Errorf("Expected the certificate not to be reloaded before SIGHUP, got %s", name)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for commonName(t, tlsConfig.GetClientCertificate) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the certificate to be reloaded after SIGHUP")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTLSConfig_GetClientCertificate(t *testing.T) {
+	cert := &tls.Certificate{}
+	cfg := &TLSConfig{
+		Enable:   true,
+		CertFile: "invalid/certfile",
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		},
+	}
+
+	tlsConfig, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tlsConfig.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil || got != cert {
+		t.Errorf("Expected the certificate of the callback, got %v, %v", got, err)
+	}
+}
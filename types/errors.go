@@ -0,0 +1,80 @@
+package types
+
+import "errors"
+
+// ErrorCode is the category of an error of a storage, shared by all the drivers.
+type ErrorCode int
+
+const (
+	// CodeUnknown is the code of the errors of no other category.
+	CodeUnknown ErrorCode = iota
+	// CodeNotFound is the code of the errors of the rows, keys or tables not found.
+	CodeNotFound
+	// CodeDuplicateKey is the code of the errors of the rows or keys that already exist.
+	CodeDuplicateKey
+	// CodeTimeout is the code of the errors of the operations that timed out.
+	CodeTimeout
+)
+
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeNotFound:
+		return "not found"
+	case CodeDuplicateKey:
+		return "duplicate key"
+	case CodeTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+// The sentinel errors of the codes, which the storage errors of the code match with errors.Is.
+var (
+	ErrNotFound     = errors.New(CodeNotFound.String())
+	ErrDuplicateKey = errors.New(CodeDuplicateKey.String())
+	ErrTimeout      = errors.New(CodeTimeout.String())
+)
+
+// StorageError is an error of an operation of a storage, categorized by its Code so it can be checked the same way
+// whatever the driver is, e.g. with errors.Is(err, ErrNotFound).
+// Its message is the one of the wrapped error, which can still be checked with errors.Is and errors.As.
+type StorageError struct {
+	// Code is the category of the error.
+	Code ErrorCode
+	// Driver is the driver the error was returned by, e.g. "mongo-go", if known.
+	Driver string
+	// Op is the name of the failed operation, e.g. "Query", if known.
+	Op string
+	// Table is the table the operation failed on, if any.
+	Table string
+	// Err is the wrapped error.
+	Err error
+}
+
+// NewError returns a StorageError of the code with the given message, e.g. for the sentinel errors of a driver.
+func NewError(code ErrorCode, msg string) *StorageError {
+	return &StorageError{Code: code, Err: errors.New(msg)}
+}
+
+func (e *StorageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the sentinel error of the code of the error.
+func (e *StorageError) Is(target error) bool {
+	switch e.Code {
+	case CodeNotFound:
+		return target == ErrNotFound
+	case CodeDuplicateKey:
+		return target == ErrDuplicateKey
+	case CodeTimeout:
+		return target == ErrTimeout
+	default:
+		return false
+	}
+}
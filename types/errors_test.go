@@ -0,0 +1,48 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStorageError(t *testing.T) {
+	errDriver := errors.New("driver error")
+	err := fmt.Errorf("wrapped: %w", &StorageError{Code: CodeNotFound, Driver: "mongo-go", Op: "Query", Err: errDriver})
+
+	if err.Error() != "wrapped: driver error" {
+		t.Errorf("Expected the message of the wrapped error, got %s", err.Error())
+	}
+
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicateKey) || errors.Is(err, ErrTimeout) {
+		t.Error("Expected the error to only match ErrNotFound")
+	}
+
+	if !errors.Is(err, errDriver) {
+		t.Error("Expected the error to match the wrapped error")
+	}
+
+	var storageErr *StorageError
+	if !errors.As(err, &storageErr) || storageErr.Op != "Query" || storageErr.Driver != "mongo-go" {
+		t.Errorf("Expected the storage error of the operation, got %v", storageErr)
+	}
+
+	if errors.Is(&StorageError{Code: CodeUnknown, Err: errDriver}, ErrNotFound) {
+		t.Error("Expected an unknown error not to match ErrNotFound")
+	}
+}
+
+func TestErrorCodeString(t *testing.T) {
+	codes := map[ErrorCode]string{
+		CodeUnknown:      "unknown",
+		CodeNotFound:     "not found",
+		CodeDuplicateKey: "duplicate key",
+		CodeTimeout:      "timeout",
+	}
+
+	for code, want := range codes {
+		if got := code.String(); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// Package types holds the types shared by the persistent and temporal storages.
+package types
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidTLSMaxVersion = errors.New(
+		"invalid MaxVersion specified. Please specify a valid TLS version: " +
+			"1.0, 1.1, 1.2, or 1.3",
+	)
+	ErrInvalidTLSMinVersion = errors.New(
+		"invalid MinVersion specified. Please specify a valid TLS version: " +
+			"1.0, 1.1, 1.2, or 1.3",
+	)
+	ErrInvalidTLSVersion = errors.New(
+		"MinVersion is higher than MaxVersion. Please specify a valid " +
+			"MinVersion that is lower or equal to MaxVersion",
+	)
+	ErrInvalidTLSCipherSuite = errors.New("invalid or insecure TLS cipher suite")
+	ErrTLSKeyWithoutCert     = errors.New("a TLS key file requires a cert file")
+	ErrAppendCertsFromPEM    = errors.New("failed to add CA certificate")
+)
+
+const (
+	defaultTLSMinVersion = "1.2"
+	defaultTLSMaxVersion = "1.3"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig is the TLS configuration of the connections of the storages.
+type TLSConfig struct {
+	// Flag that can be used to enable TLS. Defaults to false (disabled).
+	Enable bool `json:"enable"`
+	// Flag that can be used to skip TLS verification if TLS is enabled.
+	// Defaults to false.
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+	// Ignore hostname check when it differs from the original (for example with SSH tunneling).
+	// The rest of the TLS verification will still be performed.
+	AllowInvalidHostnames bool `json:"allow_invalid_hostnames"`
+	// Path to the PEM file with trusted root certificates.
+	CAFile string `json:"ca_file"`
+	// Path to the client certificate file, for mutual TLS. If KeyFile is empty,
+	// the file must contain both the certificate and its private key.
+	CertFile string `json:"cert_file"`
+	// Path to the private key file of the client certificate.
+	KeyFile string `json:"key_file"`
+	// Maximum TLS version that is supported.
+	// Options: ["1.0", "1.1", "1.2", "1.3"].
+	// Left unset by default, so the maximum version of crypto/tls applies: "1.3".
+	MaxVersion string `json:"max_version"`
+	// Minimum TLS version that is supported.
+	// Options: ["1.0", "1.1", "1.2", "1.3"].
+	// Left unset by default, so the minimum version of crypto/tls for clients applies: "1.2".
+	MinVersion string `json:"min_version"`
+	// Names of the cipher suites enabled up to TLS 1.2, e.g. "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+	// Defaults to the secure cipher suites of Go. The cipher suites of TLS 1.3 aren't configurable.
+	CipherSuites []string `json:"cipher_suites"`
+	// Interval in seconds at which the client certificate is reloaded from CertFile and KeyFile,
+	// so that it can be rotated without a restart. Defaults to 0 (never reloaded).
+	CertReloadInterval int `json:"cert_reload_interval"`
+	// Flag that can be used to reload the client certificate from CertFile and KeyFile when the process
+	// receives SIGHUP. Defaults to false.
+	ReloadCertOnSIGHUP bool `json:"reload_cert_on_sighup"`
+	// GetClientCertificate returns the client certificate of each handshake, e.g. from a secret store.
+	// It has precedence over CertFile and KeyFile.
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error) `json:"-"`
+}
+
+// Build returns the tls.Config of the configuration, loading its certificates.
+// It fails if a version, a cipher suite or a certificate is invalid.
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	minVersion, maxVersion, err := c.Versions()
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := c.cipherSuites()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		CipherSuites:       cipherSuites,
+	}
+
+	// the versions not configured are left to the defaults of crypto/tls
+	if c.MinVersion != "" {
+		tlsConfig.MinVersion = minVersion
+	}
+
+	if c.MaxVersion != "" {
+		tlsConfig.MaxVersion = maxVersion
+	}
+
+	if err := c.loadCACertificates(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	if err := c.setClientCertificate(tlsConfig); err != nil {
+		return nil, err
+	}
+
+	if c.AllowInvalidHostnames && !c.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+		verifyPeerCertificate(tlsConfig)
+	}
+
+	return tlsConfig, nil
+}
+
+// Versions returns the minimum and maximum TLS versions of the configuration, or the defaults of crypto/tls
+// if unset.
+func (c *TLSConfig) Versions() (minVersion, maxVersion uint16, err error) {
+	maxName := c.MaxVersion
+	if maxName == "" {
+		maxName = defaultTLSMaxVersion
+	}
+
+	maxVersion, ok := tlsVersions[maxName]
+	if !ok {
+		return 0, 0, ErrInvalidTLSMaxVersion
+	}
+
+	minName := c.MinVersion
+	if minName == "" {
+		minName = defaultTLSMinVersion
+	}
+
+	minVersion, ok = tlsVersions[minName]
+	if !ok {
+		return 0, maxVersion, ErrInvalidTLSMinVersion
+	}
+
+	if minVersion > maxVersion {
+		return minVersion, maxVersion, ErrInvalidTLSVersion
+	}
+
+	return minVersion, maxVersion, nil
+}
+
+func (c *TLSConfig) cipherSuites() ([]uint16, error) {
+	if len(c.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	ids := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+
+	cipherSuites := make([]uint16, 0, len(c.CipherSuites))
+
+	for _, name := range c.CipherSuites {
+		id, ok := ids[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTLSCipherSuite, name)
+		}
+
+		cipherSuites = append(cipherSuites, id)
+	}
+
+	return cipherSuites, nil
+}
+
+func (c *TLSConfig) loadCACertificates(tlsConfig *tls.Config) error {
+	if c.CAFile == "" {
+		return nil
+	}
+
+	caPem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return err
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caPem) {
+		return ErrAppendCertsFromPEM
+	}
+
+	tlsConfig.RootCAs = certPool
+
+	return nil
+}
+
+// setClientCertificate sets the client certificate of tlsConfig, which is reloaded on handshakes once stale
+// if its reloading is enabled.
+func (c *TLSConfig) setClientCertificate(tlsConfig *tls.Config) error {
+	if c.GetClientCertificate != nil {
+		tlsConfig.GetClientCertificate = c.GetClientCertificate
+
+		return nil
+	}
+
+	cert, err := c.loadClientCertificate()
+	if err != nil || cert == nil {
+		return err
+	}
+
+	if c.CertReloadInterval <= 0 && !c.ReloadCertOnSIGHUP {
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+
+		return nil
+	}
+
+	tlsConfig.GetClientCertificate = newCertificateReloader(c, cert, time.Now).getClientCertificate
+
+	return nil
+}
+
+// loadClientCertificate loads the client certificate from CertFile and KeyFile, or returns nil if there's none.
+func (c *TLSConfig) loadClientCertificate() (*tls.Certificate, error) {
+	switch {
+	case c.CertFile == "" && c.KeyFile == "":
+		return nil, nil
+	case c.CertFile == "":
+		return nil, ErrTLSKeyWithoutCert
+	case c.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return &cert, nil
+	}
+
+	// the certificate and its key are in the same file
+	raw, err := os.ReadFile(c.CertFile)
+	if err != nil {
+		return nil, errors.New("failure reading certificate file: " + err.Error())
+	}
+
+	cert, err := tls.X509KeyPair(raw, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+// verifyPeerCertificate verifies the certificate chain of the server against the root CAs of tlsConfig,
+// without checking its hostname.
+func verifyPeerCertificate(tlsConfig *tls.Config) {
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		certs := make([]*x509.Certificate, len(rawCerts))
+
+		for i, asn1Data := range rawCerts {
+			cert, err := x509.ParseCertificate(asn1Data)
+			if err != nil {
+				return err
+			}
+
+			certs[i] = cert
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         tlsConfig.RootCAs,
+			CurrentTime:   time.Now(),
+			DNSName:       "",
+			Intermediates: x509.NewCertPool(),
+		}
+
+		for i, cert := range certs {
+			if i == 0 {
+				continue
+			}
+
+			opts.Intermediates.AddCert(cert)
+		}
+
+		_, err := certs[0].Verify(opts)
+
+		return err
+	}
+}
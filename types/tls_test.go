@@ -1,24 +1,22 @@
-package tlsconfig
+package types
 
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"os"
 	"testing"
-
-	"github.com/TykTechnologies/storage/temporal/model"
-	"github.com/TykTechnologies/storage/temporal/temperr"
 )
 
-func TestHandleTLS(t *testing.T) {
+func TestTLSConfig_Build(t *testing.T) {
 	tests := []struct {
 		name        string
-		cfg         *model.TLS
+		cfg         *TLSConfig
 		expectedErr error
 	}{
 		{
 			name: "Valid config with Cert and Key",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				Enable:             true,
 				InsecureSkipVerify: false,
 				CertFile:           os.Getenv("TEST_TLS_CERT_FILE"),
@@ -30,7 +28,7 @@ func TestHandleTLS(t *testing.T) {
 		},
 		{
 			name: "Invalid Cert and Key paths",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				Enable:             true,
 				InsecureSkipVerify: false,
 				CertFile:           "invalid/certfile",
@@ -43,7 +41,7 @@ func TestHandleTLS(t *testing.T) {
 		},
 		{
 			name: "Invalid TLS version",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				Enable:             true,
 				InsecureSkipVerify: false,
 				CertFile:           os.Getenv("TEST_TLS_CERT_FILE"),
@@ -52,11 +50,11 @@ func TestHandleTLS(t *testing.T) {
 				MaxVersion:         "1.4",
 				MinVersion:         "1.2",
 			},
-			expectedErr: temperr.InvalidTLSMaxVersion,
+			expectedErr: ErrInvalidTLSMaxVersion,
 		},
 		{
 			name: "Invalid CA file",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				Enable:             true,
 				InsecureSkipVerify: false,
 				CertFile:           os.Getenv("TEST_TLS_CERT_FILE"),
@@ -67,37 +65,80 @@ func TestHandleTLS(t *testing.T) {
 			},
 			expectedErr: errors.New("open invalid/cafile: no such file or directory"),
 		},
+		{
+			name: "Key file without cert file",
+			cfg: &TLSConfig{
+				Enable:  true,
+				KeyFile: "/keyfile",
+			},
+			expectedErr: ErrTLSKeyWithoutCert,
+		},
+		{
+			name: "Valid cipher suites",
+			cfg: &TLSConfig{
+				Enable:       true,
+				CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			},
+		},
+		{
+			name: "Insecure cipher suite",
+			cfg: &TLSConfig{
+				Enable:       true,
+				CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"},
+			},
+			expectedErr: fmt.Errorf("%w: TLS_RSA_WITH_RC4_128_SHA", ErrInvalidTLSCipherSuite),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := HandleTLS(tt.cfg)
+			_, err := tt.cfg.Build()
 			if err != nil {
 				if tt.expectedErr == nil {
-					t.Errorf("HandleTLS() error = %v, expectedErr %v", err, tt.expectedErr)
+					t.Errorf("Build() error = %v, expectedErr %v", err, tt.expectedErr)
 				} else if err.Error() != tt.expectedErr.Error() {
-					t.Errorf("HandleTLS() error = %v, expectedErr %v", err, tt.expectedErr)
+					t.Errorf("Build() error = %v, expectedErr %v", err, tt.expectedErr)
 				}
 			}
 
 			if err == nil && tt.expectedErr != nil {
-				t.Errorf("HandleTLS() error = %v, expectedErr %v", err, tt.expectedErr)
+				t.Errorf("Build() error = %v, expectedErr %v", err, tt.expectedErr)
 			}
 		})
 	}
 }
 
-func TestHandleTLSVersion(t *testing.T) {
+func TestTLSConfig_BuildVersions(t *testing.T) {
+	tlsConfig, err := (&TLSConfig{Enable: true}).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if tlsConfig.MinVersion != 0 || tlsConfig.MaxVersion != 0 {
+		t.Errorf("Expected the versions to be left unset, got %v and %v", tlsConfig.MinVersion, tlsConfig.MaxVersion)
+	}
+
+	tlsConfig, err = (&TLSConfig{Enable: true, MinVersion: "1.3"}).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if tlsConfig.MinVersion != tls.VersionTLS13 || tlsConfig.MaxVersion != 0 {
+		t.Errorf("Expected only the minimum version to be set, got %v and %v", tlsConfig.MinVersion, tlsConfig.MaxVersion)
+	}
+}
+
+func TestTLSConfig_Versions(t *testing.T) {
 	tests := []struct {
 		name           string
-		cfg            *model.TLS
-		wantMinVersion int
-		wantMaxVersion int
+		cfg            *TLSConfig
+		wantMinVersion uint16
+		wantMaxVersion uint16
 		wantErr        error
 	}{
 		{
 			name: "Valid version range",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				MinVersion: "1.2",
 				MaxVersion: "1.3",
 			},
@@ -107,27 +148,27 @@ func TestHandleTLSVersion(t *testing.T) {
 		},
 		{
 			name: "Invalid max version",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				MinVersion: "1.2",
 				MaxVersion: "1.4", // invalid version
 			},
 			wantMinVersion: 0,
 			wantMaxVersion: 0,
-			wantErr:        temperr.InvalidTLSMaxVersion,
+			wantErr:        ErrInvalidTLSMaxVersion,
 		},
 		{
 			name: "Invalid min version",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				MinVersion: "1.4", // invalid version
 				MaxVersion: "1.3",
 			},
 			wantMinVersion: 0,
 			wantMaxVersion: tls.VersionTLS13,
-			wantErr:        temperr.InvalidTLSMinVersion,
+			wantErr:        ErrInvalidTLSMinVersion,
 		},
 		{
 			name: "Default values",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				MinVersion: "",
 				MaxVersion: "",
 			},
@@ -137,43 +178,67 @@ func TestHandleTLSVersion(t *testing.T) {
 		},
 		{
 			name: "Min version higher than max version",
-			cfg: &model.TLS{
+			cfg: &TLSConfig{
 				MinVersion: "1.3",
 				MaxVersion: "1.2",
 			},
 			wantMinVersion: tls.VersionTLS13,
 			wantMaxVersion: tls.VersionTLS12,
-			wantErr:        temperr.InvalidTLSVersion,
+			wantErr:        ErrInvalidTLSVersion,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			minVersion, maxVersion, err := HandleTLSVersion(tt.cfg)
+			minVersion, maxVersion, err := tt.cfg.Versions()
 
 			if (err != nil) != (tt.wantErr != nil) {
-				t.Errorf("HandleTLSVersion() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("Versions() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
 			if tt.wantErr != nil {
 				if err == nil {
-					t.Errorf("HandleTLSVersion() error = %v, wantErr %v", err, tt.wantErr)
+					t.Errorf("Versions() error = %v, wantErr %v", err, tt.wantErr)
 					return
 				}
 
 				if err.Error() != tt.wantErr.Error() {
-					t.Errorf("HandleTLSVersion() error = %v, wantErr %v", err, tt.wantErr)
+					t.Errorf("Versions() error = %v, wantErr %v", err, tt.wantErr)
 				}
 			}
 
 			if minVersion != tt.wantMinVersion {
-				t.Errorf("HandleTLSVersion() minVersion = %v, wantMinVersion %v", minVersion, tt.wantMinVersion)
+				t.Errorf("Versions() minVersion = %v, wantMinVersion %v", minVersion, tt.wantMinVersion)
 			}
 
 			if maxVersion != tt.wantMaxVersion {
-				t.Errorf("HandleTLSVersion() maxVersion = %v, wantMaxVersion %v", maxVersion, tt.wantMaxVersion)
+				t.Errorf("Versions() maxVersion = %v, wantMaxVersion %v", maxVersion, tt.wantMaxVersion)
 			}
 		})
 	}
 }
+
+func TestTLSConfig_AllowInvalidHostnames(t *testing.T) {
+	cfg := &TLSConfig{Enable: true, AllowInvalidHostnames: true}
+
+	tlsConfig, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if !tlsConfig.InsecureSkipVerify || tlsConfig.VerifyPeerCertificate == nil {
+		t.Error("Expected the certificate chain to be verified without the hostname")
+	}
+
+	cfg.InsecureSkipVerify = true
+
+	tlsConfig, err = cfg.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if tlsConfig.VerifyPeerCertificate != nil {
+		t.Error("Expected the certificate chain not to be verified")
+	}
+}